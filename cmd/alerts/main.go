@@ -0,0 +1,304 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Command alerts generates Prometheus alerting rules for the proxy's
+// metrics. The metric names and labels are read from the registered
+// pkg/metrics collectors, so renaming a metric regenerates the rules that
+// use it and a rule grouping by a label its metric lacks fails generation.
+//
+// Run it through go generate in pkg/metrics:
+//
+//	go generate ./pkg/metrics
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+	"gopkg.in/yaml.v3"
+)
+
+// header marks the generated file, per the Go generated code convention
+const header = "# Code generated by cmd/alerts; DO NOT EDIT.\n# Regenerate with: go generate ./pkg/metrics\n"
+
+// metric is a registered metric's name and variable labels
+type metric struct {
+	Name   string
+	Labels []string
+}
+
+// hasLabel reports whether the metric has a variable label
+func (m metric) hasLabel(label string) bool {
+	for _, l := range m.Labels {
+		if l == label {
+			return true
+		}
+	}
+	return false
+}
+
+// alert describes an alerting rule over one or more pkg/metrics collectors
+type alert struct {
+	Name     string
+	Metrics  []prometheus.Collector
+	By       []string // labels the expression keeps, required on every metric
+	Expr     func(by string, m ...metric) string
+	For      string
+	Severity string
+	Summary  string
+}
+
+// alerts are the generated rules with their default thresholds
+var alerts = []alert{
+	{
+		Name:     "GatewayHighErrorRate",
+		Metrics:  []prometheus.Collector{metrics.HTTPRequestErrors, metrics.HTTPRequestsTotal},
+		Expr:     ratio("5m", "> 0.05"),
+		For:      "10m",
+		Severity: "warning",
+		Summary:  "More than 5% of gateway requests are failing",
+	},
+	{
+		Name:     "GatewayHighLatency",
+		Metrics:  []prometheus.Collector{metrics.HTTPRequestDuration},
+		By:       []string{"path"},
+		Expr:     quantile(0.99, "5m", "> 30"),
+		For:      "10m",
+		Severity: "warning",
+		Summary:  "p99 latency of {{ $labels.path }} is above 30s",
+	},
+	{
+		Name:     "ProviderUnhealthy",
+		Metrics:  []prometheus.Collector{metrics.ProviderHealthy},
+		By:       []string{"provider"},
+		Expr:     gauge("== 0"),
+		For:      "5m",
+		Severity: "critical",
+		Summary:  "Provider {{ $labels.provider }} is unhealthy",
+	},
+	{
+		Name:     "ProviderHighErrorRate",
+		Metrics:  []prometheus.Collector{metrics.ProviderErrorRate},
+		By:       []string{"provider"},
+		Expr:     gauge("> 0.05"),
+		For:      "10m",
+		Severity: "warning",
+		Summary:  "Provider {{ $labels.provider }} error rate is above 5%",
+	},
+	{
+		Name:     "ProviderStreamErrors",
+		Metrics:  []prometheus.Collector{metrics.StreamErrors},
+		By:       []string{"provider"},
+		Expr:     increase("15m", "> 5"),
+		Severity: "warning",
+		Summary:  "Streams from provider {{ $labels.provider }} are failing mid-stream",
+	},
+	{
+		Name:     "ProviderQueueBacklog",
+		Metrics:  []prometheus.Collector{metrics.QueueDepth},
+		By:       []string{"provider"},
+		Expr:     gauge("> 50"),
+		For:      "5m",
+		Severity: "warning",
+		Summary:  "More than 50 requests are waiting for provider {{ $labels.provider }}",
+	},
+	{
+		Name:     "FallbackActivations",
+		Metrics:  []prometheus.Collector{metrics.FallbackActivations},
+		By:       []string{"primary"},
+		Expr:     increase("15m", "> 10"),
+		Severity: "info",
+		Summary:  "Requests to {{ $labels.primary }} are falling back to other providers",
+	},
+	{
+		Name:     "AWSRoleRefreshFailures",
+		Metrics:  []prometheus.Collector{metrics.AWSRoleRefreshes},
+		By:       []string{"role_arn"},
+		Expr:     increaseOf(`status="failure"`, "15m", "> 0"),
+		Severity: "critical",
+		Summary:  "Assuming role {{ $labels.role_arn }} is failing",
+	},
+	{
+		Name:     "NotificationsDropped",
+		Metrics:  []prometheus.Collector{metrics.NotificationsDropped},
+		By:       []string{"reason"},
+		Expr:     increase("15m", "> 0"),
+		Severity: "warning",
+		Summary:  "Webhook notifications are being dropped ({{ $labels.reason }})",
+	},
+	{
+		Name:     "BudgetExhausted",
+		Metrics:  []prometheus.Collector{metrics.BudgetRejected},
+		By:       []string{"subject"},
+		Expr:     increase("15m", "> 0"),
+		Severity: "info",
+		Summary:  "Requests of {{ $labels.subject }} are rejected for an exhausted budget",
+	},
+	{
+		Name:     "UnpricedModels",
+		Metrics:  []prometheus.Collector{metrics.UnpricedRequests},
+		By:       []string{"provider", "model"},
+		Expr:     increase("1h", "> 0"),
+		Severity: "info",
+		Summary:  "Model {{ $labels.model }} of {{ $labels.provider }} has no configured price",
+	},
+}
+
+// gauge compares a gauge's value, kept by the rule's labels
+func gauge(threshold string) func(string, ...metric) string {
+	return func(by string, m ...metric) string {
+		return fmt.Sprintf("max by (%s) (%s) %s", by, m[0].Name, threshold)
+	}
+}
+
+// increase compares a counter's increase over a range
+func increase(window, threshold string) func(string, ...metric) string {
+	return increaseOf("", window, threshold)
+}
+
+// increaseOf compares the increase of a counter's series matching a label
+// selector over a range
+func increaseOf(selector, window, threshold string) func(string, ...metric) string {
+	return func(by string, m ...metric) string {
+		name := m[0].Name
+		if selector != "" {
+			name += "{" + selector + "}"
+		}
+		return fmt.Sprintf("sum by (%s) (increase(%s[%s])) %s", by, name, window, threshold)
+	}
+}
+
+// ratio compares the rate of a counter to the rate of a second counter
+func ratio(window, threshold string) func(string, ...metric) string {
+	return func(by string, m ...metric) string {
+		return fmt.Sprintf("sum(rate(%s[%s])) / sum(rate(%s[%s])) %s", m[0].Name, window, m[1].Name, window, threshold)
+	}
+}
+
+// quantile compares a histogram quantile, kept by the rule's labels
+func quantile(q float64, window, threshold string) func(string, ...metric) string {
+	return func(by string, m ...metric) string {
+		return fmt.Sprintf("histogram_quantile(%g, sum by (le, %s) (rate(%s_bucket[%s]))) %s", q, by, m[0].Name, window, threshold)
+	}
+}
+
+// descPattern extracts the name and variable labels from a Desc's string
+// form, the only view of them client_golang exports
+var descPattern = regexp.MustCompile(`fqName: "([^"]+)".*variableLabels: \{([^}]*)\}`)
+
+// introspect returns the name and labels of a registered collector's metric
+func introspect(c prometheus.Collector) (metric, error) {
+	err := prometheus.DefaultRegisterer.Register(c)
+	if err == nil {
+		prometheus.DefaultRegisterer.Unregister(c)
+		return metric{}, errors.New("collector is not registered by pkg/metrics")
+	}
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if !errors.As(err, &alreadyRegistered) {
+		return metric{}, err
+	}
+
+	descs := make(chan *prometheus.Desc, 1)
+	go func() {
+		c.Describe(descs)
+		close(descs)
+	}()
+	var descStrings []string
+	for desc := range descs {
+		descStrings = append(descStrings, desc.String())
+	}
+	if len(descStrings) != 1 {
+		return metric{}, fmt.Errorf("collector describes %d metrics, want 1", len(descStrings))
+	}
+	match := descPattern.FindStringSubmatch(descStrings[0])
+	if match == nil {
+		return metric{}, fmt.Errorf("unrecognized metric description %s", descStrings[0])
+	}
+	m := metric{Name: match[1]}
+	if match[2] != "" {
+		m.Labels = strings.Split(match[2], ",")
+	}
+	return m, nil
+}
+
+// ruleFile is the Prometheus alerting rule file format
+type ruleFile struct {
+	Groups []ruleGroup `yaml:"groups"`
+}
+
+type ruleGroup struct {
+	Name  string `yaml:"name"`
+	Rules []rule `yaml:"rules"`
+}
+
+type rule struct {
+	Alert       string            `yaml:"alert"`
+	Expr        string            `yaml:"expr"`
+	For         string            `yaml:"for,omitempty"`
+	Labels      map[string]string `yaml:"labels"`
+	Annotations map[string]string `yaml:"annotations"`
+}
+
+// generate renders the alerting rule file
+func generate() ([]byte, error) {
+	group := ruleGroup{Name: "llm-proxy"}
+	for _, a := range alerts {
+		var used []metric
+		for _, c := range a.Metrics {
+			m, err := introspect(c)
+			if err != nil {
+				return nil, fmt.Errorf("alert %s: %w", a.Name, err)
+			}
+			for _, label := range a.By {
+				if !m.hasLabel(label) {
+					return nil, fmt.Errorf("alert %s: metric %s has no label %q", a.Name, m.Name, label)
+				}
+			}
+			used = append(used, m)
+		}
+		group.Rules = append(group.Rules, rule{
+			Alert:       a.Name,
+			Expr:        a.Expr(strings.Join(a.By, ", "), used...),
+			For:         a.For,
+			Labels:      map[string]string{"severity": a.Severity},
+			Annotations: map[string]string{"summary": a.Summary},
+		})
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(header)
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(ruleFile{Groups: []ruleGroup{group}}); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func main() {
+	output := flag.String("o", "prometheus-alerts.yaml", "output file, or - for stdout")
+	flag.Parse()
+
+	out, err := generate()
+	if err != nil {
+		log.Fatalf("Failed to generate alerting rules: %v", err)
+	}
+	if *output == "-" {
+		os.Stdout.Write(out)
+		return
+	}
+	if err := os.WriteFile(*output, out, 0644); err != nil {
+		log.Fatalf("Failed to write alerting rules: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// TestGeneratedAlertsUpToDate tests that the committed alerting rules match
+// the generator, so a metric change without go generate fails
+func TestGeneratedAlertsUpToDate(t *testing.T) {
+	want, err := generate()
+	if err != nil {
+		t.Fatalf("generate: %v", err)
+	}
+	got, err := os.ReadFile("../../deployments/prometheus-alerts.yaml")
+	if err != nil {
+		t.Fatalf("read generated rules: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("deployments/prometheus-alerts.yaml is stale; run go generate ./pkg/metrics")
+	}
+}
+
+func TestIntrospect(t *testing.T) {
+	m, err := introspect(metrics.ProviderHealthy)
+	if err != nil {
+		t.Fatalf("introspect: %v", err)
+	}
+	if m.Name != "provider_healthy" || len(m.Labels) != 1 || m.Labels[0] != "provider" {
+		t.Errorf("introspect = %+v", m)
+	}
+
+	m, err = introspect(metrics.ConnectedClients)
+	if err != nil || m.Name != "connected_clients" || len(m.Labels) != 0 {
+		t.Errorf("introspect gauge = %+v, %v", m, err)
+	}
+
+	unregistered := prometheus.NewGauge(prometheus.GaugeOpts{Name: "unregistered_gauge", Help: "Not registered"})
+	if _, err := introspect(unregistered); err == nil {
+		t.Error("introspect of an unregistered collector succeeded")
+	}
+}
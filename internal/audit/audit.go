@@ -0,0 +1,235 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package audit writes an append-only record of who requested what: one
+// JSON line per request with the principal, model, instance, status and
+// token counts, never message content. It is independent of the access
+// log, buffers its writes and reopens its file on SIGHUP so it can be
+// rotated by moving the file.
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Gin context keys handlers set for the audit log
+const (
+	// TargetKey holds the Target of a request
+	TargetKey = "audit_target"
+
+	// UsageKey holds the Usage of a response
+	UsageKey = "audit_usage"
+)
+
+// Target is the model, provider and instance a request was routed to.
+// Instance is empty for requests not served by an instance.
+type Target struct {
+	Model    string
+	Provider string
+	Instance string
+}
+
+// Usage is the token usage of a response, as the provider reported it
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// Entry is one audit log line
+type Entry struct {
+	Timestamp        time.Time `json:"timestamp"`
+	RequestID        string    `json:"request_id,omitempty"`
+	Principal        string    `json:"principal,omitempty"`
+	AuthMethod       string    `json:"auth_method,omitempty"`
+	Method           string    `json:"method"`
+	Path             string    `json:"path"`
+	Model            string    `json:"model,omitempty"`
+	Provider         string    `json:"provider,omitempty"`
+	Instance         string    `json:"instance,omitempty"`
+	Status           int       `json:"status"`
+	PromptTokens     int       `json:"prompt_tokens,omitempty"`
+	CompletionTokens int       `json:"completion_tokens,omitempty"`
+	TotalTokens      int       `json:"total_tokens,omitempty"`
+	LatencyMs        int64     `json:"latency_ms"`
+}
+
+// Config configures the audit log
+type Config struct {
+	// Path is the file entries are appended to; "-" or "stdout" writes to
+	// standard output, and empty disables the audit log
+	Path string
+
+	// FlushInterval bounds how long an entry stays buffered (default: 1s)
+	FlushInterval time.Duration
+}
+
+// Enabled reports whether the audit log is configured
+func (c Config) Enabled() bool {
+	return c.Path != ""
+}
+
+// stdout reports whether entries go to standard output
+func (c Config) stdout() bool {
+	return c.Path == "-" || c.Path == "stdout"
+}
+
+// LoadConfigFromEnv reads the audit log config from AUDIT_LOG_PATH and
+// AUDIT_LOG_FLUSH_INTERVAL
+func LoadConfigFromEnv() (Config, error) {
+	config := Config{Path: strings.TrimSpace(os.Getenv("AUDIT_LOG_PATH"))}
+	if value := os.Getenv("AUDIT_LOG_FLUSH_INTERVAL"); value != "" {
+		interval, err := time.ParseDuration(value)
+		if err != nil || interval <= 0 {
+			return Config{}, fmt.Errorf("invalid AUDIT_LOG_FLUSH_INTERVAL %q: must be a positive duration", value)
+		}
+		config.FlushInterval = interval
+	}
+	return config, nil
+}
+
+// Logger appends entries to the audit log. Entries are buffered and
+// flushed every FlushInterval, on Reopen and on Close.
+type Logger struct {
+	config Config
+
+	mu     sync.Mutex
+	file   *os.File // nil when writing to stdout
+	out    io.Writer
+	buf    *bufio.Writer
+	closed bool
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New opens the audit log and starts its periodic flush
+func New(config Config) (*Logger, error) {
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = time.Second
+	}
+	l := &Logger{
+		config: config,
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	go l.flushLoop()
+	return l, nil
+}
+
+// open opens the configured file for appending, or standard output.
+// l.mu must be held or the logger unused by other goroutines.
+func (l *Logger) open() error {
+	if l.config.stdout() {
+		l.file, l.out = nil, os.Stdout
+	} else {
+		file, err := os.OpenFile(l.config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		l.file, l.out = file, file
+	}
+	l.buf = bufio.NewWriter(l.out)
+	return nil
+}
+
+// Log appends an entry. Write errors are logged, not returned: the audit
+// log must not fail the request it records.
+func (l *Logger) Log(entry Entry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Audit log: failed to encode entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return
+	}
+	if _, err := l.buf.Write(line); err != nil {
+		log.Printf("Audit log: write failed: %v", err)
+	}
+}
+
+// Flush writes buffered entries
+func (l *Logger) Flush() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	return l.buf.Flush()
+}
+
+// Reopen flushes and closes the audit log file and opens it again by
+// path, so entries go to a new file once the old one has been moved
+// aside. It is a flush when writing to standard output.
+func (l *Logger) Reopen() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return nil
+	}
+	if err := l.buf.Flush(); err != nil {
+		log.Printf("Audit log: flush before reopen failed: %v", err)
+	}
+	if l.file == nil {
+		return nil
+	}
+	if err := l.file.Close(); err != nil {
+		log.Printf("Audit log: close before reopen failed: %v", err)
+	}
+	return l.open()
+}
+
+// Close stops the periodic flush, flushes buffered entries and closes the
+// file. Entries logged after Close are dropped.
+func (l *Logger) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	err := l.buf.Flush()
+	if l.file != nil {
+		if closeErr := l.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	l.mu.Unlock()
+
+	close(l.stop)
+	<-l.done
+	return err
+}
+
+// flushLoop flushes buffered entries every FlushInterval until Close
+func (l *Logger) flushLoop() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.config.FlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C:
+			if err := l.Flush(); err != nil {
+				log.Printf("Audit log: flush failed: %v", err)
+			}
+		}
+	}
+}
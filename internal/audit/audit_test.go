@@ -0,0 +1,152 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+)
+
+// readEntries decodes the JSON lines of an audit log file
+func readEntries(t *testing.T, path string) []Entry {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("open audit log: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			t.Fatalf("audit line %q: %v", scanner.Text(), err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func newTestLogger(t *testing.T, path string) *Logger {
+	t.Helper()
+	l, err := New(Config{Path: path, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
+// TestMiddleware tests the entry logged for a request: principal, target
+// and usage from the gin context, and no entry for health checks
+func TestMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "audit.log")
+	l := newTestLogger(t, path)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set(middleware.RequestIDKey, "req-1")
+		c.Next()
+	})
+	r.Use(l.Middleware())
+	r.POST("/v1/chat/completions", func(c *gin.Context) {
+		c.Set("user", "alice")
+		c.Set("auth_method", "api_key")
+		c.Set(TargetKey, Target{Model: "gpt-4o", Provider: "openai", Instance: "openai-east"})
+		c.Set(UsageKey, Usage{PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15})
+		c.JSON(http.StatusOK, gin.H{"choices": []string{"secret answer"}})
+	})
+	r.GET("/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	body := strings.NewReader(`{"messages":[{"role":"user","content":"secret question"}]}`)
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", body))
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/health", nil))
+
+	if entries := readEntries(t, path); len(entries) != 0 {
+		t.Errorf("entries written before a flush: %+v", entries)
+	}
+	if err := l.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	entries := readEntries(t, path)
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries, want 1: %+v", len(entries), entries)
+	}
+	got := entries[0]
+	want := Entry{
+		RequestID: "req-1", Principal: "alice", AuthMethod: "api_key",
+		Method: http.MethodPost, Path: "/v1/chat/completions",
+		Model: "gpt-4o", Provider: "openai", Instance: "openai-east",
+		Status: http.StatusOK, PromptTokens: 12, CompletionTokens: 3, TotalTokens: 15,
+	}
+	got.Timestamp, got.LatencyMs = time.Time{}, 0
+	if got != want {
+		t.Errorf("entry = %+v, want %+v", got, want)
+	}
+
+	raw, _ := os.ReadFile(path)
+	if strings.Contains(string(raw), "secret") {
+		t.Errorf("audit log contains message content: %s", raw)
+	}
+}
+
+// TestReopen tests that entries go to a new file after the old one has
+// been moved aside and the log reopened
+func TestReopen(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "audit.log")
+	l := newTestLogger(t, path)
+
+	l.Log(Entry{RequestID: "before"})
+	if err := os.Rename(path, filepath.Join(dir, "audit.log.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Reopen(); err != nil {
+		t.Fatalf("Reopen() error = %v", err)
+	}
+	l.Log(Entry{RequestID: "after"})
+	if err := l.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	l.Log(Entry{RequestID: "closed"})
+
+	rotated := readEntries(t, filepath.Join(dir, "audit.log.1"))
+	current := readEntries(t, path)
+	if len(rotated) != 1 || rotated[0].RequestID != "before" {
+		t.Errorf("rotated file entries = %+v, want the entry before reopen", rotated)
+	}
+	if len(current) != 1 || current[0].RequestID != "after" {
+		t.Errorf("current file entries = %+v, want the entry after reopen", current)
+	}
+}
+
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("AUDIT_LOG_PATH", "-")
+	t.Setenv("AUDIT_LOG_FLUSH_INTERVAL", "250ms")
+	config, err := LoadConfigFromEnv()
+	if err != nil || !config.Enabled() || !config.stdout() || config.FlushInterval != 250*time.Millisecond {
+		t.Errorf("LoadConfigFromEnv() = %+v, %v", config, err)
+	}
+
+	t.Setenv("AUDIT_LOG_FLUSH_INTERVAL", "soon")
+	if _, err := LoadConfigFromEnv(); err == nil {
+		t.Error("invalid flush interval accepted")
+	}
+
+	t.Setenv("AUDIT_LOG_PATH", "")
+	t.Setenv("AUDIT_LOG_FLUSH_INTERVAL", "")
+	if config, _ := LoadConfigFromEnv(); config.Enabled() {
+		t.Error("audit log enabled without a path")
+	}
+}
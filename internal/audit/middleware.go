@@ -0,0 +1,63 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package audit
+
+import (
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+)
+
+// unauditedPrefixes are the unauthenticated health, metrics and status
+// page routes, which carry no principal or model
+var unauditedPrefixes = []string{"/health", "/ready", "/metrics", "/ui"}
+
+// Middleware logs an entry for each request once it has been handled. It
+// must run before the authentication middleware, whose principal it
+// reads after the request completes.
+func (l *Logger) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		for _, prefix := range unauditedPrefixes {
+			if strings.HasPrefix(c.Request.URL.Path, prefix) {
+				c.Next()
+				return
+			}
+		}
+
+		start := time.Now()
+		c.Next()
+		l.Log(newEntry(c, start))
+	}
+}
+
+// newEntry builds the audit entry of a handled request
+func newEntry(c *gin.Context, start time.Time) Entry {
+	entry := Entry{
+		Timestamp:  start.UTC(),
+		RequestID:  c.GetString(middleware.RequestIDKey),
+		Principal:  c.GetString("user"),
+		AuthMethod: c.GetString("auth_method"),
+		Method:     c.Request.Method,
+		Path:       c.Request.URL.Path,
+		Status:     c.Writer.Status(),
+		LatencyMs:  time.Since(start).Milliseconds(),
+	}
+	if target, ok := c.Get(TargetKey); ok {
+		if target, ok := target.(Target); ok {
+			entry.Model = target.Model
+			entry.Provider = target.Provider
+			entry.Instance = target.Instance
+		}
+	}
+	if usage, ok := c.Get(UsageKey); ok {
+		if usage, ok := usage.(Usage); ok {
+			entry.PromptTokens = usage.PromptTokens
+			entry.CompletionTokens = usage.CompletionTokens
+			entry.TotalTokens = usage.TotalTokens
+		}
+	}
+	return entry
+}
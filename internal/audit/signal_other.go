@@ -0,0 +1,12 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix
+
+package audit
+
+import "context"
+
+// ReopenOnSignal is a no-op on platforms without SIGHUP; rotate by copy
+// and truncate instead
+func (l *Logger) ReopenOnSignal(ctx context.Context) {}
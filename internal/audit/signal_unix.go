@@ -0,0 +1,36 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unix
+
+package audit
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// ReopenOnSignal reopens the audit log each time the process receives
+// SIGHUP, until ctx is cancelled, so log rotation can move the file aside
+// and signal the proxy
+func (l *Logger) ReopenOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				if err := l.Reopen(); err != nil {
+					log.Printf("Audit log: reopen on SIGHUP failed: %v", err)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,208 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package auth
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+const (
+	// RoleRefreshBefore is how long before expiry assumed role credentials
+	// are refreshed
+	RoleRefreshBefore = 5 * time.Minute
+
+	// defaultRoleSessionName names the sessions of assumed roles
+	defaultRoleSessionName = "llm-gateway"
+)
+
+// AssumeRoleConfig configures a chain of role assumptions, for reaching
+// models only accessible from other AWS accounts
+type AssumeRoleConfig struct {
+	// RoleARNs are assumed in order, each with the credentials of the role
+	// before it; the first with the default credential chain
+	RoleARNs []string
+
+	// ExternalID is sent with each AssumeRole call, for trust policies
+	// that require it
+	ExternalID string
+
+	// Region is the region of the STS endpoint
+	Region string
+
+	// SessionName names the role sessions (default: llm-gateway)
+	SessionName string
+}
+
+// assumeRoleAPI is the STS call role assumption needs
+type assumeRoleAPI interface {
+	AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error)
+}
+
+// RoleCredentials provides the credentials of the last role of an
+// assumption chain. They are refreshed by a background goroutine
+// RoleRefreshBefore they expire; Close stops it.
+type RoleCredentials struct {
+	config AssumeRoleConfig
+	base   aws.CredentialsProvider
+
+	// newClient returns an STS client calling with the given credentials
+	newClient func(aws.CredentialsProvider) assumeRoleAPI
+
+	mu    sync.RWMutex
+	creds aws.Credentials
+
+	// retry is the wait after a failed refresh, and minRefresh the least
+	// wait between refreshes; both are overridable for tests
+	retry      time.Duration
+	minRefresh time.Duration
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewRoleCredentials assumes the chain of roles, starting from the default
+// credential chain, and starts refreshing the credentials in the background
+func NewRoleCredentials(ctx context.Context, cfg AssumeRoleConfig) (*RoleCredentials, error) {
+	if len(cfg.RoleARNs) == 0 {
+		return nil, fmt.Errorf("at least one role ARN is required")
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("unable to load AWS config: %w", err)
+	}
+	newClient := func(credentials aws.CredentialsProvider) assumeRoleAPI {
+		return sts.NewFromConfig(awsCfg, func(o *sts.Options) { o.Credentials = credentials })
+	}
+
+	r := newRoleCredentials(cfg, awsCfg.Credentials, newClient)
+	if err := r.refresh(ctx); err != nil {
+		return nil, err
+	}
+	log.Printf("✓ Assumed role %s", r.RoleARN())
+	go r.run()
+	return r, nil
+}
+
+func newRoleCredentials(cfg AssumeRoleConfig, base aws.CredentialsProvider, newClient func(aws.CredentialsProvider) assumeRoleAPI) *RoleCredentials {
+	if cfg.SessionName == "" {
+		cfg.SessionName = defaultRoleSessionName
+	}
+	return &RoleCredentials{
+		config:     cfg,
+		base:       base,
+		newClient:  newClient,
+		retry:      30 * time.Second,
+		minRefresh: 10 * time.Second,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// RoleARN returns the role whose credentials are provided
+func (r *RoleCredentials) RoleARN() string {
+	return r.config.RoleARNs[len(r.config.RoleARNs)-1]
+}
+
+// Retrieve returns the current credentials of the last role, or an error
+// once they have expired without being refreshed
+func (r *RoleCredentials) Retrieve(ctx context.Context) (aws.Credentials, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.creds.Expired() {
+		return aws.Credentials{}, fmt.Errorf("credentials for role %s expired and could not be refreshed", r.RoleARN())
+	}
+	return r.creds, nil
+}
+
+// Close stops the background refresh
+func (r *RoleCredentials) Close() {
+	close(r.stop)
+	<-r.done
+}
+
+// run refreshes the credentials before they expire until Close is called
+func (r *RoleCredentials) run() {
+	defer close(r.done)
+	wait := r.untilRefresh()
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-r.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		if err := r.refresh(context.Background()); err != nil {
+			log.Printf("Failed to refresh credentials for role %s, retrying in %s: %v", r.RoleARN(), r.retry, err)
+			wait = r.retry
+			continue
+		}
+		wait = r.untilRefresh()
+	}
+}
+
+// untilRefresh returns the time until the credentials should be refreshed
+func (r *RoleCredentials) untilRefresh() time.Duration {
+	r.mu.RLock()
+	expires := r.creds.Expires
+	r.mu.RUnlock()
+	wait := time.Until(expires) - RoleRefreshBefore
+	if wait < r.minRefresh {
+		wait = r.minRefresh
+	}
+	return wait
+}
+
+// refresh assumes each role of the chain with the previous role's
+// credentials and stores the last role's
+func (r *RoleCredentials) refresh(ctx context.Context) error {
+	provider := r.base
+	var creds aws.Credentials
+	for _, roleARN := range r.config.RoleARNs {
+		input := &sts.AssumeRoleInput{
+			RoleArn:         aws.String(roleARN),
+			RoleSessionName: aws.String(r.config.SessionName),
+		}
+		if r.config.ExternalID != "" {
+			input.ExternalId = aws.String(r.config.ExternalID)
+		}
+		out, err := r.newClient(provider).AssumeRole(ctx, input)
+		if err != nil {
+			metrics.AWSRoleRefreshes.WithLabelValues(r.RoleARN(), "failure").Inc()
+			return fmt.Errorf("unable to assume role %s: %w", roleARN, err)
+		}
+		if out.Credentials == nil {
+			metrics.AWSRoleRefreshes.WithLabelValues(r.RoleARN(), "failure").Inc()
+			return fmt.Errorf("no credentials returned for role %s", roleARN)
+		}
+		creds = aws.Credentials{
+			AccessKeyID:     aws.ToString(out.Credentials.AccessKeyId),
+			SecretAccessKey: aws.ToString(out.Credentials.SecretAccessKey),
+			SessionToken:    aws.ToString(out.Credentials.SessionToken),
+			Source:          "AssumeRole",
+			CanExpire:       true,
+			Expires:         aws.ToTime(out.Credentials.Expiration),
+		}
+		hop := creds
+		provider = aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return hop, nil
+		})
+	}
+
+	r.mu.Lock()
+	r.creds = creds
+	r.mu.Unlock()
+	metrics.AWSRoleRefreshes.WithLabelValues(r.RoleARN(), "success").Inc()
+	return nil
+}
@@ -0,0 +1,129 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/aws-sdk-go-v2/service/sts/types"
+)
+
+// fakeSTS issues credentials named after the role, recording the
+// credentials each call was made with
+type fakeSTS struct {
+	mu      sync.Mutex
+	calls   []string
+	callers []string
+	input   []*sts.AssumeRoleInput
+	expires time.Duration
+}
+
+func (f *fakeSTS) client(credentials aws.CredentialsProvider) assumeRoleAPI {
+	return &fakeSTSClient{sts: f, credentials: credentials}
+}
+
+func (f *fakeSTS) count() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.calls)
+}
+
+type fakeSTSClient struct {
+	sts         *fakeSTS
+	credentials aws.CredentialsProvider
+}
+
+func (c *fakeSTSClient) AssumeRole(ctx context.Context, params *sts.AssumeRoleInput, optFns ...func(*sts.Options)) (*sts.AssumeRoleOutput, error) {
+	caller, err := c.credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.sts.mu.Lock()
+	defer c.sts.mu.Unlock()
+	c.sts.calls = append(c.sts.calls, aws.ToString(params.RoleArn))
+	c.sts.callers = append(c.sts.callers, caller.AccessKeyID)
+	c.sts.input = append(c.sts.input, params)
+	return &sts.AssumeRoleOutput{Credentials: &types.Credentials{
+		AccessKeyId:     params.RoleArn,
+		SecretAccessKey: aws.String("secret"),
+		SessionToken:    aws.String("token"),
+		Expiration:      aws.Time(time.Now().Add(c.sts.expires)),
+	}}, nil
+}
+
+func staticCredentials(id string) aws.CredentialsProvider {
+	return aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: id}, nil
+	})
+}
+
+func TestRoleCredentialsChain(t *testing.T) {
+	fake := &fakeSTS{expires: time.Hour}
+	r := newRoleCredentials(AssumeRoleConfig{
+		RoleARNs:   []string{"arn:aws:iam::111:role/hop", "arn:aws:iam::222:role/bedrock"},
+		ExternalID: "ext-42",
+	}, staticCredentials("base"), fake.client)
+
+	if err := r.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+
+	wantCallers := []string{"base", "arn:aws:iam::111:role/hop"}
+	for i, caller := range fake.callers {
+		if caller != wantCallers[i] {
+			t.Errorf("call %d made with %q, want %q", i, caller, wantCallers[i])
+		}
+		if got := aws.ToString(fake.input[i].ExternalId); got != "ext-42" {
+			t.Errorf("call %d external ID = %q, want ext-42", i, got)
+		}
+		if got := aws.ToString(fake.input[i].RoleSessionName); got != defaultRoleSessionName {
+			t.Errorf("call %d session name = %q, want %q", i, got, defaultRoleSessionName)
+		}
+	}
+	if len(fake.calls) != 2 {
+		t.Fatalf("calls = %v, want 2", fake.calls)
+	}
+
+	creds, err := r.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if creds.AccessKeyID != r.RoleARN() {
+		t.Errorf("credentials are for %q, want %q", creds.AccessKeyID, r.RoleARN())
+	}
+}
+
+func TestRoleCredentialsExpired(t *testing.T) {
+	fake := &fakeSTS{expires: -time.Minute}
+	r := newRoleCredentials(AssumeRoleConfig{RoleARNs: []string{"arn:aws:iam::111:role/a"}}, staticCredentials("base"), fake.client)
+	if err := r.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if _, err := r.Retrieve(context.Background()); err == nil {
+		t.Error("expected an error for expired credentials")
+	}
+}
+
+func TestRoleCredentialsBackgroundRefresh(t *testing.T) {
+	// Credentials expiring just after the refresh window are refreshed
+	// after minRefresh
+	fake := &fakeSTS{expires: RoleRefreshBefore + time.Millisecond}
+	r := newRoleCredentials(AssumeRoleConfig{RoleARNs: []string{"arn:aws:iam::111:role/a"}}, staticCredentials("base"), fake.client)
+	r.minRefresh = 5 * time.Millisecond
+	if err := r.refresh(context.Background()); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	go r.run()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for fake.count() < 3 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	r.Close()
+	if n := fake.count(); n < 3 {
+		t.Errorf("AssumeRole called %d times, want background refreshes", n)
+	}
+}
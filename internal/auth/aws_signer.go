@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 )
@@ -17,6 +18,9 @@ import (
 type AWSSigner struct {
 	region  string
 	service string
+
+	// credentials replace the default credential chain when set
+	credentials aws.CredentialsProvider
 }
 
 // NewAWSSigner creates a new AWS signer with EKS-optimized credential chain
@@ -27,16 +31,38 @@ func NewAWSSigner(region, service string) (*AWSSigner, error) {
 	}, nil
 }
 
+// SetCredentials signs with the given credentials, such as an assumed
+// role's, instead of the default credential chain
+func (s *AWSSigner) SetCredentials(credentials aws.CredentialsProvider) {
+	s.credentials = credentials
+}
+
+// UnsignedPayload is signed in place of the body hash for bodies that are
+// streamed without hashing. S3 accepts it over HTTPS; most services do not.
+const UnsignedPayload = "UNSIGNED-PAYLOAD"
+
 // SignRequest signs an HTTP request using AWS Signature V4
 func (s *AWSSigner) SignRequest(req *http.Request, body []byte) error {
-	// Load AWS config with default credential chain (supports IRSA, EC2 instance profile, env vars)
-	cfg, err := config.LoadDefaultConfig(context.TODO())
-	if err != nil {
-		log.Printf("Unable to load AWS config: %v", err)
-		return fmt.Errorf("unable to load AWS config: %w", err)
+	payloadHash := sha256.Sum256(body)
+	return s.SignRequestWithPayloadHash(req, hex.EncodeToString(payloadHash[:]))
+}
+
+// SignRequestWithPayloadHash signs an HTTP request using AWS Signature V4
+// given the hex SHA-256 of its body, for bodies that are streamed rather
+// than held in memory
+func (s *AWSSigner) SignRequestWithPayloadHash(req *http.Request, hash string) error {
+	provider := s.credentials
+	if provider == nil {
+		// Load AWS config with default credential chain (supports IRSA, EC2 instance profile, env vars)
+		cfg, err := config.LoadDefaultConfig(context.TODO())
+		if err != nil {
+			log.Printf("Unable to load AWS config: %v", err)
+			return fmt.Errorf("unable to load AWS config: %w", err)
+		}
+		provider = cfg.Credentials
 	}
 
-	credentials, err := cfg.Credentials.Retrieve(context.TODO())
+	credentials, err := provider.Retrieve(context.TODO())
 	if err != nil {
 		log.Printf("Unable to retrieve AWS credentials: %v", err)
 		return fmt.Errorf("unable to retrieve AWS credentials: %w", err)
@@ -49,12 +75,17 @@ func (s *AWSSigner) SignRequest(req *http.Request, body []byte) error {
 	req.Header.Del("X-Amz-Content-Sha256")
 	req.Header.Del("X-Amz-Date")
 
-	// Calculate payload hash
-	payloadHash := sha256.Sum256(body)
-	hash := hex.EncodeToString(payloadHash[:])
+	// S3 requires the payload hash header and signs the path as sent
+	var optFns []func(*v4.SignerOptions)
+	if signsLikeS3(s.service) {
+		req.Header.Set("X-Amz-Content-Sha256", hash)
+		optFns = append(optFns, func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+	} else if hash == UnsignedPayload {
+		req.Header.Set("X-Amz-Content-Sha256", hash)
+	}
 
 	// Use AWS SDK v4 signer
-	signer := v4.NewSigner()
+	signer := v4.NewSigner(optFns...)
 	err = signer.SignHTTP(context.TODO(), credentials, req, hash, s.service, s.region, time.Now().UTC())
 	if err != nil {
 		log.Printf("Unable to sign request: %v", err)
@@ -63,3 +94,8 @@ func (s *AWSSigner) SignRequest(req *http.Request, body []byte) error {
 
 	return nil
 }
+
+// signsLikeS3 reports whether a service uses S3-style signing
+func signsLikeS3(service string) bool {
+	return service == "s3" || service == "s3-object-lambda" || service == "s3express"
+}
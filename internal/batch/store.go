@@ -0,0 +1,104 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package batch tracks asynchronous batch inference jobs submitted through the proxy.
+package batch
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// ErrJobNotFound is returned when a job ID is unknown to the store
+var ErrJobNotFound = errors.New("batch job not found")
+
+// Job is the proxy's record of a batch job
+type Job struct {
+	// Proxy-assigned batch ID (batch_...)
+	ID string
+
+	// Provider the job was submitted to
+	Provider string
+
+	// Model requested by the client, and the provider model it mapped to
+	Model         string
+	ProviderModel string
+
+	Endpoint         string
+	CompletionWindow string
+	Metadata         map[string]string
+
+	CreatedAt    time.Time
+	InProgressAt time.Time
+
+	// Latest state reported by the provider
+	State providers.BatchJob
+}
+
+// Store persists batch job metadata
+type Store interface {
+	// Create stores a new job
+	Create(ctx context.Context, job *Job) error
+
+	// Get returns a job by ID, or ErrJobNotFound
+	Get(ctx context.Context, id string) (*Job, error)
+
+	// Update replaces an existing job
+	Update(ctx context.Context, job *Job) error
+}
+
+// MemoryStore is an in-memory Store. Jobs are lost on restart.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	jobs map[string]*Job
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Create stores a new job
+func (s *MemoryStore) Create(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.jobs[job.ID]; exists {
+		return errors.New("batch job already exists: " + job.ID)
+	}
+	copied := *job
+	s.jobs[job.ID] = &copied
+	return nil
+}
+
+// Get returns a copy of the job with the given ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// Update replaces an existing job
+func (s *MemoryStore) Update(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; !ok {
+		return ErrJobNotFound
+	}
+	copied := *job
+	s.jobs[job.ID] = &copied
+	return nil
+}
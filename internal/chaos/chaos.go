@@ -0,0 +1,289 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package chaos injects faults into the requests to provider instances, so
+// circuit breakers, retries and fallbacks can be exercised before a real
+// outage does it. Faults are set at runtime through the admin API and
+// expire after their TTL.
+package chaos
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// DefaultTTL is how long a fault lasts when it sets no TTL
+const DefaultTTL = 5 * time.Minute
+
+// MaxTTL is the longest a fault may last, so a forgotten fault cannot
+// outlive the test it was set for by much
+const MaxTTL = time.Hour
+
+// Enabled reports whether fault injection is enabled (CHAOS_ENABLED=true)
+func Enabled() bool {
+	return os.Getenv("CHAOS_ENABLED") == "true"
+}
+
+// Fault kinds, the values of the fault label of
+// gateway_injected_faults_total
+const (
+	KindError   = "error"
+	KindLatency = "latency"
+	KindDrop    = "drop"
+	KindRefuse  = "refuse"
+)
+
+// FaultSpec is a fault to inject into the requests to one instance.
+// Latency is added to every request; the error status, dropped stream or
+// refused connection happen with the given probability.
+type FaultSpec struct {
+	Instance string `json:"instance"`
+
+	// Probability is the chance a request fails (default 1)
+	Probability *float64 `json:"probability,omitempty"`
+
+	// ErrorStatus is returned instead of the provider's response, such as
+	// 500 or 429
+	ErrorStatus int `json:"error_status,omitempty"`
+
+	// Latency is added before each request is sent ("200ms")
+	Latency string `json:"latency,omitempty"`
+
+	// DropAfterBytes cuts the response off after this many bytes
+	DropAfterBytes int64 `json:"drop_after_bytes,omitempty"`
+
+	// RefuseConnections fails requests as if the provider refused the
+	// connection
+	RefuseConnections bool `json:"refuse_connections,omitempty"`
+
+	// TTL is how long the fault lasts (default DefaultTTL, at most MaxTTL)
+	TTL string `json:"ttl,omitempty"`
+}
+
+// FaultStatus is an active fault, as reported by GET /admin/faults
+type FaultStatus struct {
+	FaultSpec
+	ExpiresAt time.Time `json:"expires_at"`
+	Injected  int64     `json:"injected"` // Requests faulted so far
+}
+
+// fault is an active fault with its durations parsed
+type fault struct {
+	spec        FaultSpec
+	probability float64
+	latency     time.Duration
+	expiresAt   time.Time
+	injected    atomic.Int64
+}
+
+// parse validates a fault spec
+func parse(spec FaultSpec) (*fault, error) {
+	if spec.Instance == "" {
+		return nil, errors.New("instance is required")
+	}
+	f := &fault{spec: spec, probability: 1}
+	if spec.Probability != nil {
+		if *spec.Probability < 0 || *spec.Probability > 1 {
+			return nil, fmt.Errorf("probability must be between 0 and 1, got %v", *spec.Probability)
+		}
+		f.probability = *spec.Probability
+	}
+	if spec.ErrorStatus != 0 && (spec.ErrorStatus < 400 || spec.ErrorStatus > 599) {
+		return nil, fmt.Errorf("error_status must be a 4xx or 5xx status, got %d", spec.ErrorStatus)
+	}
+	if spec.DropAfterBytes < 0 {
+		return nil, fmt.Errorf("drop_after_bytes must not be negative, got %d", spec.DropAfterBytes)
+	}
+	if spec.Latency != "" {
+		latency, err := time.ParseDuration(spec.Latency)
+		if err != nil || latency < 0 {
+			return nil, fmt.Errorf("invalid latency %q", spec.Latency)
+		}
+		f.latency = latency
+	}
+	failures := 0
+	for _, set := range []bool{spec.ErrorStatus != 0, spec.DropAfterBytes > 0, spec.RefuseConnections} {
+		if set {
+			failures++
+		}
+	}
+	if failures > 1 {
+		return nil, errors.New("error_status, drop_after_bytes and refuse_connections are exclusive")
+	}
+	if failures == 0 && f.latency == 0 {
+		return nil, errors.New("a fault needs error_status, latency, drop_after_bytes or refuse_connections")
+	}
+
+	ttl := DefaultTTL
+	if spec.TTL != "" {
+		var err error
+		if ttl, err = time.ParseDuration(spec.TTL); err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("invalid ttl %q", spec.TTL)
+		}
+		if ttl > MaxTTL {
+			return nil, fmt.Errorf("ttl %s is longer than the maximum of %s", ttl, MaxTTL)
+		}
+	}
+	f.spec.TTL = ttl.String()
+	f.expiresAt = time.Now().Add(ttl)
+	return f, nil
+}
+
+// Injector holds the active faults, one per instance. A nil Injector
+// injects nothing.
+type Injector struct {
+	mu     sync.Mutex
+	faults map[string]*fault
+
+	// random returns the draw compared with a fault's probability
+	random func() float64
+}
+
+// NewInjector creates an injector with no active faults
+func NewInjector() *Injector {
+	return &Injector{
+		faults: make(map[string]*fault),
+		random: rand.Float64,
+	}
+}
+
+// Set replaces the fault of an instance, returning its status
+func (i *Injector) Set(spec FaultSpec) (FaultStatus, error) {
+	f, err := parse(spec)
+	if err != nil {
+		return FaultStatus{}, err
+	}
+	i.mu.Lock()
+	i.faults[spec.Instance] = f
+	i.mu.Unlock()
+	log.Printf("⚠️  Chaos: fault injected into instance %s until %s: %s",
+		spec.Instance, f.expiresAt.Format(time.RFC3339), f.describe())
+	return f.status(), nil
+}
+
+// Clear removes the fault of an instance, reporting whether it had one
+func (i *Injector) Clear(instance string) bool {
+	i.mu.Lock()
+	_, ok := i.faults[instance]
+	delete(i.faults, instance)
+	i.mu.Unlock()
+	if ok {
+		log.Printf("Chaos: fault cleared from instance %s", instance)
+	}
+	return ok
+}
+
+// Faults returns the active faults, sorted by instance
+func (i *Injector) Faults() []FaultStatus {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	statuses := []FaultStatus{}
+	for name := range i.faults {
+		if f := i.lookupLocked(name); f != nil {
+			statuses = append(statuses, f.status())
+		}
+	}
+	sort.Slice(statuses, func(a, b int) bool { return statuses[a].Instance < statuses[b].Instance })
+	return statuses
+}
+
+// Active reports whether an instance has an unexpired fault
+func (i *Injector) Active(instance string) bool {
+	return i.lookup(instance) != nil
+}
+
+// lookup returns the unexpired fault of an instance
+func (i *Injector) lookup(instance string) *fault {
+	if i == nil {
+		return nil
+	}
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.lookupLocked(instance)
+}
+
+// lookupLocked returns the unexpired fault of an instance, removing it
+// if it has expired. i.mu must be held.
+func (i *Injector) lookupLocked(instance string) *fault {
+	f, ok := i.faults[instance]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(f.expiresAt) {
+		delete(i.faults, instance)
+		log.Printf("Chaos: fault on instance %s expired after injecting %d", instance, f.injected.Load())
+		return nil
+	}
+	return f
+}
+
+// fails reports whether a request should fail, drawing against the
+// fault's probability
+func (i *Injector) fails(f *fault) bool {
+	return f.probability >= 1 || i.random() < f.probability
+}
+
+// status returns the reported state of a fault
+func (f *fault) status() FaultStatus {
+	return FaultStatus{FaultSpec: f.spec, ExpiresAt: f.expiresAt, Injected: f.injected.Load()}
+}
+
+// describe summarises a fault for the log
+func (f *fault) describe() string {
+	var failure string
+	switch {
+	case f.spec.ErrorStatus != 0:
+		failure = fmt.Sprintf("status %d", f.spec.ErrorStatus)
+	case f.spec.DropAfterBytes > 0:
+		failure = fmt.Sprintf("drop after %d bytes", f.spec.DropAfterBytes)
+	case f.spec.RefuseConnections:
+		failure = "refuse connections"
+	}
+	if failure != "" {
+		failure = fmt.Sprintf("%s with probability %g", failure, f.probability)
+	}
+	if f.latency > 0 {
+		if failure != "" {
+			failure += ", "
+		}
+		failure += fmt.Sprintf("%s latency", f.latency)
+	}
+	return failure
+}
+
+// record counts and logs an injected fault
+func (f *fault) record(kind string) {
+	f.injected.Add(1)
+	metrics.InjectedFaults.WithLabelValues(f.spec.Instance, kind).Inc()
+	if kind != KindLatency {
+		log.Printf("Chaos: injected %s into instance %s", kind, f.spec.Instance)
+	}
+}
+
+// errorCode returns the provider error code of an injected status
+func errorCode(status int) string {
+	switch {
+	case status == http.StatusTooManyRequests:
+		return providers.ErrCodeRateLimitExceeded
+	case status == http.StatusUnauthorized || status == http.StatusForbidden:
+		return providers.ErrCodeAuthenticationFail
+	case status == http.StatusNotFound:
+		return providers.ErrCodeModelNotFound
+	case status < 500:
+		return providers.ErrCodeInvalidRequest
+	case status == http.StatusInternalServerError:
+		return providers.ErrCodeInternalError
+	}
+	return providers.ErrCodeServiceUnavailable
+}
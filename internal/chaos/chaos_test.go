@@ -0,0 +1,175 @@
+package chaos
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// stubProvider answers every request with a fixed body
+type stubProvider struct {
+	body  string
+	calls int
+}
+
+func (p *stubProvider) Name() string                          { return "stub" }
+func (p *stubProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (p *stubProvider) Invoke(ctx context.Context, req *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	p.calls++
+	return &providers.ProviderResponse{StatusCode: http.StatusOK, Body: []byte(p.body)}, nil
+}
+
+func (p *stubProvider) InvokeStreaming(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+	p.calls++
+	return io.NopCloser(bytes.NewReader([]byte(p.body))), nil
+}
+
+func (p *stubProvider) ListModels(ctx context.Context) ([]providers.Model, error) { return nil, nil }
+
+func (p *stubProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return nil, errors.New("not found")
+}
+
+func probability(p float64) *float64 { return &p }
+
+func TestSetValidation(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    FaultSpec
+		wantErr bool
+	}{
+		{"error status", FaultSpec{Instance: "a", ErrorStatus: 429, Probability: probability(0.5)}, false},
+		{"latency only", FaultSpec{Instance: "a", Latency: "200ms", TTL: "1m"}, false},
+		{"drop", FaultSpec{Instance: "a", DropAfterBytes: 100}, false},
+		{"refuse", FaultSpec{Instance: "a", RefuseConnections: true}, false},
+		{"no instance", FaultSpec{ErrorStatus: 500}, true},
+		{"no fault", FaultSpec{Instance: "a"}, true},
+		{"success status", FaultSpec{Instance: "a", ErrorStatus: 200}, true},
+		{"probability above 1", FaultSpec{Instance: "a", ErrorStatus: 500, Probability: probability(1.5)}, true},
+		{"two failures", FaultSpec{Instance: "a", ErrorStatus: 500, RefuseConnections: true}, true},
+		{"invalid latency", FaultSpec{Instance: "a", Latency: "soon"}, true},
+		{"ttl too long", FaultSpec{Instance: "a", ErrorStatus: 500, TTL: "24h"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewInjector().Set(tt.spec); (err != nil) != tt.wantErr {
+				t.Errorf("Set() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestErrorFault tests that injected errors follow the probability, are
+// counted per instance, and stop when the fault is cleared
+func TestErrorFault(t *testing.T) {
+	injector := NewInjector()
+	draws := []float64{0.1, 0.9}
+	injector.random = func() float64 {
+		draw := draws[0]
+		draws = append(draws[1:], draw)
+		return draw
+	}
+	stub := &stubProvider{body: "{}"}
+	if injector.Wrap("search", stub) != stub {
+		t.Fatal("provider wrapped without a fault")
+	}
+	if _, err := injector.Set(FaultSpec{Instance: "search", ErrorStatus: 429, Probability: probability(0.5)}); err != nil {
+		t.Fatal(err)
+	}
+	counter := metrics.InjectedFaults.WithLabelValues("search", KindError)
+	before := testutil.ToFloat64(counter)
+
+	provider := injector.Wrap("search", stub)
+	_, err := provider.Invoke(context.Background(), &providers.ProviderRequest{})
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != 429 || providerErr.Code != providers.ErrCodeRateLimitExceeded {
+		t.Errorf("first request error = %v, want an injected 429", err)
+	}
+	if _, err := provider.Invoke(context.Background(), &providers.ProviderRequest{}); err != nil {
+		t.Errorf("second request error = %v, want it to pass", err)
+	}
+	if stub.calls != 1 {
+		t.Errorf("provider called %d times, want 1", stub.calls)
+	}
+	if got := testutil.ToFloat64(counter) - before; got != 1 {
+		t.Errorf("injected errors counted = %v, want 1", got)
+	}
+	if faults := injector.Faults(); len(faults) != 1 || faults[0].Injected != 1 || faults[0].TTL != DefaultTTL.String() {
+		t.Errorf("Faults() = %+v", faults)
+	}
+
+	injector.Clear("search")
+	if _, err := provider.Invoke(context.Background(), &providers.ProviderRequest{}); err != nil {
+		t.Errorf("request after clear error = %v", err)
+	}
+}
+
+func TestFaultExpires(t *testing.T) {
+	injector := NewInjector()
+	if _, err := injector.Set(FaultSpec{Instance: "search", RefuseConnections: true, TTL: "1ms"}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if injector.Active("search") || len(injector.Faults()) != 0 {
+		t.Error("expired fault is still active")
+	}
+}
+
+func TestRefuseAndLatency(t *testing.T) {
+	injector := NewInjector()
+	injector.Set(FaultSpec{Instance: "search", RefuseConnections: true, Latency: "20ms"})
+	stub := &stubProvider{body: "{}"}
+
+	start := time.Now()
+	_, err := injector.Wrap("search", stub).Invoke(context.Background(), &providers.ProviderRequest{})
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusServiceUnavailable || stub.calls != 0 {
+		t.Errorf("error = %v, calls %d, want a refused connection", err, stub.calls)
+	}
+	if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+		t.Errorf("request took %s, want the injected latency", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	injector.Set(FaultSpec{Instance: "search", Latency: "1m"})
+	if _, err := injector.Wrap("search", stub).Invoke(ctx, &providers.ProviderRequest{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error = %v, want the context's", err)
+	}
+}
+
+func TestDropFault(t *testing.T) {
+	injector := NewInjector()
+	injector.Set(FaultSpec{Instance: "search", DropAfterBytes: 5})
+	provider := injector.Wrap("search", &stubProvider{body: "data: hello world"})
+
+	stream, err := provider.InvokeStreaming(context.Background(), &providers.ProviderRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(stream)
+	if string(data) != "data:" || !errors.Is(err, ErrDropped) {
+		t.Errorf("read %q, %v; want 5 bytes then ErrDropped", data, err)
+	}
+
+	resp, err := providers.InvokeStream(context.Background(), provider, &providers.ProviderRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, err := io.ReadAll(resp.Body); len(data) != 5 || !errors.Is(err, ErrDropped) {
+		t.Errorf("InvokeStream read %q, %v", data, err)
+	}
+
+	if _, err := provider.Invoke(context.Background(), &providers.ProviderRequest{}); !errors.Is(err, ErrDropped) {
+		t.Errorf("Invoke error = %v, want ErrDropped", err)
+	}
+}
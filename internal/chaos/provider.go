@@ -0,0 +1,162 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// ErrDropped ends a response cut off by a drop_after_bytes fault
+var ErrDropped = errors.New("connection dropped by injected fault")
+
+// Wrap returns the provider serving an instance with the instance's fault
+// applied, or provider itself when the instance has no fault. Handlers wrap
+// per request, so optional interfaces are only hidden while a fault is
+// active.
+func (i *Injector) Wrap(instance string, provider providers.Provider) providers.Provider {
+	if i.lookup(instance) == nil {
+		return provider
+	}
+	return &faultyProvider{Provider: provider, injector: i, instance: instance}
+}
+
+// faultyProvider injects an instance's fault into the requests to its
+// provider. The fault is looked up on every request, so requests stop
+// failing as soon as it is cleared or expires.
+type faultyProvider struct {
+	providers.Provider
+	injector *Injector
+	instance string
+}
+
+// Capabilities reports the capabilities of the wrapped provider
+func (p *faultyProvider) Capabilities() providers.Capabilities {
+	return providers.CapabilitiesOf(p.Provider)
+}
+
+// Invoke injects the fault into a buffered request
+func (p *faultyProvider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	f, drop, err := p.before(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.Provider.Invoke(ctx, request)
+	if err != nil || !drop || int64(len(resp.Body)) <= f.spec.DropAfterBytes {
+		return resp, err
+	}
+	f.record(KindDrop)
+	return nil, &providers.ProviderError{
+		Provider:   p.Name(),
+		StatusCode: http.StatusInternalServerError,
+		Code:       providers.ErrCodeInternalError,
+		Message:    fmt.Sprintf("failed to read response: %v after %d bytes", ErrDropped, f.spec.DropAfterBytes),
+		Err:        ErrDropped,
+	}
+}
+
+// InvokeStreaming injects the fault into a streaming request
+func (p *faultyProvider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
+	f, drop, err := p.before(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := p.Provider.InvokeStreaming(ctx, request)
+	if err != nil || !drop {
+		return stream, err
+	}
+	dropped := &dropReader{ReadCloser: stream, remaining: f.spec.DropAfterBytes, fault: f}
+	if headers, ok := stream.(providers.StreamHeaders); ok {
+		return providers.NewHeaderStream(dropped, headers.ResponseHeaders()), nil
+	}
+	return dropped, nil
+}
+
+// InvokeStream injects the fault into a request whose bodies are streamed
+func (p *faultyProvider) InvokeStream(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderStreamResponse, error) {
+	f, drop, err := p.before(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := providers.InvokeStream(ctx, p.Provider, request)
+	if err != nil || !drop {
+		return resp, err
+	}
+	resp.Body = &dropReader{ReadCloser: resp.Body, remaining: f.spec.DropAfterBytes, fault: f}
+	return resp, nil
+}
+
+// before applies the fault ahead of a request: it waits out the latency,
+// then returns the injected error, if the request fails with one, and
+// whether its response should be dropped
+func (p *faultyProvider) before(ctx context.Context) (*fault, bool, error) {
+	f := p.injector.lookup(p.instance)
+	if f == nil {
+		return nil, false, nil
+	}
+
+	if f.latency > 0 {
+		f.record(KindLatency)
+		timer := time.NewTimer(f.latency)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, false, ctx.Err()
+		}
+	}
+
+	if !p.injector.fails(f) {
+		return f, false, nil
+	}
+	switch {
+	case f.spec.RefuseConnections:
+		f.record(KindRefuse)
+		return nil, false, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusServiceUnavailable,
+			Code:       providers.ErrCodeServiceUnavailable,
+			Message:    "request failed: connection refused by injected fault",
+		}
+	case f.spec.ErrorStatus != 0:
+		f.record(KindError)
+		return nil, false, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: f.spec.ErrorStatus,
+			Code:       errorCode(f.spec.ErrorStatus),
+			Message:    fmt.Sprintf("injected fault: status %d", f.spec.ErrorStatus),
+		}
+	}
+	return f, f.spec.DropAfterBytes > 0, nil
+}
+
+// dropReader ends a response with ErrDropped after a number of bytes
+type dropReader struct {
+	io.ReadCloser
+	remaining int64
+	fault     *fault
+	dropped   bool
+}
+
+func (r *dropReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		if !r.dropped {
+			r.dropped = true
+			r.fault.record(KindDrop)
+		}
+		return 0, ErrDropped
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.ReadCloser.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
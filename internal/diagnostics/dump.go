@@ -0,0 +1,191 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package diagnostics
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// maxStreams bounds the number of in-flight streams listed in a dump
+	maxStreams = 50
+
+	// maxSectionBytes bounds the encoded size of each registered section
+	maxSectionBytes = 8 << 10
+)
+
+// Section returns a point-in-time view of one component (limiters, queues,
+// caches, provider health, ...). Sections are called while dumping and must
+// not block: use atomics or brief read locks.
+type Section func() interface{}
+
+// State is a runtime state dump
+type State struct {
+	Time         time.Time                  `json:"time"`
+	Uptime       string                     `json:"uptime"`
+	Goroutines   int                        `json:"goroutines"`
+	Active       map[string]int64           `json:"active_requests"`
+	Streams      []StreamInfo               `json:"streams"`
+	StreamsTotal int                        `json:"streams_total"`
+	ConfigHashes map[string]string          `json:"config_hashes,omitempty"`
+	Sections     map[string]json.RawMessage `json:"sections,omitempty"`
+}
+
+// Dumper collects runtime state from the request tracker and registered sections
+type Dumper struct {
+	tracker *Tracker
+	started time.Time
+
+	mu           sync.RWMutex
+	sections     map[string]Section
+	configHashes map[string]string
+}
+
+// NewDumper creates a new state dumper
+func NewDumper(tracker *Tracker) *Dumper {
+	return &Dumper{
+		tracker:      tracker,
+		started:      time.Now(),
+		sections:     make(map[string]Section),
+		configHashes: make(map[string]string),
+	}
+}
+
+// Register adds a named section to the dump
+func (d *Dumper) Register(name string, section Section) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sections[name] = section
+}
+
+// AddConfigFile records the SHA-256 of a loaded config file
+func (d *Dumper) AddConfigFile(name, path string) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.configHashes[name] = hex.EncodeToString(sum[:])
+}
+
+// Snapshot collects the current runtime state
+func (d *Dumper) Snapshot() *State {
+	now := time.Now()
+	streams, total := d.tracker.Streams(now, maxStreams)
+
+	d.mu.RLock()
+	sections := make(map[string]Section, len(d.sections))
+	for name, section := range d.sections {
+		sections[name] = section
+	}
+	hashes := make(map[string]string, len(d.configHashes))
+	for name, hash := range d.configHashes {
+		hashes[name] = hash
+	}
+	d.mu.RUnlock()
+
+	state := &State{
+		Time:         now,
+		Uptime:       now.Sub(d.started).Round(time.Second).String(),
+		Goroutines:   runtime.NumGoroutine(),
+		Active:       d.tracker.ActiveByGroup(),
+		Streams:      streams,
+		StreamsTotal: total,
+		ConfigHashes: hashes,
+		Sections:     make(map[string]json.RawMessage, len(sections)),
+	}
+	for name, section := range sections {
+		state.Sections[name] = encodeSection(section())
+	}
+	return state
+}
+
+// Log writes a snapshot to the log as a single JSON line
+func (d *Dumper) Log(reason string) {
+	data, err := json.Marshal(d.Snapshot())
+	if err != nil {
+		log.Printf("State dump (%s) failed: %v", reason, err)
+		return
+	}
+	log.Printf("State dump (%s): %s", reason, data)
+}
+
+// Handler serves GET /admin/state: the snapshot is logged and returned
+func (d *Dumper) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		state := d.Snapshot()
+		if data, err := json.Marshal(state); err == nil {
+			log.Printf("State dump (%s): %s", c.Request.URL.Path, data)
+		}
+		c.JSON(http.StatusOK, state)
+	}
+}
+
+// encodeSection redacts secrets and bounds the encoded size of a section
+func encodeSection(value interface{}) json.RawMessage {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return json.RawMessage(fmt.Sprintf(`{"error":%q}`, err.Error()))
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err == nil {
+		if redacted, err := json.Marshal(redact(generic)); err == nil {
+			data = redacted
+		}
+	}
+
+	if len(data) > maxSectionBytes {
+		return json.RawMessage(fmt.Sprintf(`{"truncated":true,"bytes":%d}`, len(data)))
+	}
+	return data
+}
+
+var (
+	secretKeyPattern   = regexp.MustCompile(`(?i)(^|[_-])(secret|password|passwd|token|api[_-]?key|apikey|authorization|credentials?|private[_-]?key)$`)
+	secretValuePattern = regexp.MustCompile(`(?i)^(bearer|basic)\s+\S+|^(sk|xoxb|AKIA|ASIA)[-_A-Za-z0-9]{8,}`)
+)
+
+// redact replaces values under secret-looking keys, and secret-looking strings
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, inner := range v {
+			if secretKeyPattern.MatchString(key) {
+				v[key] = "[REDACTED]"
+			} else {
+				v[key] = redact(inner)
+			}
+		}
+		return v
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+		return v
+	case string:
+		if secretValuePattern.MatchString(strings.TrimSpace(v)) {
+			return "[REDACTED]"
+		}
+		return v
+	default:
+		return v
+	}
+}
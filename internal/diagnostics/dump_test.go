@@ -0,0 +1,101 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestSnapshotTracksActiveRequestsAndStreams(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tracker := NewTracker()
+	dumper := NewDumper(tracker)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+
+	engine := gin.New()
+	engine.Use(tracker.Middleware())
+	engine.POST("/v1/chat/completions", func(c *gin.Context) {
+		MarkStreaming(c.Request.Context())
+		entered <- struct{}{}
+		<-release
+		c.Status(http.StatusOK)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+		close(done)
+	}()
+	<-entered
+
+	state := dumper.Snapshot()
+	if state.Active["v1"] != 1 {
+		t.Errorf("active requests: got %v, want v1=1", state.Active)
+	}
+	if state.StreamsTotal != 1 || len(state.Streams) != 1 || state.Streams[0].Path != "/v1/chat/completions" {
+		t.Errorf("unexpected streams: %+v", state.Streams)
+	}
+	if state.Goroutines == 0 {
+		t.Error("goroutine count missing")
+	}
+
+	close(release)
+	<-done
+
+	state = dumper.Snapshot()
+	if len(state.Active) != 0 || state.StreamsTotal != 0 {
+		t.Errorf("completed request still tracked: %+v %+v", state.Active, state.Streams)
+	}
+}
+
+func TestSectionsAreRedactedAndBounded(t *testing.T) {
+	dumper := NewDumper(NewTracker())
+	dumper.Register("provider", func() interface{} {
+		return map[string]interface{}{
+			"api_key":       "sk-live-1234567890",
+			"prompt_tokens": 12,
+			"headers":       map[string]string{"X-Forwarded": "Bearer abcdef"},
+			"region":        "us-east-1",
+		}
+	})
+	dumper.Register("huge", func() interface{} { return strings.Repeat("x", maxSectionBytes+1) })
+
+	state := dumper.Snapshot()
+	provider := string(state.Sections["provider"])
+	if strings.Contains(provider, "sk-live") || strings.Contains(provider, "abcdef") {
+		t.Errorf("secret leaked: %s", provider)
+	}
+	if !strings.Contains(provider, `"prompt_tokens":12`) || !strings.Contains(provider, "us-east-1") {
+		t.Errorf("non-secret values redacted: %s", provider)
+	}
+
+	var huge map[string]interface{}
+	if err := json.Unmarshal(state.Sections["huge"], &huge); err != nil || huge["truncated"] != true {
+		t.Errorf("oversized section not truncated: %s", state.Sections["huge"])
+	}
+}
+
+func TestStreamsAreBounded(t *testing.T) {
+	tracker := NewTracker()
+	start := time.Now()
+	for i := 0; i < maxStreams+10; i++ {
+		req := &Request{Group: "v1", Path: "/v1/chat/completions", Start: start.Add(time.Duration(i) * time.Millisecond)}
+		req.streaming.Store(true)
+		tracker.inflight.Store(uint64(i), req)
+	}
+
+	streams, total := tracker.Streams(start.Add(time.Second), maxStreams)
+	if total != maxStreams+10 || len(streams) != maxStreams {
+		t.Errorf("got %d of %d streams", len(streams), total)
+	}
+	if streams[0].Age != "1s" {
+		t.Errorf("streams should be oldest first, got age %s", streams[0].Age)
+	}
+}
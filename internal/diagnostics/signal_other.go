@@ -0,0 +1,11 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix
+
+package diagnostics
+
+import "context"
+
+// DumpOnSignal is a no-op on platforms without SIGUSR1; use GET /admin/state
+func (d *Dumper) DumpOnSignal(ctx context.Context) {}
@@ -0,0 +1,32 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unix
+
+package diagnostics
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// DumpOnSignal logs a state dump each time the process receives SIGUSR1,
+// until ctx is cancelled. Dumps run on their own goroutine.
+func (d *Dumper) DumpOnSignal(ctx context.Context) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1)
+
+	go func() {
+		defer signal.Stop(signals)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+				d.Log("SIGUSR1")
+			}
+		}
+	}()
+}
@@ -0,0 +1,138 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package diagnostics
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Tracker records in-flight requests for runtime state dumps. It uses atomic
+// counters and a sync.Map so that recording never contends with a dump.
+type Tracker struct {
+	nextID   atomic.Uint64
+	groups   sync.Map // route group -> *atomic.Int64
+	inflight sync.Map // request ID -> *Request
+}
+
+// Request is an in-flight request
+type Request struct {
+	Group     string
+	Method    string
+	Path      string
+	Start     time.Time
+	streaming atomic.Bool
+}
+
+type requestKey struct{}
+
+// NewTracker creates a new request tracker
+func NewTracker() *Tracker {
+	return &Tracker{}
+}
+
+// Middleware tracks each request from arrival until its handler returns
+func (t *Tracker) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		req := &Request{
+			Group:  routeGroup(c.FullPath()),
+			Method: c.Request.Method,
+			Path:   c.Request.URL.Path,
+			Start:  time.Now(),
+		}
+
+		id := t.nextID.Add(1)
+		counter := t.groupCounter(req.Group)
+		counter.Add(1)
+		t.inflight.Store(id, req)
+		defer func() {
+			t.inflight.Delete(id)
+			counter.Add(-1)
+		}()
+
+		c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), requestKey{}, req))
+		c.Next()
+	}
+}
+
+// MarkStreaming flags the tracked request in ctx as a stream
+func MarkStreaming(ctx context.Context) {
+	if req, ok := ctx.Value(requestKey{}).(*Request); ok {
+		req.streaming.Store(true)
+	}
+}
+
+// ActiveByGroup returns the number of in-flight requests per route group
+func (t *Tracker) ActiveByGroup() map[string]int64 {
+	active := make(map[string]int64)
+	t.groups.Range(func(key, value interface{}) bool {
+		if n := value.(*atomic.Int64).Load(); n > 0 {
+			active[key.(string)] = n
+		}
+		return true
+	})
+	return active
+}
+
+// StreamInfo describes an in-flight stream
+type StreamInfo struct {
+	Group  string `json:"group"`
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Age    string `json:"age"`
+}
+
+// Streams returns up to limit in-flight streams, oldest first, and the total count
+func (t *Tracker) Streams(now time.Time, limit int) ([]StreamInfo, int) {
+	var streams []*Request
+	t.inflight.Range(func(_, value interface{}) bool {
+		if req := value.(*Request); req.streaming.Load() {
+			streams = append(streams, req)
+		}
+		return true
+	})
+	sort.Slice(streams, func(i, j int) bool { return streams[i].Start.Before(streams[j].Start) })
+
+	total := len(streams)
+	if len(streams) > limit {
+		streams = streams[:limit]
+	}
+
+	infos := make([]StreamInfo, 0, len(streams))
+	for _, req := range streams {
+		infos = append(infos, StreamInfo{
+			Group:  req.Group,
+			Method: req.Method,
+			Path:   req.Path,
+			Age:    now.Sub(req.Start).Round(time.Millisecond).String(),
+		})
+	}
+	return infos, total
+}
+
+func (t *Tracker) groupCounter(group string) *atomic.Int64 {
+	if counter, ok := t.groups.Load(group); ok {
+		return counter.(*atomic.Int64)
+	}
+	counter, _ := t.groups.LoadOrStore(group, new(atomic.Int64))
+	return counter.(*atomic.Int64)
+}
+
+// routeGroup returns the first segment of a route pattern, e.g. /v1/chat/completions → v1
+func routeGroup(fullPath string) string {
+	if fullPath == "" {
+		return "unmatched"
+	}
+	segment := strings.SplitN(strings.TrimPrefix(fullPath, "/"), "/", 2)[0]
+	if segment == "" || strings.HasPrefix(segment, "*") || strings.HasPrefix(segment, ":") {
+		return "root"
+	}
+	return segment
+}
@@ -0,0 +1,213 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/chaos"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// AdminHandler serves operator endpoints for managing providers at runtime
+type AdminHandler struct {
+	router *router.Router
+
+	// instances and instancesLocation are the provider instances config in
+	// effect and where it was loaded from, for feature flag endpoints
+	instances         *atomic.Pointer[instance.Config]
+	instancesLocation string
+
+	// notifier is told when a reload fails
+	notifier *notify.Notifier
+
+	// faults are the injected faults managed by the fault endpoints
+	faults *chaos.Injector
+}
+
+// NewAdminHandler creates a new admin handler
+func NewAdminHandler(r *router.Router) *AdminHandler {
+	return &AdminHandler{router: r}
+}
+
+// SetNotifier sends a config reload failure event to webhooks when a
+// reload fails
+func (h *AdminHandler) SetNotifier(notifier *notify.Notifier) {
+	h.notifier = notifier
+}
+
+// SetInstanceConfig enables the feature flag endpoints for the provider
+// instances config loaded from location. Reloads store a new config in
+// config, for the handlers sharing it to load on their next request.
+func (h *AdminHandler) SetInstanceConfig(config *atomic.Pointer[instance.Config], location string) {
+	h.instances = config
+	h.instancesLocation = location
+}
+
+// SetFaults enables the fault injection endpoints
+func (h *AdminHandler) SetFaults(faults *chaos.Injector) {
+	h.faults = faults
+}
+
+// ListProviders handles GET /admin/providers, reporting the last known
+// health and drain state of each enabled provider without health-checking
+func (h *AdminHandler) ListProviders(c *gin.Context) {
+	respondJSON(c, http.StatusOK, gin.H{
+		"providers": h.router.ProviderStates(),
+	})
+}
+
+// DrainProvider handles POST /admin/providers/:name/drain. New requests are
+// routed to fallback providers, or rejected with 503; requests in flight
+// complete normally.
+func (h *AdminHandler) DrainProvider(c *gin.Context) {
+	name := c.Param("name")
+	if !h.router.Drain(name) {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "provider_not_found",
+			fmt.Sprintf("Provider %q is not registered", name))
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"name": name, "draining": true})
+}
+
+// RestoreProvider handles POST /admin/providers/:name/restore, returning a
+// drained provider to routing
+func (h *AdminHandler) RestoreProvider(c *gin.Context) {
+	name := c.Param("name")
+	if !h.router.Restore(name) {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "provider_not_found",
+			fmt.Sprintf("Provider %q is not registered", name))
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{"name": name, "draining": false})
+}
+
+// ProviderHealth handles GET /health/:provider, health-checking a single
+// provider. Draining providers report 503 so load balancers stop sending
+// them traffic, even when healthy.
+func (h *AdminHandler) ProviderHealth(c *gin.Context) {
+	status, ok := h.router.CheckProvider(c.Request.Context(), c.Param("provider"))
+	if !ok {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "provider_not_found",
+			fmt.Sprintf("Provider %q is not enabled", c.Param("provider")))
+		return
+	}
+
+	code := http.StatusOK
+	if !status.Healthy || status.Draining {
+		code = http.StatusServiceUnavailable
+	}
+	respondJSON(c, code, status)
+}
+
+// InstanceFeatures handles GET /admin/instances/:name/features, reporting
+// the feature flags in effect for an instance. The key query parameter
+// applies that API key's overrides too.
+func (h *AdminHandler) InstanceFeatures(c *gin.Context) {
+	config := h.instanceConfig()
+	if config == nil {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "instances_not_configured",
+			"No provider instances are configured")
+		return
+	}
+	name := c.Param("name")
+	if _, err := config.GetInstanceByName(name); err != nil {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "instance_not_found",
+			fmt.Sprintf("Instance %q is not configured", name))
+		return
+	}
+	respondJSON(c, http.StatusOK, gin.H{
+		"instance": name,
+		"key":      c.Query("key"),
+		"features": config.EffectiveFeatures(name, c.Query("key")),
+	})
+}
+
+// ReloadFeatures handles POST /admin/features/reload. The instances config
+// is re-read and a copy of the config in effect with its feature flags
+// replaces it; the rest of the config is unchanged until restart.
+func (h *AdminHandler) ReloadFeatures(c *gin.Context) {
+	config := h.instanceConfig()
+	if config == nil {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "instances_not_configured",
+			"No provider instances are configured")
+		return
+	}
+	loaded, err := instance.LoadConfig(h.instancesLocation)
+	if err == nil {
+		config, err = config.WithFeatures(loaded.Features)
+	}
+	if err != nil {
+		log.Printf("Feature flag reload failed: %v", err)
+		h.notifier.Notify(notify.Event{
+			Type:    notify.EventConfigReloadFailed,
+			Subject: h.instancesLocation,
+			Message: fmt.Sprintf("feature flag reload failed: %v", err),
+		})
+		respondError(c, http.StatusUnprocessableEntity, "invalid_request_error", "invalid_config",
+			fmt.Sprintf("Failed to reload feature flags: %v", err))
+		return
+	}
+	h.instances.Store(config)
+	log.Printf("Feature flags reloaded from %s", h.instancesLocation)
+	respondJSON(c, http.StatusOK, gin.H{
+		"features": config.EffectiveFeatures("", ""),
+	})
+}
+
+// instanceConfig returns the provider instances config in effect, or nil
+func (h *AdminHandler) instanceConfig() *instance.Config {
+	if h.instances == nil {
+		return nil
+	}
+	return h.instances.Load()
+}
+
+// ListFaults handles GET /admin/faults, reporting the active injected
+// faults and how many requests each has faulted
+func (h *AdminHandler) ListFaults(c *gin.Context) {
+	respondJSON(c, http.StatusOK, gin.H{"faults": h.faults.Faults()})
+}
+
+// SetFault handles POST /admin/faults, injecting a fault into the requests
+// to an instance until its TTL expires. It replaces the instance's
+// previous fault.
+func (h *AdminHandler) SetFault(c *gin.Context) {
+	var spec chaos.FaultSpec
+	if err := c.ShouldBindJSON(&spec); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_json", "Invalid request body")
+		return
+	}
+	if config := h.instanceConfig(); config != nil && spec.Instance != "" {
+		if _, err := config.GetInstanceByName(spec.Instance); err != nil {
+			respondError(c, http.StatusNotFound, "invalid_request_error", "instance_not_found",
+				fmt.Sprintf("Instance %q is not configured", spec.Instance))
+			return
+		}
+	}
+	status, err := h.faults.Set(spec)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_fault", err.Error())
+		return
+	}
+	respondJSON(c, http.StatusCreated, status)
+}
+
+// ClearFault handles DELETE /admin/faults/:instance, ending an instance's
+// fault before its TTL
+func (h *AdminHandler) ClearFault(c *gin.Context) {
+	name := c.Param("instance")
+	if !h.faults.Clear(name) {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "fault_not_found",
+			fmt.Sprintf("Instance %q has no active fault", name))
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
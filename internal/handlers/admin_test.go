@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/chaos"
+	"github.com/tosharewith/llmproxy_auth/internal/health"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+func TestDrainProvider(t *testing.T) {
+	chat, stubs := newChatTestHandler(t)
+	h := NewAdminHandler(chat.router)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/health/:provider", h.ProviderHealth)
+	engine.GET("/admin/providers", h.ListProviders)
+	engine.POST("/admin/providers/:name/drain", h.DrainProvider)
+	engine.POST("/admin/providers/:name/restore", h.RestoreProvider)
+
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/admin/providers/openai/drain"); w.Code != http.StatusOK {
+		t.Fatalf("drain status = %d, body %s", w.Code, w.Body)
+	}
+	if w := serve(http.MethodPost, "/admin/providers/unknown/drain"); w.Code != http.StatusNotFound {
+		t.Errorf("drain unknown status = %d, want 404", w.Code)
+	}
+
+	// No fallback is configured, so new requests are rejected
+	w := postChat(chat, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("chat status = %d, want 503; body %s", w.Code, w.Body)
+	}
+	if stubs["openai"].calls.Load() != 0 {
+		t.Error("draining provider was invoked")
+	}
+
+	w = serve(http.MethodGet, "/health/openai")
+	var status router.ProviderHealth
+	if err := json.Unmarshal(w.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if w.Code != http.StatusServiceUnavailable || !status.Healthy || !status.Draining {
+		t.Errorf("health = %d %+v, want 503 healthy and draining", w.Code, status)
+	}
+
+	w = serve(http.MethodGet, "/admin/providers")
+	var list struct {
+		Providers []router.ProviderHealth `json:"providers"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	for _, p := range list.Providers {
+		if p.Draining != (p.Name == "openai") {
+			t.Errorf("%s draining = %v", p.Name, p.Draining)
+		}
+	}
+
+	serve(http.MethodPost, "/admin/providers/openai/restore")
+	if w := postChat(chat, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`); w.Code != http.StatusOK {
+		t.Errorf("chat after restore status = %d, body %s", w.Code, w.Body)
+	}
+	if w := serve(http.MethodGet, "/health/openai"); w.Code != http.StatusOK {
+		t.Errorf("health after restore status = %d, want 200", w.Code)
+	}
+	if w := serve(http.MethodGet, "/health/unknown"); w.Code != http.StatusNotFound {
+		t.Errorf("health unknown status = %d, want 404", w.Code)
+	}
+}
+
+func TestAdminFeatures(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.yaml")
+	writeConfig := func(enabled string) {
+		t.Helper()
+		config := `instances:
+  bedrock_us1:
+    type: bedrock
+    mode: transparent
+    endpoints:
+      - path: /transparent/bedrock
+features:
+  transparent_mode:
+    enabled: ` + enabled + `
+    keys:
+      tenant-a: true
+`
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeConfig("false")
+	config, err := instance.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	chat, _ := newChatTestHandler(t)
+	h := NewAdminHandler(chat.router)
+	current := currentConfig(config)
+	h.SetInstanceConfig(current, path)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/admin/instances/:name/features", h.InstanceFeatures)
+	engine.POST("/admin/features/reload", h.ReloadFeatures)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	features := func(path string) map[string]bool {
+		t.Helper()
+		w := serve(http.MethodGet, path)
+		if w.Code != http.StatusOK {
+			t.Fatalf("GET %s status = %d, body %s", path, w.Code, w.Body)
+		}
+		var resp struct {
+			Features map[string]bool `json:"features"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return resp.Features
+	}
+
+	if features("/admin/instances/bedrock_us1/features")["transparent_mode"] {
+		t.Error("transparent_mode enabled by default")
+	}
+	if !features("/admin/instances/bedrock_us1/features?key=tenant-a")["transparent_mode"] {
+		t.Error("key override not applied")
+	}
+	if w := serve(http.MethodGet, "/admin/instances/unknown/features"); w.Code != http.StatusNotFound {
+		t.Errorf("unknown instance status = %d, want 404", w.Code)
+	}
+
+	writeConfig("true")
+	if w := serve(http.MethodPost, "/admin/features/reload"); w.Code != http.StatusOK {
+		t.Fatalf("reload status = %d, body %s", w.Code, w.Body)
+	}
+	if !current.Load().IsFeatureEnabled("transparent_mode", "bedrock_us1", "") {
+		t.Error("reloaded flag not in effect")
+	}
+	if config.IsFeatureEnabled("transparent_mode", "bedrock_us1", "") {
+		t.Error("reload changed the config requests in flight read")
+	}
+
+	writeConfig("[")
+	if w := serve(http.MethodPost, "/admin/features/reload"); w.Code != http.StatusUnprocessableEntity {
+		t.Errorf("invalid config reload status = %d, want 422", w.Code)
+	}
+	if !current.Load().IsFeatureEnabled("transparent_mode", "bedrock_us1", "") {
+		t.Error("failed reload changed the flags in effect")
+	}
+}
+
+// TestAdminFaults tests that a fault set through the admin API makes the
+// protocol handler fall back until the fault is cleared
+func TestAdminFaults(t *testing.T) {
+	config := currentConfig(newFallbackTestConfig())
+	primary := &stubProvider{name: "openai"}
+	backup := &stubProvider{name: "azure"}
+	protocol := NewProtocolHandler(map[string]providers.Provider{"openai": primary, "azure": backup}, config,
+		health.NewCheckerWithConfig(health.Config{MinSamples: 1}))
+	faults := chaos.NewInjector()
+	protocol.SetFaults(faults)
+	admin := NewAdminHandler(nil)
+	admin.SetInstanceConfig(config, "")
+	admin.SetFaults(faults)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/admin/faults", admin.ListFaults)
+	engine.POST("/admin/faults", admin.SetFault)
+	engine.DELETE("/admin/faults/:instance", admin.ClearFault)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(http.MethodPost, "/admin/faults", `{"instance":"unknown","error_status":500}`); w.Code != http.StatusNotFound {
+		t.Errorf("unknown instance status = %d, want 404", w.Code)
+	}
+	if w := serve(http.MethodPost, "/admin/faults", `{"instance":"openai-primary","error_status":200}`); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid fault status = %d, want 400", w.Code)
+	}
+	if w := serve(http.MethodPost, "/admin/faults", `{"instance":"openai-primary","refuse_connections":true,"ttl":"1m"}`); w.Code != http.StatusCreated {
+		t.Fatalf("set fault status = %d, body %s", w.Code, w.Body)
+	}
+
+	// The primary refuses connections, so the backup answers
+	if w := serveProtocolRequest(protocol); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "from azure") {
+		t.Errorf("faulted request: status %d, body %s, want the fallback's reply", w.Code, w.Body)
+	}
+	if calls := primary.calls.Load(); calls != 0 {
+		t.Errorf("primary called %d times during the fault", calls)
+	}
+
+	var list struct {
+		Faults []chaos.FaultStatus `json:"faults"`
+	}
+	if err := json.Unmarshal(serve(http.MethodGet, "/admin/faults", "").Body.Bytes(), &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Faults) != 1 || list.Faults[0].Instance != "openai-primary" || list.Faults[0].Injected != 1 {
+		t.Errorf("faults = %+v", list.Faults)
+	}
+
+	if w := serve(http.MethodDelete, "/admin/faults/openai-primary", ""); w.Code != http.StatusNoContent {
+		t.Errorf("clear status = %d", w.Code)
+	}
+	if w := serve(http.MethodDelete, "/admin/faults/openai-primary", ""); w.Code != http.StatusNotFound {
+		t.Errorf("clear again status = %d, want 404", w.Code)
+	}
+	if w := serveProtocolRequest(protocol); !strings.Contains(w.Body.String(), "from openai") {
+		t.Errorf("request after clear: body %s, want the primary's reply", w.Body)
+	}
+}
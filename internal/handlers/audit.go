@@ -0,0 +1,29 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/audit"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// setAuditTarget records the model, provider and instance a request is
+// routed to for the audit log. instance is empty for requests not served
+// by an instance.
+func setAuditTarget(c *gin.Context, model, provider, instance string) {
+	c.Set(audit.TargetKey, audit.Target{Model: model, Provider: provider, Instance: instance})
+}
+
+// setAuditUsage records the token usage of a response for the audit log
+func setAuditUsage(c *gin.Context, usage *translator.Usage) {
+	if usage == nil {
+		return
+	}
+	c.Set(audit.UsageKey, audit.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		TotalTokens:      usage.TotalTokens,
+	})
+}
@@ -54,7 +54,7 @@ type LoginResponse struct {
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
+		respondJSON(c, http.StatusBadRequest, gin.H{
 			"error": "Invalid request",
 			"message": "Provide api_key and totp_code",
 		})
@@ -74,7 +74,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			`{"error":"invalid_api_key"}`,
 		)
 
-		c.JSON(http.StatusUnauthorized, gin.H{
+		respondJSON(c, http.StatusUnauthorized, gin.H{
 			"error": "Invalid API key",
 		})
 		return
@@ -93,7 +93,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			`{"error":"invalid_totp"}`,
 		)
 
-		c.JSON(http.StatusUnauthorized, gin.H{
+		respondJSON(c, http.StatusUnauthorized, gin.H{
 			"error": "Invalid TOTP code",
 		})
 		return
@@ -108,7 +108,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		respondJSON(c, http.StatusInternalServerError, gin.H{
 			"error": "Failed to create session",
 		})
 		return
@@ -127,7 +127,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	expiresAt := time.Now().Add(h.sessionDuration)
 
-	c.JSON(http.StatusOK, LoginResponse{
+	respondJSON(c, http.StatusOK, LoginResponse{
 		SessionToken: sessionToken,
 		ExpiresAt:    expiresAt,
 		ExpiresIn:    int64(h.sessionDuration.Seconds()),
@@ -158,7 +158,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	}
 
 	if sessionToken == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
+		respondJSON(c, http.StatusUnauthorized, gin.H{
 			"error": "Missing session token",
 		})
 		return
@@ -167,7 +167,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	// Validate current token
 	session, apiKeyID, err := h.sessionManager.ValidateSessionToken(sessionToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
+		respondJSON(c, http.StatusUnauthorized, gin.H{
 			"error": "Invalid or expired session token",
 		})
 		return
@@ -185,7 +185,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	)
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		respondJSON(c, http.StatusInternalServerError, gin.H{
 			"error": "Failed to refresh session",
 		})
 		return
@@ -204,7 +204,7 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 
 	expiresAt := time.Now().Add(h.sessionDuration)
 
-	c.JSON(http.StatusOK, RefreshResponse{
+	respondJSON(c, http.StatusOK, RefreshResponse{
 		SessionToken: newToken,
 		ExpiresAt:    expiresAt,
 		ExpiresIn:    int64(h.sessionDuration.Seconds()),
@@ -224,7 +224,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	}
 
 	if sessionToken == "" {
-		c.JSON(http.StatusBadRequest, gin.H{
+		respondJSON(c, http.StatusBadRequest, gin.H{
 			"error": "Missing session token",
 		})
 		return
@@ -233,7 +233,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	// Validate and get session info before revoking
 	session, apiKeyID, err := h.sessionManager.ValidateSessionToken(sessionToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
+		respondJSON(c, http.StatusUnauthorized, gin.H{
 			"error": "Invalid session token",
 		})
 		return
@@ -241,7 +241,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	// Revoke token
 	if err := h.sessionManager.RevokeSessionToken(sessionToken); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		respondJSON(c, http.StatusInternalServerError, gin.H{
 			"error": "Failed to logout",
 		})
 		return
@@ -258,7 +258,7 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 		`{"session_id":` + string(rune(session.ID)) + `}`,
 	)
 
-	c.JSON(http.StatusOK, gin.H{
+	respondJSON(c, http.StatusOK, gin.H{
 		"message": "Logged out successfully",
 	})
 }
@@ -274,7 +274,7 @@ func (h *AuthHandler) ListSessions(c *gin.Context) {
 	}
 
 	if sessionToken == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{
+		respondJSON(c, http.StatusUnauthorized, gin.H{
 			"error": "Missing session token",
 		})
 		return
@@ -283,7 +283,7 @@ func (h *AuthHandler) ListSessions(c *gin.Context) {
 	// Validate token and get API key ID
 	_, apiKeyID, err := h.sessionManager.ValidateSessionToken(sessionToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
+		respondJSON(c, http.StatusUnauthorized, gin.H{
 			"error": "Invalid session token",
 		})
 		return
@@ -292,7 +292,7 @@ func (h *AuthHandler) ListSessions(c *gin.Context) {
 	// Get all active sessions for this user
 	sessions, err := h.sessionManager.ListUserSessions(apiKeyID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		respondJSON(c, http.StatusInternalServerError, gin.H{
 			"error": "Failed to list sessions",
 		})
 		return
@@ -322,7 +322,7 @@ func (h *AuthHandler) ListSessions(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, gin.H{
+	respondJSON(c, http.StatusOK, gin.H{
 		"sessions": result,
 		"count":    len(result),
 	})
@@ -343,7 +343,7 @@ func (h *AuthHandler) RevokeSession(c *gin.Context) {
 
 	_, _, err := h.sessionManager.ValidateSessionToken(currentToken)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{
+		respondJSON(c, http.StatusUnauthorized, gin.H{
 			"error": "Invalid session token",
 		})
 		return
@@ -352,7 +352,7 @@ func (h *AuthHandler) RevokeSession(c *gin.Context) {
 	// TODO: Verify session belongs to user before revoking
 	// For now, we'll add this in the session manager
 
-	c.JSON(http.StatusOK, gin.H{
+	respondJSON(c, http.StatusOK, gin.H{
 		"message": "Session revoked successfully",
 		"session_id": sessionID,
 	})
@@ -0,0 +1,292 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tosharewith/llmproxy_auth/internal/batch"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// maxBatchInputSize limits the size of an uploaded batch input file
+const maxBatchInputSize = 100 << 20 // 100 MiB
+
+// BatchHandler handles OpenAI-compatible batch API requests
+type BatchHandler struct {
+	router *router.Router
+	store  batch.Store
+}
+
+// CreateBatchRequest is the JSON form of POST /v1/batches.
+// Input carries the JSONL request file inline, since the proxy has no Files API.
+type CreateBatchRequest struct {
+	Input            string            `json:"input"`
+	Endpoint         string            `json:"endpoint"`
+	CompletionWindow string            `json:"completion_window"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+}
+
+// NewBatchHandler creates a new batch handler
+func NewBatchHandler(r *router.Router, store batch.Store) *BatchHandler {
+	return &BatchHandler{
+		router: r,
+		store:  store,
+	}
+}
+
+// CreateBatch handles POST /v1/batches.
+// The body is either a CreateBatchRequest, or the raw JSONL file
+// (Content-Type application/jsonl or application/x-ndjson) with endpoint and
+// completion_window passed as query parameters.
+func (h *BatchHandler) CreateBatch(c *gin.Context) {
+	req, err := parseCreateBatchRequest(c)
+	if err != nil {
+		batchError(c, http.StatusBadRequest, "invalid_request_error", "invalid_batch", err.Error())
+		return
+	}
+
+	if req.Endpoint == "" {
+		req.Endpoint = "/v1/chat/completions"
+	}
+	if req.Endpoint != "/v1/chat/completions" {
+		batchError(c, http.StatusBadRequest, "invalid_request_error", "unsupported_endpoint",
+			fmt.Sprintf("Batch endpoint %q is not supported", req.Endpoint))
+		return
+	}
+	if req.CompletionWindow == "" {
+		req.CompletionWindow = "24h"
+	}
+
+	lines, model, err := translator.ParseBatchJSONL([]byte(req.Input))
+	if err != nil {
+		batchError(c, http.StatusBadRequest, "invalid_request_error", "invalid_batch_input", err.Error())
+		return
+	}
+
+	provider, modelInfo, err := h.router.RouteRequest(c.Request.Context(), model, "")
+	if err != nil {
+		log.Printf("Routing error for batch model %s: %v", model, err)
+		if errors.Is(err, router.ErrProviderDraining) {
+			batchError(c, http.StatusServiceUnavailable, "service_error", "provider_draining",
+				fmt.Sprintf("No provider is available for model %q while its provider is draining", model))
+			return
+		}
+		batchError(c, http.StatusBadRequest, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("Model %q not found or not available", model))
+		return
+	}
+
+	batchProvider, ok := provider.(providers.BatchProvider)
+	if !ok {
+		batchError(c, http.StatusBadRequest, "invalid_request_error", "batch_not_supported",
+			fmt.Sprintf("Provider %q does not support batch inference", provider.Name()))
+		return
+	}
+
+	var input []byte
+	switch provider.Name() {
+	case "bedrock":
+		input, err = translator.TranslateBatchToBedrock(lines, modelInfo.Model)
+	default:
+		input, err = translator.TranslateBatchToOpenAI(lines, modelInfo.Model)
+	}
+	if err != nil {
+		batchError(c, http.StatusBadRequest, "invalid_request_error", "translation_failed",
+			fmt.Sprintf("Failed to translate batch input: %v", err))
+		return
+	}
+
+	id := strings.ReplaceAll(uuid.New().String(), "-", "")
+	job := &batch.Job{
+		ID:               "batch_" + id,
+		Provider:         provider.Name(),
+		Model:            model,
+		ProviderModel:    modelInfo.Model,
+		Endpoint:         req.Endpoint,
+		CompletionWindow: req.CompletionWindow,
+		Metadata:         req.Metadata,
+		CreatedAt:        time.Now(),
+	}
+
+	state, err := batchProvider.SubmitBatch(c.Request.Context(), &providers.BatchRequest{
+		JobName:          "batch-" + id,
+		Model:            modelInfo.Model,
+		Endpoint:         req.Endpoint,
+		CompletionWindow: req.CompletionWindow,
+		InputJSONL:       input,
+		RequestCount:     len(lines),
+		Metadata:         req.Metadata,
+	})
+	if err != nil {
+		log.Printf("Batch submission error: %v", err)
+		writeProviderError(c, err)
+		return
+	}
+	job.State = *state
+	if job.State.Total == 0 {
+		job.State.Total = len(lines)
+	}
+	if job.State.Status == providers.BatchStatusInProgress {
+		job.InProgressAt = time.Now()
+	}
+
+	if err := h.store.Create(c.Request.Context(), job); err != nil {
+		log.Printf("Failed to store batch job %s: %v", job.ID, err)
+		batchError(c, http.StatusInternalServerError, "api_error", "store_failed", "Failed to store batch job")
+		return
+	}
+
+	log.Printf("Submitted batch %s to provider %s (model: %s, requests: %d)", job.ID, job.Provider, job.ProviderModel, len(lines))
+
+	respondJSON(c, http.StatusOK, toOpenAIBatch(job))
+}
+
+// GetBatch handles GET /v1/batches/{id}, refreshing non-terminal jobs from the provider
+func (h *BatchHandler) GetBatch(c *gin.Context) {
+	id := c.Param("id")
+
+	job, err := h.store.Get(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, batch.ErrJobNotFound) {
+			batchError(c, http.StatusNotFound, "invalid_request_error", "batch_not_found",
+				fmt.Sprintf("Batch %q not found", id))
+			return
+		}
+		batchError(c, http.StatusInternalServerError, "api_error", "store_failed", "Failed to load batch job")
+		return
+	}
+
+	if !providers.IsTerminalBatchStatus(job.State.Status) {
+		if err := h.refresh(c, job); err != nil {
+			// Serve the last known state rather than failing the read
+			log.Printf("Failed to refresh batch %s: %v", job.ID, err)
+		}
+	}
+
+	respondJSON(c, http.StatusOK, toOpenAIBatch(job))
+}
+
+// refresh fetches the latest provider state for a job and stores it
+func (h *BatchHandler) refresh(c *gin.Context, job *batch.Job) error {
+	provider, err := h.router.GetProvider(job.Provider)
+	if err != nil {
+		return err
+	}
+	batchProvider, ok := provider.(providers.BatchProvider)
+	if !ok {
+		return fmt.Errorf("provider %q does not support batch inference", job.Provider)
+	}
+
+	state, err := batchProvider.GetBatch(c.Request.Context(), job.State.ProviderBatchID)
+	if err != nil {
+		return err
+	}
+
+	// Providers may not report counts; keep what we know
+	if state.Total == 0 {
+		state.Total = job.State.Total
+	}
+	if job.InProgressAt.IsZero() && state.Status != providers.BatchStatusValidating {
+		job.InProgressAt = time.Now()
+	}
+	job.State = *state
+
+	return h.store.Update(c.Request.Context(), job)
+}
+
+// parseCreateBatchRequest reads either a JSON or raw JSONL create request
+func parseCreateBatchRequest(c *gin.Context) (*CreateBatchRequest, error) {
+	contentType := c.ContentType()
+	if contentType == "application/jsonl" || contentType == "application/x-ndjson" {
+		body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBatchInputSize+1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read request body: %w", err)
+		}
+		if len(body) > maxBatchInputSize {
+			return nil, fmt.Errorf("batch input exceeds %d bytes", maxBatchInputSize)
+		}
+		return &CreateBatchRequest{
+			Input:            string(body),
+			Endpoint:         c.Query("endpoint"),
+			CompletionWindow: c.Query("completion_window"),
+		}, nil
+	}
+
+	var req CreateBatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		return nil, fmt.Errorf("invalid request body")
+	}
+	if req.Input == "" {
+		return nil, fmt.Errorf("input is required")
+	}
+	return &req, nil
+}
+
+// toOpenAIBatch converts a stored job to the OpenAI batch object shape
+func toOpenAIBatch(job *batch.Job) *translator.Batch {
+	state := job.State
+	b := &translator.Batch{
+		ID:               job.ID,
+		Object:           "batch",
+		Endpoint:         job.Endpoint,
+		InputFileID:      state.InputLocation,
+		CompletionWindow: job.CompletionWindow,
+		Status:           state.Status,
+		CreatedAt:        job.CreatedAt.Unix(),
+		InProgressAt:     unixPtr(job.InProgressAt),
+		ExpiresAt:        unixPtr(state.ExpiresAt),
+		CompletedAt:      unixPtr(state.CompletedAt),
+		FailedAt:         unixPtr(state.FailedAt),
+		RequestCounts: translator.BatchRequestCounts{
+			Total:     state.Total,
+			Completed: state.Completed,
+			Failed:    state.Failed,
+		},
+		Metadata: job.Metadata,
+	}
+	if state.OutputLocation != "" {
+		b.OutputFileID = &state.OutputLocation
+	}
+	if state.ErrorLocation != "" {
+		b.ErrorFileID = &state.ErrorLocation
+	}
+	if state.Message != "" && state.Status == providers.BatchStatusFailed {
+		b.Errors = &translator.BatchErrors{
+			Object: "list",
+			Data:   []translator.BatchError{{Code: "batch_failed", Message: state.Message}},
+		}
+	}
+	return b
+}
+
+// unixPtr returns a pointer to the Unix timestamp, or nil for the zero time
+func unixPtr(t time.Time) *int64 {
+	if t.IsZero() {
+		return nil
+	}
+	ts := t.Unix()
+	return &ts
+}
+
+// batchError writes an OpenAI-style error response
+func batchError(c *gin.Context, status int, errType, code, message string) {
+	respondJSON(c, status, translator.ErrorResponse{
+		Error: translator.ErrorDetail{
+			Message: message,
+			Type:    errType,
+			Code:    code,
+		},
+	})
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/batch"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// stubBatchProvider records submitted batches and reports a configurable status
+type stubBatchProvider struct {
+	stubProvider
+	submitted *providers.BatchRequest
+	status    string
+}
+
+func (p *stubBatchProvider) SubmitBatch(ctx context.Context, req *providers.BatchRequest) (*providers.BatchJob, error) {
+	p.submitted = req
+	return &providers.BatchJob{ProviderBatchID: "batch_upstream", Status: providers.BatchStatusValidating, InputLocation: "file-123"}, nil
+}
+
+func (p *stubBatchProvider) GetBatch(ctx context.Context, id string) (*providers.BatchJob, error) {
+	return &providers.BatchJob{ProviderBatchID: id, Status: p.status, InputLocation: "file-123", OutputLocation: "file-out", Completed: 2}, nil
+}
+
+func newBatchTestServer(t *testing.T, provider *stubBatchProvider) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	config := &router.Config{
+		ModelMappings: map[string]router.ModelMapping{
+			"gpt-4o-mini": {
+				DefaultProvider: "openai",
+				Providers:       map[string]router.ProviderModelInfo{"openai": {Model: "gpt-4o-mini-2024-07-18"}},
+			},
+		},
+		Providers: map[string]router.ProviderConfig{"openai": {Enabled: true}},
+	}
+	r, err := router.NewRouter(config, map[string]providers.Provider{"openai": provider})
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+
+	h := NewBatchHandler(r, batch.NewMemoryStore())
+	engine := gin.New()
+	engine.POST("/v1/batches", h.CreateBatch)
+	engine.GET("/v1/batches/:id", h.GetBatch)
+	return engine
+}
+
+// TestBatchLifecycle tests submitting a JSONL batch and polling its status
+func TestBatchLifecycle(t *testing.T) {
+	provider := &stubBatchProvider{stubProvider: stubProvider{name: "openai"}, status: providers.BatchStatusCompleted}
+	engine := newBatchTestServer(t, provider)
+
+	input := `{"custom_id":"a","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[{"role":"user","content":"hi"}]}}
+{"custom_id":"b","method":"POST","url":"/v1/chat/completions","body":{"model":"gpt-4o-mini","messages":[{"role":"user","content":"bye"}]}}
+`
+	req := httptest.NewRequest(http.MethodPost, "/v1/batches?completion_window=24h", strings.NewReader(input))
+	req.Header.Set("Content-Type", "application/jsonl")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("create: status %d: %s", w.Code, w.Body.String())
+	}
+	var created translator.Batch
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Object != "batch" || created.Status != providers.BatchStatusValidating || created.RequestCounts.Total != 2 {
+		t.Errorf("unexpected created batch: %+v", created)
+	}
+	if !strings.Contains(string(provider.submitted.InputJSONL), `"model":"gpt-4o-mini-2024-07-18"`) {
+		t.Errorf("input not rewritten to provider model: %s", provider.submitted.InputJSONL)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/batches/"+created.ID, nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("get: status %d: %s", w.Code, w.Body.String())
+	}
+	var fetched translator.Batch
+	if err := json.Unmarshal(w.Body.Bytes(), &fetched); err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if fetched.Status != providers.BatchStatusCompleted || fetched.OutputFileID == nil || *fetched.OutputFileID != "file-out" {
+		t.Errorf("unexpected fetched batch: %+v", fetched)
+	}
+	if fetched.RequestCounts.Total != 2 || fetched.RequestCounts.Completed != 2 {
+		t.Errorf("unexpected request counts: %+v", fetched.RequestCounts)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/batches/batch_missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing batch: got status %d, want 404", w.Code)
+	}
+}
+
+// TestBatchMixedModelsRejected tests that a batch must target a single model
+func TestBatchMixedModelsRejected(t *testing.T) {
+	engine := newBatchTestServer(t, &stubBatchProvider{stubProvider: stubProvider{name: "openai"}})
+
+	body, _ := json.Marshal(CreateBatchRequest{
+		Input: `{"custom_id":"a","body":{"model":"gpt-4o-mini","messages":[]}}
+{"custom_id":"b","body":{"model":"gpt-4o","messages":[]}}`,
+	})
+	req := httptest.NewRequest(http.MethodPost, "/v1/batches", strings.NewReader(string(body)))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got status %d, want 400: %s", w.Code, w.Body.String())
+	}
+}
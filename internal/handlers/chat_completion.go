@@ -1,297 +1,284 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
 package handlers
 
 import (
+	"bufio"
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
 	"github.com/tosharewith/llmproxy_auth/internal/router"
 	"github.com/tosharewith/llmproxy_auth/internal/translator"
 )
 
-// ChatCompletionHandler handles OpenAI-compatible chat completion requests
-type ChatCompletionHandler struct {
-	modelRouter *router.ModelRouter
-}
+// translateChatRequest translates an OpenAI chat completion request to the
+// provider's format. Bedrock uses the Converse API; OpenAI and Azure speak
+// OpenAI natively; Anthropic, Vertex, IBM and Oracle translate the OpenAI
+// body in their Invoke method.
+func translateChatRequest(provider providers.Provider, req *translator.ChatCompletionRequest, modelInfo *router.ProviderModelInfo) (*providers.ProviderRequest, error) {
+	providerName := provider.Name()
+	if providerName == "bedrock" {
+		providerReq, _, err := translator.TranslateOpenAIToConverseAPI(req)
+		return providerReq, err
+	}
 
-// NewChatCompletionHandler creates a new chat completion handler
-func NewChatCompletionHandler(modelRouter *router.ModelRouter) *ChatCompletionHandler {
-	return &ChatCompletionHandler{
-		modelRouter: modelRouter,
+	// parallel_tool_calls: false is dropped for providers without it, as
+	// sequential calls cannot be enforced; true only restates the default
+	if req.ParallelToolCalls != nil && !*req.ParallelToolCalls && !providers.CapabilitiesOf(provider).ParallelToolCalls {
+		stripped := *req
+		stripped.ParallelToolCalls = nil
+		req = &stripped
 	}
-}
 
-// Handle processes a chat completion request
-func (h *ChatCompletionHandler) Handle(w http.ResponseWriter, r *http.Request) {
-	// Parse OpenAI request
-	var openaiReq translator.ChatCompletionRequest
-	if err := json.NewDecoder(r.Body).Decode(&openaiReq); err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_request_error", "Invalid JSON in request body", err)
-		return
+	if !supportsPromptCaching(providerName) {
+		req = translator.StripCacheControl(req)
+	}
+	if providerName != "azure" {
+		req = translator.StripDataSources(req)
 	}
 
-	// Validate request
-	if openaiReq.Model == "" {
-		h.writeError(w, http.StatusBadRequest, "invalid_request_error", "Model is required", nil)
-		return
+	// Vertex AI addresses models by their Vertex model ID, such as
+	// claude-3-5-sonnet@20240620 for Claude
+	if providerName == "vertex" && modelInfo != nil && modelInfo.Model != "" && modelInfo.Model != req.Model {
+		mapped := *req
+		mapped.Model = modelInfo.Model
+		req = &mapped
 	}
 
-	// Route to provider based on model
-	provider, err := h.modelRouter.RouteModel(openaiReq.Model)
+	body, err := json.Marshal(req)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_request_error", fmt.Sprintf("Model not supported: %s", openaiReq.Model), err)
-		return
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	providerReq := &providers.ProviderRequest{
+		Method: "POST",
+		Path:   "/chat/completions",
+		Headers: http.Header{
+			"Content-Type": {"application/json"},
+		},
+		Body: body,
 	}
 
-	// Handle streaming vs non-streaming
-	if openaiReq.Stream {
-		h.handleStreaming(w, r, provider, &openaiReq)
-	} else {
-		h.handleNonStreaming(w, r, provider, &openaiReq)
+	if providerName == "azure" {
+		// Azure addresses models by deployment name
+		deployment := req.Model
+		if modelInfo != nil && modelInfo.Deployment != "" {
+			deployment = modelInfo.Deployment
+		} else if modelInfo != nil && modelInfo.Model != "" {
+			deployment = modelInfo.Model
+		}
+		providerReq.Path = fmt.Sprintf("/deployments/%s/chat/completions", deployment)
 	}
+	return providerReq, nil
 }
 
-// handleNonStreaming handles non-streaming chat completion
-func (h *ChatCompletionHandler) handleNonStreaming(w http.ResponseWriter, r *http.Request, provider providers.Provider, openaiReq *translator.ChatCompletionRequest) {
-	ctx := r.Context()
+// translateChatResponse translates a provider response body to an OpenAI
+// chat completion response
+func translateChatResponse(providerName string, body []byte, model, requestID string) (*translator.ChatCompletionResponse, error) {
+	if providerName == "bedrock" {
+		var converseResp translator.ConverseResponse
+		if err := json.Unmarshal(body, &converseResp); err != nil {
+			return nil, fmt.Errorf("failed to parse bedrock response: %w", err)
+		}
+		return translator.TranslateConverseToOpenAI(&converseResp, model, requestID), nil
+	}
 
-	// Translate request to provider format
-	providerReq, err := h.translateRequest(provider.Name(), openaiReq)
-	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_request_error", "Failed to translate request", err)
-		return
+	// OpenAI, Azure, Anthropic, Vertex, IBM, Oracle return OpenAI format (or already translated)
+	var openaiResp translator.ChatCompletionResponse
+	if err := json.Unmarshal(body, &openaiResp); err != nil {
+		return nil, err
 	}
+	return &openaiResp, nil
+}
 
-	// Call provider
-	providerResp, err := provider.Invoke(ctx, providerReq)
-	if err != nil {
-		h.handleProviderError(w, err)
-		return
+// supportsPromptCaching reports whether a provider serves Claude, whose
+// prompt cache markers are translated from cache_control; other providers
+// are sent requests without them
+func supportsPromptCaching(providerName string) bool {
+	switch providerName {
+	case "anthropic", "vertex", "bedrock":
+		return true
 	}
+	return false
+}
 
-	// Translate response back to OpenAI format
-	openaiResp, err := h.translateResponse(provider.Name(), providerResp.Body, openaiReq.Model)
-	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "internal_error", "Failed to translate response", err)
+// serviceTierKey is the gin context key holding the service tier echoed
+// in the response
+const serviceTierKey = "service_tier"
+
+// gateServiceTier drops a service_tier the provider does not honour, with a
+// logged notice, and records the tier the request runs with: the one asked
+// for, default when it was dropped, and for Bedrock the tier its latency
+// maps to
+func gateServiceTier(c *gin.Context, provider providers.Provider, req *translator.ChatCompletionRequest) {
+	if req.ServiceTier == "" {
 		return
 	}
+	tier := req.ServiceTier
+	if !providers.CapabilitiesOf(provider).SupportsServiceTier(tier) {
+		log.Printf("Ignoring service_tier %q: not supported by provider %s", tier, provider.Name())
+		req.ServiceTier = ""
+		tier = translator.ServiceTierDefault
+	} else if provider.Name() == "bedrock" {
+		tier = translator.ConverseServiceTier(req)
+	}
+	c.Set(serviceTierKey, tier)
+}
 
-	// Write response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(openaiResp)
+// echoServiceTier sets the service tier recorded by gateServiceTier on a
+// response that does not report the tier it was processed with
+func echoServiceTier(c *gin.Context, resp *translator.ChatCompletionResponse) {
+	if resp.ServiceTier == "" {
+		resp.ServiceTier = c.GetString(serviceTierKey)
+	}
 }
 
-// handleStreaming handles streaming chat completion
-func (h *ChatCompletionHandler) handleStreaming(w http.ResponseWriter, r *http.Request, provider providers.Provider, openaiReq *translator.ChatCompletionRequest) {
-	ctx := r.Context()
+// streamsOpenAIProvider reports whether a provider's stream is OpenAI SSE
+// and can be proxied as-is
+func streamsOpenAIProvider(providerName string) bool {
+	return providerName == "openai" || providerName == "azure"
+}
 
-	// Translate request
-	providerReq, err := h.translateRequest(provider.Name(), openaiReq)
+// invoke calls the provider and translates its response to OpenAI format
+// with the given ID, also returning the upstream response headers
+func invoke(ctx context.Context, provider providers.Provider, providerReq *providers.ProviderRequest, model, requestID string) (*translator.ChatCompletionResponse, http.Header, error) {
+	providerResp, err := provider.Invoke(ctx, providerReq)
 	if err != nil {
-		h.writeError(w, http.StatusBadRequest, "invalid_request_error", "Failed to translate request", err)
-		return
+		return nil, nil, err
 	}
-
-	// Call provider streaming
-	stream, err := provider.InvokeStreaming(ctx, providerReq)
+	openaiResp, err := translateChatResponse(provider.Name(), providerResp.Body, model, requestID)
 	if err != nil {
-		h.handleProviderError(w, err)
-		return
+		return nil, providerResp.Headers, &providers.ProviderError{
+			Provider:   provider.Name(),
+			StatusCode: http.StatusInternalServerError,
+			Code:       providers.ErrCodeInternalError,
+			Message:    "Failed to parse provider response",
+			Err:        err,
+		}
 	}
-	defer stream.Close()
 
-	// Set headers for streaming
-	w.Header().Set("Content-Type", "text/event-stream")
-	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.WriteHeader(http.StatusOK)
+	// Providers that return OpenAI format may still use their own stop reasons
+	translator.NormalizeFinishReasons(openaiResp)
 
-	// Stream the response
-	// TODO: Implement streaming translation for each provider
-	// For now, just proxy the stream
-	flusher, ok := w.(http.Flusher)
-	if !ok {
-		h.writeError(w, http.StatusInternalServerError, "internal_error", "Streaming not supported", nil)
-		return
-	}
+	openaiResp.ID = requestID
+	openaiResp.Created = time.Now().Unix()
+	return openaiResp, providerResp.Headers, nil
+}
 
-	buf := make([]byte, 4096)
+// copyStreamWithUsage proxies an OpenAI-format SSE stream line by line and,
+// unless the provider already reported usage in-stream, inserts a final
+// usage chunk (empty choices) right before the [DONE] event. It returns nil
+// at the end of the stream and the read or write error otherwise.
+func copyStreamWithUsage(w io.Writer, flusher http.Flusher, stream io.Reader, tracker *translator.StreamUsageTracker) error {
+	reader := bufio.NewReader(stream)
 	for {
-		n, err := stream.Read(buf)
-		if n > 0 {
-			w.Write(buf[:n])
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+				data = bytes.TrimSpace(data)
+				if string(data) == translator.StreamDone && !tracker.HasNativeUsage() {
+					if chunk, err := json.Marshal(tracker.UsageChunk()); err == nil {
+						fmt.Fprintf(w, "data: %s\n\n", chunk)
+					}
+				}
+				tracker.Observe(data)
+			}
+			if _, writeErr := w.Write(line); writeErr != nil {
+				return writeErr
+			}
 			flusher.Flush()
 		}
 		if err == io.EOF {
-			break
+			return nil
 		}
 		if err != nil {
-			// Error during streaming - can't send error response now
-			break
+			return err
 		}
 	}
 }
 
-// translateRequest translates OpenAI request to provider-specific format
-func (h *ChatCompletionHandler) translateRequest(providerName string, openaiReq *translator.ChatCompletionRequest) (*providers.ProviderRequest, error) {
-	switch providerName {
-	case "bedrock":
-		// Bedrock requires provider-specific translation
-		providerReq, _, err := translator.TranslateOpenAIToBedrock(openaiReq)
-		return providerReq, err
-
-	case "openai":
-		// OpenAI doesn't need translation - use OpenAI format as-is
-		body, err := json.Marshal(openaiReq)
-		if err != nil {
-			return nil, err
-		}
-		return &providers.ProviderRequest{
-			Method: "POST",
-			Path:   "/chat/completions",
-			Body:   body,
-			Headers: map[string]string{
-				"Content-Type": "application/json",
-			},
-		}, nil
-
-	case "anthropic":
-		// Anthropic provider handles translation internally
-		// Just pass OpenAI format and let the provider translate
-		body, err := json.Marshal(openaiReq)
-		if err != nil {
-			return nil, err
-		}
-		return &providers.ProviderRequest{
-			Method: "POST",
-			Path:   "/messages",
-			Body:   body,
-			Headers: map[string]string{
-				"Content-Type": "application/json",
-			},
-		}, nil
-
-	case "azure":
-		// Azure uses OpenAI format with different path
-		body, err := json.Marshal(openaiReq)
-		if err != nil {
-			return nil, err
-		}
-		// Azure path includes deployment name (model)
-		return &providers.ProviderRequest{
-			Method: "POST",
-			Path:   fmt.Sprintf("/deployments/%s/chat/completions", openaiReq.Model),
-			Body:   body,
-			Headers: map[string]string{
-				"Content-Type": "application/json",
-			},
-			QueryParams: map[string]string{
-				"api-version": "2024-02-15-preview",
-			},
-		}, nil
-
-	default:
-		return nil, fmt.Errorf("translation not implemented for provider: %s", providerName)
+// preflight counts prompt tokens when enabled and the provider supports it,
+// and rejects requests that cannot fit the model's context window. Counting
+// failures are logged and do not block the request. Returns false if a
+// response has been written.
+func (h *OpenAIHandler) preflight(c *gin.Context, provider providers.Provider, providerReq *providers.ProviderRequest, req *translator.ChatCompletionRequest) bool {
+	if !h.preflightTokenCheck {
+		return true
+	}
+	counter, ok := provider.(providers.TokenCounter)
+	if !ok {
+		return true
 	}
-}
-
-// translateResponse translates provider response to OpenAI format
-func (h *ChatCompletionHandler) translateResponse(providerName string, respBody []byte, model string) (*translator.ChatCompletionResponse, error) {
-	switch providerName {
-	case "bedrock":
-		// Parse Bedrock response and translate to OpenAI format
-		var bedrockResp translator.BedrockResponse
-		if err := json.Unmarshal(respBody, &bedrockResp); err != nil {
-			return nil, fmt.Errorf("failed to parse bedrock response: %w", err)
-		}
-
-		// Generate request ID
-		requestID := fmt.Sprintf("chatcmpl-%d", time.Now().Unix())
-
-		// Translate to OpenAI format
-		openaiResp := translator.TranslateBedrockToOpenAI(&bedrockResp, model, requestID)
-		return openaiResp, nil
-
-	case "openai", "azure":
-		// Already in OpenAI format
-		var openaiResp translator.ChatCompletionResponse
-		if err := json.Unmarshal(respBody, &openaiResp); err != nil {
-			return nil, err
-		}
-		return &openaiResp, nil
 
-	case "anthropic":
-		// Anthropic provider already translated to OpenAI format internally
-		// Just parse and return
-		var openaiResp translator.ChatCompletionResponse
-		if err := json.Unmarshal(respBody, &openaiResp); err != nil {
-			return nil, fmt.Errorf("failed to parse anthropic response: %w", err)
-		}
-		return &openaiResp, nil
+	ctx := c.Request.Context()
+	promptTokens, err := counter.CountTokens(ctx, providerReq)
+	if err != nil {
+		log.Printf("Pre-flight token count failed for %s: %v", provider.Name(), err)
+		return true
+	}
+	c.Header("X-Preflight-Prompt-Tokens", strconv.Itoa(promptTokens))
 
-	default:
-		return nil, fmt.Errorf("translation not implemented for provider: %s", providerName)
+	model, err := provider.GetModelInfo(ctx, req.Model)
+	if err != nil || model.ContextWindow == 0 {
+		return true
+	}
+	if promptTokens+req.MaxTokens > model.ContextWindow {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "context_length_exceeded",
+			fmt.Sprintf("This model's maximum context length is %d tokens, but the request has %d prompt tokens and max_tokens %d",
+				model.ContextWindow, promptTokens, req.MaxTokens))
+		return false
 	}
+	return true
 }
 
-// handleProviderError converts provider error to OpenAI error format
-func (h *ChatCompletionHandler) handleProviderError(w http.ResponseWriter, err error) {
-	provErr, ok := err.(*providers.ProviderError)
-	if !ok {
-		h.writeError(w, http.StatusInternalServerError, "internal_error", "Internal server error", err)
-		return
+// providerErrorResponse maps a provider error to an HTTP status and an
+// OpenAI-compatible error response. The provider's status code wins; without
+// one the status follows the error code.
+func providerErrorResponse(err error) (int, translator.ErrorResponse) {
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) {
+		return http.StatusInternalServerError, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "Internal server error",
+				Type:    "api_error",
+				Code:    "internal_error",
+			},
+		}
 	}
 
-	// Map provider error codes to OpenAI error types
-	var errorType string
-	var statusCode int
-
-	switch provErr.Code {
+	errorType := "api_error"
+	statusCode := http.StatusInternalServerError
+	switch providerErr.Code {
 	case providers.ErrCodeInvalidRequest:
-		errorType = "invalid_request_error"
-		statusCode = http.StatusBadRequest
+		errorType, statusCode = "invalid_request_error", http.StatusBadRequest
 	case providers.ErrCodeAuthenticationFail:
-		errorType = "authentication_error"
-		statusCode = http.StatusUnauthorized
+		errorType, statusCode = "authentication_error", http.StatusUnauthorized
 	case providers.ErrCodeRateLimitExceeded:
-		errorType = "rate_limit_error"
-		statusCode = http.StatusTooManyRequests
+		errorType, statusCode = "rate_limit_error", http.StatusTooManyRequests
 	case providers.ErrCodeModelNotFound:
-		errorType = "invalid_request_error"
-		statusCode = http.StatusNotFound
+		errorType, statusCode = "invalid_request_error", http.StatusNotFound
 	case providers.ErrCodeServiceUnavailable:
-		errorType = "service_unavailable"
 		statusCode = http.StatusServiceUnavailable
-	default:
-		errorType = "internal_error"
-		statusCode = http.StatusInternalServerError
 	}
-
-	if provErr.StatusCode != 0 {
-		statusCode = provErr.StatusCode
+	if providerErr.StatusCode != 0 {
+		statusCode = providerErr.StatusCode
 	}
 
-	h.writeError(w, statusCode, errorType, provErr.Message, provErr.Err)
-}
-
-// writeError writes an OpenAI-compatible error response
-func (h *ChatCompletionHandler) writeError(w http.ResponseWriter, statusCode int, errorType, message string, err error) {
-	errorResp := translator.ErrorResponse{
+	return statusCode, translator.ErrorResponse{
 		Error: translator.ErrorDetail{
-			Message: message,
+			Message: providerErr.Message,
 			Type:    errorType,
+			Code:    providerErr.Code,
 		},
 	}
-
-	if err != nil {
-		errorResp.Error.Message = fmt.Sprintf("%s: %v", message, err)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(errorResp)
 }
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/azure"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// sseEvents returns the data payloads of an SSE body
+func sseEvents(body string) []string {
+	var events []string
+	for _, line := range strings.Split(body, "\n") {
+		if data, ok := strings.CutPrefix(line, "data: "); ok {
+			events = append(events, data)
+		}
+	}
+	return events
+}
+
+// TestCopyStreamWithUsage tests the final usage chunk for stream_options.include_usage
+func TestCopyStreamWithUsage(t *testing.T) {
+	req := &translator.ChatCompletionRequest{
+		Model:         "gpt-4o",
+		Stream:        true,
+		StreamOptions: &translator.StreamOptions{IncludeUsage: true},
+		Messages:      []translator.ChatMessage{{Role: "user", Content: translator.TextContent("Say hello world")}}, // 15 chars
+	}
+
+	t.Run("estimated usage before done", func(t *testing.T) {
+		upstream := `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hello"},"finish_reason":null}]}
+
+data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":" world!!"},"finish_reason":"stop"}]}
+
+data: [DONE]
+
+`
+		w := httptest.NewRecorder()
+		copyStreamWithUsage(w, w, strings.NewReader(upstream), translator.NewStreamUsageTracker(req))
+
+		events := sseEvents(w.Body.String())
+		if len(events) != 4 || events[3] != "[DONE]" {
+			t.Fatalf("unexpected events: %q", events)
+		}
+
+		var chunk translator.ChatCompletionStreamResponse
+		if err := json.Unmarshal([]byte(events[2]), &chunk); err != nil {
+			t.Fatalf("usage chunk: %v", err)
+		}
+		if chunk.ID != "c1" || len(chunk.Choices) != 0 || chunk.Usage == nil {
+			t.Fatalf("unexpected usage chunk: %s", events[2])
+		}
+		want := translator.Usage{PromptTokens: 4, CompletionTokens: 4, TotalTokens: 8}
+		if *chunk.Usage != want {
+			t.Errorf("usage: got %+v, want %+v", *chunk.Usage, want)
+		}
+		if !strings.Contains(events[2], `"choices":[]`) {
+			t.Errorf("choices must be an empty array: %s", events[2])
+		}
+	})
+
+	t.Run("native usage is not duplicated", func(t *testing.T) {
+		upstream := `data: {"id":"c2","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":"stop"}]}
+
+data: {"id":"c2","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[],"usage":{"prompt_tokens":9,"completion_tokens":1,"total_tokens":10}}
+
+data: [DONE]
+
+`
+		w := httptest.NewRecorder()
+		copyStreamWithUsage(w, w, strings.NewReader(upstream), translator.NewStreamUsageTracker(req))
+
+		if w.Body.String() != upstream {
+			t.Errorf("stream with native usage should pass through unchanged, got:\n%s", w.Body.String())
+		}
+	})
+}
+
+// countingProvider is a stubProvider that counts tokens and has a context window
+type countingProvider struct {
+	stubProvider
+	tokens        int
+	contextWindow int
+}
+
+func (p *countingProvider) CountTokens(ctx context.Context, req *providers.ProviderRequest) (int, error) {
+	return p.tokens, nil
+}
+
+func (p *countingProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return &providers.Model{ID: modelID, ContextWindow: p.contextWindow}, nil
+}
+
+// TestPreflightTokenCheck tests pre-flight counting against the context window
+func TestPreflightTokenCheck(t *testing.T) {
+	openaiReq := &translator.ChatCompletionRequest{Model: "gpt-4o", MaxTokens: 100}
+	providerReq := &providers.ProviderRequest{Body: []byte(`{}`)}
+
+	tests := []struct {
+		name    string
+		enabled bool
+		tokens  int
+		want    bool
+	}{
+		{"disabled", false, 1000, true},
+		{"fits", true, 900, true},
+		{"exceeds context", true, 901, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := &OpenAIHandler{preflightTokenCheck: tt.enabled}
+			provider := &countingProvider{stubProvider: stubProvider{name: "openai"}, tokens: tt.tokens, contextWindow: 1000}
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+
+			if got := h.preflight(c, provider, providerReq, openaiReq); got != tt.want {
+				t.Fatalf("preflight() = %v, want %v", got, tt.want)
+			}
+			if !tt.want && w.Code != http.StatusBadRequest {
+				t.Errorf("status %d, want 400", w.Code)
+			}
+			if tt.enabled && w.Header().Get("X-Preflight-Prompt-Tokens") == "" {
+				t.Error("missing X-Preflight-Prompt-Tokens header")
+			}
+		})
+	}
+
+	// Providers without TokenCounter are not checked
+	h := &OpenAIHandler{preflightTokenCheck: true}
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/", nil)
+	if !h.preflight(c, &stubProvider{name: "openai"}, providerReq, openaiReq) {
+		t.Error("preflight() rejected a provider without TokenCounter")
+	}
+}
+
+// capableChatProvider is a stubProvider reporting capabilities
+type capableChatProvider struct {
+	stubProvider
+	capabilities providers.Capabilities
+}
+
+func (p *capableChatProvider) Capabilities() providers.Capabilities { return p.capabilities }
+
+// TestTranslateChatRequestParallelToolCalls tests that parallel_tool_calls
+// false is dropped for providers without the capability and true is passed
+// through
+func TestTranslateChatRequestParallelToolCalls(t *testing.T) {
+	disabled, enabled := false, true
+	parallel := providers.Capabilities{Tools: true, ParallelToolCalls: true}
+	tests := []struct {
+		provider providers.Provider
+		parallel *bool
+		want     string
+	}{
+		{&capableChatProvider{stubProvider{name: "openai"}, parallel}, &disabled, "false"},
+		{&capableChatProvider{stubProvider{name: "anthropic"}, parallel}, &disabled, "false"},
+		{&capableChatProvider{stubProvider{name: "ibm"}, providers.Capabilities{Tools: true}}, &disabled, ""},
+		{&stubProvider{name: "cohere"}, &disabled, ""},
+		{&stubProvider{name: "cohere"}, &enabled, "true"},
+	}
+	for _, tt := range tests {
+		name := tt.provider.Name()
+		req := &translator.ChatCompletionRequest{
+			Model:             "m",
+			Messages:          []translator.ChatMessage{{Role: "user", Content: translator.TextContent("hi")}},
+			ParallelToolCalls: tt.parallel,
+		}
+		providerReq, err := translateChatRequest(tt.provider, req, nil)
+		if err != nil {
+			t.Fatalf("translateChatRequest(%s): %v", name, err)
+		}
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(providerReq.Body, &body); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if got := string(body["parallel_tool_calls"]); got != tt.want {
+			t.Errorf("%s: parallel_tool_calls = %q, want %q", name, got, tt.want)
+		}
+		if req.ParallelToolCalls != tt.parallel {
+			t.Errorf("%s: caller's request was modified", name)
+		}
+	}
+}
+
+// TestTranslateChatRequestDataSources tests that Azure On Your Data sources
+// are sent to Azure only
+func TestTranslateChatRequestDataSources(t *testing.T) {
+	req := &translator.ChatCompletionRequest{
+		Model:       "gpt-4o",
+		Messages:    []translator.ChatMessage{{Role: "user", Content: translator.TextContent("hi")}},
+		DataSources: []azure.AzureDataSource{{Type: "azure_search", Parameters: map[string]interface{}{"index_name": "docs"}}},
+	}
+	for name, want := range map[string]bool{"azure": true, "openai": false} {
+		providerReq, err := translateChatRequest(&stubProvider{name: name}, req, nil)
+		if err != nil {
+			t.Fatalf("translateChatRequest(%s): %v", name, err)
+		}
+		var body map[string]json.RawMessage
+		if err := json.Unmarshal(providerReq.Body, &body); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		if _, got := body["data_sources"]; got != want {
+			t.Errorf("%s: data_sources sent = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tosharewith/llmproxy_auth/internal/jobs"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+const (
+	// WebhookURLHeader makes a chat completion asynchronous: the request
+	// returns 202 with a job ID and the result is POSTed to this URL
+	WebhookURLHeader = "X-Webhook-URL"
+
+	// WebhookSecretHeader is the HMAC key used to sign webhook deliveries
+	WebhookSecretHeader = "X-Webhook-Secret"
+
+	// defaultJobRetention is how long finished jobs can be retrieved
+	defaultJobRetention = 24 * time.Hour
+
+	// asyncJobTimeout bounds the provider call and webhook delivery of a job
+	asyncJobTimeout = 10 * time.Minute
+)
+
+// AsyncJobResponse is returned with 202 Accepted for asynchronous requests
+type AsyncJobResponse struct {
+	JobID  string      `json:"job_id"`
+	Status jobs.Status `json:"status"`
+}
+
+// handleAsync accepts a chat completion for background processing. The
+// request is translated and pre-flight checked before it is accepted, so
+// invalid requests still fail synchronously.
+func (h *OpenAIHandler) handleAsync(c *gin.Context, provider providers.Provider, providerReq *providers.ProviderRequest, req *translator.ChatCompletionRequest, requestID string) {
+	webhookURL := c.GetHeader(WebhookURLHeader)
+	secret := c.GetHeader(WebhookSecretHeader)
+	if err := jobs.ValidateWebhookURL(webhookURL); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_webhook_url",
+			fmt.Sprintf("Invalid %s: %v", WebhookURLHeader, err))
+		return
+	}
+	if secret == "" {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "missing_webhook_secret",
+			WebhookSecretHeader+" is required with "+WebhookURLHeader)
+		return
+	}
+	if req.Stream {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "streaming_not_supported",
+			"Streaming is not supported for webhook requests")
+		return
+	}
+	if !h.preflight(c, provider, providerReq, req) {
+		return
+	}
+
+	job := &jobs.Job{
+		ID:        "job_" + uuid.New().String(),
+		Status:    jobs.StatusQueued,
+		Model:     req.Model,
+		CreatedAt: time.Now().Unix(),
+	}
+	if err := h.jobs.Create(c.Request.Context(), job); err != nil {
+		log.Printf("Failed to create job: %v", err)
+		respondError(c, http.StatusInternalServerError, "api_error", "internal_error", "Failed to create job")
+		return
+	}
+
+	go h.runJob(job, provider, providerReq, req.Model, requestID, webhookURL, secret)
+
+	respondJSON(c, http.StatusAccepted, AsyncJobResponse{JobID: job.ID, Status: job.Status})
+}
+
+// runJob invokes the provider for an accepted job, stores the outcome and
+// delivers it to the webhook: the ChatCompletionResponse on success, or the
+// error response on failure
+func (h *OpenAIHandler) runJob(job *jobs.Job, provider providers.Provider, providerReq *providers.ProviderRequest, model, requestID, webhookURL, secret string) {
+	ctx, cancel := context.WithTimeout(context.Background(), asyncJobTimeout)
+	defer cancel()
+
+	job.Status = jobs.StatusInProgress
+	h.updateJob(ctx, job)
+
+	providerReq.Context = ctx
+	var payload []byte
+	openaiResp, _, err := invoke(ctx, provider, providerReq, model, requestID)
+	if err != nil {
+		_, errorResp := providerErrorResponse(err)
+		payload, _ = json.Marshal(errorResp)
+		job.Status = jobs.StatusFailed
+		job.Error = payload
+	} else {
+		payload, _ = json.Marshal(openaiResp)
+		job.Status = jobs.StatusCompleted
+		job.Result = payload
+	}
+	job.CompletedAt = time.Now().Unix()
+	h.updateJob(ctx, job)
+
+	if err := h.webhook.Send(ctx, webhookURL, secret, job.ID, payload); err != nil {
+		log.Printf("Webhook delivery for job %s failed: %v", job.ID, err)
+		job.WebhookError = err.Error()
+	} else {
+		job.WebhookDelivered = true
+	}
+	h.updateJob(ctx, job)
+}
+
+func (h *OpenAIHandler) updateJob(ctx context.Context, job *jobs.Job) {
+	if err := h.jobs.Update(ctx, job); err != nil {
+		log.Printf("Failed to update job %s: %v", job.ID, err)
+	}
+}
+
+// GetJob handles GET /v1/jobs/{job_id}, returning the job status and, once
+// finished, its result or error
+func (h *OpenAIHandler) GetJob(c *gin.Context) {
+	id := c.Param("id")
+	job, err := h.jobs.Get(c.Request.Context(), id)
+	if errors.Is(err, jobs.ErrJobNotFound) {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "job_not_found", "No job found with id "+id)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get job %s: %v", id, err)
+		respondError(c, http.StatusInternalServerError, "api_error", "internal_error", "Failed to get job")
+		return
+	}
+	respondJSON(c, http.StatusOK, job)
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/jobs"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// TestRunJobDeliversWebhook tests that a background job stores its result
+// and posts the signed ChatCompletionResponse to the webhook
+func TestRunJobDeliversWebhook(t *testing.T) {
+	delivered := make(chan *http.Request, 1)
+	var deliveredBody []byte
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveredBody, _ = io.ReadAll(r.Body)
+		delivered <- r
+	}))
+	defer webhook.Close()
+
+	h := &OpenAIHandler{
+		jobs:    jobs.NewMemoryStore(time.Hour),
+		webhook: jobs.NewWebhookSender(),
+	}
+	job := &jobs.Job{ID: "job_test", Status: jobs.StatusQueued, Model: "gpt-4o"}
+	if err := h.jobs.Create(t.Context(), job); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	h.runJob(job, &stubProvider{name: "openai"}, &providers.ProviderRequest{}, "gpt-4o", "chatcmpl-test", webhook.URL, "s3cret")
+
+	r := <-delivered
+	if r.Header.Get(jobs.SignatureHeader) != jobs.Sign("s3cret", deliveredBody) || r.Header.Get(jobs.JobIDHeader) != "job_test" {
+		t.Errorf("webhook headers %v", r.Header)
+	}
+	var resp translator.ChatCompletionResponse
+	if err := json.Unmarshal(deliveredBody, &resp); err != nil || len(resp.Choices) != 1 || resp.ID != "chatcmpl-test" {
+		t.Fatalf("webhook body %s: %v", deliveredBody, err)
+	}
+
+	engine := h.Handler()
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/jobs/job_test", nil))
+	var got jobs.Job
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("GetJob body %s: %v", w.Body, err)
+	}
+	if got.Status != jobs.StatusCompleted || !got.WebhookDelivered || !strings.Contains(string(got.Result), "from openai") {
+		t.Errorf("job %+v, want completed and delivered with result", got)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/jobs/job_missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("unknown job: status %d, want 404", w.Code)
+	}
+}
+
+// TestHandleAsyncValidatesWebhook tests that webhook headers are checked
+// before a job is accepted
+func TestHandleAsyncValidatesWebhook(t *testing.T) {
+	h := &OpenAIHandler{jobs: jobs.NewMemoryStore(time.Hour), webhook: jobs.NewWebhookSender()}
+	for _, headers := range []map[string]string{
+		{WebhookURLHeader: "not a url", WebhookSecretHeader: "s"},
+		{WebhookURLHeader: "https://hooks.example.com/cb"},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		for k, v := range headers {
+			c.Request.Header.Set(k, v)
+		}
+		h.handleAsync(c, &stubProvider{name: "openai"}, &providers.ProviderRequest{}, &translator.ChatCompletionRequest{Model: "gpt-4o"}, "chatcmpl-test")
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("headers %v: status %d, want 400", headers, w.Code)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"math"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// acquireSlot takes a concurrency slot for an instance, queueing if it is at
+// max_concurrency. On failure it sets Retry-After and returns the error for
+// the caller to report as 503. Instances without a limit always succeed.
+func acquireSlot(c *gin.Context, semaphores *providers.SemaphoreRegistry, instanceName string) (func(), error) {
+	semaphore := semaphores.Get(instanceName)
+	if semaphore == nil {
+		return func() {}, nil
+	}
+
+	release, err := semaphore.Acquire(c.Request.Context())
+	if err != nil {
+		retryAfter := int(math.Ceil(semaphore.QueueTimeout().Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		return nil, err
+	}
+	return release, nil
+}
+
+// providerSlots are the concurrency limits of provider instances, for the
+// handlers that route requests to a provider type rather than an instance.
+// The zero value has no limits.
+type providerSlots struct {
+	semaphores *providers.SemaphoreRegistry
+	instances  *atomic.Pointer[instance.Config]
+}
+
+// acquire takes a concurrency slot of the instance serving a model on a
+// provider type, as acquireSlot does. Provider types without a serving
+// instance always succeed.
+func (s providerSlots) acquire(c *gin.Context, providerType, model string) (func(), error) {
+	if s.semaphores == nil || s.instances == nil {
+		return func() {}, nil
+	}
+	config := s.instances.Load()
+	if config == nil {
+		return func() {}, nil
+	}
+	_, instanceName, err := servingInstance(config, model, providerType)
+	if err != nil {
+		return func() {}, nil
+	}
+	return acquireSlot(c, s.semaphores, instanceName)
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/anthropic"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/bedrock"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/openai"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/vertex"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+	"github.com/tosharewith/llmproxy_auth/internal/translator/conformance"
+)
+
+// handlerGolden is the golden of a fixture replayed through the handler:
+// the requests the provider received and the response to the client, a
+// JSON body or the data of each stream event
+type handlerGolden struct {
+	Status           int               `json:"status"`
+	ProviderRequests []providerRequest `json:"provider_requests"`
+	Body             interface{}       `json:"body,omitempty"`
+	Events           []interface{}     `json:"events,omitempty"`
+}
+
+type providerRequest struct {
+	Path string      `json:"path"`
+	Body interface{} `json:"body"`
+}
+
+// newConformanceProvider creates the fixture's provider, sending requests
+// to a fake provider at baseURL
+func newConformanceProvider(t *testing.T, fixture conformance.Fixture, baseURL string) providers.Provider {
+	t.Helper()
+	var (
+		provider providers.Provider
+		err      error
+	)
+	switch fixture.Provider {
+	case "openai":
+		provider, err = openai.NewOpenAIProvider(openai.OpenAIConfig{APIKey: "test", BaseURL: baseURL})
+	case "anthropic":
+		provider, err = anthropic.NewAnthropicProvider(anthropic.AnthropicConfig{APIKey: "test", BaseURL: baseURL})
+	case "bedrock":
+		setTestAWSCredentials(t)
+		provider, err = bedrock.NewBedrockProvider(bedrock.BedrockConfig{Region: "us-east-1", Endpoint: baseURL})
+	case "vertex":
+		provider, err = vertex.NewVertexProvider(vertex.VertexConfig{ProjectID: "conformance", AccessToken: "test", Endpoint: baseURL})
+	default:
+		t.Fatalf("unknown provider %q", fixture.Provider)
+	}
+	if err != nil {
+		t.Fatalf("new %s provider: %v", fixture.Provider, err)
+	}
+	return provider
+}
+
+// TestConformanceHandler replays each recorded provider response from a
+// fake provider and checks the chat completion handler's response
+func TestConformanceHandler(t *testing.T) {
+	fixtures, err := conformance.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			server, err := conformance.NewServer(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer server.Close()
+
+			var request struct {
+				Model string `json:"model"`
+			}
+			if err := json.Unmarshal(fixture.Request, &request); err != nil {
+				t.Fatalf("request: %v", err)
+			}
+			config := &router.Config{
+				ModelMappings: map[string]router.ModelMapping{
+					request.Model: {DefaultProvider: fixture.Provider, Providers: map[string]router.ProviderModelInfo{fixture.Provider: {Model: request.Model}}},
+				},
+				Providers: map[string]router.ProviderConfig{fixture.Provider: {Enabled: true}},
+			}
+			r, err := router.NewRouter(config, map[string]providers.Provider{
+				fixture.Provider: newConformanceProvider(t, fixture, server.URL),
+			})
+			if err != nil {
+				t.Fatalf("NewRouter: %v", err)
+			}
+			h := NewOpenAIHandler(r)
+			h.SetRequestIDFactory(RequestIDFactoryFunc(func() string { return "chatcmpl-conformance" }))
+
+			w := postChat(h, string(fixture.Request))
+
+			golden := handlerGolden{Status: w.Code}
+			for _, received := range server.Requests() {
+				body, err := conformance.Normalize(received.Body)
+				if err != nil {
+					t.Fatalf("provider request: %v", err)
+				}
+				golden.ProviderRequests = append(golden.ProviderRequests, providerRequest{Path: received.Path, Body: body})
+			}
+			if strings.HasPrefix(w.Header().Get("Content-Type"), "text/event-stream") {
+				golden.Events = conformanceEvents(t, w)
+			} else if golden.Body, err = conformance.Normalize(w.Body.Bytes()); err != nil {
+				t.Fatalf("response: %v: %s", err, w.Body)
+			}
+
+			if err := conformance.CheckGolden(fixture.GoldenPath("handler"), golden); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// conformanceEvents returns the data of each event of a streamed response,
+// [DONE] as a string
+func conformanceEvents(t *testing.T, w *httptest.ResponseRecorder) []interface{} {
+	t.Helper()
+	var events []interface{}
+	scanner := bufio.NewScanner(bytes.NewReader(w.Body.Bytes()))
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			events = append(events, data)
+			continue
+		}
+		event, err := conformance.Normalize([]byte(data))
+		if err != nil {
+			t.Fatalf("event %q: %v", data, err)
+		}
+		events = append(events, event)
+	}
+	if w.Code != http.StatusOK {
+		t.Errorf("stream status = %d", w.Code)
+	}
+	return events
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// ForwardCorrelation attaches the gateway request ID, and the request tags
+// when tag forwarding is enabled, to an upstream request. Providers that
+// forward request headers pass them on to the upstream.
+func ForwardCorrelation(c *gin.Context, providerReq *providers.ProviderRequest) {
+	if providerReq.Headers == nil {
+		providerReq.Headers = make(http.Header)
+	}
+	if requestID := c.GetString(middleware.RequestIDKey); requestID != "" {
+		providerReq.Headers.Set(providers.RequestIDHeader, requestID)
+	}
+
+	// Only sanitized tags are forwarded, never the client's raw header
+	providerReq.Headers.Del(middleware.RequestTagsHeader)
+	if !c.GetBool(middleware.ForwardRequestTagsKey) {
+		return
+	}
+	if value, exists := c.Get(middleware.RequestTagsKey); exists {
+		providerReq.Headers.Set(middleware.RequestTagsHeader, value.(middleware.RequestTags).String())
+	}
+}
+
+// RecordUpstreamRequestID exposes the provider's request ID, if any, in the
+// X-Upstream-Request-ID response header and the request log. It also
+// reasserts the gateway's X-Request-ID, which a passed-through upstream
+// header may have overwritten.
+func RecordUpstreamRequestID(c *gin.Context, headers http.Header) {
+	if requestID := c.GetString(middleware.RequestIDKey); requestID != "" {
+		c.Header(providers.RequestIDHeader, requestID)
+	}
+
+	upstreamID := providers.UpstreamRequestID(headers)
+	if upstreamID == "" {
+		return
+	}
+	c.Set(middleware.UpstreamRequestIDKey, upstreamID)
+	c.Header("X-Upstream-Request-ID", upstreamID)
+}
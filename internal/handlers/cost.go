@@ -0,0 +1,93 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/pricing"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// Cost headers, returned to callers whose API key has expose_cost
+const (
+	CostHeader             = "X-Proxy-Cost-Usd"
+	PromptTokensHeader     = "X-Proxy-Tokens-Prompt"
+	CompletionTokensHeader = "X-Proxy-Tokens-Completion"
+)
+
+// recordCost records a response's usage for the audit log, prices it into
+// the cost metrics and, if the caller's key exposes cost, sets the cost
+// headers. After a stream's body they are sent as the trailers
+// declareCostTrailers announced.
+func recordCost(c *gin.Context, table *pricing.Table, provider, model string, usage *translator.Usage) {
+	setAuditUsage(c, usage)
+	if table == nil || usage == nil {
+		return
+	}
+	cost := table.Record(provider, model, pricing.Usage{
+		PromptTokens:     usage.PromptTokens,
+		CompletionTokens: usage.CompletionTokens,
+		CacheReadTokens:  usage.CacheReadInputTokens,
+		CacheWriteTokens: usage.CacheWriteInputTokens,
+	})
+	table.Charge(c.GetString("user"), cost)
+	if !table.ExposesCost(c.GetString("user")) {
+		return
+	}
+	header := c.Writer.Header()
+	header.Set(CostHeader, strconv.FormatFloat(cost, 'f', -1, 64))
+	header.Set(PromptTokensHeader, strconv.Itoa(usage.PromptTokens))
+	header.Set(CompletionTokensHeader, strconv.Itoa(usage.CompletionTokens))
+}
+
+// declareCostTrailers announces the cost headers as trailers of a stream,
+// whose usage is only known once it ends. It must be called before the
+// status is written.
+func declareCostTrailers(c *gin.Context, table *pricing.Table) {
+	if table.ExposesCost(c.GetString("user")) {
+		c.Header("Trailer", strings.Join([]string{CostHeader, PromptTokensHeader, CompletionTokensHeader}, ", "))
+	}
+}
+
+// recordStreamCost records the cost of a finished stream, if the provider
+// reported its usage; estimated usage is not priced
+func recordStreamCost(c *gin.Context, table *pricing.Table, provider, model string, tracker *translator.StreamUsageTracker) {
+	if tracker.HasNativeUsage() {
+		usage := tracker.Usage()
+		recordCost(c, table, provider, model, &usage)
+	}
+}
+
+// budgetExhausted checks the budgets of the caller's key and tenant, with
+// the estimated usage of the request for precheck_estimate. When one is
+// exhausted it sets Retry-After to its reset and returns the error message.
+func budgetExhausted(c *gin.Context, table *pricing.Table, provider, model string, estimate pricing.Usage) (string, bool) {
+	status, allowed := table.CheckBudget(c.GetString("user"), provider, model, estimate)
+	if allowed {
+		return "", false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(status.ResetsAt).Seconds()))))
+	return fmt.Sprintf("Spend limit of $%.2f for %s reached ($%.4f spent); resets at %s",
+		status.LimitUSD, status.Subject, status.SpentUSD, status.ResetsAt.UTC().Format(time.RFC3339)), true
+}
+
+// checkBudget rejects the request with 429 budget_exhausted when the
+// caller's key or tenant has reached its budget, estimating the cost of
+// the request from its prompt and max_tokens
+func checkBudget(c *gin.Context, table *pricing.Table, provider string, req *translator.ChatCompletionRequest) bool {
+	estimate := pricing.Usage{PromptTokens: estimatePromptTokens(req), CompletionTokens: req.MaxTokens}
+	message, exhausted := budgetExhausted(c, table, provider, req.Model, estimate)
+	if exhausted {
+		respondError(c, http.StatusTooManyRequests, "insufficient_quota", "budget_exhausted", message)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/pricing"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// TestRecordCost tests that cost headers are only returned to keys with
+// expose_cost
+func TestRecordCost(t *testing.T) {
+	table, err := pricing.New(pricing.Config{
+		Models: map[string]pricing.Price{"gpt-4o": {InputPer1K: 0.002, OutputPer1K: 0.01}},
+		Keys:   map[string]pricing.KeyConfig{"alice": {ExposeCost: true}},
+	})
+	if err != nil {
+		t.Fatalf("pricing.New() error = %v", err)
+	}
+	usage := &translator.Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100}
+
+	for _, tt := range []struct {
+		user, wantCost string
+	}{
+		{"alice", "0.003"},
+		{"bob", ""},
+	} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("user", tt.user)
+		recordCost(c, table, "openai", "gpt-4o", usage)
+
+		header := c.Writer.Header()
+		if got := header.Get(CostHeader); got != tt.wantCost {
+			t.Errorf("%s: %s = %q, want %q", tt.user, CostHeader, got, tt.wantCost)
+		}
+		if tt.wantCost != "" && (header.Get(PromptTokensHeader) != "1000" || header.Get(CompletionTokensHeader) != "100") {
+			t.Errorf("%s: token headers = %q, %q", tt.user, header.Get(PromptTokensHeader), header.Get(CompletionTokensHeader))
+		}
+	}
+}
+
+// TestCheckBudget tests the 429 returned once a key's budget is spent
+func TestCheckBudget(t *testing.T) {
+	table, err := pricing.New(pricing.Config{
+		Models: map[string]pricing.Price{"gpt-4o": {InputPer1K: 0.002, OutputPer1K: 0.01}},
+		Keys:   map[string]pricing.KeyConfig{"alice": {BudgetUSD: 0.005}},
+	})
+	if err != nil {
+		t.Fatalf("pricing.New() error = %v", err)
+	}
+	req := &translator.ChatCompletionRequest{Model: "gpt-4o"}
+	usage := &translator.Usage{PromptTokens: 1000, CompletionTokens: 100, TotalTokens: 1100}
+
+	for i, wantAllowed := range []bool{true, true, false} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		c.Set("user", "alice")
+		if allowed := checkBudget(c, table, "openai", req); allowed != wantAllowed {
+			t.Fatalf("request %d: allowed = %v, want %v", i, allowed, wantAllowed)
+		}
+		if !wantAllowed {
+			if w.Code != http.StatusTooManyRequests || !strings.Contains(w.Body.String(), `"budget_exhausted"`) || w.Header().Get("Retry-After") == "" {
+				t.Errorf("rejection: %d %s, Retry-After %q", w.Code, w.Body, w.Header().Get("Retry-After"))
+			}
+			break
+		}
+		recordCost(c, table, "openai", "gpt-4o", usage)
+	}
+}
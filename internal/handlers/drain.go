@@ -0,0 +1,26 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// DrainState reports whether a provider has been drained, as
+// *router.Router does. Handlers that look providers up themselves, rather
+// than routing through the router, check it before invoking them.
+type DrainState interface {
+	IsDraining(name string) bool
+}
+
+// drainError returns router.ErrProviderDraining for a provider drains has
+// drained, and nil otherwise or when drains is nil
+func drainError(drains DrainState, providerName string) error {
+	if drains == nil || !drains.IsDraining(providerName) {
+		return nil
+	}
+	return fmt.Errorf("provider %q: %w", providerName, router.ErrProviderDraining)
+}
@@ -0,0 +1,381 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// EmbeddingsHandler handles embeddings requests
+type EmbeddingsHandler struct {
+	providers map[string]providers.Provider
+	drains    DrainState    // Optional: drained providers take no new requests
+	slots     providerSlots // Optional: concurrency limits of provider instances
+
+	// concurrency bounds the calls in flight to each provider, across
+	// requests; pools holds a semaphore of that size per provider
+	concurrency int
+	poolsMu     sync.Mutex
+	pools       map[string]chan struct{}
+}
+
+// defaultEmbeddingsConcurrency is the default bound of embeddings calls in
+// flight per provider
+const defaultEmbeddingsConcurrency = 4
+
+// cohereInputTypes are the input_type values Cohere Embed v3 accepts
+var cohereInputTypes = map[string]bool{
+	"search_document": true,
+	"search_query":    true,
+	"classification":  true,
+	"clustering":      true,
+}
+
+// cohereEmbedRequest is the body for Cohere's /v1/embed API; on Bedrock,
+// cohere.embed-* models take the same body without the model
+type cohereEmbedRequest struct {
+	Model     string   `json:"model,omitempty"`
+	Texts     []string `json:"texts"`
+	InputType string   `json:"input_type,omitempty"`
+}
+
+// cohereEmbedResponse covers the native Cohere and Bedrock response shapes
+type cohereEmbedResponse struct {
+	Embeddings []json.RawMessage `json:"embeddings"`
+	Meta       struct {
+		BilledUnits struct {
+			InputTokens int `json:"input_tokens"`
+		} `json:"billed_units"`
+	} `json:"meta"`
+}
+
+// titanEmbedRequest is the InvokeModel body for amazon.titan-embed-* models,
+// which embed one text per call
+type titanEmbedRequest struct {
+	InputText  string `json:"inputText"`
+	Dimensions int    `json:"dimensions,omitempty"`
+}
+
+// titanEmbedResponse is the InvokeModel response of amazon.titan-embed-*
+type titanEmbedResponse struct {
+	Embedding           json.RawMessage `json:"embedding"`
+	InputTextTokenCount int             `json:"inputTextTokenCount"`
+}
+
+// openaiEmbeddingsResponse is the part of an OpenAI embeddings response
+// read to reassemble batches
+type openaiEmbeddingsResponse struct {
+	Data []struct {
+		Index     int             `json:"index"`
+		Embedding json.RawMessage `json:"embedding"`
+	} `json:"data"`
+	Usage translator.Usage `json:"usage"`
+}
+
+// NewEmbeddingsHandler creates a new embeddings handler
+func NewEmbeddingsHandler(providerRegistry map[string]providers.Provider) *EmbeddingsHandler {
+	return &EmbeddingsHandler{
+		providers:   providerRegistry,
+		concurrency: defaultEmbeddingsConcurrency,
+		pools:       make(map[string]chan struct{}),
+	}
+}
+
+// SetConcurrency bounds the embeddings calls in flight to each provider.
+// Values below 1 keep the default. Call it before serving requests.
+func (h *EmbeddingsHandler) SetConcurrency(n int) {
+	if n < 1 {
+		n = defaultEmbeddingsConcurrency
+	}
+	h.concurrency = n
+}
+
+// SetDrainState rejects requests for the models of drained providers with
+// 503
+func (h *EmbeddingsHandler) SetDrainState(drains DrainState) {
+	h.drains = drains
+}
+
+// SetSemaphores enables the concurrency limits of provider instances: a
+// request takes a slot of the instance serving its model, the one GET
+// /v1/route reports
+func (h *EmbeddingsHandler) SetSemaphores(semaphores *providers.SemaphoreRegistry, instances *atomic.Pointer[instance.Config]) {
+	h.slots = providerSlots{semaphores: semaphores, instances: instances}
+}
+
+// Embeddings handles POST /v1/embeddings
+func (h *EmbeddingsHandler) Embeddings(c *gin.Context) {
+	startTime := time.Now()
+
+	var req translator.EmbeddingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "Invalid request body",
+				Type:    "invalid_request_error",
+				Code:    "invalid_json",
+			},
+		})
+		return
+	}
+
+	if req.Model == "" || len(req.Input) == 0 {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "model and input are required",
+				Type:    "invalid_request_error",
+				Code:    "missing_required_field",
+			},
+		})
+		return
+	}
+
+	providerName := embeddingsProviderForModel(req.Model)
+	provider, ok := h.providers[providerName]
+	if !ok {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: fmt.Sprintf("Model %q requires provider %q, which is not configured", req.Model, providerName),
+				Type:    "invalid_request_error",
+				Code:    "model_not_found",
+			},
+		})
+		return
+	}
+	if err := drainError(h.drains, providerName); err != nil {
+		respondError(c, http.StatusServiceUnavailable, "service_error", "provider_draining",
+			fmt.Sprintf("Model %q is not available: %v", req.Model, err))
+		return
+	}
+
+	batches, err := translateEmbeddingsRequest(providerName, &req)
+	if err != nil {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: fmt.Sprintf("Failed to translate request: %v", err),
+				Type:    "invalid_request_error",
+				Code:    "translation_failed",
+			},
+		})
+		return
+	}
+
+	// Wait for a concurrency slot; the request's calls share it
+	release, err := h.slots.acquire(c, providerName, req.Model)
+	if err != nil {
+		log.Printf("Provider %s at capacity: %v", providerName, err)
+		respondError(c, http.StatusServiceUnavailable, "service_error", "queue_timeout",
+			fmt.Sprintf("Provider %s is at capacity, retry later", providerName))
+		return
+	}
+	defer release()
+
+	log.Printf("Routing embeddings model %s to provider %s in %d calls", req.Model, providerName, len(batches))
+	setAuditTarget(c, req.Model, providerName, "")
+
+	var requestBytes int64
+	for _, batch := range batches {
+		ForwardCorrelation(c, batch.request)
+		requestBytes += int64(len(batch.request.Body))
+	}
+	defer recordPayloadSizes(c, providerName, "", requestBytes)
+
+	results, err := h.dispatch(c.Request.Context(), provider, providerName, batches, req.Partial)
+	if err != nil {
+		log.Printf("Provider invocation error: %v", err)
+		writeProviderError(c, err)
+		return
+	}
+	for _, result := range results {
+		if result.err == nil {
+			RecordUpstreamRequestID(c, result.response.Headers)
+			mergeUpstreamHeaders(c.Writer.Header(), result.response.Headers)
+		}
+	}
+
+	duration := time.Since(startTime)
+	metrics.RequestDuration.WithLabelValues("POST", "200").Observe(duration.Seconds())
+	metrics.RequestsTotal.WithLabelValues("POST", "200").Inc()
+
+	if providerName == "openai" && len(results) == 1 {
+		// Already in the OpenAI format
+		c.Data(http.StatusOK, "application/json", results[0].response.Body)
+		return
+	}
+
+	resp, err := mergeEmbeddingsResults(providerName, results, &req)
+	if err != nil {
+		log.Printf("Failed to parse embeddings response: %v", err)
+		respondJSON(c, http.StatusInternalServerError, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "Failed to parse provider response",
+				Type:    "internal_error",
+				Code:    "response_parse_error",
+			},
+		})
+		return
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// embeddingsProviderForModel picks the provider serving an embeddings model.
+// Bedrock model IDs (cohere.embed-*, amazon.titan-embed-*) go to Bedrock,
+// Cohere models (embed-*) to Cohere, mock models (mock-*) to the mock
+// provider, which takes Cohere's format, and everything else to OpenAI.
+func embeddingsProviderForModel(model string) string {
+	switch {
+	case strings.HasPrefix(model, "cohere.embed") || strings.HasPrefix(model, "amazon.titan-embed"):
+		return "bedrock"
+	case strings.HasPrefix(model, "embed-"):
+		return "cohere"
+	case strings.HasPrefix(model, "mock-"):
+		return "mock"
+	default:
+		return "openai"
+	}
+}
+
+// embeddingsBatch is one provider call of an embeddings request, embedding
+// count inputs from input offset
+type embeddingsBatch struct {
+	offset  int
+	count   int
+	request *providers.ProviderRequest
+}
+
+// embeddingsBatchSize returns the most inputs a provider embeds per call:
+// 2048 for OpenAI, 96 for Cohere models and 1 for Titan
+func embeddingsBatchSize(providerName, model string) int {
+	switch {
+	case providerName == "openai":
+		return 2048
+	case strings.HasPrefix(model, "amazon.titan-embed"):
+		return 1
+	default:
+		return 96
+	}
+}
+
+// translateEmbeddingsRequest builds the provider calls for an embeddings
+// request, splitting the input into batches of embeddingsBatchSize inputs.
+// input_type is only sent to Cohere models.
+func translateEmbeddingsRequest(providerName string, req *translator.EmbeddingsRequest) ([]embeddingsBatch, error) {
+	if req.InputType != "" && !cohereInputTypes[req.InputType] {
+		return nil, fmt.Errorf("input_type must be search_document, search_query, classification or clustering")
+	}
+	size := embeddingsBatchSize(providerName, req.Model)
+
+	var batches []embeddingsBatch
+	switch {
+	case providerName == "openai":
+		openaiReq := *req
+		openaiReq.InputType, openaiReq.Partial = "", false
+		inputs, err := openaiEmbeddingsInputs(req.Input)
+		if err != nil {
+			return nil, err
+		}
+		if inputs == nil {
+			// A string or a single token array is one input
+			body, err := json.Marshal(openaiReq)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+			}
+			return []embeddingsBatch{newEmbeddingsBatch(0, 1, "/embeddings", body)}, nil
+		}
+		for offset := 0; offset < len(inputs); offset += size {
+			chunk := inputs[offset:min(offset+size, len(inputs))]
+			if openaiReq.Input, err = json.Marshal(chunk); err != nil {
+				return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+			}
+			body, err := json.Marshal(openaiReq)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+			}
+			batches = append(batches, newEmbeddingsBatch(offset, len(chunk), "/embeddings", body))
+		}
+
+	default:
+		texts, err := req.Texts()
+		if err != nil {
+			return nil, err
+		}
+		if len(texts) == 0 {
+			return nil, fmt.Errorf("input must not be empty")
+		}
+		titan := strings.HasPrefix(req.Model, "amazon.titan-embed")
+		path := "/embed"
+		if providerName == "bedrock" {
+			path = fmt.Sprintf("/model/%s/invoke", req.Model)
+		}
+		for offset := 0; offset < len(texts); offset += size {
+			chunk := texts[offset:min(offset+size, len(texts))]
+			var body []byte
+			if titan {
+				body, err = json.Marshal(titanEmbedRequest{InputText: chunk[0], Dimensions: req.Dimensions})
+			} else {
+				cohereReq := cohereEmbedRequest{Texts: chunk, InputType: req.InputType}
+				if providerName != "bedrock" {
+					cohereReq.Model = req.Model
+				}
+				body, err = json.Marshal(cohereReq)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal embeddings request: %w", err)
+			}
+			batches = append(batches, newEmbeddingsBatch(offset, len(chunk), path, body))
+		}
+	}
+	return batches, nil
+}
+
+// openaiEmbeddingsInputs splits an OpenAI input array into its inputs. It
+// returns nil for a string or a single token array, which are one input.
+func openaiEmbeddingsInputs(input json.RawMessage) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(input)
+	if len(trimmed) == 0 || trimmed[0] != '[' {
+		return nil, nil
+	}
+	var inputs []json.RawMessage
+	if err := json.Unmarshal(trimmed, &inputs); err != nil {
+		return nil, fmt.Errorf("input must be a string, an array of strings or token arrays")
+	}
+	if len(inputs) == 0 {
+		return nil, fmt.Errorf("input must not be empty")
+	}
+	if first := bytes.TrimSpace(inputs[0]); len(first) > 0 && (first[0] == '-' || (first[0] >= '0' && first[0] <= '9')) {
+		return nil, nil
+	}
+	return inputs, nil
+}
+
+// newEmbeddingsBatch builds the provider call of a batch
+func newEmbeddingsBatch(offset, count int, path string, body []byte) embeddingsBatch {
+	return embeddingsBatch{
+		offset: offset,
+		count:  count,
+		request: &providers.ProviderRequest{
+			Method: "POST",
+			Path:   path,
+			Headers: http.Header{
+				"Content-Type": {"application/json"},
+				"Accept":       {"application/json"},
+			},
+			Body: body,
+		},
+	}
+}
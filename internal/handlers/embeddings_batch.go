@@ -0,0 +1,163 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// embeddingsResult is the outcome of one batch
+type embeddingsResult struct {
+	batch    embeddingsBatch
+	response *providers.ProviderResponse
+	err      error
+}
+
+// pool returns the semaphore bounding the calls in flight to a provider
+func (h *EmbeddingsHandler) pool(providerName string) chan struct{} {
+	h.poolsMu.Lock()
+	defer h.poolsMu.Unlock()
+	sem, ok := h.pools[providerName]
+	if !ok {
+		sem = make(chan struct{}, h.concurrency)
+		h.pools[providerName] = sem
+	}
+	return sem
+}
+
+// dispatch invokes the batches concurrently, at most h.concurrency at a
+// time per provider, and returns their results in input order. Without
+// partial, the first failure cancels the batches still running and is
+// returned; with partial, failures are kept in their results and an error
+// is only returned when every batch failed.
+func (h *EmbeddingsHandler) dispatch(ctx context.Context, provider providers.Provider, providerName string, batches []embeddingsBatch, partial bool) ([]embeddingsResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sem := h.pool(providerName)
+	results := make([]embeddingsResult, len(batches))
+	next := make(chan int, len(batches))
+	for i := range batches {
+		next <- i
+	}
+	close(next)
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		failed   int
+		wg       sync.WaitGroup
+	)
+	for w := 0; w < min(h.concurrency, len(batches)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				result := embeddingsResult{batch: batches[i]}
+				select {
+				case sem <- struct{}{}:
+					result.batch.request.Context = ctx
+					result.response, result.err = provider.Invoke(ctx, result.batch.request)
+					<-sem
+				case <-ctx.Done():
+					result.err = ctx.Err()
+				}
+				results[i] = result
+
+				if result.err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = result.err
+					}
+					failed++
+					mu.Unlock()
+					if !partial {
+						cancel()
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if firstErr != nil && (!partial || failed == len(batches)) {
+		return nil, firstErr
+	}
+	return results, nil
+}
+
+// mergeEmbeddingsResults reassembles the batch responses into one OpenAI
+// embeddings response in input order, with the usage of all batches. The
+// inputs of failed batches are listed in its errors.
+func mergeEmbeddingsResults(providerName string, results []embeddingsResult, req *translator.EmbeddingsRequest) (*translator.EmbeddingsResponse, error) {
+	resp := &translator.EmbeddingsResponse{
+		Object: "list",
+		Model:  req.Model,
+	}
+
+	for _, result := range results {
+		if result.err != nil {
+			_, errorResp := providerErrorResponse(result.err)
+			for i := 0; i < result.batch.count; i++ {
+				resp.Errors = append(resp.Errors, translator.EmbeddingError{Index: result.batch.offset + i, Error: errorResp.Error})
+			}
+			continue
+		}
+
+		vectors, tokens, err := parseEmbeddingsBatch(providerName, req.Model, result.response.Body)
+		if err != nil {
+			return nil, err
+		}
+		if len(vectors) != result.batch.count {
+			return nil, fmt.Errorf("provider returned %d embeddings for %d inputs", len(vectors), result.batch.count)
+		}
+		for i, vector := range vectors {
+			resp.Data = append(resp.Data, translator.Embedding{Object: "embedding", Index: result.batch.offset + i, Embedding: vector})
+		}
+		resp.Usage.PromptTokens += tokens
+	}
+	resp.Usage.TotalTokens = resp.Usage.PromptTokens
+	return resp, nil
+}
+
+// parseEmbeddingsBatch returns the embeddings of a batch response in input
+// order and its prompt tokens
+func parseEmbeddingsBatch(providerName, model string, body []byte) ([]json.RawMessage, int, error) {
+	switch {
+	case providerName == "openai":
+		var openaiResp openaiEmbeddingsResponse
+		if err := json.Unmarshal(body, &openaiResp); err != nil {
+			return nil, 0, err
+		}
+		vectors := make([]json.RawMessage, len(openaiResp.Data))
+		for _, data := range openaiResp.Data {
+			if data.Index < 0 || data.Index >= len(vectors) {
+				return nil, 0, fmt.Errorf("embedding index %d out of range", data.Index)
+			}
+			vectors[data.Index] = data.Embedding
+		}
+		return vectors, openaiResp.Usage.PromptTokens, nil
+
+	case strings.HasPrefix(model, "amazon.titan-embed"):
+		var titanResp titanEmbedResponse
+		if err := json.Unmarshal(body, &titanResp); err != nil {
+			return nil, 0, err
+		}
+		return []json.RawMessage{titanResp.Embedding}, titanResp.InputTextTokenCount, nil
+
+	default:
+		var cohereResp cohereEmbedResponse
+		if err := json.Unmarshal(body, &cohereResp); err != nil {
+			return nil, 0, err
+		}
+		return cohereResp.Embeddings, cohereResp.Meta.BilledUnits.InputTokens, nil
+	}
+}
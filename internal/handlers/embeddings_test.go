@@ -0,0 +1,377 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// TestTranslateEmbeddingsRequest tests provider selection and input_type
+// translation
+func TestTranslateEmbeddingsRequest(t *testing.T) {
+	tests := []struct {
+		model     string
+		provider  string
+		path      string
+		requests  int
+		inputType bool
+	}{
+		{"embed-multilingual-v3.0", "cohere", "/embed", 1, true},
+		{"cohere.embed-multilingual-v3", "bedrock", "/model/cohere.embed-multilingual-v3/invoke", 1, true},
+		{"amazon.titan-embed-text-v2:0", "bedrock", "/model/amazon.titan-embed-text-v2:0/invoke", 2, false},
+		{"text-embedding-3-small", "openai", "/embeddings", 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			req := &translator.EmbeddingsRequest{
+				Model:     tt.model,
+				Input:     json.RawMessage(`["hola", "hello"]`),
+				InputType: "search_query",
+			}
+
+			provider := embeddingsProviderForModel(tt.model)
+			if provider != tt.provider {
+				t.Fatalf("provider: got %q, want %q", provider, tt.provider)
+			}
+
+			batches, err := translateEmbeddingsRequest(provider, req)
+			if err != nil {
+				t.Fatalf("translateEmbeddingsRequest: %v", err)
+			}
+			if len(batches) != tt.requests {
+				t.Fatalf("requests: got %d, want %d", len(batches), tt.requests)
+			}
+			if batches[0].request.Path != tt.path {
+				t.Errorf("path: got %q, want %q", batches[0].request.Path, tt.path)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(batches[0].request.Body, &body); err != nil {
+				t.Fatalf("unmarshal body: %v", err)
+			}
+			if got, ok := body["input_type"]; ok != tt.inputType || (ok && got != "search_query") {
+				t.Errorf("input_type: got %v (present %v), want present %v", got, ok, tt.inputType)
+			}
+			if _, ok := body["model"]; ok != (provider != "bedrock") {
+				t.Errorf("model field present: got %v", ok)
+			}
+		})
+	}
+
+	req := &translator.EmbeddingsRequest{Model: "embed-english-v3.0", Input: json.RawMessage(`"x"`), InputType: "ranking"}
+	if _, err := translateEmbeddingsRequest("cohere", req); err == nil {
+		t.Error("unknown input_type was accepted")
+	}
+	req = &translator.EmbeddingsRequest{Model: "embed-english-v3.0", Input: json.RawMessage(`[[1, 2]]`)}
+	if _, err := translateEmbeddingsRequest("cohere", req); err == nil {
+		t.Error("token array input was accepted for Cohere")
+	}
+}
+
+// TestEmbeddingsBatching tests splitting inputs into provider batch sizes
+func TestEmbeddingsBatching(t *testing.T) {
+	tests := []struct {
+		model  string
+		input  string
+		counts []int
+	}{
+		{"embed-english-v3.0", embeddingsInput(200), []int{96, 96, 8}},
+		{"cohere.embed-english-v3", embeddingsInput(96), []int{96}},
+		{"amazon.titan-embed-text-v2:0", embeddingsInput(3), []int{1, 1, 1}},
+		{"text-embedding-3-small", embeddingsInput(2050), []int{2048, 2}},
+		{"text-embedding-3-small", `"one"`, []int{1}},
+		{"text-embedding-3-small", `[1, 2, 3]`, []int{1}},
+		{"text-embedding-3-small", `[[1, 2], [3]]`, []int{2}},
+	}
+
+	for _, tt := range tests {
+		req := &translator.EmbeddingsRequest{Model: tt.model, Input: json.RawMessage(tt.input), Partial: true}
+		batches, err := translateEmbeddingsRequest(embeddingsProviderForModel(tt.model), req)
+		if err != nil {
+			t.Fatalf("%s: translateEmbeddingsRequest: %v", tt.model, err)
+		}
+		var counts []int
+		offset := 0
+		for _, batch := range batches {
+			if batch.offset != offset {
+				t.Errorf("%s: batch offset = %d, want %d", tt.model, batch.offset, offset)
+			}
+			if strings.Contains(string(batch.request.Body), "partial") {
+				t.Errorf("%s: partial sent to the provider: %s", tt.model, batch.request.Body)
+			}
+			counts = append(counts, batch.count)
+			offset += batch.count
+		}
+		if !reflect.DeepEqual(counts, tt.counts) {
+			t.Errorf("%s: batch sizes = %v, want %v", tt.model, counts, tt.counts)
+		}
+	}
+
+	req := &translator.EmbeddingsRequest{Model: "embed-english-v3.0", Input: json.RawMessage(`[]`)}
+	if _, err := translateEmbeddingsRequest("cohere", req); err == nil {
+		t.Error("empty input was accepted")
+	}
+}
+
+// TestMergeEmbeddingsResults tests reassembling Cohere, Titan and OpenAI
+// batches
+func TestMergeEmbeddingsResults(t *testing.T) {
+	ok := func(offset, count int, body string) embeddingsResult {
+		return embeddingsResult{
+			batch:    embeddingsBatch{offset: offset, count: count},
+			response: &providers.ProviderResponse{Body: []byte(body)},
+		}
+	}
+
+	req := &translator.EmbeddingsRequest{Model: "embed-multilingual-v3.0"}
+	resp, err := mergeEmbeddingsResults("cohere", []embeddingsResult{
+		ok(0, 2, `{"embeddings":[[0.1,0.2],[0.3,0.4]],"meta":{"billed_units":{"input_tokens":7}}}`),
+		ok(2, 1, `{"embeddings":[[0.5]],"meta":{"billed_units":{"input_tokens":2}}}`),
+	}, req)
+	if err != nil {
+		t.Fatalf("mergeEmbeddingsResults: %v", err)
+	}
+	if len(resp.Data) != 3 || resp.Data[1].Index != 1 || string(resp.Data[1].Embedding) != "[0.3,0.4]" || resp.Data[2].Index != 2 || resp.Usage.PromptTokens != 9 {
+		t.Errorf("cohere response = %+v", resp)
+	}
+
+	req = &translator.EmbeddingsRequest{Model: "amazon.titan-embed-text-v2:0"}
+	resp, err = mergeEmbeddingsResults("bedrock", []embeddingsResult{
+		ok(0, 1, `{"embedding":[1],"inputTextTokenCount":2}`),
+		ok(1, 1, `{"embedding":[2],"inputTextTokenCount":3}`),
+	}, req)
+	if err != nil {
+		t.Fatalf("mergeEmbeddingsResults: %v", err)
+	}
+	if len(resp.Data) != 2 || string(resp.Data[1].Embedding) != "[2]" || resp.Usage.TotalTokens != 5 || resp.Object != "list" {
+		t.Errorf("titan response = %+v", resp)
+	}
+
+	// OpenAI data may come out of order and base64 encoded
+	req = &translator.EmbeddingsRequest{Model: "text-embedding-3-small"}
+	resp, err = mergeEmbeddingsResults("openai", []embeddingsResult{
+		ok(0, 2, `{"data":[{"index":1,"embedding":"Bw=="},{"index":0,"embedding":"AA=="}],"usage":{"prompt_tokens":4}}`),
+		{batch: embeddingsBatch{offset: 2, count: 2}, err: &providers.ProviderError{Code: providers.ErrCodeRateLimitExceeded, Message: "slow down"}},
+	}, req)
+	if err != nil {
+		t.Fatalf("mergeEmbeddingsResults: %v", err)
+	}
+	if len(resp.Data) != 2 || string(resp.Data[0].Embedding) != `"AA=="` || string(resp.Data[1].Embedding) != `"Bw=="` {
+		t.Errorf("openai data = %+v", resp.Data)
+	}
+	if len(resp.Errors) != 2 || resp.Errors[0].Index != 2 || resp.Errors[1].Index != 3 || resp.Errors[0].Error.Type != "rate_limit_error" {
+		t.Errorf("openai errors = %+v", resp.Errors)
+	}
+
+	req = &translator.EmbeddingsRequest{Model: "embed-multilingual-v3.0"}
+	if _, err := mergeEmbeddingsResults("cohere", []embeddingsResult{ok(0, 2, `{"embeddings":[[0.1]]}`)}, req); err == nil {
+		t.Error("a batch missing embeddings was accepted")
+	}
+}
+
+// newEmbeddingsProvider returns a fake Cohere that embeds each text as the
+// one element array of the text, after delay, and the most batches it had
+// in flight at once. Texts starting with "fail" fail their batch.
+func newEmbeddingsProvider(delay time.Duration) (*stubProvider, *atomic.Int32) {
+	var inFlight, maxInFlight atomic.Int32
+	invoke := func(ctx context.Context, req *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			max := maxInFlight.Load()
+			if n <= max || maxInFlight.CompareAndSwap(max, n) {
+				break
+			}
+		}
+
+		var embedReq cohereEmbedRequest
+		if err := json.Unmarshal(req.Body, &embedReq); err != nil {
+			return nil, err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		resp := cohereEmbedResponse{}
+		for _, text := range embedReq.Texts {
+			if strings.HasPrefix(text, "fail") {
+				return nil, &providers.ProviderError{Code: providers.ErrCodeServiceUnavailable, Message: "unavailable"}
+			}
+			vector, _ := json.Marshal([]string{text})
+			resp.Embeddings = append(resp.Embeddings, vector)
+		}
+		resp.Meta.BilledUnits.InputTokens = len(embedReq.Texts)
+		body, _ := json.Marshal(resp)
+		return &providers.ProviderResponse{StatusCode: http.StatusOK, Body: body}, nil
+	}
+	return &stubProvider{name: "cohere", invoke: invoke}, &maxInFlight
+}
+
+// embeddingsInput returns a JSON array of n texts t0, t1, ...
+func embeddingsInput(n int) string {
+	texts := make([]string, n)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("t%d", i)
+	}
+	input, _ := json.Marshal(texts)
+	return string(input)
+}
+
+// newEmbeddingsTestEngine serves h at /v1/embeddings
+func newEmbeddingsTestEngine(h *EmbeddingsHandler) *gin.Engine {
+	engine := gin.New()
+	engine.POST("/v1/embeddings", h.Embeddings)
+	return engine
+}
+
+func postEmbeddings(engine *gin.Engine, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/embeddings", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// TestEmbeddingsConcurrentBatches tests that batches run concurrently up to
+// the per-provider bound and come back in input order, and that a failed
+// batch fails the request unless it is partial
+func TestEmbeddingsConcurrentBatches(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	provider, maxInFlight := newEmbeddingsProvider(10 * time.Millisecond)
+	h := NewEmbeddingsHandler(map[string]providers.Provider{"cohere": provider})
+	h.SetConcurrency(3)
+	engine := newEmbeddingsTestEngine(h)
+
+	w := postEmbeddings(engine, `{"model":"embed-english-v3.0","input":`+embeddingsInput(1000)+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp translator.EmbeddingsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(resp.Data) != 1000 || resp.Usage.PromptTokens != 1000 {
+		t.Fatalf("got %d embeddings and %d tokens, want 1000", len(resp.Data), resp.Usage.PromptTokens)
+	}
+	for i, data := range resp.Data {
+		if want := fmt.Sprintf(`["t%d"]`, i); data.Index != i || string(data.Embedding) != want {
+			t.Fatalf("data[%d] = %d %s, want %s", i, data.Index, data.Embedding, want)
+		}
+	}
+	if calls := provider.calls.Load(); calls != 11 {
+		t.Errorf("provider calls = %d, want 11", calls)
+	}
+	if max := maxInFlight.Load(); max < 2 || max > 3 {
+		t.Errorf("max calls in flight = %d, want 2 to 3", max)
+	}
+
+	// A failing batch fails the whole request by default
+	texts := make([]string, 200)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("t%d", i)
+	}
+	texts[100] = "fail"
+	input, _ := json.Marshal(texts)
+	w = postEmbeddings(engine, `{"model":"embed-english-v3.0","input":`+string(input)+`}`)
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("failed batch: status = %d, want 503: %s", w.Code, w.Body)
+	}
+
+	// With partial, only the inputs of the failed batch fail
+	w = postEmbeddings(engine, `{"model":"embed-english-v3.0","partial":true,"input":`+string(input)+`}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("partial: status = %d: %s", w.Code, w.Body)
+	}
+	resp = translator.EmbeddingsResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(resp.Data) != 104 || len(resp.Errors) != 96 || resp.Errors[0].Index != 96 || resp.Errors[95].Index != 191 {
+		t.Errorf("partial: %d embeddings and %d errors from %+v", len(resp.Data), len(resp.Errors), resp.Errors[:1])
+	}
+	if resp.Data[96].Index != 192 || resp.Usage.PromptTokens != 104 {
+		t.Errorf("partial: data[96].index = %d, prompt tokens = %d", resp.Data[96].Index, resp.Usage.PromptTokens)
+	}
+}
+
+// TestEmbeddingsDraining tests that requests for the models of a drained
+// provider are rejected without invoking it until it is restored
+func TestEmbeddingsDraining(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	provider, _ := newEmbeddingsProvider(0)
+	registry := map[string]providers.Provider{"cohere": provider}
+	drains := drainedRouter(t, registry, "cohere")
+	h := NewEmbeddingsHandler(registry)
+	h.SetDrainState(drains)
+	engine := newEmbeddingsTestEngine(h)
+
+	w := postEmbeddings(engine, `{"model":"embed-english-v3.0","input":["hi"]}`)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "provider_draining") {
+		t.Errorf("drained: status %d body %s, want 503 provider_draining", w.Code, w.Body)
+	}
+	if calls := provider.calls.Load(); calls != 0 {
+		t.Errorf("the drained provider was invoked %d times", calls)
+	}
+
+	drains.Restore("cohere")
+	if w := postEmbeddings(engine, `{"model":"embed-english-v3.0","input":["hi"]}`); w.Code != http.StatusOK {
+		t.Errorf("restored: status %d body %s, want 200", w.Code, w.Body)
+	}
+}
+
+// TestEmbeddingsQueueTimeout tests that a request waits for a slot of the
+// instance serving its model and gets 503 when none frees up
+func TestEmbeddingsQueueTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	provider, _ := newEmbeddingsProvider(0)
+	h := NewEmbeddingsHandler(map[string]providers.Provider{"cohere": provider})
+	h.SetSemaphores(saturatedInstance(t, "cohere", "cohere-main"))
+
+	w := postEmbeddings(newEmbeddingsTestEngine(h), `{"model":"embed-english-v3.0","input":["hi"]}`)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "queue_timeout") {
+		t.Errorf("status %d body %s, want 503 queue_timeout", w.Code, w.Body)
+	}
+	if calls := provider.calls.Load(); calls != 0 {
+		t.Errorf("the provider was invoked %d times without a slot", calls)
+	}
+}
+
+// BenchmarkEmbeddingsDispatch compares sequential and concurrent dispatch
+// of a 1,000 input request to a provider answering each call in 5ms
+func BenchmarkEmbeddingsDispatch(b *testing.B) {
+	gin.SetMode(gin.TestMode)
+	body := `{"model":"embed-english-v3.0","input":` + embeddingsInput(1000) + `}`
+	for _, concurrency := range []int{1, defaultEmbeddingsConcurrency, 11} {
+		name := fmt.Sprintf("concurrent/%d", concurrency)
+		if concurrency == 1 {
+			name = "sequential"
+		}
+		b.Run(name, func(b *testing.B) {
+			provider, _ := newEmbeddingsProvider(5 * time.Millisecond)
+			h := NewEmbeddingsHandler(map[string]providers.Provider{"cohere": provider})
+			h.SetConcurrency(concurrency)
+			engine := newEmbeddingsTestEngine(h)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if w := postEmbeddings(engine, body); w.Code != http.StatusOK {
+					b.Fatalf("status = %d: %s", w.Code, w.Body)
+				}
+			}
+		})
+	}
+}
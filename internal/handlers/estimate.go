@@ -0,0 +1,82 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// EstimateTokensHeader set to true answers a chat completion with its
+// estimated prompt tokens instead of running it
+const EstimateTokensHeader = "X-Estimate-Tokens"
+
+// Token overheads of the chat format, as counted by OpenAI's tokenizers:
+// each message is wrapped in role markers, and the reply is primed
+const (
+	messageTokenOverhead = 3
+	replyTokenOverhead   = 3
+)
+
+// TokenEstimateResponse answers a chat completion sent with
+// X-Estimate-Tokens. MaxContext is the model's context window, omitted
+// when unknown.
+type TokenEstimateResponse struct {
+	PromptTokens int    `json:"prompt_tokens"`
+	Model        string `json:"model"`
+	MaxContext   int    `json:"max_context,omitempty"`
+	Approximate  bool   `json:"approximate"`
+}
+
+// estimateRequested reports whether the request asks for a token estimate
+func estimateRequested(c *gin.Context) bool {
+	estimate, _ := strconv.ParseBool(c.GetHeader(EstimateTokensHeader))
+	return estimate
+}
+
+// respondTokenEstimate answers with the estimated prompt tokens of a chat
+// request. Nothing is translated or sent to the provider, so the count is
+// the local estimate rather than the provider's tokenizer.
+func respondTokenEstimate(c *gin.Context, provider providers.Provider, modelInfo *router.ProviderModelInfo, req *translator.ChatCompletionRequest) {
+	maxContext := modelInfo.MaxContextTokens
+	if maxContext == 0 {
+		maxContext = providers.CapabilitiesOf(provider).MaxContextTokens
+	}
+	respondJSON(c, http.StatusOK, TokenEstimateResponse{
+		PromptTokens: estimatePromptTokens(req),
+		Model:        req.Model,
+		MaxContext:   maxContext,
+		Approximate:  true,
+	})
+}
+
+// estimatePromptTokens estimates the prompt tokens of the messages and tool
+// definitions of a chat request
+func estimatePromptTokens(req *translator.ChatCompletionRequest) int {
+	tokens := replyTokenOverhead
+	for _, msg := range req.Messages {
+		tokens += messageTokenOverhead + providers.ApproximateTokens(msg.Content.Text())
+		if msg.Name != "" {
+			tokens += providers.ApproximateTokens(msg.Name)
+		}
+		if msg.FunctionCall != nil {
+			tokens += providers.ApproximateTokens(msg.FunctionCall.Name + " " + msg.FunctionCall.Arguments)
+		}
+		for _, call := range msg.ToolCalls {
+			tokens += providers.ApproximateTokens(call.Function.Name + " " + call.Function.Arguments)
+		}
+	}
+	if len(req.Tools) > 0 {
+		if tools, err := json.Marshal(req.Tools); err == nil {
+			tokens += providers.ApproximateTokens(string(tools))
+		}
+	}
+	return tokens
+}
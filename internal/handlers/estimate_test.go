@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// TestChatCompletionsEstimateTokens tests that X-Estimate-Tokens answers
+// with the prompt estimate without invoking the provider
+func TestChatCompletionsEstimateTokens(t *testing.T) {
+	h, stubs := newChatTestHandler(t)
+	body := `{"model":"gpt-4o","messages":[{"role":"system","content":"Be brief"},{"role":"user","content":"hello world"}]}`
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EstimateTokensHeader, "true")
+	w := httptest.NewRecorder()
+	h.Handler().ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if stubs["openai"].lastReq.Load() != nil {
+		t.Error("provider was invoked for a token estimate")
+	}
+	var resp TokenEstimateResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	// 2 messages, 2 + 2 content tokens, and the reply priming
+	want := TokenEstimateResponse{PromptTokens: 13, Model: "gpt-4o", MaxContext: providers.DefaultCapabilities.MaxContextTokens, Approximate: true}
+	if resp != want {
+		t.Errorf("estimate: got %+v, want %+v", resp, want)
+	}
+}
+
+func TestEstimatePromptTokens(t *testing.T) {
+	plain := &translator.ChatCompletionRequest{
+		Messages: []translator.ChatMessage{{Role: "user", Content: translator.TextContent("hello")}},
+	}
+	withCall := &translator.ChatCompletionRequest{
+		Messages: []translator.ChatMessage{
+			{Role: "user", Content: translator.TextContent("hello")},
+			{Role: "assistant", ToolCalls: []translator.ToolCall{{Function: translator.FunctionCall{Name: "lookup", Arguments: `{"q":"x"}`}}}},
+		},
+	}
+	if got := estimatePromptTokens(plain); got != 7 {
+		t.Errorf("plain: got %d, want 7", got)
+	}
+	if got, base := estimatePromptTokens(withCall), estimatePromptTokens(plain); got <= base+messageTokenOverhead {
+		t.Errorf("tool call arguments not counted: got %d, plain %d", got, base)
+	}
+}
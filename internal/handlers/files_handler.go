@@ -0,0 +1,116 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/anthropic"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// defaultFilePurpose is reported for files whose purpose is unknown:
+// Anthropic does not store the purpose an OpenAI client uploads with
+const defaultFilePurpose = "user_data"
+
+// FilesHandler serves the OpenAI-compatible Files API from Anthropic's
+// Files API, for documents referenced across requests
+type FilesHandler struct {
+	provider *anthropic.AnthropicProvider
+}
+
+// NewFilesHandler creates a new files handler
+func NewFilesHandler(provider *anthropic.AnthropicProvider) *FilesHandler {
+	return &FilesHandler{provider: provider}
+}
+
+// UploadFile handles POST /v1/files, a multipart upload with the document
+// in the "file" field and an optional "purpose"
+func (h *FilesHandler) UploadFile(c *gin.Context) {
+	header, err := c.FormFile("file")
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "missing_file",
+			"A multipart upload with a file field is required")
+		return
+	}
+	content, err := header.Open()
+	if err != nil {
+		log.Printf("Failed to open uploaded file %s: %v", header.Filename, err)
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_file", "Failed to read uploaded file")
+		return
+	}
+	defer content.Close()
+
+	contentType := header.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	file, err := h.provider.UploadFile(c.Request.Context(), header.Filename, contentType, content)
+	if err != nil {
+		log.Printf("File upload error: %v", err)
+		writeProviderError(c, err)
+		return
+	}
+
+	log.Printf("Uploaded file %s (%s, %d bytes)", file.ID, file.Filename, file.Size)
+
+	purpose := c.PostForm("purpose")
+	if purpose == "" {
+		purpose = defaultFilePurpose
+	}
+	respondJSON(c, http.StatusOK, toOpenAIFile(file, purpose))
+}
+
+// ListFiles handles GET /v1/files
+func (h *FilesHandler) ListFiles(c *gin.Context) {
+	files, err := h.provider.ListFiles(c.Request.Context())
+	if err != nil {
+		log.Printf("File list error: %v", err)
+		writeProviderError(c, err)
+		return
+	}
+
+	resp := translator.FileList{Object: "list", Data: []translator.File{}}
+	for i := range files {
+		resp.Data = append(resp.Data, toOpenAIFile(&files[i], defaultFilePurpose))
+	}
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// GetFile handles GET /v1/files/{id}
+func (h *FilesHandler) GetFile(c *gin.Context) {
+	file, err := h.provider.GetFile(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeProviderError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, toOpenAIFile(file, defaultFilePurpose))
+}
+
+// DeleteFile handles DELETE /v1/files/{id}
+func (h *FilesHandler) DeleteFile(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.provider.DeleteFile(c.Request.Context(), id); err != nil {
+		writeProviderError(c, err)
+		return
+	}
+
+	log.Printf("Deleted file %s", id)
+	respondJSON(c, http.StatusOK, translator.FileDeleted{ID: id, Object: "file", Deleted: true})
+}
+
+// toOpenAIFile converts an Anthropic file to the OpenAI file object shape
+func toOpenAIFile(file *anthropic.AnthropicFile, purpose string) translator.File {
+	return translator.File{
+		ID:        file.ID,
+		Object:    "file",
+		Bytes:     file.Size,
+		CreatedAt: file.CreatedAt.Unix(),
+		Filename:  file.Filename,
+		Purpose:   purpose,
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/anthropic"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// fakeAnthropicFiles serves the Anthropic Files API endpoints used by FilesHandler
+func fakeAnthropicFiles(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("anthropic-beta") == "" || r.Header.Get("x-api-key") != "test-key" {
+			t.Errorf("%s %s: missing beta or API key header", r.Method, r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/files":
+			file, header, err := r.FormFile("file")
+			if err != nil {
+				t.Errorf("upload: %v", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			content, _ := io.ReadAll(file)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"id": "file_011", "type": "file", "filename": header.Filename,
+				"mime_type": header.Header.Get("Content-Type"), "size_bytes": len(content),
+				"created_at": "2025-05-01T12:00:00Z",
+			})
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files" && r.URL.Query().Get("after_id") == "":
+			io.WriteString(w, `{"data":[{"id":"file_011","type":"file","filename":"a.pdf","size_bytes":10,"created_at":"2025-05-01T12:00:00Z"}],"has_more":true,"last_id":"file_011"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files":
+			io.WriteString(w, `{"data":[{"id":"file_012","type":"file","filename":"b.txt","size_bytes":20,"created_at":"2025-05-01T12:00:00Z"}],"has_more":false,"last_id":"file_012"}`)
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/files/file_011":
+			io.WriteString(w, `{"id":"file_011","type":"file","filename":"a.pdf","size_bytes":10,"created_at":"2025-05-01T12:00:00Z"}`)
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/files/file_011":
+			io.WriteString(w, `{"id":"file_011","type":"file_deleted"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"type":"error","error":{"type":"not_found_error","message":"File not found"}}`)
+		}
+	}))
+}
+
+func newFilesEngine(t *testing.T, baseURL string) *gin.Engine {
+	provider, err := anthropic.NewAnthropicProvider(anthropic.AnthropicConfig{APIKey: "test-key", BaseURL: baseURL + "/v1"})
+	if err != nil {
+		t.Fatalf("NewAnthropicProvider: %v", err)
+	}
+	h := NewFilesHandler(provider)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/v1/files", h.UploadFile)
+	engine.GET("/v1/files", h.ListFiles)
+	engine.GET("/v1/files/:id", h.GetFile)
+	engine.DELETE("/v1/files/:id", h.DeleteFile)
+	return engine
+}
+
+func TestFilesUpload(t *testing.T) {
+	upstream := fakeAnthropicFiles(t)
+	defer upstream.Close()
+	engine := newFilesEngine(t, upstream.URL)
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("purpose", "assistants")
+	part, _ := form.CreateFormFile("file", "report.pdf")
+	part.Write([]byte("%PDF-1.7 document"))
+	form.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var file translator.File
+	if err := json.Unmarshal(w.Body.Bytes(), &file); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	want := translator.File{ID: "file_011", Object: "file", Bytes: 17, CreatedAt: 1746100800, Filename: "report.pdf", Purpose: "assistants"}
+	if file != want {
+		t.Errorf("file = %+v, want %+v", file, want)
+	}
+}
+
+func TestFilesUploadMissingFile(t *testing.T) {
+	engine := newFilesEngine(t, "http://unused")
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/files", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+}
+
+func TestFilesListGetDelete(t *testing.T) {
+	upstream := fakeAnthropicFiles(t)
+	defer upstream.Close()
+	engine := newFilesEngine(t, upstream.URL)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/files", nil))
+	var list translator.FileList
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("list: status %d, %v", w.Code, err)
+	}
+	if list.Object != "list" || len(list.Data) != 2 || list.Data[1].ID != "file_012" {
+		t.Errorf("list = %+v, want both pages", list)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/files/file_011", nil))
+	var file translator.File
+	if err := json.Unmarshal(w.Body.Bytes(), &file); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("get: status %d, %v", w.Code, err)
+	}
+	if file.Filename != "a.pdf" || file.Bytes != 10 {
+		t.Errorf("file = %+v", file)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/v1/files/file_011", nil))
+	var deleted translator.FileDeleted
+	if err := json.Unmarshal(w.Body.Bytes(), &deleted); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("delete: status %d, %v", w.Code, err)
+	}
+	if !deleted.Deleted || deleted.ID != "file_011" {
+		t.Errorf("deleted = %+v", deleted)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/files/file_missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("missing file status = %d, want 404", w.Code)
+	}
+}
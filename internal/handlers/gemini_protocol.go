@@ -0,0 +1,182 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/pricing"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/vertex"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// geminiGenerateContent is the method suffix of a Gemini generateContent path
+const geminiGenerateContent = ":generateContent"
+
+// geminiGenerator sends Gemini generateContent requests
+type geminiGenerator interface {
+	GenerateContent(ctx context.Context, request *providers.ProviderRequest, model string, vertexReq *vertex.VertexGeminiRequest) (*vertex.VertexResponse, http.Header, error)
+}
+
+// GeminiErrorResponse is an error in the Google API format
+type GeminiErrorResponse struct {
+	Error GeminiErrorDetail `json:"error"`
+}
+
+// GeminiErrorDetail describes a Google API error
+type GeminiErrorDetail struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Status  string `json:"status"`
+}
+
+// geminiStatuses are the Google API status names of HTTP status codes
+var geminiStatuses = map[int]string{
+	http.StatusBadRequest:          "INVALID_ARGUMENT",
+	http.StatusUnauthorized:        "UNAUTHENTICATED",
+	http.StatusForbidden:           "PERMISSION_DENIED",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusTooManyRequests:     "RESOURCE_EXHAUSTED",
+	http.StatusNotImplemented:      "UNIMPLEMENTED",
+	http.StatusServiceUnavailable:  "UNAVAILABLE",
+	http.StatusGatewayTimeout:      "DEADLINE_EXCEEDED",
+	http.StatusInternalServerError: "INTERNAL",
+}
+
+// handleGeminiProtocol handles Gemini API requests,
+// POST .../v1beta/models/{model}:generateContent, by sending them to the
+// instance's Vertex AI provider and answering in the Gemini format
+func (h *ProtocolHandler) handleGeminiProtocol(
+	c *gin.Context,
+	provider providers.Provider,
+	instanceCfg *instance.InstanceConfig,
+	instanceName string,
+	startTime time.Time,
+) {
+	model, ok := geminiModel(c.Request.URL.Path)
+	if !ok || c.Request.Method != http.MethodPost {
+		respondGeminiError(c, http.StatusNotImplemented,
+			"Only POST /v1beta/models/{model}:generateContent is supported")
+		return
+	}
+
+	generator, ok := provider.(geminiGenerator)
+	if !ok {
+		respondGeminiError(c, http.StatusNotImplemented,
+			"Provider "+instanceCfg.Type+" does not support the Gemini API")
+		return
+	}
+
+	requestBody := countRequestBody(c)
+	defer func() { recordPayloadSizes(c, instanceCfg.Type, instanceName, requestBody.n) }()
+	setAuditTarget(c, model, instanceCfg.Type, instanceName)
+
+	var req vertex.VertexGeminiRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondGeminiError(c, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(req.Contents) == 0 {
+		respondGeminiError(c, http.StatusBadRequest, "contents is required")
+		return
+	}
+	if message, exhausted := budgetExhausted(c, h.pricing, instanceCfg.Type, model, pricing.Usage{}); exhausted {
+		respondGeminiError(c, http.StatusTooManyRequests, message)
+		return
+	}
+
+	providerReq := &providers.ProviderRequest{
+		Method:  http.MethodPost,
+		Path:    "/publishers/google/models/" + model + geminiGenerateContent,
+		Headers: make(http.Header),
+		Context: c.Request.Context(),
+	}
+	if instanceCfg.Authentication.PassThroughAuth {
+		providerReq.PassThroughAuth = true
+		providerReq.Headers.Set("Authorization", c.GetHeader("Authorization"))
+	}
+	ForwardCorrelation(c, providerReq)
+	applyInstanceRules(providerReq, instanceCfg)
+
+	vertexResp, headers, err := generator.GenerateContent(c.Request.Context(), providerReq, model, &req)
+	h.recordOutcome(instanceName, err)
+	if err != nil {
+		log.Printf("Provider invocation error: %v", err)
+		respondGeminiProviderError(c, err)
+		return
+	}
+	RecordUpstreamRequestID(c, headers)
+	mergeUpstreamHeaders(c.Writer.Header(), headers)
+
+	recordProtocolMetrics(instanceCfg, startTime)
+
+	log.Printf("Protocol request completed: %s (status: 200, duration: %v)", instanceName, time.Since(startTime))
+
+	// Charge token usage to the caller's rate limit bucket
+	if usage := vertexResp.UsageMetadata; usage != nil {
+		c.Set(ratelimit.UsageTokensKey, usage.TotalTokenCount)
+		recordCost(c, h.pricing, instanceCfg.Type, model, &translator.Usage{
+			PromptTokens:     usage.PromptTokenCount,
+			CompletionTokens: usage.CandidatesTokenCount,
+			TotalTokens:      usage.TotalTokenCount,
+		})
+	}
+
+	respondJSON(c, http.StatusOK, vertexResp)
+}
+
+// geminiModel returns the model of a .../models/{model}:generateContent path
+func geminiModel(path string) (string, bool) {
+	i := strings.LastIndex(path, "/models/")
+	if i < 0 || !strings.HasSuffix(path, geminiGenerateContent) {
+		return "", false
+	}
+	model := strings.TrimSuffix(path[i+len("/models/"):], geminiGenerateContent)
+	if model == "" || strings.Contains(model, "/") {
+		return "", false
+	}
+	return model, true
+}
+
+// respondGeminiError writes an error in the Google API format
+func respondGeminiError(c *gin.Context, status int, message string) {
+	googleStatus, ok := geminiStatuses[status]
+	if !ok {
+		googleStatus = "UNKNOWN"
+	}
+	respondJSON(c, status, GeminiErrorResponse{
+		Error: GeminiErrorDetail{Code: status, Message: message, Status: googleStatus},
+	})
+}
+
+// respondGeminiProviderError writes a provider error in the Google API
+// format. Vertex AI errors are already in that format and pass through.
+func respondGeminiProviderError(c *gin.Context, err error) {
+	mergeProviderErrorHeaders(c.Writer.Header(), err)
+	providerErr, ok := err.(*providers.ProviderError)
+	if !ok {
+		respondGeminiError(c, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+	status := providerErr.StatusCode
+	if status == 0 {
+		status = http.StatusInternalServerError
+	}
+
+	var upstream GeminiErrorResponse
+	if json.Unmarshal([]byte(providerErr.Message), &upstream) == nil && upstream.Error.Status != "" {
+		c.Data(status, "application/json", []byte(providerErr.Message))
+		return
+	}
+	respondGeminiError(c, status, providerErr.Message)
+}
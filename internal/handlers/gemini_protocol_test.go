@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/vertex"
+)
+
+// fakeGeminiProvider answers generateContent requests and records them
+type fakeGeminiProvider struct {
+	stubProvider
+	model string
+	got   *vertex.VertexGeminiRequest
+}
+
+func (p *fakeGeminiProvider) GenerateContent(ctx context.Context, request *providers.ProviderRequest, model string, vertexReq *vertex.VertexGeminiRequest) (*vertex.VertexResponse, http.Header, error) {
+	p.model, p.got = model, vertexReq
+	if p.err != nil {
+		return nil, nil, p.err
+	}
+	return &vertex.VertexResponse{
+		Candidates: []vertex.VertexCandidate{{
+			Content:      vertex.VertexContent{Role: "model", Parts: []vertex.VertexPart{{Text: "Hello!"}}},
+			FinishReason: "STOP",
+		}},
+		UsageMetadata: &vertex.VertexUsageMetadata{PromptTokenCount: 3, CandidatesTokenCount: 2, TotalTokenCount: 5},
+	}, http.Header{}, nil
+}
+
+func newGeminiTestHandler(t *testing.T, provider providers.Provider) *gin.Engine {
+	t.Helper()
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"vertex-gemini": {
+				Type:      "vertex",
+				Mode:      "protocol",
+				Protocol:  "gemini",
+				Endpoints: []instance.EndpointConfig{{Path: "/gemini/vertex"}},
+			},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	h := NewProtocolHandler(map[string]providers.Provider{"vertex": provider}, currentConfig(config), nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/gemini/*path", h.HandleRequest)
+	return engine
+}
+
+func postGemini(engine *gin.Engine, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// TestGeminiProtocol tests that generateContent requests reach the provider
+// and are answered in the Gemini format
+func TestGeminiProtocol(t *testing.T) {
+	provider := &fakeGeminiProvider{stubProvider: stubProvider{name: "vertex"}}
+	engine := newGeminiTestHandler(t, provider)
+
+	w := postGemini(engine, "/gemini/vertex/v1beta/models/gemini-1.5-pro:generateContent",
+		`{"contents":[{"role":"user","parts":[{"text":"Hi"}]}],"generationConfig":{"temperature":0.2}}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d, body %s", w.Code, w.Body)
+	}
+	var resp vertex.VertexResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(resp.Candidates) != 1 || resp.Candidates[0].Content.Parts[0].Text != "Hello!" ||
+		resp.UsageMetadata == nil || resp.UsageMetadata.TotalTokenCount != 5 {
+		t.Errorf("unexpected response: %s", w.Body)
+	}
+	if provider.model != "gemini-1.5-pro" {
+		t.Errorf("model: got %q", provider.model)
+	}
+	if provider.got.GenerationConfig == nil || *provider.got.GenerationConfig.Temperature != 0.2 {
+		t.Errorf("generationConfig not forwarded: %+v", provider.got.GenerationConfig)
+	}
+
+	w = postGemini(engine, "/gemini/vertex/v1beta/models/gemini-1.5-pro:streamGenerateContent", `{}`)
+	if w.Code != http.StatusNotImplemented || !strings.Contains(w.Body.String(), "UNIMPLEMENTED") {
+		t.Errorf("unsupported method: status %d body %s", w.Code, w.Body)
+	}
+
+	w = postGemini(engine, "/gemini/vertex/v1beta/models/gemini-1.5-pro:generateContent", `{"contents":[]}`)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "INVALID_ARGUMENT") {
+		t.Errorf("empty contents: status %d body %s", w.Code, w.Body)
+	}
+}
+
+// TestGeminiProtocolErrors tests that provider errors are answered in the
+// Google API format
+func TestGeminiProtocolErrors(t *testing.T) {
+	upstream := `{"error":{"code":429,"message":"Quota exceeded","status":"RESOURCE_EXHAUSTED"}}`
+	provider := &fakeGeminiProvider{stubProvider: stubProvider{
+		name: "vertex",
+		err:  &providers.ProviderError{StatusCode: http.StatusTooManyRequests, Message: upstream, Provider: "vertex"},
+	}}
+	engine := newGeminiTestHandler(t, provider)
+	body := `{"contents":[{"role":"user","parts":[{"text":"Hi"}]}]}`
+
+	w := postGemini(engine, "/gemini/vertex/v1beta/models/gemini-1.5-pro:generateContent", body)
+	if w.Code != http.StatusTooManyRequests || w.Body.String() != upstream {
+		t.Errorf("upstream error: status %d body %s", w.Code, w.Body)
+	}
+
+	provider.err = &providers.ProviderError{StatusCode: http.StatusServiceUnavailable, Message: "request failed", Provider: "vertex"}
+	w = postGemini(engine, "/gemini/vertex/v1beta/models/gemini-1.5-pro:generateContent", body)
+	var resp GeminiErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Error.Code != http.StatusServiceUnavailable || resp.Error.Status != "UNAVAILABLE" {
+		t.Errorf("transport error: got %+v", resp.Error)
+	}
+}
+
+// TestValidateGemini tests that the Gemini protocol requires a Vertex instance
+func TestValidateGemini(t *testing.T) {
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai-gemini": {Type: "openai", Mode: "protocol", Protocol: "gemini"},
+		},
+	}
+	if err := config.Validate(); err == nil {
+		t.Error("expected error for gemini protocol on an openai instance")
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// stubProvider is the provider the handler tests stand in for upstreams. It
+// counts calls and records the last request. Invoke fails with err when set,
+// then answers with invoke, or by default with an OpenAI chat completion
+// naming the provider; InvokeStreaming answers with invokeStreaming and
+// fails without it.
+type stubProvider struct {
+	name            string
+	err             error
+	invoke          func(ctx context.Context, req *providers.ProviderRequest) (*providers.ProviderResponse, error)
+	invokeStreaming func(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error)
+
+	calls   atomic.Int32
+	lastReq atomic.Pointer[providers.ProviderRequest]
+}
+
+func (p *stubProvider) Name() string                          { return p.name }
+func (p *stubProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (p *stubProvider) Invoke(ctx context.Context, req *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	p.calls.Add(1)
+	p.lastReq.Store(req)
+	if p.err != nil {
+		return nil, p.err
+	}
+	if p.invoke != nil {
+		return p.invoke(ctx, req)
+	}
+	body := `{"id":"x","object":"chat.completion","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"from ` + p.name + `"},"finish_reason":"stop"}]}`
+	return &providers.ProviderResponse{StatusCode: http.StatusOK, Body: []byte(body)}, nil
+}
+
+func (p *stubProvider) InvokeStreaming(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+	p.calls.Add(1)
+	p.lastReq.Store(req)
+	if p.invokeStreaming == nil {
+		return nil, errors.New("not implemented")
+	}
+	return p.invokeStreaming(ctx, req)
+}
+
+func (p *stubProvider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return nil, errors.New("not found")
+}
+
+// respondWith returns an invoke answering 200 with body and headers
+func respondWith(body string, headers http.Header) func(context.Context, *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	return func(ctx context.Context, req *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+		return &providers.ProviderResponse{StatusCode: http.StatusOK, Headers: headers, Body: []byte(body)}, nil
+	}
+}
+
+// streamOf returns an invokeStreaming streaming body
+func streamOf(body string) func(context.Context, *providers.ProviderRequest) (io.ReadCloser, error) {
+	return func(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(body)), nil
+	}
+}
+
+// currentConfig holds config as the config in effect, as the gateway
+// passes it to the instance handlers
+func currentConfig(config *instance.Config) *atomic.Pointer[instance.Config] {
+	current := new(atomic.Pointer[instance.Config])
+	current.Store(config)
+	return current
+}
+
+// drainedRouter returns a router over registry with the named providers
+// drained, for the handlers that check drain state themselves
+func drainedRouter(t *testing.T, registry map[string]providers.Provider, drained ...string) *router.Router {
+	t.Helper()
+	config := &router.Config{Providers: make(map[string]router.ProviderConfig)}
+	for name := range registry {
+		config.Providers[name] = router.ProviderConfig{Enabled: true}
+	}
+	r, err := router.NewRouter(config, registry)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	for _, name := range drained {
+		if !r.Drain(name) {
+			t.Fatalf("Drain(%q): not registered", name)
+		}
+	}
+	return r
+}
+
+// saturatedInstance returns a config serving a provider type from one
+// instance, and semaphores limiting that instance to one request in flight
+// whose slot is taken until the test ends
+func saturatedInstance(t *testing.T, providerType, instanceName string) (*providers.SemaphoreRegistry, *atomic.Pointer[instance.Config]) {
+	t.Helper()
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{instanceName: {Type: providerType, Mode: "protocol"}},
+		Routing:   instance.RoutingConfig{Defaults: map[string]string{providerType: instanceName}},
+	}
+	semaphore := providers.NewProviderSemaphore(providerType, instanceName, 1, 10*time.Millisecond, ratelimit.ModeEnforce)
+	semaphores := providers.NewSemaphoreRegistry()
+	semaphores.Register(instanceName, semaphore)
+	release, err := semaphore.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	t.Cleanup(release)
+	return semaphores, currentConfig(config)
+}
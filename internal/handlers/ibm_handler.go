@@ -0,0 +1,309 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/ibm"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// watsonx.ai's own extraction and classification services work on uploaded
+// documents and run as asynchronous jobs, so text extraction and
+// classification are run as greedy text generation tasks instead.
+
+// ibmDefaultTaskModel is used when an extract or classify request names no
+// model
+const ibmDefaultTaskModel = "ibm/granite-13b-instruct-v2"
+
+// ibmExtractMaxTokens and ibmClassifyMaxTokens bound the generated answers
+const (
+	ibmExtractMaxTokens  = 500
+	ibmClassifyMaxTokens = 20
+)
+
+// ibmGenerator sends watsonx.ai text generation requests
+type ibmGenerator interface {
+	Generate(ctx context.Context, request *providers.ProviderRequest, ibmReq *ibm.IBMRequest) (*ibm.IBMResponse, http.Header, error)
+}
+
+// IBMExtractRequest asks for named fields to be extracted from a text
+type IBMExtractRequest struct {
+	Model  string   `json:"model,omitempty"`
+	Input  string   `json:"input"`
+	Fields []string `json:"fields"`
+}
+
+// IBMExtractResponse holds the extracted fields; fields not found in the
+// text are null
+type IBMExtractResponse struct {
+	Model  string            `json:"model"`
+	Fields map[string]any    `json:"fields"`
+	Usage  *translator.Usage `json:"usage,omitempty"`
+}
+
+// IBMClassifyRequest asks for a text to be given one of the labels
+type IBMClassifyRequest struct {
+	Model  string   `json:"model,omitempty"`
+	Input  string   `json:"input"`
+	Labels []string `json:"labels"`
+}
+
+// IBMClassifyResponse is the chosen label. Confidence is the probability
+// the model gave its answer, or 0 if watsonx.ai returned no token logprobs.
+type IBMClassifyResponse struct {
+	Label      string  `json:"label"`
+	Confidence float64 `json:"confidence"`
+}
+
+// IBMExtractHandler handles POST /providers/ibm/extract
+type IBMExtractHandler struct {
+	generator ibmGenerator
+}
+
+// IBMClassifyHandler handles POST /providers/ibm/classify
+type IBMClassifyHandler struct {
+	generator ibmGenerator
+}
+
+// NewIBMExtractHandler creates an extract handler for the IBM provider
+func NewIBMExtractHandler(provider providers.Provider) *IBMExtractHandler {
+	generator, _ := provider.(ibmGenerator)
+	return &IBMExtractHandler{generator: generator}
+}
+
+// NewIBMClassifyHandler creates a classify handler for the IBM provider
+func NewIBMClassifyHandler(provider providers.Provider) *IBMClassifyHandler {
+	generator, _ := provider.(ibmGenerator)
+	return &IBMClassifyHandler{generator: generator}
+}
+
+// Extract handles POST /providers/ibm/extract
+func (h *IBMExtractHandler) Extract(c *gin.Context) {
+	startTime := time.Now()
+
+	var req IBMExtractRequest
+	if !bindIBMTask(c, &req) {
+		return
+	}
+	if req.Input == "" || len(req.Fields) == 0 {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "missing_required_field",
+			"input and fields are required")
+		return
+	}
+	if req.Model == "" {
+		req.Model = ibmDefaultTaskModel
+	}
+
+	maxTokens := ibmExtractMaxTokens
+	ibmResp, ok := generateIBMTask(c, h.generator, &ibm.IBMRequest{
+		ModelID: req.Model,
+		Input:   extractPrompt(req.Input, req.Fields),
+		Parameters: &ibm.IBMParameters{
+			DecodingMethod: "greedy",
+			MaxNewTokens:   &maxTokens,
+		},
+	})
+	if !ok {
+		return
+	}
+
+	result := ibmResp.Results[0]
+	fields, err := parseExtractedFields(result.GeneratedText, req.Fields)
+	if err != nil {
+		log.Printf("Failed to parse IBM extraction: %v", err)
+		respondError(c, http.StatusBadGateway, "provider_error", "response_parse_error",
+			"Model did not answer with a JSON object")
+		return
+	}
+
+	recordIBMTask(startTime)
+	respondJSON(c, http.StatusOK, IBMExtractResponse{
+		Model:  req.Model,
+		Fields: fields,
+		Usage: &translator.Usage{
+			PromptTokens:     result.InputTokens,
+			CompletionTokens: result.GeneratedTokens,
+			TotalTokens:      result.InputTokens + result.GeneratedTokens,
+		},
+	})
+}
+
+// Classify handles POST /providers/ibm/classify
+func (h *IBMClassifyHandler) Classify(c *gin.Context) {
+	startTime := time.Now()
+
+	var req IBMClassifyRequest
+	if !bindIBMTask(c, &req) {
+		return
+	}
+	if req.Input == "" || len(req.Labels) == 0 {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "missing_required_field",
+			"input and labels are required")
+		return
+	}
+	if req.Model == "" {
+		req.Model = ibmDefaultTaskModel
+	}
+
+	maxTokens := ibmClassifyMaxTokens
+	ibmResp, ok := generateIBMTask(c, h.generator, &ibm.IBMRequest{
+		ModelID: req.Model,
+		Input:   classifyPrompt(req.Input, req.Labels),
+		Parameters: &ibm.IBMParameters{
+			DecodingMethod: "greedy",
+			MaxNewTokens:   &maxTokens,
+			StopSequences:  []string{"\n"},
+			ReturnOptions: &ibm.IBMReturnOptions{
+				GeneratedTokens: true,
+				TokenLogprobs:   true,
+			},
+		},
+	})
+	if !ok {
+		return
+	}
+
+	result := ibmResp.Results[0]
+	label, ok := matchLabel(result.GeneratedText, req.Labels)
+	if !ok {
+		log.Printf("IBM classification %q matches none of %v", result.GeneratedText, req.Labels)
+		respondError(c, http.StatusBadGateway, "provider_error", "unrecognised_label",
+			"Model did not answer with one of the labels")
+		return
+	}
+
+	recordIBMTask(startTime)
+	respondJSON(c, http.StatusOK, IBMClassifyResponse{
+		Label:      label,
+		Confidence: tokenConfidence(result.Tokens),
+	})
+}
+
+// bindIBMTask decodes a task request, responding with an error on failure
+func bindIBMTask(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_json",
+			"Invalid request body")
+		return false
+	}
+	return true
+}
+
+// generateIBMTask sends a task prompt to watsonx.ai. It responds with an
+// error and returns false if the request fails or yields no result.
+func generateIBMTask(c *gin.Context, generator ibmGenerator, ibmReq *ibm.IBMRequest) (*ibm.IBMResponse, bool) {
+	if generator == nil {
+		respondError(c, http.StatusNotImplemented, "not_implemented_error", "not_supported",
+			"The IBM provider does not support text generation tasks")
+		return nil, false
+	}
+
+	providerReq := &providers.ProviderRequest{
+		Method:  http.MethodPost,
+		Path:    "/ml/v1/text/generation",
+		Headers: make(http.Header),
+		Context: c.Request.Context(),
+	}
+	ForwardCorrelation(c, providerReq)
+
+	ibmResp, headers, err := generator.Generate(c.Request.Context(), providerReq, ibmReq)
+	if err != nil {
+		log.Printf("Provider invocation error: %v", err)
+		writeProviderError(c, err)
+		return nil, false
+	}
+	RecordUpstreamRequestID(c, headers)
+	mergeUpstreamHeaders(c.Writer.Header(), headers)
+
+	if len(ibmResp.Results) == 0 {
+		respondError(c, http.StatusBadGateway, "provider_error", "empty_response",
+			"Provider returned no results")
+		return nil, false
+	}
+	return ibmResp, true
+}
+
+func recordIBMTask(startTime time.Time) {
+	metrics.RequestDuration.WithLabelValues("POST", "200").Observe(time.Since(startTime).Seconds())
+	metrics.RequestsTotal.WithLabelValues("POST", "200").Inc()
+}
+
+// extractPrompt asks for the fields as a JSON object
+func extractPrompt(input string, fields []string) string {
+	return fmt.Sprintf("Extract these fields from the text: %s.\n"+
+		"Answer with a single JSON object whose keys are the field names and whose values are the extracted text. "+
+		"Use null for fields the text does not contain.\n\nText:\n%s\n\nJSON:",
+		strings.Join(fields, ", "), input)
+}
+
+// classifyPrompt asks for exactly one of the labels
+func classifyPrompt(input string, labels []string) string {
+	return fmt.Sprintf("Classify the text as one of these labels: %s.\n"+
+		"Answer with the label only.\n\nText:\n%s\n\nLabel:",
+		strings.Join(labels, ", "), input)
+}
+
+// parseExtractedFields reads the JSON object in a generated answer and
+// returns the requested fields, null where the object lacks them
+func parseExtractedFields(text string, fields []string) (map[string]any, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("no JSON object in %q", text)
+	}
+	var extracted map[string]any
+	if err := json.Unmarshal([]byte(text[start:end+1]), &extracted); err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]any, len(fields))
+	for _, field := range fields {
+		result[field] = extracted[field]
+	}
+	return result, nil
+}
+
+// matchLabel returns the label a generated answer names: an exact match,
+// ignoring case, surrounding space and trailing punctuation, or else the
+// longest label the answer starts with
+func matchLabel(text string, labels []string) (string, bool) {
+	answer := strings.ToLower(strings.Trim(strings.TrimSpace(text), ".\"'"))
+	for _, label := range labels {
+		if strings.ToLower(label) == answer {
+			return label, true
+		}
+	}
+
+	best := ""
+	for _, label := range labels {
+		if strings.HasPrefix(answer, strings.ToLower(label)) && len(label) > len(best) {
+			best = label
+		}
+	}
+	return best, best != ""
+}
+
+// tokenConfidence is the probability of a generated token sequence
+func tokenConfidence(tokens []ibm.IBMToken) float64 {
+	if len(tokens) == 0 {
+		return 0
+	}
+	var logprob float64
+	for _, token := range tokens {
+		logprob += token.Logprob
+	}
+	return math.Min(1, math.Exp(logprob))
+}
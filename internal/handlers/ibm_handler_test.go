@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/ibm"
+)
+
+// fakeIBMGenerator returns a fixed generation result and records the request
+type fakeIBMGenerator struct {
+	result ibm.IBMResult
+	got    *ibm.IBMRequest
+}
+
+func (g *fakeIBMGenerator) Generate(ctx context.Context, request *providers.ProviderRequest, ibmReq *ibm.IBMRequest) (*ibm.IBMResponse, http.Header, error) {
+	g.got = ibmReq
+	return &ibm.IBMResponse{Results: []ibm.IBMResult{g.result}}, http.Header{}, nil
+}
+
+func postIBMTask(handler gin.HandlerFunc, body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/providers/ibm/task", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+	return w
+}
+
+// TestIBMClassify tests label matching and confidence from token logprobs
+func TestIBMClassify(t *testing.T) {
+	generator := &fakeIBMGenerator{result: ibm.IBMResult{
+		GeneratedText: " Negative.",
+		Tokens:        []ibm.IBMToken{{Text: "Neg", Logprob: -0.1}, {Text: "ative", Logprob: -0.05}},
+	}}
+	h := &IBMClassifyHandler{generator: generator}
+
+	w := postIBMTask(h.Classify, `{"input":"This is awful","labels":["positive","negative"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d, body %s", w.Code, w.Body.String())
+	}
+	var resp IBMClassifyResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Label != "negative" {
+		t.Errorf("label: got %q, want negative", resp.Label)
+	}
+	if want := math.Exp(-0.15); math.Abs(resp.Confidence-want) > 1e-9 {
+		t.Errorf("confidence: got %v, want %v", resp.Confidence, want)
+	}
+
+	params := generator.got.Parameters
+	if generator.got.ModelID != ibmDefaultTaskModel || params.DecodingMethod != "greedy" ||
+		params.ReturnOptions == nil || !params.ReturnOptions.TokenLogprobs {
+		t.Errorf("unexpected generation request: %+v %+v", generator.got, params)
+	}
+	if !strings.Contains(generator.got.Input, "positive, negative") {
+		t.Errorf("prompt does not list the labels: %q", generator.got.Input)
+	}
+
+	generator.result = ibm.IBMResult{GeneratedText: "neutral"}
+	w = postIBMTask(h.Classify, `{"input":"ok","labels":["positive","negative"]}`)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("unknown label status: got %d, want 502", w.Code)
+	}
+
+	w = postIBMTask(h.Classify, `{"input":"ok"}`)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("missing labels status: got %d, want 400", w.Code)
+	}
+}
+
+// TestIBMExtract tests that requested fields are read from the answer
+func TestIBMExtract(t *testing.T) {
+	generator := &fakeIBMGenerator{result: ibm.IBMResult{
+		GeneratedText:   "Here it is: {\"name\": \"Ada\", \"city\": \"London\", \"extra\": 1}",
+		InputTokens:     40,
+		GeneratedTokens: 12,
+	}}
+	h := &IBMExtractHandler{generator: generator}
+
+	w := postIBMTask(h.Extract, `{"model":"ibm/granite-13b-chat-v2","input":"Ada lives in London","fields":["name","city","email"]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d, body %s", w.Code, w.Body.String())
+	}
+	var resp IBMExtractResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Fields["name"] != "Ada" || resp.Fields["city"] != "London" {
+		t.Errorf("fields: got %v", resp.Fields)
+	}
+	if v, ok := resp.Fields["email"]; !ok || v != nil {
+		t.Errorf("missing field should be null, got %v (present %v)", v, ok)
+	}
+	if _, ok := resp.Fields["extra"]; ok {
+		t.Errorf("unrequested field returned: %v", resp.Fields)
+	}
+	if resp.Usage == nil || resp.Usage.TotalTokens != 52 {
+		t.Errorf("usage: got %+v", resp.Usage)
+	}
+	if generator.got.ModelID != "ibm/granite-13b-chat-v2" {
+		t.Errorf("model: got %q", generator.got.ModelID)
+	}
+
+	generator.result = ibm.IBMResult{GeneratedText: "no idea"}
+	w = postIBMTask(h.Extract, `{"input":"x","fields":["name"]}`)
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("unparseable answer status: got %d, want 502", w.Code)
+	}
+}
+
+// TestMatchLabel tests exact and prefix label matching
+func TestMatchLabel(t *testing.T) {
+	labels := []string{"bug", "bug report", "feature"}
+	tests := []struct {
+		text  string
+		label string
+		ok    bool
+	}{
+		{"Feature", "feature", true},
+		{" \"bug\" ", "bug", true},
+		{"bug report, clearly", "bug report", true},
+		{"question", "", false},
+	}
+	for _, tt := range tests {
+		label, ok := matchLabel(tt.text, labels)
+		if label != tt.label || ok != tt.ok {
+			t.Errorf("matchLabel(%q): got %q %v, want %q %v", tt.text, label, ok, tt.label, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,25 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"log"
+
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// fitImages downscales the request's inline images to the instance's image
+// limits, logging each resize
+func fitImages(req *translator.ChatCompletionRequest, limits translator.ImageLimits, providerType string) (*translator.ChatCompletionRequest, error) {
+	req, resizes, err := limits.Apply(req)
+	if err != nil {
+		return nil, err
+	}
+	for _, resize := range resizes {
+		log.Printf("Downscaled image for %s: %dx%d %d bytes -> %dx%d %d bytes", providerType,
+			resize.OriginalWidth, resize.OriginalHeight, resize.OriginalBytes,
+			resize.Width, resize.Height, resize.Bytes)
+	}
+	return req, nil
+}
@@ -0,0 +1,68 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// applyInstanceRules rewrites the provider path and injects the configured
+// request headers of an instance. It runs after endpoint matching and before
+// the provider is invoked.
+func applyInstanceRules(providerReq *providers.ProviderRequest, instanceCfg *instance.InstanceConfig) {
+	providerReq.Path = instanceCfg.PathRewrite.Apply(providerReq.Path)
+
+	if len(instanceCfg.RequestHeaders) == 0 {
+		return
+	}
+	if providerReq.Headers == nil {
+		providerReq.Headers = make(http.Header)
+	}
+	for name, value := range instanceCfg.RequestHeaders {
+		providerReq.Headers.Set(name, value)
+	}
+}
+
+// forwardClientHeaders copies the client headers in an instance's
+// forward_headers to the provider request. Client authentication headers
+// are never forwarded, and headers the gateway has already set win.
+func forwardClientHeaders(c *gin.Context, providerReq *providers.ProviderRequest, instanceCfg *instance.InstanceConfig) {
+	if len(instanceCfg.ForwardHeaders) == 0 {
+		return
+	}
+	if providerReq.Headers == nil {
+		providerReq.Headers = make(http.Header)
+	}
+	for name, values := range c.Request.Header {
+		if isAuthHeader(name) || !instanceCfg.ForwardsHeader(name) || providerReq.Headers.Get(name) != "" {
+			continue
+		}
+		providerReq.Headers[name] = append([]string(nil), values...)
+	}
+}
+
+// applyResponseHeaders sets the configured response headers of an instance
+func applyResponseHeaders(c *gin.Context, instanceCfg *instance.InstanceConfig) {
+	for name, value := range instanceCfg.ResponseHeaders {
+		c.Header(name, value)
+	}
+}
+
+// featureDisabled reports whether a feature flag turns a request to an
+// instance off for the caller, responding 404 if so. Features that are not
+// configured do not gate requests: the routes they guard are only
+// registered when they are.
+func featureDisabled(c *gin.Context, config *instance.Config, feature, instanceName string) bool {
+	if !config.HasFeature(feature) || config.IsFeatureEnabled(feature, instanceName, c.GetString("user")) {
+		return false
+	}
+	respondError(c, http.StatusNotFound, "invalid_request_error", "feature_disabled",
+		fmt.Sprintf("%s is not enabled for this instance", feature))
+	return true
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// LegacyModelValidation checks the model ID in legacy Bedrock route paths
+// (/model/{id}/invoke under /model, /model/{id}/... under /bedrock and
+// /v1/bedrock) against the router config before the request is forwarded.
+// Aliases are rewritten to their Bedrock model IDs in the path param; unknown
+// models are rejected with 404 and denied models with 403. Routes whose
+// prefix is listed in legacy_routes.passthrough, and all routes when
+// legacy_routes.validate_models is off, are forwarded unchanged. The
+// config is read from getConfig on each request, so reloads apply.
+func LegacyModelValidation(getConfig func() *router.Config, prefix string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		config := getConfig()
+		if config == nil || config.LegacyRoutes.IsPassthrough(prefix) {
+			c.Next()
+			return
+		}
+
+		path := c.Param("path")
+		modelPrefix, model, rest := splitLegacyModelPath(prefix, path)
+		if model == "" {
+			// Not a model path (e.g. /bedrock/foundation-models)
+			c.Next()
+			return
+		}
+
+		resolved, err := config.ResolveBedrockModel(model)
+		switch {
+		case errors.Is(err, router.ErrModelDenied):
+			respondError(c, http.StatusForbidden, "invalid_request_error", "model_not_allowed",
+				"The model '"+model+"' is not allowed on this endpoint")
+			c.Abort()
+			return
+		case err != nil:
+			respondError(c, http.StatusNotFound, "invalid_request_error", "model_not_found",
+				"The model '"+model+"' does not exist")
+			c.Abort()
+			return
+		}
+
+		if resolved != model {
+			setParam(c, "path", modelPrefix+resolved+rest)
+		}
+		c.Next()
+	}
+}
+
+// splitLegacyModelPath splits a legacy route path into the part before the
+// model ID, the model ID and the rest. Paths under /model start with the ID;
+// the others carry it after /model/. model is empty for other paths.
+func splitLegacyModelPath(prefix, path string) (modelPrefix, model, rest string) {
+	modelPrefix = "/"
+	if prefix != "/model" {
+		modelPrefix = "/model/"
+	}
+	if !strings.HasPrefix(path, modelPrefix) {
+		return "", "", ""
+	}
+	model, rest, found := strings.Cut(path[len(modelPrefix):], "/")
+	if found {
+		rest = "/" + rest
+	}
+	return modelPrefix, model, rest
+}
+
+// setParam replaces the value of a gin path param
+func setParam(c *gin.Context, key, value string) {
+	for i := range c.Params {
+		if c.Params[i].Key == key {
+			c.Params[i].Value = value
+			return
+		}
+	}
+	c.Params = append(c.Params, gin.Param{Key: key, Value: value})
+}
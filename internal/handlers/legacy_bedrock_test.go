@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+func TestLegacyModelValidation(t *testing.T) {
+	config := &router.Config{
+		ModelMappings: map[string]router.ModelMapping{
+			"claude-3-sonnet": {Providers: map[string]router.ProviderModelInfo{
+				"bedrock": {Model: "anthropic.claude-3-sonnet-20240229-v1:0"},
+			}},
+			"claude-3-opus": {Providers: map[string]router.ProviderModelInfo{
+				"bedrock": {Model: "anthropic.claude-3-opus-20240229-v1:0"},
+			}},
+		},
+		LegacyRoutes: router.LegacyRoutesConfig{
+			ValidateModels: true,
+			DeniedModels:   []string{"anthropic.claude-3-opus-20240229-v1:0"},
+			Passthrough:    []string{"/v1/bedrock"},
+		},
+	}
+
+	current := config
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	for _, prefix := range []string{"/v1/bedrock", "/bedrock", "/model"} {
+		engine.POST(prefix+"/*path", LegacyModelValidation(func() *router.Config { return current }, prefix), func(c *gin.Context) {
+			c.String(http.StatusOK, c.Param("path"))
+		})
+	}
+
+	for _, tt := range []struct {
+		path     string
+		wantCode int
+		wantPath string
+	}{
+		{"/model/claude-3-sonnet/invoke", http.StatusOK, "/anthropic.claude-3-sonnet-20240229-v1:0/invoke"},
+		{"/model/anthropic.claude-3-sonnet-20240229-v1:0/invoke", http.StatusOK, "/anthropic.claude-3-sonnet-20240229-v1:0/invoke"},
+		{"/bedrock/model/claude-3-sonnet/invoke-with-response-stream", http.StatusOK, "/model/anthropic.claude-3-sonnet-20240229-v1:0/invoke-with-response-stream"},
+		{"/bedrock/foundation-models", http.StatusOK, "/foundation-models"},
+		{"/model/unknown-model/invoke", http.StatusNotFound, ""},
+		{"/model/claude-3-opus/invoke", http.StatusForbidden, ""},
+		{"/bedrock/model/anthropic.claude-3-opus-20240229-v1:0/invoke", http.StatusForbidden, ""},
+		{"/v1/bedrock/model/unknown-model/invoke", http.StatusOK, "/model/unknown-model/invoke"},
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, tt.path, nil))
+		if w.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d (body %s)", tt.path, w.Code, tt.wantCode, w.Body)
+			continue
+		}
+		if tt.wantPath != "" && w.Body.String() != tt.wantPath {
+			t.Errorf("%s: forwarded path = %q, want %q", tt.path, w.Body, tt.wantPath)
+		}
+	}
+
+	// A replaced config applies to the next request
+	current = &router.Config{
+		ModelMappings: map[string]router.ModelMapping{
+			"claude-3-haiku": {Providers: map[string]router.ProviderModelInfo{
+				"bedrock": {Model: "anthropic.claude-3-haiku-20240307-v1:0"},
+			}},
+		},
+		LegacyRoutes: router.LegacyRoutesConfig{ValidateModels: true},
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/model/claude-3-haiku/invoke", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "/anthropic.claude-3-haiku-20240307-v1:0/invoke" {
+		t.Errorf("reloaded config: status = %d, body %s", w.Code, w.Body)
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/model/claude-3-sonnet/invoke", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("model removed on reload: status = %d, want 404", w.Code)
+	}
+
+	// Validation is off unless enabled
+	current = config
+	config.LegacyRoutes.ValidateModels = false
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/model/unknown-model/invoke", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "/unknown-model/invoke" {
+		t.Errorf("validation off: status = %d, body %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// MaxTokensClampedHeader is set on responses whose max_tokens was lowered
+// to the configured maximum; its value is the max_tokens the client asked for
+const MaxTokensClampedHeader = "X-Proxy-MaxTokens-Clamped"
+
+// defaultMaxTokens is sent to providers that require max_tokens when the
+// client omits it
+const defaultMaxTokens = 4096
+
+// requiresMaxTokens reports whether a provider rejects requests without
+// max_tokens
+func requiresMaxTokens(providerType string) bool {
+	return providerType == "anthropic" || providerType == "bedrock"
+}
+
+// limitMaxTokens applies an output token limit to req, filling the default
+// for providers that require max_tokens. Clamped requests are annotated with
+// MaxTokensClampedHeader; instance.ErrMaxTokensExceeded is returned when the
+// limit rejects the request.
+func limitMaxTokens(c *gin.Context, req *translator.ChatCompletionRequest, limit instance.OutputTokenLimit, providerType string) error {
+	if req.MaxTokens == 0 {
+		if requiresMaxTokens(providerType) {
+			req.MaxTokens = defaultMaxTokens
+			if limit.MaxOutputTokens > 0 && limit.MaxOutputTokens < req.MaxTokens {
+				req.MaxTokens = limit.MaxOutputTokens
+			}
+		}
+		return nil
+	}
+
+	requested := req.MaxTokens
+	maxTokens, clamped, err := limit.Apply(requested)
+	if err != nil {
+		return err
+	}
+	if clamped {
+		req.MaxTokens = maxTokens
+		c.Header(MaxTokensClampedHeader, strconv.Itoa(requested))
+	}
+	return nil
+}
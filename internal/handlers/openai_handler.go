@@ -5,67 +5,190 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/tosharewith/llmproxy_auth/internal/diagnostics"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/jobs"
+	"github.com/tosharewith/llmproxy_auth/internal/pricing"
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
 	"github.com/tosharewith/llmproxy_auth/internal/router"
 	"github.com/tosharewith/llmproxy_auth/internal/translator"
 	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
-// OpenAIHandler handles OpenAI-compatible API requests
+// OpenAIHandler handles OpenAI-compatible API requests. It is the single
+// chat completion implementation: use its methods as gin handlers, or
+// Handler for an http.Handler.
 type OpenAIHandler struct {
 	router *router.Router
+
+	// preflightTokenCheck counts prompt tokens before invoking providers
+	// that implement providers.TokenCounter (PREFLIGHT_TOKEN_CHECK=true)
+	preflightTokenCheck bool
+
+	// jobs holds asynchronous requests made with X-Webhook-URL; finished
+	// jobs are kept for JOB_RETENTION (default 24h)
+	jobs    jobs.Store
+	webhook *jobs.WebhookSender
+
+	// outputTokenLimit caps max_tokens, from the instance config's global
+	// max_output_tokens
+	outputTokenLimit instance.OutputTokenLimit
+
+	// requestIDs generates chat completion IDs (default: UUIDRequestIDFactory)
+	requestIDs RequestIDFactory
+
+	// strictValidation rejects requests with top-level fields the gateway
+	// does not support instead of ignoring them (STRICT_REQUEST_VALIDATION=true)
+	strictValidation bool
+
+	// streams tracks in-flight streams for POST
+	// /v1/chat/completions/{stream_id}/cancel
+	streams *streamRegistry
+
+	// pricing prices responses for the cost metrics and cost headers
+	// (optional)
+	pricing *pricing.Table
+
+	// streamBackpressure bounds the buffer between provider streams and
+	// slow clients (default: DefaultStreamBackpressure)
+	streamBackpressure StreamBackpressure
+
+	// slots are the concurrency limits of provider instances (optional)
+	slots providerSlots
 }
 
 // NewOpenAIHandler creates a new OpenAI handler
 func NewOpenAIHandler(r *router.Router) *OpenAIHandler {
+	retention := defaultJobRetention
+	if d, err := time.ParseDuration(os.Getenv("JOB_RETENTION")); err == nil && d > 0 {
+		retention = d
+	}
+
 	return &OpenAIHandler{
-		router: r,
+		router:              r,
+		preflightTokenCheck: os.Getenv("PREFLIGHT_TOKEN_CHECK") == "true",
+		strictValidation:    os.Getenv("STRICT_REQUEST_VALIDATION") == "true",
+		jobs:                jobs.NewMemoryStore(retention),
+		webhook:             jobs.NewWebhookSender(),
+		requestIDs:          UUIDRequestIDFactory{},
+		streams:             newStreamRegistry(),
 	}
 }
 
+// SetOutputTokenLimit sets the max_tokens ceiling applied to all requests
+func (h *OpenAIHandler) SetOutputTokenLimit(limit instance.OutputTokenLimit) {
+	h.outputTokenLimit = limit
+}
+
+// SetRequestIDFactory replaces the generator of chat completion IDs
+func (h *OpenAIHandler) SetRequestIDFactory(factory RequestIDFactory) {
+	h.requestIDs = factory
+}
+
+// SetStrictValidation sets whether requests with unknown top-level fields
+// are rejected
+func (h *OpenAIHandler) SetStrictValidation(strict bool) {
+	h.strictValidation = strict
+}
+
+// SetPricing sets the price table responses are costed with
+func (h *OpenAIHandler) SetPricing(table *pricing.Table) {
+	h.pricing = table
+}
+
+// SetStreamBackpressure sets the buffering of streams for slow clients
+func (h *OpenAIHandler) SetStreamBackpressure(config StreamBackpressure) {
+	h.streamBackpressure = config
+}
+
+// SetSemaphores enables the concurrency limits of provider instances: a
+// request takes a slot of the instance serving its model, the one GET
+// /v1/route reports
+func (h *OpenAIHandler) SetSemaphores(semaphores *providers.SemaphoreRegistry, instances *atomic.Pointer[instance.Config]) {
+	h.slots = providerSlots{semaphores: semaphores, instances: instances}
+}
+
+// Handler returns the OpenAI-compatible endpoints as an http.Handler, for
+// embedding the gateway in servers that do not use gin. It serves the same
+// routes as the gateway's /v1 group, without authentication or rate limits.
+func (h *OpenAIHandler) Handler() http.Handler {
+	engine := gin.New()
+	engine.Use(gin.Recovery())
+	v1 := engine.Group("/v1")
+	v1.POST("/chat/completions", h.ChatCompletions)
+	v1.POST("/chat/completions/:stream_id/cancel", h.CancelStream)
+	v1.GET("/jobs/:id", h.GetJob)
+	v1.GET("/models", h.ListModels)
+	v1.GET("/models/:model", h.GetModel)
+	return engine
+}
+
 // ChatCompletions handles POST /v1/chat/completions
 func (h *OpenAIHandler) ChatCompletions(c *gin.Context) {
 	startTime := time.Now()
 
 	// Parse request
 	var req translator.ChatCompletionRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, translator.ErrorResponse{
-			Error: translator.ErrorDetail{
-				Message: "Invalid request body",
-				Type:    "invalid_request_error",
-				Code:    "invalid_json",
-			},
-		})
+	body, err := c.GetRawData()
+	if err == nil {
+		err = json.Unmarshal(body, &req)
+	}
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_json", "Invalid request body")
+		return
+	}
+	if h.strictValidation {
+		if unknown := translator.UnknownChatFields(body); len(unknown) > 0 {
+			respondValidationError(c, &translator.ValidationError{
+				Param:   unknown[0],
+				Code:    "unknown_parameter",
+				Message: fmt.Sprintf("Unrecognized request argument supplied: %s", strings.Join(unknown, ", ")),
+			})
+			return
+		}
+	}
+
+	// Bedrock prompt cache point, if requested
+	cachePoint, err := translator.ParseCachePoint(c.Request.Header)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_cache_point", err.Error())
+		return
+	}
+	req.CachePoint = cachePoint
+
+	// Bedrock latency-optimized inference, if requested
+	latency, err := translator.ParseLatency(c.Request.Header)
+	if err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_latency", err.Error())
 		return
 	}
+	req.Latency = latency
 
 	// Validate model is specified
 	if req.Model == "" {
-		c.JSON(http.StatusBadRequest, translator.ErrorResponse{
-			Error: translator.ErrorDetail{
-				Message: "Model is required",
-				Type:    "invalid_request_error",
-				Code:    "missing_model",
-			},
-		})
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "missing_model", "Model is required")
+		return
+	}
+	if err := translator.ValidateChatRequest(&req); err != nil {
+		respondValidationError(c, err)
 		return
 	}
 
 	// Generate request ID
-	requestID := fmt.Sprintf("chatcmpl-%s", uuid.New().String()[:8])
+	requestID := newRequestID(h.requestIDs)
 
 	// Set default values
-	if req.MaxTokens == 0 {
-		req.MaxTokens = 4096
-	}
 	if req.Temperature == 0 {
 		req.Temperature = 1.0
 	}
@@ -74,23 +197,69 @@ func (h *OpenAIHandler) ChatCompletions(c *gin.Context) {
 	provider, modelInfo, err := h.router.RouteRequest(c.Request.Context(), req.Model, "")
 	if err != nil {
 		log.Printf("Routing error for model %s: %v", req.Model, err)
-		c.JSON(http.StatusBadRequest, translator.ErrorResponse{
-			Error: translator.ErrorDetail{
-				Message: fmt.Sprintf("Model %q not found or not available", req.Model),
-				Type:    "invalid_request_error",
-				Code:    "model_not_found",
-			},
-		})
+		if errors.Is(err, router.ErrProviderDraining) {
+			respondError(c, http.StatusServiceUnavailable, "service_error", "provider_draining",
+				fmt.Sprintf("No provider is available for model %q while its provider is draining", req.Model))
+			return
+		}
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("Model %q not found or not available", req.Model))
 		return
 	}
 
 	log.Printf("Routing model %s to provider %s (model: %s)", req.Model, provider.Name(), modelInfo.Model)
 
-	// Handle streaming vs non-streaming
-	if req.Stream {
-		h.handleStreamingRequest(c, provider, &req, modelInfo, requestID)
+	// Answer token estimates without invoking the provider
+	if estimateRequested(c) {
+		respondTokenEstimate(c, provider, modelInfo, &req)
+		return
+	}
+
+	if err := translator.ValidateMaxTokens(&req, modelInfo.MaxOutputTokens); err != nil {
+		respondValidationError(c, err)
+		return
+	}
+
+	if err := limitMaxTokens(c, &req, h.outputTokenLimit, provider.Name()); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "max_tokens_exceeded", err.Error())
+		return
+	}
+
+	if !checkBudget(c, h.pricing, provider.Name(), &req) {
+		return
+	}
+	gateServiceTier(c, provider, &req)
+
+	// Translate OpenAI request to provider format
+	providerReq, err := translateChatRequest(provider, &req, modelInfo)
+	if err != nil {
+		log.Printf("Translation error: %v", err)
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "translation_failed",
+			fmt.Sprintf("Failed to translate request: %v", err))
+		return
+	}
+	providerReq.Context = c.Request.Context()
+	ForwardCorrelation(c, providerReq)
+	defer recordPayloadSizes(c, provider.Name(), "", int64(len(providerReq.Body)))
+	setAuditTarget(c, req.Model, provider.Name(), "")
+
+	// Wait for a concurrency slot
+	release, err := h.slots.acquire(c, provider.Name(), req.Model)
+	if err != nil {
+		log.Printf("Provider %s at capacity: %v", provider.Name(), err)
+		respondError(c, http.StatusServiceUnavailable, "service_error", "queue_timeout",
+			fmt.Sprintf("Provider %s is at capacity, retry later", provider.Name()))
+		return
+	}
+	defer release()
+
+	// Handle asynchronous, streaming or non-streaming
+	if c.GetHeader(WebhookURLHeader) != "" {
+		h.handleAsync(c, provider, providerReq, &req, requestID)
+	} else if req.Stream {
+		h.handleStreamingRequest(c, provider, providerReq, &req, requestID)
 	} else {
-		h.handleNonStreamingRequest(c, provider, &req, modelInfo, requestID, startTime)
+		h.handleNonStreamingRequest(c, provider, providerReq, &req, requestID, startTime)
 	}
 }
 
@@ -98,195 +267,201 @@ func (h *OpenAIHandler) ChatCompletions(c *gin.Context) {
 func (h *OpenAIHandler) handleNonStreamingRequest(
 	c *gin.Context,
 	provider providers.Provider,
+	providerReq *providers.ProviderRequest,
 	req *translator.ChatCompletionRequest,
-	modelInfo *router.ProviderModelInfo,
 	requestID string,
 	startTime time.Time,
 ) {
-	// Translate OpenAI request to provider format
-	var providerReq *providers.ProviderRequest
-	var err error
-
-	providerName := provider.Name()
-
-	if providerName == "bedrock" {
-		// Bedrock uses Converse API
-		providerReq, _, err = translator.TranslateOpenAIToConverseAPI(req)
-		if err != nil {
-			log.Printf("Translation error: %v", err)
-			c.JSON(http.StatusBadRequest, translator.ErrorResponse{
-				Error: translator.ErrorDetail{
-					Message: fmt.Sprintf("Failed to translate request: %v", err),
-					Type:    "invalid_request_error",
-					Code:    "translation_failed",
-				},
-			})
-			return
-		}
-	} else if providerName == "openai" || providerName == "azure" {
-		// OpenAI and Azure speak OpenAI natively - pass through
-		reqBody, err := json.Marshal(req)
-		if err != nil {
-			log.Printf("Failed to marshal request: %v", err)
-			c.JSON(http.StatusBadRequest, translator.ErrorResponse{
-				Error: translator.ErrorDetail{
-					Message: "Failed to marshal request",
-					Type:    "invalid_request_error",
-					Code:    "marshal_failed",
-				},
-			})
-			return
-		}
-		providerReq = &providers.ProviderRequest{
-			Method: "POST",
-			Path:   "/chat/completions",
-			Headers: map[string]string{
-				"Content-Type": "application/json",
-			},
-			Body:    reqBody,
-			Context: c.Request.Context(),
-		}
-	} else {
-		// Anthropic, Vertex, IBM, Oracle handle translation in their Invoke method
-		reqBody, err := json.Marshal(req)
-		if err != nil {
-			log.Printf("Failed to marshal request: %v", err)
-			c.JSON(http.StatusBadRequest, translator.ErrorResponse{
-				Error: translator.ErrorDetail{
-					Message: "Failed to marshal request",
-					Type:    "invalid_request_error",
-					Code:    "marshal_failed",
-				},
-			})
-			return
-		}
-		providerReq = &providers.ProviderRequest{
-			Method: "POST",
-			Path:   "/chat/completions",
-			Headers: map[string]string{
-				"Content-Type": "application/json",
-			},
-			Body:    reqBody,
-			Context: c.Request.Context(),
-		}
+	if !h.preflight(c, provider, providerReq, req) {
+		return
 	}
 
-	// Invoke provider
-	providerResp, err := provider.Invoke(c.Request.Context(), providerReq)
+	// Invoke provider and translate its response
+	openaiResp, headers, err := invoke(c.Request.Context(), provider, providerReq, req.Model, requestID)
+	RecordUpstreamRequestID(c, headers)
 	if err != nil {
 		log.Printf("Provider invocation error: %v", err)
 		h.handleProviderError(c, err)
 		return
 	}
-
-	// Parse provider response and translate if needed
-	var openaiResp *translator.ChatCompletionResponse
-
-	if providerName == "bedrock" {
-		// Bedrock returns Converse API format - translate to OpenAI
-		var converseResp translator.ConverseResponse
-		if err := json.Unmarshal(providerResp.Body, &converseResp); err != nil {
-			log.Printf("Failed to parse Bedrock response: %v", err)
-			c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
-				Error: translator.ErrorDetail{
-					Message: "Failed to parse provider response",
-					Type:    "internal_error",
-					Code:    "response_parse_error",
-				},
-			})
-			return
-		}
-		openaiResp = translator.TranslateConverseToOpenAI(&converseResp, req.Model, requestID)
-	} else {
-		// OpenAI, Azure, Anthropic, Vertex, IBM, Oracle return OpenAI format (or already translated)
-		if err := json.Unmarshal(providerResp.Body, &openaiResp); err != nil {
-			log.Printf("Failed to parse provider response: %v", err)
-			c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
-				Error: translator.ErrorDetail{
-					Message: "Failed to parse provider response",
-					Type:    "internal_error",
-					Code:    "response_parse_error",
-				},
-			})
-			return
-		}
-	}
-
-	// Set metadata
-	openaiResp.ID = requestID
-	openaiResp.Created = startTime.Unix()
+	mergeUpstreamHeaders(c.Writer.Header(), headers)
 
 	// Record metrics
 	duration := time.Since(startTime)
 	metrics.RequestDuration.WithLabelValues("POST", "200").Observe(duration.Seconds())
 	metrics.RequestsTotal.WithLabelValues("POST", "200").Inc()
 
-	c.JSON(http.StatusOK, openaiResp)
+	// Charge token usage to the caller's rate limit bucket
+	if openaiResp.Usage != nil {
+		c.Set(ratelimit.UsageTokensKey, openaiResp.Usage.TotalTokens)
+	}
+	recordCost(c, h.pricing, provider.Name(), req.Model, openaiResp.Usage)
+	echoServiceTier(c, openaiResp)
+
+	respondJSON(c, http.StatusOK, openaiResp)
 }
 
-// handleStreamingRequest handles streaming chat completion
+// handleStreamingRequest streams a chat completion: from typed events for
+// providers implementing ChatStreamer, otherwise by proxying the stream of
+// providers that stream OpenAI SSE. Errors before the stream starts get a
+// normal error response; later ones an error event.
 func (h *OpenAIHandler) handleStreamingRequest(
 	c *gin.Context,
 	provider providers.Provider,
+	providerReq *providers.ProviderRequest,
 	req *translator.ChatCompletionRequest,
-	modelInfo *router.ProviderModelInfo,
 	requestID string,
 ) {
-	// TODO: Implement streaming support
-	c.JSON(http.StatusNotImplemented, translator.ErrorResponse{
-		Error: translator.ErrorDetail{
-			Message: "Streaming not yet implemented",
-			Type:    "not_implemented_error",
-			Code:    "streaming_not_implemented",
-		},
-	})
+	if streamer, ok := provider.(providers.ChatStreamer); ok {
+		h.handleChatStream(c, provider, streamer, providerReq, req, requestID)
+		return
+	}
+	if !streamsOpenAIProvider(provider.Name()) {
+		respondError(c, http.StatusNotImplemented, "not_implemented_error", "streaming_not_implemented",
+			fmt.Sprintf("Streaming is not yet implemented for provider %s", provider.Name()))
+		return
+	}
+	if !h.preflight(c, provider, providerReq, req) {
+		return
+	}
+
+	active := h.startCancellableStream(c)
+	defer h.streams.release(active)
+	ctx := active.ctx
+	providerReq.Context = ctx
+	stream, err := provider.InvokeStreaming(ctx, providerReq)
+	if err != nil {
+		log.Printf("Provider streaming error: %v", err)
+		h.handleProviderError(c, err)
+		return
+	}
+	defer stream.Close()
+	diagnostics.MarkStreaming(ctx)
+	if streamHeaders, ok := stream.(providers.StreamHeaders); ok {
+		RecordUpstreamRequestID(c, streamHeaders.ResponseHeaders())
+		mergeUpstreamHeaders(c.Writer.Header(), streamHeaders.ResponseHeaders())
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	declareCostTrailers(c, h.pricing)
+	c.Status(http.StatusOK)
+	writeStreamIDEvent(c, active.id)
+
+	out := newStreamBuffer(c.Writer, c.Writer, h.streamBackpressure)
+	var tracker *translator.StreamUsageTracker
+	if translator.IncludeUsage(req) {
+		tracker = translator.NewStreamUsageTracker(req)
+		err = copyStreamWithUsage(out, out, stream, tracker)
+	} else {
+		err = copyStream(out, out, stream)
+	}
+	err = out.finish(err, active.cancel, provider.Name())
+	if tracker != nil {
+		c.Set(ratelimit.UsageTokensKey, tracker.Usage().TotalTokens)
+		recordStreamCost(c, h.pricing, provider.Name(), req.Model, tracker)
+	}
+	if err != nil {
+		endStream(ctx, c, active, provider.Name(), err)
+	}
+}
+
+// handleChatStream writes a provider's typed stream events as OpenAI SSE
+func (h *OpenAIHandler) handleChatStream(
+	c *gin.Context,
+	provider providers.Provider,
+	streamer providers.ChatStreamer,
+	providerReq *providers.ProviderRequest,
+	req *translator.ChatCompletionRequest,
+	requestID string,
+) {
+	if !h.preflight(c, provider, providerReq, req) {
+		return
+	}
+
+	active := h.startCancellableStream(c)
+	defer h.streams.release(active)
+	ctx := active.ctx
+	providerReq.Context = ctx
+	events, err := streamer.InvokeChatStream(ctx, &providers.ChatRequest{Request: providerReq})
+	if err != nil {
+		log.Printf("Provider streaming error: %v", err)
+		h.handleProviderError(c, err)
+		return
+	}
+	diagnostics.MarkStreaming(ctx)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	declareCostTrailers(c, h.pricing)
+	c.Status(http.StatusOK)
+	writeStreamIDEvent(c, active.id)
+
+	tracker := translator.NewStreamUsageTracker(req)
+	out := newStreamBuffer(c.Writer, c.Writer, h.streamBackpressure)
+	err = writeChatEvents(ctx, out, out, events, tracker, requestID, req.Model, translator.IncludeUsage(req))
+	err = out.finish(err, active.cancel, provider.Name())
+	c.Set(ratelimit.UsageTokensKey, tracker.Usage().TotalTokens)
+	recordStreamCost(c, h.pricing, provider.Name(), req.Model, tracker)
+	if err != nil {
+		endStream(ctx, c, active, provider.Name(), err)
+	}
 }
 
 // handleProviderError converts provider errors to OpenAI error format
 func (h *OpenAIHandler) handleProviderError(c *gin.Context, err error) {
-	if providerErr, ok := err.(*providers.ProviderError); ok {
-		statusCode := providerErr.StatusCode
-		if statusCode == 0 {
-			statusCode = http.StatusInternalServerError
-		}
+	writeProviderError(c, err)
+}
 
-		errorType := "api_error"
-		switch providerErr.Code {
-		case providers.ErrCodeInvalidRequest:
-			errorType = "invalid_request_error"
-		case providers.ErrCodeAuthenticationFail:
-			errorType = "authentication_error"
-		case providers.ErrCodeRateLimitExceeded:
-			errorType = "rate_limit_error"
-		case providers.ErrCodeModelNotFound:
-			errorType = "invalid_request_error"
-		}
+// writeProviderError writes a provider error as an OpenAI-style error response
+func writeProviderError(c *gin.Context, err error) {
+	mergeProviderErrorHeaders(c.Writer.Header(), err)
+	statusCode, errorResp := providerErrorResponse(err)
+	respondJSON(c, statusCode, errorResp)
+}
 
-		c.JSON(statusCode, translator.ErrorResponse{
-			Error: translator.ErrorDetail{
-				Message: providerErr.Message,
-				Type:    errorType,
-				Code:    providerErr.Code,
-			},
-		})
-		return
+// respondValidationError writes a 400 for a *translator.ValidationError,
+// naming the offending field in error.param
+func respondValidationError(c *gin.Context, err error) {
+	detail := translator.ErrorDetail{
+		Message: err.Error(),
+		Type:    "invalid_request_error",
+		Code:    "invalid_request",
+	}
+	var validationErr *translator.ValidationError
+	if errors.As(err, &validationErr) {
+		detail.Param = validationErr.Param
+		detail.Code = validationErr.Code
 	}
+	respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{Error: detail})
+}
 
-	// Generic error
-	c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
+// respondError writes an OpenAI-style error response
+func respondError(c *gin.Context, statusCode int, errorType, code, message string) {
+	respondJSON(c, statusCode, translator.ErrorResponse{
 		Error: translator.ErrorDetail{
-			Message: "Internal server error",
-			Type:    "api_error",
-			Code:    "internal_error",
+			Message: message,
+			Type:    errorType,
+			Code:    code,
 		},
 	})
 }
 
-// ListModels handles GET /v1/models
+// ListModels handles GET /v1/models, responding with JSON, or YAML for
+// Accept: application/yaml
 func (h *OpenAIHandler) ListModels(c *gin.Context) {
+	contentType, ok := negotiateFormat(c.GetHeader("Accept"))
+	if !ok {
+		respondError(c, http.StatusNotAcceptable, "invalid_request_error", "not_acceptable",
+			"Supported response types are application/json and application/yaml")
+		return
+	}
+
 	models, err := h.router.ListModels(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
+		respondJSON(c, http.StatusInternalServerError, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: "Failed to list models",
 				Type:    "api_error",
@@ -307,10 +482,15 @@ func (h *OpenAIHandler) ListModels(c *gin.Context) {
 		})
 	}
 
-	c.JSON(http.StatusOK, translator.ModelsResponse{
+	resp := translator.ModelsResponse{
 		Object: "list",
 		Data:   openaiModels,
-	})
+	}
+	if contentType == contentTypeYAML {
+		respondYAML(c, http.StatusOK, resp)
+		return
+	}
+	respondJSON(c, http.StatusOK, resp)
 }
 
 // GetModel handles GET /v1/models/{model}
@@ -319,7 +499,7 @@ func (h *OpenAIHandler) GetModel(c *gin.Context) {
 
 	modelInfo, err := h.router.GetModelInfo(c.Request.Context(), modelID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, translator.ErrorResponse{
+		respondJSON(c, http.StatusNotFound, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: fmt.Sprintf("Model %q not found", modelID),
 				Type:    "invalid_request_error",
@@ -329,7 +509,7 @@ func (h *OpenAIHandler) GetModel(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, translator.Model{
+	respondJSON(c, http.StatusOK, translator.Model{
 		ID:      modelInfo.ID,
 		Object:  "model",
 		Created: time.Now().Unix(),
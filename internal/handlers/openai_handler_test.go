@@ -0,0 +1,402 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"gopkg.in/yaml.v3"
+)
+
+const openaiBody = `{"id":"upstream","object":"chat.completion","model":"m","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"end_turn"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`
+
+// newChatTestHandler routes gpt-4o to openai, gpt-4o-azure to the azure
+// deployment prod-gpt4o, claude-3-sonnet to bedrock and claude-3-haiku to
+// anthropic
+func newChatTestHandler(t *testing.T) (*OpenAIHandler, map[string]*stubProvider) {
+	t.Helper()
+	stubs := map[string]*stubProvider{
+		"openai":    {name: "openai", invoke: respondWith(openaiBody, nil), invokeStreaming: streamOf(firstChunk + "data: [DONE]\n\n")},
+		"azure":     {name: "azure", invoke: respondWith(openaiBody, nil)},
+		"anthropic": {name: "anthropic", invoke: respondWith(openaiBody, nil)},
+		"bedrock": {name: "bedrock",
+			invoke: respondWith(`{"output":{"message":{"role":"assistant","content":[{"text":"hi"}]}},"stopReason":"end_turn","usage":{"inputTokens":3,"outputTokens":1,"totalTokens":4}}`, nil)},
+	}
+	registry := make(map[string]providers.Provider)
+	for name, stub := range stubs {
+		registry[name] = stub
+	}
+
+	config := &router.Config{
+		ModelMappings: map[string]router.ModelMapping{
+			"gpt-4o":          {DefaultProvider: "openai", Providers: map[string]router.ProviderModelInfo{"openai": {Model: "gpt-4o"}}},
+			"gpt-4o-azure":    {DefaultProvider: "azure", Providers: map[string]router.ProviderModelInfo{"azure": {Model: "gpt-4o", Deployment: "prod-gpt4o"}}},
+			"claude-3-sonnet": {DefaultProvider: "bedrock", Providers: map[string]router.ProviderModelInfo{"bedrock": {Model: "anthropic.claude-3-sonnet-20240229-v1:0"}}},
+			"claude-3-haiku":  {DefaultProvider: "anthropic", Providers: map[string]router.ProviderModelInfo{"anthropic": {Model: "claude-3-haiku-20240307"}}},
+		},
+		Providers: map[string]router.ProviderConfig{
+			"openai":    {Enabled: true},
+			"azure":     {Enabled: true},
+			"anthropic": {Enabled: true},
+			"bedrock":   {Enabled: true},
+		},
+	}
+	r, err := router.NewRouter(config, registry)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	return NewOpenAIHandler(r), stubs
+}
+
+func postChat(h *OpenAIHandler, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	h.Handler().ServeHTTP(w, req)
+	return w
+}
+
+// TestChatCompletionsProviders pins the translation of each provider's
+// request and response
+func TestChatCompletionsProviders(t *testing.T) {
+	tests := []struct {
+		model    string
+		provider string
+		path     string
+	}{
+		{"gpt-4o", "openai", "/chat/completions"},
+		{"gpt-4o-azure", "azure", "/deployments/prod-gpt4o/chat/completions"},
+		{"claude-3-haiku", "anthropic", "/chat/completions"},
+		{"claude-3-sonnet", "bedrock", "/model/anthropic.claude-3-sonnet-20240229-v1:0/converse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			h, stubs := newChatTestHandler(t)
+			w := postChat(h, `{"model":"`+tt.model+`","messages":[{"role":"user","content":"hello"}]}`)
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", w.Code, w.Body)
+			}
+
+			if got := stubs[tt.provider].lastReq.Load().Path; got != tt.path {
+				t.Errorf("provider path = %q, want %q", got, tt.path)
+			}
+
+			var resp translator.ChatCompletionResponse
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("response %s: %v", w.Body, err)
+			}
+			if !strings.HasPrefix(resp.ID, "chatcmpl-") || resp.Created == 0 {
+				t.Errorf("response metadata: id %q, created %d", resp.ID, resp.Created)
+			}
+			if len(resp.Choices) != 1 || resp.Choices[0].FinishReason != "stop" {
+				t.Errorf("choices = %+v, want one choice with finish_reason stop", resp.Choices)
+			}
+			if resp.Usage == nil || resp.Usage.TotalTokens != 4 {
+				t.Errorf("usage = %+v, want 4 total tokens", resp.Usage)
+			}
+		})
+	}
+}
+
+// TestChatCompletionsQueueTimeout tests that a request waits for a slot of
+// the instance serving its model and gets 503 with Retry-After when none
+// frees up, while other providers' models are served
+func TestChatCompletionsQueueTimeout(t *testing.T) {
+	h, stubs := newChatTestHandler(t)
+	h.SetSemaphores(saturatedInstance(t, "openai", "openai-main"))
+
+	w := postChat(h, `{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "queue_timeout") {
+		t.Errorf("status %d body %s, want 503 queue_timeout", w.Code, w.Body)
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", w.Header().Get("Retry-After"))
+	}
+	if stubs["openai"].lastReq.Load() != nil {
+		t.Error("the provider was invoked without a slot")
+	}
+
+	if w := postChat(h, `{"model":"claude-3-haiku","messages":[{"role":"user","content":"hello"}]}`); w.Code != http.StatusOK {
+		t.Errorf("unlimited provider: status %d body %s, want 200", w.Code, w.Body)
+	}
+}
+
+// TestChatCompletionsStreaming tests that OpenAI-format streams are proxied
+// and other providers report streaming as not implemented
+func TestChatCompletionsStreaming(t *testing.T) {
+	h, _ := newChatTestHandler(t)
+
+	w := postChat(h, `{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hello"}]}`)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if events := sseEvents(w.Body.String()); len(events) != 2 || events[1] != translator.StreamDone {
+		t.Errorf("unexpected events: %q", events)
+	}
+
+	w = postChat(h, `{"model":"claude-3-sonnet","stream":true,"messages":[{"role":"user","content":"hello"}]}`)
+	if w.Code != http.StatusNotImplemented {
+		t.Errorf("bedrock stream: status %d, want 501", w.Code)
+	}
+}
+
+// TestChatCompletionsErrors tests the request validation and routing errors
+func TestChatCompletionsErrors(t *testing.T) {
+	h, _ := newChatTestHandler(t)
+
+	tests := []struct {
+		body   string
+		status int
+		code   string
+	}{
+		{`not json`, http.StatusBadRequest, "invalid_json"},
+		{`{"messages":[{"role":"user","content":"hello"}]}`, http.StatusBadRequest, "missing_model"},
+		{`{"model":"unknown","messages":[{"role":"user","content":"hello"}]}`, http.StatusBadRequest, "model_not_found"},
+	}
+	for _, tt := range tests {
+		w := postChat(h, tt.body)
+		var resp translator.ErrorResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: response %s: %v", tt.body, w.Body, err)
+		}
+		if w.Code != tt.status || resp.Error.Code != tt.code {
+			t.Errorf("%s: got %d %q, want %d %q", tt.body, w.Code, resp.Error.Code, tt.status, tt.code)
+		}
+	}
+}
+
+// TestChatCompletionsMaxTokens tests the output token limit and the
+// max_tokens default for providers that require it
+func TestChatCompletionsMaxTokens(t *testing.T) {
+	sentMaxTokens := func(t *testing.T, stub *stubProvider) int {
+		t.Helper()
+		var body struct {
+			MaxTokens       int `json:"max_tokens"`
+			InferenceConfig struct {
+				MaxTokens int `json:"maxTokens"`
+			} `json:"inferenceConfig"`
+		}
+		if err := json.Unmarshal(stub.lastReq.Load().Body, &body); err != nil {
+			t.Fatalf("provider body %s: %v", stub.lastReq.Load().Body, err)
+		}
+		return body.MaxTokens + body.InferenceConfig.MaxTokens
+	}
+
+	tests := []struct {
+		name        string
+		limit       instance.OutputTokenLimit
+		model       string
+		provider    string
+		maxTokens   string
+		wantStatus  int
+		wantSent    int
+		wantClamped string
+	}{
+		{"no limit", instance.OutputTokenLimit{}, "gpt-4o", "openai", `,"max_tokens":9000`, http.StatusOK, 9000, ""},
+		{"clamped", instance.OutputTokenLimit{MaxOutputTokens: 1000}, "gpt-4o", "openai", `,"max_tokens":9000`, http.StatusOK, 1000, "9000"},
+		{"within limit", instance.OutputTokenLimit{MaxOutputTokens: 1000}, "gpt-4o", "openai", `,"max_tokens":500`, http.StatusOK, 500, ""},
+		{"rejected", instance.OutputTokenLimit{MaxOutputTokens: 1000, Action: instance.MaxTokensReject}, "gpt-4o", "openai", `,"max_tokens":9000`, http.StatusBadRequest, 0, ""},
+		{"omitted openai", instance.OutputTokenLimit{}, "gpt-4o", "openai", ``, http.StatusOK, 0, ""},
+		{"omitted anthropic", instance.OutputTokenLimit{}, "claude-3-haiku", "anthropic", ``, http.StatusOK, 4096, ""},
+		{"omitted bedrock under limit", instance.OutputTokenLimit{MaxOutputTokens: 1000}, "claude-3-sonnet", "bedrock", ``, http.StatusOK, 1000, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h, stubs := newChatTestHandler(t)
+			h.SetOutputTokenLimit(tt.limit)
+
+			w := postChat(h, `{"model":"`+tt.model+`","messages":[{"role":"user","content":"hello"}]`+tt.maxTokens+`}`)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if got := w.Header().Get(MaxTokensClampedHeader); got != tt.wantClamped {
+				t.Errorf("%s = %q, want %q", MaxTokensClampedHeader, got, tt.wantClamped)
+			}
+			if tt.wantStatus != http.StatusOK {
+				if stubs[tt.provider].lastReq.Load() != nil {
+					t.Error("rejected request reached the provider")
+				}
+				return
+			}
+			if got := sentMaxTokens(t, stubs[tt.provider]); got != tt.wantSent {
+				t.Errorf("sent max_tokens = %d, want %d", got, tt.wantSent)
+			}
+		})
+	}
+}
+
+// TestProviderErrorResponse pins the mapping of provider errors to OpenAI errors
+func TestProviderErrorResponse(t *testing.T) {
+	tests := []struct {
+		name       string
+		err        error
+		wantStatus int
+		wantType   string
+	}{
+		{"invalid request", &providers.ProviderError{Code: providers.ErrCodeInvalidRequest, Message: "bad"}, http.StatusBadRequest, "invalid_request_error"},
+		{"authentication", &providers.ProviderError{Code: providers.ErrCodeAuthenticationFail}, http.StatusUnauthorized, "authentication_error"},
+		{"rate limit", &providers.ProviderError{Code: providers.ErrCodeRateLimitExceeded}, http.StatusTooManyRequests, "rate_limit_error"},
+		{"model not found", &providers.ProviderError{Code: providers.ErrCodeModelNotFound}, http.StatusNotFound, "invalid_request_error"},
+		{"unavailable", &providers.ProviderError{Code: providers.ErrCodeServiceUnavailable}, http.StatusServiceUnavailable, "api_error"},
+		{"upstream status wins", &providers.ProviderError{Code: providers.ErrCodeRateLimitExceeded, StatusCode: http.StatusServiceUnavailable}, http.StatusServiceUnavailable, "rate_limit_error"},
+		{"not a provider error", io.ErrUnexpectedEOF, http.StatusInternalServerError, "api_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, resp := providerErrorResponse(tt.err)
+			if status != tt.wantStatus || resp.Error.Type != tt.wantType {
+				t.Errorf("got %d %q, want %d %q", status, resp.Error.Type, tt.wantStatus, tt.wantType)
+			}
+		})
+	}
+}
+
+// TestListModelsFormats tests JSON and YAML output of the models list
+func TestListModelsFormats(t *testing.T) {
+	h, _ := newChatTestHandler(t)
+
+	get := func(accept string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		w := httptest.NewRecorder()
+		h.Handler().ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	var jsonResp translator.ModelsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &jsonResp); err != nil || len(jsonResp.Data) == 0 {
+		t.Fatalf("JSON response %s: %v", w.Body, err)
+	}
+
+	w = get("application/yaml")
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != contentTypeYAML {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var yamlResp translator.ModelsResponse
+	if err := yaml.Unmarshal(w.Body.Bytes(), &yamlResp); err != nil {
+		t.Fatalf("YAML response %s: %v", w.Body, err)
+	}
+	if yamlResp.Object != "list" || len(yamlResp.Data) != len(jsonResp.Data) || !strings.Contains(w.Body.String(), "owned_by:") {
+		t.Errorf("YAML response does not mirror JSON:\n%s", w.Body)
+	}
+
+	if w = get("text/html"); w.Code != http.StatusNotAcceptable {
+		t.Errorf("unsupported Accept: status %d, want 406", w.Code)
+	}
+}
+
+// TestChatCompletionsValidation tests that invalid requests are rejected
+// before reaching the provider, naming the field in error.param
+func TestChatCompletionsValidation(t *testing.T) {
+	h, stubs := newChatTestHandler(t)
+	mapping := h.router.GetConfig().ModelMappings["claude-3-sonnet"]
+	info := mapping.Providers["bedrock"]
+	info.MaxOutputTokens = 4096
+	mapping.Providers["bedrock"] = info
+
+	tests := []struct {
+		name      string
+		strict    bool
+		body      string
+		wantParam string
+	}{
+		{"no messages", false, `{"model":"gpt-4o","messages":[]}`, "messages"},
+		{"bad role", false, `{"model":"gpt-4o","messages":[{"role":"robot","content":"hi"}]}`, "messages[0].role"},
+		{"temperature", false, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"temperature":3}`, "temperature"},
+		{"tool name", false, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"tools":[{"type":"function","function":{"parameters":{"type":"object"}}}]}`, "tools[0].function.name"},
+		{"model cap", false, `{"model":"claude-3-sonnet","messages":[{"role":"user","content":"hi"}],"max_tokens":8192}`, "max_tokens"},
+		{"unknown field strict", true, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"seed":1}`, "seed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h.SetStrictValidation(tt.strict)
+			w := postChat(h, tt.body)
+			if w.Code != http.StatusBadRequest {
+				t.Fatalf("status = %d, body %s", w.Code, w.Body)
+			}
+			var resp struct {
+				Error struct {
+					Type  string `json:"type"`
+					Param string `json:"param"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if resp.Error.Param != tt.wantParam || resp.Error.Type != "invalid_request_error" {
+				t.Errorf("error = %+v, want param %q", resp.Error, tt.wantParam)
+			}
+		})
+	}
+	for name, stub := range stubs {
+		if stub.calls.Load() != 0 {
+			t.Errorf("provider %s was invoked", name)
+		}
+	}
+
+	// Unknown fields are ignored unless strict
+	h.SetStrictValidation(false)
+	if w := postChat(h, `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}],"seed":1}`); w.Code != http.StatusOK {
+		t.Errorf("non-strict unknown field: status = %d, body %s", w.Code, w.Body)
+	}
+}
+
+// TestChatCompletionsServiceTier tests that service_tier is dropped for
+// providers without service tiers and the effective tier is echoed
+func TestChatCompletionsServiceTier(t *testing.T) {
+	h, stubs := newChatTestHandler(t)
+	w := postChat(h, `{"model":"gpt-4o-azure","service_tier":"flex","messages":[{"role":"user","content":"hello"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if strings.Contains(string(stubs["azure"].lastReq.Load().Body), "service_tier") {
+		t.Errorf("service_tier sent to a provider without it: %s", stubs["azure"].lastReq.Load().Body)
+	}
+	var resp translator.ChatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.ServiceTier != translator.ServiceTierDefault {
+		t.Errorf("service_tier = %q, want default", resp.ServiceTier)
+	}
+
+	w = postChat(h, `{"model":"gpt-4o","messages":[{"role":"user","content":"hello"}]}`)
+	if strings.Contains(w.Body.String(), "service_tier") {
+		t.Errorf("service_tier echoed without being requested: %s", w.Body)
+	}
+}
+
+// TestChatCompletionsCacheControl tests that cache_control markers are
+// only sent to providers serving Claude
+func TestChatCompletionsCacheControl(t *testing.T) {
+	h, stubs := newChatTestHandler(t)
+	body := `{"model":"%s","messages":[{"role":"system","content":"Be brief.","cache_control":{"type":"ephemeral"}},{"role":"user","content":"hello"}]}`
+
+	w := postChat(h, fmt.Sprintf(body, "gpt-4o-azure"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if strings.Contains(string(stubs["azure"].lastReq.Load().Body), "cache_control") {
+		t.Errorf("cache_control sent to a provider without prompt caching: %s", stubs["azure"].lastReq.Load().Body)
+	}
+
+	w = postChat(h, fmt.Sprintf(body, "claude-3-sonnet"))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if !strings.Contains(string(stubs["bedrock"].lastReq.Load().Body), "cachePoint") {
+		t.Errorf("no cache point sent to Bedrock: %s", stubs["bedrock"].lastReq.Load().Body)
+	}
+}
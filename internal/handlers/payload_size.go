@@ -0,0 +1,44 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"io"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// bodyCounter counts the bytes read from a request body
+type bodyCounter struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *bodyCounter) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// countRequestBody wraps the request body to count the bytes read from it,
+// for bodies streamed to the provider without buffering
+func countRequestBody(c *gin.Context) *bodyCounter {
+	counter := &bodyCounter{ReadCloser: c.Request.Body}
+	if c.Request.Body != nil {
+		c.Request.Body = counter
+	}
+	return counter
+}
+
+// recordPayloadSizes records the size of the request body sent to the
+// provider and of the response written to the client so far, which for
+// streams is every chunk written. Call it once the response is complete.
+func recordPayloadSizes(c *gin.Context, provider, instance string, requestBytes int64) {
+	responseBytes := c.Writer.Size()
+	if responseBytes < 0 {
+		responseBytes = 0
+	}
+	metrics.RecordPayloadSizes(provider, instance, requestBytes, int64(responseBytes))
+}
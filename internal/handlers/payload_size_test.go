@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// histogramSample returns the sample count and sum of a histogram series
+func histogramSample(t *testing.T, name, provider, instance string) (uint64, float64) {
+	t.Helper()
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatalf("Gather: %v", err)
+	}
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			labels := map[string]string{}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			if labels["provider"] == provider && labels["instance"] == instance {
+				return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+			}
+		}
+	}
+	return 0, 0
+}
+
+func TestPayloadSizeMetrics(t *testing.T) {
+	t.Run("streamed chat completion", func(t *testing.T) {
+		h, _ := newChatTestHandler(t)
+		h.router.RegisterProvider("bedrock", &chatEventProvider{
+			stubProvider: stubProvider{name: "bedrock"},
+			events: []providers.ChatEvent{
+				{Type: providers.ChatEventDelta, Text: "Hello"},
+				{Type: providers.ChatEventDelta, Text: " world"},
+				{Type: providers.ChatEventDone, FinishReason: "end_turn"},
+			},
+		})
+		requests, requestBytes := histogramSample(t, "ai_request_bytes", "bedrock", "")
+		responses, responseBytes := histogramSample(t, "ai_response_bytes", "bedrock", "")
+
+		w := postChat(h, `{"model":"claude-3-sonnet","stream":true,"messages":[{"role":"user","content":"hello"}]}`)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d", w.Code)
+		}
+
+		count, sum := histogramSample(t, "ai_request_bytes", "bedrock", "")
+		if count != requests+1 || sum <= requestBytes {
+			t.Errorf("ai_request_bytes count %d sum %v, want one more non-empty sample", count, sum)
+		}
+		// Every chunk written counts towards the response size
+		count, sum = histogramSample(t, "ai_response_bytes", "bedrock", "")
+		if count != responses+1 || sum-responseBytes != float64(w.Body.Len()) {
+			t.Errorf("ai_response_bytes count %d grew by %v, want 1 sample of %d bytes", count-responses, sum-responseBytes, w.Body.Len())
+		}
+	})
+
+	t.Run("transparent passthrough", func(t *testing.T) {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"ok":true}`))
+		}))
+		defer upstream.Close()
+
+		for _, mode := range []string{"reverse_proxy", "streaming"} {
+			engine := newPassthroughEngine(t, upstream.URL, mode)
+			_, requestBytes := histogramSample(t, "ai_request_bytes", "openai", "openai-direct")
+			_, responseBytes := histogramSample(t, "ai_response_bytes", "openai", "openai-direct")
+
+			body := `{"model":"gpt-4o","input":"hello"}`
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transparent/openai/embeddings", strings.NewReader(body)))
+			if w.Code != http.StatusOK {
+				t.Fatalf("%s: status %d", mode, w.Code)
+			}
+
+			if _, sum := histogramSample(t, "ai_request_bytes", "openai", "openai-direct"); sum-requestBytes != float64(len(body)) {
+				t.Errorf("%s: ai_request_bytes grew by %v, want %d", mode, sum-requestBytes, len(body))
+			}
+			if _, sum := histogramSample(t, "ai_response_bytes", "openai", "openai-direct"); sum-responseBytes != float64(w.Body.Len()) {
+				t.Errorf("%s: ai_response_bytes grew by %v, want %d", mode, sum-responseBytes, w.Body.Len())
+			}
+		}
+	})
+}
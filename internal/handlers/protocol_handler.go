@@ -5,33 +5,110 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/tosharewith/llmproxy_auth/internal/diagnostics"
+	"github.com/tosharewith/llmproxy_auth/internal/chaos"
+	"github.com/tosharewith/llmproxy_auth/internal/health"
 	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/pricing"
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
 	"github.com/tosharewith/llmproxy_auth/internal/translator"
 	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // ProtocolHandler handles protocol-based requests with transformations
 type ProtocolHandler struct {
 	providers map[string]providers.Provider
-	config    *instance.Config
+	config    *atomic.Pointer[instance.Config] // the config in effect, replaced by reloads
+	health    *health.Checker // Optional: per-instance outcome tracking and fallback health
+
+	semaphores *providers.SemaphoreRegistry // Optional: per-instance concurrency limits
+
+	// instanceProviders are used instead of the provider for the instance type
+	instanceProviders map[string]providers.Provider
+
+	// requestIDs generates chat completion IDs (default: UUIDRequestIDFactory)
+	requestIDs RequestIDFactory
+
+	pricing *pricing.Table // Optional: prices responses for the cost metrics and cost headers
+
+	// streamBackpressure bounds the buffer between provider streams and
+	// slow clients (default: DefaultStreamBackpressure)
+	streamBackpressure StreamBackpressure
+
+	faults *chaos.Injector // Optional: injected faults for resilience testing
+	drains DrainState      // Optional: drained providers take no new requests
 }
 
 // NewProtocolHandler creates a new protocol handler
-func NewProtocolHandler(providerRegistry map[string]providers.Provider, config *instance.Config) *ProtocolHandler {
+func NewProtocolHandler(providerRegistry map[string]providers.Provider, config *atomic.Pointer[instance.Config], healthChecker *health.Checker) *ProtocolHandler {
 	return &ProtocolHandler{
-		providers: providerRegistry,
-		config:    config,
+		providers:  providerRegistry,
+		config:     config,
+		health:     healthChecker,
+		requestIDs: UUIDRequestIDFactory{},
 	}
 }
 
+// SetFaults enables injecting faults into instance requests
+func (h *ProtocolHandler) SetFaults(faults *chaos.Injector) {
+	h.faults = faults
+}
+
+// SetDrainState rejects requests to the instances of drained providers
+// with 503, and skips fallback instances of drained providers
+func (h *ProtocolHandler) SetDrainState(drains DrainState) {
+	h.drains = drains
+}
+
+// SetSemaphores enables per-instance concurrency limits
+func (h *ProtocolHandler) SetSemaphores(semaphores *providers.SemaphoreRegistry) {
+	h.semaphores = semaphores
+}
+
+// SetInstanceProviders sets providers built for a single instance, keyed
+// by instance name, such as generic_http providers
+func (h *ProtocolHandler) SetInstanceProviders(instanceProviders map[string]providers.Provider) {
+	h.instanceProviders = instanceProviders
+}
+
+// SetRequestIDFactory replaces the generator of chat completion IDs
+func (h *ProtocolHandler) SetRequestIDFactory(factory RequestIDFactory) {
+	h.requestIDs = factory
+}
+
+// SetPricing sets the price table responses are costed with
+func (h *ProtocolHandler) SetPricing(table *pricing.Table) {
+	h.pricing = table
+}
+
+// SetStreamBackpressure sets the buffering of streams for slow clients
+func (h *ProtocolHandler) SetStreamBackpressure(config StreamBackpressure) {
+	h.streamBackpressure = config
+}
+
+// provider returns the provider serving an instance: its own provider if
+// it has one, else the provider for its type, with any injected fault
+func (h *ProtocolHandler) provider(name, providerType string) (providers.Provider, bool) {
+	provider, ok := h.instanceProviders[name]
+	if !ok {
+		provider, ok = h.providers[providerType]
+	}
+	if ok {
+		provider = h.faults.Wrap(name, provider)
+	}
+	return provider, ok
+}
+
 // HandleRequest handles a protocol-based request with transformations
 func (h *ProtocolHandler) HandleRequest(c *gin.Context) {
 	startTime := time.Now()
@@ -40,10 +117,24 @@ func (h *ProtocolHandler) HandleRequest(c *gin.Context) {
 	path := c.Request.URL.Path
 
 	// Find matching instance
-	instanceCfg, instanceName, err := h.config.GetInstanceByPath(path)
+	config := h.config.Load()
+	instanceCfg, instanceName, err := config.GetInstanceForRequest(c.Request.Method, path)
+	var methodErr *instance.MethodNotAllowedError
+	if errors.As(err, &methodErr) {
+		log.Printf("Method not allowed for path %s: %v", path, err)
+		c.Header("Allow", strings.Join(methodErr.Allowed, ", "))
+		respondJSON(c, http.StatusMethodNotAllowed, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: fmt.Sprintf("Method %s not allowed for this path", c.Request.Method),
+				Type:    "invalid_request_error",
+				Code:    "method_not_allowed",
+			},
+		})
+		return
+	}
 	if err != nil {
 		log.Printf("No instance found for path %s: %v", path, err)
-		c.JSON(http.StatusNotFound, translator.ErrorResponse{
+		respondJSON(c, http.StatusNotFound, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: "No provider instance configured for this path",
 				Type:    "invalid_request_error",
@@ -56,7 +147,7 @@ func (h *ProtocolHandler) HandleRequest(c *gin.Context) {
 	// Verify it's a protocol mode instance
 	if instanceCfg.Mode != "protocol" {
 		log.Printf("Instance %s is not in protocol mode (mode: %s)", instanceName, instanceCfg.Mode)
-		c.JSON(http.StatusBadRequest, translator.ErrorResponse{
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: "This endpoint requires protocol mode",
 				Type:    "invalid_request_error",
@@ -65,15 +156,19 @@ func (h *ProtocolHandler) HandleRequest(c *gin.Context) {
 		})
 		return
 	}
+	if featureDisabled(c, config, "protocol_mode", instanceName) {
+		return
+	}
 
 	log.Printf("Protocol request: %s → %s (instance: %s, protocol: %s)",
 		path, instanceCfg.Type, instanceName, instanceCfg.Protocol)
+	applyResponseHeaders(c, instanceCfg)
 
 	// Get provider
-	provider, ok := h.providers[instanceCfg.Type]
+	provider, ok := h.provider(instanceName, instanceCfg.Type)
 	if !ok {
 		log.Printf("Provider %s not initialized", instanceCfg.Type)
-		c.JSON(http.StatusServiceUnavailable, translator.ErrorResponse{
+		respondJSON(c, http.StatusServiceUnavailable, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: fmt.Sprintf("Provider %s not available", instanceCfg.Type),
 				Type:    "service_error",
@@ -82,12 +177,35 @@ func (h *ProtocolHandler) HandleRequest(c *gin.Context) {
 		})
 		return
 	}
+	if err := drainError(h.drains, instanceCfg.Type); err != nil {
+		log.Printf("Instance %s not available: %v", instanceName, err)
+		respondError(c, http.StatusServiceUnavailable, "service_error", "provider_draining", err.Error())
+		return
+	}
+
+	// Wait for a concurrency slot
+	release, err := acquireSlot(c, h.semaphores, instanceName)
+	if err != nil {
+		log.Printf("Instance %s at capacity: %v", instanceName, err)
+		respondJSON(c, http.StatusServiceUnavailable, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: fmt.Sprintf("Provider instance %s is at capacity, retry later", instanceName),
+				Type:    "service_error",
+				Code:    "queue_timeout",
+			},
+		})
+		return
+	}
+	defer release()
 
 	// Parse request based on protocol
-	if instanceCfg.Protocol == "openai" {
+	switch instanceCfg.Protocol {
+	case "openai":
 		h.handleOpenAIProtocol(c, provider, instanceCfg, instanceName, startTime)
-	} else {
-		c.JSON(http.StatusNotImplemented, translator.ErrorResponse{
+	case "gemini":
+		h.handleGeminiProtocol(c, provider, instanceCfg, instanceName, startTime)
+	default:
+		respondJSON(c, http.StatusNotImplemented, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: fmt.Sprintf("Protocol %s not yet implemented", instanceCfg.Protocol),
 				Type:    "not_implemented_error",
@@ -105,10 +223,24 @@ func (h *ProtocolHandler) handleOpenAIProtocol(
 	instanceName string,
 	startTime time.Time,
 ) {
+	// Remove fields the instance strips before dispatch
+	if removed, err := stripRequestFields(c, instanceCfg.StripRequestFields); err != nil {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "Invalid request body",
+				Type:    "invalid_request_error",
+				Code:    "invalid_json",
+			},
+		})
+		return
+	} else if len(removed) > 0 {
+		log.Printf("Stripped request fields %v for instance %s", removed, instanceName)
+	}
+
 	// Parse OpenAI request
 	var req translator.ChatCompletionRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, translator.ErrorResponse{
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: "Invalid request body",
 				Type:    "invalid_request_error",
@@ -118,92 +250,72 @@ func (h *ProtocolHandler) handleOpenAIProtocol(
 		return
 	}
 
-	// Generate request ID
-	requestID := fmt.Sprintf("chatcmpl-%s", uuid.New().String()[:8])
+	if err := translator.ValidateChatRequest(&req); err != nil {
+		respondValidationError(c, err)
+		return
+	}
 
-	// Apply transformation
-	var providerReq *providers.ProviderRequest
-	var err error
+	// Bedrock prompt cache point, if requested
+	cachePoint, err := translator.ParseCachePoint(c.Request.Header)
+	if err != nil {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: err.Error(),
+				Type:    "invalid_request_error",
+				Code:    "invalid_cache_point",
+			},
+		})
+		return
+	}
+	req.CachePoint = cachePoint
 
-	if instanceCfg.Transformation == nil {
-		// No transformation specified - treat as passthrough
-		reqBody, err := json.Marshal(req)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
-				Error: translator.ErrorDetail{
-					Message: "Failed to marshal request",
-					Type:    "internal_error",
-					Code:    "marshal_failed",
-				},
-			})
-			return
-		}
-		providerReq = &providers.ProviderRequest{
-			Method: "POST",
-			Path:   "/chat/completions",
-			Headers: map[string]string{
-				"Content-Type": "application/json",
+	// Bedrock latency-optimized inference, if requested; the header takes
+	// precedence over the instance's bedrock_latency option
+	latency, err := translator.ParseLatency(c.Request.Header)
+	if err != nil {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: err.Error(),
+				Type:    "invalid_request_error",
+				Code:    "invalid_latency",
 			},
-			Body:    reqBody,
-			Context: c.Request.Context(),
-		}
-	} else {
-		// Apply transformation based on configuration
-		transformTo := instanceCfg.Transformation.RequestTo
-
-		switch transformTo {
-		case "bedrock_converse":
-			providerReq, _, err = translator.TranslateOpenAIToConverseAPI(&req)
-		case "openai":
-			// Passthrough
-			reqBody, err := json.Marshal(req)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
-					Error: translator.ErrorDetail{
-						Message: "Failed to marshal request",
-						Type:    "internal_error",
-						Code:    "marshal_failed",
-					},
-				})
-				return
-			}
-			providerReq = &providers.ProviderRequest{
-				Method: "POST",
-				Path:   "/chat/completions",
-				Headers: map[string]string{
-					"Content-Type": "application/json",
-				},
-				Body:    reqBody,
-				Context: c.Request.Context(),
-			}
-		default:
-			// For other transformations, let provider handle it
-			reqBody, err := json.Marshal(req)
-			if err != nil {
-				c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
-					Error: translator.ErrorDetail{
-						Message: "Failed to marshal request",
-						Type:    "internal_error",
-						Code:    "marshal_failed",
-					},
-				})
-				return
-			}
-			providerReq = &providers.ProviderRequest{
-				Method: "POST",
-				Path:   "/chat/completions",
-				Headers: map[string]string{
-					"Content-Type": "application/json",
-				},
-				Body:    reqBody,
-				Context: c.Request.Context(),
-			}
-		}
+		})
+		return
+	}
+	req.Latency = latency
+
+	if !checkBudget(c, h.pricing, instanceCfg.Type, &req) {
+		return
 	}
 
+	// Generate request ID
+	requestID := newRequestID(h.requestIDs)
+
+	// Apply transformation
+	providerReq, err := buildProtocolRequest(c, &req, instanceCfg)
+	if errors.Is(err, instance.ErrMaxTokensExceeded) {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: err.Error(),
+				Type:    "invalid_request_error",
+				Code:    "max_tokens_exceeded",
+			},
+		})
+		return
+	}
+	if errors.Is(err, translator.ErrImageTooLarge) {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: err.Error(),
+				Type:    "invalid_request_error",
+				Code:    "image_too_large",
+			},
+		})
+		return
+	}
 	if err != nil {
 		log.Printf("Translation error: %v", err)
-		c.JSON(http.StatusBadRequest, translator.ErrorResponse{
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: fmt.Sprintf("Failed to translate request: %v", err),
 				Type:    "invalid_request_error",
@@ -213,45 +325,47 @@ func (h *ProtocolHandler) handleOpenAIProtocol(
 		return
 	}
 
-	// Invoke provider
+	defer recordPayloadSizes(c, instanceCfg.Type, instanceName, int64(len(providerReq.Body)))
+	setAuditTarget(c, req.Model, instanceCfg.Type, instanceName)
+
+	if req.Stream && streamsOpenAI(instanceCfg) {
+		h.handleOpenAIStreaming(c, provider, providerReq, instanceName)
+		return
+	}
+
+	// Invoke provider, falling back to another instance if it is unavailable
 	providerResp, err := provider.Invoke(c.Request.Context(), providerReq)
+	h.recordOutcome(instanceName, err)
+	if err != nil && isServiceUnavailable(err) {
+		if fallbackResp, fallbackCfg, fallbackErr := h.invokeFallback(c, &req, instanceCfg, instanceName); fallbackCfg != nil {
+			providerResp, instanceCfg, err = fallbackResp, fallbackCfg, fallbackErr
+		}
+	}
 	if err != nil {
 		log.Printf("Provider invocation error: %v", err)
 		h.handleProviderError(c, err)
 		return
 	}
+	RecordUpstreamRequestID(c, providerResp.Headers)
+	mergeUpstreamHeaders(c.Writer.Header(), providerResp.Headers)
 
-	// Parse and translate response
-	var openaiResp *translator.ChatCompletionResponse
+	if instanceCfg.Transformation.PassesResponseThrough() {
+		h.respondPassthrough(c, providerResp, instanceCfg, instanceName, req.Model, requestID, startTime)
+		return
+	}
 
-	if instanceCfg.Transformation != nil && instanceCfg.Transformation.ResponseFrom == "bedrock_converse" {
-		// Translate from Bedrock Converse to OpenAI
-		var converseResp translator.ConverseResponse
-		if err := json.Unmarshal(providerResp.Body, &converseResp); err != nil {
-			log.Printf("Failed to parse Bedrock response: %v", err)
-			c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
-				Error: translator.ErrorDetail{
-					Message: "Failed to parse provider response",
-					Type:    "internal_error",
-					Code:    "response_parse_error",
-				},
-			})
-			return
-		}
-		openaiResp = translator.TranslateConverseToOpenAI(&converseResp, req.Model, requestID)
-	} else {
-		// Response is already in OpenAI format or translated by provider
-		if err := json.Unmarshal(providerResp.Body, &openaiResp); err != nil {
-			log.Printf("Failed to parse provider response: %v", err)
-			c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
-				Error: translator.ErrorDetail{
-					Message: "Failed to parse provider response",
-					Type:    "internal_error",
-					Code:    "response_parse_error",
-				},
-			})
-			return
-		}
+	// Parse and translate response
+	openaiResp, err := parseProtocolResponse(providerResp.Body, instanceCfg, req.Model, requestID)
+	if err != nil {
+		log.Printf("Failed to parse provider response: %v", err)
+		respondJSON(c, http.StatusInternalServerError, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "Failed to parse provider response",
+				Type:    "internal_error",
+				Code:    "response_parse_error",
+			},
+		})
+		return
 	}
 
 	// Set metadata
@@ -259,26 +373,313 @@ func (h *ProtocolHandler) handleOpenAIProtocol(
 	openaiResp.Created = startTime.Unix()
 
 	// Record metrics
-	if instanceCfg.Metrics.Enabled {
-		duration := time.Since(startTime)
-		metrics.RequestDuration.WithLabelValues("POST", "200").Observe(duration.Seconds())
-		metrics.RequestsTotal.WithLabelValues("POST", "200").Inc()
-	}
+	recordProtocolMetrics(instanceCfg, startTime)
 
 	log.Printf("Protocol request completed: %s (status: 200, duration: %v)", instanceName, time.Since(startTime))
 
-	c.JSON(http.StatusOK, openaiResp)
+	// Charge token usage to the caller's rate limit bucket
+	if openaiResp.Usage != nil {
+		c.Set(ratelimit.UsageTokensKey, openaiResp.Usage.TotalTokens)
+	}
+	recordCost(c, h.pricing, instanceCfg.Type, req.Model, openaiResp.Usage)
+
+	respondTranslated(c, instanceCfg, openaiResp)
+}
+
+// respondPassthrough writes the provider's response body and content type
+// unchanged, for instances with response_to: passthrough. Usage is still
+// charged and costed when the body can be parsed as the instance's
+// response format.
+func (h *ProtocolHandler) respondPassthrough(
+	c *gin.Context,
+	providerResp *providers.ProviderResponse,
+	instanceCfg *instance.InstanceConfig,
+	instanceName, model, requestID string,
+	startTime time.Time,
+) {
+	recordProtocolMetrics(instanceCfg, startTime)
+	log.Printf("Protocol request completed: %s (status: %d, passthrough, duration: %v)",
+		instanceName, providerResp.StatusCode, time.Since(startTime))
+
+	if parsed, err := parseProtocolResponse(providerResp.Body, instanceCfg, model, requestID); err == nil && parsed.Usage != nil {
+		c.Set(ratelimit.UsageTokensKey, parsed.Usage.TotalTokens)
+		recordCost(c, h.pricing, instanceCfg.Type, model, parsed.Usage)
+	}
+
+	c.Data(providerResp.StatusCode, providers.ContentType(providerResp.Headers), providerResp.Body)
+}
+
+// recordProtocolMetrics records the duration and count of a successful
+// protocol request, for instances with metrics enabled
+func recordProtocolMetrics(instanceCfg *instance.InstanceConfig, startTime time.Time) {
+	if !instanceCfg.Metrics.Enabled {
+		return
+	}
+	duration := time.Since(startTime)
+	metrics.RequestDuration.WithLabelValues("POST", "200").Observe(duration.Seconds())
+	metrics.RequestsTotal.WithLabelValues("POST", "200").Inc()
+}
+
+// respondTranslated writes a translated response, with the fields of the
+// instance's response_fields removed and added
+func respondTranslated(c *gin.Context, instanceCfg *instance.InstanceConfig, resp *translator.ChatCompletionResponse) {
+	if !instanceCfg.Transformation.FiltersResponse() {
+		respondJSON(c, http.StatusOK, resp)
+		return
+	}
+	body, err := json.Marshal(resp)
+	if err == nil {
+		body, err = instanceCfg.Transformation.FilterResponse(body)
+	}
+	if err != nil {
+		log.Printf("Failed to filter response fields: %v", err)
+		respondJSON(c, http.StatusInternalServerError, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "Failed to filter response fields",
+				Type:    "internal_error",
+				Code:    "response_filter_error",
+			},
+		})
+		return
+	}
+	respondJSON(c, http.StatusOK, json.RawMessage(body))
+}
+
+// streamsOpenAI reports whether an instance's provider stream is already in
+// OpenAI SSE format and can be proxied as-is. Other response formats are
+// answered non-streaming.
+func streamsOpenAI(instanceCfg *instance.InstanceConfig) bool {
+	return instanceCfg.Transformation == nil || instanceCfg.Transformation.ResponseFrom == "openai"
+}
+
+// handleOpenAIStreaming proxies an OpenAI-format provider stream. Errors
+// before the first byte get a normal error response; later ones an error
+// event.
+func (h *ProtocolHandler) handleOpenAIStreaming(
+	c *gin.Context,
+	provider providers.Provider,
+	providerReq *providers.ProviderRequest,
+	instanceName string,
+) {
+	ctx := c.Request.Context()
+	stream, err := provider.InvokeStreaming(ctx, providerReq)
+	h.recordOutcome(instanceName, err)
+	if err != nil {
+		log.Printf("Provider streaming error: %v", err)
+		h.handleProviderError(c, err)
+		return
+	}
+	defer stream.Close()
+	diagnostics.MarkStreaming(ctx)
+	if streamHeaders, ok := stream.(providers.StreamHeaders); ok {
+		RecordUpstreamRequestID(c, streamHeaders.ResponseHeaders())
+		mergeUpstreamHeaders(c.Writer.Header(), streamHeaders.ResponseHeaders())
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Status(http.StatusOK)
+
+	out := newStreamBuffer(c.Writer, c.Writer, h.streamBackpressure)
+	err = out.finish(copyStream(out, out, stream), func() { stream.Close() }, provider.Name())
+	if err != nil {
+		writeStreamError(ctx, c.Writer, c.Writer, provider.Name(), err)
+	}
+}
+
+// invokeFallback retries a request against the instance's fallback provider.
+// It returns a nil instance config when no usable fallback is configured, in
+// which case the primary error should be returned. Only one level of fallback
+// is attempted.
+func (h *ProtocolHandler) invokeFallback(
+	c *gin.Context,
+	req *translator.ChatCompletionRequest,
+	primaryCfg *instance.InstanceConfig,
+	primaryName string,
+) (*providers.ProviderResponse, *instance.InstanceConfig, error) {
+	fallbackName := primaryCfg.FallbackProvider
+	if fallbackName == "" {
+		return nil, nil, nil
+	}
+
+	fallbackCfg, err := h.config.Load().GetInstanceByName(fallbackName)
+	if err != nil {
+		log.Printf("Fallback instance %s for %s not found: %v", fallbackName, primaryName, err)
+		return nil, nil, nil
+	}
+	if !fallbackCfg.SupportsProtocol(primaryCfg.Protocol) {
+		log.Printf("Fallback instance %s does not support protocol %s", fallbackName, primaryCfg.Protocol)
+		return nil, nil, nil
+	}
+	if err := drainError(h.drains, fallbackCfg.Type); err != nil {
+		log.Printf("Fallback instance %s not available: %v", fallbackName, err)
+		return nil, nil, nil
+	}
+	if h.health != nil && !h.health.IsProviderHealthy(fallbackName) {
+		log.Printf("Fallback instance %s is unhealthy, not falling back", fallbackName)
+		return nil, nil, nil
+	}
+
+	fallbackProvider, ok := h.provider(fallbackName, fallbackCfg.Type)
+	if !ok {
+		log.Printf("Fallback provider %s not initialized", fallbackCfg.Type)
+		return nil, nil, nil
+	}
+
+	providerReq, err := buildProtocolRequest(c, req, fallbackCfg)
+	if err != nil {
+		log.Printf("Failed to translate request for fallback %s: %v", fallbackName, err)
+		return nil, nil, nil
+	}
+
+	log.Printf("Provider for %s unavailable, falling back to %s", primaryName, fallbackName)
+	metrics.FallbackActivations.WithLabelValues(primaryName, fallbackName).Inc()
+
+	resp, err := fallbackProvider.Invoke(c.Request.Context(), providerReq)
+	h.recordOutcome(fallbackName, err)
+	return resp, fallbackCfg, err
+}
+
+// recordOutcome records a provider call against the instance in the health checker
+func (h *ProtocolHandler) recordOutcome(instanceName string, err error) {
+	if h.health == nil {
+		return
+	}
+	if err != nil {
+		h.health.RecordError(instanceName)
+	} else {
+		h.health.RecordSuccess(instanceName)
+	}
+}
+
+// isServiceUnavailable reports whether a provider error means the provider is unavailable
+func isServiceUnavailable(err error) bool {
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) {
+		return false
+	}
+	return providerErr.Code == providers.ErrCodeServiceUnavailable ||
+		providerErr.StatusCode == http.StatusServiceUnavailable
+}
+
+// buildProtocolRequest applies an instance's request transformation
+func buildProtocolRequest(c *gin.Context, req *translator.ChatCompletionRequest, instanceCfg *instance.InstanceConfig) (*providers.ProviderRequest, error) {
+	options := instanceCfg.Transformation.RequestOptions()
+	req = options.Apply(req)
+	if err := limitMaxTokens(c, req, instanceCfg.OutputTokenLimit, instanceCfg.Type); err != nil {
+		return nil, err
+	}
+	req, err := fitImages(req, instanceCfg.ImageLimits, instanceCfg.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	var providerReq *providers.ProviderRequest
+	if instanceCfg.Transformation.HasRequestTemplate() {
+		reqBody, err := instanceCfg.Transformation.RenderRequest(req)
+		if err != nil {
+			return nil, err
+		}
+		providerReq = &providers.ProviderRequest{
+			Method: "POST",
+			Path:   "/chat/completions",
+			Headers: http.Header{
+				"Content-Type": {"application/json"},
+			},
+			Body:    reqBody,
+			Context: c.Request.Context(),
+		}
+	} else if instanceCfg.Transformation != nil && instanceCfg.Transformation.RequestTo == "bedrock_converse" {
+		var err error
+		providerReq, _, err = translator.TranslateOpenAIToConverseAPI(req)
+		if err != nil {
+			return nil, err
+		}
+	} else if instanceCfg.Transformation != nil && instanceCfg.Transformation.RequestTo == "bedrock_kb" {
+		var err error
+		providerReq, err = translator.TranslateOpenAIToKnowledgeBase(req, instanceCfg.KnowledgeBaseID, instanceCfg.ModelARN)
+		if err != nil {
+			return nil, err
+		}
+		providerReq.Context = c.Request.Context()
+	} else {
+		// No transformation, "openai" passthrough, or provider-side translation
+		if !supportsPromptCaching(instanceCfg.Type) {
+			req = translator.StripCacheControl(req)
+		}
+		if instanceCfg.Type != "azure" {
+			req = translator.StripDataSources(req)
+		}
+		reqBody, err := json.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+		providerReq = &providers.ProviderRequest{
+			Method: "POST",
+			Path:   "/chat/completions",
+			Headers: http.Header{
+				"Content-Type": {"application/json"},
+			},
+			Body:    reqBody,
+			Context: c.Request.Context(),
+		}
+	}
+
+	if instanceCfg.Authentication.PassThroughAuth {
+		if providerReq.Headers == nil {
+			providerReq.Headers = make(http.Header)
+		}
+		providerReq.PassThroughAuth = true
+		providerReq.Headers.Set("Authorization", c.GetHeader("Authorization"))
+	}
+	ForwardCorrelation(c, providerReq)
+	if options.AnthropicVersion != "" && instanceCfg.Type == "anthropic" {
+		providerReq.Headers.Set("anthropic-version", options.AnthropicVersion)
+	}
+	forwardClientHeaders(c, providerReq, instanceCfg)
+	applyInstanceRules(providerReq, instanceCfg)
+
+	return providerReq, nil
+}
+
+// parseProtocolResponse applies an instance's response transformation
+func parseProtocolResponse(body []byte, instanceCfg *instance.InstanceConfig, model, requestID string) (*translator.ChatCompletionResponse, error) {
+	if instanceCfg.Transformation != nil && instanceCfg.Transformation.ResponseFrom == "bedrock_converse" {
+		// Translate from Bedrock Converse to OpenAI
+		var converseResp translator.ConverseResponse
+		if err := json.Unmarshal(body, &converseResp); err != nil {
+			return nil, err
+		}
+		return translator.TranslateConverseToOpenAI(&converseResp, model, requestID), nil
+	}
+
+	if instanceCfg.Transformation != nil && instanceCfg.Transformation.ResponseFrom == "bedrock_kb" {
+		var kbResp translator.RetrieveAndGenerateResponse
+		if err := json.Unmarshal(body, &kbResp); err != nil {
+			return nil, err
+		}
+		return translator.TranslateKnowledgeBaseToOpenAI(&kbResp, model, requestID), nil
+	}
+
+	// Response is already in OpenAI format or translated by provider
+	var openaiResp translator.ChatCompletionResponse
+	if err := json.Unmarshal(body, &openaiResp); err != nil {
+		return nil, err
+	}
+	return &openaiResp, nil
 }
 
 // handleProviderError converts provider errors to protocol error format
 func (h *ProtocolHandler) handleProviderError(c *gin.Context, err error) {
+	mergeProviderErrorHeaders(c.Writer.Header(), err)
 	if providerErr, ok := err.(*providers.ProviderError); ok {
 		statusCode := providerErr.StatusCode
 		if statusCode == 0 {
 			statusCode = http.StatusInternalServerError
 		}
 
-		c.JSON(statusCode, translator.ErrorResponse{
+		respondJSON(c, statusCode, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: providerErr.Message,
 				Type:    "provider_error",
@@ -286,7 +687,7 @@ func (h *ProtocolHandler) handleProviderError(c *gin.Context, err error) {
 			},
 		})
 	} else {
-		c.JSON(http.StatusInternalServerError, translator.ErrorResponse{
+		respondJSON(c, http.StatusInternalServerError, translator.ErrorResponse{
 			Error: translator.ErrorDetail{
 				Message: "Internal server error",
 				Type:    "internal_error",
@@ -0,0 +1,798 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/health"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/openai"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+func newFallbackTestConfig() *instance.Config {
+	return &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai-primary": {
+				Type:             "openai",
+				Mode:             "protocol",
+				Protocol:         "openai",
+				FallbackProvider: "azure-backup",
+				Endpoints:        []instance.EndpointConfig{{Path: "/openai/primary"}},
+			},
+			"azure-backup": {
+				Type:      "azure",
+				Mode:      "protocol",
+				Protocol:  "openai",
+				Endpoints: []instance.EndpointConfig{{Path: "/openai/backup"}},
+			},
+		},
+	}
+}
+
+func serveProtocolRequest(h *ProtocolHandler) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/openai/*path", h.HandleRequest)
+
+	req := httptest.NewRequest(http.MethodPost, "/openai/primary/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+// TestProtocolFallbackOn503 tests that an unavailable primary falls back once
+func TestProtocolFallbackOn503(t *testing.T) {
+	tests := []struct {
+		name           string
+		primaryErr     error
+		backupHealthy  bool
+		wantStatus     int
+		wantBackupCall bool
+	}{
+		{
+			name:           "service unavailable falls back",
+			primaryErr:     &providers.ProviderError{Provider: "openai", StatusCode: 503, Message: "overloaded"},
+			backupHealthy:  true,
+			wantStatus:     http.StatusOK,
+			wantBackupCall: true,
+		},
+		{
+			name:          "other errors do not fall back",
+			primaryErr:    &providers.ProviderError{Provider: "openai", StatusCode: 400, Code: providers.ErrCodeInvalidRequest, Message: "bad"},
+			backupHealthy: true,
+			wantStatus:    http.StatusBadRequest,
+		},
+		{
+			name:          "unhealthy fallback is skipped",
+			primaryErr:    &providers.ProviderError{Provider: "openai", Code: providers.ErrCodeServiceUnavailable, StatusCode: 503, Message: "down"},
+			backupHealthy: false,
+			wantStatus:    http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary := &stubProvider{name: "openai", err: tt.primaryErr}
+			backup := &stubProvider{name: "azure"}
+
+			checker := health.NewCheckerWithConfig(health.Config{MinSamples: 1})
+			if !tt.backupHealthy {
+				checker.RecordError("azure-backup")
+			}
+
+			h := NewProtocolHandler(map[string]providers.Provider{"openai": primary, "azure": backup}, currentConfig(newFallbackTestConfig()), checker)
+			w := serveProtocolRequest(h)
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status: got %d, want %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if got := backup.calls.Load() > 0; got != tt.wantBackupCall {
+				t.Errorf("backup called: got %v, want %v", got, tt.wantBackupCall)
+			}
+			if tt.wantBackupCall && !strings.Contains(w.Body.String(), "from azure") {
+				t.Errorf("expected fallback response, got %s", w.Body.String())
+			}
+		})
+	}
+}
+
+// TestProtocolDraining tests that requests to an instance of a drained
+// provider are rejected without invoking it, and that a drained fallback
+// is skipped
+func TestProtocolDraining(t *testing.T) {
+	primary := &stubProvider{name: "openai", err: &providers.ProviderError{Provider: "openai", StatusCode: 503, Message: "overloaded"}}
+	backup := &stubProvider{name: "azure"}
+	registry := map[string]providers.Provider{"openai": primary, "azure": backup}
+	drains := drainedRouter(t, registry, "azure")
+	h := NewProtocolHandler(registry, currentConfig(newFallbackTestConfig()), nil)
+	h.SetDrainState(drains)
+
+	if w := serveProtocolRequest(h); w.Code != http.StatusServiceUnavailable || backup.calls.Load() != 0 {
+		t.Errorf("drained fallback: status %d, backup calls %d, want 503 without calls", w.Code, backup.calls.Load())
+	}
+
+	drains.Drain("openai")
+	w := serveProtocolRequest(h)
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "provider_draining") {
+		t.Errorf("drained provider: status %d body %s, want 503 provider_draining", w.Code, w.Body)
+	}
+	if calls := primary.calls.Load(); calls != 1 {
+		t.Errorf("primary calls = %d, want 1: the drained provider was invoked", calls)
+	}
+}
+
+// TestValidateFallbacks tests fallback_provider configuration checks
+func TestValidateFallbacks(t *testing.T) {
+	config := newFallbackTestConfig()
+	if err := config.ValidateFallbacks(); err != nil {
+		t.Fatalf("valid config rejected: %v", err)
+	}
+
+	chained := newFallbackTestConfig()
+	backup := chained.Instances["azure-backup"]
+	backup.FallbackProvider = "openai-primary"
+	chained.Instances["azure-backup"] = backup
+	if err := chained.ValidateFallbacks(); err == nil {
+		t.Error("expected error for fallback chain")
+	}
+
+	mismatched := newFallbackTestConfig()
+	backup = mismatched.Instances["azure-backup"]
+	backup.Protocol = "anthropic"
+	mismatched.Instances["azure-backup"] = backup
+	if err := mismatched.ValidateFallbacks(); err == nil {
+		t.Error("expected error for protocol mismatch")
+	}
+}
+
+// TestProtocolPassThroughAuth tests that the client Authorization header replaces provider credentials
+func TestProtocolPassThroughAuth(t *testing.T) {
+	var gotAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Write([]byte(`{"id":"x","object":"chat.completion","choices":[{"index":0,"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+
+	vllm, err := openai.NewOpenAIProvider(openai.OpenAIConfig{APIKey: "proxy-key", BaseURL: upstream.URL})
+	if err != nil {
+		t.Fatalf("NewOpenAIProvider: %v", err)
+	}
+
+	for _, passThrough := range []bool{true, false} {
+		config := &instance.Config{
+			Instances: map[string]instance.InstanceConfig{
+				"vllm": {
+					Type:           "openai",
+					Mode:           "protocol",
+					Protocol:       "openai",
+					Authentication: instance.AuthenticationConfig{Type: "bearer_token", PassThroughAuth: passThrough},
+					Endpoints:      []instance.EndpointConfig{{Path: "/openai/vllm"}},
+				},
+			},
+		}
+		h := NewProtocolHandler(map[string]providers.Provider{"openai": vllm}, currentConfig(config), nil)
+
+		gin.SetMode(gin.TestMode)
+		engine := gin.New()
+		engine.POST("/openai/*path", h.HandleRequest)
+		req := httptest.NewRequest(http.MethodPost, "/openai/vllm/chat/completions",
+			strings.NewReader(`{"model":"llama","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer client-token")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+
+		if w.Code != http.StatusOK {
+			t.Fatalf("pass_through_auth=%v: status %d: %s", passThrough, w.Code, w.Body.String())
+		}
+		want := "Bearer proxy-key"
+		if passThrough {
+			want = "Bearer client-token"
+		}
+		if gotAuth != want {
+			t.Errorf("pass_through_auth=%v: upstream Authorization %q, want %q", passThrough, gotAuth, want)
+		}
+	}
+}
+
+// TestValidatePassThroughAuth tests that pass_through_auth is rejected with aws_sigv4
+func TestValidatePassThroughAuth(t *testing.T) {
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"bedrock": {
+				Type:           "bedrock",
+				Authentication: instance.AuthenticationConfig{Type: "aws_sigv4", PassThroughAuth: true},
+			},
+		},
+	}
+	if err := config.Validate(); err == nil {
+		t.Error("expected error for pass_through_auth with aws_sigv4")
+	}
+}
+
+// TestValidateKnowledgeBase tests bedrock_kb instance configuration checks
+func TestValidateKnowledgeBase(t *testing.T) {
+	tests := []struct {
+		name    string
+		inst    instance.InstanceConfig
+		wantErr bool
+	}{
+		{
+			name: "transparent",
+			inst: instance.InstanceConfig{Type: "bedrock_kb", Mode: "transparent",
+				Authentication: instance.AuthenticationConfig{Type: "aws_sigv4_agent_runtime"}},
+		},
+		{
+			name: "protocol with knowledge base and model",
+			inst: instance.InstanceConfig{Type: "bedrock_kb", Mode: "protocol", KnowledgeBaseID: "KB123", ModelARN: "arn:aws:bedrock:us-east-1::foundation-model/x",
+				Authentication: instance.AuthenticationConfig{Type: "aws_sigv4_agent_runtime"}},
+		},
+		{
+			name: "protocol without knowledge base",
+			inst: instance.InstanceConfig{Type: "bedrock_kb", Mode: "protocol",
+				Authentication: instance.AuthenticationConfig{Type: "aws_sigv4_agent_runtime"}},
+			wantErr: true,
+		},
+		{
+			name: "wrong authentication",
+			inst: instance.InstanceConfig{Type: "bedrock_kb", Mode: "transparent",
+				Authentication: instance.AuthenticationConfig{Type: "aws_sigv4"}},
+			wantErr: true,
+		},
+		{
+			name: "pass-through auth",
+			inst: instance.InstanceConfig{Type: "bedrock_kb", Mode: "transparent",
+				Authentication: instance.AuthenticationConfig{Type: "aws_sigv4_agent_runtime", PassThroughAuth: true}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &instance.Config{Instances: map[string]instance.InstanceConfig{"kb": tt.inst}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestProtocolMethodNotAllowed tests that endpoint methods are enforced with 405 and Allow
+func TestProtocolMethodNotAllowed(t *testing.T) {
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"primary": {
+				Type:      "openai",
+				Mode:      "protocol",
+				Protocol:  "openai",
+				Endpoints: []instance.EndpointConfig{{Path: "/openai/primary", Methods: []string{"post", "GET"}}},
+			},
+		},
+	}
+	provider := &stubProvider{name: "primary"}
+	h := NewProtocolHandler(map[string]providers.Provider{"openai": provider}, currentConfig(config), nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/openai/*path", h.HandleRequest)
+	req := httptest.NewRequest(http.MethodDelete, "/openai/primary/chat/completions", nil)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("status %d, want 405: %s", w.Code, w.Body.String())
+	}
+	if allow := w.Header().Get("Allow"); allow != "GET, POST" {
+		t.Errorf("Allow = %q, want %q", allow, "GET, POST")
+	}
+	if calls := provider.calls.Load(); calls != 0 {
+		t.Errorf("provider called %d times, want 0", calls)
+	}
+}
+
+// TestProtocolQueueTimeout tests that a saturated instance returns 503 with Retry-After
+func TestProtocolQueueTimeout(t *testing.T) {
+	config := newFallbackTestConfig()
+	provider := &stubProvider{name: "primary"}
+	h := NewProtocolHandler(map[string]providers.Provider{"openai": provider}, currentConfig(config), nil)
+
+	semaphore := providers.NewProviderSemaphore("openai", "openai-primary", 1, 10*time.Millisecond, ratelimit.ModeEnforce)
+	semaphores := providers.NewSemaphoreRegistry()
+	semaphores.Register("openai-primary", semaphore)
+	h.SetSemaphores(semaphores)
+
+	release, err := semaphore.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer release()
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/openai/*path", h.HandleRequest)
+	req := httptest.NewRequest(http.MethodPost, "/openai/primary/chat/completions",
+		strings.NewReader(`{"model":"m","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status %d, want 503: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Retry-After") != "1" {
+		t.Errorf("Retry-After = %q, want 1", w.Header().Get("Retry-After"))
+	}
+	if calls := provider.calls.Load(); calls != 0 {
+		t.Errorf("provider called %d times, want 0", calls)
+	}
+}
+
+// TestBuildProtocolRequestOptions tests the effect of each transformation
+// option on the translated provider request
+func TestBuildProtocolRequestOptions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	req := func() *translator.ChatCompletionRequest {
+		return &translator.ChatCompletionRequest{
+			Model: "claude-3-haiku",
+			Messages: []translator.ChatMessage{
+				{Role: "system", Content: translator.TextContent("Be brief.")},
+				{Role: "user", Content: translator.TextContent("Hi")},
+				{Role: "system", Content: translator.TextContent("Answer in French.")},
+			},
+			LogitBias: map[string]int{"1": 1},
+		}
+	}
+	converse := &instance.TransformationConfig{RequestFrom: "openai", RequestTo: "bedrock_converse", ResponseFrom: "bedrock_converse"}
+
+	tests := []struct {
+		name           string
+		instanceType   string
+		transformation *instance.TransformationConfig
+		options        map[string]interface{}
+		check          func(t *testing.T, providerReq *providers.ProviderRequest, body map[string]interface{})
+	}{
+		{"default_max_tokens", "bedrock", converse, map[string]interface{}{"default_max_tokens": 512},
+			func(t *testing.T, _ *providers.ProviderRequest, body map[string]interface{}) {
+				if got := body["inferenceConfig"].(map[string]interface{})["maxTokens"]; got != float64(512) {
+					t.Errorf("maxTokens = %v, want 512", got)
+				}
+			}},
+		{"anthropic_version converse", "bedrock", converse, map[string]interface{}{"anthropic_version": "bedrock-2023-05-31"},
+			func(t *testing.T, _ *providers.ProviderRequest, body map[string]interface{}) {
+				fields, _ := body["additionalModelRequestFields"].(map[string]interface{})
+				if fields["anthropic_version"] != "bedrock-2023-05-31" {
+					t.Errorf("additionalModelRequestFields = %v", body["additionalModelRequestFields"])
+				}
+			}},
+		{"anthropic_version header", "anthropic", nil, map[string]interface{}{"anthropic_version": "2024-01-01"},
+			func(t *testing.T, providerReq *providers.ProviderRequest, _ map[string]interface{}) {
+				if got := providerReq.Headers.Get("anthropic-version"); got != "2024-01-01" {
+					t.Errorf("anthropic-version = %q", got)
+				}
+			}},
+		{"force_system_merge", "openai", nil, map[string]interface{}{"force_system_merge": true},
+			func(t *testing.T, _ *providers.ProviderRequest, body map[string]interface{}) {
+				messages := body["messages"].([]interface{})
+				first := messages[0].(map[string]interface{})
+				if len(messages) != 2 || first["role"] != "system" || first["content"] != "Be brief.\n\nAnswer in French." {
+					t.Errorf("messages = %v, want one merged system message first", messages)
+				}
+			}},
+		{"drop_unsupported_params", "openai", nil, map[string]interface{}{"drop_unsupported_params": true},
+			func(t *testing.T, _ *providers.ProviderRequest, body map[string]interface{}) {
+				if _, ok := body["logit_bias"]; ok {
+					t.Errorf("logit_bias forwarded: %v", body)
+				}
+			}},
+		{"model_override", "openai", nil, map[string]interface{}{"model_override": "llama3:70b"},
+			func(t *testing.T, _ *providers.ProviderRequest, body map[string]interface{}) {
+				if body["model"] != "llama3:70b" {
+					t.Errorf("model = %v, want llama3:70b", body["model"])
+				}
+			}},
+		{"inject_metadata openai", "openai", nil, map[string]interface{}{"inject_metadata": map[string]interface{}{"team": "search"}},
+			func(t *testing.T, _ *providers.ProviderRequest, body map[string]interface{}) {
+				if metadata, _ := body["metadata"].(map[string]interface{}); metadata["team"] != "search" {
+					t.Errorf("metadata = %v", body["metadata"])
+				}
+			}},
+		{"inject_metadata converse", "bedrock", converse, map[string]interface{}{"inject_metadata": map[string]interface{}{"team": "search"}},
+			func(t *testing.T, _ *providers.ProviderRequest, body map[string]interface{}) {
+				if metadata, _ := body["requestMetadata"].(map[string]interface{}); metadata["team"] != "search" {
+					t.Errorf("requestMetadata = %v", body["requestMetadata"])
+				}
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transformation := &instance.TransformationConfig{Options: tt.options}
+			if tt.transformation != nil {
+				copied := *tt.transformation
+				copied.Options = tt.options
+				transformation = &copied
+			}
+			config := &instance.Config{Instances: map[string]instance.InstanceConfig{
+				"test": {Type: tt.instanceType, Mode: "protocol", Protocol: "openai", Transformation: transformation},
+			}}
+			if err := config.Validate(); err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+			instanceCfg := config.Instances["test"]
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest(http.MethodPost, "/openai/test", nil)
+			providerReq, err := buildProtocolRequest(c, req(), &instanceCfg)
+			if err != nil {
+				t.Fatalf("buildProtocolRequest: %v", err)
+			}
+			var body map[string]interface{}
+			if err := json.Unmarshal(providerReq.Body, &body); err != nil {
+				t.Fatalf("unmarshal %s: %v", providerReq.Body, err)
+			}
+			tt.check(t, providerReq, body)
+		})
+	}
+}
+
+// TestBuildProtocolRequestTemplate tests that a request template renders the
+// provider body after the transformation options are applied
+func TestBuildProtocolRequestTemplate(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := &instance.Config{Instances: map[string]instance.InstanceConfig{
+		"exotic": {Type: "openai", Mode: "protocol", Protocol: "openai", Transformation: &instance.TransformationConfig{
+			RequestTo:       "bedrock_converse",
+			Options:         map[string]interface{}{"model_override": "tiny-v2"},
+			RequestTemplate: `{"engine": {{ json .model }}, "input": {{ json (last .messages).content }}}`,
+		}},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	instanceCfg := config.Instances["exotic"]
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/openai/exotic", nil)
+	providerReq, err := buildProtocolRequest(c, &translator.ChatCompletionRequest{
+		Model:    "tiny",
+		Messages: []translator.ChatMessage{{Role: "user", Content: translator.TextContent("Hi")}},
+	}, &instanceCfg)
+	if err != nil {
+		t.Fatalf("buildProtocolRequest: %v", err)
+	}
+	if want := `{"engine": "tiny-v2", "input": "Hi"}`; string(providerReq.Body) != want {
+		t.Errorf("body = %s, want %s", providerReq.Body, want)
+	}
+	if got := providerReq.Headers.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+}
+
+// TestBuildProtocolRequestForwardHeaders tests that only allowlisted client
+// headers reach the provider request, and that configured request headers
+// win over forwarded ones
+func TestBuildProtocolRequestForwardHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := &instance.Config{Instances: map[string]instance.InstanceConfig{
+		"claude": {
+			Type:           "anthropic",
+			Mode:           "protocol",
+			Protocol:       "openai",
+			ForwardHeaders: []string{"anthropic-beta", "traceparent", "X-B3-*", "X-Tenant"},
+			RequestHeaders: map[string]string{"X-Tenant": "search"},
+		},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	instanceCfg := config.Instances["claude"]
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/anthropic/claude", nil)
+	for name, value := range map[string]string{
+		"Anthropic-Beta":  "prompt-caching-2024-07-31",
+		"Traceparent":     "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"X-B3-Traceid":    "80f198ee56343ba8",
+		"X-Tenant":        "client",
+		"X-Other":         "dropped",
+		"Authorization":   "Bearer gateway-key",
+		"X-Api-Key":       "gateway-key",
+		"X-Session-Token": "session",
+		"Content-Type":    "text/plain",
+	} {
+		c.Request.Header.Set(name, value)
+	}
+
+	providerReq, err := buildProtocolRequest(c, &translator.ChatCompletionRequest{
+		Model:    "claude",
+		Messages: []translator.ChatMessage{{Role: "user", Content: translator.TextContent("Hi")}},
+	}, &instanceCfg)
+	if err != nil {
+		t.Fatalf("buildProtocolRequest: %v", err)
+	}
+
+	want := map[string]string{
+		"Anthropic-Beta":  "prompt-caching-2024-07-31",
+		"Traceparent":     "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01",
+		"X-B3-Traceid":    "80f198ee56343ba8",
+		"X-Tenant":        "search",
+		"X-Other":         "",
+		"Authorization":   "",
+		"X-Api-Key":       "",
+		"X-Session-Token": "",
+		"Content-Type":    "application/json",
+	}
+	for name, value := range want {
+		if got := providerReq.Headers.Get(name); got != value {
+			t.Errorf("%s = %q, want %q", name, got, value)
+		}
+	}
+}
+
+// TestProtocolMaxTokensReject tests that an instance limit with the reject
+// action answers 400 without invoking the provider
+func TestProtocolMaxTokensReject(t *testing.T) {
+	config := &instance.Config{Instances: map[string]instance.InstanceConfig{
+		"openai-primary": {
+			Type:             "openai",
+			Mode:             "protocol",
+			Protocol:         "openai",
+			Endpoints:        []instance.EndpointConfig{{Path: "/openai/primary"}},
+			OutputTokenLimit: instance.OutputTokenLimit{MaxOutputTokens: 100, Action: instance.MaxTokensReject},
+		},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	primary := &stubProvider{name: "openai"}
+	h := NewProtocolHandler(map[string]providers.Provider{"openai": primary}, currentConfig(config), nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/openai/*path", h.HandleRequest)
+	req := httptest.NewRequest(http.MethodPost, "/openai/primary/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","max_tokens":500,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "max_tokens_exceeded") {
+		t.Errorf("status %d body %s, want 400 max_tokens_exceeded", w.Code, w.Body)
+	}
+	if calls := primary.calls.Load(); calls != 0 {
+		t.Errorf("provider called %d times, want 0", calls)
+	}
+}
+
+// TestProtocolFeatureFlag tests that protocol_mode is checked per instance
+// and API key, and that a reload takes effect without a new handler
+func TestProtocolFeatureFlag(t *testing.T) {
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai-primary": {
+				Type:      "openai",
+				Mode:      "protocol",
+				Protocol:  "openai",
+				Endpoints: []instance.EndpointConfig{{Path: "/openai/primary"}},
+			},
+		},
+		Features: map[string]instance.FeatureConfig{
+			"protocol_mode": {Enabled: false, Keys: map[string]bool{"tenant-a": true}},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	primary := &stubProvider{name: "openai"}
+	current := currentConfig(config)
+	h := NewProtocolHandler(map[string]providers.Provider{"openai": primary}, current, nil)
+
+	gin.SetMode(gin.TestMode)
+	serve := func(user string) *httptest.ResponseRecorder {
+		engine := gin.New()
+		engine.POST("/openai/*path", func(c *gin.Context) {
+			if user != "" {
+				c.Set("user", user)
+			}
+			h.HandleRequest(c)
+		})
+		req := httptest.NewRequest(http.MethodPost, "/openai/primary/chat/completions",
+			strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := serve(""); w.Code != http.StatusNotFound || !strings.Contains(w.Body.String(), "feature_disabled") {
+		t.Errorf("disabled: status %d body %s, want 404 feature_disabled", w.Code, w.Body)
+	}
+	if w := serve("tenant-a"); w.Code != http.StatusOK {
+		t.Errorf("key override: status %d body %s, want 200", w.Code, w.Body)
+	}
+
+	reloaded, err := config.WithFeatures(map[string]instance.FeatureConfig{"protocol_mode": {Enabled: true}})
+	if err != nil {
+		t.Fatalf("WithFeatures() error = %v", err)
+	}
+	current.Store(reloaded)
+	if w := serve(""); w.Code != http.StatusOK {
+		t.Errorf("after reload: status %d body %s, want 200", w.Code, w.Body)
+	}
+}
+
+// TestProtocolResponseFields tests that response_fields removes and adds
+// fields of translated responses and keeps the rest
+func TestProtocolResponseFields(t *testing.T) {
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai-compliant": {
+				Type:      "openai",
+				Mode:      "protocol",
+				Protocol:  "openai",
+				Endpoints: []instance.EndpointConfig{{Path: "/openai/compliant"}},
+				Transformation: &instance.TransformationConfig{
+					RequestTo:    "openai",
+					ResponseFrom: "openai",
+					ResponseFields: &instance.ResponseFieldFilter{
+						Remove: []string{"system_fingerprint", "service_tier"},
+						Add:    map[string]interface{}{"region": "eu"},
+					},
+				},
+			},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	provider := &stubProvider{
+		name:   "openai",
+		invoke: respondWith(`{"id":"upstream","object":"chat.completion","model":"m","system_fingerprint":"fp_1","service_tier":"default","choices":[{"index":0,"message":{"role":"assistant","content":"hi"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1,"total_tokens":4}}`, nil),
+	}
+	h := NewProtocolHandler(map[string]providers.Provider{"openai": provider}, currentConfig(config), nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/openai/*path", h.HandleRequest)
+	req := httptest.NewRequest(http.MethodPost, "/openai/compliant/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+
+	var resp map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response %s: %v", w.Body, err)
+	}
+	if _, ok := resp["system_fingerprint"]; ok {
+		t.Errorf("system_fingerprint not removed: %s", w.Body)
+	}
+	if _, ok := resp["service_tier"]; ok {
+		t.Errorf("service_tier not removed: %s", w.Body)
+	}
+	if resp["region"] != "eu" || resp["choices"] == nil || resp["usage"] == nil {
+		t.Errorf("response = %s", w.Body)
+	}
+}
+
+// TestProtocolImageLimits tests that inline images over the instance's
+// limits reach the provider downscaled
+func TestProtocolImageLimits(t *testing.T) {
+	config := &instance.Config{Instances: map[string]instance.InstanceConfig{
+		"openai-primary": {
+			Type:        "openai",
+			Mode:        "protocol",
+			Protocol:    "openai",
+			Endpoints:   []instance.EndpointConfig{{Path: "/openai/primary"}},
+			ImageLimits: translator.ImageLimits{MaxDimension: 64},
+		},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	primary := &stubProvider{name: "openai", invoke: respondWith(openaiBody, nil)}
+	h := NewProtocolHandler(map[string]providers.Provider{"openai": primary}, currentConfig(config), nil)
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, image.NewGray(image.Rect(0, 0, 256, 128))); err != nil {
+		t.Fatal(err)
+	}
+	imageURL := "data:image/png;base64," + base64.StdEncoding.EncodeToString(encoded.Bytes())
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/openai/*path", h.HandleRequest)
+	req := httptest.NewRequest(http.MethodPost, "/openai/primary/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":[{"type":"image_url","image_url":{"url":"`+imageURL+`"}}]}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var sent translator.ChatCompletionRequest
+	if err := json.Unmarshal(primary.lastReq.Load().Body, &sent); err != nil {
+		t.Fatalf("provider request %s: %v", primary.lastReq.Load().Body, err)
+	}
+	if url := sent.Messages[0].Content[0].ImageURL.URL; !strings.HasPrefix(url, "data:image/jpeg;base64,") {
+		t.Errorf("provider image = %.40s..., want a downscaled JPEG", url)
+	}
+}
+
+// TestProtocolResponsePassthrough tests that response_to: passthrough
+// returns the provider's body and content type unchanged, while usage is
+// still charged to the caller
+func TestProtocolResponsePassthrough(t *testing.T) {
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"bedrock-native": {
+				Type:      "bedrock",
+				Mode:      "protocol",
+				Protocol:  "openai",
+				Endpoints: []instance.EndpointConfig{{Path: "/openai/native"}},
+				Transformation: &instance.TransformationConfig{
+					RequestFrom:  "openai",
+					RequestTo:    "bedrock_converse",
+					ResponseFrom: "bedrock_converse",
+					ResponseTo:   instance.ResponsePassthrough,
+				},
+			},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	const contentType = "application/vnd.amazon.converse+json"
+	native := `{"output":{"message":{"role":"assistant","content":[{"text":"hi"}]}},"stopReason":"end_turn","usage":{"inputTokens":3,"outputTokens":1,"totalTokens":4}}`
+	provider := &stubProvider{
+		name:   "bedrock",
+		invoke: respondWith(native, http.Header{"Content-Type": {contentType}}),
+	}
+	h := NewProtocolHandler(map[string]providers.Provider{"bedrock": provider}, currentConfig(config), nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var usage interface{}
+	engine.Use(func(c *gin.Context) {
+		c.Next()
+		usage, _ = c.Get(ratelimit.UsageTokensKey)
+	})
+	engine.POST("/openai/*path", h.HandleRequest)
+	req := httptest.NewRequest(http.MethodPost, "/openai/native/chat/completions",
+		strings.NewReader(`{"model":"claude-3-haiku","messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	if w.Body.String() != native {
+		t.Errorf("body = %s, want the provider's body unchanged", w.Body)
+	}
+	if got := w.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("Content-Type = %q, want %q", got, contentType)
+	}
+	if usage != 4 {
+		t.Errorf("usage tokens = %v, want 4", usage)
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// RequestIDFactory generates chat completion IDs. Tests can inject a
+// deterministic factory with SetRequestIDFactory.
+type RequestIDFactory interface {
+	NewRequestID() string
+}
+
+// RequestIDFactoryFunc adapts a function to RequestIDFactory
+type RequestIDFactoryFunc func() string
+
+// NewRequestID calls f
+func (f RequestIDFactoryFunc) NewRequestID() string {
+	return f()
+}
+
+// UUIDRequestIDFactory generates IDs in OpenAI's format from random UUIDs:
+// chatcmpl- followed by 24 hex characters (96 random bits), so IDs do not
+// collide under concurrent load
+type UUIDRequestIDFactory struct{}
+
+// NewRequestID returns a new chatcmpl- ID
+func (UUIDRequestIDFactory) NewRequestID() string {
+	return "chatcmpl-" + strings.ReplaceAll(uuid.New().String(), "-", "")[:24]
+}
+
+// newRequestID generates an ID with factory, or the UUID factory if nil
+func newRequestID(factory RequestIDFactory) string {
+	if factory == nil {
+		factory = UUIDRequestIDFactory{}
+	}
+	return factory.NewRequestID()
+}
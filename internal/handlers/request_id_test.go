@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sync"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+func TestUUIDRequestIDFactory(t *testing.T) {
+	format := regexp.MustCompile(`^chatcmpl-[0-9a-f]{24}$`)
+
+	const goroutines, perGoroutine = 16, 500
+	var mu sync.Mutex
+	seen := make(map[string]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < perGoroutine; j++ {
+				id := UUIDRequestIDFactory{}.NewRequestID()
+				mu.Lock()
+				seen[id] = true
+				mu.Unlock()
+				if !format.MatchString(id) {
+					t.Errorf("request ID %q does not match %s", id, format)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(seen) != goroutines*perGoroutine {
+		t.Errorf("generated %d unique IDs, want %d", len(seen), goroutines*perGoroutine)
+	}
+}
+
+func TestSetRequestIDFactory(t *testing.T) {
+	h, _ := newChatTestHandler(t)
+	h.SetRequestIDFactory(RequestIDFactoryFunc(func() string { return "chatcmpl-fixed" }))
+
+	w := postChat(h, `{"model":"claude-3-sonnet","messages":[{"role":"user","content":"hello"}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp translator.ChatCompletionResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response %s: %v", w.Body, err)
+	}
+	if resp.ID != "chatcmpl-fixed" {
+		t.Errorf("response ID = %q, want chatcmpl-fixed", resp.ID)
+	}
+}
@@ -0,0 +1,281 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// RerankHandler handles document reranking requests
+type RerankHandler struct {
+	providers map[string]providers.Provider
+	drains    DrainState    // Optional: drained providers take no new requests
+	slots     providerSlots // Optional: concurrency limits of provider instances
+}
+
+// RerankRequest represents a rerank request
+type RerankRequest struct {
+	Model     string   `json:"model"`
+	Query     string   `json:"query"`
+	Documents []string `json:"documents"`
+	TopN      int      `json:"top_n,omitempty"`
+}
+
+// RerankResponse represents a normalised rerank response
+type RerankResponse struct {
+	Model   string         `json:"model"`
+	Results []RerankResult `json:"results"`
+}
+
+// RerankResult is a single scored document, ordered by relevance
+type RerankResult struct {
+	Index          int     `json:"index"`
+	RelevanceScore float64 `json:"relevance_score"`
+	Document       string  `json:"document"`
+}
+
+// bedrockRerankRequest is the InvokeModel body for Bedrock rerank models
+type bedrockRerankRequest struct {
+	Query      string   `json:"query"`
+	Documents  []string `json:"documents"`
+	TopN       int      `json:"top_n,omitempty"`
+	APIVersion int      `json:"api_version,omitempty"` // Required by cohere.rerank-* on Bedrock
+}
+
+// cohereRerankRequest is the body for Cohere's /v1/rerank API
+type cohereRerankRequest struct {
+	Model           string   `json:"model"`
+	Query           string   `json:"query"`
+	Documents       []string `json:"documents"`
+	TopN            int      `json:"top_n,omitempty"`
+	ReturnDocuments bool     `json:"return_documents"`
+}
+
+// rerankProviderResponse covers both the Bedrock and Cohere response shapes
+type rerankProviderResponse struct {
+	Results []struct {
+		Index          int     `json:"index"`
+		RelevanceScore float64 `json:"relevance_score"`
+	} `json:"results"`
+}
+
+// NewRerankHandler creates a new rerank handler
+func NewRerankHandler(providerRegistry map[string]providers.Provider) *RerankHandler {
+	return &RerankHandler{
+		providers: providerRegistry,
+	}
+}
+
+// SetDrainState rejects requests for the models of drained providers with
+// 503
+func (h *RerankHandler) SetDrainState(drains DrainState) {
+	h.drains = drains
+}
+
+// SetSemaphores enables the concurrency limits of provider instances: a
+// request takes a slot of the instance serving its model, the one GET
+// /v1/route reports
+func (h *RerankHandler) SetSemaphores(semaphores *providers.SemaphoreRegistry, instances *atomic.Pointer[instance.Config]) {
+	h.slots = providerSlots{semaphores: semaphores, instances: instances}
+}
+
+// Rerank handles POST /v1/rerank
+func (h *RerankHandler) Rerank(c *gin.Context) {
+	startTime := time.Now()
+
+	var req RerankRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "Invalid request body",
+				Type:    "invalid_request_error",
+				Code:    "invalid_json",
+			},
+		})
+		return
+	}
+
+	if req.Model == "" || req.Query == "" || len(req.Documents) == 0 {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "model, query and documents are required",
+				Type:    "invalid_request_error",
+				Code:    "missing_required_field",
+			},
+		})
+		return
+	}
+
+	providerName := rerankProviderForModel(req.Model)
+	provider, ok := h.providers[providerName]
+	if !ok {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: fmt.Sprintf("Model %q requires provider %q, which is not configured", req.Model, providerName),
+				Type:    "invalid_request_error",
+				Code:    "model_not_found",
+			},
+		})
+		return
+	}
+	if err := drainError(h.drains, providerName); err != nil {
+		respondError(c, http.StatusServiceUnavailable, "service_error", "provider_draining",
+			fmt.Sprintf("Model %q is not available: %v", req.Model, err))
+		return
+	}
+
+	providerReq, err := translateRerankRequest(providerName, &req)
+	if err != nil {
+		respondJSON(c, http.StatusBadRequest, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: fmt.Sprintf("Failed to translate request: %v", err),
+				Type:    "invalid_request_error",
+				Code:    "translation_failed",
+			},
+		})
+		return
+	}
+	providerReq.Context = c.Request.Context()
+
+	// Wait for a concurrency slot
+	release, err := h.slots.acquire(c, providerName, req.Model)
+	if err != nil {
+		log.Printf("Provider %s at capacity: %v", providerName, err)
+		respondError(c, http.StatusServiceUnavailable, "service_error", "queue_timeout",
+			fmt.Sprintf("Provider %s is at capacity, retry later", providerName))
+		return
+	}
+	defer release()
+
+	log.Printf("Routing rerank model %s to provider %s", req.Model, providerName)
+
+	ForwardCorrelation(c, providerReq)
+	defer recordPayloadSizes(c, providerName, "", int64(len(providerReq.Body)))
+	setAuditTarget(c, req.Model, providerName, "")
+	providerResp, err := provider.Invoke(c.Request.Context(), providerReq)
+	if err != nil {
+		log.Printf("Provider invocation error: %v", err)
+		writeProviderError(c, err)
+		return
+	}
+	RecordUpstreamRequestID(c, providerResp.Headers)
+	mergeUpstreamHeaders(c.Writer.Header(), providerResp.Headers)
+
+	resp, err := normaliseRerankResponse(providerResp.Body, &req)
+	if err != nil {
+		log.Printf("Failed to parse rerank response: %v", err)
+		respondJSON(c, http.StatusInternalServerError, translator.ErrorResponse{
+			Error: translator.ErrorDetail{
+				Message: "Failed to parse provider response",
+				Type:    "internal_error",
+				Code:    "response_parse_error",
+			},
+		})
+		return
+	}
+
+	duration := time.Since(startTime)
+	metrics.RequestDuration.WithLabelValues("POST", "200").Observe(duration.Seconds())
+	metrics.RequestsTotal.WithLabelValues("POST", "200").Inc()
+
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// rerankProviderForModel picks the provider serving a rerank model.
+// Bedrock model IDs (amazon.rerank-*, cohere.rerank-*) go to Bedrock;
+// everything else is treated as a native Cohere model.
+func rerankProviderForModel(model string) string {
+	if strings.HasPrefix(model, "amazon.rerank") || strings.HasPrefix(model, "cohere.rerank") {
+		return "bedrock"
+	}
+	return "cohere"
+}
+
+// translateRerankRequest builds the provider request for a rerank call
+func translateRerankRequest(providerName string, req *RerankRequest) (*providers.ProviderRequest, error) {
+	var path string
+	var body []byte
+	var err error
+
+	switch providerName {
+	case "bedrock":
+		bedrockReq := bedrockRerankRequest{
+			Query:     req.Query,
+			Documents: req.Documents,
+			TopN:      req.TopN,
+		}
+		if strings.HasPrefix(req.Model, "cohere.") {
+			bedrockReq.APIVersion = 2
+		}
+		path = fmt.Sprintf("/model/%s/invoke", req.Model)
+		body, err = json.Marshal(bedrockReq)
+	default:
+		path = "/rerank"
+		body, err = json.Marshal(cohereRerankRequest{
+			Model:     req.Model,
+			Query:     req.Query,
+			Documents: req.Documents,
+			TopN:      req.TopN,
+		})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal rerank request: %w", err)
+	}
+
+	return &providers.ProviderRequest{
+		Method: "POST",
+		Path:   path,
+		Headers: http.Header{
+			"Content-Type": {"application/json"},
+			"Accept":       {"application/json"},
+		},
+		Body: body,
+	}, nil
+}
+
+// normaliseRerankResponse converts a provider response into a RerankResponse,
+// filling in document text from the original request
+func normaliseRerankResponse(body []byte, req *RerankRequest) (*RerankResponse, error) {
+	var providerResp rerankProviderResponse
+	if err := json.Unmarshal(body, &providerResp); err != nil {
+		return nil, err
+	}
+
+	results := make([]RerankResult, 0, len(providerResp.Results))
+	for _, r := range providerResp.Results {
+		if r.Index < 0 || r.Index >= len(req.Documents) {
+			return nil, fmt.Errorf("result index %d out of range", r.Index)
+		}
+		results = append(results, RerankResult{
+			Index:          r.Index,
+			RelevanceScore: r.RelevanceScore,
+			Document:       req.Documents[r.Index],
+		})
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelevanceScore > results[j].RelevanceScore
+	})
+	if req.TopN > 0 && len(results) > req.TopN {
+		results = results[:req.TopN]
+	}
+
+	return &RerankResponse{
+		Model:   req.Model,
+		Results: results,
+	}, nil
+}
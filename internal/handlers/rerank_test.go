@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// TestTranslateRerankRequest tests provider selection and request body shape
+func TestTranslateRerankRequest(t *testing.T) {
+	tests := []struct {
+		model      string
+		provider   string
+		path       string
+		apiVersion bool
+	}{
+		{"amazon.rerank-v1:0", "bedrock", "/model/amazon.rerank-v1:0/invoke", false},
+		{"cohere.rerank-v3-5:0", "bedrock", "/model/cohere.rerank-v3-5:0/invoke", true},
+		{"rerank-v3.5", "cohere", "/rerank", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			req := &RerankRequest{Model: tt.model, Query: "q", Documents: []string{"a", "b"}, TopN: 1}
+
+			provider := rerankProviderForModel(tt.model)
+			if provider != tt.provider {
+				t.Fatalf("provider: got %q, want %q", provider, tt.provider)
+			}
+
+			providerReq, err := translateRerankRequest(provider, req)
+			if err != nil {
+				t.Fatalf("translateRerankRequest: %v", err)
+			}
+			if providerReq.Path != tt.path {
+				t.Errorf("path: got %q, want %q", providerReq.Path, tt.path)
+			}
+
+			var body map[string]interface{}
+			if err := json.Unmarshal(providerReq.Body, &body); err != nil {
+				t.Fatalf("unmarshal body: %v", err)
+			}
+			if _, ok := body["api_version"]; ok != tt.apiVersion {
+				t.Errorf("api_version present: got %v, want %v", ok, tt.apiVersion)
+			}
+			if _, ok := body["model"]; ok != (provider == "cohere") {
+				t.Errorf("model field present: got %v", ok)
+			}
+		})
+	}
+}
+
+// TestNormaliseRerankResponse tests ordering, top_n and document filling
+func TestNormaliseRerankResponse(t *testing.T) {
+	req := &RerankRequest{Model: "amazon.rerank-v1:0", Documents: []string{"zero", "one", "two"}, TopN: 2}
+	body := []byte(`{"results":[{"index":2,"relevance_score":0.1},{"index":0,"relevance_score":0.9},{"index":1,"relevance_score":0.5}]}`)
+
+	resp, err := normaliseRerankResponse(body, req)
+	if err != nil {
+		t.Fatalf("normaliseRerankResponse: %v", err)
+	}
+	if len(resp.Results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(resp.Results))
+	}
+	if resp.Results[0].Index != 0 || resp.Results[0].Document != "zero" {
+		t.Errorf("unexpected first result: %+v", resp.Results[0])
+	}
+	if resp.Results[1].Index != 1 || resp.Results[1].Document != "one" {
+		t.Errorf("unexpected second result: %+v", resp.Results[1])
+	}
+
+	if _, err := normaliseRerankResponse([]byte(`{"results":[{"index":5,"relevance_score":1}]}`), req); err == nil {
+		t.Error("expected error for out-of-range index")
+	}
+}
+
+// TestRerankDraining tests that requests for the models of a drained
+// provider are rejected without invoking it until it is restored
+func TestRerankDraining(t *testing.T) {
+	provider := &stubProvider{
+		name:   "cohere",
+		invoke: respondWith(`{"results":[{"index":0,"relevance_score":0.9}]}`, nil),
+	}
+	registry := map[string]providers.Provider{"cohere": provider}
+	drains := drainedRouter(t, registry, "cohere")
+	h := NewRerankHandler(registry)
+	h.SetDrainState(drains)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/v1/rerank", h.Rerank)
+	serve := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/rerank",
+			strings.NewReader(`{"model":"rerank-v3.5","query":"q","documents":["a"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve()
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "provider_draining") {
+		t.Errorf("drained: status %d body %s, want 503 provider_draining", w.Code, w.Body)
+	}
+	if provider.lastReq.Load() != nil {
+		t.Error("the drained provider was invoked")
+	}
+
+	drains.Restore("cohere")
+	if w := serve(); w.Code != http.StatusOK {
+		t.Errorf("restored: status %d body %s, want 200", w.Code, w.Body)
+	}
+}
+
+// TestRerankQueueTimeout tests that a request waits for a slot of the
+// instance serving its model and gets 503 when none frees up
+func TestRerankQueueTimeout(t *testing.T) {
+	provider := &stubProvider{name: "cohere"}
+	h := NewRerankHandler(map[string]providers.Provider{"cohere": provider})
+	h.SetSemaphores(saturatedInstance(t, "cohere", "cohere-main"))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/v1/rerank", h.Rerank)
+	req := httptest.NewRequest(http.MethodPost, "/v1/rerank",
+		strings.NewReader(`{"model":"rerank-v3.5","query":"q","documents":["a"]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "queue_timeout") {
+		t.Errorf("status %d body %s, want 503 queue_timeout", w.Code, w.Body)
+	}
+	if provider.lastReq.Load() != nil {
+		t.Error("the provider was invoked without a slot")
+	}
+}
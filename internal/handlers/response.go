@@ -0,0 +1,100 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeYAML = "application/yaml"
+)
+
+// negotiateFormat picks JSON or YAML for the Accept header, preferring the
+// highest q-value and the earlier type on ties. Wildcards and a missing
+// header select JSON. ok is false if only unsupported types are accepted.
+func negotiateFormat(accept string) (contentType string, ok bool) {
+	if strings.TrimSpace(accept) == "" {
+		return contentTypeJSON, true
+	}
+
+	bestQ := 0.0
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, exists := params["q"]; exists {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+
+		var candidate string
+		switch mediaType {
+		case contentTypeJSON, "application/*", "*/*":
+			candidate = contentTypeJSON
+		case contentTypeYAML, "application/x-yaml", "text/yaml":
+			candidate = contentTypeYAML
+		default:
+			continue
+		}
+		if q > bestQ {
+			contentType, bestQ = candidate, q
+		}
+	}
+	return contentType, contentType != ""
+}
+
+// wantsPretty reports whether the client asked for indented JSON with
+// ?pretty=1 or X-Pretty: true. Responses are compact by default.
+func wantsPretty(r *http.Request) bool {
+	if pretty, err := strconv.ParseBool(r.URL.Query().Get("pretty")); err == nil && pretty {
+		return true
+	}
+	pretty, err := strconv.ParseBool(r.Header.Get("X-Pretty"))
+	return err == nil && pretty
+}
+
+// respondJSON writes a JSON response, indented if the client asked for it.
+// It is for error and non-streaming responses; SSE chunks are always compact.
+func respondJSON(c *gin.Context, status int, obj interface{}) {
+	if wantsPretty(c.Request) {
+		c.IndentedJSON(status, obj)
+		return
+	}
+	c.JSON(status, obj)
+}
+
+// writeJSON is the net/http equivalent of respondJSON
+func writeJSON(w http.ResponseWriter, r *http.Request, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+
+	encoder := json.NewEncoder(w)
+	if wantsPretty(r) {
+		encoder.SetIndent("", "    ")
+	}
+	encoder.Encode(obj)
+}
+
+// respondYAML writes obj as YAML. Types served this way carry yaml tags
+// matching their json tags, so both formats share one schema.
+func respondYAML(c *gin.Context, status int, obj interface{}) {
+	body, err := yaml.Marshal(obj)
+	if err != nil {
+		respondError(c, http.StatusInternalServerError, "api_error", "internal_error", "Failed to encode response")
+		return
+	}
+	c.Data(status, contentTypeYAML, body)
+}
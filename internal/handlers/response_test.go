@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRespondJSONPretty(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/status", func(c *gin.Context) {
+		respondJSON(c, http.StatusOK, gin.H{"status": "ok"})
+	})
+
+	tests := []struct {
+		name   string
+		target string
+		header string
+		pretty bool
+	}{
+		{"default compact", "/status", "", false},
+		{"query", "/status?pretty=1", "", true},
+		{"header", "/status", "true", true},
+		{"disabled", "/status?pretty=0", "false", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			if tt.header != "" {
+				req.Header.Set("X-Pretty", tt.header)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if got := strings.Contains(w.Body.String(), "\n"); got != tt.pretty {
+				t.Errorf("body %q, want pretty=%v", w.Body, tt.pretty)
+			}
+		})
+	}
+}
+
+func TestWriteJSONPretty(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/v1/chat/completions?pretty=true", nil)
+	w := httptest.NewRecorder()
+	writeJSON(w, req, http.StatusBadRequest, map[string]string{"error": "bad"})
+
+	if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/json" {
+		t.Errorf("status %d, Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w.Body.String() != "{\n    \"error\": \"bad\"\n}\n" {
+		t.Errorf("body %q, want indented JSON", w.Body)
+	}
+}
+
+func TestNegotiateFormat(t *testing.T) {
+	tests := []struct {
+		accept string
+		want   string
+		ok     bool
+	}{
+		{"", contentTypeJSON, true},
+		{"application/json", contentTypeJSON, true},
+		{"application/yaml", contentTypeYAML, true},
+		{"text/html, application/yaml", contentTypeYAML, true},
+		{"application/yaml;q=0.5, application/json", contentTypeJSON, true},
+		{"application/json;q=0.5, application/yaml", contentTypeYAML, true},
+		{"*/*", contentTypeJSON, true},
+		{"text/html", "", false},
+		{"application/xml, text/plain", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := negotiateFormat(tt.accept)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("negotiateFormat(%q) = %q, %v, want %q, %v", tt.accept, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// RouteHandler reports where chat completion requests for a model would be
+// routed, without invoking the provider
+type RouteHandler struct {
+	router *router.Router
+	config *atomic.Pointer[instance.Config] // Optional: provider instances configuration in effect
+}
+
+// RouteResponse is the effective routing for a model
+type RouteResponse struct {
+	Model           string              `json:"model"`
+	Provider        string              `json:"provider"`
+	ProviderModel   string              `json:"provider_model"`
+	DefaultProvider string              `json:"default_provider"`
+	Fallback        bool                `json:"fallback"` // the default provider is unavailable
+	Region          string              `json:"region,omitempty"`
+	Instance        string              `json:"instance,omitempty"`
+	Transformation  RouteTransformation `json:"transformation"`
+	Capabilities    []string            `json:"capabilities"`
+}
+
+// RouteTransformation describes how requests and responses are translated
+type RouteTransformation struct {
+	RequestFrom  string                 `json:"request_from"`
+	RequestTo    string                 `json:"request_to"`
+	ResponseFrom string                 `json:"response_from"`
+	ResponseTo   string                 `json:"response_to"`
+	Options      map[string]interface{} `json:"options,omitempty"` // the instance's transformation options
+}
+
+// NewRouteHandler creates a new route introspection handler. config may be nil.
+func NewRouteHandler(r *router.Router, config *atomic.Pointer[instance.Config]) *RouteHandler {
+	return &RouteHandler{
+		router: r,
+		config: config,
+	}
+}
+
+// instances returns the provider instances config in effect, or nil
+func (h *RouteHandler) instances() *instance.Config {
+	if h.config == nil {
+		return nil
+	}
+	return h.config.Load()
+}
+
+// GetRoute handles GET /v1/route?model=...&provider=..., resolving the model
+// exactly as POST /v1/chat/completions would. provider is the optional
+// preferred provider.
+func (h *RouteHandler) GetRoute(c *gin.Context) {
+	model := c.Query("model")
+	if model == "" {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "missing_model", "The model query parameter is required")
+		return
+	}
+
+	provider, modelInfo, err := h.router.RouteRequest(c.Request.Context(), model, c.Query("provider"))
+	if errors.Is(err, router.ErrProviderDraining) {
+		respondError(c, http.StatusServiceUnavailable, "service_error", "provider_draining", err.Error())
+		return
+	}
+	if err != nil {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "model_not_found", err.Error())
+		return
+	}
+
+	routerConfig := h.router.GetConfig()
+	resp := RouteResponse{
+		Model:           model,
+		Provider:        provider.Name(),
+		ProviderModel:   modelInfo.Model,
+		DefaultProvider: routerConfig.GetDefaultProvider(model),
+		Region:          modelInfo.Region,
+		Transformation: RouteTransformation{
+			RequestFrom:  "openai",
+			RequestTo:    provider.Name(),
+			ResponseFrom: provider.Name(),
+			ResponseTo:   "openai",
+		},
+		Capabilities: []string{},
+	}
+	resp.Fallback = resp.DefaultProvider != "" && resp.DefaultProvider != resp.Provider && c.Query("provider") == ""
+
+	if resp.Region == "" {
+		resp.Region = modelInfo.Location
+	}
+	if providerConfig, ok := routerConfig.GetProviderConfig(provider.Name()); ok && resp.Region == "" {
+		resp.Region = providerConfig.Region
+		if resp.Region == "" {
+			resp.Region = providerConfig.Location
+		}
+	}
+
+	// The instance serving the model
+	if config := h.instances(); config != nil {
+		if instanceCfg, name, err := servingInstance(config, model, provider.Name()); err == nil {
+			resp.Instance = name
+			if resp.Region == "" {
+				resp.Region = instanceCfg.Region
+			}
+			if t := instanceCfg.Transformation; t != nil {
+				resp.Transformation.Options = t.Options
+			}
+		}
+	}
+
+	// The model's own capabilities, else those of its provider
+	if info, err := provider.GetModelInfo(c.Request.Context(), modelInfo.Model); err == nil && info.Capabilities != nil {
+		resp.Capabilities = info.Capabilities
+	} else {
+		resp.Capabilities = providers.CapabilitiesOf(provider).List()
+	}
+
+	respondJSON(c, http.StatusOK, resp)
+}
+
+// servingInstance returns the instance serving requests for a model routed
+// to a provider type: a pin of the model to an instance of that type, else
+// the type's default instance
+func servingInstance(config *instance.Config, model, providerType string) (*instance.InstanceConfig, string, error) {
+	instanceCfg, name, err := config.GetPinnedInstance(model)
+	if err != nil || instanceCfg.Type != providerType {
+		instanceCfg, name, err = config.GetDefaultInstance(providerType)
+	}
+	return instanceCfg, name, err
+}
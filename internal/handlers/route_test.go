@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+)
+
+func TestGetRoute(t *testing.T) {
+	chat, stubs := newChatTestHandler(t)
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"bedrock-us": {
+				Type:           "bedrock",
+				Mode:           "protocol",
+				Region:         "us-east-1",
+				Transformation: &instance.TransformationConfig{Options: map[string]interface{}{"default_max_tokens": 1024}},
+			},
+		},
+	}
+	config.Routing.Defaults = map[string]string{"bedrock": "bedrock-us"}
+	h := NewRouteHandler(chat.router, currentConfig(config))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/v1/route", h.GetRoute)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/route?model=claude-3-sonnet", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	var resp RouteResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Provider != "bedrock" || resp.ProviderModel != "anthropic.claude-3-sonnet-20240229-v1:0" || resp.Fallback {
+		t.Errorf("route = %+v, want bedrock default", resp)
+	}
+	if resp.Instance != "bedrock-us" || resp.Region != "us-east-1" {
+		t.Errorf("instance = %q, region = %q, want bedrock-us in us-east-1", resp.Instance, resp.Region)
+	}
+	if resp.Transformation.RequestFrom != "openai" || resp.Transformation.RequestTo != "bedrock" || resp.Transformation.Options["default_max_tokens"] != float64(1024) {
+		t.Errorf("transformation = %+v", resp.Transformation)
+	}
+	if stubs["bedrock"].calls.Load() != 0 || stubs["bedrock"].lastReq.Load() != nil {
+		t.Error("provider was invoked")
+	}
+
+	for query, want := range map[string]int{
+		"":                     http.StatusBadRequest,
+		"?model=unknown-model": http.StatusNotFound,
+	} {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/route"+query, nil))
+		if w.Code != want {
+			t.Errorf("GET /v1/route%s status = %d, want %d", query, w.Code, want)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// streamErrorCode is the error code of the event sent when a provider
+// stream fails after the response has started
+const streamErrorCode = "stream_error"
+
+// copyStream proxies a provider stream to the client, flushing after each
+// read. It returns nil at the end of the stream and the read or write error
+// otherwise.
+func copyStream(w io.Writer, flusher http.Flusher, stream io.Reader) error {
+	buf := make([]byte, 4096)
+	for {
+		n, err := stream.Read(buf)
+		if n > 0 {
+			if _, writeErr := w.Write(buf[:n]); writeErr != nil {
+				return writeErr
+			}
+			flusher.Flush()
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeStreamError ends a stream that failed after headers were sent. The
+// status code can no longer change, so an OpenAI-style error event is sent
+// for clients to detect the failure instead of a silently truncated stream.
+// Nothing is sent if the client has gone away.
+func writeStreamError(ctx context.Context, w io.Writer, flusher http.Flusher, providerName string, err error) {
+	if ctx.Err() != nil {
+		log.Printf("Stream from %s ended: client disconnected", providerName)
+		return
+	}
+
+	metrics.StreamErrors.WithLabelValues(providerName).Inc()
+	log.Printf("Stream from %s failed: %v", providerName, err)
+
+	message := "Provider stream ended unexpectedly"
+	var providerErr *providers.ProviderError
+	if errors.As(err, &providerErr) && providerErr.Message != "" {
+		message = providerErr.Message
+	}
+	event, marshalErr := json.Marshal(translator.ErrorResponse{
+		Error: translator.ErrorDetail{
+			Message: message,
+			Type:    "provider_error",
+			Code:    streamErrorCode,
+		},
+	})
+	if marshalErr != nil {
+		return
+	}
+	fmt.Fprintf(w, "data: %s\n\n", event)
+	flusher.Flush()
+}
+
+// writeChatEvents writes typed provider stream events as OpenAI SSE chunks,
+// then the usage chunk if includeUsage and [DONE]. It returns the error of a
+// ChatEventError, the error writing to the client, or ctx's error if the
+// client went away.
+func writeChatEvents(
+	ctx context.Context,
+	w io.Writer,
+	flusher http.Flusher,
+	events <-chan providers.ChatEvent,
+	tracker *translator.StreamUsageTracker,
+	id, model string,
+	includeUsage bool,
+) error {
+	created := time.Now().Unix()
+	writeChunk := func(chunk *translator.ChatCompletionStreamResponse) error {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return nil
+		}
+		tracker.Observe(data)
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+
+	for event := range events {
+		switch event.Type {
+		case providers.ChatEventError:
+			return event.Err
+		case providers.ChatEventUsage:
+			tracker.SetUsage(translator.ChatEventUsage(event.Usage))
+		default:
+			if chunk := translator.ChatEventChunk(event, id, model, created); chunk != nil {
+				if err := writeChunk(chunk); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if includeUsage {
+		if err := writeChunk(tracker.UsageChunk()); err != nil {
+			return err
+		}
+	}
+	if _, err := fmt.Fprintf(w, "data: %s\n\n", translator.StreamDone); err != nil {
+		return err
+	}
+	flusher.Flush()
+	return nil
+}
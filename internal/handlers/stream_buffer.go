@@ -0,0 +1,240 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// slowClientErrorCode is the error code of the event sent when a stream is
+// cancelled because its client stopped reading
+const slowClientErrorCode = "slow_client"
+
+// errSlowClient is returned by streamBuffer writes when the client has not
+// kept up with the stream for longer than the slow client timeout
+var errSlowClient = errors.New("client is not reading the stream")
+
+// StreamBackpressure configures the buffer between provider streams and
+// clients
+type StreamBackpressure struct {
+	// BufferChunks is how many provider reads are held for a client that
+	// reads more slowly than the provider writes (default: 64)
+	BufferChunks int
+
+	// SlowClientTimeout is how long the buffer may stay full, or one write
+	// to the client may block, before the stream is cancelled (default: 30s)
+	SlowClientTimeout time.Duration
+}
+
+// DefaultStreamBackpressure is used by handlers without
+// SetStreamBackpressure
+var DefaultStreamBackpressure = StreamBackpressure{BufferChunks: 64, SlowClientTimeout: 30 * time.Second}
+
+// withDefaults fills unset fields from DefaultStreamBackpressure
+func (s StreamBackpressure) withDefaults() StreamBackpressure {
+	if s.BufferChunks <= 0 {
+		s.BufferChunks = DefaultStreamBackpressure.BufferChunks
+	}
+	if s.SlowClientTimeout <= 0 {
+		s.SlowClientTimeout = DefaultStreamBackpressure.SlowClientTimeout
+	}
+	return s
+}
+
+// streamBuffer decouples reading a provider stream from writing it to the
+// client. Writes are queued and written by a separate goroutine, flushing
+// whenever the queue empties, so the provider stream keeps being read
+// while the client catches up. A write fails with errSlowClient when the
+// queue stays full, or a write to the client blocks, for longer than the
+// slow client timeout; the stream should then be cancelled and ended with
+// abort.
+//
+// Write and Flush must be called from one goroutine, and Close or abort
+// once when the stream ends.
+type streamBuffer struct {
+	w       io.Writer
+	flusher http.Flusher
+	rc      *http.ResponseController // nil when w is not a ResponseWriter
+	timeout time.Duration
+
+	queue   chan []byte
+	aborted atomic.Bool
+	done    chan struct{}
+
+	writeErr error // client write error, set by the pump before done closes
+	err      error // first failed Write, returned by later ones
+}
+
+// newStreamBuffer starts buffering writes to a client
+func newStreamBuffer(w io.Writer, flusher http.Flusher, config StreamBackpressure) *streamBuffer {
+	config = config.withDefaults()
+	b := &streamBuffer{
+		w:       w,
+		flusher: flusher,
+		timeout: config.SlowClientTimeout,
+		queue:   make(chan []byte, config.BufferChunks),
+		done:    make(chan struct{}),
+	}
+	if rw, ok := w.(http.ResponseWriter); ok {
+		b.rc = http.NewResponseController(rw)
+	}
+	// Send the headers now, so the pump's first write does not read them
+	// while the handler still sets trailers
+	if headerWriter, ok := w.(interface{ WriteHeaderNow() }); ok {
+		headerWriter.WriteHeaderNow()
+	}
+	go b.pump()
+	return b
+}
+
+// Write queues a copy of p for the client, waiting up to the slow client
+// timeout for room in the queue
+func (b *streamBuffer) Write(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	chunk := append([]byte(nil), p...)
+	select {
+	case b.queue <- chunk:
+		return len(p), nil
+	default:
+	}
+
+	timer := time.NewTimer(b.timeout)
+	defer timer.Stop()
+	select {
+	case b.queue <- chunk:
+		return len(p), nil
+	case <-b.done:
+		b.err = b.writeErr
+	case <-timer.C:
+		b.err = errSlowClient
+	}
+	return 0, b.err
+}
+
+// Flush is a no-op: the pump flushes whenever it empties the queue
+func (b *streamBuffer) Flush() {}
+
+// pump writes queued chunks to the client until the queue is closed or a
+// write fails. Each write gets the slow client timeout as its deadline, so
+// a client that stops reading cannot block it indefinitely.
+func (b *streamBuffer) pump() {
+	defer close(b.done)
+	for chunk := range b.queue {
+		if b.aborted.Load() {
+			continue
+		}
+		b.setWriteDeadline(time.Now().Add(b.timeout))
+		if _, err := b.w.Write(chunk); err != nil {
+			if isTimeout(err) {
+				err = errSlowClient
+			}
+			b.writeErr = err
+			return
+		}
+		if len(b.queue) == 0 {
+			b.flusher.Flush()
+		}
+	}
+}
+
+// setWriteDeadline sets the client connection's write deadline, where the
+// writer supports it
+func (b *streamBuffer) setWriteDeadline(deadline time.Time) {
+	if b.rc != nil {
+		b.rc.SetWriteDeadline(deadline)
+	}
+}
+
+// Close waits for the queued chunks to be written and returns the error
+// writing them, if any
+func (b *streamBuffer) Close() error {
+	close(b.queue)
+	<-b.done
+	b.setWriteDeadline(time.Time{})
+	return b.writeErr
+}
+
+// abort drops the queued chunks, waits for the write in progress and ends
+// the stream with a slow_client error event, unless the client is gone.
+// The write in progress ends by its deadline; if the writer does not
+// support deadlines and it is still blocked after the slow client timeout,
+// abort returns without the event rather than wait for the client.
+func (b *streamBuffer) abort(providerName string) {
+	b.aborted.Store(true)
+	close(b.queue)
+
+	metrics.SlowStreamClients.WithLabelValues(providerName).Inc()
+	log.Printf("Stream from %s cancelled: client is not reading", providerName)
+
+	// The deadline of the write in progress is at most one timeout away
+	timer := time.NewTimer(2 * b.timeout)
+	defer timer.Stop()
+	select {
+	case <-b.done:
+	case <-timer.C:
+		log.Printf("Stream from %s: write to the client still blocked, not sending the error event", providerName)
+		return
+	}
+	if b.writeErr != nil && !errors.Is(b.writeErr, errSlowClient) {
+		return
+	}
+	// The blocked write may have left the deadline passed; allow one more
+	// write for the error event
+	b.setWriteDeadline(time.Now().Add(b.timeout))
+	defer b.setWriteDeadline(time.Time{})
+	event, err := json.Marshal(translator.ErrorResponse{
+		Error: translator.ErrorDetail{
+			Message: "The stream was cancelled because the client stopped reading it",
+			Type:    "api_error",
+			Code:    slowClientErrorCode,
+		},
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(b.w, "data: %s\n\n", event)
+	b.flusher.Flush()
+}
+
+// finish ends buffered writing of a stream that stopped with err. A slow
+// client's stream is cancelled with cancel, to release the provider, and
+// ended with an error event; finish then returns nil. Otherwise the queued
+// chunks are written and err, or the error writing them, is returned for
+// the caller to report.
+func (b *streamBuffer) finish(err error, cancel func(), providerName string) error {
+	if errors.Is(err, errSlowClient) {
+		cancel()
+		b.abort(providerName)
+		return nil
+	}
+	if closeErr := b.Close(); err == nil {
+		err = closeErr
+	}
+	if errors.Is(err, errSlowClient) {
+		// The provider stream ended, but the client stopped reading the rest
+		cancel()
+		metrics.SlowStreamClients.WithLabelValues(providerName).Inc()
+		log.Printf("Stream from %s cut short: client is not reading", providerName)
+		return nil
+	}
+	return err
+}
+
+// isTimeout reports whether err is a deadline exceeded error
+func isTimeout(err error) bool {
+	var timeout interface{ Timeout() bool }
+	return errors.As(err, &timeout) && timeout.Timeout()
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// blockedWriter blocks every write until release is closed
+type blockedWriter struct {
+	release chan struct{}
+	mu      sync.Mutex
+	buf     bytes.Buffer
+}
+
+func (w *blockedWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockedWriter) Flush() {}
+
+func (w *blockedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestStreamBuffer(t *testing.T) {
+	t.Run("writes everything in order", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		out := newStreamBuffer(w, w, StreamBackpressure{BufferChunks: 2})
+		var want strings.Builder
+		for i := 0; i < 100; i++ {
+			chunk := strings.Repeat(string(rune('a'+i%26)), i)
+			want.WriteString(chunk)
+			if _, err := out.Write([]byte(chunk)); err != nil {
+				t.Fatalf("Write: %v", err)
+			}
+		}
+		if err := out.finish(nil, func() { t.Error("stream cancelled") }, "openai"); err != nil {
+			t.Fatalf("finish: %v", err)
+		}
+		if w.Body.String() != want.String() {
+			t.Errorf("client got %d bytes, want %d", w.Body.Len(), want.Len())
+		}
+	})
+
+	t.Run("slow client", func(t *testing.T) {
+		w := &blockedWriter{release: make(chan struct{})}
+		out := newStreamBuffer(w, w, StreamBackpressure{BufferChunks: 2, SlowClientTimeout: 20 * time.Millisecond})
+		before := testutil.ToFloat64(metrics.SlowStreamClients.WithLabelValues("openai"))
+
+		// One chunk is being written and two are queued; the fourth waits
+		// for the timeout
+		var err error
+		for i := 0; i < 4 && err == nil; i++ {
+			_, err = out.Write([]byte("data: {}\n\n"))
+		}
+		if !errors.Is(err, errSlowClient) {
+			t.Fatalf("Write error = %v, want errSlowClient", err)
+		}
+		if _, err := out.Write([]byte("data: {}\n\n")); !errors.Is(err, errSlowClient) {
+			t.Errorf("Write after failure = %v, want errSlowClient", err)
+		}
+
+		// Cancelling the upstream unblocks the client here
+		cancelled := false
+		err = out.finish(err, func() { cancelled = true; close(w.release) }, "openai")
+		if err != nil || !cancelled {
+			t.Fatalf("finish = %v, cancelled %v", err, cancelled)
+		}
+		events := sseEvents(w.String())
+		if len(events) != 2 || !strings.Contains(events[1], slowClientErrorCode) {
+			t.Errorf("client got %q, want the chunk in progress and a slow_client event", events)
+		}
+		if got := testutil.ToFloat64(metrics.SlowStreamClients.WithLabelValues("openai")) - before; got != 1 {
+			t.Errorf("slow client metric increased by %v, want 1", got)
+		}
+	})
+
+	t.Run("provider error", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		out := newStreamBuffer(w, w, StreamBackpressure{})
+		err := out.finish(copyStream(out, out, failingStream()), func() { t.Error("stream cancelled") }, "openai")
+		if err == nil {
+			t.Fatal("finish returned no error for a failed provider stream")
+		}
+		writeStreamError(context.Background(), w, w, "openai", err)
+		assertStreamError(t, w.Body.String())
+	})
+}
+
+// endlessStream yields SSE chunks until closed
+type endlessStream struct {
+	closed atomic.Bool
+}
+
+func (s *endlessStream) Read(p []byte) (int, error) {
+	if s.closed.Load() {
+		return 0, errors.New("read on closed stream")
+	}
+	return copy(p, firstChunk), nil
+}
+
+func (s *endlessStream) Close() error {
+	s.closed.Store(true)
+	return nil
+}
+
+// TestProtocolStreamSlowClient tests that a client that stops reading has
+// its provider stream closed instead of stalling it
+func TestProtocolStreamSlowClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai-primary": {
+				Type:      "openai",
+				Mode:      "protocol",
+				Protocol:  "openai",
+				Endpoints: []instance.EndpointConfig{{Path: "/openai/primary"}},
+			},
+		},
+	}
+	stream := &endlessStream{}
+	provider := &stubProvider{name: "openai", invokeStreaming: func(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+		return stream, nil
+	}}
+	h := NewProtocolHandler(map[string]providers.Provider{"openai": provider}, currentConfig(config), nil)
+	h.SetStreamBackpressure(StreamBackpressure{BufferChunks: 4, SlowClientTimeout: 50 * time.Millisecond})
+
+	// The gateway's global middleware wraps the writer the deadlines are
+	// set through
+	handled := make(chan struct{})
+	engine := gin.New()
+	engine.Use(middleware.Recovery(), middleware.Security(), middleware.Metrics(),
+		middleware.NormaliseHeaders(), middleware.ConfidenceHeader(middleware.ConfidenceConfig{Enabled: true}))
+	engine.POST("/openai/*path", func(c *gin.Context) {
+		defer close(handled)
+		h.HandleRequest(c)
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/openai/primary/chat/completions", "application/json",
+		strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	defer resp.Body.Close()
+	if line, err := bufio.NewReader(resp.Body).ReadString('\n'); err != nil || !strings.HasPrefix(line, "data:") {
+		t.Fatalf("first line = %q, %v", line, err)
+	}
+
+	// Stop reading; the handler must give up on the client
+	select {
+	case <-handled:
+	case <-time.After(10 * time.Second):
+		t.Fatal("handler still streaming to a client that stopped reading")
+	}
+	if !stream.closed.Load() {
+		t.Error("provider stream was not closed")
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+const (
+	// StreamIDHeader carries the ID of a streamed chat completion, for
+	// cancelling it with POST /v1/chat/completions/{stream_id}/cancel
+	StreamIDHeader = "X-Stream-ID"
+
+	// StreamIDEventHeader, set to "true" on a streaming request, makes the
+	// stream start with a stream_id event carrying the ID. The event is
+	// opt-in because OpenAI SDKs treat named events as chunks.
+	StreamIDEventHeader = "X-Stream-ID-Event"
+
+	// streamIDEvent is the name of the SSE event carrying the stream ID
+	streamIDEvent = "stream_id"
+)
+
+// StreamCancelResponse is returned when a stream is cancelled
+type StreamCancelResponse struct {
+	ID     string `json:"id"`
+	Object string `json:"object"`
+	Status string `json:"status"`
+}
+
+// streamRegistry tracks the in-flight streams that can be cancelled. Each
+// stream belongs to the principal that started it; only that principal can
+// cancel it.
+type streamRegistry struct {
+	mu      sync.Mutex
+	streams map[string]*activeStream
+}
+
+// activeStream is an in-flight stream and the cancel func of its context
+type activeStream struct {
+	id        string
+	principal string
+	ctx       context.Context
+	cancel    context.CancelFunc
+	cancelled atomic.Bool
+}
+
+// Cancelled reports whether the stream was cancelled through the registry
+func (s *activeStream) Cancelled() bool {
+	return s.cancelled.Load()
+}
+
+func newStreamRegistry() *streamRegistry {
+	return &streamRegistry{streams: make(map[string]*activeStream)}
+}
+
+// register adds a stream for principal, with a context derived from ctx.
+// The stream must be released when it ends.
+func (r *streamRegistry) register(ctx context.Context, principal string) *activeStream {
+	stream := &activeStream{
+		id:        "stream_" + uuid.New().String(),
+		principal: principal,
+	}
+	stream.ctx, stream.cancel = context.WithCancel(ctx)
+
+	r.mu.Lock()
+	r.streams[stream.id] = stream
+	r.mu.Unlock()
+	return stream
+}
+
+// release removes a finished stream and frees its context
+func (r *streamRegistry) release(stream *activeStream) {
+	r.mu.Lock()
+	delete(r.streams, stream.id)
+	r.mu.Unlock()
+	stream.cancel()
+}
+
+// cancel cancels a principal's stream, reporting whether it was found
+func (r *streamRegistry) cancel(id, principal string) bool {
+	r.mu.Lock()
+	stream, ok := r.streams[id]
+	r.mu.Unlock()
+	if !ok || stream.principal != principal {
+		return false
+	}
+	stream.cancelled.Store(true)
+	stream.cancel()
+	return true
+}
+
+// startCancellableStream registers a stream for the caller and sets its ID
+// header. The stream must be run with the stream's context, and released
+// when it ends.
+func (h *OpenAIHandler) startCancellableStream(c *gin.Context) *activeStream {
+	stream := h.streams.register(c.Request.Context(), c.GetString("user"))
+	c.Header(StreamIDHeader, stream.id)
+	return stream
+}
+
+// writeStreamIDEvent sends the stream ID as the first event if the client
+// asked for it
+func writeStreamIDEvent(c *gin.Context, id string) {
+	if c.GetHeader(StreamIDEventHeader) != "true" {
+		return
+	}
+	data, err := json.Marshal(map[string]string{"stream_id": id})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", streamIDEvent, data)
+	c.Writer.Flush()
+}
+
+// endStream ends a stream that stopped with err: with [DONE] if its client
+// cancelled it, so the client sees a complete stream, and otherwise with an
+// error event
+func endStream(ctx context.Context, c *gin.Context, stream *activeStream, providerName string, err error) {
+	if !stream.Cancelled() {
+		writeStreamError(ctx, c.Writer, c.Writer, providerName, err)
+		return
+	}
+	log.Printf("Stream %s from %s cancelled by client", stream.id, providerName)
+	fmt.Fprintf(c.Writer, "data: %s\n\n", translator.StreamDone)
+	c.Writer.Flush()
+}
+
+// CancelStream handles POST /v1/chat/completions/:stream_id/cancel. Streams
+// of other principals are reported as not found.
+func (h *OpenAIHandler) CancelStream(c *gin.Context) {
+	id := c.Param("stream_id")
+	if !h.streams.cancel(id, c.GetString("user")) {
+		respondError(c, http.StatusNotFound, "invalid_request_error", "stream_not_found",
+			"No active stream found with id "+id)
+		return
+	}
+	respondJSON(c, http.StatusOK, StreamCancelResponse{
+		ID:     id,
+		Object: "chat.completion.stream",
+		Status: "cancelled",
+	})
+}
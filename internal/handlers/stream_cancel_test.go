@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// blockingStream streams one chunk, then blocks until the request context
+// is cancelled
+func blockingStream(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+	reader, writer := io.Pipe()
+	go func() {
+		writer.Write([]byte(firstChunk))
+		<-ctx.Done()
+		writer.CloseWithError(ctx.Err())
+	}()
+	return reader, nil
+}
+
+// TestCancelStream tests that a stream is cancelled by its ID, ends with
+// [DONE] and is removed from the registry
+func TestCancelStream(t *testing.T) {
+	registry := map[string]providers.Provider{
+		"openai": &stubProvider{name: "openai", invokeStreaming: blockingStream},
+	}
+	r, err := router.NewRouter(&router.Config{
+		ModelMappings: map[string]router.ModelMapping{
+			"gpt-4o": {DefaultProvider: "openai", Providers: map[string]router.ProviderModelInfo{"openai": {Model: "gpt-4o"}}},
+		},
+		Providers: map[string]router.ProviderConfig{"openai": {Enabled: true}},
+	}, registry)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	h := NewOpenAIHandler(r)
+	server := httptest.NewServer(h.Handler())
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, server.URL+"/v1/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(StreamIDEventHeader, "true")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("stream request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	id := resp.Header.Get(StreamIDHeader)
+	if !strings.HasPrefix(id, "stream_") {
+		t.Fatalf("stream ID header: got %q", id)
+	}
+	body := bufio.NewReader(resp.Body)
+	if line, _ := body.ReadString('\n'); line != "event: "+streamIDEvent+"\n" {
+		t.Fatalf("first line: got %q", line)
+	}
+	if line, _ := body.ReadString('\n'); !strings.Contains(line, id) {
+		t.Fatalf("stream_id event data: got %q", line)
+	}
+
+	// Another principal's or an unknown stream is not found
+	if h.streams.cancel(id, "someone-else") {
+		t.Error("stream cancelled by another principal")
+	}
+	cancelResp, err := http.Post(server.URL+"/v1/chat/completions/stream_unknown/cancel", "application/json", nil)
+	if err != nil {
+		t.Fatalf("cancel request: %v", err)
+	}
+	cancelResp.Body.Close()
+	if cancelResp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown stream status: got %d, want 404", cancelResp.StatusCode)
+	}
+
+	cancelResp, err = http.Post(server.URL+"/v1/chat/completions/"+id+"/cancel", "application/json", nil)
+	if err != nil {
+		t.Fatalf("cancel request: %v", err)
+	}
+	cancelResp.Body.Close()
+	if cancelResp.StatusCode != http.StatusOK {
+		t.Fatalf("cancel status: got %d, want 200", cancelResp.StatusCode)
+	}
+
+	rest, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("read stream: %v", err)
+	}
+	if !strings.HasSuffix(string(rest), "data: [DONE]\n\n") || strings.Contains(string(rest), "error") {
+		t.Errorf("stream did not end cleanly: %q", rest)
+	}
+
+	h.streams.mu.Lock()
+	remaining := len(h.streams.streams)
+	h.streams.mu.Unlock()
+	if remaining != 0 {
+		t.Errorf("registry has %d streams after completion", remaining)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+const firstChunk = `data: {"id":"c1","object":"chat.completion.chunk","created":1,"model":"gpt-4o","choices":[{"index":0,"delta":{"content":"Hel"},"finish_reason":null}]}
+
+`
+
+// failingStream yields firstChunk and then fails
+func failingStream() io.ReadCloser {
+	return io.NopCloser(io.MultiReader(strings.NewReader(firstChunk), resetReader{}))
+}
+
+// resetReader fails every read with a provider error
+type resetReader struct{}
+
+func (resetReader) Read([]byte) (int, error) {
+	return 0, &providers.ProviderError{Provider: "openai", Message: "upstream connection reset"}
+}
+
+// assertStreamError checks that body is firstChunk followed by a stream_error event
+func assertStreamError(t *testing.T, body string) {
+	t.Helper()
+	events := sseEvents(body)
+	if len(events) != 2 {
+		t.Fatalf("unexpected events: %q", events)
+	}
+	var errResp translator.ErrorResponse
+	if err := json.Unmarshal([]byte(events[1]), &errResp); err != nil {
+		t.Fatalf("error event: %v", err)
+	}
+	if errResp.Error.Code != streamErrorCode || errResp.Error.Message != "upstream connection reset" {
+		t.Errorf("unexpected error event: %s", events[1])
+	}
+}
+
+func TestWriteStreamError(t *testing.T) {
+	t.Run("error event after partial stream", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		if err := copyStream(w, w, failingStream()); err != nil {
+			writeStreamError(context.Background(), w, w, "openai", err)
+		}
+		assertStreamError(t, w.Body.String())
+	})
+
+	t.Run("usage tracking stream", func(t *testing.T) {
+		req := &translator.ChatCompletionRequest{Model: "gpt-4o", StreamOptions: &translator.StreamOptions{IncludeUsage: true}}
+		w := httptest.NewRecorder()
+		if err := copyStreamWithUsage(w, w, failingStream(), translator.NewStreamUsageTracker(req)); err != nil {
+			writeStreamError(context.Background(), w, w, "openai", err)
+		}
+		assertStreamError(t, w.Body.String())
+	})
+
+	t.Run("client disconnected", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		w := httptest.NewRecorder()
+		writeStreamError(ctx, w, w, "openai", context.Canceled)
+		if w.Body.Len() != 0 {
+			t.Errorf("nothing should be written after disconnect, got %q", w.Body.String())
+		}
+	})
+
+	t.Run("clean end of stream", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		if err := copyStream(w, w, strings.NewReader(firstChunk)); err != nil {
+			t.Errorf("copyStream: %v", err)
+		}
+	})
+}
+
+func TestProtocolStreamingError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai-primary": {
+				Type:      "openai",
+				Mode:      "protocol",
+				Protocol:  "openai",
+				Endpoints: []instance.EndpointConfig{{Path: "/openai/primary"}},
+			},
+		},
+	}
+	h := NewProtocolHandler(map[string]providers.Provider{
+		"openai": &stubProvider{name: "openai", invokeStreaming: func(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+			return failingStream(), nil
+		}},
+	}, currentConfig(config), nil)
+
+	engine := gin.New()
+	engine.POST("/openai/*path", h.HandleRequest)
+	req := httptest.NewRequest(http.MethodPost, "/openai/primary/chat/completions",
+		strings.NewReader(`{"model":"gpt-4o","stream":true,"messages":[{"role":"user","content":"hi"}]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status = %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	assertStreamError(t, w.Body.String())
+}
+
+func TestStreamsOpenAI(t *testing.T) {
+	if !streamsOpenAI(&instance.InstanceConfig{}) {
+		t.Error("instance without transformation should stream")
+	}
+	if !streamsOpenAI(&instance.InstanceConfig{Transformation: &instance.TransformationConfig{ResponseFrom: "openai"}}) {
+		t.Error("openai response format should stream")
+	}
+	if streamsOpenAI(&instance.InstanceConfig{Transformation: &instance.TransformationConfig{ResponseFrom: "bedrock_converse"}}) {
+		t.Error("bedrock_converse response format should not stream")
+	}
+}
+
+// chatEventProvider is a stubProvider streaming typed events
+type chatEventProvider struct {
+	stubProvider
+	events []providers.ChatEvent
+}
+
+func (p *chatEventProvider) InvokeChatStream(ctx context.Context, req *providers.ChatRequest) (<-chan providers.ChatEvent, error) {
+	events := make(chan providers.ChatEvent, len(p.events))
+	for _, event := range p.events {
+		events <- event
+	}
+	close(events)
+	return events, nil
+}
+
+func TestChatCompletionsChatStream(t *testing.T) {
+	h, _ := newChatTestHandler(t)
+	h.router.RegisterProvider("bedrock", &chatEventProvider{
+		stubProvider: stubProvider{name: "bedrock"},
+		events: []providers.ChatEvent{
+			{Type: providers.ChatEventDelta, Role: "assistant"},
+			{Type: providers.ChatEventDelta, Text: "Checking"},
+			{Type: providers.ChatEventToolCallDelta, ToolCall: &providers.ToolCallDelta{ID: "t1", Name: "weather"}},
+			{Type: providers.ChatEventToolCallDelta, ToolCall: &providers.ToolCallDelta{Arguments: `{"city":"Paris"}`}},
+			{Type: providers.ChatEventUsage, Usage: &providers.ChatUsage{InputTokens: 3, OutputTokens: 5}},
+			{Type: providers.ChatEventDone, FinishReason: "tool_use"},
+		},
+	})
+
+	w := postChat(h, `{"model":"claude-3-sonnet","stream":true,"stream_options":{"include_usage":true},"messages":[{"role":"user","content":"hello"}]}`)
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("status %d, content type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	events := sseEvents(w.Body.String())
+	if len(events) != 7 || events[6] != translator.StreamDone {
+		t.Fatalf("unexpected events: %q", events)
+	}
+
+	chunks := make([]translator.ChatCompletionStreamResponse, 6)
+	for i := range chunks {
+		if err := json.Unmarshal([]byte(events[i]), &chunks[i]); err != nil {
+			t.Fatalf("chunk %d: %v", i, err)
+		}
+		if chunks[i].Object != "chat.completion.chunk" || chunks[i].Model != "claude-3-sonnet" || !strings.HasPrefix(chunks[i].ID, "chatcmpl-") {
+			t.Errorf("chunk %d = %s", i, events[i])
+		}
+	}
+	if delta := chunks[0].Choices[0].Delta; delta.Role != "assistant" {
+		t.Errorf("first delta = %+v, want the role", delta)
+	}
+	if delta := chunks[1].Choices[0].Delta; delta.Content != "Checking" {
+		t.Errorf("text delta = %+v", delta)
+	}
+	if call := chunks[2].Choices[0].Delta.ToolCalls[0]; call.ID != "t1" || call.Type != "function" || call.Function.Name != "weather" {
+		t.Errorf("tool call start = %+v", call)
+	}
+	if call := chunks[3].Choices[0].Delta.ToolCalls[0]; call.ID != "" || call.Function.Arguments != `{"city":"Paris"}` {
+		t.Errorf("tool call arguments = %+v", call)
+	}
+	if reason := chunks[4].Choices[0].FinishReason; reason == nil || *reason != translator.FinishReasonToolCalls {
+		t.Errorf("finish reason = %v, want tool_calls", reason)
+	}
+	if usage := chunks[5].Usage; usage == nil || len(chunks[5].Choices) != 0 || usage.PromptTokens != 3 || usage.CompletionTokens != 5 || usage.TotalTokens != 8 {
+		t.Errorf("usage chunk = %s", events[5])
+	}
+}
+
+func TestWriteChatEventsError(t *testing.T) {
+	events := make(chan providers.ChatEvent, 2)
+	events <- providers.ChatEvent{Type: providers.ChatEventDelta, Text: "Hel"}
+	events <- providers.ChatEvent{Type: providers.ChatEventError, Err: &providers.ProviderError{Provider: "bedrock", Message: "upstream connection reset"}}
+	close(events)
+
+	req := &translator.ChatCompletionRequest{Model: "claude-3-sonnet"}
+	w := httptest.NewRecorder()
+	ctx := context.Background()
+	if err := writeChatEvents(ctx, w, w, events, translator.NewStreamUsageTracker(req), "chatcmpl-1", req.Model, false); err != nil {
+		writeStreamError(ctx, w, w, "bedrock", err)
+	}
+	assertStreamError(t, w.Body.String())
+}
@@ -0,0 +1,61 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/gin-gonic/gin"
+)
+
+// stripJSONFields removes the named top-level fields from a JSON object,
+// leaving every other field byte for byte as sent. Bodies that are not JSON
+// objects, and objects without any of the fields, are returned unchanged.
+func stripJSONFields(body []byte, fields []string) ([]byte, []string) {
+	if len(fields) == 0 {
+		return body, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil || object == nil {
+		return body, nil
+	}
+
+	var removed []string
+	for _, field := range fields {
+		if _, ok := object[field]; ok {
+			delete(object, field)
+			removed = append(removed, field)
+		}
+	}
+	if len(removed) == 0 {
+		return body, nil
+	}
+
+	stripped, err := json.Marshal(object)
+	if err != nil {
+		return body, nil
+	}
+	return stripped, removed
+}
+
+// stripRequestFields applies an instance's strip_request_fields to the
+// request body, buffering it when there are fields to strip
+func stripRequestFields(c *gin.Context, fields []string) ([]string, error) {
+	if len(fields) == 0 || c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return nil, nil
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+
+	stripped, removed := stripJSONFields(body, fields)
+	c.Request.Body = io.NopCloser(bytes.NewReader(stripped))
+	c.Request.ContentLength = int64(len(stripped))
+	return removed, nil
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/openai"
+)
+
+func TestStripJSONFields(t *testing.T) {
+	body := []byte(`{"model":"gpt-4o","functions":[{"name":"f"}],"x_custom":{"keep":[1,2.50]},"user":"u"}`)
+	stripped, removed := stripJSONFields(body, []string{"functions", "user", "absent"})
+	if !reflect.DeepEqual(removed, []string{"functions", "user"}) {
+		t.Errorf("removed = %v", removed)
+	}
+
+	var got map[string]json.RawMessage
+	if err := json.Unmarshal(stripped, &got); err != nil {
+		t.Fatalf("stripped body is not JSON: %v", err)
+	}
+	if _, ok := got["functions"]; ok {
+		t.Error("functions was not removed")
+	}
+	if string(got["x_custom"]) != `{"keep":[1,2.50]}` || string(got["model"]) != `"gpt-4o"` {
+		t.Errorf("other fields changed: %s", stripped)
+	}
+
+	for _, body := range []string{`{"model":"gpt-4o"}`, `[{"functions":1}]`, `not json`} {
+		if out, removed := stripJSONFields([]byte(body), []string{"functions"}); string(out) != body || removed != nil {
+			t.Errorf("stripJSONFields(%s) = %s, %v; want it unchanged", body, out, removed)
+		}
+	}
+}
+
+// TestTransparentStripRequestFields tests listed fields are removed from
+// the forwarded body and the upstream gets the new Content-Length
+func TestTransparentStripRequestFields(t *testing.T) {
+	var gotBody []byte
+	var gotLength int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLength = r.ContentLength
+		gotBody, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+
+	provider, err := openai.NewOpenAIProvider(openai.OpenAIConfig{APIKey: "test", BaseURL: upstream.URL})
+	if err != nil {
+		t.Fatalf("NewOpenAIProvider: %v", err)
+	}
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai-direct": {
+				Type:               "openai",
+				Mode:               "transparent",
+				Endpoints:          []instance.EndpointConfig{{Path: "/transparent/openai"}},
+				StripRequestFields: []string{"functions", "function_call"},
+			},
+		},
+	}
+	h := NewTransparentHandler(map[string]providers.Provider{"openai": provider}, currentConfig(config))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/transparent/*path", h.HandleRequest)
+
+	body := `{"model":"gpt-4o","functions":[{"name":"f"}],"function_call":"auto","seed":7}`
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transparent/openai/chat/completions", strings.NewReader(body)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if string(gotBody) != `{"model":"gpt-4o","seed":7}` {
+		t.Errorf("upstream body = %s", gotBody)
+	}
+	if gotLength != int64(len(gotBody)) {
+		t.Errorf("upstream Content-Length = %d, want %d", gotLength, len(gotBody))
+	}
+}
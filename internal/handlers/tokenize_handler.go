@@ -0,0 +1,116 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// TokenizeHandler counts the tokens of a text for a model, without running
+// a completion
+type TokenizeHandler struct {
+	router *router.Router
+}
+
+// TokenizeRequest asks for the token count of Text under Model
+type TokenizeRequest struct {
+	Model        string `json:"model"`
+	Text         string `json:"text"`
+	ReturnTokens bool   `json:"return_tokens,omitempty"`
+}
+
+// TokenizeResponse is the token count of a text. Approximate is set when
+// the model's provider cannot count tokens and the count is estimated.
+type TokenizeResponse struct {
+	Model       string `json:"model"`
+	TokenCount  int    `json:"token_count"`
+	Tokens      []int  `json:"tokens,omitempty"`
+	Approximate bool   `json:"approximate,omitempty"`
+}
+
+// NewTokenizeHandler creates a tokenize handler
+func NewTokenizeHandler(r *router.Router) *TokenizeHandler {
+	return &TokenizeHandler{router: r}
+}
+
+// Tokenize handles POST /v1/tokenize. The text is counted as a single user
+// message by the provider the model routes to: Anthropic's count_tokens
+// API, an OpenAI completion with max_tokens=1, or a local estimate.
+func (h *TokenizeHandler) Tokenize(c *gin.Context) {
+	var req TokenizeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "invalid_json",
+			"Invalid request body")
+		return
+	}
+	if req.Model == "" || req.Text == "" {
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "missing_required_field",
+			"model and text are required")
+		return
+	}
+
+	// No provider API returns token IDs, and no tokenizer vocabulary is
+	// bundled, so only counts can be served
+	if req.ReturnTokens {
+		respondError(c, http.StatusNotImplemented, "not_implemented_error", "not_supported",
+			"Token IDs are not available; providers expose token counts only")
+		return
+	}
+
+	provider, modelInfo, err := h.router.RouteRequest(c.Request.Context(), req.Model, "")
+	if err != nil {
+		log.Printf("Routing error for model %s: %v", req.Model, err)
+		if errors.Is(err, router.ErrProviderDraining) {
+			respondError(c, http.StatusServiceUnavailable, "service_error", "provider_draining",
+				fmt.Sprintf("No provider is available for model %q while its provider is draining", req.Model))
+			return
+		}
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "model_not_found",
+			fmt.Sprintf("Model %q not found or not available", req.Model))
+		return
+	}
+
+	counter, ok := provider.(providers.TokenCounter)
+	if !ok {
+		respondJSON(c, http.StatusOK, TokenizeResponse{
+			Model:       req.Model,
+			TokenCount:  providers.ApproximateTokens(req.Text),
+			Approximate: true,
+		})
+		return
+	}
+
+	chatReq := &translator.ChatCompletionRequest{
+		Model:    req.Model,
+		Messages: []translator.ChatMessage{{Role: "user", Content: translator.TextContent(req.Text)}},
+	}
+	providerReq, err := translateChatRequest(provider, chatReq, modelInfo)
+	if err != nil {
+		log.Printf("Translation error: %v", err)
+		respondError(c, http.StatusBadRequest, "invalid_request_error", "translation_failed",
+			fmt.Sprintf("Failed to translate request: %v", err))
+		return
+	}
+	providerReq.Context = c.Request.Context()
+	ForwardCorrelation(c, providerReq)
+
+	count, err := counter.CountTokens(c.Request.Context(), providerReq)
+	if err != nil {
+		log.Printf("Token count failed for %s: %v", provider.Name(), err)
+		writeProviderError(c, err)
+		return
+	}
+	respondJSON(c, http.StatusOK, TokenizeResponse{
+		Model:      req.Model,
+		TokenCount: count,
+	})
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// TestTokenize tests provider counts, local estimates and rejected token ID
+// requests
+func TestTokenize(t *testing.T) {
+	registry := map[string]providers.Provider{
+		"openai": &countingProvider{stubProvider: stubProvider{name: "openai"}, tokens: 11},
+		"azure":  &stubProvider{name: "azure"},
+	}
+	r, err := router.NewRouter(&router.Config{
+		ModelMappings: map[string]router.ModelMapping{
+			"gpt-4o": {DefaultProvider: "openai", Providers: map[string]router.ProviderModelInfo{"openai": {Model: "gpt-4o"}}},
+			"gpt-35": {DefaultProvider: "azure", Providers: map[string]router.ProviderModelInfo{"azure": {Model: "gpt-35"}}},
+		},
+		Providers: map[string]router.ProviderConfig{"openai": {Enabled: true}, "azure": {Enabled: true}},
+	}, registry)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	h := NewTokenizeHandler(r)
+
+	w := postIBMTask(h.Tokenize, `{"model":"gpt-4o","text":"hello world"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d, body %s", w.Code, w.Body)
+	}
+	var resp TokenizeResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.TokenCount != 11 || resp.Approximate || resp.Model != "gpt-4o" {
+		t.Errorf("provider count: got %+v", resp)
+	}
+
+	w = postIBMTask(h.Tokenize, `{"model":"gpt-35","text":"hello world"}`)
+	resp = TokenizeResponse{}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.TokenCount != providers.ApproximateTokens("hello world") || !resp.Approximate {
+		t.Errorf("estimated count: got %+v", resp)
+	}
+
+	if w := postIBMTask(h.Tokenize, `{"model":"gpt-4o","text":"hi","return_tokens":true}`); w.Code != http.StatusNotImplemented {
+		t.Errorf("return_tokens status: got %d, want 501", w.Code)
+	}
+	if w := postIBMTask(h.Tokenize, `{"model":"unknown","text":"hi"}`); w.Code != http.StatusBadRequest {
+		t.Errorf("unknown model status: got %d, want 400", w.Code)
+	}
+}
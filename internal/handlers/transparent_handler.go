@@ -4,13 +4,20 @@
 package handlers
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
 	"time"
 
+	"github.com/tosharewith/llmproxy_auth/internal/chaos"
 	"github.com/tosharewith/llmproxy_auth/internal/instance"
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/bootstrap"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/sigv4"
 	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
 	"github.com/gin-gonic/gin"
 )
@@ -18,16 +25,160 @@ import (
 // TransparentHandler handles transparent passthrough requests
 // This mode adds authentication and metrics but does not transform requests/responses
 type TransparentHandler struct {
-	providers map[string]providers.Provider
-	config    *instance.Config
+	providers  map[string]providers.Provider
+	config     *atomic.Pointer[instance.Config] // the config in effect, replaced by reloads
+	semaphores *providers.SemaphoreRegistry // Optional: per-instance concurrency limits
+
+	// instanceProviders are used instead of the provider for the instance
+	// type: a generic SigV4 provider per aws_sigv4 instance, and those set
+	// with SetInstanceProviders
+	instanceProviders map[string]providers.Provider
+
+	// proxies holds the reverse proxy transport of each instance whose
+	// provider supports it; other instances go through Invoke
+	proxies map[string]*passthroughTransport
+
+	// transports are the proxies' connection pools, one per distinct
+	// instance timeouts
+	transports map[providers.HTTPTimeouts]http.RoundTripper
+	bufferPool *proxyBufferPool
+
+	faults *chaos.Injector // Optional: injected faults for resilience testing
+	drains DrainState      // Optional: drained providers take no new requests
 }
 
 // NewTransparentHandler creates a new transparent handler
-func NewTransparentHandler(providerRegistry map[string]providers.Provider, config *instance.Config) *TransparentHandler {
-	return &TransparentHandler{
-		providers: providerRegistry,
-		config:    config,
+func NewTransparentHandler(providerRegistry map[string]providers.Provider, config *atomic.Pointer[instance.Config]) *TransparentHandler {
+	h := &TransparentHandler{
+		providers:         providerRegistry,
+		config:            config,
+		instanceProviders: newSigV4Providers(config.Load()),
+		proxies:           make(map[string]*passthroughTransport),
+		transports:        make(map[providers.HTTPTimeouts]http.RoundTripper),
+		bufferPool:        &proxyBufferPool{},
+	}
+	for name, inst := range config.Load().ListInstancesByMode("transparent") {
+		h.addProxy(name, inst)
+	}
+	return h
+}
+
+// SetInstanceProviders adds providers built for a single instance, keyed
+// by instance name, such as generic_http providers
+func (h *TransparentHandler) SetInstanceProviders(instanceProviders map[string]providers.Provider) {
+	for name, provider := range instanceProviders {
+		h.instanceProviders[name] = provider
+		if inst, ok := h.config.Load().Instances[name]; ok && inst.Mode == "transparent" {
+			h.addProxy(name, inst)
+		}
+	}
+}
+
+// addProxy selects the reverse proxy path for an instance when its
+// provider supports it
+func (h *TransparentHandler) addProxy(name string, inst instance.InstanceConfig) {
+	provider, ok := h.provider(name, inst.Type)
+	if !ok {
+		return
+	}
+	timeouts, err := bootstrap.Timeouts(inst)
+	if err != nil {
+		log.Printf("Invalid timeouts for instance %s: %v", name, err)
+		return
 	}
+	transport, ok := newPassthroughTransport(h.proxyTransport(timeouts), provider, inst)
+	if !ok {
+		return
+	}
+	h.proxies[name] = transport
+	mode := "streaming"
+	if transport.buffersBody() {
+		mode = "buffered for signing"
+	}
+	log.Printf("✓ Reverse proxy for %s: %s (%s)", name, transport.passthrough.BaseURL(), mode)
+}
+
+// proxyTransport returns the connection pool of reverse-proxied instances
+// with timeouts, creating it on first use
+func (h *TransparentHandler) proxyTransport(timeouts providers.HTTPTimeouts) http.RoundTripper {
+	timeouts = timeouts.WithDefaults()
+	transport, ok := h.transports[timeouts]
+	if !ok {
+		transport = providers.WithRequestTimeout(newProxyTransport(timeouts), timeouts.Request)
+		h.transports[timeouts] = transport
+	}
+	return transport
+}
+
+// provider returns the provider serving an instance: its own provider if
+// it has one, else the provider for its type, with any injected fault
+func (h *TransparentHandler) provider(name, providerType string) (providers.Provider, bool) {
+	provider, ok := h.instanceProviders[name]
+	if !ok {
+		provider, ok = h.providers[providerType]
+	}
+	if ok {
+		provider = h.faults.Wrap(name, provider)
+	}
+	return provider, ok
+}
+
+// newSigV4Providers creates a generic SigV4 provider for every transparent
+// instance with aws_sigv4 authentication, so any AWS service (S3,
+// SageMaker, ...) can be proxied, not only those with a dedicated provider
+func newSigV4Providers(config *instance.Config) map[string]providers.Provider {
+	signers := make(map[string]providers.Provider)
+	for name, inst := range config.ListInstancesByMode("transparent") {
+		if inst.Authentication.Type != "aws_sigv4" || inst.Authentication.Service == "" {
+			continue
+		}
+
+		region := inst.Authentication.Region
+		if region == "" {
+			region = inst.Region
+		}
+		if region == "" {
+			region = os.Getenv("AWS_REGION")
+		}
+		if region == "" {
+			region = "us-east-1"
+		}
+
+		timeouts, err := bootstrap.Timeouts(inst)
+		if err != nil {
+			log.Printf("Invalid timeouts for instance %s: %v", name, err)
+			continue
+		}
+		provider, err := sigv4.NewSigV4Provider(sigv4.SigV4Config{
+			Service:  inst.Authentication.Service,
+			Region:   region,
+			Endpoint: inst.Endpoint,
+			Timeouts: timeouts,
+		})
+		if err != nil {
+			log.Printf("Failed to create SigV4 signer for instance %s: %v", name, err)
+			continue
+		}
+		signers[name] = provider
+		log.Printf("✓ SigV4 transparent signing for %s: %s (%s)", name, provider.BaseURL(), region)
+	}
+	return signers
+}
+
+// SetFaults enables injecting faults into instance requests
+func (h *TransparentHandler) SetFaults(faults *chaos.Injector) {
+	h.faults = faults
+}
+
+// SetDrainState rejects requests to the instances of drained providers
+// with 503
+func (h *TransparentHandler) SetDrainState(drains DrainState) {
+	h.drains = drains
+}
+
+// SetSemaphores enables per-instance concurrency limits
+func (h *TransparentHandler) SetSemaphores(semaphores *providers.SemaphoreRegistry) {
+	h.semaphores = semaphores
 }
 
 // HandleRequest handles a transparent passthrough request
@@ -38,10 +189,20 @@ func (h *TransparentHandler) HandleRequest(c *gin.Context) {
 	path := c.Request.URL.Path
 
 	// Find matching instance
-	instanceCfg, instanceName, err := h.config.GetInstanceByPath(path)
+	config := h.config.Load()
+	instanceCfg, instanceName, err := config.GetInstanceForRequest(c.Request.Method, path)
+	var methodErr *instance.MethodNotAllowedError
+	if errors.As(err, &methodErr) {
+		log.Printf("Method not allowed for path %s: %v", path, err)
+		c.Header("Allow", strings.Join(methodErr.Allowed, ", "))
+		respondJSON(c, http.StatusMethodNotAllowed, gin.H{
+			"error": fmt.Sprintf("Method %s not allowed for this path", c.Request.Method),
+		})
+		return
+	}
 	if err != nil {
 		log.Printf("No instance found for path %s: %v", path, err)
-		c.JSON(http.StatusNotFound, gin.H{
+		respondJSON(c, http.StatusNotFound, gin.H{
 			"error": "No provider instance configured for this path",
 		})
 		return
@@ -50,34 +211,60 @@ func (h *TransparentHandler) HandleRequest(c *gin.Context) {
 	// Verify it's a transparent mode instance
 	if instanceCfg.Mode != "transparent" {
 		log.Printf("Instance %s is not in transparent mode (mode: %s)", instanceName, instanceCfg.Mode)
-		c.JSON(http.StatusBadRequest, gin.H{
+		respondJSON(c, http.StatusBadRequest, gin.H{
 			"error": "This endpoint requires transparent mode",
 		})
 		return
 	}
+	if featureDisabled(c, config, "transparent_mode", instanceName) {
+		return
+	}
 
 	log.Printf("Transparent passthrough: %s → %s (instance: %s)", path, instanceCfg.Type, instanceName)
 
-	// Get provider
-	provider, ok := h.providers[instanceCfg.Type]
+	// Get provider: aws_sigv4 instances are signed generically for their service
+	provider, ok := h.provider(instanceName, instanceCfg.Type)
 	if !ok {
 		log.Printf("Provider %s not initialized", instanceCfg.Type)
-		c.JSON(http.StatusServiceUnavailable, gin.H{
+		respondJSON(c, http.StatusServiceUnavailable, gin.H{
 			"error": fmt.Sprintf("Provider %s not available", instanceCfg.Type),
 		})
 		return
 	}
+	if err := drainError(h.drains, instanceCfg.Type); err != nil {
+		log.Printf("Instance %s not available: %v", instanceName, err)
+		respondJSON(c, http.StatusServiceUnavailable, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
-	// Read request body
-	body, err := c.GetRawData()
+	// Wait for a concurrency slot
+	release, err := acquireSlot(c, h.semaphores, instanceName)
 	if err != nil {
-		log.Printf("Failed to read request body: %v", err)
-		c.JSON(http.StatusBadRequest, gin.H{
+		log.Printf("Instance %s at capacity: %v", instanceName, err)
+		respondJSON(c, http.StatusServiceUnavailable, gin.H{
+			"error": fmt.Sprintf("Provider instance %s is at capacity, retry later", instanceName),
+		})
+		return
+	}
+	defer release()
+
+	// Remove fields the instance strips before dispatch; this buffers the body
+	if removed, err := stripRequestFields(c, instanceCfg.StripRequestFields); err != nil {
+		respondJSON(c, http.StatusBadRequest, gin.H{
 			"error": "Failed to read request body",
 		})
 		return
+	} else if len(removed) > 0 {
+		log.Printf("Stripped request fields %v for instance %s", removed, instanceName)
 	}
 
+	// The body is streamed to the provider, so it is measured as it is read
+	requestBody := countRequestBody(c)
+	defer func() { recordPayloadSizes(c, instanceCfg.Type, instanceName, requestBody.n) }()
+	setAuditTarget(c, "", instanceCfg.Type, instanceName)
+
 	// Extract the actual provider path
 	// Remove the transparent prefix to get the real API path
 	// Example: /transparent/bedrock/model/invoke → /model/invoke
@@ -87,67 +274,90 @@ func (h *TransparentHandler) HandleRequest(c *gin.Context) {
 	providerReq := &providers.ProviderRequest{
 		Method:      c.Request.Method,
 		Path:        providerPath,
-		Headers:     make(map[string]string),
-		Body:        body,
+		Headers:     providers.ForwardHeaders(c.Request.Header),
 		QueryParams: make(map[string]string),
 		Context:     c.Request.Context(),
 	}
 
-	// Copy headers (except authentication which will be added by provider)
-	for key := range c.Request.Header {
-		if !isAuthHeader(key) {
-			providerReq.Headers[key] = c.Request.Header.Get(key)
+	// Drop client authentication; the provider adds its own
+	for key := range providerReq.Headers {
+		if isAuthHeader(key) {
+			providerReq.Headers.Del(key)
 		}
 	}
+	ForwardCorrelation(c, providerReq)
+	applyInstanceRules(providerReq, instanceCfg)
 
 	// Copy query params
 	for key := range c.Request.URL.Query() {
 		providerReq.QueryParams[key] = c.Request.URL.Query().Get(key)
 	}
 
-	// Invoke provider (provider handles authentication)
-	providerResp, err := provider.Invoke(c.Request.Context(), providerReq)
+	// Instances with an injected fault go through the provider, which
+	// applies it
+	if transport, ok := h.proxies[instanceName]; ok && !h.faults.Active(instanceName) {
+		h.reverseProxy(c, transport, providerReq, instanceCfg, startTime)
+		log.Printf("Transparent passthrough completed: %s (status: %d, duration: %v)",
+			instanceName, c.Writer.Status(), time.Since(startTime))
+		return
+	}
+
+	// Stream the request body to the provider rather than buffering it
+	if c.Request.Body != nil && c.Request.ContentLength != 0 {
+		providerReq.BodyReader = c.Request.Body
+		providerReq.ContentLength = c.Request.ContentLength
+	}
+
+	// Invoke provider (provider handles authentication). Providers without
+	// streaming support buffer both bodies.
+	providerResp, err := providers.InvokeStream(c.Request.Context(), provider, providerReq)
 	if err != nil {
 		log.Printf("Provider invocation error: %v", err)
 		if providerErr, ok := err.(*providers.ProviderError); ok {
 			c.Data(providerErr.StatusCode, "application/json", []byte(providerErr.Message))
 		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{
+			respondJSON(c, http.StatusInternalServerError, gin.H{
 				"error": "Provider request failed",
 			})
 		}
 		return
 	}
+	defer providerResp.Body.Close()
 
-	// Record metrics
-	if instanceCfg.Metrics.Enabled {
-		duration := time.Since(startTime)
+	recordTransparentMetrics(instanceCfg, c.Request.Method, providerResp.StatusCode, startTime)
 
-		// Build label values
-		labelValues := []string{
-			c.Request.Method,
-			fmt.Sprintf("%d", providerResp.StatusCode),
-		}
+	// Return response as-is (transparent passthrough)
+	providers.ApplyHeaders(c.Writer.Header(), providers.ForwardHeaders(providerResp.Headers))
+	applyResponseHeaders(c, instanceCfg)
+	RecordUpstreamRequestID(c, providerResp.Headers)
+	c.DataFromReader(providerResp.StatusCode, -1, providers.ContentType(providerResp.Headers), providerResp.Body, nil)
 
-		// Add custom labels from config
-		if instanceCfg.Metrics.Labels != nil {
-			for _, value := range instanceCfg.Metrics.Labels {
-				labelValues = append(labelValues, value)
-			}
-		}
+	log.Printf("Transparent passthrough completed: %s (status: %d, duration: %v)",
+		instanceName, providerResp.StatusCode, time.Since(startTime))
+}
 
-		metrics.RequestDuration.WithLabelValues(labelValues[:2]...).Observe(duration.Seconds())
-		metrics.RequestsTotal.WithLabelValues(labelValues[:2]...).Inc()
+// recordTransparentMetrics records the duration and count of a transparent request
+func recordTransparentMetrics(instanceCfg *instance.InstanceConfig, method string, status int, startTime time.Time) {
+	if !instanceCfg.Metrics.Enabled {
+		return
 	}
+	duration := time.Since(startTime)
 
-	// Return response as-is (transparent passthrough)
-	for key, value := range providerResp.Headers {
-		c.Header(key, value)
+	// Build label values
+	labelValues := []string{
+		method,
+		fmt.Sprintf("%d", status),
 	}
-	c.Data(providerResp.StatusCode, getContentType(providerResp.Headers), providerResp.Body)
 
-	log.Printf("Transparent passthrough completed: %s (status: %d, duration: %v)",
-		instanceName, providerResp.StatusCode, time.Since(startTime))
+	// Add custom labels from config
+	if instanceCfg.Metrics.Labels != nil {
+		for _, value := range instanceCfg.Metrics.Labels {
+			labelValues = append(labelValues, value)
+		}
+	}
+
+	metrics.RequestDuration.WithLabelValues(labelValues[:2]...).Observe(duration.Seconds())
+	metrics.RequestsTotal.WithLabelValues(labelValues[:2]...).Inc()
 }
 
 // extractProviderPath extracts the actual provider API path from the full request path
@@ -163,30 +373,16 @@ func extractProviderPath(fullPath string, endpoints []instance.EndpointConfig) s
 	return fullPath
 }
 
-// isAuthHeader checks if a header is an authentication header
-func isAuthHeader(headerName string) bool {
-	authHeaders := []string{
-		"Authorization",
-		"X-API-Key",
-		"x-api-key",
-		"api-key",
-		"X-Auth-Token",
-	}
-	for _, authHeader := range authHeaders {
-		if headerName == authHeader {
-			return true
-		}
-	}
-	return false
+// authHeaders are the client authentication headers, lowercased
+var authHeaders = map[string]bool{
+	"authorization": true,
+	"x-api-key":     true,
+	"api-key":       true,
+	"x-auth-token":  true,
 }
 
-// getContentType extracts content type from headers
-func getContentType(headers map[string]string) string {
-	if ct, ok := headers["Content-Type"]; ok {
-		return ct
-	}
-	if ct, ok := headers["content-type"]; ok {
-		return ct
-	}
-	return "application/json"
+// isAuthHeader checks if a header is an authentication header, in any
+// capitalisation
+func isAuthHeader(headerName string) bool {
+	return authHeaders[strings.ToLower(headerName)]
 }
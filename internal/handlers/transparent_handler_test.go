@@ -0,0 +1,601 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/generic"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/openai"
+)
+
+// TestTransparentHeaders tests that multi-value and rate limit headers
+// survive in both directions while hop-by-hop and client auth headers are
+// dropped
+func TestTransparentHeaders(t *testing.T) {
+	respHeaders := http.Header{}
+	respHeaders.Add("Set-Cookie", "a=1")
+	respHeaders.Add("Set-Cookie", "b=2")
+	respHeaders.Set("Connection", "close")
+	respHeaders.Set("Transfer-Encoding", "chunked")
+	respHeaders.Set("X-Ratelimit-Remaining-Requests", "42")
+	respHeaders.Set("Anthropic-Ratelimit-Tokens-Remaining", "9000")
+	respHeaders.Set("Retry-After", "3")
+	provider := &stubProvider{name: "echo", invoke: respondWith(`{}`, respHeaders)}
+
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"echo-direct": {
+				Type:      "echo",
+				Mode:      "transparent",
+				Endpoints: []instance.EndpointConfig{{Path: "/transparent/echo"}},
+			},
+		},
+	}
+	h := NewTransparentHandler(map[string]providers.Provider{"echo": provider}, currentConfig(config))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/transparent/*path", h.HandleRequest)
+
+	req := httptest.NewRequest(http.MethodGet, "/transparent/echo/items", nil)
+	req.Header.Add("Accept", "application/json")
+	req.Header.Add("Accept", "text/plain")
+	req.Header.Set("X-Api-Key", "client-key")
+	req.Header.Set("Connection", "keep-alive")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	forwarded := provider.lastReq.Load()
+	if !reflect.DeepEqual(forwarded.Headers.Values("Accept"), []string{"application/json", "text/plain"}) {
+		t.Errorf("forwarded Accept = %q, want both values", forwarded.Headers.Values("Accept"))
+	}
+	if forwarded.Headers.Get("X-Api-Key") != "" || forwarded.Headers.Get("Connection") != "" {
+		t.Errorf("forwarded headers %v include auth or hop-by-hop headers", forwarded.Headers)
+	}
+	if !reflect.DeepEqual(w.Header().Values("Set-Cookie"), []string{"a=1", "b=2"}) {
+		t.Errorf("response Set-Cookie = %q, want both values", w.Header().Values("Set-Cookie"))
+	}
+	for _, name := range []string{"X-Ratelimit-Remaining-Requests", "Anthropic-Ratelimit-Tokens-Remaining", "Retry-After"} {
+		if w.Header().Get(name) != respHeaders.Get(name) {
+			t.Errorf("response %s = %q, want it passed through", name, w.Header().Get(name))
+		}
+	}
+	if w.Header().Get("Transfer-Encoding") != "" || w.Header().Get("Connection") != "" {
+		t.Errorf("response headers %v include hop-by-hop headers", w.Header())
+	}
+}
+
+// TestTransparentDraining tests that requests to an instance of a drained
+// provider are rejected without invoking it until it is restored
+func TestTransparentDraining(t *testing.T) {
+	provider := &stubProvider{name: "echo", invoke: respondWith(`{}`, nil)}
+	registry := map[string]providers.Provider{"echo": provider}
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"echo-direct": {
+				Type:      "echo",
+				Mode:      "transparent",
+				Endpoints: []instance.EndpointConfig{{Path: "/transparent/echo"}},
+			},
+		},
+	}
+	drains := drainedRouter(t, registry, "echo")
+	h := NewTransparentHandler(registry, currentConfig(config))
+	h.SetDrainState(drains)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/transparent/*path", h.HandleRequest)
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transparent/echo/items", nil))
+		return w
+	}
+
+	w := serve()
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "draining") {
+		t.Errorf("drained: status %d body %s, want 503 draining", w.Code, w.Body)
+	}
+	if calls := provider.calls.Load(); calls != 0 {
+		t.Errorf("the drained provider was invoked %d times", calls)
+	}
+
+	drains.Restore("echo")
+	if w := serve(); w.Code != http.StatusOK {
+		t.Errorf("restored: status %d body %s, want 200", w.Code, w.Body)
+	}
+}
+
+// TestTransparentRequestIDs tests that the gateway request ID and sanitized
+// tags are sent upstream and the upstream's own ID is returned alongside it
+func TestTransparentRequestIDs(t *testing.T) {
+	provider := &stubProvider{name: "echo", invoke: respondWith(`{}`, http.Header{"X-Request-Id": {"req_upstream_1"}})}
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"echo-direct": {
+				Type:      "echo",
+				Mode:      "transparent",
+				Endpoints: []instance.EndpointConfig{{Path: "/transparent/echo"}},
+			},
+		},
+	}
+	h := NewTransparentHandler(map[string]providers.Provider{"echo": provider}, currentConfig(config))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middleware.RequestID())
+	engine.Use(middleware.RequestTagging(middleware.RequestTagsConfig{Forward: true}))
+	engine.Any("/transparent/*path", h.HandleRequest)
+
+	req := httptest.NewRequest(http.MethodGet, "/transparent/echo/items", nil)
+	req.Header.Set("X-Request-ID", "client-supplied")
+	req.Header.Set("X-Request-Tags", "Team=search, bad tag=x")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	requestID := w.Header().Get("X-Request-ID")
+	if requestID == "" || requestID == "client-supplied" || requestID == "req_upstream_1" {
+		t.Errorf("X-Request-ID = %q, want the gateway's generated ID", requestID)
+	}
+	forwarded := provider.lastReq.Load()
+	if got := forwarded.Headers.Get("X-Request-ID"); got != requestID {
+		t.Errorf("upstream X-Request-ID = %q, want %q", got, requestID)
+	}
+	if got := forwarded.Headers.Get("X-Request-Tags"); got != "team=search" {
+		t.Errorf("upstream X-Request-Tags = %q, want sanitized tags", got)
+	}
+	if got := w.Header().Get("X-Upstream-Request-ID"); got != "req_upstream_1" {
+		t.Errorf("X-Upstream-Request-ID = %q, want req_upstream_1", got)
+	}
+}
+
+// TestTransparentInstanceRules tests that configured headers are injected
+// in both directions and the provider path is rewritten
+func TestTransparentInstanceRules(t *testing.T) {
+	provider := &stubProvider{name: "echo", invoke: respondWith(`{}`, http.Header{"X-Served-By": {"upstream"}})}
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"echo-direct": {
+				Type:            "echo",
+				Mode:            "transparent",
+				Endpoints:       []instance.EndpointConfig{{Path: "/transparent/echo"}},
+				RequestHeaders:  map[string]string{"X-Tenant": "search"},
+				ResponseHeaders: map[string]string{"X-Served-By": "gateway"},
+				PathRewrite:     &instance.PathRewrite{Regex: `^/v1/(.*)$`, Replacement: "/api/$1"},
+			},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	h := NewTransparentHandler(map[string]providers.Provider{"echo": provider}, currentConfig(config))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/transparent/*path", h.HandleRequest)
+
+	req := httptest.NewRequest(http.MethodGet, "/transparent/echo/v1/items", nil)
+	req.Header.Set("X-Tenant", "client")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	forwarded := provider.lastReq.Load()
+	if forwarded.Path != "/api/items" {
+		t.Errorf("provider path = %q, want /api/items", forwarded.Path)
+	}
+	if got := forwarded.Headers.Values("X-Tenant"); !reflect.DeepEqual(got, []string{"search"}) {
+		t.Errorf("upstream X-Tenant = %q, want the configured value only", got)
+	}
+	if got := w.Header().Values("X-Served-By"); !reflect.DeepEqual(got, []string{"gateway"}) {
+		t.Errorf("X-Served-By = %q, want the configured value only", got)
+	}
+}
+
+// TestTransparentStreamsBodies tests that a streaming provider receives the
+// client body with its length and the upstream body is returned unchanged
+func TestTransparentStreamsBodies(t *testing.T) {
+	var gotLength int64
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotLength = r.ContentLength
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	}))
+	defer upstream.Close()
+
+	engine := newPassthroughEngine(t, upstream.URL, "reverse_proxy")
+	payload := strings.Repeat("x", 64<<10)
+	req := httptest.NewRequest(http.MethodPost, "/transparent/openai/files", strings.NewReader(payload))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", w.Code, w.Body)
+	}
+	if gotLength != int64(len(payload)) {
+		t.Errorf("upstream Content-Length = %d, want %d", gotLength, len(payload))
+	}
+	if w.Body.String() != payload {
+		t.Errorf("response body has %d bytes, want the %d bytes sent", w.Body.Len(), len(payload))
+	}
+	if got := w.Header().Get("Content-Type"); got != "application/octet-stream" {
+		t.Errorf("Content-Type = %q, want application/octet-stream", got)
+	}
+}
+
+// TestTransparentReverseProxy tests the reverse proxy path: the provider's
+// credentials replace the client's, the query is kept verbatim and upstream
+// errors are returned as-is with the gateway request ID
+func TestTransparentReverseProxy(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer test" {
+			t.Errorf("upstream Authorization = %q, want the provider key", got)
+		}
+		if got := r.Header.Get("X-Api-Key"); got != "" {
+			t.Errorf("client X-Api-Key forwarded: %q", got)
+		}
+		if r.URL.Path != "/models" || r.URL.RawQuery != "a=1&a=2&b=%2F" {
+			t.Errorf("upstream URL = %s, want /models?a=1&a=2&b=%%2F", r.URL)
+		}
+		w.Header().Set("X-Request-Id", "req_upstream_1")
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"error":{"message":"slow down"}}`)
+	}))
+	defer upstream.Close()
+
+	engine := newPassthroughEngine(t, upstream.URL, "reverse_proxy", middleware.RequestID())
+	req := httptest.NewRequest(http.MethodGet, "/transparent/openai/models?a=1&a=2&b=%2F", nil)
+	req.Header.Set("X-API-Key", "client-key")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+
+	if w.Code != http.StatusTooManyRequests || w.Body.String() != `{"error":{"message":"slow down"}}` {
+		t.Errorf("response = %d %s, want the upstream error verbatim", w.Code, w.Body)
+	}
+	if got := w.Header().Values("X-Request-ID"); len(got) != 1 || got[0] == "req_upstream_1" {
+		t.Errorf("X-Request-ID = %q, want the gateway's ID only", got)
+	}
+	if got := w.Header().Get("X-Upstream-Request-ID"); got != "req_upstream_1" {
+		t.Errorf("X-Upstream-Request-ID = %q, want req_upstream_1", got)
+	}
+	if got := w.Header().Get("Retry-After"); got != "3" {
+		t.Errorf("Retry-After = %q, want 3", got)
+	}
+}
+
+// TestTransparentReverseProxyUnreachable tests that a transport failure is
+// reported as a bad gateway
+func TestTransparentReverseProxyUnreachable(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstream.Close()
+
+	engine := newPassthroughEngine(t, upstream.URL, "reverse_proxy")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/transparent/openai/models", nil))
+
+	if w.Code != http.StatusBadGateway {
+		t.Errorf("status = %d, want 502", w.Code)
+	}
+}
+
+// TestTransparentSigV4PayloadHash tests that SigV4 instances sign the hash
+// of a buffered body, or UNSIGNED-PAYLOAD when allowed, in which case the
+// body is streamed with its length
+func TestTransparentSigV4PayloadHash(t *testing.T) {
+	setTestAWSCredentials(t)
+	payload := "object contents"
+	sum := sha256.Sum256([]byte(payload))
+
+	for _, tc := range []struct {
+		unsigned bool
+		want     string
+	}{
+		{false, hex.EncodeToString(sum[:])},
+		{true, "UNSIGNED-PAYLOAD"},
+	} {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if got := r.Header.Get("X-Amz-Content-Sha256"); got != tc.want {
+				t.Errorf("unsigned=%v: X-Amz-Content-Sha256 = %q, want %q", tc.unsigned, got, tc.want)
+			}
+			if !strings.Contains(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+				t.Errorf("unsigned=%v: request is not signed", tc.unsigned)
+			}
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != payload || r.ContentLength != int64(len(payload)) {
+				t.Errorf("unsigned=%v: body = %q (length %d), want %q", tc.unsigned, body, r.ContentLength, payload)
+			}
+		}))
+
+		engine := newSigV4PassthroughEngine(t, upstream.URL, tc.unsigned)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/transparent/s3/bucket/key", strings.NewReader(payload)))
+		if w.Code != http.StatusOK {
+			t.Errorf("unsigned=%v: status = %d, body %s", tc.unsigned, w.Code, w.Body)
+		}
+		upstream.Close()
+	}
+}
+
+// BenchmarkTransparentPassthrough sends bodies of 1, 10 and 100 MB through
+// the transparent handler in both directions, on the reverse proxy path
+// and on the Invoke path with a streaming and a buffering provider.
+// Streaming keeps B/op flat as the size grows; the buffered runs, whose
+// provider hides InvokeStream, grow linearly.
+func BenchmarkTransparentPassthrough(b *testing.B) {
+	for _, size := range []int64{1 << 20, 10 << 20, 100 << 20} {
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.Copy(io.Discard, r.Body)
+			w.Header().Set("Content-Type", "application/octet-stream")
+			io.Copy(w, io.LimitReader(zeroReader{}, size))
+		}))
+
+		for _, mode := range []string{"reverse_proxy", "streaming", "buffered"} {
+			b.Run(fmt.Sprintf("%s/%dMB", mode, size>>20), func(b *testing.B) {
+				benchmarkPassthrough(b, newPassthroughEngine(b, upstream.URL, mode), "/transparent/openai/files", size)
+			})
+		}
+		upstream.Close()
+	}
+}
+
+// BenchmarkTransparentSigV4 compares signing the hash of a buffered body
+// with streaming it as UNSIGNED-PAYLOAD
+func BenchmarkTransparentSigV4(b *testing.B) {
+	setTestAWSCredentials(b)
+	size := int64(10 << 20)
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		io.Copy(w, io.LimitReader(zeroReader{}, size))
+	}))
+	defer upstream.Close()
+
+	for _, unsigned := range []bool{false, true} {
+		name := "buffered"
+		if unsigned {
+			name = "unsigned_payload"
+		}
+		b.Run(fmt.Sprintf("%s/%dMB", name, size>>20), func(b *testing.B) {
+			benchmarkPassthrough(b, newSigV4PassthroughEngine(b, upstream.URL, unsigned), "/transparent/s3/bucket/key", size)
+		})
+	}
+}
+
+// benchmarkPassthrough sends size bytes to path and expects as many back
+func benchmarkPassthrough(b *testing.B, engine *gin.Engine, path string, size int64) {
+	b.ReportAllocs()
+	b.SetBytes(size)
+	for i := 0; i < b.N; i++ {
+		req := httptest.NewRequest(http.MethodPut, path, io.LimitReader(zeroReader{}, size))
+		req.ContentLength = size
+		w := &discardResponseWriter{header: http.Header{}}
+		engine.ServeHTTP(w, req)
+		if w.status != http.StatusOK || w.written != size {
+			b.Fatalf("status = %d, wrote %d bytes", w.status, w.written)
+		}
+	}
+}
+
+// newPassthroughEngine routes /transparent/openai to an OpenAI provider at
+// baseURL. mode selects the path: "reverse_proxy", "streaming" for the
+// Invoke path, or "buffered" for the Invoke path with a provider that hides
+// InvokeStream.
+func newPassthroughEngine(tb testing.TB, baseURL, mode string, middlewares ...gin.HandlerFunc) *gin.Engine {
+	tb.Helper()
+	openaiProvider, err := openai.NewOpenAIProvider(openai.OpenAIConfig{APIKey: "test", BaseURL: baseURL})
+	if err != nil {
+		tb.Fatalf("NewOpenAIProvider: %v", err)
+	}
+	var provider providers.Provider = openaiProvider
+	if mode == "buffered" {
+		provider = struct{ providers.Provider }{openaiProvider}
+	}
+	reverseProxy := mode == "reverse_proxy"
+
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai-direct": {
+				Type:         "openai",
+				Mode:         "transparent",
+				Endpoints:    []instance.EndpointConfig{{Path: "/transparent/openai"}},
+				ReverseProxy: &reverseProxy,
+			},
+		},
+	}
+	h := NewTransparentHandler(map[string]providers.Provider{"openai": provider}, currentConfig(config))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(middlewares...)
+	engine.Any("/transparent/*path", h.HandleRequest)
+	return engine
+}
+
+// newSigV4PassthroughEngine routes /transparent/s3 to an S3 endpoint at
+// baseURL, signed with SigV4
+func newSigV4PassthroughEngine(tb testing.TB, baseURL string, unsignedPayload bool) *gin.Engine {
+	tb.Helper()
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"s3": {
+				Type:      "s3",
+				Mode:      "transparent",
+				Endpoint:  baseURL,
+				Endpoints: []instance.EndpointConfig{{Path: "/transparent/s3"}},
+				Authentication: instance.AuthenticationConfig{
+					Type:            "aws_sigv4",
+					Service:         "s3",
+					Region:          "us-east-1",
+					UnsignedPayload: unsignedPayload,
+				},
+			},
+		},
+	}
+	h := NewTransparentHandler(map[string]providers.Provider{}, currentConfig(config))
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/transparent/*path", h.HandleRequest)
+	return engine
+}
+
+// setTestAWSCredentials points the AWS credential chain at static test keys
+func setTestAWSCredentials(tb testing.TB) {
+	tb.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	tb.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	tb.Setenv("AWS_SESSION_TOKEN", "")
+	tb.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	tb.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+}
+
+// zeroReader is an endless source of zero bytes
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+// discardResponseWriter counts the response body instead of recording it
+type discardResponseWriter struct {
+	header  http.Header
+	status  int
+	written int64
+}
+
+func (w *discardResponseWriter) Header() http.Header { return w.header }
+
+func (w *discardResponseWriter) WriteHeader(status int) { w.status = status }
+
+func (w *discardResponseWriter) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.written += int64(len(p))
+	return len(p), nil
+}
+
+func (w *discardResponseWriter) Flush() {}
+
+// TestTransparentInstanceProvider tests that a provider set for an instance
+// serves it instead of the provider for its type
+func TestTransparentInstanceProvider(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-API-Key"); got != "internal-key" {
+			t.Errorf("upstream X-API-Key = %q, want the instance key", got)
+		}
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer upstream.Close()
+
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"scorer": {
+				Type:      "generic_http",
+				Mode:      "transparent",
+				Endpoints: []instance.EndpointConfig{{Path: "/transparent/scorer"}},
+			},
+		},
+	}
+	provider, err := generic.NewGenericHTTPProvider(generic.GenericHTTPConfig{
+		Name:    "scorer",
+		BaseURL: upstream.URL,
+		Auth:    generic.GenericHTTPAuth{Type: generic.AuthAPIKey, Key: "internal-key"},
+	})
+	if err != nil {
+		t.Fatalf("NewGenericHTTPProvider: %v", err)
+	}
+	h := NewTransparentHandler(map[string]providers.Provider{}, currentConfig(config))
+	h.SetInstanceProviders(map[string]providers.Provider{"scorer": provider})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/transparent/*path", h.HandleRequest)
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transparent/scorer/v1/score", strings.NewReader(`{}`)))
+
+	if w.Code != http.StatusOK || w.Body.String() != "/v1/score" {
+		t.Errorf("response = %d %s, want 200 /v1/score", w.Code, w.Body)
+	}
+}
+
+func TestIsAuthHeader(t *testing.T) {
+	for _, name := range []string{"Authorization", "authorization", "AUTHORIZATION", "x-api-key", "Api-Key", "X-Auth-Token"} {
+		if !isAuthHeader(name) {
+			t.Errorf("isAuthHeader(%q) = false", name)
+		}
+	}
+	if isAuthHeader("X-Request-ID") {
+		t.Error("isAuthHeader(X-Request-ID) = true")
+	}
+}
+
+// TestTransparentReverseProxyTimeouts tests that reverse-proxied requests
+// get their instance's request timeout, which does not cut off streams
+func TestTransparentReverseProxyTimeouts(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stream" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 3; i++ {
+				io.WriteString(w, "data: {}\n\n")
+				w.(http.Flusher).Flush()
+				time.Sleep(60 * time.Millisecond)
+			}
+			return
+		}
+		time.Sleep(300 * time.Millisecond)
+		io.WriteString(w, `{}`)
+	}))
+	defer upstream.Close()
+
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"scorer": {
+				Type:         "generic_http",
+				Mode:         "transparent",
+				Endpoints:    []instance.EndpointConfig{{Path: "/transparent/scorer"}},
+				HTTPTimeouts: instance.HTTPTimeouts{RequestTimeout: "100ms"},
+			},
+		},
+	}
+	provider, err := generic.NewGenericHTTPProvider(generic.GenericHTTPConfig{Name: "scorer", BaseURL: upstream.URL})
+	if err != nil {
+		t.Fatalf("NewGenericHTTPProvider: %v", err)
+	}
+	h := NewTransparentHandler(map[string]providers.Provider{}, currentConfig(config))
+	h.SetInstanceProviders(map[string]providers.Provider{"scorer": provider})
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/transparent/*path", h.HandleRequest)
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transparent/scorer/slow", strings.NewReader(`{}`)))
+	if w.Code != http.StatusGatewayTimeout {
+		t.Errorf("slow upstream: status = %d, want 504 after the instance's 100ms", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/transparent/scorer/stream", strings.NewReader(`{}`)))
+	if w.Code != http.StatusOK || strings.Count(w.Body.String(), "data:") != 3 {
+		t.Errorf("stream: %d %q, want all 3 events", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,208 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/auth"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// passthroughTransport authorizes reverse-proxied requests with the
+// provider's credentials. Providers that sign the payload get its hash:
+// UNSIGNED-PAYLOAD when the instance allows it, otherwise the body is
+// buffered to be hashed.
+type passthroughTransport struct {
+	base            http.RoundTripper
+	provider        providers.Provider
+	passthrough     providers.Passthrough
+	unsignedPayload bool
+}
+
+// newPassthroughTransport returns the reverse proxy transport for an
+// instance, or false when it uses the Invoke path: its provider does not
+// implement providers.Passthrough or reverse_proxy is disabled
+func newPassthroughTransport(base http.RoundTripper, provider providers.Provider, inst instance.InstanceConfig) (*passthroughTransport, bool) {
+	if inst.ReverseProxy != nil && !*inst.ReverseProxy {
+		return nil, false
+	}
+	passthrough, ok := provider.(providers.Passthrough)
+	if !ok {
+		return nil, false
+	}
+	return &passthroughTransport{
+		base:            base,
+		provider:        provider,
+		passthrough:     passthrough,
+		unsignedPayload: inst.Authentication.UnsignedPayload,
+	}, true
+}
+
+// buffersBody reports whether request bodies are read whole to be signed
+func (t *passthroughTransport) buffersBody() bool {
+	return t.passthrough.SignsPayload() && !t.unsignedPayload
+}
+
+// RoundTrip authorizes and sends a request
+func (t *passthroughTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var payloadHash string
+	if t.passthrough.SignsPayload() {
+		payloadHash = auth.UnsignedPayload
+		if !t.unsignedPayload {
+			hash, err := bufferBody(req)
+			if err != nil {
+				return nil, &providers.ProviderError{
+					Provider:   t.provider.Name(),
+					StatusCode: http.StatusBadRequest,
+					Code:       providers.ErrCodeInvalidRequest,
+					Message:    "Failed to read request body",
+					Err:        err,
+				}
+			}
+			payloadHash = hash
+		}
+	}
+
+	if err := t.passthrough.Authorize(req, payloadHash); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, &providers.ProviderError{
+			Provider:   t.provider.Name(),
+			StatusCode: http.StatusInternalServerError,
+			Code:       providers.ErrCodeAuthenticationFail,
+			Message:    "Failed to sign request",
+			Err:        err,
+		}
+	}
+	return t.base.RoundTrip(req)
+}
+
+// bufferBody reads the request body into memory, replacing it with a
+// rewindable copy, and returns its hex SHA-256
+func bufferBody(req *http.Request) (string, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		req.ContentLength = int64(len(body))
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// newProxyTransport returns a connection pool for reverse-proxied
+// instances, sized for many concurrent requests to few upstream hosts, with
+// the connect timeout applied to the dialer and the TLS handshake
+func newProxyTransport(timeouts providers.HTTPTimeouts) *http.Transport {
+	transport := providers.NewTransport(timeouts)
+	transport.MaxIdleConns = 200
+	transport.MaxIdleConnsPerHost = 50
+	return transport
+}
+
+// proxyBufferPool reuses the copy buffers of reverse-proxied bodies
+type proxyBufferPool struct {
+	pool sync.Pool
+}
+
+func (p *proxyBufferPool) Get() []byte {
+	if buf, ok := p.pool.Get().(*[]byte); ok {
+		return *buf
+	}
+	return make([]byte, 32<<10)
+}
+
+func (p *proxyBufferPool) Put(buf []byte) {
+	p.pool.Put(&buf)
+}
+
+// reverseProxy forwards a transparent request with httputil.ReverseProxy,
+// streaming both bodies. providerReq carries the prepared path and headers.
+// Upstream responses, including error statuses, are returned verbatim.
+func (h *TransparentHandler) reverseProxy(c *gin.Context, transport *passthroughTransport, providerReq *providers.ProviderRequest, instanceCfg *instance.InstanceConfig, startTime time.Time) {
+	target, err := url.Parse(transport.passthrough.BaseURL() + providerReq.Path)
+	if err != nil {
+		log.Printf("Invalid provider path %s: %v", providerReq.Path, err)
+		respondJSON(c, http.StatusBadRequest, gin.H{
+			"error": "Invalid provider path",
+		})
+		return
+	}
+	target.RawQuery = c.Request.URL.RawQuery
+
+	proxy := &httputil.ReverseProxy{
+		Rewrite: func(pr *httputil.ProxyRequest) {
+			pr.Out.URL = target
+			pr.Out.Host = ""
+			pr.Out.Header = providerReq.Headers
+		},
+		// ReverseProxy flushes SSE and unknown-length responses as they arrive
+		Transport:  transport,
+		BufferPool: h.bufferPool,
+		ModifyResponse: func(resp *http.Response) error {
+			// Upstream headers replace those already set, as on the Invoke path
+			for key := range resp.Header {
+				c.Writer.Header().Del(key)
+			}
+			RecordUpstreamRequestID(c, resp.Header)
+			if c.GetString(middleware.RequestIDKey) != "" {
+				resp.Header.Del(providers.RequestIDHeader)
+			}
+			for name := range instanceCfg.ResponseHeaders {
+				resp.Header.Del(name)
+			}
+			applyResponseHeaders(c, instanceCfg)
+
+			recordTransparentMetrics(instanceCfg, c.Request.Method, resp.StatusCode, startTime)
+			return nil
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			log.Printf("Provider invocation error: %v", err)
+			status := http.StatusBadGateway
+			var providerErr *providers.ProviderError
+			if errors.As(err, &providerErr) && providerErr.StatusCode != 0 {
+				status = providerErr.StatusCode
+			} else if isTimeout(err) {
+				status = http.StatusGatewayTimeout
+			}
+			respondJSON(c, status, gin.H{
+				"error": "Provider request failed",
+			})
+		},
+	}
+
+	// Without a cancellable context ReverseProxy falls back to CloseNotify,
+	// which gin's writer only supports over a real connection
+	req := c.Request
+	if req.Context().Done() == nil {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+	proxy.ServeHTTP(c.Writer, req)
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// mergeUpstreamHeaders copies the provider's rate limit and retry-after
+// headers (providers.PropagatedHeaders) into dst. Where the gateway's own
+// limiter already set a header, the stricter value wins: the lower limit or
+// remaining count, and the longer retry-after or reset.
+func mergeUpstreamHeaders(dst, upstream http.Header) {
+	for key, values := range providers.PropagatedHeaders(upstream) {
+		value := values[0]
+		if existing := dst.Get(key); existing != "" {
+			value = stricterHeader(key, existing, value)
+		}
+		dst.Set(key, value)
+	}
+}
+
+// mergeProviderErrorHeaders merges the upstream headers of a provider error,
+// so clients see retry-after on upstream 429s
+func mergeProviderErrorHeaders(dst http.Header, err error) {
+	if providerErr, ok := err.(*providers.ProviderError); ok && providerErr.Headers != nil {
+		mergeUpstreamHeaders(dst, providerErr.Headers)
+	}
+}
+
+// stricterHeader returns the stricter of the gateway (local) and upstream
+// values of a rate limit header. Values that cannot be compared keep the
+// upstream value.
+func stricterHeader(key, local, upstream string) string {
+	name := strings.ToLower(key)
+	longer := strings.HasPrefix(name, "retry-after") || strings.Contains(name, "reset")
+
+	if a, err := strconv.ParseFloat(local, 64); err == nil {
+		if b, err := strconv.ParseFloat(upstream, 64); err == nil {
+			if (longer && a > b) || (!longer && a < b) {
+				return local
+			}
+			return upstream
+		}
+	}
+	if a, err := time.ParseDuration(local); err == nil {
+		if b, err := time.ParseDuration(upstream); err == nil && a > b {
+			return local
+		}
+		return upstream
+	}
+	if a, err := time.Parse(time.RFC3339, local); err == nil {
+		if b, err := time.Parse(time.RFC3339, upstream); err == nil && a.After(b) {
+			return local
+		}
+	}
+	return upstream
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+func TestMergeUpstreamHeaders(t *testing.T) {
+	// Gateway limiter headers already on the response
+	dst := http.Header{}
+	dst.Set("X-Ratelimit-Remaining-Requests", "5")
+	dst.Set("X-Ratelimit-Reset-Requests", "30s")
+	dst.Set("X-Ratelimit-Limit-Tokens", "1000")
+
+	upstream := http.Header{}
+	upstream.Set("X-Ratelimit-Remaining-Requests", "499")
+	upstream.Set("X-Ratelimit-Reset-Requests", "1m0s")
+	upstream.Set("X-Ratelimit-Limit-Tokens", "200")
+	upstream.Set("Anthropic-Ratelimit-Requests-Reset", "2025-01-01T00:01:00Z")
+	upstream.Set("Retry-After", "7")
+	upstream.Set("Set-Cookie", "session=upstream")
+
+	mergeUpstreamHeaders(dst, upstream)
+
+	want := map[string]string{
+		"X-Ratelimit-Remaining-Requests":     "5",    // gateway is stricter
+		"X-Ratelimit-Reset-Requests":         "1m0s", // longer reset wins
+		"X-Ratelimit-Limit-Tokens":           "200",  // upstream is stricter
+		"Anthropic-Ratelimit-Requests-Reset": "2025-01-01T00:01:00Z",
+		"Retry-After":                        "7",
+		"Set-Cookie":                         "", // not propagated
+	}
+	for key, value := range want {
+		if got := dst.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestStricterHeader(t *testing.T) {
+	tests := []struct {
+		key, local, upstream, want string
+	}{
+		{"Retry-After", "10", "3", "10"},
+		{"Retry-After", "2", "3", "3"},
+		{"X-Ratelimit-Remaining-Tokens", "100", "90", "90"},
+		{"Anthropic-Ratelimit-Tokens-Reset", "2025-01-01T00:02:00Z", "2025-01-01T00:01:00Z", "2025-01-01T00:02:00Z"},
+		{"X-Ratelimit-Reset-Tokens", "soon", "6m0s", "6m0s"},
+	}
+	for _, tt := range tests {
+		if got := stricterHeader(tt.key, tt.local, tt.upstream); got != tt.want {
+			t.Errorf("stricterHeader(%s, %q, %q) = %q, want %q", tt.key, tt.local, tt.upstream, got, tt.want)
+		}
+	}
+}
+
+func TestMergeProviderErrorHeaders(t *testing.T) {
+	dst := http.Header{}
+	err := &providers.ProviderError{StatusCode: http.StatusTooManyRequests, Headers: http.Header{"Retry-After": {"20"}}}
+	mergeProviderErrorHeaders(dst, err)
+	if dst.Get("Retry-After") != "20" {
+		t.Errorf("Retry-After = %q, want upstream value", dst.Get("Retry-After"))
+	}
+
+	mergeProviderErrorHeaders(dst, errors.New("plain error"))
+}
@@ -1,38 +1,144 @@
 package health
 
 import (
+	"fmt"
 	"net/http"
+	"sort"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
 )
 
-// Checker provides health and readiness checking functionality
-type Checker struct {
-	healthy     int32
-	ready       int32
-	errors      int64
-	successes   int64
+// windowBuckets is the number of buckets a sliding window is divided into.
+// Samples expire one bucket (Window/windowBuckets) at a time.
+const windowBuckets = 10
+
+// Config controls how error rates are tracked and judged
+type Config struct {
+	// Window is the period over which error rates are computed
+	Window time.Duration
+
+	// MinSamples is the minimum number of samples in the window before a
+	// provider can be marked unhealthy
+	MinSamples int
+
+	// ErrorRateThreshold marks a provider unhealthy when its error rate
+	// exceeds this value (0.0-1.0)
+	ErrorRateThreshold float64
+}
+
+// DefaultConfig returns the default health tracking configuration
+func DefaultConfig() Config {
+	return Config{
+		Window:             5 * time.Minute,
+		MinSamples:         10,
+		ErrorRateThreshold: 0.5,
+	}
+}
+
+// ProviderStats is a snapshot of a provider's recent traffic
+type ProviderStats struct {
+	Provider    string    `json:"provider"`
+	Healthy     bool      `json:"healthy"`
+	ErrorRate   float64   `json:"error_rate"`
+	Samples     int64     `json:"samples"`
+	Errors      int64     `json:"errors"`
+	Successes   int64     `json:"successes"`
+	LastError   time.Time `json:"last_error,omitempty"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+}
+
+// bucket holds the counts for one slice of the window
+type bucket struct {
+	start     time.Time
+	errors    int64
+	successes int64
+}
+
+// window is a time-bucketed sliding window of request outcomes
+type window struct {
+	buckets     [windowBuckets]bucket
 	lastError   time.Time
 	lastSuccess time.Time
-	startTime   time.Time
+	unhealthy   bool // health as last judged, for detecting transitions
 }
 
-// NewChecker creates a new health checker
+// Checker provides health and readiness checking functionality.
+// Request outcomes are tracked per provider over a sliding window, so old
+// errors age out and a trickle of successes cannot mask a high error rate.
+type Checker struct {
+	ready     int32
+	startTime time.Time
+	config    Config
+
+	mu      sync.Mutex
+	windows map[string]*window
+
+	// notifier is told when a provider's error rate crosses the threshold
+	notifier *notify.Notifier
+
+	// now is overridable for tests
+	now func() time.Time
+}
+
+// NewChecker creates a new health checker with the default configuration
 func NewChecker() *Checker {
-	checker := &Checker{
-		healthy:     1,
-		ready:       1,
-		startTime:   time.Now(),
-		lastSuccess: time.Now(),
+	return NewCheckerWithConfig(DefaultConfig())
+}
+
+// NewCheckerWithConfig creates a new health checker; zero config fields use defaults
+func NewCheckerWithConfig(config Config) *Checker {
+	defaults := DefaultConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.MinSamples <= 0 {
+		config.MinSamples = defaults.MinSamples
+	}
+	if config.ErrorRateThreshold <= 0 {
+		config.ErrorRateThreshold = defaults.ErrorRateThreshold
+	}
+
+	return &Checker{
+		ready:     1,
+		startTime: time.Now(),
+		config:    config,
+		windows:   make(map[string]*window),
+		now:       time.Now,
 	}
-	return checker
 }
 
-// IsHealthy returns true if the service is healthy
+// IsHealthy returns true if the service is healthy, i.e. the error rate
+// across all providers within the window does not exceed the threshold
 func (c *Checker) IsHealthy() bool {
-	return atomic.LoadInt32(&c.healthy) == 1
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errors, successes int64
+	now := c.now()
+	for _, w := range c.windows {
+		e, s := c.counts(w, now)
+		errors += e
+		successes += s
+	}
+	return c.judge(errors, successes)
+}
+
+// IsProviderHealthy returns true if the provider's recent error rate is acceptable.
+// Providers without traffic are considered healthy.
+func (c *Checker) IsProviderHealthy(provider string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	w, ok := c.windows[provider]
+	if !ok {
+		return true
+	}
+	return c.judge(c.counts(w, c.now()))
 }
 
 // IsReady returns true if the service is ready to serve traffic
@@ -40,25 +146,20 @@ func (c *Checker) IsReady() bool {
 	return atomic.LoadInt32(&c.ready) == 1
 }
 
-// RecordError records a service error
-func (c *Checker) RecordError() {
-	atomic.AddInt64(&c.errors, 1)
-	c.lastError = time.Now()
-
-	// Mark as unhealthy if error rate is too high
-	errorRate := c.getErrorRate()
-	if errorRate > 0.5 { // More than 50% errors
-		atomic.StoreInt32(&c.healthy, 0)
-	}
+// RecordError records a failed request to the given provider or instance
+func (c *Checker) RecordError(provider string) {
+	c.record(provider, true)
 }
 
-// RecordSuccess records a successful operation
-func (c *Checker) RecordSuccess() {
-	atomic.AddInt64(&c.successes, 1)
-	c.lastSuccess = time.Now()
+// RecordSuccess records a successful request to the given provider or instance
+func (c *Checker) RecordSuccess(provider string) {
+	c.record(provider, false)
+}
 
-	// Mark as healthy if we have recent success
-	atomic.StoreInt32(&c.healthy, 1)
+// SetNotifier sends a health transition event to webhooks whenever a
+// provider's error rate crosses the threshold
+func (c *Checker) SetNotifier(notifier *notify.Notifier) {
+	c.notifier = notifier
 }
 
 // SetReady sets the readiness state
@@ -70,30 +171,155 @@ func (c *Checker) SetReady(ready bool) {
 	}
 }
 
-// getErrorRate calculates the current error rate
-func (c *Checker) getErrorRate() float64 {
-	errors := atomic.LoadInt64(&c.errors)
-	successes := atomic.LoadInt64(&c.successes)
+// record adds a sample to the provider's current bucket and updates metrics
+func (c *Checker) record(provider string, failed bool) {
+	c.mu.Lock()
+	now := c.now()
+
+	w, ok := c.windows[provider]
+	if !ok {
+		w = &window{}
+		c.windows[provider] = w
+	}
+
+	b := c.currentBucket(w, now)
+	if failed {
+		b.errors++
+		w.lastError = now
+	} else {
+		b.successes++
+		w.lastSuccess = now
+	}
+
+	stats := c.stats(provider, w, now)
+	transition := stats.Healthy == w.unhealthy
+	w.unhealthy = !stats.Healthy
+	c.mu.Unlock()
+
+	metrics.SetProviderHealth(provider, stats.ErrorRate, stats.Samples, stats.Healthy)
+	if transition {
+		c.notifyTransition(stats)
+	}
+}
+
+// notifyTransition reports a provider whose health changed
+func (c *Checker) notifyTransition(stats ProviderStats) {
+	state := "unhealthy"
+	if stats.Healthy {
+		state = "healthy"
+	}
+	c.notifier.Notify(notify.Event{
+		Type:    notify.EventHealthTransition,
+		Subject: stats.Provider,
+		State:   state,
+		Message: fmt.Sprintf("%s: error rate %.0f%% over %d requests (threshold %.0f%%)",
+			state, stats.ErrorRate*100, stats.Samples, c.config.ErrorRateThreshold*100),
+		Details: map[string]string{"error_rate": fmt.Sprintf("%.4f", stats.ErrorRate)},
+	})
+}
+
+// currentBucket returns the bucket for now, resetting it if it is stale
+func (c *Checker) currentBucket(w *window, now time.Time) *bucket {
+	width := c.bucketWidth()
+	start := now.Truncate(width)
+	b := &w.buckets[(start.UnixNano()/int64(width))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	return b
+}
+
+// counts sums the buckets that fall inside the window ending at now
+func (c *Checker) counts(w *window, now time.Time) (errors, successes int64) {
+	oldest := now.Truncate(c.bucketWidth()).Add(-c.config.Window)
+	for i := range w.buckets {
+		b := &w.buckets[i]
+		if b.start.After(oldest) {
+			errors += b.errors
+			successes += b.successes
+		}
+	}
+	return errors, successes
+}
+
+// judge applies the min-sample and error-rate thresholds
+func (c *Checker) judge(errors, successes int64) bool {
 	total := errors + successes
+	if total < int64(c.config.MinSamples) {
+		return true
+	}
+	return float64(errors)/float64(total) <= c.config.ErrorRateThreshold
+}
 
-	if total == 0 {
-		return 0.0
+func (c *Checker) bucketWidth() time.Duration {
+	width := c.config.Window / windowBuckets
+	if width <= 0 {
+		width = time.Nanosecond
 	}
+	return width
+}
 
-	return float64(errors) / float64(total)
+// stats builds a snapshot for one provider; the caller must hold c.mu
+func (c *Checker) stats(provider string, w *window, now time.Time) ProviderStats {
+	errors, successes := c.counts(w, now)
+	total := errors + successes
+
+	var rate float64
+	if total > 0 {
+		rate = float64(errors) / float64(total)
+	}
+
+	return ProviderStats{
+		Provider:    provider,
+		Healthy:     c.judge(errors, successes),
+		ErrorRate:   rate,
+		Samples:     total,
+		Errors:      errors,
+		Successes:   successes,
+		LastError:   w.lastError,
+		LastSuccess: w.lastSuccess,
+	}
+}
+
+// ProviderStats returns per-provider traffic statistics, sorted by provider
+func (c *Checker) ProviderStats() []ProviderStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	result := make([]ProviderStats, 0, len(c.windows))
+	for name, w := range c.windows {
+		result = append(result, c.stats(name, w, now))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Provider < result[j].Provider
+	})
+	return result
 }
 
 // GetStats returns health statistics
 func (c *Checker) GetStats() map[string]interface{} {
+	var errors, successes int64
+	providerStats := c.ProviderStats()
+	for _, s := range providerStats {
+		errors += s.Errors
+		successes += s.Successes
+	}
+
+	var errorRate float64
+	if total := errors + successes; total > 0 {
+		errorRate = float64(errors) / float64(total)
+	}
+
 	return map[string]interface{}{
-		"healthy":      c.IsHealthy(),
-		"ready":        c.IsReady(),
-		"errors":       atomic.LoadInt64(&c.errors),
-		"successes":    atomic.LoadInt64(&c.successes),
-		"error_rate":   c.getErrorRate(),
-		"uptime":       time.Since(c.startTime).String(),
-		"last_error":   c.lastError.Format(time.RFC3339),
-		"last_success": c.lastSuccess.Format(time.RFC3339),
+		"healthy":    c.IsHealthy(),
+		"ready":      c.IsReady(),
+		"window":     c.config.Window.String(),
+		"errors":     errors,
+		"successes":  successes,
+		"error_rate": errorRate,
+		"uptime":     time.Since(c.startTime).String(),
+		"providers":  providerStats,
 	}
 }
 
@@ -1,9 +1,25 @@
 package health
 
 import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
 )
 
+// newTestChecker returns a checker with a controllable clock
+func newTestChecker(config Config) (*Checker, *time.Time) {
+	checker := NewCheckerWithConfig(config)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	checker.now = func() time.Time { return now }
+	return checker, &now
+}
+
 func TestNewChecker(t *testing.T) {
 	checker := NewChecker()
 
@@ -21,12 +37,12 @@ func TestRecordError(t *testing.T) {
 
 	// Record some successes first to establish a baseline
 	for i := 0; i < 10; i++ {
-		checker.RecordSuccess()
+		checker.RecordSuccess("bedrock")
 	}
 
 	// Record some errors
 	for i := 0; i < 10; i++ {
-		checker.RecordError()
+		checker.RecordError("bedrock")
 	}
 
 	// Should still be healthy with balanced errors/successes
@@ -36,32 +52,116 @@ func TestRecordError(t *testing.T) {
 
 	// Record many more errors to tip the balance
 	for i := 0; i < 100; i++ {
-		checker.RecordError()
+		checker.RecordError("bedrock")
 	}
 
 	// Now should be unhealthy due to high error rate
 	if checker.IsHealthy() {
 		t.Error("Checker should be unhealthy with high error rate")
 	}
+	if checker.IsProviderHealthy("bedrock") {
+		t.Error("bedrock should be unhealthy with high error rate")
+	}
 }
 
 func TestRecordSuccess(t *testing.T) {
-	checker := NewChecker()
+	checker, now := newTestChecker(Config{Window: time.Minute, MinSamples: 10})
 
 	// Make unhealthy first
 	for i := 0; i < 100; i++ {
-		checker.RecordError()
+		checker.RecordError("bedrock")
 	}
 
 	if checker.IsHealthy() {
 		t.Error("Checker should be unhealthy")
 	}
 
-	// Record success should make it healthy again
-	checker.RecordSuccess()
+	// A single success must not mask a 99% error rate
+	checker.RecordSuccess("bedrock")
+	if checker.IsHealthy() {
+		t.Error("Checker should remain unhealthy after a single success")
+	}
+
+	// Once the errors age out of the window, successes make it healthy again
+	*now = now.Add(2 * time.Minute)
+	for i := 0; i < 10; i++ {
+		checker.RecordSuccess("bedrock")
+	}
 
 	if !checker.IsHealthy() {
-		t.Error("Checker should be healthy after success")
+		t.Error("Checker should be healthy after errors leave the window")
+	}
+}
+
+func TestSlidingWindowExpiry(t *testing.T) {
+	checker, now := newTestChecker(Config{Window: time.Minute, MinSamples: 5, ErrorRateThreshold: 0.5})
+
+	for i := 0; i < 10; i++ {
+		checker.RecordError("vertex")
+	}
+	if checker.IsProviderHealthy("vertex") {
+		t.Fatal("vertex should be unhealthy after a burst of errors")
+	}
+
+	// Half a window later the burst still counts
+	*now = now.Add(30 * time.Second)
+	if checker.IsProviderHealthy("vertex") {
+		t.Error("vertex should still be unhealthy within the window")
+	}
+
+	// After a full window the burst has expired; too few samples to judge
+	*now = now.Add(31 * time.Second)
+	if !checker.IsProviderHealthy("vertex") {
+		t.Error("vertex should be healthy once the burst leaves the window")
+	}
+
+	stats := checker.ProviderStats()
+	if len(stats) != 1 || stats[0].Samples != 0 {
+		t.Errorf("expected empty window, got %+v", stats)
+	}
+}
+
+func TestMinSamples(t *testing.T) {
+	checker, _ := newTestChecker(Config{Window: time.Minute, MinSamples: 10})
+
+	for i := 0; i < 9; i++ {
+		checker.RecordError("openai")
+	}
+	if !checker.IsProviderHealthy("openai") {
+		t.Error("openai should not be judged below the minimum sample count")
+	}
+
+	checker.RecordError("openai")
+	if checker.IsProviderHealthy("openai") {
+		t.Error("openai should be unhealthy once the minimum sample count is reached")
+	}
+}
+
+func TestPerProviderAttribution(t *testing.T) {
+	checker, _ := newTestChecker(Config{Window: time.Minute, MinSamples: 5})
+
+	for i := 0; i < 10; i++ {
+		checker.RecordError("azure")
+		checker.RecordSuccess("bedrock")
+		checker.RecordSuccess("bedrock")
+		checker.RecordSuccess("bedrock")
+	}
+
+	if checker.IsProviderHealthy("azure") {
+		t.Error("azure should be unhealthy")
+	}
+	if !checker.IsProviderHealthy("bedrock") {
+		t.Error("bedrock should be healthy")
+	}
+
+	// Aggregate rate is 25%, below threshold
+	if !checker.IsHealthy() {
+		t.Error("overall health should reflect the aggregate error rate")
+	}
+
+	stats := checker.ProviderStats()
+	if len(stats) != 2 || stats[0].Provider != "azure" || stats[0].ErrorRate != 1.0 || stats[1].Samples != 30 {
+		t.Errorf("unexpected provider stats: %+v", stats)
 	}
 }
 
@@ -82,3 +182,70 @@ func TestReadiness(t *testing.T) {
 		t.Error("Checker should be ready after SetReady(true)")
 	}
 }
+
+func TestCheckTracker(t *testing.T) {
+	tracker := NewCheckTracker(Thresholds{FailureThreshold: 2, SuccessThreshold: 2})
+	failed := errors.New("unreachable")
+
+	if state := tracker.State("bedrock"); !state.Healthy {
+		t.Error("providers should start healthy")
+	}
+	if state := tracker.Observe("bedrock", failed); !state.Healthy || state.ConsecutiveFailures != 1 {
+		t.Errorf("after 1 failure: %+v", state)
+	}
+	// A success resets the failure run
+	tracker.Observe("bedrock", nil)
+	tracker.Observe("bedrock", failed)
+	if state := tracker.Observe("bedrock", failed); state.Healthy || state.LastError != failed {
+		t.Errorf("after 2 consecutive failures: %+v", state)
+	}
+	if state := tracker.Observe("bedrock", nil); state.Healthy || state.ConsecutiveSuccesses != 1 {
+		t.Errorf("after 1 success: %+v", state)
+	}
+	if state := tracker.Observe("bedrock", nil); !state.Healthy {
+		t.Errorf("after 2 successes: %+v", state)
+	}
+
+	// Seeding ignores the thresholds
+	if state := tracker.Seed("vertex", failed); state.Healthy {
+		t.Errorf("seeded failure: %+v", state)
+	}
+}
+
+// TestHealthTransitionNotification tests that crossing the error rate
+// threshold, in each direction, is notified once
+func TestHealthTransitionNotification(t *testing.T) {
+	var mu sync.Mutex
+	var events []notify.Event
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event notify.Event
+		json.NewDecoder(r.Body).Decode(&event)
+		mu.Lock()
+		events = append(events, event)
+		mu.Unlock()
+	}))
+	defer server.Close()
+
+	notifier, err := notify.New(notify.Config{URLs: []string{server.URL}})
+	if err != nil {
+		t.Fatalf("notify.New() error = %v", err)
+	}
+	checker, _ := newTestChecker(Config{Window: time.Minute, MinSamples: 4, ErrorRateThreshold: 0.5})
+	checker.SetNotifier(notifier)
+
+	for i := 0; i < 6; i++ {
+		checker.RecordError("bedrock")
+	}
+	for i := 0; i < 12; i++ {
+		checker.RecordSuccess("bedrock")
+	}
+	notifier.Close()
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2: %+v", len(events), events)
+	}
+	if events[0].Type != notify.EventHealthTransition || events[0].Subject != "bedrock" ||
+		events[0].State != "unhealthy" || events[1].State != "healthy" {
+		t.Errorf("unexpected events: %+v", events)
+	}
+}
@@ -0,0 +1,132 @@
+package health
+
+import (
+	"sync"
+
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// Thresholds debounce active health check results, so a single failed or
+// successful check does not flip a provider's state
+type Thresholds struct {
+	// FailureThreshold is the number of consecutive failed checks that
+	// mark a healthy provider unhealthy
+	FailureThreshold int
+
+	// SuccessThreshold is the number of consecutive successful checks
+	// that mark an unhealthy provider healthy again
+	SuccessThreshold int
+}
+
+// DefaultThresholds returns thresholds that act on every check result
+func DefaultThresholds() Thresholds {
+	return Thresholds{FailureThreshold: 1, SuccessThreshold: 1}
+}
+
+// CheckState is the debounced health of one provider
+type CheckState struct {
+	Healthy              bool  `json:"healthy"`
+	ConsecutiveFailures  int   `json:"consecutive_failures"`
+	ConsecutiveSuccesses int   `json:"consecutive_successes"`
+	LastError            error `json:"-"` // Most recent failed check
+}
+
+// CheckTracker applies Thresholds to the health check results of each
+// provider. Providers start healthy.
+type CheckTracker struct {
+	thresholds Thresholds
+
+	mu     sync.Mutex
+	states map[string]*CheckState
+}
+
+// NewCheckTracker creates a tracker; zero thresholds use the defaults
+func NewCheckTracker(thresholds Thresholds) *CheckTracker {
+	defaults := DefaultThresholds()
+	if thresholds.FailureThreshold <= 0 {
+		thresholds.FailureThreshold = defaults.FailureThreshold
+	}
+	if thresholds.SuccessThreshold <= 0 {
+		thresholds.SuccessThreshold = defaults.SuccessThreshold
+	}
+	return &CheckTracker{
+		thresholds: thresholds,
+		states:     make(map[string]*CheckState),
+	}
+}
+
+// Observe records a check result (err is nil on success) and returns the
+// provider's state after it
+func (t *CheckTracker) Observe(provider string, err error) CheckState {
+	t.mu.Lock()
+	state := t.state(provider)
+	wasHealthy := state.Healthy
+	if err != nil {
+		state.ConsecutiveFailures++
+		state.ConsecutiveSuccesses = 0
+		state.LastError = err
+		if state.ConsecutiveFailures >= t.thresholds.FailureThreshold {
+			state.Healthy = false
+		}
+	} else {
+		state.ConsecutiveSuccesses++
+		state.ConsecutiveFailures = 0
+		if state.ConsecutiveSuccesses >= t.thresholds.SuccessThreshold {
+			state.Healthy = true
+		}
+	}
+	result := *state
+	t.mu.Unlock()
+
+	recordCheckState(provider, result, wasHealthy != result.Healthy)
+	return result
+}
+
+// Seed sets a provider's state from a single check, ignoring the
+// thresholds, such as for the result of a startup warm-up
+func (t *CheckTracker) Seed(provider string, err error) CheckState {
+	t.mu.Lock()
+	state := t.state(provider)
+	wasHealthy := state.Healthy
+	*state = CheckState{Healthy: err == nil, LastError: err}
+	if err != nil {
+		state.ConsecutiveFailures = 1
+	} else {
+		state.ConsecutiveSuccesses = 1
+	}
+	result := *state
+	t.mu.Unlock()
+
+	recordCheckState(provider, result, wasHealthy != result.Healthy)
+	return result
+}
+
+// State returns a provider's current state
+func (t *CheckTracker) State(provider string) CheckState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return *t.state(provider)
+}
+
+// state returns a provider's state, creating it; the caller must hold t.mu
+func (t *CheckTracker) state(provider string) *CheckState {
+	state, ok := t.states[provider]
+	if !ok {
+		state = &CheckState{Healthy: true}
+		t.states[provider] = state
+	}
+	return state
+}
+
+func recordCheckState(provider string, state CheckState, transitioned bool) {
+	metrics.HealthCheckConsecutive.WithLabelValues(provider, "failure").Set(float64(state.ConsecutiveFailures))
+	metrics.HealthCheckConsecutive.WithLabelValues(provider, "success").Set(float64(state.ConsecutiveSuccesses))
+	if !transitioned {
+		return
+	}
+	label := "healthy"
+	if !state.Healthy {
+		label = "unhealthy"
+	}
+	metrics.HealthCheckTransitions.WithLabelValues(provider, label).Inc()
+}
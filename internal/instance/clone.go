@@ -0,0 +1,146 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"maps"
+	"slices"
+)
+
+// Clone returns a deep copy of the config: its maps, slices and pointed-to
+// settings are copied, so a reload can change or re-validate the copy while
+// requests read the original. Compiled request templates and path regexps
+// are shared, as they are not modified after Validate.
+func (c *Config) Clone() *Config {
+	if c == nil {
+		return nil
+	}
+	clone := &Config{
+		Global:           c.Global.clone(),
+		Routing:          c.Routing.clone(),
+		Features:         cloneFeatures(c.Features),
+		DeprecatedRoutes: maps.Clone(c.DeprecatedRoutes),
+		files:            slices.Clone(c.files),
+	}
+	if c.Instances != nil {
+		clone.Instances = make(map[string]InstanceConfig, len(c.Instances))
+		for name, inst := range c.Instances {
+			clone.Instances[name] = inst.clone()
+		}
+	}
+	if c.endpoints != nil {
+		clone.endpoints = make([]endpointRoute, len(c.endpoints))
+		for i, route := range c.endpoints {
+			route.endpoint.Methods = slices.Clone(route.endpoint.Methods)
+			clone.endpoints[i] = route
+		}
+	}
+	return clone
+}
+
+func (g GlobalConfig) clone() GlobalConfig {
+	g.Authentication = cloneValues(g.Authentication)
+	return g
+}
+
+func (r RoutingConfig) clone() RoutingConfig {
+	r.Defaults = maps.Clone(r.Defaults)
+	r.ModelPins = maps.Clone(r.ModelPins)
+	return r
+}
+
+func (inst InstanceConfig) clone() InstanceConfig {
+	inst.Required = clonePointer(inst.Required)
+	inst.ReverseProxy = clonePointer(inst.ReverseProxy)
+	inst.Authentication.RoleChain = slices.Clone(inst.Authentication.RoleChain)
+	inst.Transformation = inst.Transformation.clone()
+	if inst.Endpoints != nil {
+		endpoints := make([]EndpointConfig, len(inst.Endpoints))
+		for i, endpoint := range inst.Endpoints {
+			endpoint.Methods = slices.Clone(endpoint.Methods)
+			endpoints[i] = endpoint
+		}
+		inst.Endpoints = endpoints
+	}
+	inst.RequestHeaders = maps.Clone(inst.RequestHeaders)
+	inst.ResponseHeaders = maps.Clone(inst.ResponseHeaders)
+	inst.ForwardHeaders = slices.Clone(inst.ForwardHeaders)
+	inst.PathRewrite = clonePointer(inst.PathRewrite)
+	inst.StripRequestFields = slices.Clone(inst.StripRequestFields)
+	inst.Metrics.Labels = maps.Clone(inst.Metrics.Labels)
+	return inst
+}
+
+func (t *TransformationConfig) clone() *TransformationConfig {
+	if t == nil {
+		return nil
+	}
+	clone := *t
+	clone.Options = cloneValues(t.Options)
+	clone.requestOptions.InjectMetadata = maps.Clone(t.requestOptions.InjectMetadata)
+	if t.ResponseFields != nil {
+		clone.ResponseFields = &ResponseFieldFilter{
+			Remove: slices.Clone(t.ResponseFields.Remove),
+			Add:    cloneValues(t.ResponseFields.Add),
+			add:    maps.Clone(t.ResponseFields.add),
+		}
+	}
+	return &clone
+}
+
+// cloneFeatures copies feature flags with their overrides
+func cloneFeatures(features map[string]FeatureConfig) map[string]FeatureConfig {
+	if features == nil {
+		return nil
+	}
+	clone := make(map[string]FeatureConfig, len(features))
+	for name, feature := range features {
+		feature.Instances = maps.Clone(feature.Instances)
+		feature.Keys = maps.Clone(feature.Keys)
+		clone[name] = feature
+	}
+	return clone
+}
+
+// cloneValues deep-copies a map decoded from YAML
+func cloneValues(values map[string]interface{}) map[string]interface{} {
+	if values == nil {
+		return nil
+	}
+	clone := make(map[string]interface{}, len(values))
+	for key, value := range values {
+		clone[key] = cloneValue(value)
+	}
+	return clone
+}
+
+// cloneValue deep-copies a value decoded from YAML; scalars are returned
+// as they are
+func cloneValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return cloneValues(v)
+	case []interface{}:
+		clone := make([]interface{}, len(v))
+		for i, item := range v {
+			clone[i] = cloneValue(item)
+		}
+		return clone
+	case []string:
+		return slices.Clone(v)
+	case map[string]string:
+		return maps.Clone(v)
+	default:
+		return v
+	}
+}
+
+// clonePointer returns a pointer to a copy of *p, or nil
+func clonePointer[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	clone := *p
+	return &clone
+}
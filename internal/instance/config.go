@@ -5,10 +5,12 @@ package instance
 
 import (
 	"fmt"
-	"os"
+	"sort"
 	"strings"
+	"text/template"
+	"time"
 
-	"gopkg.in/yaml.v3"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
 )
 
 // Config represents the provider instances configuration
@@ -17,6 +19,23 @@ type Config struct {
 	Instances map[string]InstanceConfig  `yaml:"instances"`
 	Routing   RoutingConfig              `yaml:"routing"`
 	Features  map[string]FeatureConfig   `yaml:"features"`
+
+	// DeprecatedRoutes marks gateway route prefixes (e.g. /v1/bedrock) as
+	// deprecated
+	DeprecatedRoutes map[string]DeprecationConfig `yaml:"deprecated_routes,omitempty"`
+
+	// endpoints is the path matching table built by Validate
+	endpoints []endpointRoute
+
+	// files are the files LoadConfig merged
+	files []string
+}
+
+// endpointRoute maps an endpoint path prefix to the instance that serves it
+type endpointRoute struct {
+	path     string
+	instance string
+	endpoint EndpointConfig
 }
 
 // GlobalConfig represents global settings
@@ -26,37 +45,85 @@ type GlobalConfig struct {
 		CaptureRequestBody  bool `yaml:"capture_request_body"`
 		CaptureResponseBody bool `yaml:"capture_response_body"`
 	} `yaml:"metrics"`
-	DefaultTimeout   string                 `yaml:"default_timeout"`
+	DefaultTimeout   string                 `yaml:"default_timeout"` // Deprecated: request_timeout
 	Authentication   map[string]interface{} `yaml:"authentication"`
+
+	// OutputTokenLimit applies to instances without their own limit
+	OutputTokenLimit `yaml:",inline"`
+
+	// HTTPTimeouts apply to instances without their own timeouts
+	HTTPTimeouts `yaml:",inline"`
 }
 
 // InstanceConfig represents a provider instance configuration
 type InstanceConfig struct {
-	Type           string                 `yaml:"type"`
-	Mode           string                 `yaml:"mode"` // transparent or protocol
-	Protocol       string                 `yaml:"protocol,omitempty"` // openai, anthropic, etc.
-	Description    string                 `yaml:"description"`
-	Region         string                 `yaml:"region,omitempty"`
-	Endpoint       string                 `yaml:"endpoint,omitempty"`
-	BaseURL        string                 `yaml:"base_url,omitempty"`
-	ProjectID      string                 `yaml:"project_id,omitempty"`
-	Location       string                 `yaml:"location,omitempty"`
-	APIVersion     string                 `yaml:"api_version,omitempty"`
-	CompartmentID  string                 `yaml:"compartment_id,omitempty"`
-	Authentication AuthenticationConfig   `yaml:"authentication"`
-	Transformation *TransformationConfig  `yaml:"transformation,omitempty"`
-	Endpoints      []EndpointConfig       `yaml:"endpoints"`
-	Metrics        MetricsConfig          `yaml:"metrics"`
+	Type             string                `yaml:"type"`
+	Mode             string                `yaml:"mode"`               // transparent or protocol
+	Protocol         string                `yaml:"protocol,omitempty"` // openai, anthropic, etc.
+	Description      string                `yaml:"description"`
+	Required         *bool                 `yaml:"required,omitempty"`          // Readiness fails when unhealthy (default: used as a routing default)
+	FallbackProvider string                `yaml:"fallback_provider,omitempty"` // Instance to retry on 503 (one level only)
+	Region           string                `yaml:"region,omitempty"`
+	Endpoint         string                `yaml:"endpoint,omitempty"`
+	BaseURL          string                `yaml:"base_url,omitempty"`
+	ProjectID        string                `yaml:"project_id,omitempty"`
+	Location         string                `yaml:"location,omitempty"`
+	APIVersion       string                `yaml:"api_version,omitempty"`
+	CompartmentID    string                `yaml:"compartment_id,omitempty"`
+	KnowledgeBaseID  string                `yaml:"knowledge_base_id,omitempty"` // bedrock_kb
+	ModelARN         string                `yaml:"model_arn,omitempty"`         // bedrock_kb generation model
+	MaxConcurrency   int                   `yaml:"max_concurrency,omitempty"`   // In-flight request limit (0 = unlimited)
+	QueueTimeout     string                `yaml:"queue_timeout,omitempty"`     // Wait for a slot before 503 (default: no wait)
+	Authentication   AuthenticationConfig  `yaml:"authentication"`
+	Transformation   *TransformationConfig `yaml:"transformation,omitempty"`
+	Endpoints        []EndpointConfig      `yaml:"endpoints"`
+	RequestHeaders   map[string]string     `yaml:"request_headers,omitempty"`   // Static headers sent to the provider
+	ResponseHeaders  map[string]string     `yaml:"response_headers,omitempty"`  // Static headers returned to the client
+	ForwardHeaders   []string              `yaml:"forward_headers,omitempty"`   // Protocol: client headers forwarded to the provider ("X-B3-*" matches a prefix)
+	PathRewrite      *PathRewrite          `yaml:"path_rewrite,omitempty"`      // Applied to the provider path
+	ReverseProxy     *bool                 `yaml:"reverse_proxy,omitempty"`     // Transparent: stream through httputil.ReverseProxy when the provider supports it (default true)
+	HealthCheckPath  string                `yaml:"health_check_path,omitempty"` // generic_http: GET path that answers 2xx when healthy
+	Timeout          string                `yaml:"timeout,omitempty"`           // Deprecated: request_timeout
+	ImageLimits      translator.ImageLimits `yaml:"image_limits,omitempty"`    // Protocol: downscale inline images over these limits
+	StripRequestFields []string            `yaml:"strip_request_fields,omitempty"` // Top-level JSON request fields removed before dispatch
+	Metrics          MetricsConfig         `yaml:"metrics"`
+
+	// OutputTokenLimit caps max_tokens; Validate fills it from the global
+	// limit when unset
+	OutputTokenLimit `yaml:",inline"`
+
+	// HTTPTimeouts bound provider requests; Validate fills unset ones from
+	// the global timeouts
+	HTTPTimeouts `yaml:",inline"`
 }
 
 // AuthenticationConfig represents authentication configuration
 type AuthenticationConfig struct {
-	Type    string `yaml:"type"` // aws_sigv4, api_key, bearer_token, gcp_oauth2
-	Service string `yaml:"service,omitempty"` // For AWS
-	Region  string `yaml:"region,omitempty"` // For AWS
-	Header  string `yaml:"header,omitempty"` // For API key
-	Key     string `yaml:"key,omitempty"`
-	Token   string `yaml:"token,omitempty"`
+	Type     string `yaml:"type"`              // aws_sigv4, aws_sigv4_agent_runtime, api_key, bearer_token, basic, gcp_oauth2
+	Service  string `yaml:"service,omitempty"` // For AWS
+	Region   string `yaml:"region,omitempty"`  // For AWS
+	Header   string `yaml:"header,omitempty"`  // For API key
+	Key      string `yaml:"key,omitempty"`
+	Token    string `yaml:"token,omitempty"`
+	Username string `yaml:"username,omitempty"` // For basic
+	Password string `yaml:"password,omitempty"` // For basic
+
+	// PassThroughAuth forwards the client's Authorization header verbatim
+	// instead of the instance credentials (e.g. self-hosted vLLM or Ollama).
+	// Requests are still translated; not valid with aws_sigv4.
+	PassThroughAuth bool `yaml:"pass_through_auth,omitempty"`
+
+	// AssumeRoleARN is assumed by bedrock instances to call Bedrock from
+	// another account, after the roles of RoleChain in order. ExternalID
+	// is sent with each AssumeRole call.
+	AssumeRoleARN string   `yaml:"assume_role_arn,omitempty"`
+	RoleChain     []string `yaml:"role_chain,omitempty"`
+	ExternalID    string   `yaml:"external_id,omitempty"`
+
+	// UnsignedPayload signs transparent SigV4 requests with UNSIGNED-PAYLOAD
+	// so their bodies are streamed rather than buffered to be hashed. Only
+	// services that accept it (S3 over HTTPS) should enable it.
+	UnsignedPayload bool `yaml:"unsigned_payload,omitempty"`
 }
 
 // TransformationConfig represents transformation configuration
@@ -66,12 +133,51 @@ type TransformationConfig struct {
 	ResponseFrom string                 `yaml:"response_from"`
 	ResponseTo   string                 `yaml:"response_to"`
 	Options      map[string]interface{} `yaml:"options,omitempty"`
+
+	// RequestTemplate is a text/template rendering the provider request
+	// body from the OpenAI request, in place of request_to translation
+	RequestTemplate string `yaml:"request_template,omitempty"`
+
+	// ResponseFields removes and adds top-level fields of non-streaming
+	// responses after they are translated
+	ResponseFields *ResponseFieldFilter `yaml:"response_fields,omitempty"`
+
+	// requestOptions is Options decoded by Validate
+	requestOptions translator.RequestOptions
+
+	// requestTemplate is RequestTemplate compiled by Validate
+	requestTemplate *template.Template
 }
 
 // EndpointConfig represents an endpoint configuration
 type EndpointConfig struct {
 	Path    string   `yaml:"path"`
-	Methods []string `yaml:"methods"`
+	Methods []string `yaml:"methods"` // empty means all methods
+}
+
+// AllowsMethod reports whether the endpoint accepts an HTTP method
+func (e *EndpointConfig) AllowsMethod(method string) bool {
+	if len(e.Methods) == 0 {
+		return true
+	}
+	for _, m := range e.Methods {
+		if strings.EqualFold(m, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// MethodNotAllowedError is returned when a request path matches an instance
+// endpoint that does not accept the request method
+type MethodNotAllowedError struct {
+	Instance string
+	Method   string
+	Allowed  []string
+}
+
+func (e *MethodNotAllowedError) Error() string {
+	return fmt.Sprintf("method %s not allowed for instance %s (allowed: %s)", e.Method, e.Instance, strings.Join(e.Allowed, ", "))
 }
 
 // MetricsConfig represents metrics configuration
@@ -82,8 +188,9 @@ type MetricsConfig struct {
 
 // RoutingConfig represents routing configuration
 type RoutingConfig struct {
-	Defaults   map[string]string `yaml:"defaults"`
-	PathBased  struct {
+	Defaults  map[string]string `yaml:"defaults"`
+	ModelPins map[string]string `yaml:"model_pins,omitempty"` // model -> instance name; overrides defaults
+	PathBased struct {
 		Enabled bool `yaml:"enabled"`
 	} `yaml:"path_based"`
 	Fallback struct {
@@ -92,41 +199,372 @@ type RoutingConfig struct {
 	} `yaml:"fallback"`
 }
 
-// FeatureConfig represents a feature flag
-type FeatureConfig struct {
-	Enabled     bool   `yaml:"enabled"`
-	Description string `yaml:"description"`
+// LoadConfig loads provider instances configuration from YAML. The location
+// is a file, a directory of .yaml/.yml files or a comma-separated list of
+// either; files are merged in order together with the files they include.
+// Each call re-reads every file, so a reload sees changes to any of them.
+func LoadConfig(location string) (*Config, error) {
+	paths, err := configPaths(location)
+	if err != nil {
+		return nil, err
+	}
+	merger := newConfigMerger()
+	for _, path := range paths {
+		if err := merger.mergeFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var config Config
+	if merger.root != nil {
+		if err := merger.root.Decode(&config); err != nil {
+			return nil, fmt.Errorf("failed to parse config: %w", err)
+		}
+	}
+	config.files = merger.files
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// Files returns the files the config was loaded from, in merge order
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// Validate checks cross-field and cross-instance constraints
+func (c *Config) Validate() error {
+	if err := c.Global.OutputTokenLimit.validate(); err != nil {
+		return fmt.Errorf("global: %w", err)
+	}
+	if c.Global.RequestTimeout == "" {
+		c.Global.RequestTimeout = c.Global.DefaultTimeout
+	}
+	if _, _, err := c.Global.HTTPTimeouts.Durations(); err != nil {
+		return fmt.Errorf("global: %w", err)
+	}
+	for name, inst := range c.Instances {
+		if inst.Authentication.PassThroughAuth && inst.Authentication.IsSigV4() {
+			return fmt.Errorf("instance %s: pass_through_auth cannot be combined with %s authentication", name, inst.Authentication.Type)
+		}
+		if inst.Authentication.UnsignedPayload && !inst.Authentication.IsSigV4() {
+			return fmt.Errorf("instance %s: unsigned_payload requires aws_sigv4 authentication", name)
+		}
+		if err := inst.validateKnowledgeBase(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.validateConcurrency(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.validateGenericHTTP(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.validateGemini(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.validateHeaderRules(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.Transformation.decodeOptions(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.Transformation.compileTemplate(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.Transformation.compileResponseFields(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.OutputTokenLimit.validate(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.ImageLimits.Validate(); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if err := inst.applyTimeouts(c.Global.HTTPTimeouts); err != nil {
+			return fmt.Errorf("instance %s: %w", name, err)
+		}
+		if inst.MaxOutputTokens == 0 && c.Global.MaxOutputTokens > 0 {
+			inst.OutputTokenLimit = c.Global.OutputTokenLimit
+		}
+		c.Instances[name] = inst
+	}
+	for route, deprecation := range c.DeprecatedRoutes {
+		if err := deprecation.validate(); err != nil {
+			return fmt.Errorf("deprecated_routes %s: %w", route, err)
+		}
+	}
+	if err := c.validateFeatures(c.Features); err != nil {
+		return err
+	}
+	if err := c.ValidateModelPins(); err != nil {
+		return err
+	}
+	if err := c.ValidateFallbacks(); err != nil {
+		return err
+	}
+	if err := c.ValidateEndpoints(); err != nil {
+		return err
+	}
+
+	c.endpoints = c.buildEndpointTable()
+	return nil
+}
+
+// ValidateEndpoints checks that no two instances claim the same endpoint path
+func (c *Config) ValidateEndpoints() error {
+	owners := make(map[string]string)
+	for _, route := range c.buildEndpointTable() {
+		if owner, ok := owners[route.path]; ok && owner != route.instance {
+			return fmt.Errorf("endpoint path %q is claimed by both instance %s and instance %s", route.path, owner, route.instance)
+		}
+		owners[route.path] = route.instance
+	}
+	return nil
+}
+
+// buildEndpointTable returns all endpoint paths ordered for matching: longest
+// path first, ties broken by instance name so matching is deterministic
+func (c *Config) buildEndpointTable() []endpointRoute {
+	var routes []endpointRoute
+	for name, instance := range c.Instances {
+		for _, endpoint := range instance.Endpoints {
+			routes = append(routes, endpointRoute{path: endpoint.Path, instance: name, endpoint: endpoint})
+		}
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if len(routes[i].path) != len(routes[j].path) {
+			return len(routes[i].path) > len(routes[j].path)
+		}
+		if routes[i].path != routes[j].path {
+			return routes[i].path < routes[j].path
+		}
+		return routes[i].instance < routes[j].instance
+	})
+	return routes
+}
+
+// validateKnowledgeBase checks bedrock_kb instances: they must sign for the
+// agent runtime, and protocol mode needs a knowledge base and model to target
+func (i *InstanceConfig) validateKnowledgeBase() error {
+	if i.Type != "bedrock_kb" {
+		return nil
+	}
+	if i.Authentication.Type != "aws_sigv4_agent_runtime" {
+		return fmt.Errorf("bedrock_kb requires aws_sigv4_agent_runtime authentication, got %q", i.Authentication.Type)
+	}
+	if i.Mode == "protocol" && (i.KnowledgeBaseID == "" || i.ModelARN == "") {
+		return fmt.Errorf("bedrock_kb in protocol mode requires knowledge_base_id and model_arn")
+	}
+	return nil
+}
+
+// validateGemini checks that the Gemini protocol is only served by Vertex AI,
+// whose generateContent API it exposes
+func (i *InstanceConfig) validateGemini() error {
+	if i.Mode == "protocol" && i.Protocol == "gemini" && i.Type != "vertex" {
+		return fmt.Errorf("protocol gemini requires type vertex, got %q", i.Type)
+	}
+	return nil
+}
+
+// validateGenericHTTP checks the settings a generic_http instance is built from
+func (i *InstanceConfig) validateGenericHTTP() error {
+	if i.Type != "generic_http" {
+		return nil
+	}
+	if i.BaseURL == "" {
+		return fmt.Errorf("generic_http requires base_url")
+	}
+	switch i.Authentication.Type {
+	case "", "none", "api_key", "bearer_token":
+	case "basic":
+		if i.Authentication.Username == "" {
+			return fmt.Errorf("basic authentication requires username")
+		}
+	default:
+		return fmt.Errorf("generic_http does not support %q authentication", i.Authentication.Type)
+	}
+	if i.HealthCheckPath != "" && !strings.HasPrefix(i.HealthCheckPath, "/") {
+		return fmt.Errorf("health_check_path must start with /")
+	}
+	return nil
 }
 
-// LoadConfig loads provider instances configuration from YAML file
-func LoadConfig(path string) (*Config, error) {
-	data, err := os.ReadFile(path)
+// TimeoutDuration returns the parsed timeout, zero if unset
+func (i *InstanceConfig) TimeoutDuration() (time.Duration, error) {
+	if i.Timeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(i.Timeout)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read config file: %w", err)
+		return 0, fmt.Errorf("invalid timeout %q: %w", i.Timeout, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("timeout cannot be negative")
 	}
+	return d, nil
+}
 
-	// Expand environment variables
-	expanded := os.ExpandEnv(string(data))
+// validateConcurrency checks max_concurrency and queue_timeout
+func (i *InstanceConfig) validateConcurrency() error {
+	if i.MaxConcurrency < 0 {
+		return fmt.Errorf("max_concurrency cannot be negative")
+	}
+	if i.QueueTimeout != "" && i.MaxConcurrency == 0 {
+		return fmt.Errorf("queue_timeout requires max_concurrency")
+	}
+	_, err := i.QueueTimeoutDuration()
+	return err
+}
 
-	var config Config
-	if err := yaml.Unmarshal([]byte(expanded), &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
+// QueueTimeoutDuration returns the parsed queue_timeout, zero if unset
+func (i *InstanceConfig) QueueTimeoutDuration() (time.Duration, error) {
+	if i.QueueTimeout == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(i.QueueTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid queue_timeout %q: %w", i.QueueTimeout, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("queue_timeout cannot be negative")
 	}
+	return d, nil
+}
 
-	return &config, nil
+// IsSigV4 reports whether the authentication type uses AWS Signature V4
+func (a *AuthenticationConfig) IsSigV4() bool {
+	return a.Type == "aws_sigv4" || a.Type == "aws_sigv4_agent_runtime"
+}
+
+// ValidateModelPins checks that every routing.model_pins entry names a known instance
+func (c *Config) ValidateModelPins() error {
+	for model, name := range c.Routing.ModelPins {
+		if model == "" {
+			return fmt.Errorf("routing.model_pins: model name cannot be empty")
+		}
+		if _, ok := c.Instances[name]; !ok {
+			return fmt.Errorf("routing.model_pins: model %q pinned to unknown instance %q", model, name)
+		}
+	}
+	return nil
+}
+
+// ValidateFallbacks checks fallback_provider references: the fallback must
+// exist, speak the same protocol, and not itself have a fallback.
+func (c *Config) ValidateFallbacks() error {
+	for name, inst := range c.Instances {
+		if inst.FallbackProvider == "" {
+			continue
+		}
+		if inst.FallbackProvider == name {
+			return fmt.Errorf("instance %s: fallback_provider cannot reference itself", name)
+		}
+		fallback, ok := c.Instances[inst.FallbackProvider]
+		if !ok {
+			return fmt.Errorf("instance %s: fallback_provider %q not found", name, inst.FallbackProvider)
+		}
+		if !fallback.SupportsProtocol(inst.Protocol) {
+			return fmt.Errorf("instance %s: fallback_provider %q does not support protocol %q", name, inst.FallbackProvider, inst.Protocol)
+		}
+		if fallback.FallbackProvider != "" {
+			return fmt.Errorf("instance %s: fallback_provider %q has its own fallback; only one level of fallback is supported", name, inst.FallbackProvider)
+		}
+	}
+	return nil
+}
+
+// SupportsProtocol reports whether a protocol-mode instance accepts the given client protocol
+func (i *InstanceConfig) SupportsProtocol(protocol string) bool {
+	return i.Mode == "protocol" && i.Protocol == protocol
 }
 
-// GetInstanceByPath returns the instance configuration for a given request path
+// GetInstanceByPath returns the instance configuration for a given request
+// path. The instance with the longest matching endpoint path wins.
 func (c *Config) GetInstanceByPath(path string) (*InstanceConfig, string, error) {
-	for name, instance := range c.Instances {
+	route, ok := c.matchEndpoint(path)
+	if !ok {
+		return nil, "", fmt.Errorf("no instance found for path: %s", path)
+	}
+
+	instance := c.Instances[route.instance]
+	return &instance, route.instance, nil
+}
+
+// GetInstanceForRequest is GetInstanceByPath that also enforces the matched
+// endpoint's methods, returning a *MethodNotAllowedError on a mismatch
+func (c *Config) GetInstanceForRequest(method, path string) (*InstanceConfig, string, error) {
+	route, ok := c.matchEndpoint(path)
+	if !ok {
+		return nil, "", fmt.Errorf("no instance found for path: %s", path)
+	}
+	if !route.endpoint.AllowsMethod(method) {
+		return nil, route.instance, &MethodNotAllowedError{
+			Instance: route.instance,
+			Method:   method,
+			Allowed:  normalizeMethods(route.endpoint.Methods),
+		}
+	}
+
+	instance := c.Instances[route.instance]
+	return &instance, route.instance, nil
+}
+
+// matchEndpoint returns the endpoint with the longest path prefixing path
+func (c *Config) matchEndpoint(path string) (endpointRoute, bool) {
+	routes := c.endpoints
+	if routes == nil {
+		// Config was not loaded through LoadConfig/Validate
+		routes = c.buildEndpointTable()
+	}
+
+	for _, route := range routes {
+		if strings.HasPrefix(path, route.path) {
+			return route, true
+		}
+	}
+	return endpointRoute{}, false
+}
+
+// EndpointMethods returns the methods accepted by endpoints of mode instances
+// whose path starts with prefix, for route registration. A nil result means
+// at least one matching endpoint accepts all methods.
+func (c *Config) EndpointMethods(mode, prefix string) []string {
+	var methods []string
+	for _, instance := range c.Instances {
+		if instance.Mode != mode {
+			continue
+		}
 		for _, endpoint := range instance.Endpoints {
-			if strings.HasPrefix(path, endpoint.Path) {
-				return &instance, name, nil
+			if !strings.HasPrefix(endpoint.Path, prefix) {
+				continue
+			}
+			if len(endpoint.Methods) == 0 {
+				return nil
 			}
+			methods = append(methods, endpoint.Methods...)
 		}
 	}
+	return normalizeMethods(methods)
+}
 
-	return nil, "", fmt.Errorf("no instance found for path: %s", path)
+// normalizeMethods upper-cases, de-duplicates and sorts HTTP methods
+func normalizeMethods(methods []string) []string {
+	seen := make(map[string]bool, len(methods))
+	normalized := make([]string, 0, len(methods))
+	for _, m := range methods {
+		m = strings.ToUpper(m)
+		if !seen[m] {
+			seen[m] = true
+			normalized = append(normalized, m)
+		}
+	}
+	sort.Strings(normalized)
+	return normalized
 }
 
 // GetInstanceByName returns the instance configuration by name
@@ -153,6 +591,66 @@ func (c *Config) GetDefaultInstance(providerType string) (*InstanceConfig, strin
 	return instance, defaultName, nil
 }
 
+// GetPinnedInstance returns the instance a model is pinned to via routing.model_pins
+func (c *Config) GetPinnedInstance(model string) (*InstanceConfig, string, error) {
+	name, ok := c.Routing.ModelPins[model]
+	if !ok {
+		return nil, "", fmt.Errorf("no instance pinned for model: %s", model)
+	}
+
+	instance, err := c.GetInstanceByName(name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return instance, name, nil
+}
+
+// IsInstanceRequired reports whether an instance's provider health gates readiness.
+// An explicit `required` setting wins; otherwise an instance is required only
+// when it is a routing default for its provider type.
+func (c *Config) IsInstanceRequired(name string) bool {
+	if instance, ok := c.Instances[name]; ok && instance.Required != nil {
+		return *instance.Required
+	}
+
+	for _, defaultName := range c.Routing.Defaults {
+		if defaultName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RequiredProviderTypes returns the provider types backing required instances
+func (c *Config) RequiredProviderTypes() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for name, instance := range c.Instances {
+		if seen[instance.Type] || !c.IsInstanceRequired(name) {
+			continue
+		}
+		seen[instance.Type] = true
+		types = append(types, instance.Type)
+	}
+	return types
+}
+
+// ProviderTypes returns the distinct provider types of all instances, sorted
+func (c *Config) ProviderTypes() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, instance := range c.Instances {
+		if !seen[instance.Type] {
+			seen[instance.Type] = true
+			types = append(types, instance.Type)
+		}
+	}
+	sort.Strings(types)
+	return types
+}
+
 // ListInstances returns all instance names
 func (c *Config) ListInstances() []string {
 	names := make([]string, 0, len(c.Instances))
@@ -183,12 +681,3 @@ func (c *Config) ListInstancesByType(providerType string) map[string]InstanceCon
 	}
 	return instances
 }
-
-// IsFeatureEnabled checks if a feature is enabled
-func (c *Config) IsFeatureEnabled(featureName string) bool {
-	feature, ok := c.Features[featureName]
-	if !ok {
-		return false
-	}
-	return feature.Enabled
-}
@@ -0,0 +1,871 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+func newPathTestConfig() *Config {
+	return &Config{
+		Instances: map[string]InstanceConfig{
+			"bedrock":    {Endpoints: []EndpointConfig{{Path: "/openai/bedrock"}}},
+			"bedrock_eu": {Endpoints: []EndpointConfig{{Path: "/openai/bedrock_eu"}}},
+			"azure":      {Endpoints: []EndpointConfig{{Path: "/openai/azure"}, {Path: "/openai/azure/deployments/gpt4"}}},
+			"catchall":   {Endpoints: []EndpointConfig{{Path: "/openai"}}},
+		},
+	}
+}
+
+func TestGetInstanceByPathLongestPrefix(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/openai/bedrock/v1/chat/completions", "bedrock"},
+		{"/openai/bedrock_eu/v1/chat/completions", "bedrock_eu"},
+		{"/openai/azure/deployments/gpt4/chat/completions", "azure"},
+		{"/openai/other/v1/chat/completions", "catchall"},
+	}
+
+	config := newPathTestConfig()
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	// Map iteration order varies between runs; repeat to catch nondeterminism
+	for i := 0; i < 50; i++ {
+		for _, tt := range tests {
+			_, name, err := config.GetInstanceByPath(tt.path)
+			if err != nil {
+				t.Fatalf("GetInstanceByPath(%q) error = %v", tt.path, err)
+			}
+			if name != tt.want {
+				t.Fatalf("GetInstanceByPath(%q) = %s, want %s", tt.path, name, tt.want)
+			}
+		}
+	}
+
+	if _, _, err := config.GetInstanceByPath("/anthropic/claude"); err == nil {
+		t.Error("GetInstanceByPath() for unmatched path succeeded, want error")
+	}
+}
+
+func TestGetInstanceByPathWithoutValidate(t *testing.T) {
+	config := newPathTestConfig()
+	for i := 0; i < 50; i++ {
+		if _, name, _ := config.GetInstanceByPath("/openai/bedrock_eu/v1/chat/completions"); name != "bedrock_eu" {
+			t.Fatalf("GetInstanceByPath() = %s, want bedrock_eu", name)
+		}
+	}
+}
+
+func TestGetInstanceByPathTieBreak(t *testing.T) {
+	// Duplicate paths fail validation, but an unvalidated config must still
+	// resolve them the same way every time
+	config := &Config{Instances: map[string]InstanceConfig{}}
+	for i := 0; i < 10; i++ {
+		config.Instances[fmt.Sprintf("instance_%d", i)] = InstanceConfig{
+			Endpoints: []EndpointConfig{{Path: "/openai/shared"}},
+		}
+	}
+
+	for i := 0; i < 50; i++ {
+		if _, name, _ := config.GetInstanceByPath("/openai/shared/v1"); name != "instance_0" {
+			t.Fatalf("GetInstanceByPath() = %s, want instance_0", name)
+		}
+	}
+}
+
+func TestValidateEndpoints(t *testing.T) {
+	config := newPathTestConfig()
+	config.Instances["bedrock_copy"] = InstanceConfig{Endpoints: []EndpointConfig{{Path: "/openai/bedrock"}}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() accepted two instances with the same endpoint path")
+	}
+
+	config = newPathTestConfig()
+	config.Instances["bedrock"] = InstanceConfig{Endpoints: []EndpointConfig{{Path: "/openai/bedrock"}, {Path: "/openai/bedrock"}}}
+	if err := config.Validate(); err != nil {
+		t.Errorf("Validate() rejected a path repeated within one instance: %v", err)
+	}
+}
+
+func TestGetInstanceForRequestMethods(t *testing.T) {
+	config := &Config{
+		Instances: map[string]InstanceConfig{
+			"bedrock": {Mode: "transparent", Endpoints: []EndpointConfig{{Path: "/transparent/bedrock", Methods: []string{"POST"}}}},
+			"any":     {Mode: "transparent", Endpoints: []EndpointConfig{{Path: "/transparent/any"}}},
+		},
+	}
+
+	if _, _, err := config.GetInstanceForRequest("POST", "/transparent/bedrock/model/invoke"); err != nil {
+		t.Errorf("POST: error = %v", err)
+	}
+
+	_, name, err := config.GetInstanceForRequest("DELETE", "/transparent/bedrock/model/invoke")
+	methodErr, ok := err.(*MethodNotAllowedError)
+	if !ok {
+		t.Fatalf("DELETE: error = %v, want *MethodNotAllowedError", err)
+	}
+	if name != "bedrock" || len(methodErr.Allowed) != 1 || methodErr.Allowed[0] != "POST" {
+		t.Errorf("DELETE: instance %q, allowed %v; want bedrock, [POST]", name, methodErr.Allowed)
+	}
+
+	if _, _, err := config.GetInstanceForRequest("DELETE", "/transparent/any/x"); err != nil {
+		t.Errorf("empty methods should allow all, got %v", err)
+	}
+}
+
+func TestEndpointMethods(t *testing.T) {
+	config := &Config{
+		Instances: map[string]InstanceConfig{
+			"a":     {Mode: "protocol", Endpoints: []EndpointConfig{{Path: "/openai/a", Methods: []string{"POST"}}}},
+			"b":     {Mode: "protocol", Endpoints: []EndpointConfig{{Path: "/openai/b", Methods: []string{"get", "POST"}}}},
+			"c":     {Mode: "protocol", Endpoints: []EndpointConfig{{Path: "/anthropic/c"}}},
+			"trans": {Mode: "transparent", Endpoints: []EndpointConfig{{Path: "/openai/trans", Methods: []string{"PUT"}}}},
+		},
+	}
+
+	if got := config.EndpointMethods("protocol", "/openai/"); fmt.Sprint(got) != "[GET POST]" {
+		t.Errorf("EndpointMethods(/openai/) = %v, want [GET POST]", got)
+	}
+	if got := config.EndpointMethods("protocol", "/anthropic/"); got != nil {
+		t.Errorf("EndpointMethods(/anthropic/) = %v, want nil (all methods)", got)
+	}
+	if got := config.EndpointMethods("protocol", "/gemini/"); got == nil || len(got) != 0 {
+		t.Errorf("EndpointMethods(/gemini/) = %#v, want empty", got)
+	}
+}
+
+func TestValidateConcurrency(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance InstanceConfig
+		wantErr  bool
+	}{
+		{"unset", InstanceConfig{}, false},
+		{"limit only", InstanceConfig{MaxConcurrency: 10}, false},
+		{"limit and timeout", InstanceConfig{MaxConcurrency: 10, QueueTimeout: "5s"}, false},
+		{"negative limit", InstanceConfig{MaxConcurrency: -1}, true},
+		{"timeout without limit", InstanceConfig{QueueTimeout: "5s"}, true},
+		{"invalid timeout", InstanceConfig{MaxConcurrency: 10, QueueTimeout: "soon"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Instances: map[string]InstanceConfig{"test": tt.instance}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPathRewriteApply(t *testing.T) {
+	tests := []struct {
+		name    string
+		rewrite *PathRewrite
+		path    string
+		want    string
+	}{
+		{"nil", nil, "/v1/items", "/v1/items"},
+		{"strip prefix", &PathRewrite{StripPrefix: "/v1"}, "/v1/items", "/items"},
+		{"strip whole path", &PathRewrite{StripPrefix: "/v1"}, "/v1", "/"},
+		{"strip non-matching", &PathRewrite{StripPrefix: "/v2"}, "/v1/items", "/v1/items"},
+		{"add prefix", &PathRewrite{AddPrefix: "/api/"}, "/items", "/api/items"},
+		{"regex capture group", &PathRewrite{Regex: `^/v1/(.*)$`, Replacement: "/api/$1"}, "/v1/items/42", "/api/items/42"},
+		{"regex named groups", &PathRewrite{Regex: `^/models/(?P<model>[^/]+)/(?P<action>\w+)$`, Replacement: "/${action}/${model}"}, "/models/claude/invoke", "/invoke/claude"},
+		{"regex no match", &PathRewrite{Regex: `^/v2/(.*)$`, Replacement: "/api/$1"}, "/v1/items", "/v1/items"},
+		{"strip, regex, add", &PathRewrite{StripPrefix: "/gw", Regex: `^/deployments/([^/]+)/`, Replacement: "/d/${1}/", AddPrefix: "/openai"}, "/gw/deployments/gpt4/chat", "/openai/d/gpt4/chat"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.rewrite != nil {
+				if err := tt.rewrite.compile(); err != nil {
+					t.Fatalf("compile() error = %v", err)
+				}
+			}
+			if got := tt.rewrite.Apply(tt.path); got != tt.want {
+				t.Errorf("Apply(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateHeaderRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		instance InstanceConfig
+		wantErr  bool
+	}{
+		{"custom headers", InstanceConfig{RequestHeaders: map[string]string{"X-Tenant": "search"}, ResponseHeaders: map[string]string{"X-Served-By": "gateway"}}, false},
+		{"authorization", InstanceConfig{RequestHeaders: map[string]string{"authorization": "Bearer x"}}, true},
+		{"api key", InstanceConfig{RequestHeaders: map[string]string{"X-API-Key": "x"}}, true},
+		{"configured auth header", InstanceConfig{Authentication: AuthenticationConfig{Header: "X-Custom-Auth"}, RequestHeaders: map[string]string{"x-custom-auth": "x"}}, true},
+		{"content length", InstanceConfig{ResponseHeaders: map[string]string{"Content-Length": "0"}}, true},
+		{"request id", InstanceConfig{ResponseHeaders: map[string]string{"X-Request-ID": "fixed"}}, true},
+		{"valid regex", InstanceConfig{PathRewrite: &PathRewrite{Regex: `^/v1/(.*)$`, Replacement: "/$1"}}, false},
+		{"invalid regex", InstanceConfig{PathRewrite: &PathRewrite{Regex: `^/v1/(`}}, true},
+		{"replacement without regex", InstanceConfig{PathRewrite: &PathRewrite{Replacement: "/$1"}}, true},
+		{"forward headers", InstanceConfig{ForwardHeaders: []string{"anthropic-beta", "traceparent", "X-B3-*"}}, false},
+		{"forward authorization", InstanceConfig{ForwardHeaders: []string{"Authorization"}}, true},
+		{"forward session token", InstanceConfig{ForwardHeaders: []string{"x-session-token"}}, true},
+		{"forward prefix matching api key", InstanceConfig{ForwardHeaders: []string{"X-Api-*"}}, true},
+		{"forward prefix matching auth header", InstanceConfig{Authentication: AuthenticationConfig{Header: "X-Custom-Auth"}, ForwardHeaders: []string{"X-Custom-*"}}, true},
+		{"forward everything", InstanceConfig{ForwardHeaders: []string{"*"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{Instances: map[string]InstanceConfig{"test": tt.instance}}
+			if err := config.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTransformationOptions(t *testing.T) {
+	config := &Config{Instances: map[string]InstanceConfig{
+		"bedrock": {Transformation: &TransformationConfig{Options: map[string]interface{}{
+			"default_max_tokens": 4096,
+			"anthropic_version":  "bedrock-2023-05-31",
+			"force_system_merge": true,
+			"inject_metadata":    map[string]interface{}{"team": "search"},
+		}}},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	options := config.Instances["bedrock"].Transformation.RequestOptions()
+	if options.DefaultMaxTokens != 4096 || options.AnthropicVersion != "bedrock-2023-05-31" || !options.ForceSystemMerge || options.InjectMetadata["team"] != "search" {
+		t.Errorf("RequestOptions() = %+v", options)
+	}
+
+	config.Instances["bedrock"].Transformation.Options["preserve_original_model_id"] = false
+	err := config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "instance bedrock") || !strings.Contains(err.Error(), "preserve_original_model_id") {
+		t.Errorf("Validate() error = %v, want unknown option named with the instance", err)
+	}
+
+	config = &Config{Instances: map[string]InstanceConfig{
+		"bedrock": {Transformation: &TransformationConfig{Options: map[string]interface{}{"default_max_tokens": "many"}}},
+	}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() accepted a non-numeric default_max_tokens")
+	}
+}
+
+func TestOutputTokenLimitApply(t *testing.T) {
+	tests := []struct {
+		name        string
+		limit       OutputTokenLimit
+		requested   int
+		want        int
+		wantClamped bool
+		wantErr     bool
+	}{
+		{"unlimited", OutputTokenLimit{}, 100000, 100000, false, false},
+		{"unset", OutputTokenLimit{MaxOutputTokens: 1000}, 0, 0, false, false},
+		{"within limit", OutputTokenLimit{MaxOutputTokens: 1000}, 1000, 1000, false, false},
+		{"clamped by default", OutputTokenLimit{MaxOutputTokens: 1000}, 4000, 1000, true, false},
+		{"clamp", OutputTokenLimit{MaxOutputTokens: 1000, Action: MaxTokensClamp}, 4000, 1000, true, false},
+		{"reject", OutputTokenLimit{MaxOutputTokens: 1000, Action: MaxTokensReject}, 4000, 0, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, clamped, err := tt.limit.Apply(tt.requested)
+			if (err != nil) != tt.wantErr || got != tt.want || clamped != tt.wantClamped {
+				t.Errorf("Apply(%d) = %d, %v, %v; want %d, %v, error %v", tt.requested, got, clamped, err, tt.want, tt.wantClamped, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateOutputTokenLimit(t *testing.T) {
+	config := &Config{
+		Global: GlobalConfig{OutputTokenLimit: OutputTokenLimit{MaxOutputTokens: 2000}},
+		Instances: map[string]InstanceConfig{
+			"inherits": {},
+			"own":      {OutputTokenLimit: OutputTokenLimit{MaxOutputTokens: 500, Action: MaxTokensReject}},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if got := config.Instances["inherits"].MaxOutputTokens; got != 2000 {
+		t.Errorf("inherited max_output_tokens = %d, want 2000", got)
+	}
+	if got := config.Instances["own"].OutputTokenLimit; got.MaxOutputTokens != 500 || got.Action != MaxTokensReject {
+		t.Errorf("own limit = %+v, want 500 reject", got)
+	}
+
+	config = &Config{Instances: map[string]InstanceConfig{
+		"bad": {OutputTokenLimit: OutputTokenLimit{MaxOutputTokens: 500, Action: "truncate"}},
+	}}
+	if err := config.Validate(); err == nil {
+		t.Error("Validate() accepted an unknown max_output_tokens_action")
+	}
+}
+
+func TestRequestTemplate(t *testing.T) {
+	config := &Config{Instances: map[string]InstanceConfig{
+		"exotic": {Transformation: &TransformationConfig{
+			RequestTemplate: `{"prompt": {{ json (last .messages).content }}, "limit": {{ default 256 .max_tokens }}, "engine": {{ json (upper .model) }}}`,
+		}},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	transformation := config.Instances["exotic"].Transformation
+	if !transformation.HasRequestTemplate() {
+		t.Fatal("HasRequestTemplate() = false after Validate")
+	}
+
+	body, err := transformation.RenderRequest(&translator.ChatCompletionRequest{
+		Model: "tiny",
+		Messages: []translator.ChatMessage{
+			{Role: "system", Content: translator.TextContent("Be brief.")},
+			{Role: "user", Content: translator.TextContent(`Say "hi"`)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("RenderRequest() error = %v", err)
+	}
+	if want := `{"prompt": "Say \"hi\"", "limit": 256, "engine": "TINY"}`; string(body) != want {
+		t.Errorf("RenderRequest() = %s, want %s", body, want)
+	}
+
+	for _, tmpl := range []string{
+		`{"prompt": {{ json .messages }`,  // parse error
+		`{"n": {{ len .model.missing }}}`, // fails on the sample request
+	} {
+		config.Instances["exotic"].Transformation.RequestTemplate = tmpl
+		err := config.Validate()
+		if err == nil || !strings.Contains(err.Error(), "instance exotic") || !strings.Contains(err.Error(), "request_template") {
+			t.Errorf("Validate(%s) error = %v, want a request_template error", tmpl, err)
+		}
+	}
+
+	config.Instances["exotic"].Transformation.RequestTemplate = `not json`
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	if _, err := transformation.RenderRequest(templateSample); err == nil {
+		t.Error("RenderRequest() accepted a body that is not JSON")
+	}
+}
+
+func TestResponseFields(t *testing.T) {
+	config := &Config{Instances: map[string]InstanceConfig{
+		"compliant": {Transformation: &TransformationConfig{ResponseFields: &ResponseFieldFilter{
+			Remove: []string{"system_fingerprint", "service_tier"},
+			Add:    map[string]interface{}{"region": "eu", "gateway": map[string]interface{}{"zone": 2}},
+		}}},
+	}}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	transformation := config.Instances["compliant"].Transformation
+	if !transformation.FiltersResponse() {
+		t.Fatal("FiltersResponse() = false after Validate")
+	}
+
+	body, err := transformation.FilterResponse([]byte(`{"id":"x","system_fingerprint":"fp","service_tier":"default","region":"us","custom":{"a":[1, 2]}}`))
+	if err != nil {
+		t.Fatalf("FilterResponse() error = %v", err)
+	}
+	if want := `{"custom":{"a":[1,2]},"gateway":{"zone":2},"id":"x","region":"eu"}`; string(body) != want {
+		t.Errorf("FilterResponse() = %s, want %s", body, want)
+	}
+	if _, err := transformation.FilterResponse([]byte(`[1]`)); err == nil {
+		t.Error("FilterResponse() accepted a body that is not an object")
+	}
+
+	var none *TransformationConfig
+	if body, err := none.FilterResponse([]byte(`{"a":1}`)); err != nil || string(body) != `{"a":1}` {
+		t.Errorf("nil FilterResponse() = %s, %v", body, err)
+	}
+
+	transformation.ResponseFields.Add["service_tier"] = "flex"
+	err = config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "instance compliant") || !strings.Contains(err.Error(), "service_tier") {
+		t.Errorf("Validate() error = %v, want a field both removed and added", err)
+	}
+	delete(transformation.ResponseFields.Add, "service_tier")
+
+	transformation.ResponseTo = ResponsePassthrough
+	if !transformation.PassesResponseThrough() {
+		t.Error("PassesResponseThrough() = false for response_to: passthrough")
+	}
+	err = config.Validate()
+	if err == nil || !strings.Contains(err.Error(), "passthrough") {
+		t.Errorf("Validate() error = %v, want response_fields rejected with passthrough", err)
+	}
+	if none.PassesResponseThrough() {
+		t.Error("nil PassesResponseThrough() = true")
+	}
+}
+
+func TestValidateGenericHTTP(t *testing.T) {
+	tests := []struct {
+		inst    InstanceConfig
+		wantErr string
+	}{
+		{InstanceConfig{Type: "generic_http", BaseURL: "https://scorer.internal", Timeout: "30s",
+			Authentication: AuthenticationConfig{Type: "basic", Username: "svc"}}, ""},
+		{InstanceConfig{Type: "generic_http"}, "base_url"},
+		{InstanceConfig{Type: "generic_http", BaseURL: "https://x", Authentication: AuthenticationConfig{Type: "basic"}}, "username"},
+		{InstanceConfig{Type: "generic_http", BaseURL: "https://x", Authentication: AuthenticationConfig{Type: "gcp_oauth2"}}, "gcp_oauth2"},
+		{InstanceConfig{Type: "generic_http", BaseURL: "https://x", Timeout: "soon"}, "timeout"},
+		{InstanceConfig{Type: "generic_http", BaseURL: "https://x", HealthCheckPath: "healthz"}, "health_check_path"},
+	}
+	for _, tt := range tests {
+		config := &Config{Instances: map[string]InstanceConfig{"internal": tt.inst}}
+		err := config.Validate()
+		if tt.wantErr == "" && err != nil {
+			t.Errorf("Validate(%+v) error = %v", tt.inst, err)
+		}
+		if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+			t.Errorf("Validate(%+v) error = %v, want one mentioning %s", tt.inst, err, tt.wantErr)
+		}
+	}
+}
+
+// TestValidateTimeouts tests that instances inherit unset timeouts from the
+// global ones and that the deprecated timeouts still apply
+func TestValidateTimeouts(t *testing.T) {
+	config := &Config{
+		Global: GlobalConfig{DefaultTimeout: "300s", HTTPTimeouts: HTTPTimeouts{ConnectTimeout: "5s"}},
+		Instances: map[string]InstanceConfig{
+			"inherits": {Type: "openai"},
+			"own":      {Type: "anthropic", HTTPTimeouts: HTTPTimeouts{ConnectTimeout: "2s", RequestTimeout: "10m"}},
+			"legacy":   {Type: "generic_http", BaseURL: "https://x", Timeout: "30s"},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+	want := map[string]HTTPTimeouts{
+		"inherits": {ConnectTimeout: "5s", RequestTimeout: "300s"},
+		"own":      {ConnectTimeout: "2s", RequestTimeout: "10m"},
+		"legacy":   {ConnectTimeout: "5s", RequestTimeout: "30s"},
+	}
+	for name, timeouts := range want {
+		if got := config.Instances[name].HTTPTimeouts; got != timeouts {
+			t.Errorf("%s timeouts = %+v, want %+v", name, got, timeouts)
+		}
+	}
+
+	config = &Config{Instances: map[string]InstanceConfig{
+		"bad": {Type: "openai", HTTPTimeouts: HTTPTimeouts{ConnectTimeout: "-1s"}},
+	}}
+	if err := config.Validate(); err == nil || !strings.Contains(err.Error(), "connect_timeout") {
+		t.Errorf("Validate() error = %v, want one mentioning connect_timeout", err)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("PROXY_REGION", "eu-west-1")
+	t.Setenv("PROXY_EMPTY", "")
+	secret := filepath.Join(t.TempDir(), "api-key")
+	if err := os.WriteFile(secret, []byte("sk-secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"region: ${PROXY_REGION}", "region: eu-west-1"},
+		{"region: $PROXY_REGION", "region: eu-west-1"},
+		{"region: ${PROXY_UNSET}", "region: "},
+		{"region: ${PROXY_UNSET:-us-east-1}", "region: us-east-1"},
+		{"region: ${PROXY_EMPTY:-us-east-1}", "region: us-east-1"},
+		{"region: ${PROXY_REGION:-us-east-1}", "region: eu-west-1"},
+		{"region: ${PROXY_REGION:?region is required}", "region: eu-west-1"},
+		{"api_key: ${file:" + secret + "}", "api_key: sk-secret"},
+	}
+	for _, tt := range tests {
+		got, err := ExpandEnv(tt.in)
+		if err != nil {
+			t.Errorf("ExpandEnv(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ExpandEnv(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestExpandEnvErrors(t *testing.T) {
+	t.Setenv("PROXY_EMPTY", "")
+
+	tests := []struct {
+		in          string
+		wantName    string
+		wantMessage string
+	}{
+		{"key: ${PROXY_UNSET:?set it to the Azure key}", "PROXY_UNSET", "set it to the Azure key"},
+		{"key: ${PROXY_EMPTY:?}", "PROXY_EMPTY", "not set"},
+	}
+	for _, tt := range tests {
+		_, err := ExpandEnv(tt.in)
+		var missing *MissingEnvError
+		if !errors.As(err, &missing) {
+			t.Errorf("ExpandEnv(%q) error = %v, want a MissingEnvError", tt.in, err)
+			continue
+		}
+		if missing.Name != tt.wantName || missing.Message != tt.wantMessage {
+			t.Errorf("ExpandEnv(%q) error = %+v, want %s: %s", tt.in, missing, tt.wantName, tt.wantMessage)
+		}
+	}
+
+	missingFile := filepath.Join(t.TempDir(), "missing")
+	if _, err := ExpandEnv("key: ${file:" + missingFile + "}"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("ExpandEnv(missing file) error = %v, want ErrNotExist", err)
+	}
+}
+
+func TestLoadConfigRequiredEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instances.yaml")
+	config := `instances:
+  azure:
+    type: azure
+    endpoint: ${PROXY_AZURE_ENDPOINT:?Azure endpoint}
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(path)
+	var missing *MissingEnvError
+	if !errors.As(err, &missing) || missing.Name != "PROXY_AZURE_ENDPOINT" {
+		t.Fatalf("LoadConfig() error = %v, want a MissingEnvError for PROXY_AZURE_ENDPOINT", err)
+	}
+}
+
+// writeConfigFiles writes files, keyed by path relative to a new temporary
+// directory, and returns the directory
+func writeConfigFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadConfigIncludes(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"main.yaml": `include:
+  - instances/*.yaml
+global:
+  default_timeout: 30s
+instances:
+  bedrock:
+    type: bedrock
+    region: us-east-1
+    request_headers:
+      X-Team: platform
+`,
+		"instances/a-bedrock.yaml": `instances:
+  bedrock:
+    region: eu-west-1
+    request_headers:
+      X-Env: prod
+`,
+		"instances/b-azure.yaml": `include: ../shared/*.yaml
+instances:
+  azure:
+    type: azure
+    region: westeurope
+`,
+		"shared/azure.yaml": `instances:
+  azure:
+    region: northeurope
+`,
+	})
+
+	config, err := LoadConfig(filepath.Join(dir, "main.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	bedrock := config.Instances["bedrock"]
+	if bedrock.Type != "bedrock" || bedrock.Region != "eu-west-1" {
+		t.Errorf("bedrock = type %q region %q, want bedrock in eu-west-1", bedrock.Type, bedrock.Region)
+	}
+	if bedrock.RequestHeaders["X-Team"] != "platform" || bedrock.RequestHeaders["X-Env"] != "prod" {
+		t.Errorf("bedrock request_headers = %v, want both files' headers", bedrock.RequestHeaders)
+	}
+	if got := config.Instances["azure"].Region; got != "northeurope" {
+		t.Errorf("azure region = %q, want northeurope from the nested include", got)
+	}
+	if config.Global.DefaultTimeout != "30s" {
+		t.Errorf("global default_timeout = %q", config.Global.DefaultTimeout)
+	}
+
+	var files []string
+	for _, file := range config.Files() {
+		rel, _ := filepath.Rel(dir, file)
+		files = append(files, filepath.ToSlash(rel))
+	}
+	want := "main.yaml instances/a-bedrock.yaml instances/b-azure.yaml shared/azure.yaml"
+	if strings.Join(files, " ") != want {
+		t.Errorf("Files() = %v, want %s", files, want)
+	}
+}
+
+func TestLoadConfigLocations(t *testing.T) {
+	dir := writeConfigFiles(t, map[string]string{
+		"conf.d/10-base.yaml":     "instances:\n  openai:\n    type: openai\n    region: us\n",
+		"conf.d/20-override.yml":  "instances:\n  openai:\n    region: eu\n",
+		"conf.d/notes.txt":        "not config",
+		"extra/anthropic.yaml":    "instances:\n  anthropic:\n    type: anthropic\n",
+		"extra/openai-local.yaml": "instances:\n  openai:\n    region: local\n",
+	})
+
+	config, err := LoadConfig(filepath.Join(dir, "conf.d"))
+	if err != nil {
+		t.Fatalf("LoadConfig(directory) error = %v", err)
+	}
+	if got := config.Instances["openai"].Region; got != "eu" || len(config.Files()) != 2 {
+		t.Errorf("directory: openai region %q from %v, want eu from two files", got, config.Files())
+	}
+
+	list := filepath.Join(dir, "conf.d") + ", " + filepath.Join(dir, "extra/anthropic.yaml") + "," + filepath.Join(dir, "extra/openai-local.yaml")
+	config, err = LoadConfig(list)
+	if err != nil {
+		t.Fatalf("LoadConfig(list) error = %v", err)
+	}
+	if got := config.Instances["openai"].Region; got != "local" {
+		t.Errorf("list: openai region = %q, want local from the last file", got)
+	}
+	if _, ok := config.Instances["anthropic"]; !ok {
+		t.Error("list: anthropic instance missing")
+	}
+}
+
+func TestLoadConfigIncludeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		wantErr []string
+	}{
+		{
+			name: "conflicting types",
+			files: map[string]string{
+				"main.yaml":     "include: [override.yaml]\ninstances:\n  openai:\n    type: openai\n    request_headers:\n      X-Team: platform\n",
+				"override.yaml": "instances:\n  openai:\n    request_headers: [X-Team]\n",
+			},
+			wantErr: []string{"override.yaml:3", "instances.openai.request_headers is a list", "main.yaml:6", "as a mapping"},
+		},
+		{
+			name: "cycle",
+			files: map[string]string{
+				"main.yaml": "include: a.yaml\n",
+				"a.yaml":    "include: b.yaml\n",
+				"b.yaml":    "include: a.yaml\n",
+			},
+			wantErr: []string{"include cycle", "a.yaml -> ", "b.yaml"},
+		},
+		{
+			name:    "missing include",
+			files:   map[string]string{"main.yaml": "include: missing.yaml\n"},
+			wantErr: []string{"missing.yaml does not exist"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeConfigFiles(t, tt.files)
+			_, err := LoadConfig(filepath.Join(dir, "main.yaml"))
+			if err == nil {
+				t.Fatal("LoadConfig() error = nil")
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("LoadConfig() error = %v, want it to mention %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateDeprecatedRoutes(t *testing.T) {
+	for name, tt := range map[string]struct {
+		deprecation DeprecationConfig
+		wantErr     bool
+	}{
+		"dates":              {DeprecationConfig{Since: "2025-01-01", Sunset: "2025-06-30T12:00:00Z", BlockAfterSunset: true}, false},
+		"no dates":           {DeprecationConfig{}, false},
+		"bad sunset":         {DeprecationConfig{Sunset: "30/06/2025"}, true},
+		"block needs sunset": {DeprecationConfig{BlockAfterSunset: true}, true},
+	} {
+		config := &Config{DeprecatedRoutes: map[string]DeprecationConfig{"/model": tt.deprecation}}
+		if err := config.Validate(); (err != nil) != tt.wantErr {
+			t.Errorf("%s: Validate() error = %v, wantErr %v", name, err, tt.wantErr)
+		}
+	}
+
+	d := DeprecationConfig{Sunset: "2025-06-30", BlockAfterSunset: true}
+	if d.Blocked(time.Date(2025, 6, 29, 23, 59, 0, 0, time.UTC)) || !d.Blocked(time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)) {
+		t.Error("Blocked() does not switch at the sunset date")
+	}
+}
+
+func TestFeatureOverrides(t *testing.T) {
+	config := newPathTestConfig()
+	config.Features = map[string]FeatureConfig{
+		"transparent_mode": {
+			Enabled:   false,
+			Instances: map[string]bool{"bedrock": true},
+			Keys:      map[string]bool{"tenant-a": true, "tenant-b": false},
+		},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	for _, tt := range []struct {
+		instance, identity string
+		want               bool
+	}{
+		{"", "", false},
+		{"azure", "", false},
+		{"bedrock", "", true},
+		{"azure", "tenant-a", true},
+		{"bedrock", "tenant-b", false},
+		{"bedrock", "tenant-c", true},
+	} {
+		if got := config.IsFeatureEnabled("transparent_mode", tt.instance, tt.identity); got != tt.want {
+			t.Errorf("IsFeatureEnabled(%q, %q) = %v, want %v", tt.instance, tt.identity, got, tt.want)
+		}
+	}
+	if config.IsFeatureEnabled("unknown", "bedrock", "tenant-a") {
+		t.Error("unconfigured feature is enabled")
+	}
+
+	// A reload takes a copy with the new flags, leaving the config as it was
+	reloaded, err := config.WithFeatures(map[string]FeatureConfig{"transparent_mode": {Enabled: true}})
+	if err != nil {
+		t.Fatalf("WithFeatures() error = %v", err)
+	}
+	if !reloaded.IsFeatureEnabled("transparent_mode", "azure", "tenant-b") {
+		t.Error("reloaded flag is not in effect")
+	}
+	if got := reloaded.EffectiveFeatures("azure", ""); len(got) != 1 || !got["transparent_mode"] {
+		t.Errorf("EffectiveFeatures() = %v", got)
+	}
+	if config.IsFeatureEnabled("transparent_mode", "azure", "tenant-b") {
+		t.Error("WithFeatures() changed the flags of the original config")
+	}
+
+	_, err = reloaded.WithFeatures(map[string]FeatureConfig{"protocol_mode": {Instances: map[string]bool{"missing": true}}})
+	if err == nil || !strings.Contains(err.Error(), "missing") {
+		t.Errorf("WithFeatures() with an unknown instance error = %v", err)
+	}
+}
+
+// TestClone tests that changes to a clone, including its nested maps and
+// slices, leave the original as it was for the requests reading it
+func TestClone(t *testing.T) {
+	required := true
+	config := &Config{
+		Global: GlobalConfig{Authentication: map[string]interface{}{
+			"oidc": map[string]interface{}{"issuers": []interface{}{"https://a.example"}},
+		}},
+		Instances: map[string]InstanceConfig{
+			"openai": {
+				Type:           "openai",
+				Mode:           "protocol",
+				Required:       &required,
+				Endpoints:      []EndpointConfig{{Path: "/openai/main", Methods: []string{"POST"}}},
+				RequestHeaders: map[string]string{"X-Team": "search"},
+				PathRewrite:    &PathRewrite{StripPrefix: "/v1"},
+				Transformation: &TransformationConfig{
+					Options: map[string]interface{}{"inject_metadata": map[string]interface{}{"team": "search"}},
+					ResponseFields: &ResponseFieldFilter{
+						Remove: []string{"system_fingerprint"},
+						Add:    map[string]interface{}{"region": "eu"},
+					},
+				},
+			},
+		},
+		Routing:  RoutingConfig{Defaults: map[string]string{"openai": "openai"}},
+		Features: map[string]FeatureConfig{"protocol_mode": {Enabled: true, Instances: map[string]bool{"openai": true}}},
+	}
+	if err := config.Validate(); err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	clone := config.Clone()
+	if !reflect.DeepEqual(clone, config) {
+		t.Fatalf("Clone() = %+v, want %+v", clone, config)
+	}
+
+	// Requests keep reading the original while the clone is changed
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			config.GetInstanceForRequest(http.MethodPost, "/openai/main/chat/completions")
+			config.IsFeatureEnabled("protocol_mode", "openai", "")
+		}
+	}()
+
+	inst := clone.Instances["openai"]
+	inst.Endpoints[0].Methods[0] = http.MethodGet
+	inst.RequestHeaders["X-Team"] = "ads"
+	*inst.Required = false
+	inst.PathRewrite.StripPrefix = "/v2"
+	inst.Transformation.Options["inject_metadata"].(map[string]interface{})["team"] = "ads"
+	inst.Transformation.ResponseFields.Remove[0] = "id"
+	clone.endpoints[0].endpoint.Methods[0] = http.MethodGet
+	clone.Global.Authentication["oidc"].(map[string]interface{})["issuers"].([]interface{})[0] = "https://b.example"
+	clone.Routing.Defaults["openai"] = "other"
+	clone.Features["protocol_mode"].Instances["openai"] = false
+	delete(clone.Instances, "openai")
+	<-done
+
+	orig := config.Instances["openai"]
+	if _, name, err := config.GetInstanceForRequest(http.MethodPost, "/openai/main/chat/completions"); err != nil || name != "openai" {
+		t.Errorf("GetInstanceForRequest() = %q, %v", name, err)
+	}
+	if orig.Endpoints[0].Methods[0] != http.MethodPost || orig.RequestHeaders["X-Team"] != "search" ||
+		!*orig.Required || orig.PathRewrite.StripPrefix != "/v1" {
+		t.Errorf("instance changed by its clone: %+v", orig)
+	}
+	if team := orig.Transformation.Options["inject_metadata"].(map[string]interface{})["team"]; team != "search" {
+		t.Errorf("options changed by the clone: team = %v", team)
+	}
+	if orig.Transformation.ResponseFields.Remove[0] != "system_fingerprint" {
+		t.Errorf("response_fields changed by the clone: %v", orig.Transformation.ResponseFields.Remove)
+	}
+	if issuer := config.Global.Authentication["oidc"].(map[string]interface{})["issuers"].([]interface{})[0]; issuer != "https://a.example" {
+		t.Errorf("global authentication changed by the clone: issuer = %v", issuer)
+	}
+	if config.Routing.Defaults["openai"] != "openai" {
+		t.Errorf("routing defaults changed by the clone: %v", config.Routing.Defaults)
+	}
+	if !config.IsFeatureEnabled("protocol_mode", "openai", "") {
+		t.Error("feature flags changed by the clone")
+	}
+
+	var none *Config
+	if none.Clone() != nil {
+		t.Error("nil Clone() != nil")
+	}
+}
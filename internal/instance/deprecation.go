@@ -0,0 +1,67 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"fmt"
+	"time"
+)
+
+// DeprecationConfig marks a route prefix as deprecated. Requests are counted
+// and answered with Deprecation and Sunset headers; with block_after_sunset
+// they are refused with 410 once the sunset date has passed.
+type DeprecationConfig struct {
+	Since            string `yaml:"since,omitempty"`  // Date deprecated, YYYY-MM-DD or RFC 3339 (default: Deprecation: true)
+	Sunset           string `yaml:"sunset,omitempty"` // Date the route is removed, YYYY-MM-DD or RFC 3339
+	Link             string `yaml:"link,omitempty"`   // Migration guide, sent as a Link header
+	Message          string `yaml:"message,omitempty"`
+	BlockAfterSunset bool   `yaml:"block_after_sunset,omitempty"`
+}
+
+// SinceTime returns the deprecation date, or the zero time if unset
+func (d DeprecationConfig) SinceTime() time.Time {
+	t, _ := parseDeprecationDate(d.Since)
+	return t
+}
+
+// SunsetTime returns the sunset date, or the zero time if unset
+func (d DeprecationConfig) SunsetTime() time.Time {
+	t, _ := parseDeprecationDate(d.Sunset)
+	return t
+}
+
+// Blocked reports whether requests are refused at the given time
+func (d DeprecationConfig) Blocked(now time.Time) bool {
+	sunset := d.SunsetTime()
+	return d.BlockAfterSunset && !sunset.IsZero() && !now.Before(sunset)
+}
+
+func (d DeprecationConfig) validate() error {
+	if _, err := parseDeprecationDate(d.Since); err != nil {
+		return fmt.Errorf("since: %w", err)
+	}
+	if _, err := parseDeprecationDate(d.Sunset); err != nil {
+		return fmt.Errorf("sunset: %w", err)
+	}
+	if d.BlockAfterSunset && d.Sunset == "" {
+		return fmt.Errorf("block_after_sunset requires a sunset date")
+	}
+	return nil
+}
+
+// parseDeprecationDate parses a YYYY-MM-DD date (midnight UTC) or an
+// RFC 3339 timestamp; an empty string is the zero time
+func parseDeprecationDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is not a YYYY-MM-DD date or an RFC 3339 timestamp", value)
+	}
+	return t, nil
+}
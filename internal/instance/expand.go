@@ -0,0 +1,78 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// fileRefPrefix marks a ${file:/path} secret file reference
+const fileRefPrefix = "file:"
+
+// MissingEnvError is returned by ExpandEnv for a ${VAR:?message} reference
+// to an unset or empty variable
+type MissingEnvError struct {
+	Name    string
+	Message string
+}
+
+func (e *MissingEnvError) Error() string {
+	return fmt.Sprintf("environment variable %s is required: %s", e.Name, e.Message)
+}
+
+// ExpandEnv expands references in config file contents:
+//
+//	${VAR}              the value of VAR, or empty if unset ($VAR also works)
+//	${VAR:-default}     the value of VAR, or default if VAR is unset or empty
+//	${VAR:?message}     the value of VAR; a *MissingEnvError if unset or empty
+//	${file:/path}       the contents of a file such as a mounted secret,
+//	                    without trailing newlines
+//
+// The first failing reference is returned as the error.
+func ExpandEnv(s string) (string, error) {
+	var expandErr error
+	expanded := os.Expand(s, func(ref string) string {
+		value, err := expandRef(ref)
+		if err != nil && expandErr == nil {
+			expandErr = err
+		}
+		return value
+	})
+	if expandErr != nil {
+		return "", expandErr
+	}
+	return expanded, nil
+}
+
+// expandRef resolves the contents of one ${...} reference
+func expandRef(ref string) (string, error) {
+	if path, ok := strings.CutPrefix(ref, fileRefPrefix); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("failed to read secret file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if name, def, ok := strings.Cut(ref, ":-"); ok {
+		if value := os.Getenv(name); value != "" {
+			return value, nil
+		}
+		return def, nil
+	}
+
+	if name, message, ok := strings.Cut(ref, ":?"); ok {
+		if value := os.Getenv(name); value != "" {
+			return value, nil
+		}
+		if message == "" {
+			message = "not set"
+		}
+		return "", &MissingEnvError{Name: name, Message: message}
+	}
+
+	return os.Getenv(ref), nil
+}
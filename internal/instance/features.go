@@ -0,0 +1,84 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import "fmt"
+
+// FeatureConfig represents a feature flag. Enabled is the global default;
+// an instance can override it, and an API key (the authenticated identity)
+// overrides both.
+type FeatureConfig struct {
+	Enabled     bool            `yaml:"enabled"`
+	Description string          `yaml:"description"`
+	Instances   map[string]bool `yaml:"instances,omitempty"` // Instance name -> enabled
+	Keys        map[string]bool `yaml:"keys,omitempty"`      // API key name -> enabled
+}
+
+// HasFeature reports whether a feature flag is configured at all
+func (c *Config) HasFeature(featureName string) bool {
+	_, ok := c.Features[featureName]
+	return ok
+}
+
+// IsFeatureEnabled reports whether a feature is enabled for requests to an
+// instance by an identity. The key override wins over the instance
+// override, which wins over the global default; an empty instance or
+// identity skips its layer. Unconfigured features are disabled.
+func (c *Config) IsFeatureEnabled(featureName, instanceName, identity string) bool {
+	feature, ok := c.Features[featureName]
+	if !ok {
+		return false
+	}
+	return feature.enabledFor(instanceName, identity)
+}
+
+// EffectiveFeatures returns whether each configured feature is enabled for
+// an instance and identity, as IsFeatureEnabled decides
+func (c *Config) EffectiveFeatures(instanceName, identity string) map[string]bool {
+	effective := make(map[string]bool, len(c.Features))
+	for name, feature := range c.Features {
+		effective[name] = feature.enabledFor(instanceName, identity)
+	}
+	return effective
+}
+
+// WithFeatures returns a copy of the config with features as its feature
+// flags, such as those of a freshly loaded config; the config itself is
+// unchanged. Features whose routes are registered at startup
+// (transparent_mode, protocol_mode) must be configured then for a reload
+// to enable them.
+func (c *Config) WithFeatures(features map[string]FeatureConfig) (*Config, error) {
+	clone := c.Clone()
+	clone.Features = cloneFeatures(features)
+	if err := clone.validateFeatures(clone.Features); err != nil {
+		return nil, err
+	}
+	return clone, nil
+}
+
+// validateFeatures checks that instance overrides name known instances
+func (c *Config) validateFeatures(features map[string]FeatureConfig) error {
+	for name, feature := range features {
+		for instanceName := range feature.Instances {
+			if _, ok := c.Instances[instanceName]; !ok {
+				return fmt.Errorf("features %s: unknown instance %q", name, instanceName)
+			}
+		}
+	}
+	return nil
+}
+
+func (f FeatureConfig) enabledFor(instanceName, identity string) bool {
+	if identity != "" {
+		if enabled, ok := f.Keys[identity]; ok {
+			return enabled
+		}
+	}
+	if instanceName != "" {
+		if enabled, ok := f.Instances[instanceName]; ok {
+			return enabled
+		}
+	}
+	return f.Enabled
+}
@@ -0,0 +1,260 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// includeKey lists further config files to merge, as globs relative to the
+// file that includes them
+const includeKey = "include"
+
+// configPaths returns the files named by a config location: a file, a
+// directory (its *.yaml and *.yml files, in name order) or a comma-separated
+// list of either
+func configPaths(location string) ([]string, error) {
+	var paths []string
+	for _, entry := range strings.Split(location, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		info, err := os.Stat(entry)
+		if err != nil || !info.IsDir() {
+			// Missing files are reported when they are read
+			paths = append(paths, entry)
+			continue
+		}
+
+		var files []string
+		for _, pattern := range []string{"*.yaml", "*.yml"} {
+			matches, err := filepath.Glob(filepath.Join(entry, pattern))
+			if err != nil {
+				return nil, fmt.Errorf("failed to list config directory %s: %w", entry, err)
+			}
+			files = append(files, matches...)
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("config directory %s has no .yaml or .yml files", entry)
+		}
+		sort.Strings(files)
+		paths = append(paths, files...)
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no config files in %q", location)
+	}
+	return paths, nil
+}
+
+// configMerger merges config files into one YAML mapping. Files are merged
+// in order, each followed by the files it includes, so later files override
+// earlier ones: mappings are merged key by key, and scalars and lists are
+// replaced. A null value does not override.
+type configMerger struct {
+	root  *yaml.Node
+	files []string
+
+	// origins maps each node to the file it was read from, for errors
+	origins map[*yaml.Node]string
+	// merged holds the absolute path of each file merged, so a file
+	// included twice is merged once
+	merged map[string]bool
+	// including holds the chain of files whose includes are being merged
+	including []string
+}
+
+func newConfigMerger() *configMerger {
+	return &configMerger{
+		origins: make(map[*yaml.Node]string),
+		merged:  make(map[string]bool),
+	}
+}
+
+// mergeFile merges a file and, after it, the files it includes
+func (m *configMerger) mergeFile(path string) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve config file %s: %w", path, err)
+	}
+	for _, including := range m.including {
+		if including == abs {
+			return fmt.Errorf("config include cycle: %s -> %s", strings.Join(m.including, " -> "), abs)
+		}
+	}
+	if m.merged[abs] {
+		return nil
+	}
+	m.merged[abs] = true
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// Expand environment variables and secret file references
+	expanded, err := ExpandEnv(string(data))
+	if err != nil {
+		return fmt.Errorf("failed to expand config %s: %w", path, err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(expanded), &doc); err != nil {
+		return fmt.Errorf("failed to parse config %s: %w", path, err)
+	}
+	m.files = append(m.files, path)
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("%s:%d: config must be a mapping", path, root.Line)
+	}
+
+	includes, err := takeIncludes(root, path)
+	if err != nil {
+		return err
+	}
+	m.setOrigin(root, path)
+	if m.root == nil {
+		m.root = root
+	} else if err := m.mergeMapping(m.root, root, ""); err != nil {
+		return err
+	}
+
+	m.including = append(m.including, abs)
+	defer func() { m.including = m.including[:len(m.including)-1] }()
+	for _, pattern := range includes {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(filepath.Dir(path), pattern)
+		}
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return fmt.Errorf("%s: invalid include %q: %w", path, pattern, err)
+		}
+		if len(matches) == 0 && !hasGlobMeta(pattern) {
+			return fmt.Errorf("%s: included file %s does not exist", path, pattern)
+		}
+		for _, match := range matches {
+			if err := m.mergeFile(match); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// mergeMapping merges src into dst; keyPath names dst for errors
+func (m *configMerger) mergeMapping(dst, src *yaml.Node, keyPath string) error {
+	for i := 0; i+1 < len(src.Content); i += 2 {
+		key, value := src.Content[i], resolveAlias(src.Content[i+1])
+		name := key.Value
+		if keyPath != "" {
+			name = keyPath + "." + key.Value
+		}
+
+		j := mappingIndex(dst, key.Value)
+		if j < 0 {
+			dst.Content = append(dst.Content, key, value)
+			continue
+		}
+		existing := resolveAlias(dst.Content[j+1])
+
+		switch {
+		case isNull(value):
+		case isNull(existing):
+			dst.Content[j+1] = value
+		case existing.Kind != value.Kind:
+			return fmt.Errorf("%s:%d: %s is a %s, but %s:%d defines it as a %s",
+				m.origins[value], value.Line, name, nodeKind(value),
+				m.origins[existing], existing.Line, nodeKind(existing))
+		case value.Kind == yaml.MappingNode:
+			if err := m.mergeMapping(existing, value, name); err != nil {
+				return err
+			}
+		default:
+			dst.Content[j+1] = value
+		}
+	}
+	return nil
+}
+
+// setOrigin records path as the file of node and its descendants
+func (m *configMerger) setOrigin(node *yaml.Node, path string) {
+	m.origins[node] = path
+	for _, child := range node.Content {
+		m.setOrigin(child, path)
+	}
+}
+
+// takeIncludes removes the include key from a file's root mapping and
+// returns its globs. The key takes one glob or a list of them.
+func takeIncludes(root *yaml.Node, path string) ([]string, error) {
+	i := mappingIndex(root, includeKey)
+	if i < 0 {
+		return nil, nil
+	}
+	value := root.Content[i+1]
+	root.Content = append(root.Content[:i], root.Content[i+2:]...)
+
+	switch {
+	case isNull(value):
+		return nil, nil
+	case value.Kind == yaml.ScalarNode:
+		return []string{value.Value}, nil
+	case value.Kind == yaml.SequenceNode:
+		includes := make([]string, 0, len(value.Content))
+		for _, item := range value.Content {
+			if item.Kind != yaml.ScalarNode {
+				return nil, fmt.Errorf("%s:%d: include entries must be file paths or globs", path, item.Line)
+			}
+			includes = append(includes, item.Value)
+		}
+		return includes, nil
+	default:
+		return nil, fmt.Errorf("%s:%d: include must be a path, a glob or a list of them", path, value.Line)
+	}
+}
+
+// mappingIndex returns the index of key's key node in a mapping, or -1
+func mappingIndex(mapping *yaml.Node, key string) int {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+func nodeKind(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "list"
+	default:
+		return "scalar"
+	}
+}
+
+func hasGlobMeta(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
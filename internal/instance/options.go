@@ -0,0 +1,71 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"gopkg.in/yaml.v3"
+)
+
+// transformationOptions are the keys accepted under transformation.options
+var transformationOptions = map[string]bool{
+	"default_max_tokens":       true,
+	"anthropic_version":        true,
+	"force_system_merge":       true,
+	"drop_unsupported_params":  true,
+	"model_override":           true,
+	"inject_metadata":          true,
+	"bedrock_latency":          true,
+	"auto_cache_system_prompt": true,
+}
+
+// RequestOptions returns the decoded transformation options. A nil
+// transformation has no options.
+func (t *TransformationConfig) RequestOptions() translator.RequestOptions {
+	if t == nil {
+		return translator.RequestOptions{}
+	}
+	return t.requestOptions
+}
+
+// decodeOptions checks the option keys and decodes Options into typed
+// request options
+func (t *TransformationConfig) decodeOptions() error {
+	if t == nil || len(t.Options) == 0 {
+		return nil
+	}
+
+	var unknown []string
+	for key := range t.Options {
+		if !transformationOptions[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown transformation options: %s", strings.Join(unknown, ", "))
+	}
+
+	// Round-trip through YAML to reuse its type conversions and errors
+	data, err := yaml.Marshal(t.Options)
+	if err != nil {
+		return fmt.Errorf("invalid transformation options: %w", err)
+	}
+	var options translator.RequestOptions
+	if err := yaml.Unmarshal(data, &options); err != nil {
+		return fmt.Errorf("invalid transformation options: %w", err)
+	}
+	if options.DefaultMaxTokens < 0 {
+		return fmt.Errorf("transformation option default_max_tokens must not be negative")
+	}
+	if err := translator.ValidateLatency(options.BedrockLatency); err != nil {
+		return fmt.Errorf("transformation option bedrock_latency: %w", err)
+	}
+	t.requestOptions = options
+	return nil
+}
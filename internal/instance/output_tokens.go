@@ -0,0 +1,51 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrMaxTokensExceeded is returned by OutputTokenLimit.Apply when a request
+// asks for more output tokens than allowed and the action is reject
+var ErrMaxTokensExceeded = errors.New("max_tokens exceeds the configured maximum")
+
+// Output token limit actions
+const (
+	MaxTokensClamp  = "clamp"
+	MaxTokensReject = "reject"
+)
+
+// OutputTokenLimit caps the max_tokens a request may ask for. It is set
+// globally and per instance; instances without a limit inherit the global one.
+type OutputTokenLimit struct {
+	MaxOutputTokens int    `yaml:"max_output_tokens,omitempty"`        // 0 = unlimited
+	Action          string `yaml:"max_output_tokens_action,omitempty"` // clamp (default) or reject
+}
+
+// Apply returns the max_tokens to send for a requested value and whether it
+// was clamped. A requested value of 0 (unset) is returned unchanged.
+func (l OutputTokenLimit) Apply(requested int) (int, bool, error) {
+	if l.MaxOutputTokens <= 0 || requested <= l.MaxOutputTokens {
+		return requested, false, nil
+	}
+	if l.Action == MaxTokensReject {
+		return 0, false, fmt.Errorf("%w: requested %d, maximum %d", ErrMaxTokensExceeded, requested, l.MaxOutputTokens)
+	}
+	return l.MaxOutputTokens, true, nil
+}
+
+// validate checks the limit and action
+func (l OutputTokenLimit) validate() error {
+	if l.MaxOutputTokens < 0 {
+		return fmt.Errorf("max_output_tokens must not be negative")
+	}
+	switch l.Action {
+	case "", MaxTokensClamp, MaxTokensReject:
+		return nil
+	default:
+		return fmt.Errorf("invalid max_output_tokens_action %q (want clamp or reject)", l.Action)
+	}
+}
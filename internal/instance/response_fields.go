@@ -0,0 +1,91 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ResponseFieldFilter removes and adds top-level fields of translated
+// responses, for deployments that must not return provider metadata such
+// as system_fingerprint. Fields it does not name are kept as they are.
+type ResponseFieldFilter struct {
+	Remove []string               `yaml:"remove,omitempty"`
+	Add    map[string]interface{} `yaml:"add,omitempty"`
+
+	// add is Add encoded as JSON by Validate
+	add map[string]json.RawMessage
+}
+
+// ResponsePassthrough is the response_to value that returns the provider's
+// response body and content type unchanged, skipping the translation to
+// OpenAI
+const ResponsePassthrough = "passthrough"
+
+// PassesResponseThrough reports whether responses are returned as the
+// provider sent them
+func (t *TransformationConfig) PassesResponseThrough() bool {
+	return t != nil && t.ResponseTo == ResponsePassthrough
+}
+
+// FiltersResponse reports whether the transformation rewrites response
+// fields
+func (t *TransformationConfig) FiltersResponse() bool {
+	return t != nil && t.ResponseFields != nil && (len(t.ResponseFields.Remove) > 0 || len(t.ResponseFields.add) > 0)
+}
+
+// FilterResponse applies response_fields to a JSON object response body:
+// removed fields are deleted and added fields set, replacing fields of the
+// same name. Bodies that are not JSON objects are an error.
+func (t *TransformationConfig) FilterResponse(body []byte) ([]byte, error) {
+	if !t.FiltersResponse() {
+		return body, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(body, &object); err != nil || object == nil {
+		return nil, fmt.Errorf("response_fields: response is not a JSON object")
+	}
+	for _, field := range t.ResponseFields.Remove {
+		delete(object, field)
+	}
+	for field, value := range t.ResponseFields.add {
+		object[field] = value
+	}
+	return json.Marshal(object)
+}
+
+// compileResponseFields checks response_fields and encodes its added
+// values, so values without a JSON form fail config loading
+func (t *TransformationConfig) compileResponseFields() error {
+	if t == nil || t.ResponseFields == nil {
+		return nil
+	}
+	if t.PassesResponseThrough() {
+		return fmt.Errorf("response_fields rewrites translated responses and cannot be used with response_to: %s", ResponsePassthrough)
+	}
+	f := t.ResponseFields
+	removed := make(map[string]bool, len(f.Remove))
+	for _, field := range f.Remove {
+		if field == "" {
+			return fmt.Errorf("response_fields.remove: empty field name")
+		}
+		removed[field] = true
+	}
+	f.add = make(map[string]json.RawMessage, len(f.Add))
+	for field, value := range f.Add {
+		if field == "" {
+			return fmt.Errorf("response_fields.add: empty field name")
+		}
+		if removed[field] {
+			return fmt.Errorf("response_fields: %q is both removed and added", field)
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return fmt.Errorf("response_fields.add.%s: %w", field, err)
+		}
+		f.add[field] = encoded
+	}
+	return nil
+}
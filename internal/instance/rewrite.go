@@ -0,0 +1,161 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// PathRewrite rewrites the provider path of an instance's requests. Steps
+// run in order: strip_prefix, then the regex replacement, then add_prefix.
+type PathRewrite struct {
+	StripPrefix string `yaml:"strip_prefix,omitempty"`
+	AddPrefix   string `yaml:"add_prefix,omitempty"`
+
+	// Regex is matched against the path; matches are replaced with
+	// Replacement, which may reference capture groups as $1 or ${name}
+	Regex       string `yaml:"regex,omitempty"`
+	Replacement string `yaml:"replacement,omitempty"`
+
+	// regex is compiled by Validate
+	regex *regexp.Regexp
+}
+
+// Apply rewrites path. A nil rewrite returns path unchanged.
+func (p *PathRewrite) Apply(path string) string {
+	if p == nil {
+		return path
+	}
+	if p.StripPrefix != "" {
+		path = strings.TrimPrefix(path, p.StripPrefix)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	if p.regex != nil {
+		path = p.regex.ReplaceAllString(path, p.Replacement)
+	}
+	if p.AddPrefix != "" {
+		path = strings.TrimSuffix(p.AddPrefix, "/") + path
+	}
+	return path
+}
+
+// compile checks and compiles the regex
+func (p *PathRewrite) compile() error {
+	if p.Regex == "" {
+		if p.Replacement != "" {
+			return fmt.Errorf("path_rewrite.replacement requires path_rewrite.regex")
+		}
+		return nil
+	}
+	regex, err := regexp.Compile(p.Regex)
+	if err != nil {
+		return fmt.Errorf("invalid path_rewrite.regex %q: %w", p.Regex, err)
+	}
+	p.regex = regex
+	return nil
+}
+
+// managedHeaders are set by the gateway or the HTTP client and cannot be
+// injected through request_headers or response_headers
+var managedHeaders = map[string]bool{
+	"Authorization":        true,
+	"Proxy-Authorization":  true,
+	"X-Api-Key":            true,
+	"Api-Key":              true,
+	"X-Amz-Date":           true,
+	"X-Amz-Security-Token": true,
+	"X-Amz-Content-Sha256": true,
+	"Content-Length":       true,
+	"Transfer-Encoding":    true,
+	"Connection":           true,
+	"Host":                 true,
+	"X-Request-Id":         true,
+}
+
+// gatewayHeaders carry the caller's gateway credentials or are rewritten by
+// the gateway, so they are never forwarded to a provider
+var gatewayHeaders = map[string]bool{
+	"X-Session-Token":   true,
+	"X-Totp-Code":       true,
+	"X-Service-Account": true,
+	"X-Namespace":       true,
+	"X-Request-Tags":    true,
+}
+
+// validateHeaderRules checks request_headers and response_headers against
+// the managed headers, including the instance's own authentication header,
+// checks forward_headers against those and the gateway's own headers, and
+// compiles path_rewrite
+func (i *InstanceConfig) validateHeaderRules() error {
+	for field, headers := range map[string]map[string]string{
+		"request_headers":  i.RequestHeaders,
+		"response_headers": i.ResponseHeaders,
+	} {
+		for name := range headers {
+			if i.isManagedHeader(name) {
+				return fmt.Errorf("%s: %s is managed by the gateway and cannot be set", field, name)
+			}
+		}
+	}
+	for _, name := range i.ForwardHeaders {
+		prefix, wildcard := strings.CutSuffix(name, "*")
+		if prefix == "" || strings.Contains(prefix, "*") {
+			return fmt.Errorf("forward_headers: invalid header %q", name)
+		}
+		if !wildcard {
+			if i.isManagedHeader(name) || gatewayHeaders[http.CanonicalHeaderKey(name)] {
+				return fmt.Errorf("forward_headers: %s is managed by the gateway and cannot be forwarded", name)
+			}
+			continue
+		}
+		// A prefix must not match any header that cannot be forwarded
+		reserved := []string{i.Authentication.Header}
+		for header := range managedHeaders {
+			reserved = append(reserved, header)
+		}
+		for header := range gatewayHeaders {
+			reserved = append(reserved, header)
+		}
+		for _, header := range reserved {
+			if header != "" && i.matchesForwardHeader(name, header) {
+				return fmt.Errorf("forward_headers: %s matches %s, which is managed by the gateway", name, header)
+			}
+		}
+	}
+	if i.PathRewrite != nil {
+		return i.PathRewrite.compile()
+	}
+	return nil
+}
+
+// isManagedHeader reports whether a header is managed by the gateway or is
+// the instance's own authentication header
+func (i *InstanceConfig) isManagedHeader(name string) bool {
+	canonical := http.CanonicalHeaderKey(name)
+	return managedHeaders[canonical] || (i.Authentication.Header != "" && canonical == http.CanonicalHeaderKey(i.Authentication.Header))
+}
+
+// matchesForwardHeader reports whether a forward_headers entry matches a
+// header name, case-insensitively. An entry ending in "*" matches a prefix.
+func (i *InstanceConfig) matchesForwardHeader(entry, name string) bool {
+	if prefix, ok := strings.CutSuffix(entry, "*"); ok {
+		return len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix)
+	}
+	return strings.EqualFold(entry, name)
+}
+
+// ForwardsHeader reports whether a client header is in forward_headers
+func (i *InstanceConfig) ForwardsHeader(name string) bool {
+	for _, entry := range i.ForwardHeaders {
+		if i.matchesForwardHeader(entry, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,102 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// templateFuncs are the helpers available to request templates, in addition
+// to the text/template builtins
+var templateFuncs = template.FuncMap{
+	// json encodes a value as compact JSON: {{ json .messages }}
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	// default returns def when value is empty: {{ default 256 .max_tokens }}
+	"default": func(def, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return def
+		}
+		return value
+	},
+	// last returns the last element of a list: {{ (last .messages).content }}
+	"last": func(list []interface{}) interface{} {
+		if len(list) == 0 {
+			return nil
+		}
+		return list[len(list)-1]
+	},
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+// templateSample is the request templates are dry-run against by Validate
+var templateSample = &translator.ChatCompletionRequest{
+	Model:    "model",
+	Messages: []translator.ChatMessage{{Role: "user", Content: translator.TextContent("Hello")}},
+}
+
+// HasRequestTemplate reports whether the transformation renders request
+// bodies from a template
+func (t *TransformationConfig) HasRequestTemplate() bool {
+	return t != nil && t.requestTemplate != nil
+}
+
+// RenderRequest renders the provider request body from the request
+// template. The template's data is the OpenAI request as decoded JSON, so
+// fields use their wire names: {{ .model }}, {{ .max_tokens }}.
+func (t *TransformationConfig) RenderRequest(req *translator.ChatCompletionRequest) ([]byte, error) {
+	body, err := t.executeTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("request_template rendered invalid JSON: %s", body)
+	}
+	return body, nil
+}
+
+func (t *TransformationConfig) executeTemplate(req *translator.ChatCompletionRequest) ([]byte, error) {
+	encoded, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(encoded, &data); err != nil {
+		return nil, fmt.Errorf("failed to decode request: %w", err)
+	}
+
+	var body bytes.Buffer
+	if err := t.requestTemplate.Execute(&body, data); err != nil {
+		return nil, fmt.Errorf("request_template: %w", err)
+	}
+	return body.Bytes(), nil
+}
+
+// compileTemplate parses RequestTemplate and dry-runs it, so template
+// errors fail config loading rather than requests
+func (t *TransformationConfig) compileTemplate() error {
+	if t == nil || t.RequestTemplate == "" {
+		return nil
+	}
+	tmpl, err := template.New("request_template").Funcs(templateFuncs).Option("missingkey=zero").Parse(t.RequestTemplate)
+	if err != nil {
+		return fmt.Errorf("invalid request_template: %w", err)
+	}
+	t.requestTemplate = tmpl
+	if _, err := t.executeTemplate(templateSample); err != nil {
+		t.requestTemplate = nil
+		return fmt.Errorf("invalid %w", err)
+	}
+	return nil
+}
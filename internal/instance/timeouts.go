@@ -0,0 +1,68 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package instance
+
+import (
+	"fmt"
+	"time"
+)
+
+// HTTPTimeouts bound the requests sent to a provider. ConnectTimeout covers
+// dialing and the TLS handshake; RequestTimeout the whole request,
+// including reading the response. They are set globally and per instance;
+// instances inherit each unset timeout from the global one.
+type HTTPTimeouts struct {
+	ConnectTimeout string `yaml:"connect_timeout,omitempty"` // default 10s
+	RequestTimeout string `yaml:"request_timeout,omitempty"` // default 120s
+}
+
+// Durations returns the parsed connect and request timeouts, zero if unset
+func (t HTTPTimeouts) Durations() (connect, request time.Duration, err error) {
+	if connect, err = parseTimeout("connect_timeout", t.ConnectTimeout); err != nil {
+		return 0, 0, err
+	}
+	if request, err = parseTimeout("request_timeout", t.RequestTimeout); err != nil {
+		return 0, 0, err
+	}
+	return connect, request, nil
+}
+
+// inherit returns the timeouts with unset ones taken from global
+func (t HTTPTimeouts) inherit(global HTTPTimeouts) HTTPTimeouts {
+	if t.ConnectTimeout == "" {
+		t.ConnectTimeout = global.ConnectTimeout
+	}
+	if t.RequestTimeout == "" {
+		t.RequestTimeout = global.RequestTimeout
+	}
+	return t
+}
+
+func parseTimeout(field, value string) (time.Duration, error) {
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q: %w", field, value, err)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("%s cannot be negative", field)
+	}
+	return d, nil
+}
+
+// applyTimeouts takes the deprecated timeout as the request timeout,
+// inherits unset timeouts from global and checks them
+func (i *InstanceConfig) applyTimeouts(global HTTPTimeouts) error {
+	if _, err := i.TimeoutDuration(); err != nil {
+		return err
+	}
+	if i.RequestTimeout == "" {
+		i.RequestTimeout = i.Timeout
+	}
+	i.HTTPTimeouts = i.HTTPTimeouts.inherit(global)
+	_, _, err := i.HTTPTimeouts.Durations()
+	return err
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package jobs tracks asynchronous chat completion jobs whose results are
+// delivered to a client webhook.
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrJobNotFound is returned when a job ID is unknown or its retention has expired
+var ErrJobNotFound = errors.New("job not found")
+
+// Status is the state of a job
+type Status string
+
+const (
+	StatusQueued     Status = "queued"
+	StatusInProgress Status = "in_progress"
+	StatusCompleted  Status = "completed"
+	StatusFailed     Status = "failed"
+)
+
+// Job is the record of an asynchronous request
+type Job struct {
+	ID     string `json:"id"`
+	Status Status `json:"status"`
+	Model  string `json:"model"`
+
+	CreatedAt   int64 `json:"created_at"`
+	CompletedAt int64 `json:"completed_at,omitempty"`
+
+	// Result holds the response body once the job has completed
+	Result json.RawMessage `json:"result,omitempty"`
+
+	// Error holds the error response body if the job failed
+	Error json.RawMessage `json:"error,omitempty"`
+
+	// WebhookDelivered reports whether the webhook accepted the result;
+	// WebhookError holds the last delivery error otherwise
+	WebhookDelivered bool   `json:"webhook_delivered"`
+	WebhookError     string `json:"webhook_error,omitempty"`
+}
+
+// Done reports whether the job has finished
+func (j *Job) Done() bool {
+	return j.Status == StatusCompleted || j.Status == StatusFailed
+}
+
+// Store persists jobs
+type Store interface {
+	// Create stores a new job
+	Create(ctx context.Context, job *Job) error
+
+	// Get returns a job by ID, or ErrJobNotFound
+	Get(ctx context.Context, id string) (*Job, error)
+
+	// Update replaces an existing job
+	Update(ctx context.Context, job *Job) error
+}
+
+// MemoryStore is an in-memory Store. Finished jobs are kept for the
+// retention period; all jobs are lost on restart.
+type MemoryStore struct {
+	mu        sync.RWMutex
+	jobs      map[string]*Job
+	retention time.Duration
+	now       func() time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store keeping finished jobs for retention
+func NewMemoryStore(retention time.Duration) *MemoryStore {
+	return &MemoryStore{
+		jobs:      make(map[string]*Job),
+		retention: retention,
+		now:       time.Now,
+	}
+}
+
+// Create stores a new job, dropping finished jobs past their retention
+func (s *MemoryStore) Create(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, existing := range s.jobs {
+		if s.expired(existing) {
+			delete(s.jobs, id)
+		}
+	}
+
+	if _, exists := s.jobs[job.ID]; exists {
+		return errors.New("job already exists: " + job.ID)
+	}
+	copied := *job
+	s.jobs[job.ID] = &copied
+	return nil
+}
+
+// Get returns a copy of the job with the given ID
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok || s.expired(job) {
+		return nil, ErrJobNotFound
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// Update replaces an existing job
+func (s *MemoryStore) Update(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; !ok {
+		return ErrJobNotFound
+	}
+	copied := *job
+	s.jobs[job.ID] = &copied
+	return nil
+}
+
+// expired reports whether a finished job is past the retention period
+func (s *MemoryStore) expired(job *Job) bool {
+	if !job.Done() {
+		return false
+	}
+	return s.now().Sub(time.Unix(job.CompletedAt, 0)) > s.retention
+}
@@ -0,0 +1,31 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreRetention(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	s := NewMemoryStore(time.Hour)
+	s.now = func() time.Time { return now }
+
+	running := &Job{ID: "job_running", Status: StatusInProgress, CreatedAt: now.Unix()}
+	done := &Job{ID: "job_done", Status: StatusCompleted, CreatedAt: now.Unix(), CompletedAt: now.Unix()}
+	for _, job := range []*Job{running, done} {
+		if err := s.Create(ctx, job); err != nil {
+			t.Fatalf("Create(%s): %v", job.ID, err)
+		}
+	}
+
+	now = now.Add(2 * time.Hour)
+	if _, err := s.Get(ctx, "job_done"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("Get after retention: error = %v, want ErrJobNotFound", err)
+	}
+	if _, err := s.Get(ctx, "job_running"); err != nil {
+		t.Errorf("unfinished jobs do not expire: %v", err)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of the webhook body, as
+	// sha256=<hex>, keyed with the client's webhook secret
+	SignatureHeader = "X-Webhook-Signature"
+
+	// JobIDHeader carries the job ID on webhook deliveries
+	JobIDHeader = "X-Job-ID"
+)
+
+// Sign returns the signature header value for body
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// ValidateWebhookURL checks that raw is an absolute http or https URL
+func ValidateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("webhook URL must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// WebhookSender posts job results to client webhooks, retrying failures
+type WebhookSender struct {
+	client   *http.Client
+	attempts int
+	backoff  time.Duration
+}
+
+// NewWebhookSender creates a sender making up to 3 attempts per delivery
+func NewWebhookSender() *WebhookSender {
+	return &WebhookSender{
+		client:   &http.Client{Timeout: 30 * time.Second},
+		attempts: 3,
+		backoff:  time.Second,
+	}
+}
+
+// Send posts body to webhookURL, signed with secret. Any 2xx response is a
+// successful delivery; other responses and network errors are retried with
+// exponential backoff.
+func (s *WebhookSender) Send(ctx context.Context, webhookURL, secret, jobID string, body []byte) error {
+	signature := Sign(secret, body)
+	backoff := s.backoff
+
+	var lastErr error
+	for attempt := 1; attempt <= s.attempts; attempt++ {
+		if lastErr = s.post(ctx, webhookURL, signature, jobID, body); lastErr == nil {
+			return nil
+		}
+		if attempt == s.attempts {
+			break
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return fmt.Errorf("webhook delivery failed after %d attempts: %w", s.attempts, lastErr)
+}
+
+func (s *WebhookSender) post(ctx context.Context, webhookURL, signature, jobID string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, signature)
+	req.Header.Set(JobIDHeader, jobID)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
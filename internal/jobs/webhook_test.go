@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookSenderSignsAndRetries(t *testing.T) {
+	var attempts int
+	var gotBody []byte
+	var gotSignature, gotJobID string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(SignatureHeader)
+		gotJobID = r.Header.Get(JobIDHeader)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	s := NewWebhookSender()
+	s.backoff = time.Millisecond
+
+	body := []byte(`{"id":"chatcmpl-1"}`)
+	if err := s.Send(context.Background(), server.URL, "s3cret", "job_1", body); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if attempts != 2 {
+		t.Errorf("attempts = %d, want a retry after 503", attempts)
+	}
+	if string(gotBody) != string(body) || gotJobID != "job_1" {
+		t.Errorf("delivered %q for %q", gotBody, gotJobID)
+	}
+	// HMAC-SHA256("s3cret", body)
+	if want := Sign("s3cret", body); gotSignature != want || len(want) != len("sha256=")+64 {
+		t.Errorf("signature %q, want %q", gotSignature, want)
+	}
+}
+
+func TestValidateWebhookURL(t *testing.T) {
+	for raw, valid := range map[string]bool{
+		"https://hooks.example.com/ai": true,
+		"http://10.0.0.5:8080/cb":      true,
+		"ftp://example.com/x":          false,
+		"/relative":                    false,
+		"https://":                     false,
+	} {
+		if err := ValidateWebhookURL(raw); (err == nil) != valid {
+			t.Errorf("ValidateWebhookURL(%q) = %v, want valid=%v", raw, err, valid)
+		}
+	}
+}
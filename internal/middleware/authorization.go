@@ -4,12 +4,20 @@
 package middleware
 
 import (
+	"bytes"
 	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
 )
 
 // AuthConfig holds authorization configuration
@@ -172,17 +180,76 @@ func LoadAPIKeysFromSecret(secretPath string) (map[string]string, error) {
 
 // RateLimitByUser provides per-user rate limiting
 func RateLimitByUser(requestsPerMinute int) gin.HandlerFunc {
-	// This is a placeholder - implement with Redis or in-memory store
+	return RateLimit(ratelimit.NewLimiter(ratelimit.Config{
+		RequestsPerWindow: requestsPerMinute,
+		Window:            time.Minute,
+	}))
+}
+
+// RateLimit enforces per-key, per-model limits. The key is the caller's API
+// key (or authenticated user, or client IP); the model is read from the JSON
+// request body. Tokens reported by the handler are charged after the request.
+// The bucket state is returned in x-ratelimit-* response headers.
+func RateLimit(limiter *ratelimit.Limiter) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		user, exists := c.Get("user")
-		if !exists {
-			user = c.ClientIP()
-		}
+		key := rateLimitKey(c)
+		model := requestModel(c)
 
-		// TODO: Implement rate limiting logic
-		// Use Redis or in-memory cache to track requests per user
-		_ = user
+		status, allowed := limiter.Allow(key, model)
+		limiter.SetHeaders(c.Writer.Header(), status)
+		if !allowed {
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(time.Until(status.ResetsAt).Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+				"error": gin.H{
+					"message": fmt.Sprintf("Rate limit exceeded for model %s", model),
+					"type":    "rate_limit_error",
+					"code":    "rate_limit_exceeded",
+				},
+			})
+			return
+		}
 
 		c.Next()
+
+		if tokens, ok := c.Get(ratelimit.UsageTokensKey); ok {
+			if n, ok := tokens.(int); ok {
+				limiter.RecordTokens(key, model, n)
+			}
+		}
+	}
+}
+
+// rateLimitKey identifies the caller for rate limiting
+func rateLimitKey(c *gin.Context) string {
+	if apiKey := c.GetHeader("X-API-Key"); apiKey != "" {
+		return apiKey
+	}
+	if authHeader := c.GetHeader("Authorization"); strings.HasPrefix(authHeader, "Bearer ") {
+		return strings.TrimPrefix(authHeader, "Bearer ")
+	}
+	if user, ok := c.Get("user"); ok {
+		return fmt.Sprintf("user:%v", user)
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// requestModel peeks at the model field of a JSON request body, restoring the body
+func requestModel(c *gin.Context) string {
+	if c.Request.Body == nil || !strings.Contains(c.ContentType(), "json") {
+		return "*"
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "*"
+	}
+
+	var req struct {
+		Model string `json:"model"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.Model == "" {
+		return "*"
 	}
+	return req.Model
 }
@@ -0,0 +1,194 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfidenceScoreHeader carries the mean token probability of a response
+const ConfidenceScoreHeader = "X-Confidence-Score"
+
+// defaultLogprobsField is where OpenAI chat completions return token logprobs
+const defaultLogprobsField = "choices.0.logprobs.content"
+
+// ConfidenceConfig controls the X-Confidence-Score response header
+type ConfidenceConfig struct {
+	Enabled bool
+
+	// LogprobsField is the dot-separated path to the token logprobs in the
+	// JSON response; numeric segments index arrays. Entries are objects with
+	// a "logprob" number or plain numbers. Default: choices.0.logprobs.content
+	LogprobsField string
+}
+
+// LoadConfidenceConfigFromEnv reads CONFIDENCE_HEADER_ENABLED and
+// CONFIDENCE_LOGPROBS_FIELD
+func LoadConfidenceConfigFromEnv() ConfidenceConfig {
+	config := ConfidenceConfig{LogprobsField: os.Getenv("CONFIDENCE_LOGPROBS_FIELD")}
+	config.Enabled, _ = strconv.ParseBool(os.Getenv("CONFIDENCE_HEADER_ENABLED"))
+	return config
+}
+
+// ConfidenceHeader sets X-Confidence-Score on JSON responses that carry
+// token logprobs, computed as exp(mean(logprobs)): the geometric mean token
+// probability. JSON responses are buffered so the header can precede the
+// body; other responses (streams) pass through, as do responses without
+// logprobs.
+func ConfidenceHeader(config ConfidenceConfig) gin.HandlerFunc {
+	path := config.LogprobsField
+	if path == "" {
+		path = defaultLogprobsField
+	}
+	fields := strings.Split(path, ".")
+
+	return func(c *gin.Context) {
+		if !config.Enabled {
+			c.Next()
+			return
+		}
+
+		writer := &confidenceWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = writer
+		defer func() { c.Writer = writer.ResponseWriter }()
+
+		c.Next()
+
+		if !writer.buffering {
+			if !writer.decided {
+				writer.ResponseWriter.WriteHeader(writer.status)
+			}
+			return
+		}
+		if score, ok := confidenceScore(writer.body.Bytes(), fields); ok {
+			writer.Header().Set(ConfidenceScoreHeader, strconv.FormatFloat(score, 'f', 2, 64))
+		}
+		writer.ResponseWriter.WriteHeader(writer.status)
+		writer.ResponseWriter.Write(writer.body.Bytes())
+	}
+}
+
+// confidenceWriter buffers JSON responses and passes others through. The
+// choice is made on the first write, once the handler has set Content-Type.
+type confidenceWriter struct {
+	gin.ResponseWriter
+	status    int
+	decided   bool
+	buffering bool
+	body      bytes.Buffer
+}
+
+func (w *confidenceWriter) WriteHeader(code int) {
+	if !w.decided {
+		w.status = code
+		return
+	}
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *confidenceWriter) WriteHeaderNow() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *confidenceWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *confidenceWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Flush is a no-op while buffering
+func (w *confidenceWriter) Flush() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *confidenceWriter) Status() int {
+	if w.buffering || !w.decided {
+		return w.status
+	}
+	return w.ResponseWriter.Status()
+}
+
+func (w *confidenceWriter) Written() bool {
+	return w.decided
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController; streams
+// pass through, so their write deadlines apply to the connection
+func (w *confidenceWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// decide buffers JSON responses and passes others through
+func (w *confidenceWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+// confidenceScore returns exp(mean(logprobs)) of the logprobs at fields in
+// body. It reports false when the body is not JSON or has no logprobs.
+func confidenceScore(body []byte, fields []string) (float64, bool) {
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return 0, false
+	}
+	for _, field := range fields {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			value = v[field]
+		case []interface{}:
+			i, err := strconv.Atoi(field)
+			if err != nil || i < 0 || i >= len(v) {
+				return 0, false
+			}
+			value = v[i]
+		default:
+			return 0, false
+		}
+	}
+
+	entries, ok := value.([]interface{})
+	if !ok || len(entries) == 0 {
+		return 0, false
+	}
+	var sum float64
+	for _, entry := range entries {
+		if object, ok := entry.(map[string]interface{}); ok {
+			entry = object["logprob"]
+		}
+		logprob, ok := entry.(float64)
+		if !ok {
+			return 0, false
+		}
+		sum += logprob
+	}
+	return math.Exp(sum / float64(len(entries))), true
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestConfidenceHeader(t *testing.T) {
+	// ln(0.9) and ln(0.8): exp of their mean is sqrt(0.72) = 0.8485
+	const withLogprobs = `{"choices":[{"index":0,"message":{"role":"assistant","content":"spam"},"logprobs":{"content":[{"token":"sp","logprob":-0.10536051565782628},{"token":"am","logprob":-0.2231435513142097}]}}]}`
+
+	tests := []struct {
+		name        string
+		config      ConfidenceConfig
+		contentType string
+		body        string
+		want        string
+	}{
+		{"logprobs", ConfidenceConfig{Enabled: true}, "application/json; charset=utf-8", withLogprobs, "0.85"},
+		{"disabled", ConfidenceConfig{}, "application/json", withLogprobs, ""},
+		{"no logprobs", ConfidenceConfig{Enabled: true}, "application/json", `{"choices":[{"index":0,"message":{"content":"spam"}}]}`, ""},
+		{"null logprobs", ConfidenceConfig{Enabled: true}, "application/json", `{"choices":[{"index":0,"logprobs":null}]}`, ""},
+		{"empty logprobs", ConfidenceConfig{Enabled: true}, "application/json", `{"choices":[{"index":0,"logprobs":{"content":[]}}]}`, ""},
+		{"custom field", ConfidenceConfig{Enabled: true, LogprobsField: "result.logprobs"}, "application/json", `{"result":{"logprobs":[0,0]}}`, "1.00"},
+		{"not json", ConfidenceConfig{Enabled: true}, "text/plain", withLogprobs, ""},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.Use(ConfidenceHeader(tt.config))
+			engine.POST("/v1/chat/completions", func(c *gin.Context) {
+				c.Data(http.StatusCreated, tt.contentType, []byte(tt.body))
+			})
+
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil))
+
+			if got := w.Header().Get(ConfidenceScoreHeader); got != tt.want {
+				t.Errorf("%s = %q, want %q", ConfidenceScoreHeader, got, tt.want)
+			}
+			if w.Code != http.StatusCreated || w.Body.String() != tt.body {
+				t.Errorf("response = %d %q, want the handler's response unchanged", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+// TestConfidenceHeaderStream tests that streams are passed through unbuffered
+func TestConfidenceHeaderStream(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(ConfidenceHeader(ConfidenceConfig{Enabled: true}))
+	flushedBeforeEnd := false
+	engine.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Writer.WriteString("data: {}\n\n")
+		c.Writer.Flush()
+		flushedBeforeEnd = c.Writer.Written()
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stream", nil))
+	if !flushedBeforeEnd || !w.Flushed || w.Body.String() != "data: {}\n\n" {
+		t.Errorf("stream not passed through: flushed %v, body %q", w.Flushed, w.Body)
+	}
+}
+
+func TestConfidenceHeaderNoBody(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(ConfidenceHeader(ConfidenceConfig{Enabled: true}))
+	engine.GET("/empty", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/empty", nil))
+	if w.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want 204", w.Code)
+	}
+}
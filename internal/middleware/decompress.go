@@ -0,0 +1,92 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// DecompressedBytesKey is the gin context key holding the size of a
+// request body after decompression, for the request log
+const DecompressedBytesKey = "decompressed_bytes"
+
+// DefaultMaxRequestBytes bounds decompressed request bodies when
+// MAX_REQUEST_BYTES is not set
+const DefaultMaxRequestBytes = 32 << 20
+
+// requestDecoders open the request body encodings Decompress accepts
+var requestDecoders = map[string]func(io.Reader) (io.Reader, error){
+	"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+	"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+}
+
+// Decompress decodes gzip and Brotli (br) encoded request bodies, so the
+// handlers that parse bodies read them plain; routes that forward bodies
+// unchanged should not use it. Install it after authentication. Bodies that
+// decompress to more than maxBytes are rejected with 413, which bounds the
+// memory a small compressed body can claim; maxBytes <= 0 disables the
+// limit. Other encodings are rejected with 415.
+func Decompress(maxBytes int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		encoding := strings.ToLower(strings.TrimSpace(c.GetHeader("Content-Encoding")))
+		if encoding == "" || encoding == "identity" || c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		decoder, ok := requestDecoders[encoding]
+		if !ok {
+			abortDecompress(c, http.StatusUnsupportedMediaType, "unsupported_content_encoding",
+				fmt.Sprintf("Unsupported Content-Encoding %q: use gzip or br", encoding))
+			return
+		}
+
+		reader, err := decoder(c.Request.Body)
+		var body []byte
+		if err == nil {
+			if maxBytes > 0 {
+				reader = io.LimitReader(reader, maxBytes+1)
+			}
+			body, err = io.ReadAll(reader)
+		}
+		if err != nil {
+			abortDecompress(c, http.StatusBadRequest, "invalid_content_encoding",
+				fmt.Sprintf("Request body is not valid %s data", encoding))
+			return
+		}
+		if maxBytes > 0 && int64(len(body)) > maxBytes {
+			abortDecompress(c, http.StatusRequestEntityTooLarge, "request_too_large",
+				fmt.Sprintf("Request body decompresses to more than %d bytes", maxBytes))
+			return
+		}
+
+		c.Request.Body.Close()
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Request.ContentLength = int64(len(body))
+		c.Request.Header.Del("Content-Encoding")
+		c.Request.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		c.Set(DecompressedBytesKey, len(body))
+		c.Next()
+	}
+}
+
+// abortDecompress rejects a request whose body cannot be decompressed
+func abortDecompress(c *gin.Context, status int, code, message string) {
+	c.AbortWithStatusJSON(status, gin.H{
+		"error": gin.H{
+			"message": message,
+			"type":    "invalid_request_error",
+			"param":   nil,
+			"code":    code,
+		},
+	})
+}
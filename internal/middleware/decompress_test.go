@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+func brotliBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := brotli.NewWriter(&buf)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func gzipBytes(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	w.Write(data)
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecompress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const payload = `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+
+	var gotBody, gotEncoding string
+	var gotSize interface{}
+	engine := gin.New()
+	engine.Use(Decompress(1024))
+	engine.POST("/", func(c *gin.Context) {
+		body, _ := io.ReadAll(c.Request.Body)
+		gotBody = string(body)
+		gotEncoding = c.GetHeader("Content-Encoding")
+		gotSize, _ = c.Get(DecompressedBytesKey)
+		c.Status(http.StatusNoContent)
+	})
+	post := func(encoding string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+		if encoding != "" {
+			req.Header.Set("Content-Encoding", encoding)
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w
+	}
+
+	for _, tt := range []struct {
+		encoding string
+		body     []byte
+	}{
+		{"br", brotliBytes(t, []byte(payload))},
+		{"gzip", gzipBytes(t, []byte(payload))},
+		{"", []byte(payload)},
+	} {
+		gotBody, gotEncoding, gotSize = "", "", nil
+		if w := post(tt.encoding, tt.body); w.Code != http.StatusNoContent {
+			t.Fatalf("%q: status = %d: %s", tt.encoding, w.Code, w.Body)
+		}
+		if gotBody != payload || gotEncoding != "" {
+			t.Errorf("%q: handler got body %q with Content-Encoding %q", tt.encoding, gotBody, gotEncoding)
+		}
+		if tt.encoding != "" && gotSize != len(payload) {
+			t.Errorf("%q: %s = %v, want %d", tt.encoding, DecompressedBytesKey, gotSize, len(payload))
+		}
+	}
+
+	// A small body decompressing past the limit is a decompression bomb
+	bomb := brotliBytes(t, bytes.Repeat([]byte("a"), 1<<20))
+	if w := post("br", bomb); w.Code != http.StatusRequestEntityTooLarge || !strings.Contains(w.Body.String(), "request_too_large") {
+		t.Errorf("bomb: status = %d: %s", w.Code, w.Body)
+	}
+	if w := post("br", []byte("not brotli")); w.Code != http.StatusBadRequest {
+		t.Errorf("invalid br: status = %d", w.Code)
+	}
+	if w := post("zstd", []byte(payload)); w.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("zstd: status = %d", w.Code)
+	}
+}
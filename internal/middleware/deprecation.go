@@ -0,0 +1,72 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// Deprecation marks the routes under a prefix as deprecated. Each request
+// is counted in deprecated_endpoint_requests_total by route and the
+// identity set by the auth middleware, and gets Deprecation (RFC 9745) and
+// Sunset (RFC 8594) headers. After the sunset date, configs with
+// block_after_sunset refuse requests with 410 Gone.
+func Deprecation(route string, config instance.DeprecationConfig) gin.HandlerFunc {
+	return deprecation(route, config, time.Now)
+}
+
+func deprecation(route string, config instance.DeprecationConfig, now func() time.Time) gin.HandlerFunc {
+	deprecationHeader := "true"
+	if since := config.SinceTime(); !since.IsZero() {
+		deprecationHeader = fmt.Sprintf("@%d", since.Unix())
+	}
+	var sunsetHeader string
+	if sunset := config.SunsetTime(); !sunset.IsZero() {
+		sunsetHeader = sunset.UTC().Format(http.TimeFormat)
+	}
+
+	return func(c *gin.Context) {
+		metrics.DeprecatedEndpointRequests.WithLabelValues(route, requestIdentity(c)).Inc()
+
+		c.Header("Deprecation", deprecationHeader)
+		if sunsetHeader != "" {
+			c.Header("Sunset", sunsetHeader)
+		}
+		if config.Link != "" {
+			c.Header("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, config.Link))
+		}
+
+		if config.Blocked(now()) {
+			message := config.Message
+			if message == "" {
+				message = fmt.Sprintf("%s was removed on %s", route, config.Sunset)
+			}
+			c.AbortWithStatusJSON(http.StatusGone, gin.H{
+				"error": gin.H{
+					"message": message,
+					"type":    "invalid_request_error",
+					"param":   nil,
+					"code":    "endpoint_removed",
+				},
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requestIdentity returns the user set by the auth middleware, or
+// "anonymous"
+func requestIdentity(c *gin.Context) string {
+	if user := c.GetString("user"); user != "" {
+		return user
+	}
+	return "anonymous"
+}
@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+)
+
+func TestDeprecation(t *testing.T) {
+	config := instance.DeprecationConfig{
+		Since:            "2025-01-01",
+		Sunset:           "2025-06-30",
+		Link:             "https://docs.example.com/migrate",
+		Message:          "Use /v1/chat/completions",
+		BlockAfterSunset: true,
+	}
+	now := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/model/*path", deprecation("/model", config, func() time.Time { return now }), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/model/x/invoke", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("before sunset: status = %d", w.Code)
+	}
+	if got := w.Header().Get("Deprecation"); got != "@1735689600" {
+		t.Errorf("Deprecation = %q", got)
+	}
+	if got := w.Header().Get("Sunset"); got != "Mon, 30 Jun 2025 00:00:00 GMT" {
+		t.Errorf("Sunset = %q", got)
+	}
+	if got := w.Header().Get("Link"); got != `<https://docs.example.com/migrate>; rel="deprecation"` {
+		t.Errorf("Link = %q", got)
+	}
+
+	now = time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/model/x/invoke", nil))
+	if w.Code != http.StatusGone || !strings.Contains(w.Body.String(), "Use /v1/chat/completions") {
+		t.Errorf("after sunset: status = %d, body %s", w.Code, w.Body)
+	}
+	if w.Header().Get("Sunset") == "" {
+		t.Error("Sunset header missing on 410")
+	}
+}
+
+func TestDeprecationWithoutDates(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/bedrock/*path", Deprecation("/bedrock", instance.DeprecationConfig{}), func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/bedrock/foundation-models", nil))
+	if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "true" || w.Header().Get("Sunset") != "" {
+		t.Errorf("status = %d, headers %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// NormaliseHeaders canonicalises response header keys before they are
+// written, so headers copied from providers that spell them inconsistently
+// (content-type, CONTENT-TYPE) reach clients as Content-Type, once. See
+// providers.CanonicalizeHeaders for how duplicate spellings are resolved.
+func NormaliseHeaders() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		writer := &normalisingWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+		defer func() { c.Writer = writer.ResponseWriter }()
+
+		c.Next()
+
+		// Responses without a body are written by gin after the chain
+		writer.normalise()
+	}
+}
+
+// normalisingWriter canonicalises the header keys on the first write
+type normalisingWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *normalisingWriter) WriteHeaderNow() {
+	w.normalise()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *normalisingWriter) Write(data []byte) (int, error) {
+	w.normalise()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *normalisingWriter) WriteString(s string) (int, error) {
+	w.normalise()
+	return w.ResponseWriter.WriteString(s)
+}
+
+func (w *normalisingWriter) Flush() {
+	w.normalise()
+	w.ResponseWriter.Flush()
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (w *normalisingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *normalisingWriter) normalise() {
+	if !w.Written() {
+		providers.CanonicalizeHeaders(w.Header())
+	}
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestNormaliseHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(NormaliseHeaders())
+	engine.GET("/body", func(c *gin.Context) {
+		header := c.Writer.Header()
+		header["content-type"] = []string{"application/json"}
+		header["X-REQUEST-ID"] = []string{"req_1"}
+		c.Writer.WriteString(`{}`)
+	})
+	engine.GET("/empty", func(c *gin.Context) {
+		c.Writer.Header()["retry-after"] = []string{"3"}
+		c.Status(http.StatusTooManyRequests)
+	})
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/body", nil))
+	want := http.Header{"Content-Type": {"application/json"}, "X-Request-Id": {"req_1"}}
+	if got := w.Result().Header; !reflect.DeepEqual(got, want) {
+		t.Errorf("headers = %v, want %v", got, want)
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/empty", nil))
+	if got := w.Result().Header; !reflect.DeepEqual(got, http.Header{"Retry-After": {"3"}}) {
+		t.Errorf("headers without a body = %v, want Retry-After", got)
+	}
+}
+
+// TestWriterDeadlines tests that write deadlines can be set through the
+// writers the middleware wraps responses in, as streams need
+func TestWriterDeadlines(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(NormaliseHeaders(), ConfidenceHeader(ConfidenceConfig{Enabled: true}))
+	engine.GET("/stream", func(c *gin.Context) {
+		rc := http.NewResponseController(c.Writer)
+		if err := rc.SetWriteDeadline(time.Now().Add(time.Minute)); err != nil {
+			t.Errorf("SetWriteDeadline() error = %v", err)
+		}
+		c.Status(http.StatusNoContent)
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+}
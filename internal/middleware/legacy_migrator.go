@@ -0,0 +1,211 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// LegacyMigrator rewrites the deprecated function calling fields of chat
+// completion requests to their tool calling replacements, so handlers only
+// see the current spec:
+//
+//   - functions becomes tools, each function wrapped as a function tool
+//   - function_call becomes tool_choice
+//   - assistant messages' function_call becomes a tool call, and "function"
+//     role messages become "tool" messages answering it
+//
+// Each migrated field is counted in deprecated_field_used_total. Bodies
+// that are not JSON objects are passed on unchanged for the handler to
+// reject.
+func LegacyMigrator() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil {
+			c.Next()
+			return
+		}
+		body, err := io.ReadAll(c.Request.Body)
+		c.Request.Body.Close()
+		if err != nil {
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+			c.Next()
+			return
+		}
+
+		if migrated, fields := migrateLegacyChatRequest(body); len(fields) > 0 {
+			for _, field := range fields {
+				metrics.DeprecatedFieldUsed.WithLabelValues(field).Inc()
+			}
+			log.Printf("Migrated deprecated chat request fields %v for %s", fields, requestIdentity(c))
+			body = migrated
+			c.Request.ContentLength = int64(len(body))
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		c.Next()
+	}
+}
+
+// legacyFunctionCall is an assistant message's deprecated function_call
+type legacyFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+// migrateLegacyChatRequest returns the request with deprecated fields
+// rewritten, and the names of the fields migrated; none when the body is
+// left as is
+func migrateLegacyChatRequest(body []byte) ([]byte, []string) {
+	var req map[string]json.RawMessage
+	if err := json.Unmarshal(body, &req); err != nil {
+		return body, nil
+	}
+	var fields []string
+
+	if raw, ok := req["functions"]; ok {
+		var functions []json.RawMessage
+		if err := json.Unmarshal(raw, &functions); err != nil {
+			return body, nil
+		}
+		var tools []json.RawMessage
+		if existing, ok := req["tools"]; ok {
+			if err := json.Unmarshal(existing, &tools); err != nil {
+				return body, nil
+			}
+		}
+		for _, function := range functions {
+			tools = append(tools, mustMarshal(map[string]json.RawMessage{
+				"type":     json.RawMessage(`"function"`),
+				"function": function,
+			}))
+		}
+		req["tools"] = mustMarshal(tools)
+		delete(req, "functions")
+		fields = append(fields, "functions")
+	}
+
+	if raw, ok := req["function_call"]; ok {
+		if _, set := req["tool_choice"]; !set {
+			choice, err := legacyToolChoice(raw)
+			if err != nil {
+				return body, nil
+			}
+			req["tool_choice"] = choice
+		}
+		delete(req, "function_call")
+		fields = append(fields, "function_call")
+	}
+
+	if raw, ok := req["messages"]; ok {
+		messages, migrated, err := migrateLegacyMessages(raw)
+		if err != nil {
+			return body, nil
+		}
+		if len(migrated) > 0 {
+			req["messages"] = messages
+			fields = append(fields, migrated...)
+		}
+	}
+
+	if len(fields) == 0 {
+		return body, nil
+	}
+	return mustMarshal(req), fields
+}
+
+// legacyToolChoice maps a function_call ("none", "auto" or {"name": ...})
+// to the equivalent tool_choice
+func legacyToolChoice(raw json.RawMessage) (json.RawMessage, error) {
+	var mode string
+	if err := json.Unmarshal(raw, &mode); err == nil {
+		return raw, nil
+	}
+	var named struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal(raw, &named); err != nil || named.Name == "" {
+		return nil, fmt.Errorf("invalid function_call")
+	}
+	return mustMarshal(map[string]any{
+		"type":     "function",
+		"function": map[string]string{"name": named.Name},
+	}), nil
+}
+
+// migrateLegacyMessages turns assistant function calls into tool calls and
+// "function" messages into "tool" messages answering the call before them
+func migrateLegacyMessages(raw json.RawMessage) (json.RawMessage, []string, error) {
+	var messages []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &messages); err != nil {
+		return nil, nil, err
+	}
+
+	var fields []string
+	callID := ""
+	calls := 0
+	for _, msg := range messages {
+		if rawCall, ok := msg["function_call"]; ok {
+			var call legacyFunctionCall
+			if err := json.Unmarshal(rawCall, &call); err != nil {
+				return nil, nil, err
+			}
+			calls++
+			callID = fmt.Sprintf("call_legacy_%d", calls)
+			msg["tool_calls"] = mustMarshal([]map[string]any{{
+				"id":       callID,
+				"type":     "function",
+				"function": call,
+			}})
+			delete(msg, "function_call")
+			fields = appendOnce(fields, "messages.function_call")
+		}
+
+		var role string
+		if err := json.Unmarshal(msg["role"], &role); err != nil || role != "function" {
+			continue
+		}
+		msg["role"] = json.RawMessage(`"tool"`)
+		if _, ok := msg["tool_call_id"]; !ok {
+			id := callID
+			if id == "" {
+				// A function result without a preceding call is tied to
+				// its function's name
+				var name string
+				_ = json.Unmarshal(msg["name"], &name)
+				id = "call_legacy_" + name
+			}
+			msg["tool_call_id"] = mustMarshal(id)
+		}
+		fields = appendOnce(fields, "messages.role.function")
+	}
+
+	if len(fields) == 0 {
+		return raw, nil, nil
+	}
+	return mustMarshal(messages), fields, nil
+}
+
+func appendOnce(fields []string, field string) []string {
+	for _, f := range fields {
+		if f == field {
+			return fields
+		}
+	}
+	return append(fields, field)
+}
+
+// mustMarshal marshals values built from decoded JSON, which cannot fail
+func mustMarshal(v any) json.RawMessage {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
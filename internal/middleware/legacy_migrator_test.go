@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestLegacyMigrator(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var received []byte
+	engine := gin.New()
+	engine.POST("/v1/chat/completions", LegacyMigrator(), func(c *gin.Context) {
+		received, _ = io.ReadAll(c.Request.Body)
+		c.Status(http.StatusOK)
+	})
+	post := func(body string) map[string]any {
+		t.Helper()
+		received = nil
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+		var req map[string]any
+		if err := json.Unmarshal(received, &req); err != nil {
+			t.Fatalf("migrated body %q: %v", received, err)
+		}
+		return req
+	}
+
+	req := post(`{
+		"model": "gpt-4o",
+		"seed": 12345678901234567,
+		"functions": [{"name": "get_weather", "parameters": {"type": "object"}}],
+		"function_call": {"name": "get_weather"},
+		"messages": [
+			{"role": "user", "content": "Weather in Paris?"},
+			{"role": "assistant", "content": null, "function_call": {"name": "get_weather", "arguments": "{\"city\":\"Paris\"}"}},
+			{"role": "function", "name": "get_weather", "content": "sunny"}
+		]
+	}`)
+
+	if _, ok := req["functions"]; ok {
+		t.Error("functions not removed")
+	}
+	if _, ok := req["function_call"]; ok {
+		t.Error("function_call not removed")
+	}
+	tools, _ := req["tools"].([]any)
+	if len(tools) != 1 {
+		t.Fatalf("tools = %v", req["tools"])
+	}
+	tool := tools[0].(map[string]any)
+	if tool["type"] != "function" || tool["function"].(map[string]any)["name"] != "get_weather" {
+		t.Errorf("tool = %v", tool)
+	}
+	choice, _ := req["tool_choice"].(map[string]any)
+	if choice["type"] != "function" || choice["function"].(map[string]any)["name"] != "get_weather" {
+		t.Errorf("tool_choice = %v", req["tool_choice"])
+	}
+	if !strings.Contains(string(received), `"seed":12345678901234567`) {
+		t.Errorf("other fields must pass through unchanged: %s", received)
+	}
+
+	messages := req["messages"].([]any)
+	assistant := messages[1].(map[string]any)
+	calls, _ := assistant["tool_calls"].([]any)
+	if len(calls) != 1 || assistant["function_call"] != nil {
+		t.Fatalf("assistant message = %v", assistant)
+	}
+	call := calls[0].(map[string]any)
+	if call["function"].(map[string]any)["arguments"] != `{"city":"Paris"}` {
+		t.Errorf("tool call = %v", call)
+	}
+	result := messages[2].(map[string]any)
+	if result["role"] != "tool" || result["tool_call_id"] != call["id"] {
+		t.Errorf("function message = %v, want a tool message answering %v", result, call["id"])
+	}
+
+	if req := post(`{"function_call": "auto"}`); req["tool_choice"] != "auto" {
+		t.Errorf("tool_choice = %v, want auto", req["tool_choice"])
+	}
+
+	current := `{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`
+	post(current)
+	if string(received) != current {
+		t.Errorf("current request rewritten: %s", received)
+	}
+}
@@ -10,11 +10,21 @@ import (
 func Logger() gin.HandlerFunc {
 	return gin.LoggerWithFormatter(func(param gin.LogFormatterParams) string {
 		requestID := "unknown"
-		if id, exists := param.Keys["request_id"]; exists {
+		if id, exists := param.Keys[RequestIDKey]; exists {
 			requestID = fmt.Sprintf("%v", id)
 		}
+		correlation := ""
+		if id, exists := param.Keys[UpstreamRequestIDKey]; exists {
+			correlation = fmt.Sprintf(" upstream_request_id=%v", id)
+		}
+		if tags, ok := param.Keys[RequestTagsKey].(RequestTags); ok {
+			correlation += fmt.Sprintf(" tags=%s", tags)
+		}
+		if size, exists := param.Keys[DecompressedBytesKey]; exists {
+			correlation += fmt.Sprintf(" decompressed_bytes=%v", size)
+		}
 
-		return fmt.Sprintf("[%s] %s %s %s %d %s \"%s\" %s \"%s\" request_id=%v\n",
+		return fmt.Sprintf("[%s] %s %s %s %d %s \"%s\" %s \"%s\" request_id=%v%s\n",
 			param.TimeStamp.Format("2006/01/02 - 15:04:05"),
 			param.Method,
 			param.Path,
@@ -25,6 +35,7 @@ func Logger() gin.HandlerFunc {
 			param.ErrorMessage,
 			param.Request.Referer(),
 			requestID,
+			correlation,
 		)
 	})
 }
@@ -4,6 +4,15 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -27,16 +36,144 @@ func Security() gin.HandlerFunc {
 	}
 }
 
-// CORS handles Cross-Origin Resource Sharing
-func CORS() gin.HandlerFunc {
+// CORSConfig lists the origins allowed to call the gateway from a browser.
+// It is shared by the CORS middleware and the WebSocket origin check.
+type CORSConfig struct {
+	AllowedOrigins   []string // "*" allows any origin; empty means same-origin only
+	AllowedHeaders   []string // request headers allowed in preflight; defaults to defaultCORSHeaders
+	MaxAge           int      // seconds browsers may cache a preflight; defaults to 86400
+	AllowCredentials bool     // send Access-Control-Allow-Credentials; "*" is then not honored
+}
+
+// defaultCORSHeaders are the request headers allowed when AllowedHeaders is empty
+var defaultCORSHeaders = []string{"Content-Type", "Authorization", "X-Amz-Date", "X-Amz-Security-Token"}
+
+// defaultCORSMaxAge is the preflight cache lifetime when MaxAge is zero
+const defaultCORSMaxAge = 86400
+
+// LoadCORSConfigFromEnv reads CORS_ALLOWED_ORIGINS and CORS_ALLOWED_HEADERS
+// (comma-separated), CORS_MAX_AGE (seconds) and CORS_ALLOW_CREDENTIALS
+func LoadCORSConfigFromEnv() (CORSConfig, error) {
+	var config CORSConfig
+	for _, origin := range strings.Split(os.Getenv("CORS_ALLOWED_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			config.AllowedOrigins = append(config.AllowedOrigins, strings.TrimSuffix(origin, "/"))
+		}
+	}
+	for _, header := range strings.Split(os.Getenv("CORS_ALLOWED_HEADERS"), ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			config.AllowedHeaders = append(config.AllowedHeaders, header)
+		}
+	}
+	if value := os.Getenv("CORS_MAX_AGE"); value != "" {
+		maxAge, err := strconv.Atoi(value)
+		if err != nil || maxAge < 0 {
+			return CORSConfig{}, fmt.Errorf("invalid CORS_MAX_AGE %q", value)
+		}
+		config.MaxAge = maxAge
+	}
+	config.AllowCredentials = os.Getenv("CORS_ALLOW_CREDENTIALS") == "true"
+	return config, config.Validate()
+}
+
+// Validate rejects configurations browsers would refuse: a "*" origin
+// cannot be combined with credentials
+func (c CORSConfig) Validate() error {
+	if c.AllowCredentials && c.allowsWildcard() {
+		return fmt.Errorf("CORS allowed origin \"*\" cannot be used with credentials")
+	}
+	return nil
+}
+
+func (c CORSConfig) allowsWildcard() bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOriginAllowed reports whether origin is on the allowlist. With no
+// allowlist only same-origin requests (Origin host equal to host) are allowed.
+// "*" is ignored when credentials are allowed.
+func (c CORSConfig) IsOriginAllowed(origin, host string) bool {
+	if len(c.AllowedOrigins) == 0 {
+		u, err := url.Parse(origin)
+		return err == nil && strings.EqualFold(u.Host, host)
+	}
+	for _, allowed := range c.AllowedOrigins {
+		if (allowed == "*" && !c.AllowCredentials) || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckOrigin returns a function for a WebSocket Upgrader's CheckOrigin.
+// Requests without an Origin header are not from browsers and are allowed.
+func CheckOrigin(config CORSConfig) func(r *http.Request) bool {
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		return origin == "" || config.IsOriginAllowed(origin, r.Host)
+	}
+}
+
+// WebSocketOriginGuard rejects WebSocket upgrade requests from origins that
+// are not allowed with 403, before the handler performs the upgrade
+func WebSocketOriginGuard(config CORSConfig) gin.HandlerFunc {
+	checkOrigin := CheckOrigin(config)
 	return func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Amz-Date, X-Amz-Security-Token")
-		c.Header("Access-Control-Max-Age", "86400")
+		if strings.EqualFold(c.GetHeader("Upgrade"), "websocket") && !checkOrigin(c.Request) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{
+				"error": "Origin not allowed",
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// CORS handles Cross-Origin Resource Sharing for the configured origins.
+// Allowed origins are echoed in Access-Control-Allow-Origin; OPTIONS
+// requests are answered with 204 and the preflight headers, passing through
+// only to OPTIONS routes registered with RegisterOptionsRoutes, which add
+// the route's own methods.
+func CORS(config CORSConfig) gin.HandlerFunc {
+	allowHeaders := config.AllowedHeaders
+	if len(allowHeaders) == 0 {
+		allowHeaders = defaultCORSHeaders
+	}
+	maxAge := config.MaxAge
+	if maxAge == 0 {
+		maxAge = defaultCORSMaxAge
+	}
+
+	return func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Origin")
+		origin := c.GetHeader("Origin")
+		allowed := origin != "" && config.IsOriginAllowed(origin, c.Request.Host)
+		if allowed {
+			c.Header("Access-Control-Allow-Origin", origin)
+			if config.AllowCredentials {
+				c.Header("Access-Control-Allow-Credentials", "true")
+			}
+		}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if c.Request.Method == http.MethodOptions {
+			if allowed {
+				c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
+				c.Header("Access-Control-Allow-Headers", strings.Join(allowHeaders, ", "))
+				c.Header("Access-Control-Max-Age", strconv.Itoa(maxAge))
+			}
+			if c.HandlerName() == allowMethodsName {
+				// An OPTIONS route of RegisterOptionsRoutes answers with
+				// its methods; other routes, such as those registered with
+				// Any behind authentication, are not run for preflights
+				c.Next()
+				return
+			}
+			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
 
@@ -44,20 +181,143 @@ func CORS() gin.HandlerFunc {
 	}
 }
 
+// RegisterOptionsRoutes answers OPTIONS for every route under prefix with
+// 204 and an Allow header listing the methods registered for its path. The
+// OPTIONS routes are registered on the engine, outside any route group, so
+// preflight requests do not pass through authentication. Call it after the
+// routes under prefix are registered.
+func RegisterOptionsRoutes(engine *gin.Engine, prefix string) {
+	methods := make(map[string][]string)
+	hasOptions := make(map[string]bool)
+	var paths []string
+	for _, route := range engine.Routes() {
+		if !strings.HasPrefix(route.Path, prefix) {
+			continue
+		}
+		if route.Method == http.MethodOptions {
+			// Routes registered with Any handle OPTIONS themselves
+			hasOptions[route.Path] = true
+			continue
+		}
+		if _, ok := methods[route.Path]; !ok {
+			paths = append(paths, route.Path)
+		}
+		methods[route.Path] = append(methods[route.Path], route.Method)
+	}
+	for _, path := range paths {
+		if !hasOptions[path] {
+			engine.OPTIONS(path, allowMethods(methods[path]))
+		}
+	}
+}
+
+// allowMethodsName is the handler name of the OPTIONS routes registered by
+// RegisterOptionsRoutes, which CORS lets preflight requests through to
+var allowMethodsName = runtime.FuncForPC(reflect.ValueOf(allowMethods(nil)).Pointer()).Name()
+
+// allowMethods answers OPTIONS with 204 and the allowed methods
+func allowMethods(methods []string) gin.HandlerFunc {
+	sorted := append([]string(nil), methods...)
+	sorted = append(sorted, http.MethodOptions)
+	sort.Strings(sorted)
+	allow := strings.Join(sorted, ", ")
+
+	return func(c *gin.Context) {
+		c.Header("Allow", allow)
+		if c.Writer.Header().Get("Access-Control-Allow-Origin") != "" {
+			c.Header("Access-Control-Allow-Methods", allow)
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// RequestIDKey is the gin context key holding the request ID
+const RequestIDKey = "request_id"
+
+// UpstreamRequestIDKey is the gin context key holding the provider's own
+// request ID for the call, when it returned one
+const UpstreamRequestIDKey = "upstream_request_id"
+
+// maxRequestIDLength bounds inbound request IDs
+const maxRequestIDLength = 128
+
+// RequestIDConfig lists the networks (load balancers, API gateways) whose
+// X-Request-ID is reused instead of generating a new one
+type RequestIDConfig struct {
+	TrustedNetworks []*net.IPNet
+}
+
+// LoadRequestIDConfigFromEnv reads REQUEST_ID_TRUSTED_CIDRS (comma-separated)
+func LoadRequestIDConfigFromEnv() (RequestIDConfig, error) {
+	var config RequestIDConfig
+	for _, cidr := range strings.Split(os.Getenv("REQUEST_ID_TRUSTED_CIDRS"), ",") {
+		if cidr = strings.TrimSpace(cidr); cidr == "" {
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return RequestIDConfig{}, fmt.Errorf("invalid REQUEST_ID_TRUSTED_CIDRS entry %q: %w", cidr, err)
+		}
+		config.TrustedNetworks = append(config.TrustedNetworks, network)
+	}
+	return config, nil
+}
+
+// isTrusted reports whether a client at remoteIP may supply its own request ID
+func (c RequestIDConfig) isTrusted(remoteIP string) bool {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+	for _, network := range c.TrustedNetworks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
 // RequestID adds a unique request ID to each request
 func RequestID() gin.HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+// RequestIDWithConfig adds a request ID to each request, reusing a
+// well-formed inbound X-Request-ID from trusted networks. The ID is returned
+// in the X-Request-ID response header and stored under RequestIDKey.
+func RequestIDWithConfig(config RequestIDConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		requestID := generateRequestID()
+		requestID := c.GetHeader("X-Request-ID")
+		if !validRequestID(requestID) || !config.isTrusted(c.RemoteIP()) {
+			requestID = generateRequestID()
+		}
 		c.Header("X-Request-ID", requestID)
-		c.Set("request_id", requestID)
+		c.Set(RequestIDKey, requestID)
 		c.Next()
 	}
 }
 
+// validRequestID reports whether an inbound request ID is safe to log and
+// forward: non-empty, bounded and limited to URL-safe characters
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
 // Recovery provides panic recovery with proper logging
 func Recovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
-		requestID, _ := c.Get("request_id")
+		requestID, _ := c.Get(RequestIDKey)
 
 		gin.DefaultErrorWriter.Write([]byte(fmt.Sprintf(
 			"[PANIC] %s - Request ID: %v - Error: %v\n",
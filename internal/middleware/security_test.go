@@ -0,0 +1,251 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestWebSocketOriginGuard(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     CORSConfig
+		origin     string
+		wantStatus int
+	}{
+		{"same origin without allowlist", CORSConfig{}, "https://gateway.example.com", http.StatusOK},
+		{"cross origin without allowlist", CORSConfig{}, "https://evil.example.com", http.StatusForbidden},
+		{"no origin header", CORSConfig{}, "", http.StatusOK},
+		{"listed origin", CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}, "https://app.example.com", http.StatusOK},
+		{"unlisted origin", CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}, "https://gateway.example.com", http.StatusForbidden},
+		{"wildcard", CORSConfig{AllowedOrigins: []string{"*"}}, "https://evil.example.com", http.StatusOK},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			engine := gin.New()
+			engine.GET("/ws", WebSocketOriginGuard(tt.config), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "http://gateway.example.com/ws", nil)
+			req.Header.Set("Connection", "Upgrade")
+			req.Header.Set("Upgrade", "websocket")
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status: got %d, want %d", w.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestLoadCORSConfigFromEnv(t *testing.T) {
+	t.Setenv("CORS_ALLOWED_ORIGINS", "https://a.example.com/, https://b.example.com")
+	t.Setenv("CORS_ALLOWED_HEADERS", "Content-Type, X-Request-ID")
+	t.Setenv("CORS_MAX_AGE", "600")
+	config, err := LoadCORSConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadCORSConfigFromEnv: %v", err)
+	}
+	if len(config.AllowedOrigins) != 2 || config.AllowedOrigins[0] != "https://a.example.com" {
+		t.Errorf("unexpected origins: %v", config.AllowedOrigins)
+	}
+	if len(config.AllowedHeaders) != 2 || config.MaxAge != 600 || config.AllowCredentials {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	t.Setenv("CORS_ALLOWED_ORIGINS", "*")
+	t.Setenv("CORS_ALLOW_CREDENTIALS", "true")
+	if _, err := LoadCORSConfigFromEnv(); err == nil {
+		t.Error("expected error for wildcard origin with credentials")
+	}
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		config      CORSConfig
+		method      string
+		origin      string
+		wantStatus  int
+		wantOrigin  string
+		wantHeaders string
+	}{
+		{"preflight allowed", CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}, http.MethodOptions, "https://app.example.com", http.StatusNoContent, "https://app.example.com", "Content-Type, Authorization, X-Amz-Date, X-Amz-Security-Token"},
+		{"preflight custom headers", CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowedHeaders: []string{"X-Api-Key"}}, http.MethodOptions, "https://app.example.com", http.StatusNoContent, "https://app.example.com", "X-Api-Key"},
+		{"preflight rejected", CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}, http.MethodOptions, "https://evil.example.com", http.StatusNoContent, "", ""},
+		{"request allowed", CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}, http.MethodPost, "https://app.example.com", http.StatusOK, "https://app.example.com", ""},
+		{"request rejected", CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}, http.MethodPost, "https://evil.example.com", http.StatusOK, "", ""},
+		{"wildcard", CORSConfig{AllowedOrigins: []string{"*"}}, http.MethodPost, "https://any.example.com", http.StatusOK, "https://any.example.com", ""},
+		{"wildcard ignored with credentials", CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}, http.MethodPost, "https://any.example.com", http.StatusOK, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CORS(tt.config))
+			router.POST("/v1/chat/completions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(tt.method, "/v1/chat/completions", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Headers"); got != tt.wantHeaders {
+				t.Errorf("Access-Control-Allow-Headers = %q, want %q", got, tt.wantHeaders)
+			}
+		})
+	}
+}
+
+func TestCORSCredentials(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}, AllowCredentials: true, MaxAge: 600}))
+	router.POST("/v1/chat/completions", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/chat/completions", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	if got := w.Header().Get("Access-Control-Allow-Credentials"); got != "true" {
+		t.Errorf("Access-Control-Allow-Credentials = %q, want true", got)
+	}
+	if got := w.Header().Get("Access-Control-Max-Age"); got != "600" {
+		t.Errorf("Access-Control-Max-Age = %q, want 600", got)
+	}
+}
+
+func TestRequestIDWithConfig(t *testing.T) {
+	t.Setenv("REQUEST_ID_TRUSTED_CIDRS", "10.0.0.0/8, 192.0.2.1/32")
+	config, err := LoadRequestIDConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadRequestIDConfigFromEnv: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		inbound    string
+		wantReused bool
+	}{
+		{"trusted network", "10.1.2.3:5000", "lb-7f3a", true},
+		{"untrusted network", "203.0.113.9:5000", "lb-7f3a", false},
+		{"trusted but malformed", "192.0.2.1:5000", "bad id\twith tab", false},
+		{"trusted without header", "10.1.2.3:5000", "", false},
+	}
+
+	gin.SetMode(gin.TestMode)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stored string
+			engine := gin.New()
+			engine.GET("/", RequestIDWithConfig(config), func(c *gin.Context) {
+				stored = c.GetString(RequestIDKey)
+				c.Status(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.inbound != "" {
+				req.Header.Set("X-Request-ID", tt.inbound)
+			}
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+
+			got := w.Header().Get("X-Request-ID")
+			if got == "" || got != stored {
+				t.Fatalf("X-Request-ID %q, context %q", got, stored)
+			}
+			if reused := got == tt.inbound; reused != tt.wantReused {
+				t.Errorf("X-Request-ID = %q, want reused=%v", got, tt.wantReused)
+			}
+		})
+	}
+
+	t.Setenv("REQUEST_ID_TRUSTED_CIDRS", "10.0.0.0")
+	if _, err := LoadRequestIDConfigFromEnv(); err == nil {
+		t.Error("invalid CIDR accepted")
+	}
+}
+
+func TestRegisterOptionsRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}))
+	v1 := router.Group("/v1")
+	v1.Use(func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	})
+	v1.POST("/chat/completions", func(c *gin.Context) { c.Status(http.StatusOK) })
+	v1.GET("/files/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	v1.DELETE("/files/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+	v1.Any("/bedrock/*path", func(c *gin.Context) { c.Status(http.StatusOK) })
+	RegisterOptionsRoutes(router, "/v1/")
+
+	for path, want := range map[string]string{
+		"/v1/chat/completions": "OPTIONS, POST",
+		"/v1/files/file-1":     "DELETE, GET, OPTIONS",
+	} {
+		req := httptest.NewRequest(http.MethodOptions, path, nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		if w.Code != http.StatusNoContent {
+			t.Errorf("%s: status = %d, want 204 without authentication", path, w.Code)
+		}
+		if got := w.Header().Get("Allow"); got != want {
+			t.Errorf("%s: Allow = %q, want %q", path, got, want)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Methods"); got != want {
+			t.Errorf("%s: Access-Control-Allow-Methods = %q, want %q", path, got, want)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+			t.Errorf("%s: Access-Control-Allow-Origin = %q", path, got)
+		}
+	}
+
+	// Preflights to routes registered with Any are answered by CORS rather
+	// than running the route's authentication
+	req := httptest.NewRequest(http.MethodOptions, "/v1/bedrock/model/m/invoke", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		w.Header().Get("Access-Control-Allow-Methods") == "" {
+		t.Errorf("Any route preflight: status = %d, headers %v, want 204 with the preflight headers", w.Code, w.Header())
+	}
+
+	// Without CORS configured, OPTIONS still answers with Allow
+	router = gin.New()
+	router.POST("/v1/chat/completions", func(c *gin.Context) { c.Status(http.StatusOK) })
+	RegisterOptionsRoutes(router, "/v1/")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodOptions, "/v1/chat/completions", nil))
+	if w.Code != http.StatusNoContent || w.Header().Get("Allow") != "OPTIONS, POST" || w.Header().Get("Access-Control-Allow-Methods") != "" {
+		t.Errorf("status = %d, headers %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,183 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package middleware
+
+import (
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// RequestTagsHeader carries comma-separated key=value correlation tags
+const RequestTagsHeader = "X-Request-Tags"
+
+// RequestTagsKey is the gin context key holding the parsed RequestTags
+const RequestTagsKey = "request_tags"
+
+// ForwardRequestTagsKey is set in the gin context when tags should be
+// forwarded to providers
+const ForwardRequestTagsKey = "forward_request_tags"
+
+const (
+	maxRequestTags         = 10
+	maxTagKeyLength        = 32
+	maxTagValueLength      = 64
+	defaultMaxMetricValues = 20
+)
+
+// otherTagValue replaces tag values beyond the metric cardinality bound
+const otherTagValue = "other"
+
+// RequestTags are the sanitized tags of a request
+type RequestTags map[string]string
+
+// String formats tags as a sorted key=value list, the header format
+func (t RequestTags) String() string {
+	keys := make([]string, 0, len(t))
+	for key := range t {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + t[key]
+	}
+	return strings.Join(pairs, ",")
+}
+
+// ParseRequestTags parses an X-Request-Tags header. Keys are lowercased.
+// Pairs with an invalid or overlong key or value are dropped, as are pairs
+// beyond the first 10 distinct keys; for a repeated key the last value wins.
+func ParseRequestTags(header string) RequestTags {
+	tags := make(RequestTags)
+	for _, pair := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+		if !validTag(key, maxTagKeyLength) || !validTag(value, maxTagValueLength) {
+			continue
+		}
+		if _, exists := tags[key]; !exists && len(tags) >= maxRequestTags {
+			continue
+		}
+		tags[key] = value
+	}
+	return tags
+}
+
+// validTag reports whether s is a non-empty tag key or value of at most
+// maxLength characters from [A-Za-z0-9_.:/@-]
+func validTag(s string, maxLength int) bool {
+	if s == "" || len(s) > maxLength {
+		return false
+	}
+	for _, r := range s {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case strings.ContainsRune("_.:/@-", r):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// RequestTagsConfig controls how request tags are recorded and forwarded
+type RequestTagsConfig struct {
+	// MetricKeys are the tag keys recorded in gateway_requests_by_tag_total.
+	// Other keys appear only in logs.
+	MetricKeys []string
+
+	// MaxMetricValues bounds the distinct values recorded per metric key;
+	// further values are recorded as "other"
+	MaxMetricValues int
+
+	// Forward sends the sanitized tags to providers as X-Request-Tags
+	Forward bool
+}
+
+// LoadRequestTagsConfigFromEnv reads REQUEST_TAG_METRIC_KEYS
+// (comma-separated), REQUEST_TAG_METRIC_MAX_VALUES and REQUEST_TAGS_FORWARD
+func LoadRequestTagsConfigFromEnv() RequestTagsConfig {
+	config := RequestTagsConfig{MaxMetricValues: defaultMaxMetricValues}
+	for _, key := range strings.Split(os.Getenv("REQUEST_TAG_METRIC_KEYS"), ",") {
+		if key = strings.ToLower(strings.TrimSpace(key)); key != "" {
+			config.MetricKeys = append(config.MetricKeys, key)
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("REQUEST_TAG_METRIC_MAX_VALUES")); err == nil && n > 0 {
+		config.MaxMetricValues = n
+	}
+	config.Forward, _ = strconv.ParseBool(os.Getenv("REQUEST_TAGS_FORWARD"))
+	return config
+}
+
+// RequestTagging parses X-Request-Tags into the request context for logging,
+// metrics and provider forwarding
+func RequestTagging(config RequestTagsConfig) gin.HandlerFunc {
+	values := newTagValueSet(config.MetricKeys, config.MaxMetricValues)
+	return func(c *gin.Context) {
+		if header := c.GetHeader(RequestTagsHeader); header != "" {
+			if tags := ParseRequestTags(header); len(tags) > 0 {
+				c.Set(RequestTagsKey, tags)
+				for key, value := range tags {
+					if label, ok := values.label(key, value); ok {
+						metrics.RequestsByTag.WithLabelValues(key, label).Inc()
+					}
+				}
+			}
+		}
+		if config.Forward {
+			c.Set(ForwardRequestTagsKey, true)
+		}
+		c.Next()
+	}
+}
+
+// tagValueSet bounds metric label cardinality per tag key
+type tagValueSet struct {
+	mu        sync.Mutex
+	maxValues int
+	seen      map[string]map[string]struct{}
+}
+
+func newTagValueSet(keys []string, maxValues int) *tagValueSet {
+	if maxValues <= 0 {
+		maxValues = defaultMaxMetricValues
+	}
+	s := &tagValueSet{maxValues: maxValues, seen: make(map[string]map[string]struct{})}
+	for _, key := range keys {
+		s.seen[key] = make(map[string]struct{})
+	}
+	return s
+}
+
+// label returns the metric label for a tag value, or false if key is not a
+// metric key. Values beyond the bound map to "other".
+func (s *tagValueSet) label(key, value string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen, ok := s.seen[key]
+	if !ok {
+		return "", false
+	}
+	if _, exists := seen[value]; exists {
+		return value, true
+	}
+	if len(seen) >= s.maxValues {
+		return otherTagValue, true
+	}
+	seen[value] = struct{}{}
+	return value, true
+}
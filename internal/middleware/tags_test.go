@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseRequestTags(t *testing.T) {
+	got := ParseRequestTags(" Team=search, env=prod ,bad key=x, empty=, novalue, user=a@b.com, team=ranking, note=has space")
+	want := RequestTags{"team": "ranking", "env": "prod", "user": "a@b.com"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseRequestTags = %v, want %v", got, want)
+	}
+	if got.String() != "env=prod,team=ranking,user=a@b.com" {
+		t.Errorf("String() = %q", got.String())
+	}
+
+	var many []string
+	for i := 0; i < 15; i++ {
+		many = append(many, "k"+strings.Repeat("x", i)+"=v")
+	}
+	if n := len(ParseRequestTags(strings.Join(many, ","))); n != maxRequestTags {
+		t.Errorf("parsed %d tags, want at most %d", n, maxRequestTags)
+	}
+	if len(ParseRequestTags("k="+strings.Repeat("v", maxTagValueLength+1))) != 0 {
+		t.Error("overlong value accepted")
+	}
+}
+
+func TestRequestTagging(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var tags RequestTags
+	var forward bool
+	engine.GET("/", RequestTagging(RequestTagsConfig{Forward: true}), func(c *gin.Context) {
+		value, _ := c.Get(RequestTagsKey)
+		tags, _ = value.(RequestTags)
+		forward = c.GetBool(ForwardRequestTagsKey)
+		c.Status(http.StatusOK)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(RequestTagsHeader, "tenant=acme,trace=xyz")
+	engine.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !reflect.DeepEqual(tags, RequestTags{"tenant": "acme", "trace": "xyz"}) || !forward {
+		t.Errorf("context tags %v, forward %v", tags, forward)
+	}
+}
+
+func TestTagValueSetBounded(t *testing.T) {
+	s := newTagValueSet([]string{"tenant"}, 2)
+
+	for _, tt := range []struct{ value, want string }{
+		{"a", "a"}, {"b", "b"}, {"c", "other"}, {"a", "a"}, {"d", "other"},
+	} {
+		if got, ok := s.label("tenant", tt.value); !ok || got != tt.want {
+			t.Errorf("label(tenant, %s) = %q, %v; want %q", tt.value, got, ok, tt.want)
+		}
+	}
+	if _, ok := s.label("trace", "xyz"); ok {
+		t.Error("non-metric key labelled")
+	}
+}
@@ -0,0 +1,165 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package notify
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config configures webhook notifications
+type Config struct {
+	// URLs are the webhooks every event is posted to
+	URLs []string
+
+	// Secret, if set, signs payloads in SignatureHeader
+	Secret string
+
+	// Template is a text/template rendering an Event as the payload, with
+	// a json function for quoting values. Empty posts the event as JSON.
+	Template string
+
+	// Events are the event types to send; empty sends all
+	Events []EventType
+
+	// Cooldown is the minimum time between events of one type for one
+	// subject and state (default: 5m)
+	Cooldown time.Duration
+
+	// QueueSize bounds the events waiting for delivery (default: 100)
+	QueueSize int
+
+	// MaxRetries is the number of retries of a failed delivery (default: 3)
+	MaxRetries int
+
+	// RetryBackoff is the wait before the first retry, doubling after each
+	// (default: 1s)
+	RetryBackoff time.Duration
+
+	// Timeout bounds each delivery attempt (default: 5s)
+	Timeout time.Duration
+}
+
+// knownEvents are the event types that can be subscribed to
+var knownEvents = map[EventType]bool{
+	EventCircuitOpen:        true,
+	EventCircuitClose:       true,
+	EventHealthTransition:   true,
+	EventConfigReloadFailed: true,
+	EventQuotaExhausted:     true,
+	EventBudgetThreshold:    true,
+	EventBudgetExhausted:    true,
+}
+
+// Enabled reports whether any webhook is configured
+func (c Config) Enabled() bool {
+	return len(c.URLs) > 0
+}
+
+// Validate checks the webhook URLs and event types
+func (c Config) Validate() error {
+	for _, raw := range c.URLs {
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("invalid webhook URL %q", raw)
+		}
+	}
+	for _, event := range c.Events {
+		if !knownEvents[event] {
+			return fmt.Errorf("unknown notification event %q", event)
+		}
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("notification max retries must not be negative")
+	}
+	return nil
+}
+
+func (c Config) withDefaults() Config {
+	if c.Cooldown <= 0 {
+		c.Cooldown = 5 * time.Minute
+	}
+	if c.QueueSize <= 0 {
+		c.QueueSize = 100
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = time.Second
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = 5 * time.Second
+	}
+	return c
+}
+
+// LoadConfigFromEnv reads the notification config from NOTIFY_WEBHOOK_URLS
+// (comma-separated), NOTIFY_WEBHOOK_SECRET, NOTIFY_WEBHOOK_TEMPLATE ("slack"
+// or a template), NOTIFY_EVENTS (comma-separated), NOTIFY_COOLDOWN,
+// NOTIFY_QUEUE_SIZE, NOTIFY_MAX_RETRIES and NOTIFY_TIMEOUT
+func LoadConfigFromEnv() (Config, error) {
+	config := Config{
+		URLs:       splitList(os.Getenv("NOTIFY_WEBHOOK_URLS")),
+		Secret:     os.Getenv("NOTIFY_WEBHOOK_SECRET"),
+		Template:   os.Getenv("NOTIFY_WEBHOOK_TEMPLATE"),
+		MaxRetries: 3,
+	}
+	if config.Template == "slack" {
+		config.Template = SlackTemplate
+	}
+	for _, event := range splitList(os.Getenv("NOTIFY_EVENTS")) {
+		config.Events = append(config.Events, EventType(event))
+	}
+
+	var err error
+	if config.Cooldown, err = envDuration("NOTIFY_COOLDOWN"); err != nil {
+		return Config{}, err
+	}
+	if config.Timeout, err = envDuration("NOTIFY_TIMEOUT"); err != nil {
+		return Config{}, err
+	}
+	if config.QueueSize, err = envInt("NOTIFY_QUEUE_SIZE", 0); err != nil {
+		return Config{}, err
+	}
+	if config.MaxRetries, err = envInt("NOTIFY_MAX_RETRIES", config.MaxRetries); err != nil {
+		return Config{}, err
+	}
+	return config, config.Validate()
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func envDuration(name string) (time.Duration, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return d, nil
+}
+
+func envInt(name string, fallback int) (int, error) {
+	value := os.Getenv(name)
+	if value == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return n, nil
+}
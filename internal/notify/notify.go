@@ -0,0 +1,269 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package notify posts operational events, such as a provider being
+// ejected from routing, to webhooks
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// EventType names an event webhooks can be notified of
+type EventType string
+
+const (
+	// EventCircuitOpen is sent when a provider is ejected from routing
+	// after failing health checks
+	EventCircuitOpen EventType = "circuit_open"
+
+	// EventCircuitClose is sent when an ejected provider is restored
+	EventCircuitClose EventType = "circuit_close"
+
+	// EventHealthTransition is sent when a provider's error rate crosses
+	// the health threshold, in either direction
+	EventHealthTransition EventType = "provider_health_transition"
+
+	// EventConfigReloadFailed is sent when reloading configuration fails
+	EventConfigReloadFailed EventType = "config_reload_failed"
+
+	// EventQuotaExhausted is sent when a key is rejected by the rate limiter
+	EventQuotaExhausted EventType = "quota_exhausted"
+
+	// EventBudgetThreshold is sent when the spend of a key or tenant
+	// crosses a soft threshold of its budget
+	EventBudgetThreshold EventType = "budget_threshold"
+
+	// EventBudgetExhausted is sent when a key is rejected for reaching its
+	// own or its tenant's budget
+	EventBudgetExhausted EventType = "budget_exhausted"
+)
+
+// SignatureHeader carries the hex HMAC-SHA256 of the payload, keyed with
+// the webhook secret, as "sha256=<hex>"
+const SignatureHeader = "X-Signature-256"
+
+// SlackTemplate renders events as Slack incoming webhook messages
+const SlackTemplate = `{"text": {{json (printf "[%s] %s: %s" .Type .Subject .Message)}}}`
+
+// Event is an operational event. Subject is what the event is about: a
+// provider, a key prefix or a config file. State distinguishes events of
+// one type that are not repeats, such as a provider turning unhealthy and
+// turning healthy again.
+type Event struct {
+	Type    EventType         `json:"type"`
+	Subject string            `json:"subject"`
+	State   string            `json:"state,omitempty"`
+	Message string            `json:"message"`
+	Details map[string]string `json:"details,omitempty"`
+	Time    time.Time         `json:"time"`
+}
+
+// Notifier posts events to webhooks. Delivery is asynchronous: events are
+// queued, and dropped if the queue is full. Repeats of an event for the
+// same subject and state within the cooldown are suppressed.
+//
+// A nil *Notifier discards events, so components can notify unconditionally.
+type Notifier struct {
+	config   Config
+	template *template.Template
+	events   map[EventType]bool
+	client   *http.Client
+	queue    chan Event
+	done     chan struct{}
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time
+
+	// now and sleep are overridable for tests
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+// New creates a notifier and starts its delivery worker. Zero config
+// fields use defaults.
+func New(config Config) (*Notifier, error) {
+	config = config.withDefaults()
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	var tmpl *template.Template
+	if config.Template != "" {
+		var err error
+		tmpl, err = template.New("webhook").Funcs(template.FuncMap{"json": jsonValue}).Parse(config.Template)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook template: %w", err)
+		}
+	}
+
+	var events map[EventType]bool
+	if len(config.Events) > 0 {
+		events = make(map[EventType]bool, len(config.Events))
+		for _, event := range config.Events {
+			events[event] = true
+		}
+	}
+
+	n := &Notifier{
+		config:   config,
+		template: tmpl,
+		events:   events,
+		client:   &http.Client{Timeout: config.Timeout},
+		queue:    make(chan Event, config.QueueSize),
+		done:     make(chan struct{}),
+		lastSent: make(map[string]time.Time),
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+	go n.run()
+	return n, nil
+}
+
+// Notify queues an event for delivery, unless its type is not subscribed
+// to or it repeats a recent event for the same subject and state
+func (n *Notifier) Notify(event Event) {
+	if n == nil || (n.events != nil && !n.events[event.Type]) {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = n.now()
+	}
+
+	n.mu.Lock()
+	key := string(event.Type) + "|" + event.Subject + "|" + event.State
+	if last, ok := n.lastSent[key]; ok && event.Time.Sub(last) < n.config.Cooldown {
+		n.mu.Unlock()
+		metrics.Notifications.WithLabelValues(string(event.Type), "suppressed").Inc()
+		return
+	}
+	n.lastSent[key] = event.Time
+	n.mu.Unlock()
+
+	select {
+	case n.queue <- event:
+		metrics.Notifications.WithLabelValues(string(event.Type), "queued").Inc()
+	default:
+		log.Printf("Notification queue full, dropping %s event for %s", event.Type, event.Subject)
+		metrics.Notifications.WithLabelValues(string(event.Type), "dropped").Inc()
+		metrics.NotificationsDropped.WithLabelValues("queue_full").Inc()
+	}
+}
+
+// Close stops accepting events and waits for queued ones to be delivered
+func (n *Notifier) Close() {
+	if n == nil {
+		return
+	}
+	close(n.queue)
+	<-n.done
+}
+
+// run delivers queued events to every webhook
+func (n *Notifier) run() {
+	defer close(n.done)
+	for event := range n.queue {
+		payload, err := n.render(event)
+		if err != nil {
+			log.Printf("Failed to render %s notification: %v", event.Type, err)
+			metrics.NotificationsDropped.WithLabelValues("render_failed").Inc()
+			continue
+		}
+		for _, url := range n.config.URLs {
+			if err := n.deliver(url, payload); err != nil {
+				log.Printf("Failed to deliver %s notification to webhook: %v", event.Type, err)
+				metrics.NotificationsDropped.WithLabelValues("delivery_failed").Inc()
+			}
+		}
+	}
+}
+
+// render builds the webhook payload for an event: the template's output,
+// or the event as JSON
+func (n *Notifier) render(event Event) ([]byte, error) {
+	if n.template == nil {
+		return json.Marshal(event)
+	}
+	var buf bytes.Buffer
+	if err := n.template.Execute(&buf, event); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// deliver posts a payload, retrying with exponential backoff on network
+// errors, 429s and 5xx responses
+func (n *Notifier) deliver(url string, payload []byte) error {
+	backoff := n.config.RetryBackoff
+	var err error
+	for attempt := 0; attempt <= n.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			n.sleep(backoff)
+			backoff *= 2
+		}
+		var retry bool
+		retry, err = n.post(url, payload)
+		if err == nil || !retry {
+			return err
+		}
+	}
+	return err
+}
+
+// post sends one delivery attempt, reporting whether a failure is worth
+// retrying
+func (n *Notifier) post(url string, payload []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), n.config.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.config.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(n.config.Secret, payload))
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook returned %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("webhook returned %d", resp.StatusCode)
+	}
+}
+
+// Sign returns the SignatureHeader value for a payload
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// jsonValue renders a value as JSON, for building payloads in templates
+func jsonValue(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
@@ -0,0 +1,177 @@
+package notify
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// webhook records the payloads and signatures it receives, failing the
+// first failures requests with a 503
+type webhook struct {
+	mu         sync.Mutex
+	failures   int
+	attempts   int
+	payloads   []string
+	signatures []string
+}
+
+func (w *webhook) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.attempts++
+	if w.attempts <= w.failures {
+		rw.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.payloads = append(w.payloads, string(body))
+	w.signatures = append(w.signatures, r.Header.Get(SignatureHeader))
+}
+
+func newTestNotifier(t *testing.T, config Config) *Notifier {
+	t.Helper()
+	n, err := New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	n.sleep = func(time.Duration) {}
+	return n
+}
+
+// TestNotifierDelivery tests signed JSON delivery, retries and cooldown
+// suppression
+func TestNotifierDelivery(t *testing.T) {
+	hook := &webhook{failures: 2}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	n := newTestNotifier(t, Config{URLs: []string{server.URL}, Secret: "s3cret", MaxRetries: 3})
+	n.Notify(Event{Type: EventCircuitOpen, Subject: "bedrock", Message: "ejected"})
+	n.Notify(Event{Type: EventCircuitOpen, Subject: "bedrock", Message: "ejected again"})
+	n.Notify(Event{Type: EventCircuitClose, Subject: "bedrock", Message: "restored"})
+	n.Close()
+
+	if len(hook.payloads) != 2 {
+		t.Fatalf("delivered %d payloads, want 2 (repeat suppressed): %v", len(hook.payloads), hook.payloads)
+	}
+	if hook.attempts != 4 {
+		t.Errorf("attempts: got %d, want 4 (two retries)", hook.attempts)
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(hook.payloads[0]), &event); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if event.Type != EventCircuitOpen || event.Subject != "bedrock" || event.Time.IsZero() {
+		t.Errorf("unexpected event: %+v", event)
+	}
+	if want := Sign("s3cret", []byte(hook.payloads[0])); hook.signatures[0] != want {
+		t.Errorf("signature: got %q, want %q", hook.signatures[0], want)
+	}
+}
+
+// TestNotifierGivesUp tests that delivery stops after the retries and on
+// client errors
+func TestNotifierGivesUp(t *testing.T) {
+	hook := &webhook{failures: 10}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	n := newTestNotifier(t, Config{URLs: []string{server.URL}, MaxRetries: 2})
+	n.Notify(Event{Type: EventQuotaExhausted, Subject: "abcd1234"})
+	n.Close()
+	if hook.attempts != 3 {
+		t.Errorf("attempts: got %d, want 3", hook.attempts)
+	}
+
+	rejecting := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer rejecting.Close()
+	n = newTestNotifier(t, Config{URLs: []string{rejecting.URL}})
+	if err := n.deliver(rejecting.URL, []byte(`{}`)); err == nil {
+		t.Error("expected error for a 400 response")
+	}
+	n.Close()
+}
+
+// TestNotifierTemplate tests Slack payloads and event filtering
+func TestNotifierTemplate(t *testing.T) {
+	hook := &webhook{}
+	server := httptest.NewServer(hook)
+	defer server.Close()
+
+	n := newTestNotifier(t, Config{
+		URLs:     []string{server.URL},
+		Template: SlackTemplate,
+		Events:   []EventType{EventHealthTransition},
+	})
+	n.Notify(Event{Type: EventCircuitOpen, Subject: "openai"})
+	n.Notify(Event{Type: EventHealthTransition, Subject: "openai", State: "unhealthy", Message: `error rate "60%"`})
+	n.Notify(Event{Type: EventHealthTransition, Subject: "openai", State: "healthy", Message: "error rate 10%"})
+	n.Close()
+
+	if len(hook.payloads) != 2 {
+		t.Fatalf("delivered %d payloads, want 2: %v", len(hook.payloads), hook.payloads)
+	}
+	var message struct {
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(hook.payloads[0]), &message); err != nil {
+		t.Fatalf("payload is not JSON: %v: %s", err, hook.payloads[0])
+	}
+	if want := `[provider_health_transition] openai: error rate "60%"`; message.Text != want {
+		t.Errorf("text: got %q, want %q", message.Text, want)
+	}
+}
+
+// TestNotifierQueueFull tests that events are dropped rather than blocking
+func TestNotifierQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+
+	n := newTestNotifier(t, Config{URLs: []string{server.URL}, QueueSize: 1})
+	done := make(chan struct{})
+	go func() {
+		for _, subject := range []string{"a", "b", "c", "d"} {
+			n.Notify(Event{Type: EventCircuitOpen, Subject: subject})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Notify blocked on a full queue")
+	}
+	close(release)
+	n.Close()
+}
+
+// TestLoadConfigFromEnv tests the slack template shorthand and validation
+func TestLoadConfigFromEnv(t *testing.T) {
+	t.Setenv("NOTIFY_WEBHOOK_URLS", "https://hooks.example.com/a, https://hooks.example.com/b")
+	t.Setenv("NOTIFY_WEBHOOK_TEMPLATE", "slack")
+	t.Setenv("NOTIFY_EVENTS", "circuit_open,quota_exhausted")
+	t.Setenv("NOTIFY_COOLDOWN", "1m")
+
+	config, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigFromEnv() error = %v", err)
+	}
+	if len(config.URLs) != 2 || config.Template != SlackTemplate || len(config.Events) != 2 ||
+		config.Cooldown != time.Minute || config.MaxRetries != 3 {
+		t.Errorf("unexpected config: %+v", config)
+	}
+
+	t.Setenv("NOTIFY_EVENTS", "circuit_opened")
+	if _, err := LoadConfigFromEnv(); err == nil {
+		t.Error("expected error for an unknown event")
+	}
+}
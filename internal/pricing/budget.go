@@ -0,0 +1,313 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package pricing
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// DefaultBudgetWindow is the spend window of budgets without a window
+const DefaultBudgetWindow = 24 * time.Hour
+
+// DefaultSoftThresholds are the fractions of a budget at which a
+// budget_threshold event is sent, when none are configured
+var DefaultSoftThresholds = []float64{0.5, 0.9}
+
+// BudgetConfig configures the spend limits of keys and tenants. Limits are
+// per-replica soft limits: spend is counted in each replica's memory, so
+// with N replicas a key can spend up to N times its budget in a window,
+// and a restart or rollout starts every window over at zero.
+type BudgetConfig struct {
+	// Window is the fixed window spend is counted over, starting at a
+	// key's or tenant's first priced response (default 24h)
+	Window string `yaml:"window,omitempty"`
+
+	// SoftThresholds are the fractions of a budget whose crossing sends a
+	// budget_threshold webhook event (default 0.5 and 0.9)
+	SoftThresholds []float64 `yaml:"soft_thresholds,omitempty"`
+
+	// PrecheckEstimate also rejects requests whose estimated cost, the
+	// prompt and max_tokens at the model's price, exceeds the remaining
+	// budget
+	PrecheckEstimate bool `yaml:"precheck_estimate,omitempty"`
+}
+
+// TenantConfig holds the spend limit shared by the keys of a tenant
+type TenantConfig struct {
+	// BudgetUSD is the tenant's per-replica spend limit per budget window
+	BudgetUSD float64 `yaml:"budget_usd"`
+}
+
+// window returns the parsed spend window
+func (c BudgetConfig) window() (time.Duration, error) {
+	if c.Window == "" {
+		return DefaultBudgetWindow, nil
+	}
+	d, err := time.ParseDuration(c.Window)
+	if err != nil {
+		return 0, fmt.Errorf("invalid budget window %q: %w", c.Window, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("budget window must be positive")
+	}
+	return d, nil
+}
+
+// thresholds returns the sorted soft thresholds
+func (c BudgetConfig) thresholds() []float64 {
+	if len(c.SoftThresholds) == 0 {
+		return DefaultSoftThresholds
+	}
+	thresholds := append([]float64(nil), c.SoftThresholds...)
+	sort.Float64s(thresholds)
+	return thresholds
+}
+
+func (c BudgetConfig) validate() error {
+	if _, err := c.window(); err != nil {
+		return err
+	}
+	for _, threshold := range c.SoftThresholds {
+		if threshold <= 0 || threshold >= 1 {
+			return fmt.Errorf("soft thresholds must be between 0 and 1")
+		}
+	}
+	return nil
+}
+
+// hasBudgets reports whether any key or tenant has a spend limit
+func (c Config) hasBudgets() bool {
+	for _, key := range c.Keys {
+		if key.BudgetUSD > 0 {
+			return true
+		}
+	}
+	for _, tenant := range c.Tenants {
+		if tenant.BudgetUSD > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// BudgetStatus is the spend of a key or tenant in the current window
+type BudgetStatus struct {
+	// Subject is "key/<name>" or "tenant/<name>"
+	Subject  string    `json:"subject"`
+	LimitUSD float64   `json:"limit_usd"`
+	SpentUSD float64   `json:"spent_usd"`
+	ResetsAt time.Time `json:"resets_at"`
+}
+
+// budgetLimit is one spend limit applying to a key
+type budgetLimit struct {
+	subject string
+	usd     float64
+}
+
+// spend is the spend of a subject in its current window
+type spend struct {
+	windowStart time.Time
+	usd         float64
+
+	// notified is the number of soft thresholds already sent this window
+	notified int
+}
+
+// budgets tracks spend per key and tenant in process memory. Nothing is
+// shared between replicas or kept across restarts, so each replica
+// enforces the full limits on its own traffic only.
+type budgets struct {
+	window     time.Duration
+	thresholds []float64
+	precheck   bool
+	now        func() time.Time
+
+	mu     sync.Mutex
+	spends map[string]*spend
+
+	// notifier is told when spend crosses a soft threshold and when a
+	// request is rejected
+	notifier *notify.Notifier
+}
+
+func newBudgets(config BudgetConfig) (*budgets, error) {
+	window, err := config.window()
+	if err != nil {
+		return nil, err
+	}
+	return &budgets{
+		window:     window,
+		thresholds: config.thresholds(),
+		precheck:   config.PrecheckEstimate,
+		now:        time.Now,
+		spends:     make(map[string]*spend),
+	}, nil
+}
+
+// SetNotifier sends budget_threshold and budget_exhausted events to
+// webhooks
+func (t *Table) SetNotifier(notifier *notify.Notifier) {
+	if t != nil && t.budgets != nil {
+		t.budgets.notifier = notifier
+	}
+}
+
+// limits returns the spend limits applying to the named API key: its own
+// and its tenant's
+func (t *Table) limits(keyName string) []budgetLimit {
+	if t == nil || t.budgets == nil || keyName == "" {
+		return nil
+	}
+	key, ok := t.config.Keys[keyName]
+	if !ok {
+		return nil
+	}
+	var limits []budgetLimit
+	if key.BudgetUSD > 0 {
+		limits = append(limits, budgetLimit{subject: "key/" + keyName, usd: key.BudgetUSD})
+	}
+	if tenant := t.config.Tenants[key.Tenant]; key.Tenant != "" && tenant.BudgetUSD > 0 {
+		limits = append(limits, budgetLimit{subject: "tenant/" + key.Tenant, usd: tenant.BudgetUSD})
+	}
+	return limits
+}
+
+// CheckBudget reports whether the named API key may send a request, and
+// the status of the budget rejecting it if not. A request is rejected when
+// the key's or its tenant's spend has reached its limit or, with
+// precheck_estimate, when the cost of estimate would exceed what remains.
+func (t *Table) CheckBudget(keyName, provider, model string, estimate Usage) (BudgetStatus, bool) {
+	limits := t.limits(keyName)
+	if len(limits) == 0 {
+		return BudgetStatus{}, true
+	}
+	var estimated float64
+	if t.budgets.precheck {
+		estimated, _ = t.Cost(provider, model, estimate)
+	}
+
+	b := t.budgets
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, limit := range limits {
+		s := b.spend(limit.subject)
+		if s.usd < limit.usd && s.usd+estimated <= limit.usd {
+			continue
+		}
+		status := b.status(limit, s)
+		metrics.BudgetRejected.WithLabelValues(limit.subject).Inc()
+		b.notifier.Notify(notify.Event{
+			Type:    notify.EventBudgetExhausted,
+			Subject: limit.subject,
+			Message: fmt.Sprintf("spend limit of $%.2f reached", limit.usd),
+			Details: map[string]string{
+				"spent_usd": fmt.Sprintf("%.4f", s.usd),
+				"resets_at": status.ResetsAt.UTC().Format(time.RFC3339),
+			},
+		})
+		return status, false
+	}
+	return BudgetStatus{}, true
+}
+
+// Charge adds the cost of a response to the spend of the named API key
+// and its tenant, notifying webhooks of soft thresholds crossed
+func (t *Table) Charge(keyName string, cost float64) {
+	limits := t.limits(keyName)
+	if len(limits) == 0 || cost <= 0 {
+		return
+	}
+
+	b := t.budgets
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, limit := range limits {
+		s := b.spend(limit.subject)
+		s.usd += cost
+		metrics.BudgetSpentUSD.WithLabelValues(limit.subject).Set(s.usd)
+		for s.notified < len(b.thresholds) && s.usd >= b.thresholds[s.notified]*limit.usd {
+			threshold := b.thresholds[s.notified]
+			s.notified++
+			b.notifier.Notify(notify.Event{
+				Type:    notify.EventBudgetThreshold,
+				Subject: limit.subject,
+				State:   fmt.Sprintf("%g%%", threshold*100),
+				Message: fmt.Sprintf("%g%% of the $%.2f spend limit used", threshold*100, limit.usd),
+				Details: map[string]string{
+					"spent_usd": fmt.Sprintf("%.4f", s.usd),
+					"resets_at": s.windowStart.Add(b.window).UTC().Format(time.RFC3339),
+				},
+			})
+		}
+	}
+}
+
+// BudgetStatuses returns the spend of the keys and tenants with a budget
+// in their current window, sorted by subject
+func (t *Table) BudgetStatuses() []BudgetStatus {
+	if t == nil || t.budgets == nil {
+		return nil
+	}
+	var limits []budgetLimit
+	for name, key := range t.config.Keys {
+		if key.BudgetUSD > 0 {
+			limits = append(limits, budgetLimit{subject: "key/" + name, usd: key.BudgetUSD})
+		}
+	}
+	for name, tenant := range t.config.Tenants {
+		if tenant.BudgetUSD > 0 {
+			limits = append(limits, budgetLimit{subject: "tenant/" + name, usd: tenant.BudgetUSD})
+		}
+	}
+
+	b := t.budgets
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	statuses := make([]BudgetStatus, 0, len(limits))
+	for _, limit := range limits {
+		if s, ok := b.spends[limit.subject]; ok {
+			b.rollWindow(s, b.now())
+			statuses = append(statuses, b.status(limit, s))
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Subject < statuses[j].Subject })
+	return statuses
+}
+
+// spend returns the current-window spend of a subject; callers hold b.mu
+func (b *budgets) spend(subject string) *spend {
+	now := b.now()
+	s, ok := b.spends[subject]
+	if !ok {
+		s = &spend{windowStart: now}
+		b.spends[subject] = s
+	}
+	b.rollWindow(s, now)
+	return s
+}
+
+// rollWindow resets a spend whose window has elapsed
+func (b *budgets) rollWindow(s *spend, now time.Time) {
+	if now.Sub(s.windowStart) >= b.window {
+		s.windowStart = now
+		s.usd = 0
+		s.notified = 0
+	}
+}
+
+func (b *budgets) status(limit budgetLimit, s *spend) BudgetStatus {
+	return BudgetStatus{
+		Subject:  limit.subject,
+		LimitUSD: limit.usd,
+		SpentUSD: s.usd,
+		ResetsAt: s.windowStart.Add(b.window),
+	}
+}
@@ -0,0 +1,107 @@
+package pricing
+
+import (
+	"testing"
+	"time"
+)
+
+func newBudgetTable(t *testing.T, budget BudgetConfig) (*Table, *time.Time) {
+	t.Helper()
+	table, err := New(Config{
+		Models: map[string]Price{"gpt-4o": {InputPer1K: 1, OutputPer1K: 2}},
+		Keys: map[string]KeyConfig{
+			"alice": {BudgetUSD: 10, Tenant: "acme"},
+			"bob":   {Tenant: "acme"},
+			"carol": {},
+		},
+		Tenants: map[string]TenantConfig{"acme": {BudgetUSD: 15}},
+		Budget:  budget,
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	table.budgets.now = func() time.Time { return now }
+	return table, &now
+}
+
+// TestBudgets tests key and tenant spend limits and their window
+func TestBudgets(t *testing.T) {
+	table, now := newBudgetTable(t, BudgetConfig{Window: "1h"})
+
+	table.Charge("alice", 9)
+	if _, ok := table.CheckBudget("alice", "openai", "gpt-4o", Usage{}); !ok {
+		t.Error("alice rejected under her budget")
+	}
+	table.Charge("alice", 1)
+	status, ok := table.CheckBudget("alice", "openai", "gpt-4o", Usage{})
+	if ok || status.Subject != "key/alice" || status.SpentUSD != 10 {
+		t.Errorf("alice at her budget: got %+v, %v", status, ok)
+	}
+	if !status.ResetsAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("ResetsAt = %v, want %v", status.ResetsAt, now.Add(time.Hour))
+	}
+
+	// Bob shares the acme tenant budget, 10 of 15 spent by alice
+	table.Charge("bob", 5)
+	if status, ok := table.CheckBudget("bob", "openai", "gpt-4o", Usage{}); ok || status.Subject != "tenant/acme" {
+		t.Errorf("bob at the tenant budget: got %+v, %v", status, ok)
+	}
+	if _, ok := table.CheckBudget("carol", "openai", "gpt-4o", Usage{}); !ok {
+		t.Error("carol has no budget")
+	}
+
+	*now = now.Add(time.Hour)
+	if _, ok := table.CheckBudget("alice", "openai", "gpt-4o", Usage{}); !ok {
+		t.Error("alice rejected after the window reset")
+	}
+
+	statuses := table.BudgetStatuses()
+	if len(statuses) != 2 || statuses[0].Subject != "key/alice" || statuses[1].Subject != "tenant/acme" {
+		t.Errorf("BudgetStatuses() = %+v", statuses)
+	}
+}
+
+// TestBudgetPrecheck tests that an estimated request larger than the
+// remaining budget is rejected
+func TestBudgetPrecheck(t *testing.T) {
+	table, _ := newBudgetTable(t, BudgetConfig{PrecheckEstimate: true})
+	table.Charge("alice", 8)
+
+	// 1000 prompt tokens and 500 max_tokens cost $2
+	if _, ok := table.CheckBudget("alice", "openai", "gpt-4o", Usage{PromptTokens: 1000, CompletionTokens: 500}); !ok {
+		t.Error("request within the remaining budget rejected")
+	}
+	if _, ok := table.CheckBudget("alice", "openai", "gpt-4o", Usage{PromptTokens: 1000, CompletionTokens: 1000}); ok {
+		t.Error("request over the remaining budget allowed")
+	}
+}
+
+func TestBudgetSoftThresholds(t *testing.T) {
+	table, _ := newBudgetTable(t, BudgetConfig{SoftThresholds: []float64{0.9, 0.5}})
+	table.Charge("alice", 6)
+	if s := table.budgets.spends["key/alice"]; s.notified != 1 {
+		t.Errorf("after 60%%: %d thresholds notified, want 1", s.notified)
+	}
+	table.Charge("alice", 4)
+	if s := table.budgets.spends["key/alice"]; s.notified != 2 {
+		t.Errorf("after 100%%: %d thresholds notified, want 2", s.notified)
+	}
+}
+
+func TestBudgetValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"budget without prices", Config{Keys: map[string]KeyConfig{"a": {BudgetUSD: 1}}}},
+		{"negative budget", Config{Models: map[string]Price{"m": {}}, Tenants: map[string]TenantConfig{"t": {BudgetUSD: -1}}}},
+		{"bad window", Config{Models: map[string]Price{"m": {}}, Budget: BudgetConfig{Window: "daily"}}},
+		{"bad threshold", Config{Models: map[string]Price{"m": {}}, Budget: BudgetConfig{SoftThresholds: []float64{1.5}}}},
+	}
+	for _, tt := range tests {
+		if err := tt.config.Validate(); err == nil {
+			t.Errorf("%s: expected an error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package pricing prices the token usage of responses from a configured
+// per-model price table, for the cost metrics, the cost headers returned
+// to callers and the spend limits of keys and tenants
+package pricing
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// Price is the USD price of a model's tokens
+type Price struct {
+	InputPer1K  float64 `yaml:"input_per_1k"`
+	OutputPer1K float64 `yaml:"output_per_1k"`
+
+	// CacheReadDiscount is the fraction taken off the input price for
+	// prompt tokens read from the prompt cache (0.9 bills them at 10%)
+	CacheReadDiscount float64 `yaml:"cache_read_discount,omitempty"`
+
+	// CacheWritePremium is the fraction added to the input price for
+	// prompt tokens written to the prompt cache (0.25 bills them at 125%)
+	CacheWritePremium float64 `yaml:"cache_write_premium,omitempty"`
+}
+
+// KeyConfig holds the pricing settings of one API key, by key name
+type KeyConfig struct {
+	// ExposeCost returns the cost headers on the key's responses
+	ExposeCost bool `yaml:"expose_cost"`
+
+	// BudgetUSD is the key's per-replica spend limit per budget window
+	// (0 = none)
+	BudgetUSD float64 `yaml:"budget_usd,omitempty"`
+
+	// Tenant names the tenant whose budget the key's spend also counts
+	// against
+	Tenant string `yaml:"tenant,omitempty"`
+}
+
+// Config is the pricing section of the router config. Models are priced
+// by the model name requested; models without a price use their
+// provider's default price. Keys and tenants with a budget are refused
+// once the spend a replica counted in the budget window reaches it.
+type Config struct {
+	Models    map[string]Price        `yaml:"models,omitempty"`
+	Providers map[string]Price        `yaml:"providers,omitempty"`
+	Keys      map[string]KeyConfig    `yaml:"keys,omitempty"`
+	Tenants   map[string]TenantConfig `yaml:"tenants,omitempty"`
+	Budget    BudgetConfig            `yaml:"budget,omitempty"`
+}
+
+// Enabled reports whether any price is configured
+func (c Config) Enabled() bool {
+	return len(c.Models) > 0 || len(c.Providers) > 0
+}
+
+// Validate checks that prices and budgets are not negative, discounts are
+// fractions and budgets have prices to count spend by
+func (c Config) Validate() error {
+	for model, price := range c.Models {
+		if err := price.validate(); err != nil {
+			return fmt.Errorf("pricing for model %s: %w", model, err)
+		}
+	}
+	for provider, price := range c.Providers {
+		if err := price.validate(); err != nil {
+			return fmt.Errorf("pricing for provider %s: %w", provider, err)
+		}
+	}
+	for name, key := range c.Keys {
+		if key.BudgetUSD < 0 {
+			return fmt.Errorf("budget of key %s must not be negative", name)
+		}
+	}
+	for name, tenant := range c.Tenants {
+		if tenant.BudgetUSD < 0 {
+			return fmt.Errorf("budget of tenant %s must not be negative", name)
+		}
+	}
+	if c.hasBudgets() && !c.Enabled() {
+		return fmt.Errorf("budgets require model or provider prices")
+	}
+	return c.Budget.validate()
+}
+
+func (p Price) validate() error {
+	if p.InputPer1K < 0 || p.OutputPer1K < 0 {
+		return fmt.Errorf("prices must not be negative")
+	}
+	if p.CacheReadDiscount < 0 || p.CacheReadDiscount > 1 {
+		return fmt.Errorf("cache_read_discount must be between 0 and 1")
+	}
+	if p.CacheWritePremium < 0 {
+		return fmt.Errorf("cache_write_premium must not be negative")
+	}
+	return nil
+}
+
+// Usage is the token usage of one response. PromptTokens excludes
+// CacheReadTokens and CacheWriteTokens, as Claude reports them.
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	CacheReadTokens  int
+	CacheWriteTokens int
+}
+
+// Table prices usage. A nil *Table prices nothing and records no metrics,
+// so handlers can use it unconditionally.
+type Table struct {
+	config Config
+
+	// budgets tracks spend, when keys or tenants have budgets
+	budgets *budgets
+}
+
+// New creates a price table from a validated config
+func New(config Config) (*Table, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	table := &Table{config: config}
+	if config.hasBudgets() {
+		b, err := newBudgets(config.Budget)
+		if err != nil {
+			return nil, err
+		}
+		table.budgets = b
+		log.Printf("Spend limits are per-replica soft limits: spend is kept in memory, not shared between replicas, and reset on restart")
+	}
+	return table, nil
+}
+
+// Price returns the price of a model, falling back to its provider's
+// default price
+func (t *Table) Price(provider, model string) (Price, bool) {
+	if t == nil {
+		return Price{}, false
+	}
+	if price, ok := t.config.Models[model]; ok {
+		return price, true
+	}
+	price, ok := t.config.Providers[provider]
+	return price, ok
+}
+
+// Cost returns the USD cost of usage, and false when the model has no
+// price
+func (t *Table) Cost(provider, model string, usage Usage) (float64, bool) {
+	price, ok := t.Price(provider, model)
+	if !ok {
+		return 0, false
+	}
+	input := float64(usage.PromptTokens) +
+		float64(usage.CacheReadTokens)*(1-price.CacheReadDiscount) +
+		float64(usage.CacheWriteTokens)*(1+price.CacheWritePremium)
+	return (input*price.InputPer1K + float64(usage.CompletionTokens)*price.OutputPer1K) / 1000, true
+}
+
+// Record prices usage and adds it to the cost metrics. Unpriced models
+// cost zero and are counted in gateway_unpriced_requests_total.
+func (t *Table) Record(provider, model string, usage Usage) float64 {
+	if t == nil {
+		return 0
+	}
+	cost, ok := t.Cost(provider, model, usage)
+	if !ok {
+		metrics.UnpricedRequests.WithLabelValues(provider, model).Inc()
+		return 0
+	}
+	metrics.CostUSD.WithLabelValues(provider, model).Add(cost)
+	return cost
+}
+
+// ExposesCost reports whether the named API key gets cost headers
+func (t *Table) ExposesCost(keyName string) bool {
+	if t == nil || keyName == "" {
+		return false
+	}
+	return t.config.Keys[keyName].ExposeCost
+}
@@ -0,0 +1,59 @@
+package pricing
+
+import (
+	"math"
+	"testing"
+)
+
+// TestCost tests model prices, provider defaults, cache read discounts and
+// cache write premiums
+func TestCost(t *testing.T) {
+	table, err := New(Config{
+		Models: map[string]Price{
+			"gpt-4o":     {InputPer1K: 0.0025, OutputPer1K: 0.01},
+			"claude-3-5": {InputPer1K: 0.003, OutputPer1K: 0.015, CacheReadDiscount: 0.9, CacheWritePremium: 0.25},
+		},
+		Providers: map[string]Price{"openai": {InputPer1K: 0.001, OutputPer1K: 0.002}},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		provider, model string
+		usage           Usage
+		want            float64
+		priced          bool
+	}{
+		{"openai", "gpt-4o", Usage{PromptTokens: 1000, CompletionTokens: 500}, 0.0075, true},
+		{"bedrock", "claude-3-5", Usage{PromptTokens: 1000, CompletionTokens: 1000, CacheReadTokens: 10000}, 0.021, true},
+		{"anthropic", "claude-3-5", Usage{PromptTokens: 1000, CacheWriteTokens: 4000}, 0.018, true},
+		{"openai", "gpt-4o-mini", Usage{PromptTokens: 2000, CompletionTokens: 1000}, 0.004, true},
+		{"vertex", "gemini-pro", Usage{PromptTokens: 1000}, 0, false},
+	}
+	for _, tt := range tests {
+		got, priced := table.Cost(tt.provider, tt.model, tt.usage)
+		if priced != tt.priced || math.Abs(got-tt.want) > 1e-12 {
+			t.Errorf("Cost(%s, %s) = %v, %v; want %v, %v", tt.provider, tt.model, got, priced, tt.want, tt.priced)
+		}
+	}
+
+	var unconfigured *Table
+	if cost := unconfigured.Record("openai", "gpt-4o", Usage{PromptTokens: 1}); cost != 0 || unconfigured.ExposesCost("alice") {
+		t.Error("a nil table should price nothing")
+	}
+}
+
+// TestValidate tests that negative prices and premiums and discounts over 1
+// are rejected
+func TestValidate(t *testing.T) {
+	for _, config := range []Config{
+		{Models: map[string]Price{"m": {InputPer1K: -1}}},
+		{Providers: map[string]Price{"p": {CacheReadDiscount: 1.5}}},
+		{Providers: map[string]Price{"p": {CacheWritePremium: -0.25}}},
+	} {
+		if err := config.Validate(); err == nil {
+			t.Errorf("Validate(%+v) accepted an invalid price", config)
+		}
+	}
+}
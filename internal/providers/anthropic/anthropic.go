@@ -27,18 +27,26 @@ type AnthropicProvider struct {
 type AnthropicConfig struct {
 	APIKey  string `yaml:"api_key"`
 	BaseURL string `yaml:"base_url"` // Optional, defaults to https://api.anthropic.com/v1
+	Timeouts providers.HTTPTimeouts // Optional, defaults to 10s connect and 120s request
 }
 
 // Anthropic Messages API types
 type AnthropicRequest struct {
-	Model       string              `json:"model"`
+	Model       string              `json:"model,omitempty"` // Unset where the URL names the model (Vertex AI)
 	Messages    []AnthropicMessage  `json:"messages"`
 	MaxTokens   int                 `json:"max_tokens"`
 	Temperature *float64            `json:"temperature,omitempty"`
-	System      string              `json:"system,omitempty"`
+	System      interface{}         `json:"system,omitempty"` // string or []AnthropicContentBlock
 	Tools       []AnthropicTool     `json:"tools,omitempty"`
 	ToolChoice  interface{}         `json:"tool_choice,omitempty"`
 	Stream      bool                `json:"stream,omitempty"`
+	Metadata    *AnthropicMetadata  `json:"metadata,omitempty"`
+	ServiceTier string              `json:"service_tier,omitempty"` // auto or standard_only
+}
+
+// AnthropicMetadata is the request metadata; Anthropic only accepts a user ID
+type AnthropicMetadata struct {
+	UserID string `json:"user_id"`
 }
 
 type AnthropicMessage struct {
@@ -68,11 +76,19 @@ type AnthropicContentBlock struct {
 	ID    string                 `json:"id,omitempty"`    // for tool_use
 	Name  string                 `json:"name,omitempty"`  // for tool_use
 	Input map[string]interface{} `json:"input,omitempty"` // for tool_use
+
+	CacheControl *translator.CacheControl `json:"cache_control,omitempty"` // ends a prompt prefix to cache
 }
 
 type AnthropicUsage struct {
-	InputTokens  int `json:"input_tokens"`
-	OutputTokens int `json:"output_tokens"`
+	InputTokens  int    `json:"input_tokens"`
+	OutputTokens int    `json:"output_tokens"`
+	ServiceTier  string `json:"service_tier,omitempty"` // standard, priority or batch
+
+	// Prompt tokens written to and read from the prompt cache, apart from
+	// InputTokens
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
 }
 
 // NewAnthropicProvider creates a new Anthropic provider
@@ -89,9 +105,7 @@ func NewAnthropicProvider(config AnthropicConfig) (*AnthropicProvider, error) {
 	return &AnthropicProvider{
 		apiKey:  config.APIKey,
 		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		httpClient: providers.NewHTTPClient(config.Timeouts),
 	}, nil
 }
 
@@ -100,6 +114,18 @@ func (p *AnthropicProvider) Name() string {
 	return "anthropic"
 }
 
+// Capabilities reports what Anthropic's models support
+func (p *AnthropicProvider) Capabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Streaming:         true,
+		Vision:            true,
+		Tools:             true,
+		ParallelToolCalls: true,
+		MaxContextTokens:  200000,
+		ServiceTiers:      []string{"auto", "default"}, // auto may use Priority Tier capacity
+	}
+}
+
 // HealthCheck checks if the provider is accessible
 func (p *AnthropicProvider) HealthCheck(ctx context.Context) error {
 	// Anthropic doesn't have a dedicated health endpoint, so we'll skip for now
@@ -111,7 +137,7 @@ func (p *AnthropicProvider) HealthCheck(ctx context.Context) error {
 func (p *AnthropicProvider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
 	// Parse OpenAI request
 	var openaiReq translator.ChatCompletionRequest
-	if err := json.Unmarshal(request.Body, &openaiReq); err != nil {
+	if err := json.NewDecoder(request.BodyStream()).Decode(&openaiReq); err != nil {
 		return nil, &providers.ProviderError{
 			StatusCode: http.StatusBadRequest,
 			Message:    fmt.Sprintf("failed to parse request: %v", err),
@@ -144,8 +170,9 @@ func (p *AnthropicProvider) Invoke(ctx context.Context, request *providers.Provi
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", p.apiKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	providers.SetCredentials(httpReq, request, "x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", apiVersion(request))
+	providers.ApplyHeaders(httpReq.Header, request.Headers)
 
 	// Send request
 	resp, err := p.httpClient.Do(httpReq)
@@ -174,6 +201,7 @@ func (p *AnthropicProvider) Invoke(ctx context.Context, request *providers.Provi
 			StatusCode: resp.StatusCode,
 			Message:    string(respBody),
 			Provider:   "anthropic",
+			Headers:    resp.Header.Clone(),
 		}
 	}
 
@@ -201,12 +229,7 @@ func (p *AnthropicProvider) Invoke(ctx context.Context, request *providers.Provi
 	}
 
 	// Build provider response
-	headers := make(map[string]string)
-	for k, v := range resp.Header {
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
-	}
+	headers := resp.Header.Clone()
 
 	return &providers.ProviderResponse{
 		StatusCode: resp.StatusCode,
@@ -215,11 +238,26 @@ func (p *AnthropicProvider) Invoke(ctx context.Context, request *providers.Provi
 	}, nil
 }
 
+// InvokeStream sends a request to Anthropic, decoding the request directly
+// from BodyReader. The response is translated to the OpenAI format, so it is
+// read whole before it is returned.
+func (p *AnthropicProvider) InvokeStream(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderStreamResponse, error) {
+	resp, err := p.Invoke(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &providers.ProviderStreamResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Headers,
+		Body:       io.NopCloser(bytes.NewReader(resp.Body)),
+	}, nil
+}
+
 // InvokeStreaming sends a streaming request to Anthropic
 func (p *AnthropicProvider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
 	// Parse and translate request
 	var openaiReq translator.ChatCompletionRequest
-	if err := json.Unmarshal(request.Body, &openaiReq); err != nil {
+	if err := json.NewDecoder(request.BodyStream()).Decode(&openaiReq); err != nil {
 		return nil, &providers.ProviderError{
 			StatusCode: http.StatusBadRequest,
 			Message:    fmt.Sprintf("failed to parse request: %v", err),
@@ -249,8 +287,9 @@ func (p *AnthropicProvider) InvokeStreaming(ctx context.Context, request *provid
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("x-api-key", p.apiKey)
-	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	providers.SetCredentials(httpReq, request, "x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", apiVersion(request))
+	providers.ApplyHeaders(httpReq.Header, request.Headers)
 
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
@@ -268,10 +307,11 @@ func (p *AnthropicProvider) InvokeStreaming(ctx context.Context, request *provid
 			StatusCode: resp.StatusCode,
 			Message:    string(body),
 			Provider:   "anthropic",
+			Headers:    resp.Header.Clone(),
 		}
 	}
 
-	return resp.Body, nil
+	return providers.NewHeaderStream(resp.Body, resp.Header.Clone()), nil
 }
 
 // ListModels lists available Anthropic models
@@ -299,6 +339,18 @@ func (p *AnthropicProvider) GetModelInfo(ctx context.Context, modelID string) (*
 	return nil, fmt.Errorf("model not found: %s", modelID)
 }
 
+// TranslateRequest converts an OpenAI chat request to an Anthropic Messages
+// request, for providers that serve Claude through the Messages API
+func TranslateRequest(req *translator.ChatCompletionRequest) *AnthropicRequest {
+	return translateOpenAIToAnthropic(req)
+}
+
+// TranslateResponse converts an Anthropic Messages response to an OpenAI
+// chat completion
+func TranslateResponse(resp *AnthropicResponse, model string) *translator.ChatCompletionResponse {
+	return translateAnthropicToOpenAI(resp, model)
+}
+
 // translateOpenAIToAnthropic converts OpenAI format to Anthropic format
 func translateOpenAIToAnthropic(req *translator.ChatCompletionRequest) *AnthropicRequest {
 	anthropicReq := &AnthropicRequest{
@@ -310,16 +362,20 @@ func translateOpenAIToAnthropic(req *translator.ChatCompletionRequest) *Anthropi
 		anthropicReq.Temperature = &req.Temperature
 	}
 
-	// Convert messages
+	// Convert messages. Messages marked with cache_control are sent as a
+	// text block carrying the marker.
 	for _, msg := range req.Messages {
 		if msg.Role == "system" {
-			// Extract system message
-			anthropicReq.System = extractTextContent(msg.Content)
+			// Extract system message; an empty one leaves system unset
+			anthropicReq.System = nil
+			if msg.Content.Text() != "" {
+				anthropicReq.System = cacheableText(msg)
+			}
 		} else {
 			// User and assistant messages
 			anthropicReq.Messages = append(anthropicReq.Messages, AnthropicMessage{
 				Role:    msg.Role,
-				Content: extractTextContent(msg.Content),
+				Content: cacheableText(msg),
 			})
 		}
 	}
@@ -342,9 +398,9 @@ func translateOpenAIToAnthropic(req *translator.ChatCompletionRequest) *Anthropi
 		switch tc := req.ToolChoice.(type) {
 		case string:
 			if tc == "auto" {
-				anthropicReq.ToolChoice = map[string]string{"type": "auto"}
+				anthropicReq.ToolChoice = map[string]interface{}{"type": "auto"}
 			} else if tc == "required" || tc == "any" {
-				anthropicReq.ToolChoice = map[string]string{"type": "any"}
+				anthropicReq.ToolChoice = map[string]interface{}{"type": "any"}
 			}
 		case map[string]interface{}:
 			if tcType, ok := tc["type"].(string); ok && tcType == "function" {
@@ -360,9 +416,61 @@ func translateOpenAIToAnthropic(req *translator.ChatCompletionRequest) *Anthropi
 		}
 	}
 
+	// Anthropic metadata only carries a user ID
+	if userID := req.Metadata["user_id"]; userID != "" {
+		anthropicReq.Metadata = &AnthropicMetadata{UserID: userID}
+	} else if req.User != "" {
+		anthropicReq.Metadata = &AnthropicMetadata{UserID: req.User}
+	}
+
+	// OpenAI's default tier keeps to standard capacity; auto may use
+	// Priority Tier capacity
+	switch req.ServiceTier {
+	case "auto":
+		anthropicReq.ServiceTier = "auto"
+	case "default":
+		anthropicReq.ServiceTier = "standard_only"
+	}
+
+	// parallel_tool_calls: false is an option of the tool choice; without an
+	// explicit choice it is set on the default auto choice
+	if req.ParallelToolCalls != nil && !*req.ParallelToolCalls && len(anthropicReq.Tools) > 0 {
+		if req.ToolChoice == nil {
+			anthropicReq.ToolChoice = map[string]interface{}{"type": "auto"}
+		}
+		if choice, ok := anthropicReq.ToolChoice.(map[string]interface{}); ok {
+			choice["disable_parallel_tool_use"] = true
+		}
+	}
+
 	return anthropicReq
 }
 
+// cacheableText returns a message's text, as a text block with its
+// cache_control when it is marked for caching
+func cacheableText(msg translator.ChatMessage) interface{} {
+	if msg.CacheControl == nil {
+		return msg.Content.Text()
+	}
+	return []AnthropicContentBlock{{
+		Type:         "text",
+		Text:         msg.Content.Text(),
+		CacheControl: msg.CacheControl,
+	}}
+}
+
+// defaultAPIVersion is the anthropic-version sent unless the request sets one
+const defaultAPIVersion = "2023-06-01"
+
+// apiVersion returns the request's anthropic-version header, set from an
+// instance's anthropic_version option, or the default
+func apiVersion(request *providers.ProviderRequest) string {
+	if version := request.Headers.Get("anthropic-version"); version != "" {
+		return version
+	}
+	return defaultAPIVersion
+}
+
 // translateAnthropicToOpenAI converts Anthropic response to OpenAI format
 func translateAnthropicToOpenAI(resp *AnthropicResponse, model string) *translator.ChatCompletionResponse {
 	var content string
@@ -395,7 +503,7 @@ func translateAnthropicToOpenAI(resp *AnthropicResponse, model string) *translat
 
 	message := translator.ChatMessage{
 		Role:    "assistant",
-		Content: content,
+		Content: translator.TextContent(content),
 	}
 
 	if len(toolCalls) > 0 {
@@ -414,32 +522,20 @@ func translateAnthropicToOpenAI(resp *AnthropicResponse, model string) *translat
 				FinishReason: finishReason,
 			},
 		},
-		Usage: &translator.Usage{
-			PromptTokens:     resp.Usage.InputTokens,
-			CompletionTokens: resp.Usage.OutputTokens,
-			TotalTokens:      resp.Usage.InputTokens + resp.Usage.OutputTokens,
-		},
+		Usage: translator.NewCacheUsage(resp.Usage.InputTokens, resp.Usage.OutputTokens,
+			resp.Usage.CacheReadInputTokens, resp.Usage.CacheCreationInputTokens),
+		ServiceTier: openAIServiceTier(resp.Usage.ServiceTier),
 	}
 }
 
-// extractTextContent extracts text content from OpenAI message content
-func extractTextContent(content interface{}) string {
-	switch c := content.(type) {
-	case string:
-		return c
-	case []interface{}:
-		var text string
-		for _, part := range c {
-			if partMap, ok := part.(map[string]interface{}); ok {
-				if partType, ok := partMap["type"].(string); ok && partType == "text" {
-					if textVal, ok := partMap["text"].(string); ok {
-						text += textVal
-					}
-				}
-			}
-		}
-		return text
-	default:
-		return fmt.Sprintf("%v", content)
+// openAIServiceTier maps the tier Anthropic processed a request with to
+// OpenAI's service_tier
+func openAIServiceTier(tier string) string {
+	switch tier {
+	case "standard":
+		return "default"
+	case "priority":
+		return "priority"
 	}
+	return ""
 }
@@ -0,0 +1,115 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// TestTranslatePromptCache tests that messages marked with cache_control
+// are sent as text blocks carrying the marker
+func TestTranslatePromptCache(t *testing.T) {
+	req := &translator.ChatCompletionRequest{
+		Model: "claude-3-5-sonnet-20241022",
+		Messages: []translator.ChatMessage{
+			{Role: "system", Content: translator.TextContent("Long instructions"), CacheControl: &translator.CacheControl{Type: "ephemeral"}},
+			{Role: "user", Content: translator.TextContent("Document"), CacheControl: &translator.CacheControl{Type: "ephemeral", TTL: "1h"}},
+			{Role: "assistant", Content: translator.TextContent("Read it.")},
+			{Role: "user", Content: translator.TextContent("Summarize")},
+		},
+	}
+	body, err := json.Marshal(translateOpenAIToAnthropic(req))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var got struct {
+		System   []AnthropicContentBlock `json:"system"`
+		Messages []json.RawMessage       `json:"messages"`
+	}
+	if err := json.Unmarshal(body, &got); err != nil {
+		t.Fatalf("unmarshal %s: %v", body, err)
+	}
+	if len(got.System) != 1 || got.System[0].Text != "Long instructions" || got.System[0].CacheControl == nil {
+		t.Errorf("system = %+v, want one text block with cache_control", got.System)
+	}
+
+	var marked struct {
+		Content []AnthropicContentBlock `json:"content"`
+	}
+	if err := json.Unmarshal(got.Messages[0], &marked); err != nil {
+		t.Fatalf("marked message %s: %v", got.Messages[0], err)
+	}
+	if len(marked.Content) != 1 || marked.Content[0].CacheControl == nil || marked.Content[0].CacheControl.TTL != "1h" {
+		t.Errorf("marked message = %s, want a text block with cache_control ttl 1h", got.Messages[0])
+	}
+	var plain struct {
+		Content string `json:"content"`
+	}
+	if err := json.Unmarshal(got.Messages[2], &plain); err != nil || plain.Content != "Summarize" {
+		t.Errorf("unmarked message = %s, want string content", got.Messages[2])
+	}
+
+	// Without markers the system prompt stays a string
+	req.Messages[0].CacheControl = nil
+	if system := translateOpenAIToAnthropic(req).System; system != "Long instructions" {
+		t.Errorf("unmarked system = %#v, want a string", system)
+	}
+}
+
+// TestTranslateCacheUsage tests that cache token counts are reported in
+// usage and prompt_tokens_details
+func TestTranslateCacheUsage(t *testing.T) {
+	var resp AnthropicResponse
+	body := `{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"ok"}],
+		"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":3,
+		"cache_creation_input_tokens":2048,"cache_read_input_tokens":4096}}`
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	usage := translateAnthropicToOpenAI(&resp, "claude-3-5-sonnet").Usage
+	if usage.PromptTokens != 12 || usage.CacheReadInputTokens != 4096 || usage.CacheWriteInputTokens != 2048 {
+		t.Errorf("usage = %+v", usage)
+	}
+	if d := usage.PromptTokensDetails; d == nil || d.CachedTokens != 4096 || d.CacheCreationTokens != 2048 {
+		t.Errorf("prompt_tokens_details = %+v, want 4096 cached and 2048 created", d)
+	}
+
+	resp.Usage.CacheCreationInputTokens, resp.Usage.CacheReadInputTokens = 0, 0
+	if usage := translateAnthropicToOpenAI(&resp, "claude-3-5-sonnet").Usage; usage.PromptTokensDetails != nil {
+		t.Errorf("prompt_tokens_details without cache use: %+v", usage.PromptTokensDetails)
+	}
+}
+
+// TestInvokeRequestHeaders tests that request headers, such as forwarded
+// anthropic-beta, reach Anthropic alongside the provider's API key
+func TestInvokeRequestHeaders(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"msg_1","type":"message","role":"assistant","content":[{"type":"text","text":"Hi"}],"stop_reason":"end_turn","usage":{"input_tokens":1,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	p, err := NewAnthropicProvider(AnthropicConfig{APIKey: "sk-ant", BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Invoke(context.Background(), &providers.ProviderRequest{
+		Path:    "/chat/completions",
+		Headers: http.Header{"Anthropic-Beta": {"prompt-caching-2024-07-31"}},
+		Body:    []byte(`{"model":"claude-3-5-sonnet","messages":[{"role":"user","content":"Hi"}]}`),
+	})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if got.Get("Anthropic-Beta") != "prompt-caching-2024-07-31" || got.Get("X-Api-Key") != "sk-ant" || got.Get("Anthropic-Version") != defaultAPIVersion {
+		t.Errorf("upstream headers = %v", got)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package anthropic
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// countTokensRequest is the body of POST /v1/messages/count_tokens
+type countTokensRequest struct {
+	Model      string             `json:"model"`
+	Messages   []AnthropicMessage `json:"messages"`
+	System     interface{}        `json:"system,omitempty"`
+	Tools      []AnthropicTool    `json:"tools,omitempty"`
+	ToolChoice interface{}        `json:"tool_choice,omitempty"`
+}
+
+// CountTokens counts prompt tokens with the Messages count_tokens API
+func (p *AnthropicProvider) CountTokens(ctx context.Context, request *providers.ProviderRequest) (int, error) {
+	var openaiReq translator.ChatCompletionRequest
+	if err := json.Unmarshal(request.Body, &openaiReq); err != nil {
+		return 0, &providers.ProviderError{
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("failed to parse request: %v", err),
+			Provider:   "anthropic",
+		}
+	}
+
+	anthropicReq := translateOpenAIToAnthropic(&openaiReq)
+	body, err := json.Marshal(countTokensRequest{
+		Model:      anthropicReq.Model,
+		Messages:   anthropicReq.Messages,
+		System:     anthropicReq.System,
+		Tools:      anthropicReq.Tools,
+		ToolChoice: anthropicReq.ToolChoice,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal count_tokens request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/messages/count_tokens", bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	providers.SetCredentials(httpReq, request, "x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return 0, &providers.ProviderError{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    fmt.Sprintf("count_tokens request failed: %v", err),
+			Provider:   "anthropic",
+		}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read count_tokens response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, &providers.ProviderError{
+			StatusCode: resp.StatusCode,
+			Message:    string(respBody),
+			Provider:   "anthropic",
+		}
+	}
+
+	var result struct {
+		InputTokens int `json:"input_tokens"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse count_tokens response: %w", err)
+	}
+	return result.InputTokens, nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// filesBeta enables the Files API, which is in beta
+const filesBeta = "files-api-2025-04-14"
+
+// quoteEscaper escapes a multipart filename as mime/multipart does
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// AnthropicFile is a file stored with the Files API
+type AnthropicFile struct {
+	ID        string    `json:"id"`
+	Type      string    `json:"type"` // "file"
+	Filename  string    `json:"filename"`
+	MimeType  string    `json:"mime_type"`
+	Size      int64     `json:"size_bytes"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// anthropicFileList is the body of GET /v1/files
+type anthropicFileList struct {
+	Data    []AnthropicFile `json:"data"`
+	HasMore bool            `json:"has_more"`
+	LastID  string          `json:"last_id"`
+}
+
+// UploadFile stores content with the Files API. The content is streamed to
+// Anthropic as a multipart upload rather than buffered.
+func (p *AnthropicProvider) UploadFile(ctx context.Context, filename, contentType string, content io.Reader) (*AnthropicFile, error) {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+	go func() {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(filename)))
+		header.Set("Content-Type", contentType)
+		part, err := form.CreatePart(header)
+		if err == nil {
+			_, err = io.Copy(part, content)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	var file AnthropicFile
+	if err := p.doFiles(ctx, http.MethodPost, "/files", form.FormDataContentType(), body, &file); err != nil {
+		body.Close()
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListFiles returns all stored files, following pagination
+func (p *AnthropicProvider) ListFiles(ctx context.Context) ([]AnthropicFile, error) {
+	var files []AnthropicFile
+	query := url.Values{"limit": {"1000"}}
+	for {
+		var page anthropicFileList
+		if err := p.doFiles(ctx, http.MethodGet, "/files?"+query.Encode(), "", nil, &page); err != nil {
+			return nil, err
+		}
+		files = append(files, page.Data...)
+		if !page.HasMore || page.LastID == "" {
+			return files, nil
+		}
+		query.Set("after_id", page.LastID)
+	}
+}
+
+// GetFile returns the metadata of a stored file
+func (p *AnthropicProvider) GetFile(ctx context.Context, id string) (*AnthropicFile, error) {
+	var file AnthropicFile
+	if err := p.doFiles(ctx, http.MethodGet, "/files/"+url.PathEscape(id), "", nil, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// DeleteFile deletes a stored file
+func (p *AnthropicProvider) DeleteFile(ctx context.Context, id string) error {
+	return p.doFiles(ctx, http.MethodDelete, "/files/"+url.PathEscape(id), "", nil, nil)
+}
+
+// doFiles sends a Files API request and decodes the JSON response into out,
+// when out is not nil
+func (p *AnthropicProvider) doFiles(ctx context.Context, method, path, contentType string, body io.Reader, out interface{}) error {
+	httpReq, err := http.NewRequestWithContext(ctx, method, p.baseURL+path, body)
+	if err != nil {
+		return fmt.Errorf("failed to create files request: %w", err)
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	httpReq.Header.Set("x-api-key", p.apiKey)
+	httpReq.Header.Set("anthropic-version", "2023-06-01")
+	httpReq.Header.Set("anthropic-beta", filesBeta)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return &providers.ProviderError{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    fmt.Sprintf("files request failed: %v", err),
+			Provider:   "anthropic",
+		}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read files response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &providers.ProviderError{
+			StatusCode: resp.StatusCode,
+			Message:    strings.TrimSpace(string(respBody)),
+			Provider:   "anthropic",
+			Headers:    resp.Header.Clone(),
+		}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse files response: %w", err)
+	}
+	return nil
+}
@@ -4,13 +4,12 @@
 package azure
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
 )
@@ -28,6 +27,7 @@ type AzureConfig struct {
 	Endpoint   string `yaml:"endpoint"`   // Azure OpenAI endpoint
 	APIKey     string `yaml:"api_key"`    // Azure API key
 	APIVersion string `yaml:"api_version"` // API version
+	Timeouts   providers.HTTPTimeouts           // Optional, defaults to 10s connect and 120s request
 }
 
 // NewAzureProvider creates a new Azure OpenAI provider
@@ -46,9 +46,7 @@ func NewAzureProvider(config AzureConfig) (*AzureProvider, error) {
 		endpoint:   config.Endpoint,
 		apiKey:     config.APIKey,
 		apiVersion: config.APIVersion,
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		httpClient: providers.NewHTTPClient(config.Timeouts),
 	}, nil
 }
 
@@ -57,6 +55,17 @@ func (p *AzureProvider) Name() string {
 	return "azure"
 }
 
+// Capabilities reports what Azure OpenAI deployments support
+func (p *AzureProvider) Capabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Streaming:         true,
+		Vision:            true,
+		Tools:             true,
+		ParallelToolCalls: true,
+		MaxContextTokens:  128000,
+	}
+}
+
 // HealthCheck checks if the provider is accessible
 func (p *AzureProvider) HealthCheck(ctx context.Context) error {
 	// Try to list deployments as a health check
@@ -84,43 +93,9 @@ func (p *AzureProvider) HealthCheck(ctx context.Context) error {
 
 // Invoke sends a request to Azure OpenAI
 func (p *AzureProvider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
-	// Azure uses deployment names instead of model names
-	// The path should be /openai/deployments/{deployment-id}/chat/completions
-	deploymentID := extractDeploymentID(request.Path)
-	if deploymentID == "" {
-		return nil, &providers.ProviderError{
-			StatusCode: http.StatusBadRequest,
-			Message:    "deployment ID is required for Azure",
-			Provider:   "azure",
-		}
-	}
-
-	// Build Azure-specific URL
-	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
-		p.endpoint, deploymentID, p.apiVersion)
-
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, request.Method, url, bytes.NewReader(request.Body))
+	resp, err := p.do(ctx, request)
 	if err != nil {
-		return nil, &providers.ProviderError{
-			StatusCode: http.StatusInternalServerError,
-			Message:    fmt.Sprintf("failed to create request: %v", err),
-			Provider:   "azure",
-		}
-	}
-
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("api-key", p.apiKey)
-
-	// Send request
-	resp, err := p.httpClient.Do(httpReq)
-	if err != nil {
-		return nil, &providers.ProviderError{
-			StatusCode: http.StatusServiceUnavailable,
-			Message:    fmt.Sprintf("request failed: %v", err),
-			Provider:   "azure",
-		}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -134,22 +109,8 @@ func (p *AzureProvider) Invoke(ctx context.Context, request *providers.ProviderR
 		}
 	}
 
-	// Check for errors
-	if resp.StatusCode != http.StatusOK {
-		return nil, &providers.ProviderError{
-			StatusCode: resp.StatusCode,
-			Message:    string(body),
-			Provider:   "azure",
-		}
-	}
-
 	// Build provider response
-	headers := make(map[string]string)
-	for k, v := range resp.Header {
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
-	}
+	headers := resp.Header.Clone()
 
 	return &providers.ProviderResponse{
 		StatusCode: resp.StatusCode,
@@ -160,6 +121,33 @@ func (p *AzureProvider) Invoke(ctx context.Context, request *providers.ProviderR
 
 // InvokeStreaming sends a streaming request to Azure OpenAI
 func (p *AzureProvider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// InvokeStream sends a request to Azure OpenAI without buffering either body
+func (p *AzureProvider) InvokeStream(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderStreamResponse, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &providers.ProviderStreamResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       resp.Body,
+	}, nil
+}
+
+// do sends a request to the deployment named in the request path. On Your
+// Data sources in the body are validated and sent as they are. Error
+// statuses are returned as a ProviderError; otherwise the caller must close
+// the response body.
+func (p *AzureProvider) do(ctx context.Context, request *providers.ProviderRequest) (*http.Response, error) {
+	// Azure uses deployment names instead of model names
+	// The path should be /openai/deployments/{deployment-id}/chat/completions
 	deploymentID := extractDeploymentID(request.Path)
 	if deploymentID == "" {
 		return nil, &providers.ProviderError{
@@ -169,10 +157,16 @@ func (p *AzureProvider) InvokeStreaming(ctx context.Context, request *providers.
 		}
 	}
 
+	if err := p.checkExtensions(request); err != nil {
+		return nil, err
+	}
+
+	// Build Azure-specific URL
 	url := fmt.Sprintf("%s/openai/deployments/%s/chat/completions?api-version=%s",
 		p.endpoint, deploymentID, p.apiVersion)
 
-	httpReq, err := http.NewRequestWithContext(ctx, request.Method, url, bytes.NewReader(request.Body))
+	// Create HTTP request
+	httpReq, err := providers.NewBodyRequest(ctx, request.Method, url, request)
 	if err != nil {
 		return nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
@@ -181,9 +175,11 @@ func (p *AzureProvider) InvokeStreaming(ctx context.Context, request *providers.
 		}
 	}
 
+	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("api-key", p.apiKey)
+	providers.SetCredentials(httpReq, request, "api-key", p.apiKey)
 
+	// Send request
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, &providers.ProviderError{
@@ -193,6 +189,7 @@ func (p *AzureProvider) InvokeStreaming(ctx context.Context, request *providers.
 		}
 	}
 
+	// Check for errors
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 		body, _ := io.ReadAll(resp.Body)
@@ -203,7 +200,7 @@ func (p *AzureProvider) InvokeStreaming(ctx context.Context, request *providers.
 		}
 	}
 
-	return resp.Body, nil
+	return resp, nil
 }
 
 // ListModels lists available Azure OpenAI deployments
@@ -293,11 +290,14 @@ func (p *AzureProvider) GetModelInfo(ctx context.Context, modelID string) (*prov
 	}, nil
 }
 
-// extractDeploymentID extracts the deployment ID from the request path or metadata
+// extractDeploymentID extracts the deployment ID from the request path
 func extractDeploymentID(path string) string {
-	// Path could be /v1/chat/completions with deployment in metadata
-	// Or could already contain deployment ID
-	// For now, we'll expect it in the path or return empty
-	// This will be populated by the router based on model mapping
-	return ""
+	// Path is /deployments/{deployment-id}/chat/completions, with the
+	// deployment resolved from the model mapping by the handler
+	_, rest, ok := strings.Cut(path, "/deployments/")
+	if !ok {
+		return ""
+	}
+	deploymentID, _, _ := strings.Cut(rest, "/")
+	return deploymentID
 }
@@ -0,0 +1,85 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// minDataSourcesAPIVersion is the first API version taking data_sources in
+// the chat completions body. Earlier versions used the extensions endpoint.
+const minDataSourcesAPIVersion = "2024-02-15-preview"
+
+// AzureExtensions are the Azure OpenAI On Your Data fields of a chat
+// completion request, which ground the reply in the caller's own data
+type AzureExtensions struct {
+	DataSources []AzureDataSource `json:"data_sources,omitempty"`
+}
+
+// AzureDataSource is a data source searched for the reply, such as
+// azure_search with its endpoint, index_name and authentication parameters
+type AzureDataSource struct {
+	Type       string                 `json:"type"`
+	Parameters map[string]interface{} `json:"parameters"`
+}
+
+// AzureContext is the retrieval context Azure returns in the assistant
+// message of a grounded reply
+type AzureContext struct {
+	Citations []AzureCitation `json:"citations,omitempty"`
+	Intent    string          `json:"intent,omitempty"`
+}
+
+// AzureCitation is a retrieved document the reply cites as [docN]
+type AzureCitation struct {
+	Content  string `json:"content"`
+	Title    string `json:"title,omitempty"`
+	URL      string `json:"url,omitempty"`
+	Filepath string `json:"filepath,omitempty"`
+	ChunkID  string `json:"chunk_id,omitempty"`
+}
+
+// Validate checks that every data source has a type and parameters
+func (e *AzureExtensions) Validate() error {
+	for i, source := range e.DataSources {
+		if source.Type == "" {
+			return fmt.Errorf("data_sources[%d]: type is required", i)
+		}
+		if len(source.Parameters) == 0 {
+			return fmt.Errorf("data_sources[%d]: parameters are required for %s", i, source.Type)
+		}
+	}
+	return nil
+}
+
+// checkExtensions validates the data sources of a request body before it
+// is sent. Bodies that are streamed or are not JSON are left to Azure.
+func (p *AzureProvider) checkExtensions(request *providers.ProviderRequest) error {
+	if len(request.Body) == 0 {
+		return nil
+	}
+	var extensions AzureExtensions
+	if err := json.Unmarshal(request.Body, &extensions); err != nil || len(extensions.DataSources) == 0 {
+		return nil
+	}
+
+	err := extensions.Validate()
+	// API versions are dates, so they compare as strings
+	if err == nil && p.apiVersion < minDataSourcesAPIVersion {
+		err = fmt.Errorf("data_sources requires api_version %s or later, got %s", minDataSourcesAPIVersion, p.apiVersion)
+	}
+	if err != nil {
+		return &providers.ProviderError{
+			StatusCode: http.StatusBadRequest,
+			Code:       providers.ErrCodeInvalidRequest,
+			Message:    err.Error(),
+			Provider:   "azure",
+		}
+	}
+	return nil
+}
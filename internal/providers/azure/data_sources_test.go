@@ -0,0 +1,88 @@
+package azure
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+const groundedBody = `{"model":"gpt-4o","messages":[{"role":"user","content":"What is our leave policy?"}],` +
+	`"data_sources":[{"type":"azure_search","parameters":{"endpoint":"https://search.example.net","index_name":"hr-docs"}}]}`
+
+// TestInvokeDataSources tests that data sources reach Azure and that the
+// citations of the reply come back in the message context
+func TestInvokeDataSources(t *testing.T) {
+	var sent AzureExtensions
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &sent)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Twenty days [doc1].",` +
+			`"context":{"intent":"[\"leave policy\"]","citations":[{"content":"Employees get twenty days.","title":"Leave","filepath":"leave.md","chunk_id":"0"}]}}}]}`))
+	}))
+	defer server.Close()
+
+	p, err := NewAzureProvider(AzureConfig{Endpoint: server.URL, APIKey: "key"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := p.Invoke(context.Background(), &providers.ProviderRequest{Method: "POST", Path: "/deployments/gpt-4o/chat/completions", Body: []byte(groundedBody)})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if len(sent.DataSources) != 1 || sent.DataSources[0].Type != "azure_search" || sent.DataSources[0].Parameters["index_name"] != "hr-docs" {
+		t.Errorf("data_sources sent = %+v", sent.DataSources)
+	}
+
+	var reply struct {
+		Choices []struct {
+			Message struct {
+				Context AzureContext `json:"context"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(resp.Body, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if citations := reply.Choices[0].Message.Context.Citations; len(citations) != 1 || citations[0].Filepath != "leave.md" {
+		t.Errorf("citations = %+v", citations)
+	}
+}
+
+// TestInvokeInvalidDataSources tests that invalid data sources are rejected
+// before they reach Azure
+func TestInvokeInvalidDataSources(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("invalid request reached Azure")
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name       string
+		apiVersion string
+		body       string
+	}{
+		{"missing type", "", `{"data_sources":[{"parameters":{"index_name":"hr-docs"}}]}`},
+		{"missing parameters", "", `{"data_sources":[{"type":"azure_search"}]}`},
+		{"old API version", "2023-12-01-preview", groundedBody},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := NewAzureProvider(AzureConfig{Endpoint: server.URL, APIKey: "key", APIVersion: tt.apiVersion})
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = p.Invoke(context.Background(), &providers.ProviderRequest{Method: "POST", Path: "/deployments/gpt-4o/chat/completions", Body: []byte(tt.body)})
+			var providerErr *providers.ProviderError
+			if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusBadRequest || providerErr.Code != providers.ErrCodeInvalidRequest {
+				t.Errorf("Invoke error = %v, want a 400 invalid_request", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import (
+	"context"
+	"time"
+)
+
+// BatchProvider is implemented by providers that support asynchronous batch inference
+// (OpenAI Batch API, Bedrock batch inference). It is optional: callers should
+// type-assert a Provider to BatchProvider before use.
+type BatchProvider interface {
+	// SubmitBatch uploads the input and starts a batch job
+	SubmitBatch(ctx context.Context, request *BatchRequest) (*BatchJob, error)
+
+	// GetBatch returns the current state of a previously submitted job
+	GetBatch(ctx context.Context, providerBatchID string) (*BatchJob, error)
+}
+
+// BatchRequest describes a batch job to submit
+type BatchRequest struct {
+	// Unique job name/ID assigned by the proxy
+	JobName string
+
+	// Provider model ID used by every request in the batch
+	Model string
+
+	// Target endpoint (e.g. /v1/chat/completions)
+	Endpoint string
+
+	// Completion window requested by the client (e.g. 24h)
+	CompletionWindow string
+
+	// Input in the provider's native JSONL format
+	InputJSONL []byte
+
+	// Number of requests in the input
+	RequestCount int
+
+	// Client-supplied metadata
+	Metadata map[string]string
+}
+
+// BatchJob is the provider-side state of a batch job
+type BatchJob struct {
+	// Provider's identifier for the job (OpenAI batch ID, Bedrock job ARN)
+	ProviderBatchID string
+
+	// Normalised status (see BatchStatus* constants)
+	Status string
+
+	// Provider-specific input/output locations (file IDs or S3 URIs)
+	InputLocation  string
+	OutputLocation string
+	ErrorLocation  string
+
+	// Request counts, when reported by the provider
+	Total     int
+	Completed int
+	Failed    int
+
+	// Error message for failed jobs
+	Message string
+
+	// Timestamps reported by the provider (zero if unknown)
+	CompletedAt time.Time
+	FailedAt    time.Time
+	ExpiresAt   time.Time
+}
+
+// Batch status values (OpenAI batch object semantics)
+const (
+	BatchStatusValidating = "validating"
+	BatchStatusFailed     = "failed"
+	BatchStatusInProgress = "in_progress"
+	BatchStatusFinalizing = "finalizing"
+	BatchStatusCompleted  = "completed"
+	BatchStatusExpired    = "expired"
+	BatchStatusCancelling = "cancelling"
+	BatchStatusCancelled  = "cancelled"
+)
+
+// IsTerminalBatchStatus reports whether a batch status will no longer change
+func IsTerminalBatchStatus(status string) bool {
+	switch status {
+	case BatchStatusFailed, BatchStatusCompleted, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
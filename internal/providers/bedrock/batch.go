@@ -0,0 +1,267 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/storage"
+)
+
+// BatchConfig configures Bedrock batch inference.
+// Bedrock reads batch input from and writes output to S3, and assumes
+// RoleARN to do so.
+type BatchConfig struct {
+	// Storage used to upload the JSONL input
+	Storage storage.StorageProvider
+
+	// S3 location for batch input and output (s3://bucket/prefix)
+	S3URI string
+
+	// IAM service role Bedrock assumes to access S3
+	RoleARN string
+}
+
+// createJobRequest is the CreateModelInvocationJob request body
+type createJobRequest struct {
+	JobName          string          `json:"jobName"`
+	RoleArn          string          `json:"roleArn"`
+	ModelID          string          `json:"modelId"`
+	InputDataConfig  json.RawMessage `json:"inputDataConfig"`
+	OutputDataConfig json.RawMessage `json:"outputDataConfig"`
+}
+
+// invocationJob is the subset of GetModelInvocationJob we use
+type invocationJob struct {
+	JobArn            string    `json:"jobArn"`
+	Status            string    `json:"status"`
+	Message           string    `json:"message"`
+	EndTime           time.Time `json:"endTime"`
+	JobExpirationTime time.Time `json:"jobExpirationTime"`
+	InputDataConfig   struct {
+		S3InputDataConfig struct {
+			S3URI string `json:"s3Uri"`
+		} `json:"s3InputDataConfig"`
+	} `json:"inputDataConfig"`
+	OutputDataConfig struct {
+		S3OutputDataConfig struct {
+			S3URI string `json:"s3Uri"`
+		} `json:"s3OutputDataConfig"`
+	} `json:"outputDataConfig"`
+}
+
+// EnableBatch enables batch inference for this provider
+func (p *BedrockProvider) EnableBatch(config BatchConfig) error {
+	if config.Storage == nil {
+		return fmt.Errorf("batch storage is required")
+	}
+	if !strings.HasPrefix(config.S3URI, "s3://") {
+		return fmt.Errorf("batch S3 URI must start with s3://: %q", config.S3URI)
+	}
+	if config.RoleARN == "" {
+		return fmt.Errorf("batch role ARN is required")
+	}
+	config.S3URI = strings.TrimSuffix(config.S3URI, "/")
+	p.batch = &config
+	return nil
+}
+
+// SubmitBatch uploads the JSONL input to S3 and creates a model invocation job.
+// The input must already be in Bedrock's {"recordId","modelInput"} record format.
+func (p *BedrockProvider) SubmitBatch(ctx context.Context, request *providers.BatchRequest) (*providers.BatchJob, error) {
+	if p.batch == nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusNotImplemented,
+			Code:       providers.ErrCodeInvalidRequest,
+			Message:    "Batch inference is not configured for Bedrock",
+		}
+	}
+
+	bucket, prefix := splitS3URI(p.batch.S3URI)
+	inputKey := joinKey(prefix, request.JobName, "input.jsonl")
+
+	_, err := p.batch.Storage.PutObject(ctx, &storage.PutObjectRequest{
+		Bucket:      bucket,
+		Key:         inputKey,
+		Body:        bytes.NewReader(request.InputJSONL),
+		ContentType: "application/jsonl",
+	})
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusBadGateway,
+			Code:       providers.ErrCodeServiceUnavailable,
+			Message:    "Failed to upload batch input",
+			Err:        err,
+		}
+	}
+
+	inputURI := fmt.Sprintf("s3://%s/%s", bucket, inputKey)
+	outputURI := fmt.Sprintf("s3://%s/%s/", bucket, joinKey(prefix, request.JobName, "output"))
+
+	body, err := json.Marshal(createJobRequest{
+		JobName:          request.JobName,
+		RoleArn:          p.batch.RoleARN,
+		ModelID:          request.Model,
+		InputDataConfig:  json.RawMessage(fmt.Sprintf(`{"s3InputDataConfig":{"s3Uri":%q,"s3InputFormat":"JSONL"}}`, inputURI)),
+		OutputDataConfig: json.RawMessage(fmt.Sprintf(`{"s3OutputDataConfig":{"s3Uri":%q}}`, outputURI)),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch job request: %w", err)
+	}
+
+	respBody, err := p.controlPlaneRequest(ctx, http.MethodPost, "/model-invocation-job", body)
+	if err != nil {
+		return nil, err
+	}
+
+	var created struct {
+		JobArn string `json:"jobArn"`
+	}
+	if err := json.Unmarshal(respBody, &created); err != nil {
+		return nil, fmt.Errorf("failed to parse batch job response: %w", err)
+	}
+
+	return &providers.BatchJob{
+		ProviderBatchID: created.JobArn,
+		Status:          providers.BatchStatusValidating,
+		InputLocation:   inputURI,
+		OutputLocation:  outputURI,
+		Total:           request.RequestCount,
+	}, nil
+}
+
+// GetBatch retrieves a model invocation job by ARN
+func (p *BedrockProvider) GetBatch(ctx context.Context, providerBatchID string) (*providers.BatchJob, error) {
+	respBody, err := p.controlPlaneRequest(ctx, http.MethodGet, "/model-invocation-job/"+url.PathEscape(providerBatchID), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var job invocationJob
+	if err := json.Unmarshal(respBody, &job); err != nil {
+		return nil, fmt.Errorf("failed to parse batch job: %w", err)
+	}
+
+	result := &providers.BatchJob{
+		ProviderBatchID: job.JobArn,
+		Status:          mapBatchStatus(job.Status),
+		InputLocation:   job.InputDataConfig.S3InputDataConfig.S3URI,
+		OutputLocation:  job.OutputDataConfig.S3OutputDataConfig.S3URI,
+		Message:         job.Message,
+		ExpiresAt:       job.JobExpirationTime,
+	}
+	switch result.Status {
+	case providers.BatchStatusCompleted:
+		result.CompletedAt = job.EndTime
+	case providers.BatchStatusFailed:
+		result.FailedAt = job.EndTime
+	}
+
+	return result, nil
+}
+
+// controlPlaneRequest sends a signed request to the Bedrock control plane
+// (bedrock.<region>.amazonaws.com), which hosts the batch job APIs
+func (p *BedrockProvider) controlPlaneRequest(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	endpoint := fmt.Sprintf("https://bedrock.%s.amazonaws.com%s", p.region, path)
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider: p.Name(),
+			Code:     providers.ErrCodeInternalError,
+			Message:  "Failed to create request",
+			Err:      err,
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	if err := p.signer.SignRequest(req, body); err != nil {
+		return nil, &providers.ProviderError{
+			Provider: p.Name(),
+			Code:     providers.ErrCodeAuthenticationFail,
+			Message:  "Failed to sign request",
+			Err:      err,
+		}
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider: p.Name(),
+			Code:     providers.ErrCodeServiceUnavailable,
+			Message:  "Request failed",
+			Err:      err,
+		}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider: p.Name(),
+			Code:     providers.ErrCodeInternalError,
+			Message:  "Failed to read response",
+			Err:      err,
+		}
+	}
+
+	if resp.StatusCode >= 400 {
+		return nil, p.handleErrorResponse(resp.StatusCode, respBody)
+	}
+
+	return respBody, nil
+}
+
+// mapBatchStatus maps Bedrock job statuses to OpenAI batch statuses
+func mapBatchStatus(status string) string {
+	switch status {
+	case "Submitted", "Validating", "Scheduled":
+		return providers.BatchStatusValidating
+	case "InProgress":
+		return providers.BatchStatusInProgress
+	case "Completed", "PartiallyCompleted":
+		return providers.BatchStatusCompleted
+	case "Failed":
+		return providers.BatchStatusFailed
+	case "Stopping":
+		return providers.BatchStatusCancelling
+	case "Stopped":
+		return providers.BatchStatusCancelled
+	case "Expired":
+		return providers.BatchStatusExpired
+	default:
+		return providers.BatchStatusInProgress
+	}
+}
+
+// splitS3URI splits s3://bucket/prefix into bucket and prefix
+func splitS3URI(uri string) (string, string) {
+	path := strings.TrimPrefix(uri, "s3://")
+	bucket, prefix, _ := strings.Cut(path, "/")
+	return bucket, prefix
+}
+
+// joinKey joins S3 key segments, skipping empty ones
+func joinKey(parts ...string) string {
+	nonEmpty := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part != "" {
+			nonEmpty = append(nonEmpty, strings.Trim(part, "/"))
+		}
+	}
+	return strings.Join(nonEmpty, "/")
+}
@@ -6,10 +6,13 @@ package bedrock
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/tosharewith/llmproxy_auth/internal/auth"
@@ -18,43 +21,129 @@ import (
 
 // BedrockProvider implements the Provider interface for AWS Bedrock
 type BedrockProvider struct {
+	name      string
 	region    string
 	baseURL   string
 	signer    *auth.AWSSigner
 	httpClient *http.Client
+
+	// roleCredentials are the assumed role's credentials (nil if no role
+	// is assumed)
+	roleCredentials *auth.RoleCredentials
+
+	// Batch inference configuration (nil if batch is not enabled)
+	batch *BatchConfig
+}
+
+// BedrockConfig configures a Bedrock provider
+type BedrockConfig struct {
+	// Region of the Bedrock runtime endpoint
+	Region string
+
+	// Timeouts bound requests (default: 10s connect, 120s request)
+	Timeouts providers.HTTPTimeouts
+
+	// AssumeRoleARN, if set, is assumed to call Bedrock from another
+	// account. The roles of RoleChain are assumed first, in order, each
+	// with the previous role's credentials.
+	AssumeRoleARN string
+	RoleChain     []string
+
+	// ExternalID is sent with each AssumeRole call
+	ExternalID string
+
+	// Endpoint replaces the regional runtime endpoint, such as for a VPC
+	// endpoint (optional)
+	Endpoint string
 }
 
-// NewBedrockProvider creates a new Bedrock provider
-func NewBedrockProvider(region string) (*BedrockProvider, error) {
+// roleARNs returns the roles to assume, in order
+func (c BedrockConfig) roleARNs() []string {
+	if c.AssumeRoleARN == "" {
+		return nil
+	}
+	return append(append([]string(nil), c.RoleChain...), c.AssumeRoleARN)
+}
+
+// NewBedrockProvider creates a new Bedrock provider. When a role is
+// configured it is assumed before the provider is created, and its
+// credentials are refreshed in the background.
+func NewBedrockProvider(config BedrockConfig) (*BedrockProvider, error) {
 	// Create AWS signer
-	signer, err := auth.NewAWSSigner(region, "bedrock")
+	signer, err := auth.NewAWSSigner(config.Region, "bedrock")
 	if err != nil {
 		return nil, fmt.Errorf("failed to create AWS signer: %w", err)
 	}
 
-	// Create HTTP client with reasonable timeout
+	var roleCredentials *auth.RoleCredentials
+	if roles := config.roleARNs(); len(roles) > 0 {
+		roleCredentials, err = auth.NewRoleCredentials(context.Background(), auth.AssumeRoleConfig{
+			RoleARNs:   roles,
+			ExternalID: config.ExternalID,
+			Region:     config.Region,
+		})
+		if err != nil {
+			return nil, err
+		}
+		signer.SetCredentials(roleCredentials)
+	}
+
+	// Create HTTP client with connection pooling
+	transport := providers.NewTransport(config.Timeouts)
+	transport.MaxIdleConns = 100
+	transport.MaxIdleConnsPerHost = 10
+	transport.IdleConnTimeout = 90 * time.Second
 	httpClient := &http.Client{
-		Timeout: 120 * time.Second,
-		Transport: &http.Transport{
-			MaxIdleConns:        100,
-			MaxIdleConnsPerHost: 10,
-			IdleConnTimeout:     90 * time.Second,
-		},
+		Transport: providers.WithRequestTimeout(transport, config.Timeouts.WithDefaults().Request),
 	}
 
-	baseURL := fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", region)
+	baseURL := fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", config.Region)
+	if config.Endpoint != "" {
+		baseURL = strings.TrimSuffix(config.Endpoint, "/")
+	}
 
 	return &BedrockProvider{
-		region:     region,
-		baseURL:    baseURL,
-		signer:     signer,
-		httpClient: httpClient,
+		name:            "bedrock",
+		region:          config.Region,
+		baseURL:         baseURL,
+		signer:          signer,
+		roleCredentials: roleCredentials,
+		httpClient:      httpClient,
 	}, nil
 }
 
 // Name returns the provider identifier
 func (p *BedrockProvider) Name() string {
-	return "bedrock"
+	return p.name
+}
+
+// Capabilities reports what Bedrock's chat models support; Amazon Nova
+// accepts video
+func (p *BedrockProvider) Capabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Streaming:         true,
+		Vision:            true,
+		Video:             true,
+		Tools:             true,
+		ParallelToolCalls: true,
+		MaxContextTokens:  200000,
+		ServiceTiers:      []string{"default", "priority"}, // as performanceConfig latency
+	}
+}
+
+// BaseURL returns the endpoint requests are sent to
+func (p *BedrockProvider) BaseURL() string {
+	return p.baseURL
+}
+
+// SignsPayload reports true: SigV4 signatures cover the body hash
+func (p *BedrockProvider) SignsPayload() bool {
+	return true
+}
+
+// Authorize signs a forwarded request with AWS Signature V4
+func (p *BedrockProvider) Authorize(req *http.Request, payloadHash string) error {
+	return p.signer.SignRequestWithPayloadHash(req, payloadHash)
 }
 
 // HealthCheck verifies the provider is accessible
@@ -88,55 +177,9 @@ func (p *BedrockProvider) HealthCheck(ctx context.Context) error {
 func (p *BedrockProvider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
 	startTime := time.Now()
 
-	// Build full URL
-	url := p.baseURL + request.Path
-
-	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, request.Method, url, bytes.NewReader(request.Body))
-	if err != nil {
-		return nil, &providers.ProviderError{
-			Provider:   p.Name(),
-			Code:       providers.ErrCodeInternalError,
-			Message:    "Failed to create request",
-			Err:        err,
-		}
-	}
-
-	// Set headers
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/json")
-	for key, value := range request.Headers {
-		req.Header.Set(key, value)
-	}
-
-	// Add query parameters
-	if len(request.QueryParams) > 0 {
-		q := req.URL.Query()
-		for key, value := range request.QueryParams {
-			q.Add(key, value)
-		}
-		req.URL.RawQuery = q.Encode()
-	}
-
-	// Sign the request with AWS Signature V4
-	if err := p.signer.SignRequest(req, request.Body); err != nil {
-		return nil, &providers.ProviderError{
-			Provider:   p.Name(),
-			Code:       providers.ErrCodeAuthenticationFail,
-			Message:    "Failed to sign request",
-			Err:        err,
-		}
-	}
-
-	// Send request
-	resp, err := p.httpClient.Do(req)
+	resp, err := p.do(ctx, request, "application/json")
 	if err != nil {
-		return nil, &providers.ProviderError{
-			Provider:   p.Name(),
-			Code:       providers.ErrCodeServiceUnavailable,
-			Message:    "Request failed",
-			Err:        err,
-		}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -151,16 +194,11 @@ func (p *BedrockProvider) Invoke(ctx context.Context, request *providers.Provide
 		}
 	}
 
-	// Handle error responses
-	if resp.StatusCode >= 400 {
-		return nil, p.handleErrorResponse(resp.StatusCode, respBody)
-	}
-
 	// Build response
 	latency := time.Since(startTime)
 	response := &providers.ProviderResponse{
 		StatusCode: resp.StatusCode,
-		Headers:    make(map[string]string),
+		Headers:    resp.Header.Clone(),
 		Body:       respBody,
 		Metadata: providers.ResponseMetadata{
 			Latency:    latency,
@@ -168,43 +206,83 @@ func (p *BedrockProvider) Invoke(ctx context.Context, request *providers.Provide
 		},
 	}
 
-	// Copy response headers
-	for key := range resp.Header {
-		response.Headers[key] = resp.Header.Get(key)
-	}
-
 	return response, nil
 }
 
 // InvokeStreaming handles streaming responses
 func (p *BedrockProvider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
+	resp, err := p.do(ctx, request, "application/vnd.amazon.eventstream")
+	if err != nil {
+		return nil, err
+	}
+
+	// Return the response body as a ReadCloser
+	return resp.Body, nil
+}
+
+// InvokeStream sends a request to Bedrock without buffering the response.
+// The request body is streamed when BodyReader is seekable; other readers
+// are buffered, since the signature covers the body hash.
+func (p *BedrockProvider) InvokeStream(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderStreamResponse, error) {
+	resp, err := p.do(ctx, request, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	return &providers.ProviderStreamResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       resp.Body,
+	}, nil
+}
+
+// do builds, signs and sends a request. Error statuses are returned as a
+// ProviderError; otherwise the caller must close the response body.
+func (p *BedrockProvider) do(ctx context.Context, request *providers.ProviderRequest, accept string) (*http.Response, error) {
+	// Hash the body before it is read for sending
+	hash, err := payloadHash(request)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.Name(),
+			Code:       providers.ErrCodeInvalidRequest,
+			Message:    "Failed to read request body",
+			Err:        err,
+		}
+	}
+
 	// Build full URL
 	url := p.baseURL + request.Path
 
 	// Create HTTP request
-	req, err := http.NewRequestWithContext(ctx, request.Method, url, bytes.NewReader(request.Body))
+	req, err := providers.NewBodyRequest(ctx, request.Method, url, request)
 	if err != nil {
 		return nil, &providers.ProviderError{
 			Provider:   p.Name(),
 			Code:       providers.ErrCodeInternalError,
-			Message:    "Failed to create streaming request",
+			Message:    "Failed to create request",
 			Err:        err,
 		}
 	}
 
 	// Set headers
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Accept", "application/vnd.amazon.eventstream")
-	for key, value := range request.Headers {
-		req.Header.Set(key, value)
+	req.Header.Set("Accept", accept)
+	providers.ApplyHeaders(req.Header, request.Headers)
+
+	// Add query parameters
+	if len(request.QueryParams) > 0 {
+		q := req.URL.Query()
+		for key, value := range request.QueryParams {
+			q.Add(key, value)
+		}
+		req.URL.RawQuery = q.Encode()
 	}
 
-	// Sign the request
-	if err := p.signer.SignRequest(req, request.Body); err != nil {
+	// Sign the request with AWS Signature V4
+	if err := p.signer.SignRequestWithPayloadHash(req, hash); err != nil {
 		return nil, &providers.ProviderError{
 			Provider:   p.Name(),
 			Code:       providers.ErrCodeAuthenticationFail,
-			Message:    "Failed to sign streaming request",
+			Message:    "Failed to sign request",
 			Err:        err,
 		}
 	}
@@ -215,20 +293,45 @@ func (p *BedrockProvider) InvokeStreaming(ctx context.Context, request *provider
 		return nil, &providers.ProviderError{
 			Provider:   p.Name(),
 			Code:       providers.ErrCodeServiceUnavailable,
-			Message:    "Streaming request failed",
+			Message:    "Request failed",
 			Err:        err,
 		}
 	}
 
-	// Check for error status
+	// Handle error responses
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
 		resp.Body.Close()
 		return nil, p.handleErrorResponse(resp.StatusCode, body)
 	}
 
-	// Return the response body as a ReadCloser
-	return resp.Body, nil
+	return resp, nil
+}
+
+// payloadHash returns the hex SHA-256 of the request body for signing. A
+// seekable BodyReader is hashed and rewound so it can still be streamed;
+// any other BodyReader is buffered into Body.
+func payloadHash(request *providers.ProviderRequest) (string, error) {
+	if seeker, ok := request.BodyReader.(io.ReadSeeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return "", err
+		}
+		hash := sha256.New()
+		if _, err := io.Copy(hash, seeker); err != nil {
+			return "", err
+		}
+		if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	if err := request.ReadBody(); err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(request.Body)
+	return hex.EncodeToString(sum[:]), nil
 }
 
 // ListModels returns available Bedrock models
@@ -0,0 +1,187 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// converseStreamEvent is the payload of a ConverseStream event; which
+// fields are set depends on the :event-type header
+type converseStreamEvent struct {
+	Role              string `json:"role"`
+	ContentBlockIndex int    `json:"contentBlockIndex"`
+	Start             *struct {
+		ToolUse *struct {
+			ToolUseID string `json:"toolUseId"`
+			Name      string `json:"name"`
+		} `json:"toolUse"`
+	} `json:"start"`
+	Delta *struct {
+		Text    string `json:"text"`
+		ToolUse *struct {
+			Input string `json:"input"`
+		} `json:"toolUse"`
+	} `json:"delta"`
+	StopReason string `json:"stopReason"`
+	Usage      *struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+		TotalTokens  int `json:"totalTokens"`
+
+		CacheReadInputTokenCount  int `json:"cacheReadInputTokenCount"`
+		CacheWriteInputTokenCount int `json:"cacheWriteInputTokenCount"`
+	} `json:"usage"`
+	Message string `json:"message"` // exceptions
+}
+
+// streamExceptionCodes maps ConverseStream exception types to error codes
+var streamExceptionCodes = map[string]string{
+	"throttlingException":         providers.ErrCodeRateLimitExceeded,
+	"validationException":         providers.ErrCodeInvalidRequest,
+	"serviceUnavailableException": providers.ErrCodeServiceUnavailable,
+	"modelStreamErrorException":   providers.ErrCodeInternalError,
+	"internalServerException":     providers.ErrCodeInternalError,
+}
+
+// InvokeChatStream sends a Converse request to the ConverseStream API and
+// decodes the event stream response into events
+func (p *BedrockProvider) InvokeChatStream(ctx context.Context, request *providers.ChatRequest) (<-chan providers.ChatEvent, error) {
+	providerReq := *request.Request
+	if path, ok := strings.CutSuffix(providerReq.Path, "/converse"); ok {
+		providerReq.Path = path + "/converse-stream"
+	}
+
+	resp, err := p.do(ctx, &providerReq, "application/vnd.amazon.eventstream")
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan providers.ChatEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		p.readConverseStream(ctx, resp.Body, events)
+	}()
+	return events, nil
+}
+
+// readConverseStream sends the events of a ConverseStream response, ending
+// with ChatEventDone at the end of the stream or ChatEventError
+func (p *BedrockProvider) readConverseStream(ctx context.Context, body io.Reader, events chan<- providers.ChatEvent) {
+	send := func(event providers.ChatEvent) bool {
+		return providers.SendChatEvent(ctx, events, event)
+	}
+	fail := func(code, message string, err error) {
+		send(providers.ChatEvent{Type: providers.ChatEventError, Err: &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusBadGateway,
+			Code:       code,
+			Message:    message,
+			Err:        err,
+		}})
+	}
+
+	decoder := eventstream.NewDecoder()
+	var payloadBuf []byte
+	toolCalls := make(map[int]int) // content block index -> tool call index
+	stopReason := ""
+	for {
+		msg, err := decoder.Decode(body, payloadBuf)
+		if errors.Is(err, io.EOF) {
+			send(providers.ChatEvent{Type: providers.ChatEventDone, FinishReason: stopReason})
+			return
+		}
+		if err != nil {
+			fail(providers.ErrCodeInternalError, "Failed to read stream", err)
+			return
+		}
+		payloadBuf = msg.Payload[:0]
+
+		var event converseStreamEvent
+		if err := json.Unmarshal(msg.Payload, &event); err != nil {
+			fail(providers.ErrCodeInternalError, "Failed to read stream", fmt.Errorf("invalid event: %w", err))
+			return
+		}
+
+		if messageType := headerString(msg.Headers, ":message-type"); messageType != "event" {
+			exceptionType := headerString(msg.Headers, ":exception-type")
+			code, ok := streamExceptionCodes[exceptionType]
+			if !ok {
+				code = providers.ErrCodeInternalError
+			}
+			fail(code, fmt.Sprintf("%s: %s", exceptionType, event.Message), nil)
+			return
+		}
+
+		var out providers.ChatEvent
+		switch headerString(msg.Headers, ":event-type") {
+		case "messageStart":
+			out = providers.ChatEvent{Type: providers.ChatEventDelta, Role: event.Role}
+		case "contentBlockStart":
+			if event.Start == nil || event.Start.ToolUse == nil {
+				continue
+			}
+			index := len(toolCalls)
+			toolCalls[event.ContentBlockIndex] = index
+			out = providers.ChatEvent{Type: providers.ChatEventToolCallDelta, ToolCall: &providers.ToolCallDelta{
+				Index: index,
+				ID:    event.Start.ToolUse.ToolUseID,
+				Name:  event.Start.ToolUse.Name,
+			}}
+		case "contentBlockDelta":
+			switch {
+			case event.Delta == nil:
+				continue
+			case event.Delta.ToolUse != nil:
+				out = providers.ChatEvent{Type: providers.ChatEventToolCallDelta, ToolCall: &providers.ToolCallDelta{
+					Index:     toolCalls[event.ContentBlockIndex],
+					Arguments: event.Delta.ToolUse.Input,
+				}}
+			case event.Delta.Text != "":
+				out = providers.ChatEvent{Type: providers.ChatEventDelta, Text: event.Delta.Text}
+			default:
+				continue
+			}
+		case "messageStop":
+			// The stream ends after the metadata event that follows
+			stopReason = event.StopReason
+			continue
+		case "metadata":
+			if event.Usage == nil {
+				continue
+			}
+			out = providers.ChatEvent{Type: providers.ChatEventUsage, Usage: &providers.ChatUsage{
+				InputTokens:  event.Usage.InputTokens,
+				OutputTokens: event.Usage.OutputTokens,
+				TotalTokens:  event.Usage.TotalTokens,
+
+				CacheReadTokens:  event.Usage.CacheReadInputTokenCount,
+				CacheWriteTokens: event.Usage.CacheWriteInputTokenCount,
+			}}
+		default:
+			continue
+		}
+		if !send(out) {
+			return
+		}
+	}
+}
+
+// headerString returns the value of an event stream header, or ""
+func headerString(headers eventstream.Headers, name string) string {
+	if value := headers.Get(name); value != nil {
+		return value.String()
+	}
+	return ""
+}
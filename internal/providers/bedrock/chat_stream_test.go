@@ -0,0 +1,98 @@
+package bedrock
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// converseStream encodes ConverseStream messages; an eventType starting
+// with "!" is an exception of that type
+func converseStream(t *testing.T, messages ...[2]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	encoder := eventstream.NewEncoder()
+	for _, m := range messages {
+		var headers eventstream.Headers
+		if exceptionType, ok := strings.CutPrefix(m[0], "!"); ok {
+			headers.Set(":message-type", eventstream.StringValue("exception"))
+			headers.Set(":exception-type", eventstream.StringValue(exceptionType))
+		} else {
+			headers.Set(":message-type", eventstream.StringValue("event"))
+			headers.Set(":event-type", eventstream.StringValue(m[0]))
+		}
+		if err := encoder.Encode(&buf, eventstream.Message{Headers: headers, Payload: []byte(m[1])}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &buf
+}
+
+func readEvents(t *testing.T, stream *bytes.Buffer) []providers.ChatEvent {
+	t.Helper()
+	events := make(chan providers.ChatEvent)
+	go func() {
+		defer close(events)
+		(&BedrockProvider{name: "bedrock"}).readConverseStream(context.Background(), stream, events)
+	}()
+
+	var got []providers.ChatEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	return got
+}
+
+func TestReadConverseStream(t *testing.T) {
+	got := readEvents(t, converseStream(t,
+		[2]string{"messageStart", `{"role":"assistant"}`},
+		[2]string{"contentBlockDelta", `{"contentBlockIndex":0,"delta":{"text":"Let me check"}}`},
+		[2]string{"contentBlockStop", `{"contentBlockIndex":0}`},
+		[2]string{"contentBlockStart", `{"contentBlockIndex":1,"start":{"toolUse":{"toolUseId":"tooluse_1","name":"weather"}}}`},
+		[2]string{"contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"{\"city\":"}}}`},
+		[2]string{"contentBlockDelta", `{"contentBlockIndex":1,"delta":{"toolUse":{"input":"\"Paris\"}"}}}`},
+		[2]string{"contentBlockStop", `{"contentBlockIndex":1}`},
+		[2]string{"messageStop", `{"stopReason":"tool_use"}`},
+		[2]string{"metadata", `{"usage":{"inputTokens":10,"outputTokens":20,"totalTokens":30},"metrics":{"latencyMs":100}}`},
+	))
+
+	if len(got) != 7 {
+		t.Fatalf("got %d events: %+v", len(got), got)
+	}
+	if got[0].Role != "assistant" || got[1].Type != providers.ChatEventDelta || got[1].Text != "Let me check" {
+		t.Errorf("deltas = %+v, %+v", got[0], got[1])
+	}
+	// The tool call is the first, although it is the second content block
+	if call := got[2].ToolCall; call == nil || call.Index != 0 || call.ID != "tooluse_1" || call.Name != "weather" {
+		t.Errorf("tool call start = %+v", got[2])
+	}
+	if args := got[3].ToolCall.Arguments + got[4].ToolCall.Arguments; args != `{"city":"Paris"}` || got[4].ToolCall.Index != 0 {
+		t.Errorf("tool call arguments = %q", args)
+	}
+	if got[5].Type != providers.ChatEventUsage || got[5].Usage.InputTokens != 10 || got[5].Usage.TotalTokens != 30 {
+		t.Errorf("usage = %+v", got[5])
+	}
+	if got[6].Type != providers.ChatEventDone || got[6].FinishReason != "tool_use" {
+		t.Errorf("done = %+v", got[6])
+	}
+}
+
+func TestReadConverseStreamException(t *testing.T) {
+	got := readEvents(t, converseStream(t,
+		[2]string{"messageStart", `{"role":"assistant"}`},
+		[2]string{"!throttlingException", `{"message":"Too many requests"}`},
+	))
+
+	if len(got) != 2 || got[1].Type != providers.ChatEventError {
+		t.Fatalf("events = %+v, want a delta then an error", got)
+	}
+	var providerErr *providers.ProviderError
+	if !errors.As(got[1].Err, &providerErr) || providerErr.Code != providers.ErrCodeRateLimitExceeded {
+		t.Errorf("error = %v, want rate_limit_exceeded", got[1].Err)
+	}
+}
@@ -0,0 +1,67 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// perMessageTokens approximates the role and separator tokens added per message
+const perMessageTokens = 3
+
+// textFields are the keys whose string values carry prompt text across the
+// Bedrock model families (Anthropic messages, Titan, Llama, Mistral)
+var textFields = map[string]bool{
+	"system":    true,
+	"content":   true,
+	"text":      true,
+	"prompt":    true,
+	"inputText": true,
+}
+
+// CountTokens approximates prompt tokens locally. Bedrock has no counting
+// API, so the native request body is walked and its text estimated with
+// providers.ApproximateTokens; no request is sent.
+func (p *BedrockProvider) CountTokens(ctx context.Context, request *providers.ProviderRequest) (int, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(request.Body, &body); err != nil {
+		return 0, fmt.Errorf("failed to parse request: %w", err)
+	}
+
+	tokens := countTextTokens(body, false)
+	if messages, ok := body["messages"].([]interface{}); ok {
+		tokens += perMessageTokens * len(messages)
+	}
+	return tokens, nil
+}
+
+// countTextTokens sums the approximate tokens of all text strings in value;
+// inText reports whether value sits under a text-bearing key
+func countTextTokens(value interface{}, inText bool) int {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		tokens := 0
+		for key, inner := range v {
+			tokens += countTextTokens(inner, textFields[key])
+		}
+		return tokens
+	case []interface{}:
+		tokens := 0
+		for _, inner := range v {
+			tokens += countTextTokens(inner, inText)
+		}
+		return tokens
+	case string:
+		if inText {
+			return providers.ApproximateTokens(v)
+		}
+		return 0
+	default:
+		return 0
+	}
+}
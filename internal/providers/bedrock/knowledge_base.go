@@ -0,0 +1,80 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bedrock
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/tosharewith/llmproxy_auth/internal/auth"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// KnowledgeBaseSigningService is the SigV4 signing name for bedrock-agent-runtime.
+// AWS signs Agents for Bedrock Runtime requests with the "bedrock" service name.
+const KnowledgeBaseSigningService = "bedrock"
+
+// KnowledgeBaseProvider implements the Provider interface for Bedrock
+// Knowledge Bases (bedrock-agent-runtime RetrieveAndGenerate). Requests are
+// SigV4-signed and sent as-is to the agent runtime endpoint.
+type KnowledgeBaseProvider struct {
+	*BedrockProvider
+}
+
+// NewKnowledgeBaseProvider creates a new Bedrock Knowledge Base provider.
+// An empty signingService defaults to KnowledgeBaseSigningService.
+func NewKnowledgeBaseProvider(config BedrockConfig, signingService string) (*KnowledgeBaseProvider, error) {
+	if signingService == "" {
+		signingService = KnowledgeBaseSigningService
+	}
+
+	base, err := NewBedrockProvider(config)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := auth.NewAWSSigner(config.Region, signingService)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS signer: %w", err)
+	}
+	if base.roleCredentials != nil {
+		signer.SetCredentials(base.roleCredentials)
+	}
+
+	base.name = "bedrock_kb"
+	base.baseURL = fmt.Sprintf("https://bedrock-agent-runtime.%s.amazonaws.com", config.Region)
+	base.signer = signer
+
+	return &KnowledgeBaseProvider{BedrockProvider: base}, nil
+}
+
+// HealthCheck verifies that AWS credentials can be resolved and used to sign.
+// The agent runtime has no cheap read-only call that needs no knowledge base ID.
+func (p *KnowledgeBaseProvider) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/retrieveAndGenerate", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+
+	if err := p.signer.SignRequest(req, nil); err != nil {
+		return fmt.Errorf("failed to sign health check request: %w", err)
+	}
+
+	return nil
+}
+
+// ListModels returns no models; the generation model is set per instance
+func (p *KnowledgeBaseProvider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	return []providers.Model{}, nil
+}
+
+// GetModelInfo is not supported for knowledge bases
+func (p *KnowledgeBaseProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return nil, &providers.ProviderError{
+		Provider: p.Name(),
+		Code:     providers.ErrCodeModelNotFound,
+		Message:  fmt.Sprintf("Model %q not found", modelID),
+	}
+}
@@ -3,7 +3,11 @@
 
 package bedrock
 
-import "github.com/tosharewith/llmproxy_auth/internal/providers"
+import (
+	"strings"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
 
 // BedrockModels defines all available Bedrock models
 var BedrockModels = []providers.Model{
@@ -88,6 +92,41 @@ var BedrockModels = []providers.Model{
 		Available:     true,
 	},
 
+	// Amazon Nova family (Converse API only)
+	{
+		ID:            "amazon-nova-pro",
+		Provider:      "bedrock",
+		Name:          "Nova Pro",
+		Description:   "Amazon's multimodal model with image and video understanding",
+		Capabilities:  []string{providers.CapabilityChat, providers.CapabilityStreaming, providers.CapabilityVision, providers.CapabilityVideo, providers.CapabilityFunctionCalling},
+		ContextWindow: 300000,
+		InputPrice:    0.80,   // $0.80 per 1M input tokens
+		OutputPrice:   3.20,   // $3.20 per 1M output tokens
+		Available:     true,
+	},
+	{
+		ID:            "amazon-nova-lite",
+		Provider:      "bedrock",
+		Name:          "Nova Lite",
+		Description:   "Low-cost Amazon multimodal model for fast image and video processing",
+		Capabilities:  []string{providers.CapabilityChat, providers.CapabilityStreaming, providers.CapabilityVision, providers.CapabilityVideo, providers.CapabilityFunctionCalling},
+		ContextWindow: 300000,
+		InputPrice:    0.06,   // $0.06 per 1M input tokens
+		OutputPrice:   0.24,   // $0.24 per 1M output tokens
+		Available:     true,
+	},
+	{
+		ID:            "amazon-nova-micro",
+		Provider:      "bedrock",
+		Name:          "Nova Micro",
+		Description:   "Text-only Amazon model with the lowest latency in the Nova family",
+		Capabilities:  []string{providers.CapabilityChat, providers.CapabilityStreaming, providers.CapabilityFunctionCalling},
+		ContextWindow: 128000,
+		InputPrice:    0.035,  // $0.035 per 1M input tokens
+		OutputPrice:   0.14,   // $0.14 per 1M output tokens
+		Available:     true,
+	},
+
 	// Meta Llama family
 	{
 		ID:            "llama2-13b",
@@ -164,6 +203,14 @@ var BedrockModelIDMap = map[string]string{
 	"amazon-titan-text-lite":       "amazon.titan-text-lite-v1",
 	"amazon-titan-embed-text":      "amazon.titan-embed-text-v1",
 
+	// Amazon Nova
+	"amazon-nova-pro":              "amazon.nova-pro-v1:0",
+	"amazon-nova-lite":             "amazon.nova-lite-v1:0",
+	"amazon-nova-micro":            "amazon.nova-micro-v1:0",
+	"nova-pro":                     "amazon.nova-pro-v1:0",
+	"nova-lite":                    "amazon.nova-lite-v1:0",
+	"nova-micro":                   "amazon.nova-micro-v1:0",
+
 	// Meta Llama
 	"llama2-13b":                   "meta.llama2-13b-chat-v1",
 	"llama2-70b":                   "meta.llama2-70b-chat-v1",
@@ -176,14 +223,38 @@ var BedrockModelIDMap = map[string]string{
 // GetBedrockModelID returns the full Bedrock model ID for a friendly name
 func GetBedrockModelID(friendlyName string) (string, bool) {
 	// Check if it's already a full Bedrock model ID
-	if len(friendlyName) > 0 && (friendlyName[0:1] == "anthropic." ||
-		friendlyName[0:1] == "amazon." ||
-		friendlyName[0:1] == "meta." ||
-		friendlyName[0:1] == "mistral.") {
-		return friendlyName, true
+	for _, prefix := range []string{"anthropic.", "amazon.", "meta.", "mistral."} {
+		if strings.HasPrefix(friendlyName, prefix) {
+			return friendlyName, true
+		}
 	}
 
 	// Look up in map
 	modelID, exists := BedrockModelIDMap[friendlyName]
 	return modelID, exists
 }
+
+// latencyOptimizedModels are the models Bedrock serves with
+// performanceConfig latency "optimized"; others reject the setting
+var latencyOptimizedModels = map[string]bool{
+	"anthropic.claude-3-5-haiku-20241022-v1:0": true,
+	"meta.llama3-1-70b-instruct-v1:0":          true,
+	"meta.llama3-1-405b-instruct-v1:0":         true,
+	"amazon.nova-pro-v1:0":                     true,
+}
+
+// SupportsLatencyOptimized reports whether a Bedrock model accepts
+// latency-optimized inference
+func SupportsLatencyOptimized(modelID string) bool {
+	return latencyOptimizedModels[modelID]
+}
+
+// SupportsVideo reports whether a Bedrock model accepts video content blocks
+func SupportsVideo(modelID string) bool {
+	if info := GetBedrockModelInfo(modelID); info != nil {
+		return info.HasCapability(providers.CapabilityVideo)
+	}
+
+	// Full model IDs are not in BedrockModels; Nova Pro and Lite accept video
+	return strings.HasPrefix(modelID, "amazon.nova-pro") || strings.HasPrefix(modelID, "amazon.nova-lite")
+}
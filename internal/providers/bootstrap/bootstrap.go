@@ -0,0 +1,175 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package bootstrap constructs the gateway's providers from configuration.
+// A Registry maps provider types to factories; Build creates one provider
+// per configured type, and BuildInstances one provider per instance of the
+// types configured per instance.
+package bootstrap
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// ErrNotConfigured is returned by a Factory when settings the provider
+// requires, such as credentials, are missing. Build skips such providers.
+var ErrNotConfigured = errors.New("provider not configured")
+
+// Factory creates the provider for a provider type. cfg is the first
+// instance of that type in the instance config, or the zero value when the
+// type is only enabled in the router config. Unset fields fall back to the
+// provider's environment variables.
+type Factory func(cfg instance.InstanceConfig) (providers.Provider, error)
+
+// InstanceFactory creates the provider for a single instance, for provider
+// types whose behavior comes entirely from each instance's config
+type InstanceFactory func(name string, cfg instance.InstanceConfig) (providers.Provider, error)
+
+// Registry holds provider factories keyed by provider type
+type Registry struct {
+	factories         map[string]Factory
+	instanceFactories map[string]InstanceFactory
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		factories:         make(map[string]Factory),
+		instanceFactories: make(map[string]InstanceFactory),
+	}
+}
+
+// Register adds the factory for a provider type, replacing any existing one
+func (r *Registry) Register(providerType string, factory Factory) {
+	r.factories[providerType] = factory
+}
+
+// RegisterInstance adds the per-instance factory for a provider type,
+// replacing any existing one
+func (r *Registry) RegisterInstance(providerType string, factory InstanceFactory) {
+	r.instanceFactories[providerType] = factory
+}
+
+// Types returns the registered provider types, sorted
+func (r *Registry) Types() []string {
+	types := make([]string, 0, len(r.factories))
+	for providerType := range r.factories {
+		types = append(types, providerType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Build creates one provider per distinct type in types, configured from
+// the first instance (by name) of that type in instances. Types without a
+// factory, providers that are not configured and providers that fail to
+// initialize are logged and skipped.
+func (r *Registry) Build(types []string, instances map[string]instance.InstanceConfig) map[string]providers.Provider {
+	registry := make(map[string]providers.Provider)
+	for _, providerType := range uniqueSorted(types) {
+		if _, ok := r.instanceFactories[providerType]; ok {
+			continue // Built by BuildInstances
+		}
+		factory, ok := r.factories[providerType]
+		if !ok {
+			log.Printf("No provider factory for type %q, skipping", providerType)
+			continue
+		}
+
+		provider, err := factory(firstInstance(instances, providerType))
+		if errors.Is(err, ErrNotConfigured) {
+			log.Printf("Provider %s not configured, skipping: %v", providerType, err)
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to create %s provider: %v", providerType, err)
+			continue
+		}
+		registry[providerType] = provider
+		log.Printf("✓ %s provider initialized", providerType)
+	}
+	return registry
+}
+
+// BuildInstances creates a provider for every instance whose type has a
+// per-instance factory, keyed by instance name. Instances of other types
+// whose timeouts differ from those of the first instance of their type,
+// which Build configures the type's provider from, also get a provider of
+// their own, built from their settings. Providers that fail to initialize
+// are logged and skipped.
+func (r *Registry) BuildInstances(instances map[string]instance.InstanceConfig) map[string]providers.Provider {
+	names := make([]string, 0, len(instances))
+	for name := range instances {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	registry := make(map[string]providers.Provider)
+	for _, name := range names {
+		inst := instances[name]
+		var provider providers.Provider
+		var err error
+		if factory, ok := r.instanceFactories[inst.Type]; ok {
+			provider, err = factory(name, inst)
+		} else if factory, ok := r.factories[inst.Type]; ok && !sameTimeouts(inst, firstInstance(instances, inst.Type)) {
+			provider, err = factory(inst)
+		} else {
+			continue
+		}
+		if err != nil {
+			log.Printf("Warning: Failed to create %s provider for instance %s: %v", inst.Type, name, err)
+			continue
+		}
+		registry[name] = provider
+		log.Printf("✓ %s provider initialized for instance %s", inst.Type, name)
+	}
+	return registry
+}
+
+// sameTimeouts reports whether two instances resolve to the same valid
+// timeouts; invalid timeouts are left for the factories to report
+func sameTimeouts(a, b instance.InstanceConfig) bool {
+	aTimeouts, aErr := Timeouts(a)
+	bTimeouts, bErr := Timeouts(b)
+	return aErr == nil && bErr == nil && aTimeouts.WithDefaults() == bTimeouts.WithDefaults()
+}
+
+// firstInstance returns the first instance of providerType by name, or the
+// zero value if there is none
+func firstInstance(instances map[string]instance.InstanceConfig, providerType string) instance.InstanceConfig {
+	names := make([]string, 0, len(instances))
+	for name, inst := range instances {
+		if inst.Type == providerType {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return instance.InstanceConfig{Type: providerType}
+	}
+	sort.Strings(names)
+	return instances[names[0]]
+}
+
+func uniqueSorted(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	var unique []string
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			unique = append(unique, value)
+		}
+	}
+	sort.Strings(unique)
+	return unique
+}
+
+// notConfigured reports the settings a provider is missing
+func notConfigured(format string, args ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrNotConfigured, fmt.Sprintf(format, args...))
+}
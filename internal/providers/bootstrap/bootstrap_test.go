@@ -0,0 +1,283 @@
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// namedProvider is a provider that only knows its name
+type namedProvider struct {
+	name string
+}
+
+func (p *namedProvider) Name() string                          { return p.name }
+func (p *namedProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (p *namedProvider) Invoke(ctx context.Context, req *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *namedProvider) InvokeStreaming(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *namedProvider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	return nil, nil
+}
+
+func (p *namedProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return nil, errors.New("not found")
+}
+
+func TestRegistryBuild(t *testing.T) {
+	var gotBaseURL string
+	calls := 0
+
+	r := NewRegistry()
+	r.Register("openai", func(cfg instance.InstanceConfig) (providers.Provider, error) {
+		calls++
+		gotBaseURL = cfg.BaseURL
+		return &namedProvider{name: "openai"}, nil
+	})
+	r.Register("ibm", func(cfg instance.InstanceConfig) (providers.Provider, error) {
+		return nil, notConfigured("IBM_API_KEY is required")
+	})
+	r.Register("oracle", func(cfg instance.InstanceConfig) (providers.Provider, error) {
+		return nil, errors.New("bad endpoint")
+	})
+
+	instances := map[string]instance.InstanceConfig{
+		"openai_b": {Type: "openai", BaseURL: "https://b.example.com"},
+		"openai_a": {Type: "openai", BaseURL: "https://a.example.com"},
+	}
+	registry := r.Build([]string{"openai", "ibm", "oracle", "unknown", "openai"}, instances)
+
+	if len(registry) != 1 || registry["openai"] == nil {
+		t.Fatalf("registry = %v, want only openai", registry)
+	}
+	if calls != 1 {
+		t.Errorf("openai factory called %d times, want 1", calls)
+	}
+	if gotBaseURL != "https://a.example.com" {
+		t.Errorf("factory got base URL %q, want the first instance by name", gotBaseURL)
+	}
+}
+
+func TestDefaultFactoriesNotConfigured(t *testing.T) {
+	for _, key := range []string{"OPENAI_API_KEY", "AZURE_OPENAI_ENDPOINT", "AZURE_OPENAI_API_KEY", "GCP_PROJECT_ID"} {
+		t.Setenv(key, "")
+	}
+
+	r := Default()
+	for _, providerType := range []string{"openai", "azure", "vertex"} {
+		if _, err := r.factories[providerType](instance.InstanceConfig{Type: providerType}); !errors.Is(err, ErrNotConfigured) {
+			t.Errorf("%s: error = %v, want ErrNotConfigured", providerType, err)
+		}
+	}
+
+	// Instance credentials take precedence over the environment
+	provider, err := r.factories["openai"](instance.InstanceConfig{
+		Type:           "openai",
+		Authentication: instance.AuthenticationConfig{Token: "sk-test"},
+	})
+	if err != nil || provider.Name() != "openai" {
+		t.Errorf("openai with instance token: %v, %v", provider, err)
+	}
+}
+
+// TestMockFactory tests that the mock provider needs ENABLE_MOCK_PROVIDER
+// and reads its settings from the environment
+func TestMockFactory(t *testing.T) {
+	t.Setenv("ENABLE_MOCK_PROVIDER", "")
+	factory := Default().factories["mock"]
+	if _, err := factory(instance.InstanceConfig{Type: "mock"}); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("without ENABLE_MOCK_PROVIDER: error = %v, want ErrNotConfigured", err)
+	}
+
+	t.Setenv("ENABLE_MOCK_PROVIDER", "true")
+	t.Setenv("MOCK_ERROR_RATE", "1")
+	t.Setenv("MOCK_ERROR_STATUS", "429")
+	t.Setenv("MOCK_ERROR_CODE", "rate_limit_exceeded")
+	provider, err := factory(instance.InstanceConfig{Type: "mock"})
+	if err != nil {
+		t.Fatalf("mock factory: %v", err)
+	}
+	_, err = provider.Invoke(context.Background(), &providers.ProviderRequest{Path: "/chat/completions", Body: []byte(`{}`)})
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != 429 || providerErr.Code != "rate_limit_exceeded" {
+		t.Errorf("Invoke error = %v, want the injected 429", err)
+	}
+
+	t.Setenv("MOCK_LATENCY", "soon")
+	if _, err := factory(instance.InstanceConfig{Type: "mock"}); err == nil {
+		t.Error("mock factory accepted an invalid MOCK_LATENCY")
+	}
+}
+
+func TestRegistryBuildInstances(t *testing.T) {
+	r := NewRegistry()
+	r.RegisterInstance("generic_http", func(name string, cfg instance.InstanceConfig) (providers.Provider, error) {
+		if cfg.BaseURL == "" {
+			return nil, errors.New("base_url is required")
+		}
+		return &namedProvider{name: name}, nil
+	})
+
+	instances := map[string]instance.InstanceConfig{
+		"scorer":    {Type: "generic_http", BaseURL: "https://scorer.internal"},
+		"ranker":    {Type: "generic_http", BaseURL: "https://ranker.internal"},
+		"broken":    {Type: "generic_http"},
+		"openai_us": {Type: "openai"},
+	}
+	registry := r.BuildInstances(instances)
+	if len(registry) != 2 || registry["scorer"].Name() != "scorer" || registry["ranker"].Name() != "ranker" {
+		t.Errorf("BuildInstances = %v, want one provider per valid generic_http instance", registry)
+	}
+
+	// Per-instance types are not built per type
+	if registry := r.Build([]string{"generic_http"}, instances); len(registry) != 0 {
+		t.Errorf("Build = %v, want no per-type generic_http provider", registry)
+	}
+}
+
+// TestTimeouts tests the instance timeouts and their environment fallbacks
+func TestTimeouts(t *testing.T) {
+	t.Setenv("PROVIDER_CONNECT_TIMEOUT", "3s")
+	t.Setenv("PROVIDER_REQUEST_TIMEOUT", "")
+
+	timeouts, err := Timeouts(instance.InstanceConfig{Timeout: "45s"})
+	if err != nil {
+		t.Fatalf("Timeouts() error = %v", err)
+	}
+	if timeouts.Connect != 3*time.Second || timeouts.Request != 45*time.Second {
+		t.Errorf("Timeouts() = %+v, want 3s connect from the environment and the deprecated 45s timeout", timeouts)
+	}
+
+	cfg := instance.InstanceConfig{HTTPTimeouts: instance.HTTPTimeouts{ConnectTimeout: "1s", RequestTimeout: "10m"}}
+	if timeouts, _ = Timeouts(cfg); timeouts.Connect != time.Second || timeouts.Request != 10*time.Minute {
+		t.Errorf("Timeouts() = %+v, want the instance's 1s and 10m", timeouts)
+	}
+
+	t.Setenv("PROVIDER_REQUEST_TIMEOUT", "forever")
+	if _, err := Timeouts(instance.InstanceConfig{}); err == nil {
+		t.Error("Timeouts() accepted an invalid PROVIDER_REQUEST_TIMEOUT")
+	}
+}
+
+// TestBuildInstancesTimeouts tests that instances whose timeouts differ from
+// the first instance of their type get a provider of their own
+func TestBuildInstancesTimeouts(t *testing.T) {
+	t.Setenv("PROVIDER_CONNECT_TIMEOUT", "")
+	t.Setenv("PROVIDER_REQUEST_TIMEOUT", "")
+
+	built := make(map[string]string)
+	r := NewRegistry()
+	r.Register("openai", func(cfg instance.InstanceConfig) (providers.Provider, error) {
+		built[cfg.BaseURL] = cfg.RequestTimeout
+		return &namedProvider{name: "openai"}, nil
+	})
+
+	slow := instance.HTTPTimeouts{RequestTimeout: "10m"}
+	instances := map[string]instance.InstanceConfig{
+		"openai_a":    {Type: "openai", BaseURL: "https://a.example.com"},
+		"openai_b":    {Type: "openai", BaseURL: "https://b.example.com", HTTPTimeouts: instance.HTTPTimeouts{RequestTimeout: "120s"}},
+		"openai_slow": {Type: "openai", BaseURL: "https://slow.example.com", HTTPTimeouts: slow},
+	}
+	registry := r.BuildInstances(instances)
+	if len(registry) != 1 || registry["openai_slow"] == nil {
+		t.Fatalf("BuildInstances() = %v, want only openai_slow", registry)
+	}
+	if got, ok := built["https://slow.example.com"]; !ok || got != "10m" {
+		t.Errorf("factory calls = %v, want openai_slow built from its own settings", built)
+	}
+}
+
+// TestBedrockEndpoint tests that the bedrock provider sends requests to the
+// first bedrock instance's endpoint, or else BEDROCK_ENDPOINT
+func TestBedrockEndpoint(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("BEDROCK_ENDPOINT", "https://vpce-env.bedrock-runtime.us-east-1.vpce.amazonaws.com")
+
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		io.WriteString(w, `{}`)
+	}))
+	defer server.Close()
+
+	registry := Default().Build([]string{"bedrock"}, map[string]instance.InstanceConfig{
+		"bedrock-vpc": {Type: "bedrock", Endpoint: server.URL + "/"},
+	})
+	provider, ok := registry["bedrock"].(providers.Passthrough)
+	if !ok {
+		t.Fatalf("bedrock provider not built: %v", registry)
+	}
+	if provider.BaseURL() != server.URL {
+		t.Errorf("BaseURL() = %q, want the instance endpoint %q", provider.BaseURL(), server.URL)
+	}
+	resp, err := registry["bedrock"].Invoke(context.Background(), &providers.ProviderRequest{
+		Method: http.MethodPost,
+		Path:   "/model/anthropic.claude-3-haiku-20240307-v1:0/invoke",
+		Body:   []byte(`{}`),
+	})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invoke() = %v, %v", resp, err)
+	}
+	if gotPath != "/model/anthropic.claude-3-haiku-20240307-v1:0/invoke" {
+		t.Errorf("endpoint got path %q", gotPath)
+	}
+
+	registry = Default().Build([]string{"bedrock"}, nil)
+	if provider, ok := registry["bedrock"].(providers.Passthrough); !ok ||
+		provider.BaseURL() != "https://vpce-env.bedrock-runtime.us-east-1.vpce.amazonaws.com" {
+		t.Errorf("bedrock provider without instances does not use BEDROCK_ENDPOINT: %v", registry)
+	}
+}
+
+// TestVertexEndpoint tests that the vertex provider sends requests to the
+// instance's endpoint, such as a Private Service Connect endpoint
+func TestVertexEndpoint(t *testing.T) {
+	t.Setenv("GCP_PROJECT_ID", "proj")
+	t.Setenv("GCP_LOCATION", "europe-west4")
+	t.Setenv("GCP_ACCESS_TOKEN", "token")
+	t.Setenv("VERTEX_ENDPOINT", "")
+
+	var gotPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Hi."}]},"finishReason":"STOP"}]}`)
+	}))
+	defer server.Close()
+
+	registry := Default().Build([]string{"vertex"}, map[string]instance.InstanceConfig{
+		"vertex-psc": {Type: "vertex", Endpoint: server.URL},
+	})
+	provider, ok := registry["vertex"]
+	if !ok {
+		t.Fatal("vertex provider not built")
+	}
+	resp, err := provider.Invoke(context.Background(), &providers.ProviderRequest{
+		Method: http.MethodPost,
+		Path:   "/chat/completions",
+		Body:   []byte(`{"model":"gemini-1.5-pro","messages":[{"role":"user","content":"Hi"}]}`),
+	})
+	if err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("Invoke() = %v, %v", resp, err)
+	}
+	if want := "/v1/projects/proj/locations/europe-west4/publishers/google/models/gemini-1.5-pro:generateContent"; gotPath != want {
+		t.Errorf("endpoint got path %q, want %q", gotPath, want)
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package bootstrap
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/anthropic"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/azure"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/bedrock"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/cohere"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/generic"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/ibm"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/mock"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/openai"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/oracle"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/vertex"
+)
+
+// Default returns a registry with the built-in provider types
+func Default() *Registry {
+	r := NewRegistry()
+	r.Register("bedrock", newBedrock)
+	r.Register("bedrock_kb", newBedrockKnowledgeBase)
+	r.Register("azure", newAzure)
+	r.Register("openai", newOpenAI)
+	r.Register("anthropic", newAnthropic)
+	r.Register("cohere", newCohere)
+	r.Register("vertex", newVertex)
+	r.Register("ibm", newIBM)
+	r.Register("oracle", newOracle)
+	r.Register("mock", newMock)
+	r.RegisterInstance("generic_http", newGenericHTTP)
+	return r
+}
+
+// setting returns the first non-empty of the instance value, the
+// environment variable and the default
+func setting(value, envKey, defaultValue string) string {
+	if value != "" {
+		return value
+	}
+	if env := os.Getenv(envKey); env != "" {
+		return env
+	}
+	return defaultValue
+}
+
+// credential returns the instance's API key or token, or the environment
+// variable
+func credential(cfg instance.InstanceConfig, envKey string) string {
+	if cfg.Authentication.Key != "" {
+		return cfg.Authentication.Key
+	}
+	return setting(cfg.Authentication.Token, envKey, "")
+}
+
+// Timeouts returns an instance's provider timeouts. Unset timeouts fall
+// back to PROVIDER_CONNECT_TIMEOUT and PROVIDER_REQUEST_TIMEOUT, then to the
+// provider defaults.
+func Timeouts(cfg instance.InstanceConfig) (providers.HTTPTimeouts, error) {
+	request := cfg.RequestTimeout
+	if request == "" {
+		request = cfg.Timeout
+	}
+	connect, requestTimeout, err := instance.HTTPTimeouts{
+		ConnectTimeout: setting(cfg.ConnectTimeout, "PROVIDER_CONNECT_TIMEOUT", ""),
+		RequestTimeout: setting(request, "PROVIDER_REQUEST_TIMEOUT", ""),
+	}.Durations()
+	if err != nil {
+		return providers.HTTPTimeouts{}, err
+	}
+	return providers.HTTPTimeouts{Connect: connect, Request: requestTimeout}, nil
+}
+
+// bedrockConfig returns the Bedrock settings of an instance. The role to
+// assume falls back to BEDROCK_ASSUME_ROLE_ARN, BEDROCK_ROLE_CHAIN
+// (comma-separated) and BEDROCK_EXTERNAL_ID.
+func bedrockConfig(cfg instance.InstanceConfig, region string) (bedrock.BedrockConfig, error) {
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return bedrock.BedrockConfig{}, err
+	}
+	roleChain := cfg.Authentication.RoleChain
+	if len(roleChain) == 0 {
+		for _, arn := range strings.Split(os.Getenv("BEDROCK_ROLE_CHAIN"), ",") {
+			if arn = strings.TrimSpace(arn); arn != "" {
+				roleChain = append(roleChain, arn)
+			}
+		}
+	}
+	return bedrock.BedrockConfig{
+		Region:        region,
+		Timeouts:      timeouts,
+		AssumeRoleARN: setting(cfg.Authentication.AssumeRoleARN, "BEDROCK_ASSUME_ROLE_ARN", ""),
+		RoleChain:     roleChain,
+		ExternalID:    setting(cfg.Authentication.ExternalID, "BEDROCK_EXTERNAL_ID", ""),
+	}, nil
+}
+
+// newBedrock uses AWS_REGION rather than an instance region: one Bedrock
+// provider serves every bedrock instance, and instances in other regions
+// are reached through their own SigV4 providers. The instance's endpoint,
+// or BEDROCK_ENDPOINT, replaces the regional runtime endpoint, such as for
+// a VPC endpoint.
+func newBedrock(cfg instance.InstanceConfig) (providers.Provider, error) {
+	config, err := bedrockConfig(cfg, setting("", "AWS_REGION", "us-east-1"))
+	if err != nil {
+		return nil, err
+	}
+	config.Endpoint = setting(cfg.Endpoint, "BEDROCK_ENDPOINT", "")
+	return bedrock.NewBedrockProvider(config)
+}
+
+// newBedrockKnowledgeBase signs for the first bedrock_kb instance's region
+// and service; all knowledge base instances share the provider
+func newBedrockKnowledgeBase(cfg instance.InstanceConfig) (providers.Provider, error) {
+	region := cfg.Authentication.Region
+	if region == "" {
+		region = cfg.Region
+	}
+	config, err := bedrockConfig(cfg, setting(region, "AWS_REGION", "us-east-1"))
+	if err != nil {
+		return nil, err
+	}
+	return bedrock.NewKnowledgeBaseProvider(config, cfg.Authentication.Service)
+}
+
+func newAzure(cfg instance.InstanceConfig) (providers.Provider, error) {
+	endpoint := setting(cfg.Endpoint, "AZURE_OPENAI_ENDPOINT", "")
+	apiKey := credential(cfg, "AZURE_OPENAI_API_KEY")
+	if endpoint == "" || apiKey == "" {
+		return nil, notConfigured("AZURE_OPENAI_ENDPOINT and AZURE_OPENAI_API_KEY are required")
+	}
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return azure.NewAzureProvider(azure.AzureConfig{
+		Endpoint:   endpoint,
+		APIKey:     apiKey,
+		APIVersion: setting(cfg.APIVersion, "AZURE_API_VERSION", "2024-02-15-preview"),
+		Timeouts:   timeouts,
+	})
+}
+
+func newOpenAI(cfg instance.InstanceConfig) (providers.Provider, error) {
+	apiKey := credential(cfg, "OPENAI_API_KEY")
+	if apiKey == "" {
+		return nil, notConfigured("OPENAI_API_KEY is required")
+	}
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return openai.NewOpenAIProvider(openai.OpenAIConfig{
+		APIKey:   apiKey,
+		BaseURL:  setting(cfg.BaseURL, "OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		Timeouts: timeouts,
+	})
+}
+
+func newAnthropic(cfg instance.InstanceConfig) (providers.Provider, error) {
+	apiKey := credential(cfg, "ANTHROPIC_API_KEY")
+	if apiKey == "" {
+		return nil, notConfigured("ANTHROPIC_API_KEY is required")
+	}
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return anthropic.NewAnthropicProvider(anthropic.AnthropicConfig{
+		APIKey:   apiKey,
+		BaseURL:  setting(cfg.BaseURL, "ANTHROPIC_BASE_URL", "https://api.anthropic.com/v1"),
+		Timeouts: timeouts,
+	})
+}
+
+func newCohere(cfg instance.InstanceConfig) (providers.Provider, error) {
+	apiKey := credential(cfg, "COHERE_API_KEY")
+	if apiKey == "" {
+		return nil, notConfigured("COHERE_API_KEY is required")
+	}
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return cohere.NewCohereProvider(cohere.CohereConfig{
+		APIKey:   apiKey,
+		BaseURL:  setting(cfg.BaseURL, "COHERE_BASE_URL", "https://api.cohere.com/v1"),
+		Timeouts: timeouts,
+	})
+}
+
+func newVertex(cfg instance.InstanceConfig) (providers.Provider, error) {
+	projectID := setting(cfg.ProjectID, "GCP_PROJECT_ID", "")
+	if projectID == "" {
+		return nil, notConfigured("GCP_PROJECT_ID is required")
+	}
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return vertex.NewVertexProvider(vertex.VertexConfig{
+		ProjectID:   projectID,
+		Location:    setting(cfg.Location, "GCP_LOCATION", "us-central1"),
+		AccessToken: credential(cfg, "GCP_ACCESS_TOKEN"), // Or use Application Default Credentials
+		Timeouts:    timeouts,
+		Endpoint:    setting(cfg.Endpoint, "VERTEX_ENDPOINT", ""),
+	})
+}
+
+func newIBM(cfg instance.InstanceConfig) (providers.Provider, error) {
+	apiKey := credential(cfg, "IBM_API_KEY")
+	projectID := setting(cfg.ProjectID, "IBM_PROJECT_ID", "")
+	if apiKey == "" || projectID == "" {
+		return nil, notConfigured("IBM_API_KEY and IBM_PROJECT_ID are required")
+	}
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return ibm.NewIBMProvider(ibm.IBMConfig{
+		APIKey:    apiKey,
+		ProjectID: projectID,
+		BaseURL:   setting(cfg.BaseURL, "IBM_BASE_URL", "https://us-south.ml.cloud.ibm.com"),
+		Timeouts:  timeouts,
+	})
+}
+
+func newOracle(cfg instance.InstanceConfig) (providers.Provider, error) {
+	endpoint := setting(cfg.Endpoint, "ORACLE_ENDPOINT", "")
+	authToken := credential(cfg, "ORACLE_AUTH_TOKEN")
+	compartmentID := setting(cfg.CompartmentID, "ORACLE_COMPARTMENT_ID", "")
+	if endpoint == "" || authToken == "" || compartmentID == "" {
+		return nil, notConfigured("ORACLE_ENDPOINT, ORACLE_AUTH_TOKEN and ORACLE_COMPARTMENT_ID are required")
+	}
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return oracle.NewOracleProvider(oracle.OracleConfig{
+		Endpoint:      endpoint,
+		AuthToken:     authToken,
+		CompartmentID: compartmentID,
+		Timeouts:      timeouts,
+	})
+}
+
+// newMock builds the in-process mock provider for tests. It is not
+// configured unless ENABLE_MOCK_PROVIDER=true, so a model mapping alone
+// cannot route real traffic to canned responses. Its latency, injected
+// errors and token counts come from the MOCK_* environment variables.
+func newMock(cfg instance.InstanceConfig) (providers.Provider, error) {
+	if os.Getenv("ENABLE_MOCK_PROVIDER") != "true" {
+		return nil, notConfigured("ENABLE_MOCK_PROVIDER=true is required")
+	}
+	var config mock.MockConfig
+	var err error
+	if latency := os.Getenv("MOCK_LATENCY"); latency != "" {
+		if config.Latency, err = time.ParseDuration(latency); err != nil {
+			return nil, fmt.Errorf("invalid MOCK_LATENCY: %w", err)
+		}
+	}
+	if rate := os.Getenv("MOCK_ERROR_RATE"); rate != "" {
+		if config.ErrorRate, err = strconv.ParseFloat(rate, 64); err != nil {
+			return nil, fmt.Errorf("invalid MOCK_ERROR_RATE: %w", err)
+		}
+	}
+	for _, field := range []struct {
+		envKey string
+		value  *int
+	}{
+		{"MOCK_ERROR_STATUS", &config.ErrorStatus},
+		{"MOCK_PROMPT_TOKENS", &config.PromptTokens},
+		{"MOCK_COMPLETION_TOKENS", &config.CompletionTokens},
+		{"MOCK_EMBEDDING_DIMENSIONS", &config.Dimensions},
+	} {
+		if env := os.Getenv(field.envKey); env != "" {
+			if *field.value, err = strconv.Atoi(env); err != nil {
+				return nil, fmt.Errorf("invalid %s: %w", field.envKey, err)
+			}
+		}
+	}
+	config.ErrorCode = os.Getenv("MOCK_ERROR_CODE")
+	return mock.NewMockProvider(config)
+}
+
+// newGenericHTTP builds a provider from the instance alone; there are no
+// environment fallbacks other than the timeouts since every generic_http
+// instance is different
+func newGenericHTTP(name string, cfg instance.InstanceConfig) (providers.Provider, error) {
+	timeouts, err := Timeouts(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return generic.NewGenericHTTPProvider(generic.GenericHTTPConfig{
+		Name:    name,
+		BaseURL: cfg.BaseURL,
+		Auth: generic.GenericHTTPAuth{
+			Type:     cfg.Authentication.Type,
+			Header:   cfg.Authentication.Header,
+			Key:      cfg.Authentication.Key,
+			Token:    cfg.Authentication.Token,
+			Username: cfg.Authentication.Username,
+			Password: cfg.Authentication.Password,
+		},
+		Headers:         cfg.RequestHeaders,
+		HealthCheckPath: cfg.HealthCheckPath,
+		Timeouts:        timeouts,
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+// Capabilities describes what a provider's chat models support, so callers
+// can check a request against the provider before sending it
+type Capabilities struct {
+	// Streaming reports whether chat completions can be streamed
+	Streaming bool
+
+	// Vision reports whether messages may contain images
+	Vision bool
+
+	// Video reports whether messages may contain video
+	Video bool
+
+	// Tools reports whether tool (function) calling is supported
+	Tools bool
+
+	// ParallelToolCalls reports whether parallel_tool_calls: false is
+	// honoured, natively or through translation; it is dropped otherwise
+	ParallelToolCalls bool
+
+	// MaxContextTokens is the largest context window of the provider's models
+	MaxContextTokens int
+
+	// ServiceTiers are the OpenAI service_tier values the provider honours,
+	// natively or mapped to its own options; others are not sent
+	ServiceTiers []string
+}
+
+// CapabilityReporter is implemented by providers that describe their
+// capabilities. It is optional: use CapabilitiesOf, which falls back to
+// DefaultCapabilities.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// DefaultCapabilities are assumed for providers that do not report their
+// own: plain, non-streaming chat with a small context window
+var DefaultCapabilities = Capabilities{
+	MaxContextTokens: 4096,
+}
+
+// SupportsServiceTier reports whether the provider honours a service_tier
+func (c Capabilities) SupportsServiceTier(tier string) bool {
+	for _, supported := range c.ServiceTiers {
+		if supported == tier {
+			return true
+		}
+	}
+	return false
+}
+
+// CapabilitiesOf returns the capabilities of a provider
+func CapabilitiesOf(provider Provider) Capabilities {
+	if reporter, ok := provider.(CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+	return DefaultCapabilities
+}
+
+// List returns the capabilities as Model capability names (chat, streaming,
+// vision, video, function_calling)
+func (c Capabilities) List() []string {
+	list := []string{CapabilityChat}
+	if c.Streaming {
+		list = append(list, CapabilityStreaming)
+	}
+	if c.Vision {
+		list = append(list, CapabilityVision)
+	}
+	if c.Video {
+		list = append(list, CapabilityVideo)
+	}
+	if c.Tools {
+		list = append(list, CapabilityFunctionCalling)
+	}
+	return list
+}
@@ -0,0 +1,91 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import "context"
+
+// ChatStreamer is implemented by providers that parse their own chat
+// completion streams into typed events, so callers need not know the
+// provider's wire format. It is optional: callers should type-assert a
+// Provider to ChatStreamer and fall back to InvokeStreaming.
+type ChatStreamer interface {
+	// InvokeChatStream sends a streaming chat request. Errors before the
+	// stream starts, including error statuses, are returned as by Invoke;
+	// later ones arrive as a ChatEventError. The channel is closed after
+	// ChatEventDone or ChatEventError, or when ctx is done.
+	InvokeChatStream(ctx context.Context, request *ChatRequest) (<-chan ChatEvent, error)
+}
+
+// ChatRequest is a streaming chat completion request
+type ChatRequest struct {
+	// Request is the chat request translated to the provider's format, as
+	// it would be passed to InvokeStreaming
+	Request *ProviderRequest
+}
+
+// ChatEventType identifies the kind of a ChatEvent
+type ChatEventType string
+
+// Chat stream event types
+const (
+	ChatEventDelta         ChatEventType = "delta"           // Role and/or Text
+	ChatEventToolCallDelta ChatEventType = "tool_call_delta" // ToolCall
+	ChatEventUsage         ChatEventType = "usage"           // Usage
+	ChatEventDone          ChatEventType = "done"            // FinishReason; the last event
+	ChatEventError         ChatEventType = "error"           // Err; the last event
+)
+
+// ChatEvent is one event of a chat completion stream
+type ChatEvent struct {
+	Type ChatEventType
+
+	// Role of the message, set on its first delta
+	Role string
+
+	// Text is the next fragment of the message content
+	Text string
+
+	// ToolCall is the next fragment of a tool call
+	ToolCall *ToolCallDelta
+
+	// Usage is the token usage of the request
+	Usage *ChatUsage
+
+	// FinishReason is the provider's stop reason, untranslated
+	FinishReason string
+
+	// Err is the error that ended the stream
+	Err error
+}
+
+// ToolCallDelta is a fragment of a tool call. Fragments with the same Index
+// belong to the same call; the first carries its ID and Name.
+type ToolCallDelta struct {
+	Index     int
+	ID        string
+	Name      string
+	Arguments string // the next fragment of the JSON-encoded arguments
+}
+
+// ChatUsage is the token usage reported in a chat stream
+type ChatUsage struct {
+	InputTokens  int
+	OutputTokens int
+	TotalTokens  int
+
+	// Prompt tokens read from and written to the prompt cache
+	CacheReadTokens  int
+	CacheWriteTokens int
+}
+
+// SendChatEvent sends an event unless ctx is done first, reporting whether
+// it was sent. Stream goroutines should stop when it returns false.
+func SendChatEvent(ctx context.Context, events chan<- ChatEvent, event ChatEvent) bool {
+	select {
+	case events <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
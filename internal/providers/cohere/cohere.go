@@ -0,0 +1,167 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cohere
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// CohereProvider implements the Provider interface for the Cohere API
+// Requests are passed through in Cohere's native format (e.g. /rerank, /embed)
+type CohereProvider struct {
+	apiKey     string
+	baseURL    string
+	httpClient *http.Client
+}
+
+// Config for Cohere provider
+type CohereConfig struct {
+	APIKey   string                 `yaml:"api_key"`
+	BaseURL  string                 `yaml:"base_url"` // Optional, defaults to https://api.cohere.com/v1
+	Timeouts providers.HTTPTimeouts // Optional, defaults to 10s connect and 120s request
+}
+
+// NewCohereProvider creates a new Cohere provider
+func NewCohereProvider(config CohereConfig) (*CohereProvider, error) {
+	if config.APIKey == "" {
+		return nil, fmt.Errorf("Cohere API key is required")
+	}
+
+	baseURL := config.BaseURL
+	if baseURL == "" {
+		baseURL = "https://api.cohere.com/v1"
+	}
+
+	return &CohereProvider{
+		apiKey:     config.APIKey,
+		baseURL:    baseURL,
+		httpClient: providers.NewHTTPClient(config.Timeouts),
+	}, nil
+}
+
+// Name returns the provider name
+func (p *CohereProvider) Name() string {
+	return "cohere"
+}
+
+// HealthCheck checks if the provider is accessible
+func (p *CohereProvider) HealthCheck(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("health check failed with status %d: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// Invoke sends a request to Cohere
+func (p *CohereProvider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	startTime := time.Now()
+	url := p.baseURL + request.Path
+
+	httpReq, err := http.NewRequestWithContext(ctx, request.Method, url, bytes.NewReader(request.Body))
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("failed to create request: %v", err),
+			Provider:   "cohere",
+		}
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Accept", "application/json")
+	providers.SetCredentials(httpReq, request, "Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    fmt.Sprintf("request failed: %v", err),
+			Provider:   "cohere",
+		}
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("failed to read response: %v", err),
+			Provider:   "cohere",
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &providers.ProviderError{
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+			Provider:   "cohere",
+		}
+	}
+
+	headers := resp.Header.Clone()
+
+	return &providers.ProviderResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    headers,
+		Body:       body,
+		Metadata: providers.ResponseMetadata{
+			Latency: time.Since(startTime),
+		},
+	}, nil
+}
+
+// InvokeStreaming is not supported for Cohere
+func (p *CohereProvider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
+	return nil, &providers.ProviderError{
+		StatusCode: http.StatusNotImplemented,
+		Message:    "streaming not yet implemented for Cohere provider",
+		Provider:   "cohere",
+	}
+}
+
+// ListModels lists available Cohere models
+func (p *CohereProvider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	// Hardcoded list of Cohere rerank and embed models
+	models := []providers.Model{
+		{ID: "rerank-v3.5", Name: "Rerank v3.5", Provider: "cohere"},
+		{ID: "rerank-english-v3.0", Name: "Rerank English v3.0", Provider: "cohere"},
+		{ID: "rerank-multilingual-v3.0", Name: "Rerank Multilingual v3.0", Provider: "cohere"},
+		{ID: "embed-english-v3.0", Name: "Embed English v3.0", Provider: "cohere"},
+		{ID: "embed-multilingual-v3.0", Name: "Embed Multilingual v3.0", Provider: "cohere"},
+	}
+
+	return models, nil
+}
+
+// GetModelInfo gets information about a specific Cohere model
+func (p *CohereProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	models, _ := p.ListModels(ctx)
+	for _, m := range models {
+		if m.ID == modelID {
+			return &m, nil
+		}
+	}
+	return nil, fmt.Errorf("model not found: %s", modelID)
+}
@@ -0,0 +1,19 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import "net/http"
+
+// SetCredentials sets the provider's credential header on an upstream request.
+// If the request passes client auth through, the client's Authorization header
+// is forwarded verbatim instead, and the provider credential is never sent.
+func SetCredentials(httpReq *http.Request, request *ProviderRequest, header, value string) {
+	if request != nil && request.PassThroughAuth {
+		if auth := request.Headers.Get("Authorization"); auth != "" {
+			httpReq.Header.Set("Authorization", auth)
+		}
+		return
+	}
+	httpReq.Header.Set(header, value)
+}
@@ -0,0 +1,282 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package generic implements the generic_http provider, which forwards
+// requests to an HTTP endpoint described entirely by its instance config.
+package generic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// Authentication types supported by the generic provider
+const (
+	AuthNone   = "none"
+	AuthAPIKey = "api_key"
+	AuthBearer = "bearer_token"
+	AuthBasic  = "basic"
+)
+
+// defaultAPIKeyHeader carries api_key credentials when no header is configured
+const defaultAPIKeyHeader = "X-API-Key"
+
+// GenericHTTPProvider forwards requests to an HTTP endpoint unchanged,
+// adding the configured credentials and static headers. Error statuses
+// are reported as a ProviderError carrying the upstream body.
+type GenericHTTPProvider struct {
+	name            string
+	baseURL         string
+	auth            GenericHTTPAuth
+	headers         map[string]string
+	healthCheckPath string
+	httpClient      *http.Client
+}
+
+// GenericHTTPConfig configures a generic HTTP provider
+type GenericHTTPConfig struct {
+	// Name identifies the provider in errors and logs, usually the instance name
+	Name string
+
+	// BaseURL is prepended to request paths
+	BaseURL string
+
+	// Auth is added to every request
+	Auth GenericHTTPAuth
+
+	// Headers are set on every request, including health checks
+	Headers map[string]string
+
+	// HealthCheckPath is requested with GET by HealthCheck; a 2xx status
+	// is healthy. Empty disables the check.
+	HealthCheckPath string
+
+	// Timeouts bound dialing and the TLS handshake, and each whole request
+	// including reading the response body (default: 10s connect, 120s
+	// request)
+	Timeouts providers.HTTPTimeouts
+}
+
+// GenericHTTPAuth holds the credentials of a generic HTTP provider
+type GenericHTTPAuth struct {
+	Type     string // none (default), api_key, bearer_token or basic
+	Header   string // api_key header (default: X-API-Key)
+	Key      string // api_key value, or the bearer token when Token is empty
+	Token    string // bearer_token value
+	Username string // basic
+	Password string // basic
+}
+
+// NewGenericHTTPProvider creates a new generic HTTP provider
+func NewGenericHTTPProvider(config GenericHTTPConfig) (*GenericHTTPProvider, error) {
+	if config.BaseURL == "" {
+		return nil, fmt.Errorf("generic_http base URL is required")
+	}
+	if _, err := url.Parse(config.BaseURL); err != nil {
+		return nil, fmt.Errorf("invalid generic_http base URL: %w", err)
+	}
+	switch config.Auth.Type {
+	case "", AuthNone, AuthAPIKey, AuthBearer, AuthBasic:
+	default:
+		return nil, fmt.Errorf("unsupported generic_http authentication type %q", config.Auth.Type)
+	}
+	if config.Auth.Type == AuthAPIKey && config.Auth.Header == "" {
+		config.Auth.Header = defaultAPIKeyHeader
+	}
+	if config.Name == "" {
+		config.Name = "generic_http"
+	}
+
+	return &GenericHTTPProvider{
+		name:            config.Name,
+		baseURL:         strings.TrimSuffix(config.BaseURL, "/"),
+		auth:            config.Auth,
+		headers:         config.Headers,
+		healthCheckPath: config.HealthCheckPath,
+		httpClient:      providers.NewHTTPClient(config.Timeouts),
+	}, nil
+}
+
+// Name returns the provider name
+func (p *GenericHTTPProvider) Name() string {
+	return p.name
+}
+
+// BaseURL returns the endpoint requests are sent to
+func (p *GenericHTTPProvider) BaseURL() string {
+	return p.baseURL
+}
+
+// SignsPayload reports false: credentials do not depend on the body
+func (p *GenericHTTPProvider) SignsPayload() bool {
+	return false
+}
+
+// Authorize adds the static headers and credentials to a forwarded request
+func (p *GenericHTTPProvider) Authorize(req *http.Request, payloadHash string) error {
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+	switch p.auth.Type {
+	case AuthAPIKey:
+		req.Header.Set(p.auth.Header, p.auth.Key)
+	case AuthBearer:
+		token := p.auth.Token
+		if token == "" {
+			token = p.auth.Key
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	case AuthBasic:
+		req.SetBasicAuth(p.auth.Username, p.auth.Password)
+	}
+	return nil
+}
+
+// HealthCheck requests the health check path, if configured
+func (p *GenericHTTPProvider) HealthCheck(ctx context.Context) error {
+	if p.healthCheckPath == "" {
+		return nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+p.healthCheckPath, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create health check request: %w", err)
+	}
+	p.Authorize(req, "")
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("health check failed: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("health check failed with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Invoke forwards a request and returns the response unchanged
+func (p *GenericHTTPProvider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.name,
+			StatusCode: http.StatusBadGateway,
+			Code:       providers.ErrCodeInternalError,
+			Message:    "Failed to read response",
+			Err:        err,
+		}
+	}
+
+	return &providers.ProviderResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       body,
+	}, nil
+}
+
+// InvokeStreaming forwards a request and returns the response body as it
+// arrives, with the response headers
+func (p *GenericHTTPProvider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return providers.NewHeaderStream(resp.Body, resp.Header.Clone()), nil
+}
+
+// InvokeStream forwards a request without buffering either body
+func (p *GenericHTTPProvider) InvokeStream(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderStreamResponse, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &providers.ProviderStreamResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       resp.Body,
+	}, nil
+}
+
+// ListModels returns no models: the endpoint's models are not discoverable
+func (p *GenericHTTPProvider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	return nil, nil
+}
+
+// GetModelInfo is not supported
+func (p *GenericHTTPProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return nil, fmt.Errorf("model info not available for %s", p.name)
+}
+
+// do sends a request. Error statuses are returned as a ProviderError;
+// otherwise the caller must close the response body.
+func (p *GenericHTTPProvider) do(ctx context.Context, request *providers.ProviderRequest) (*http.Response, error) {
+	target := p.baseURL + request.Path
+	if len(request.QueryParams) > 0 {
+		query := url.Values{}
+		for key, value := range request.QueryParams {
+			query.Set(key, value)
+		}
+		target += "?" + query.Encode()
+	}
+
+	method := request.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := providers.NewBodyRequest(ctx, method, target, request)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.name,
+			StatusCode: http.StatusInternalServerError,
+			Code:       providers.ErrCodeInternalError,
+			Message:    "Failed to create request",
+			Err:        err,
+		}
+	}
+
+	providers.ApplyHeaders(req.Header, request.Headers)
+	if request.PassThroughAuth {
+		for name, value := range p.headers {
+			req.Header.Set(name, value)
+		}
+	} else {
+		p.Authorize(req, "")
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.name,
+			StatusCode: http.StatusServiceUnavailable,
+			Code:       providers.ErrCodeServiceUnavailable,
+			Message:    fmt.Sprintf("request failed: %v", err),
+			Err:        err,
+		}
+	}
+
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &providers.ProviderError{
+			Provider:   p.name,
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+			Headers:    resp.Header.Clone(),
+		}
+	}
+	return resp, nil
+}
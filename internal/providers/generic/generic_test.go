@@ -0,0 +1,97 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+func TestGenericHTTPAuth(t *testing.T) {
+	tests := []struct {
+		auth   GenericHTTPAuth
+		header string
+		want   string
+	}{
+		{GenericHTTPAuth{Type: AuthAPIKey, Key: "k1"}, "X-API-Key", "k1"},
+		{GenericHTTPAuth{Type: AuthAPIKey, Header: "api-key", Key: "k2"}, "Api-Key", "k2"},
+		{GenericHTTPAuth{Type: AuthBearer, Token: "t1"}, "Authorization", "Bearer t1"},
+		{GenericHTTPAuth{Type: AuthBasic, Username: "svc", Password: "pw"}, "Authorization", "Basic c3ZjOnB3"},
+		{GenericHTTPAuth{}, "Authorization", ""},
+	}
+	for _, tt := range tests {
+		var got http.Header
+		upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.Header.Clone()
+			io.WriteString(w, `{"ok":true}`)
+		}))
+
+		provider, err := NewGenericHTTPProvider(GenericHTTPConfig{
+			Name:    "internal",
+			BaseURL: upstream.URL + "/",
+			Auth:    tt.auth,
+			Headers: map[string]string{"X-Team": "ml"},
+		})
+		if err != nil {
+			t.Fatalf("NewGenericHTTPProvider(%+v): %v", tt.auth, err)
+		}
+		resp, err := provider.Invoke(context.Background(), &providers.ProviderRequest{
+			Method: http.MethodPost,
+			Path:   "/v1/generate",
+			Body:   []byte(`{}`),
+		})
+		upstream.Close()
+		if err != nil || string(resp.Body) != `{"ok":true}` {
+			t.Fatalf("%s auth: Invoke = %v, %v", tt.auth.Type, resp, err)
+		}
+		if got.Get(tt.header) != tt.want {
+			t.Errorf("%s auth: %s = %q, want %q", tt.auth.Type, tt.header, got.Get(tt.header), tt.want)
+		}
+		if got.Get("X-Team") != "ml" {
+			t.Errorf("%s auth: static header X-Team = %q", tt.auth.Type, got.Get("X-Team"))
+		}
+	}
+}
+
+func TestGenericHTTPErrors(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/healthz" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusTooManyRequests)
+		io.WriteString(w, `{"error":"busy"}`)
+	}))
+	defer upstream.Close()
+
+	provider, err := NewGenericHTTPProvider(GenericHTTPConfig{Name: "internal", BaseURL: upstream.URL, HealthCheckPath: "/healthz"})
+	if err != nil {
+		t.Fatalf("NewGenericHTTPProvider: %v", err)
+	}
+
+	_, err = provider.Invoke(context.Background(), &providers.ProviderRequest{Method: http.MethodPost, Path: "/generate"})
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusTooManyRequests || providerErr.Message != `{"error":"busy"}` {
+		t.Errorf("Invoke error = %v, want the upstream 429", err)
+	}
+	if providerErr != nil && providerErr.Provider != "internal" {
+		t.Errorf("error provider = %q, want the instance name", providerErr.Provider)
+	}
+
+	if err := provider.HealthCheck(context.Background()); err == nil {
+		t.Error("HealthCheck() = nil for a 503 health endpoint")
+	}
+}
+
+func TestNewGenericHTTPProviderValidation(t *testing.T) {
+	if _, err := NewGenericHTTPProvider(GenericHTTPConfig{}); err == nil {
+		t.Error("accepted a config without a base URL")
+	}
+	if _, err := NewGenericHTTPProvider(GenericHTTPConfig{BaseURL: "https://x", Auth: GenericHTTPAuth{Type: "aws_sigv4"}}); err == nil {
+		t.Error("accepted aws_sigv4 authentication")
+	}
+}
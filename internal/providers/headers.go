@@ -0,0 +1,169 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import (
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// hopByHopHeaders are connection-specific headers that must not be forwarded
+// by a proxy (RFC 7230 section 6.1)
+var hopByHopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// ForwardHeaders returns a copy of h that is safe to forward to the next hop.
+// Hop-by-hop headers and any header named in Connection are removed, as are
+// Host and Content-Length, which the HTTP client or server sets from the URL
+// and body. All values of multi-value headers are kept.
+func ForwardHeaders(h http.Header) http.Header {
+	forwarded := h.Clone()
+	if forwarded == nil {
+		return http.Header{}
+	}
+	CanonicalizeHeaders(forwarded)
+
+	for _, value := range h.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				forwarded.Del(name)
+			}
+		}
+	}
+	for _, name := range hopByHopHeaders {
+		forwarded.Del(name)
+	}
+	forwarded.Del("Host")
+	forwarded.Del("Content-Length")
+	return forwarded
+}
+
+// CanonicalizeHeaders rewrites the keys of h in canonical form
+// (content-type → Content-Type), as net/http does when it parses headers.
+// Headers built as map literals can hold other spellings, which Get, Del
+// and Set do not find and which are written to the wire verbatim. When a
+// header has a canonical key it wins and other spellings are dropped;
+// otherwise the values of all spellings are merged.
+func CanonicalizeHeaders(h http.Header) {
+	var keys []string
+	for key := range h {
+		if http.CanonicalHeaderKey(key) != key {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	present := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		canonical := http.CanonicalHeaderKey(key)
+		if _, ok := present[canonical]; !ok {
+			_, present[canonical] = h[canonical]
+		}
+		if !present[canonical] {
+			h[canonical] = append(h[canonical], h[key]...)
+		}
+		delete(h, key)
+	}
+}
+
+// ApplyHeaders sets each header in src on dst, replacing any existing values
+// for that header and keeping all of src's values
+func ApplyHeaders(dst, src http.Header) {
+	for key, values := range src {
+		dst.Del(key)
+		for _, value := range values {
+			dst.Add(key, value)
+		}
+	}
+}
+
+// ContentType returns the upstream Content-Type verbatim, looking the header
+// up case-insensitively, or application/octet-stream when it is absent.
+// Content-Encoding is left to ForwardHeaders, so a compressed body keeps its
+// encoding label.
+func ContentType(h http.Header) string {
+	for key, values := range h {
+		if !strings.EqualFold(key, "Content-Type") {
+			continue
+		}
+		for _, value := range values {
+			if value != "" {
+				return value
+			}
+		}
+	}
+	return "application/octet-stream"
+}
+
+// RequestIDHeader carries the gateway request ID to upstreams that accept it
+const RequestIDHeader = "X-Request-ID"
+
+// upstreamRequestIDHeaders are the response headers providers use for their
+// own request IDs, in order of preference
+var upstreamRequestIDHeaders = []string{
+	"X-Amzn-Requestid", // AWS (Bedrock, SageMaker, ...)
+	"X-Request-Id",     // OpenAI, Azure, Cohere
+	"Request-Id",       // Anthropic
+}
+
+// UpstreamRequestID returns the provider's request ID from its response
+// headers, or "" if it did not send one
+func UpstreamRequestID(h http.Header) string {
+	for _, name := range upstreamRequestIDHeaders {
+		if id := h.Get(name); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// PropagatedHeaders returns the upstream response headers clients use for
+// adaptive backoff: the x-ratelimit-* and anthropic-ratelimit-* families and
+// retry-after. Handlers that build their own response copy these from the
+// provider response.
+func PropagatedHeaders(h http.Header) http.Header {
+	propagated := make(http.Header)
+	for key, values := range h {
+		canonical := http.CanonicalHeaderKey(key)
+		if strings.HasPrefix(canonical, "X-Ratelimit-") ||
+			strings.HasPrefix(canonical, "Anthropic-Ratelimit-") ||
+			canonical == "Retry-After" || canonical == "Retry-After-Ms" {
+			propagated[canonical] = append([]string(nil), values...)
+		}
+	}
+	return propagated
+}
+
+// StreamHeaders is implemented by streams returned from InvokeStreaming that
+// expose the upstream response headers
+type StreamHeaders interface {
+	ResponseHeaders() http.Header
+}
+
+// headerStream is a streaming response body carrying its response headers
+type headerStream struct {
+	io.ReadCloser
+	headers http.Header
+}
+
+func (s *headerStream) ResponseHeaders() http.Header {
+	return s.headers
+}
+
+// NewHeaderStream wraps a streaming response body so callers can read the
+// upstream response headers through StreamHeaders
+func NewHeaderStream(body io.ReadCloser, headers http.Header) io.ReadCloser {
+	return &headerStream{ReadCloser: body, headers: headers}
+}
@@ -0,0 +1,158 @@
+package providers
+
+import (
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestForwardHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Add("Set-Cookie", "a=1")
+	h.Add("Set-Cookie", "b=2")
+	h.Add("Via", "1.1 edge")
+	h.Add("Via", "1.1 lb")
+	h.Set("Connection", "keep-alive, X-Internal-Hop")
+	h.Set("X-Internal-Hop", "secret")
+	h.Set("Keep-Alive", "timeout=5")
+	h.Set("Transfer-Encoding", "chunked")
+	h.Set("Upgrade", "h2c")
+	h.Set("Host", "example.com")
+	h.Set("Content-Length", "42")
+	h.Set("Content-Type", "application/json")
+
+	got := ForwardHeaders(h)
+
+	if !reflect.DeepEqual(got.Values("Set-Cookie"), []string{"a=1", "b=2"}) {
+		t.Errorf("Set-Cookie = %q, want both values", got.Values("Set-Cookie"))
+	}
+	if !reflect.DeepEqual(got.Values("Via"), []string{"1.1 edge", "1.1 lb"}) {
+		t.Errorf("Via = %q, want both values", got.Values("Via"))
+	}
+	for _, name := range []string{"Connection", "X-Internal-Hop", "Keep-Alive", "Transfer-Encoding", "Upgrade", "Host", "Content-Length"} {
+		if got.Get(name) != "" {
+			t.Errorf("%s was forwarded", name)
+		}
+	}
+	if got.Get("Content-Type") != "application/json" {
+		t.Errorf("Content-Type = %q", got.Get("Content-Type"))
+	}
+
+	// The input is not modified
+	if h.Get("Connection") == "" || len(h.Values("Set-Cookie")) != 2 {
+		t.Error("ForwardHeaders modified its input")
+	}
+}
+
+func TestApplyHeaders(t *testing.T) {
+	dst := http.Header{}
+	dst.Set("Vary", "Origin")
+	dst.Set("X-Keep", "1")
+
+	src := http.Header{}
+	src.Add("Vary", "Accept")
+	src.Add("Vary", "Accept-Encoding")
+
+	ApplyHeaders(dst, src)
+
+	if !reflect.DeepEqual(dst.Values("Vary"), []string{"Accept", "Accept-Encoding"}) {
+		t.Errorf("Vary = %q, want src values replacing dst", dst.Values("Vary"))
+	}
+	if dst.Get("X-Keep") != "1" {
+		t.Error("unrelated header removed")
+	}
+}
+
+func TestContentType(t *testing.T) {
+	tests := []struct {
+		headers http.Header
+		want    string
+	}{
+		{http.Header{"Content-Type": {"image/png"}}, "image/png"},
+		{http.Header{"content-type": {"text/event-stream; charset=utf-8"}}, "text/event-stream; charset=utf-8"},
+		{http.Header{"CONTENT-TYPE": {"", "text/plain"}}, "text/plain"},
+		{http.Header{}, "application/octet-stream"},
+		{nil, "application/octet-stream"},
+	}
+	for _, tt := range tests {
+		if got := ContentType(tt.headers); got != tt.want {
+			t.Errorf("ContentType(%v) = %q, want %q", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestUpstreamRequestID(t *testing.T) {
+	tests := []struct {
+		headers http.Header
+		want    string
+	}{
+		{http.Header{"X-Amzn-Requestid": {"aws-1"}, "X-Request-Id": {"other"}}, "aws-1"},
+		{http.Header{"X-Request-Id": {"req_openai"}}, "req_openai"},
+		{http.Header{"Request-Id": {"req_anthropic"}}, "req_anthropic"},
+		{http.Header{}, ""},
+	}
+	for _, tt := range tests {
+		if got := UpstreamRequestID(tt.headers); got != tt.want {
+			t.Errorf("UpstreamRequestID(%v) = %q, want %q", tt.headers, got, tt.want)
+		}
+	}
+}
+
+func TestPropagatedHeaders(t *testing.T) {
+	h := http.Header{}
+	h.Set("X-Ratelimit-Remaining-Tokens", "100")
+	h.Set("anthropic-ratelimit-requests-reset", "2025-01-01T00:00:00Z")
+	h.Set("Retry-After", "5")
+	h.Set("X-Request-Id", "req_1")
+	h.Set("Content-Type", "application/json")
+
+	got := PropagatedHeaders(h)
+	if len(got) != 3 || got.Get("Anthropic-Ratelimit-Requests-Reset") == "" || got.Get("Retry-After") != "5" {
+		t.Errorf("PropagatedHeaders = %v", got)
+	}
+}
+
+func TestHeaderStream(t *testing.T) {
+	stream := NewHeaderStream(io.NopCloser(strings.NewReader("data: {}\n\n")), http.Header{"Retry-After": {"1"}})
+	headers, ok := stream.(StreamHeaders)
+	if !ok || headers.ResponseHeaders().Get("Retry-After") != "1" {
+		t.Fatal("stream does not expose response headers")
+	}
+	if body, _ := io.ReadAll(stream); string(body) != "data: {}\n\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestCanonicalizeHeaders(t *testing.T) {
+	h := http.Header{
+		"content-type":   {"text/plain"},
+		"Content-Type":   {"application/json"},
+		"x-ratelimit-id": {"a"},
+		"X-RATELIMIT-ID": {"b"},
+		"retry-after":    {"3"},
+	}
+	CanonicalizeHeaders(h)
+
+	want := http.Header{
+		"Content-Type":   {"application/json"},
+		"X-Ratelimit-Id": {"b", "a"},
+		"Retry-After":    {"3"},
+	}
+	if !reflect.DeepEqual(h, want) {
+		t.Errorf("CanonicalizeHeaders = %v, want %v", h, want)
+	}
+}
+
+func TestForwardHeadersNonCanonical(t *testing.T) {
+	got := ForwardHeaders(http.Header{
+		"connection":     {"close"},
+		"content-length": {"42"},
+		"x-custom":       {"kept"},
+	})
+	want := http.Header{"X-Custom": {"kept"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ForwardHeaders = %v, want %v", got, want)
+	}
+}
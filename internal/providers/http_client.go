@@ -0,0 +1,151 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	// DefaultConnectTimeout bounds dialing a provider and the TLS handshake
+	DefaultConnectTimeout = 10 * time.Second
+
+	// DefaultRequestTimeout bounds a whole provider request
+	DefaultRequestTimeout = 120 * time.Second
+)
+
+// HTTPTimeouts bound the requests a provider sends. Connect is short so an
+// unreachable provider fails fast; Request can be long for slow
+// generations. Request bounds a request until its response headers arrive
+// and, unless the response is streamed, until its body is read; streams
+// run for as long as they last. Zero fields use the defaults.
+type HTTPTimeouts struct {
+	Connect time.Duration
+	Request time.Duration
+}
+
+// WithDefaults returns the timeouts with zero fields set to the defaults
+func (t HTTPTimeouts) WithDefaults() HTTPTimeouts {
+	if t.Connect <= 0 {
+		t.Connect = DefaultConnectTimeout
+	}
+	if t.Request <= 0 {
+		t.Request = DefaultRequestTimeout
+	}
+	return t
+}
+
+// NewTransport returns a transport applying the connect timeout to the
+// dialer and the TLS handshake
+func NewTransport(timeouts HTTPTimeouts) *http.Transport {
+	timeouts = timeouts.WithDefaults()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   timeouts.Connect,
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.TLSHandshakeTimeout = timeouts.Connect
+	return transport
+}
+
+// NewHTTPClient returns a client whose requests are bounded by the connect
+// timeout while connecting and by the request timeout as their deadline
+func NewHTTPClient(timeouts HTTPTimeouts) *http.Client {
+	timeouts = timeouts.WithDefaults()
+	return &http.Client{
+		Transport: WithRequestTimeout(NewTransport(timeouts), timeouts.Request),
+	}
+}
+
+// WithRequestTimeout returns a transport giving each request a deadline of
+// timeout, through its context. The deadline ends when the response is
+// streamed, that is of unknown length, once its headers arrive, so long
+// streams are not cut off; other responses must be read within it.
+func WithRequestTimeout(base http.RoundTripper, timeout time.Duration) http.RoundTripper {
+	if timeout <= 0 {
+		return base
+	}
+	return &deadlineTransport{base: base, timeout: timeout}
+}
+
+// deadlineTransport applies the request timeout; see WithRequestTimeout
+type deadlineTransport struct {
+	base    http.RoundTripper
+	timeout time.Duration
+}
+
+// RoundTrip sends a request, cancelling it if the timeout passes first
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithCancelCause(req.Context())
+	expired := &requestTimeoutError{timeout: t.timeout}
+	timer := time.AfterFunc(t.timeout, func() { cancel(expired) })
+
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		timer.Stop()
+		cancel(nil)
+		return nil, expired.wrap(ctx, err)
+	}
+	if resp.ContentLength < 0 {
+		timer.Stop() // Streamed: only the client's context bounds it
+	}
+	resp.Body = &deadlineBody{ReadCloser: resp.Body, ctx: ctx, expired: expired, stop: func() {
+		timer.Stop()
+		cancel(nil)
+	}}
+	return resp, nil
+}
+
+// deadlineBody ends a request's deadline when its response body is closed
+type deadlineBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	expired *requestTimeoutError
+	stop    func()
+}
+
+func (b *deadlineBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err != nil && err != io.EOF {
+		err = b.expired.wrap(b.ctx, err)
+	}
+	return n, err
+}
+
+func (b *deadlineBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.stop()
+	return err
+}
+
+// requestTimeoutError reports a request that ran past its request timeout.
+// It is a net.Error timeout, like the http.Client timeout errors.
+type requestTimeoutError struct {
+	timeout time.Duration
+	err     error
+}
+
+// wrap returns err as a request timeout error if the deadline cancelled ctx
+func (e *requestTimeoutError) wrap(ctx context.Context, err error) error {
+	if context.Cause(ctx) != e {
+		return err
+	}
+	return &requestTimeoutError{timeout: e.timeout, err: err}
+}
+
+func (e *requestTimeoutError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("request timeout of %s exceeded", e.timeout)
+	}
+	return fmt.Sprintf("request timeout of %s exceeded: %v", e.timeout, e.err)
+}
+
+func (e *requestTimeoutError) Unwrap() error   { return e.err }
+func (e *requestTimeoutError) Timeout() bool   { return true }
+func (e *requestTimeoutError) Temporary() bool { return false }
@@ -0,0 +1,77 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestRequestTimeout tests that the request timeout bounds a request until
+// its response, and the body of a response of known length, but not a
+// stream that outlasts it
+func TestRequestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			io.WriteString(w, "late")
+		case "/stream":
+			w.Header().Set("Content-Type", "text/event-stream")
+			for i := 0; i < 4; i++ {
+				io.WriteString(w, "data: {}\n\n")
+				w.(http.Flusher).Flush()
+				time.Sleep(50 * time.Millisecond)
+			}
+		case "/stalled":
+			w.Header().Set("Content-Length", "8")
+			io.WriteString(w, "half")
+			w.(http.Flusher).Flush()
+			time.Sleep(200 * time.Millisecond)
+			io.WriteString(w, "done")
+		}
+	}))
+	defer server.Close()
+	client := NewHTTPClient(HTTPTimeouts{Request: 100 * time.Millisecond})
+
+	_, err := client.Get(server.URL + "/slow")
+	var netErr net.Error
+	if err == nil || !strings.Contains(err.Error(), "request timeout of 100ms exceeded") {
+		t.Errorf("slow response: error = %v, want the request timeout", err)
+	} else if !errors.As(err, &netErr) || !netErr.Timeout() {
+		t.Errorf("slow response: error %v is not a timeout", err)
+	}
+
+	resp, err := client.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil || strings.Count(string(body), "data:") != 4 {
+		t.Errorf("stream longer than the request timeout: %q, %v", body, err)
+	}
+
+	resp, err = client.Get(server.URL + "/stalled")
+	if err != nil {
+		t.Fatalf("stalled: %v", err)
+	}
+	_, err = io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err == nil || !strings.Contains(err.Error(), "request timeout") {
+		t.Errorf("stalled body: error = %v, want the request timeout", err)
+	}
+
+	// The client's own cancellation is not reported as a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/slow", nil)
+	if _, err := client.Do(req); err == nil || strings.Contains(err.Error(), "request timeout") {
+		t.Errorf("cancelled request: error = %v", err)
+	}
+}
@@ -29,6 +29,7 @@ type IBMConfig struct {
 	APIKey    string `yaml:"api_key"`
 	ProjectID string `yaml:"project_id"`
 	BaseURL   string `yaml:"base_url"` // Optional, defaults to https://us-south.ml.cloud.ibm.com
+	Timeouts  providers.HTTPTimeouts // Optional, defaults to 10s connect and 120s request
 }
 
 // IBM watsonx.ai request/response types
@@ -46,6 +47,20 @@ type IBMParameters struct {
 	TopP           *float64 `json:"top_p,omitempty"`
 	TopK           *int     `json:"top_k,omitempty"`
 	StopSequences  []string `json:"stop_sequences,omitempty"`
+	DecodingMethod string   `json:"decoding_method,omitempty"` // "greedy" or "sample"
+	ReturnOptions  *IBMReturnOptions `json:"return_options,omitempty"`
+}
+
+// IBMReturnOptions asks watsonx.ai for per-token details in the results
+type IBMReturnOptions struct {
+	GeneratedTokens bool `json:"generated_tokens,omitempty"`
+	TokenLogprobs   bool `json:"token_logprobs,omitempty"`
+}
+
+// IBMToken is one generated token, returned when ReturnOptions asks for it
+type IBMToken struct {
+	Text    string  `json:"text"`
+	Logprob float64 `json:"logprob"`
 }
 
 type IBMResponse struct {
@@ -59,6 +74,7 @@ type IBMResult struct {
 	GeneratedTokens  int    `json:"generated_token_count"`
 	InputTokens      int    `json:"input_token_count"`
 	StopReason       string `json:"stop_reason"`
+	Tokens           []IBMToken `json:"generated_tokens,omitempty"`
 }
 
 // NewIBMProvider creates a new IBM watsonx.ai provider
@@ -79,9 +95,7 @@ func NewIBMProvider(config IBMConfig) (*IBMProvider, error) {
 		apiKey:    config.APIKey,
 		projectID: config.ProjectID,
 		baseURL:   baseURL,
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		httpClient: providers.NewHTTPClient(config.Timeouts),
 	}, nil
 }
 
@@ -90,6 +104,13 @@ func (p *IBMProvider) Name() string {
 	return "ibm"
 }
 
+// Capabilities reports what watsonx.ai models support through the proxy
+func (p *IBMProvider) Capabilities() providers.Capabilities {
+	return providers.Capabilities{
+		MaxContextTokens: 8192,
+	}
+}
+
 // HealthCheck checks if the provider is accessible
 func (p *IBMProvider) HealthCheck(ctx context.Context) error {
 	// Could check API availability, but skip for now
@@ -108,13 +129,45 @@ func (p *IBMProvider) Invoke(ctx context.Context, request *providers.ProviderReq
 		}
 	}
 
-	// Translate to IBM format
+	// Translate to IBM format and send it
 	ibmReq := translateOpenAIToIBM(&openaiReq, p.projectID)
+	ibmResp, headers, err := p.Generate(ctx, request, ibmReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Translate back to OpenAI format
+	openaiResp := translateIBMToOpenAI(ibmResp, openaiReq.Model)
+
+	// Marshal OpenAI response
+	openaiBody, err := json.Marshal(openaiResp)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("failed to marshal response: %v", err),
+			Provider:   "ibm",
+		}
+	}
+
+	return &providers.ProviderResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       openaiBody,
+	}, nil
+}
+
+// Generate sends a watsonx.ai text generation request and returns its
+// response and headers. The provider's project is used when the request
+// names none; credentials are set as for Invoke.
+func (p *IBMProvider) Generate(ctx context.Context, request *providers.ProviderRequest, ibmReq *IBMRequest) (*IBMResponse, http.Header, error) {
+	if ibmReq.ProjectID == "" {
+		ibmReq.ProjectID = p.projectID
+	}
 
 	// Marshal request
 	body, err := json.Marshal(ibmReq)
 	if err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
 			Message:    fmt.Sprintf("failed to marshal request: %v", err),
 			Provider:   "ibm",
@@ -125,7 +178,7 @@ func (p *IBMProvider) Invoke(ctx context.Context, request *providers.ProviderReq
 	url := fmt.Sprintf("%s/ml/v1/text/generation?version=2023-05-29", p.baseURL)
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
 			Message:    fmt.Sprintf("failed to create request: %v", err),
 			Provider:   "ibm",
@@ -134,13 +187,13 @@ func (p *IBMProvider) Invoke(ctx context.Context, request *providers.ProviderReq
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	providers.SetCredentials(httpReq, request, "Authorization", "Bearer "+p.apiKey)
 	httpReq.Header.Set("Accept", "application/json")
 
 	// Send request
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusServiceUnavailable,
 			Message:    fmt.Sprintf("request failed: %v", err),
 			Provider:   "ibm",
@@ -151,7 +204,7 @@ func (p *IBMProvider) Invoke(ctx context.Context, request *providers.ProviderReq
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
 			Message:    fmt.Sprintf("failed to read response: %v", err),
 			Provider:   "ibm",
@@ -160,7 +213,7 @@ func (p *IBMProvider) Invoke(ctx context.Context, request *providers.ProviderReq
 
 	// Check for errors
 	if resp.StatusCode != http.StatusOK {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: resp.StatusCode,
 			Message:    string(respBody),
 			Provider:   "ibm",
@@ -170,39 +223,14 @@ func (p *IBMProvider) Invoke(ctx context.Context, request *providers.ProviderReq
 	// Parse IBM response
 	var ibmResp IBMResponse
 	if err := json.Unmarshal(respBody, &ibmResp); err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
 			Message:    fmt.Sprintf("failed to parse response: %v", err),
 			Provider:   "ibm",
 		}
 	}
 
-	// Translate back to OpenAI format
-	openaiResp := translateIBMToOpenAI(&ibmResp, openaiReq.Model)
-
-	// Marshal OpenAI response
-	openaiBody, err := json.Marshal(openaiResp)
-	if err != nil {
-		return nil, &providers.ProviderError{
-			StatusCode: http.StatusInternalServerError,
-			Message:    fmt.Sprintf("failed to marshal response: %v", err),
-			Provider:   "ibm",
-		}
-	}
-
-	// Build provider response
-	headers := make(map[string]string)
-	for k, v := range resp.Header {
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
-	}
-
-	return &providers.ProviderResponse{
-		StatusCode: resp.StatusCode,
-		Headers:    headers,
-		Body:       openaiBody,
-	}, nil
+	return &ibmResp, resp.Header.Clone(), nil
 }
 
 // InvokeStreaming sends a streaming request to IBM watsonx.ai
@@ -246,7 +274,7 @@ func translateOpenAIToIBM(req *translator.ChatCompletionRequest, projectID strin
 	// IBM's simple generation API doesn't support multi-turn chat directly
 	var input string
 	for _, msg := range req.Messages {
-		text := extractTextContent(msg.Content)
+		text := msg.Content.Text()
 		if msg.Role == "system" {
 			input += fmt.Sprintf("System: %s\n\n", text)
 		} else if msg.Role == "user" {
@@ -311,7 +339,7 @@ func translateIBMToOpenAI(resp *IBMResponse, model string) *translator.ChatCompl
 				Index: 0,
 				Message: translator.ChatMessage{
 					Role:    "assistant",
-					Content: content,
+					Content: translator.TextContent(content),
 				},
 				FinishReason: finishReason,
 			},
@@ -323,25 +351,3 @@ func translateIBMToOpenAI(resp *IBMResponse, model string) *translator.ChatCompl
 		},
 	}
 }
-
-// extractTextContent extracts text from content interface
-func extractTextContent(content interface{}) string {
-	switch c := content.(type) {
-	case string:
-		return c
-	case []interface{}:
-		var text string
-		for _, part := range c {
-			if partMap, ok := part.(map[string]interface{}); ok {
-				if partType, ok := partMap["type"].(string); ok && partType == "text" {
-					if textVal, ok := partMap["text"].(string); ok {
-						text += textVal
-					}
-				}
-			}
-		}
-		return text
-	default:
-		return fmt.Sprintf("%v", content)
-	}
-}
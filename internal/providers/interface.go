@@ -6,6 +6,7 @@ package providers
 import (
 	"context"
 	"io"
+	"net/http"
 	"time"
 )
 
@@ -40,17 +41,30 @@ type ProviderRequest struct {
 	Path string
 
 	// HTTP headers
-	Headers map[string]string
+	Headers http.Header
 
-	// Request body (usually JSON bytes)
+	// Request body (usually JSON bytes). Ignored when BodyReader is set.
 	Body []byte
 
+	// BodyReader streams the request body instead of Body: when non-nil it
+	// takes precedence and Body is ignored. It is read at most once. Only
+	// providers implementing StreamingInvoker read it; send such requests
+	// with InvokeStream, which buffers it into Body for the others.
+	BodyReader io.Reader
+
+	// ContentLength is the length of BodyReader, or 0 when unknown
+	ContentLength int64
+
 	// URL query parameters
 	QueryParams map[string]string
 
 	// Additional metadata (user info, tracing, etc.)
 	Metadata map[string]any
 
+	// Forward Headers["Authorization"] from the client verbatim instead of
+	// the provider's own credentials (instance authentication.pass_through_auth)
+	PassThroughAuth bool
+
 	// Original request context
 	Context context.Context
 }
@@ -61,7 +75,7 @@ type ProviderResponse struct {
 	StatusCode int
 
 	// Response headers
-	Headers map[string]string
+	Headers http.Header
 
 	// Response body (usually JSON bytes)
 	Body []byte
@@ -106,7 +120,7 @@ type Model struct {
 	// Model description
 	Description string
 
-	// Capabilities (chat, completion, embeddings, streaming, vision, function_calling)
+	// Capabilities (chat, completion, embeddings, streaming, vision, video, function_calling)
 	Capabilities []string
 
 	// Max context window length in tokens
@@ -170,6 +184,9 @@ type ProviderError struct {
 
 	// Original error
 	Err error
+
+	// Upstream response headers, when the error is an upstream HTTP status
+	Headers http.Header
 }
 
 func (e *ProviderError) Error() string {
@@ -190,6 +207,7 @@ const (
 	CapabilityEmbeddings      = "embeddings"
 	CapabilityStreaming       = "streaming"
 	CapabilityVision          = "vision"
+	CapabilityVideo           = "video"
 	CapabilityFunctionCalling = "function_calling"
 	CapabilityJSON            = "json_mode"
 )
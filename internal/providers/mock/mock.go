@@ -0,0 +1,457 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package mock implements an in-process provider answering with
+// deterministic canned responses, so the gateway can be run end to end
+// without cloud credentials. It is only registered when
+// ENABLE_MOCK_PROVIDER=true.
+package mock
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// ReplyPrefix starts every chat reply; the rest echoes the text of the
+// last user message
+const ReplyPrefix = "Mock response to: "
+
+// DefaultDimensions is the size of embeddings when Dimensions is not set
+const DefaultDimensions = 8
+
+// MockProvider implements the Provider interface with canned responses.
+// Chat requests are read in the OpenAI format and answered with a reply
+// echoing the last user message; embeddings requests are answered in the
+// OpenAI, Cohere or Titan format they were sent in.
+type MockProvider struct {
+	config   MockConfig
+	requests atomic.Int64
+}
+
+// MockConfig for the mock provider
+type MockConfig struct {
+	Latency          time.Duration // Delay before every response
+	ErrorRate        float64       // Fraction of requests failed, from 0 to 1
+	ErrorStatus      int           // Status of injected errors, defaults to 503
+	ErrorCode        string        // Code of injected errors, defaults to service_unavailable
+	PromptTokens     int           // Reported prompt tokens, defaults to the words of the input
+	CompletionTokens int           // Reported completion tokens, defaults to the words of the reply
+	Dimensions       int           // Embedding size, defaults to DefaultDimensions
+}
+
+// NewMockProvider creates a new mock provider
+func NewMockProvider(config MockConfig) (*MockProvider, error) {
+	if config.ErrorRate < 0 || config.ErrorRate > 1 {
+		return nil, fmt.Errorf("mock error rate must be between 0 and 1, got %v", config.ErrorRate)
+	}
+	if config.ErrorStatus == 0 {
+		config.ErrorStatus = http.StatusServiceUnavailable
+	}
+	if config.ErrorCode == "" {
+		config.ErrorCode = providers.ErrCodeServiceUnavailable
+	}
+	if config.Dimensions <= 0 {
+		config.Dimensions = DefaultDimensions
+	}
+	return &MockProvider{config: config}, nil
+}
+
+// Name returns the provider name
+func (p *MockProvider) Name() string {
+	return "mock"
+}
+
+// Capabilities reports streaming, vision and tool support, so requests
+// needing them are routed to the mock rather than rejected
+func (p *MockProvider) Capabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Streaming:         true,
+		Vision:            true,
+		Tools:             true,
+		ParallelToolCalls: true,
+		MaxContextTokens:  128000,
+	}
+}
+
+// HealthCheck always succeeds
+func (p *MockProvider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// Invoke answers a chat completion or embeddings request
+func (p *MockProvider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	startTime := time.Now()
+	n, err := p.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		body   []byte
+		tokens usage
+	)
+	switch {
+	case strings.HasSuffix(request.Path, "/embeddings"):
+		body, tokens, err = p.openAIEmbeddings(request.Body)
+	case request.Path == "/embed" || strings.HasPrefix(request.Path, "/model/"):
+		body, tokens, err = p.nativeEmbeddings(request.Body)
+	default:
+		var chat *chatReply
+		if chat, err = p.reply(n, request.Body); err == nil {
+			tokens = chat.usage
+			body, err = json.Marshal(chat.response())
+		}
+	}
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   "mock",
+			StatusCode: http.StatusBadRequest,
+			Code:       providers.ErrCodeInvalidRequest,
+			Message:    err.Error(),
+		}
+	}
+
+	return &providers.ProviderResponse{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"application/json"}},
+		Body:       body,
+		Metadata: providers.ResponseMetadata{
+			Latency:      time.Since(startTime),
+			InputTokens:  tokens.prompt,
+			OutputTokens: tokens.completion,
+			TotalTokens:  tokens.prompt + tokens.completion,
+		},
+	}, nil
+}
+
+// InvokeStreaming answers a chat completion request with an OpenAI SSE
+// stream, one chunk per word of the reply
+func (p *MockProvider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
+	n, err := p.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chat, err := p.reply(n, request.Body)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   "mock",
+			StatusCode: http.StatusBadRequest,
+			Code:       providers.ErrCodeInvalidRequest,
+			Message:    err.Error(),
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, chunk := range chat.chunks() {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal stream chunk: %w", err)
+		}
+		fmt.Fprintf(&buf, "data: %s\n\n", data)
+	}
+	buf.WriteString("data: [DONE]\n\n")
+	return io.NopCloser(&buf), nil
+}
+
+// InvokeChatStream answers a chat completion request with typed events,
+// one delta per word of the reply
+func (p *MockProvider) InvokeChatStream(ctx context.Context, request *providers.ChatRequest) (<-chan providers.ChatEvent, error) {
+	n, err := p.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	chat, err := p.reply(n, request.Request.Body)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   "mock",
+			StatusCode: http.StatusBadRequest,
+			Code:       providers.ErrCodeInvalidRequest,
+			Message:    err.Error(),
+		}
+	}
+
+	events := make(chan providers.ChatEvent)
+	go func() {
+		defer close(events)
+		send := func(event providers.ChatEvent) bool {
+			return providers.SendChatEvent(ctx, events, event)
+		}
+		for i, word := range chat.words() {
+			event := providers.ChatEvent{Type: providers.ChatEventDelta, Text: word}
+			if i == 0 {
+				event.Role = "assistant"
+			}
+			if !send(event) {
+				return
+			}
+		}
+		usage := &providers.ChatUsage{
+			InputTokens:  chat.usage.prompt,
+			OutputTokens: chat.usage.completion,
+			TotalTokens:  chat.usage.prompt + chat.usage.completion,
+		}
+		if !send(providers.ChatEvent{Type: providers.ChatEventUsage, Usage: usage}) {
+			return
+		}
+		send(providers.ChatEvent{Type: providers.ChatEventDone, FinishReason: "stop"})
+	}()
+	return events, nil
+}
+
+// ListModels lists the mock's chat and embeddings models
+func (p *MockProvider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	return []providers.Model{
+		{
+			ID:            "mock-chat",
+			Provider:      "mock",
+			Name:          "Mock Chat",
+			Capabilities:  p.Capabilities().List(),
+			ContextWindow: 128000,
+			Available:     true,
+		},
+		{
+			ID:           "mock-embed",
+			Provider:     "mock",
+			Name:         "Mock Embeddings",
+			Capabilities: []string{providers.CapabilityEmbeddings},
+			Available:    true,
+		},
+	}, nil
+}
+
+// GetModelInfo gets information about a mock model
+func (p *MockProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	models, _ := p.ListModels(ctx)
+	for _, m := range models {
+		if m.ID == modelID {
+			return &m, nil
+		}
+	}
+	return nil, fmt.Errorf("model not found: %s", modelID)
+}
+
+// begin waits for the configured latency and injects an error into the
+// configured fraction of requests. Errors are spread evenly rather than
+// drawn at random: with a rate of 0.25, every fourth request fails.
+func (p *MockProvider) begin(ctx context.Context) (int64, error) {
+	n := p.requests.Add(1)
+
+	if p.config.Latency > 0 {
+		timer := time.NewTimer(p.config.Latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return n, &providers.ProviderError{
+				Provider:   "mock",
+				StatusCode: http.StatusGatewayTimeout,
+				Message:    "request canceled",
+				Err:        ctx.Err(),
+			}
+		}
+	}
+
+	rate := p.config.ErrorRate
+	if int64(float64(n)*rate) > int64(float64(n-1)*rate) {
+		return n, &providers.ProviderError{
+			Provider:   "mock",
+			StatusCode: p.config.ErrorStatus,
+			Code:       p.config.ErrorCode,
+			Message:    fmt.Sprintf("injected mock error for request %d", n),
+		}
+	}
+	return n, nil
+}
+
+// usage is the token usage reported for a response
+type usage struct {
+	prompt     int
+	completion int
+}
+
+// tokens returns the configured usage, or counts words for unset counts
+func (p *MockProvider) tokens(input, output string) usage {
+	u := usage{prompt: p.config.PromptTokens, completion: p.config.CompletionTokens}
+	if u.prompt == 0 {
+		u.prompt = len(strings.Fields(input))
+	}
+	if u.completion == 0 && output != "" {
+		u.completion = len(strings.Fields(output))
+	}
+	return u
+}
+
+// chatReply is the canned answer to a chat completion request
+type chatReply struct {
+	id    string
+	model string
+	text  string
+	usage usage
+}
+
+// reply builds the answer to the nth request, an OpenAI chat completion
+// request body
+func (p *MockProvider) reply(n int64, body []byte) (*chatReply, error) {
+	var req translator.ChatCompletionRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, fmt.Errorf("invalid chat completion request: %w", err)
+	}
+
+	var input []string
+	lastUser := ""
+	for _, message := range req.Messages {
+		text := message.Content.Text()
+		input = append(input, text)
+		if message.Role == "user" {
+			lastUser = text
+		}
+	}
+	text := ReplyPrefix + lastUser
+	return &chatReply{
+		id:    fmt.Sprintf("chatcmpl-mock-%d", n),
+		model: req.Model,
+		text:  text,
+		usage: p.tokens(strings.Join(input, "\n"), text),
+	}, nil
+}
+
+// words splits the reply into stream deltas, keeping the spaces
+func (r *chatReply) words() []string {
+	return strings.SplitAfter(r.text, " ")
+}
+
+// response returns the reply as an OpenAI chat completion
+func (r *chatReply) response() *translator.ChatCompletionResponse {
+	return &translator.ChatCompletionResponse{
+		ID:      r.id,
+		Object:  "chat.completion",
+		Created: time.Now().Unix(),
+		Model:   r.model,
+		Choices: []translator.ChatCompletionChoice{{
+			Message:      translator.ChatMessage{Role: "assistant", Content: translator.TextContent(r.text)},
+			FinishReason: "stop",
+		}},
+		Usage: &translator.Usage{
+			PromptTokens:     r.usage.prompt,
+			CompletionTokens: r.usage.completion,
+			TotalTokens:      r.usage.prompt + r.usage.completion,
+		},
+	}
+}
+
+// chunks returns the reply as OpenAI chat completion chunks: one per word,
+// then one with the finish reason and usage
+func (r *chatReply) chunks() []translator.ChatCompletionStreamResponse {
+	created := time.Now().Unix()
+	chunk := func(delta translator.ChatMessageDelta) translator.ChatCompletionStreamResponse {
+		return translator.ChatCompletionStreamResponse{
+			ID:      r.id,
+			Object:  "chat.completion.chunk",
+			Created: created,
+			Model:   r.model,
+			Choices: []translator.ChatCompletionStreamChoice{{Delta: delta}},
+		}
+	}
+
+	var chunks []translator.ChatCompletionStreamResponse
+	for i, word := range r.words() {
+		delta := translator.ChatMessageDelta{Content: word}
+		if i == 0 {
+			delta.Role = "assistant"
+		}
+		chunks = append(chunks, chunk(delta))
+	}
+	last := chunk(translator.ChatMessageDelta{})
+	stop := "stop"
+	last.Choices[0].FinishReason = &stop
+	last.Usage = &translator.Usage{
+		PromptTokens:     r.usage.prompt,
+		CompletionTokens: r.usage.completion,
+		TotalTokens:      r.usage.prompt + r.usage.completion,
+	}
+	return append(chunks, last)
+}
+
+// openAIEmbeddings answers an OpenAI embeddings request
+func (p *MockProvider) openAIEmbeddings(body []byte) ([]byte, usage, error) {
+	var req translator.EmbeddingsRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, usage{}, fmt.Errorf("invalid embeddings request: %w", err)
+	}
+	texts, err := req.Texts()
+	if err != nil {
+		return nil, usage{}, err
+	}
+
+	tokens := p.tokens(strings.Join(texts, "\n"), "")
+	resp := translator.EmbeddingsResponse{
+		Object: "list",
+		Model:  req.Model,
+		Usage:  translator.Usage{PromptTokens: tokens.prompt, TotalTokens: tokens.prompt},
+	}
+	for i, text := range texts {
+		vector, err := json.Marshal(p.embed(text))
+		if err != nil {
+			return nil, usage{}, err
+		}
+		resp.Data = append(resp.Data, translator.Embedding{Object: "embedding", Index: i, Embedding: vector})
+	}
+	data, err := json.Marshal(resp)
+	return data, tokens, err
+}
+
+// nativeEmbeddings answers a Cohere embed request ({"texts": [...]}) or a
+// Titan one ({"inputText": "..."}) in the same format
+func (p *MockProvider) nativeEmbeddings(body []byte) ([]byte, usage, error) {
+	var req struct {
+		Texts     []string `json:"texts"`
+		InputText *string  `json:"inputText"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, usage{}, fmt.Errorf("invalid embeddings request: %w", err)
+	}
+
+	if req.InputText != nil {
+		tokens := p.tokens(*req.InputText, "")
+		data, err := json.Marshal(map[string]interface{}{
+			"embedding":           p.embed(*req.InputText),
+			"inputTextTokenCount": tokens.prompt,
+		})
+		return data, tokens, err
+	}
+
+	tokens := p.tokens(strings.Join(req.Texts, "\n"), "")
+	embeddings := make([][]float64, len(req.Texts))
+	for i, text := range req.Texts {
+		embeddings[i] = p.embed(text)
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"embeddings": embeddings,
+		"meta": map[string]interface{}{
+			"billed_units": map[string]int{"input_tokens": tokens.prompt},
+		},
+	})
+	return data, tokens, err
+}
+
+// embed returns a deterministic embedding of text, with values in [-1, 1]
+func (p *MockProvider) embed(text string) []float64 {
+	vector := make([]float64, p.config.Dimensions)
+	for i := range vector {
+		h := fnv.New64a()
+		fmt.Fprintf(h, "%d:%s", i, text)
+		vector[i] = float64(h.Sum64()%2001)/1000 - 1
+	}
+	return vector
+}
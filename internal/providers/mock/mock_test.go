@@ -0,0 +1,157 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+const chatBody = `{"model":"mock-chat","messages":[{"role":"system","content":"Be brief"},{"role":"user","content":"What is a qubit?"}]}`
+
+func newTestProvider(t *testing.T, config MockConfig) *MockProvider {
+	t.Helper()
+	p, err := NewMockProvider(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestInvokeChat(t *testing.T) {
+	p := newTestProvider(t, MockConfig{})
+	resp, err := p.Invoke(context.Background(), &providers.ProviderRequest{Path: "/chat/completions", Body: []byte(chatBody)})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	var chat translator.ChatCompletionResponse
+	if err := json.Unmarshal(resp.Body, &chat); err != nil {
+		t.Fatal(err)
+	}
+	if got := chat.Choices[0].Message.Content.Text(); got != ReplyPrefix+"What is a qubit?" {
+		t.Errorf("reply = %q", got)
+	}
+	// Tokens are counted in words: 6 of input, 7 of reply
+	if chat.Model != "mock-chat" || chat.Usage.PromptTokens != 6 || chat.Usage.CompletionTokens != 7 {
+		t.Errorf("model %q, usage %+v", chat.Model, chat.Usage)
+	}
+
+	p = newTestProvider(t, MockConfig{PromptTokens: 100, CompletionTokens: 20})
+	resp, err = p.Invoke(context.Background(), &providers.ProviderRequest{Path: "/chat/completions", Body: []byte(chatBody)})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+	if resp.Metadata.InputTokens != 100 || resp.Metadata.OutputTokens != 20 {
+		t.Errorf("configured usage = %+v", resp.Metadata)
+	}
+}
+
+func TestInvokeChatStream(t *testing.T) {
+	p := newTestProvider(t, MockConfig{})
+	events, err := p.InvokeChatStream(context.Background(), &providers.ChatRequest{
+		Request: &providers.ProviderRequest{Path: "/chat/completions", Body: []byte(chatBody)},
+	})
+	if err != nil {
+		t.Fatalf("InvokeChatStream: %v", err)
+	}
+	var text strings.Builder
+	var last providers.ChatEvent
+	for event := range events {
+		text.WriteString(event.Text)
+		last = event
+	}
+	if text.String() != ReplyPrefix+"What is a qubit?" || last.Type != providers.ChatEventDone {
+		t.Errorf("streamed %q ending with %+v", text.String(), last)
+	}
+
+	stream, err := p.InvokeStreaming(context.Background(), &providers.ProviderRequest{Path: "/chat/completions", Body: []byte(chatBody)})
+	if err != nil {
+		t.Fatalf("InvokeStreaming: %v", err)
+	}
+	data, _ := io.ReadAll(stream)
+	if !strings.Contains(string(data), `"content":"qubit?"`) || !strings.HasSuffix(string(data), "data: [DONE]\n\n") {
+		t.Errorf("SSE stream = %s", data)
+	}
+}
+
+// TestErrorInjection tests that injected errors are spread evenly
+func TestErrorInjection(t *testing.T) {
+	p := newTestProvider(t, MockConfig{ErrorRate: 0.5, ErrorStatus: 429, ErrorCode: providers.ErrCodeRateLimitExceeded})
+	var failed []int
+	for i := 1; i <= 6; i++ {
+		_, err := p.Invoke(context.Background(), &providers.ProviderRequest{Path: "/chat/completions", Body: []byte(chatBody)})
+		var providerErr *providers.ProviderError
+		if errors.As(err, &providerErr) {
+			if providerErr.StatusCode != 429 || providerErr.Code != providers.ErrCodeRateLimitExceeded {
+				t.Errorf("request %d: error = %+v", i, providerErr)
+			}
+			failed = append(failed, i)
+		}
+	}
+	if len(failed) != 3 || failed[0] != 2 || failed[2] != 6 {
+		t.Errorf("failed requests = %v, want every second one", failed)
+	}
+
+	if _, err := NewMockProvider(MockConfig{ErrorRate: 2}); err == nil {
+		t.Error("NewMockProvider accepted an error rate above 1")
+	}
+}
+
+func TestLatency(t *testing.T) {
+	p := newTestProvider(t, MockConfig{Latency: time.Minute})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := p.Invoke(ctx, &providers.ProviderRequest{Path: "/chat/completions", Body: []byte(chatBody)})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Invoke error = %v, want the context's", err)
+	}
+}
+
+func TestInvokeEmbeddings(t *testing.T) {
+	p := newTestProvider(t, MockConfig{Dimensions: 4})
+
+	resp, err := p.Invoke(context.Background(), &providers.ProviderRequest{Path: "/embeddings", Body: []byte(`{"model":"mock-embed","input":["a","b"]}`)})
+	if err != nil {
+		t.Fatalf("OpenAI format: %v", err)
+	}
+	var openai translator.EmbeddingsResponse
+	if err := json.Unmarshal(resp.Body, &openai); err != nil || len(openai.Data) != 2 || openai.Data[1].Index != 1 {
+		t.Errorf("OpenAI format: %s", resp.Body)
+	}
+
+	resp, err = p.Invoke(context.Background(), &providers.ProviderRequest{Path: "/embed", Body: []byte(`{"texts":["a","b"]}`)})
+	if err != nil {
+		t.Fatalf("Cohere format: %v", err)
+	}
+	var cohere struct {
+		Embeddings [][]float64 `json:"embeddings"`
+	}
+	if err := json.Unmarshal(resp.Body, &cohere); err != nil || len(cohere.Embeddings) != 2 || len(cohere.Embeddings[0]) != 4 {
+		t.Fatalf("Cohere format: %s", resp.Body)
+	}
+
+	resp, err = p.Invoke(context.Background(), &providers.ProviderRequest{Path: "/model/amazon.titan-embed-text-v2:0/invoke", Body: []byte(`{"inputText":"a"}`)})
+	if err != nil {
+		t.Fatalf("Titan format: %v", err)
+	}
+	var titan struct {
+		Embedding []float64 `json:"embedding"`
+	}
+	if err := json.Unmarshal(resp.Body, &titan); err != nil {
+		t.Fatalf("Titan format: %s", resp.Body)
+	}
+
+	// The same text always has the same embedding
+	for i, value := range titan.Embedding {
+		if value != cohere.Embeddings[0][i] || value < -1 || value > 1 {
+			t.Errorf("embedding of %q = %v, then %v", "a", titan.Embedding, cohere.Embeddings[0])
+			break
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// openaiFile is the subset of the OpenAI file object we use
+type openaiFile struct {
+	ID string `json:"id"`
+}
+
+// openaiBatch is the subset of the OpenAI batch object we use
+type openaiBatch struct {
+	ID           string `json:"id"`
+	Status       string `json:"status"`
+	InputFileID  string `json:"input_file_id"`
+	OutputFileID string `json:"output_file_id"`
+	ErrorFileID  string `json:"error_file_id"`
+	CompletedAt  int64  `json:"completed_at"`
+	FailedAt     int64  `json:"failed_at"`
+	ExpiresAt    int64  `json:"expires_at"`
+	Errors       *struct {
+		Data []struct {
+			Message string `json:"message"`
+		} `json:"data"`
+	} `json:"errors"`
+	RequestCounts struct {
+		Total     int `json:"total"`
+		Completed int `json:"completed"`
+		Failed    int `json:"failed"`
+	} `json:"request_counts"`
+}
+
+// SubmitBatch uploads the JSONL input as a batch file and creates an OpenAI batch
+func (p *OpenAIProvider) SubmitBatch(ctx context.Context, request *providers.BatchRequest) (*providers.BatchJob, error) {
+	fileID, err := p.uploadBatchFile(ctx, request.JobName+".jsonl", request.InputJSONL)
+	if err != nil {
+		return nil, err
+	}
+
+	completionWindow := request.CompletionWindow
+	if completionWindow == "" {
+		completionWindow = "24h"
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"input_file_id":     fileID,
+		"endpoint":          request.Endpoint,
+		"completion_window": completionWindow,
+		"metadata":          request.Metadata,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal batch request: %w", err)
+	}
+
+	resp, err := p.Invoke(ctx, &providers.ProviderRequest{
+		Method: "POST",
+		Path:   "/batches",
+		Body:   body,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBatch(resp.Body)
+}
+
+// GetBatch retrieves an OpenAI batch
+func (p *OpenAIProvider) GetBatch(ctx context.Context, providerBatchID string) (*providers.BatchJob, error) {
+	resp, err := p.Invoke(ctx, &providers.ProviderRequest{
+		Method: "GET",
+		Path:   "/batches/" + providerBatchID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return parseBatch(resp.Body)
+}
+
+// uploadBatchFile uploads JSONL content via the Files API with purpose=batch
+func (p *OpenAIProvider) uploadBatchFile(ctx context.Context, filename string, content []byte) (string, error) {
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	if err := writer.WriteField("purpose", "batch"); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	part, err := writer.CreateFormFile("file", filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return "", fmt.Errorf("failed to build upload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.baseURL+"/files", &buf)
+	if err != nil {
+		return "", fmt.Errorf("failed to create upload request: %w", err)
+	}
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", &providers.ProviderError{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    fmt.Sprintf("file upload failed: %v", err),
+			Provider:   "openai",
+		}
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read upload response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", &providers.ProviderError{
+			StatusCode: resp.StatusCode,
+			Message:    string(respBody),
+			Provider:   "openai",
+		}
+	}
+
+	var file openaiFile
+	if err := json.Unmarshal(respBody, &file); err != nil {
+		return "", fmt.Errorf("failed to parse upload response: %w", err)
+	}
+	return file.ID, nil
+}
+
+// parseBatch converts an OpenAI batch object to a BatchJob
+func parseBatch(body []byte) (*providers.BatchJob, error) {
+	var b openaiBatch
+	if err := json.Unmarshal(body, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse batch response: %w", err)
+	}
+
+	job := &providers.BatchJob{
+		ProviderBatchID: b.ID,
+		Status:          b.Status, // OpenAI statuses are already normalised
+		InputLocation:   b.InputFileID,
+		OutputLocation:  b.OutputFileID,
+		ErrorLocation:   b.ErrorFileID,
+		Total:           b.RequestCounts.Total,
+		Completed:       b.RequestCounts.Completed,
+		Failed:          b.RequestCounts.Failed,
+		CompletedAt:     unixOrZero(b.CompletedAt),
+		FailedAt:        unixOrZero(b.FailedAt),
+		ExpiresAt:       unixOrZero(b.ExpiresAt),
+	}
+	if b.Errors != nil && len(b.Errors.Data) > 0 {
+		job.Message = b.Errors.Data[0].Message
+	}
+	return job, nil
+}
+
+func unixOrZero(ts int64) time.Time {
+	if ts == 0 {
+		return time.Time{}
+	}
+	return time.Unix(ts, 0)
+}
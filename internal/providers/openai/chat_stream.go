@@ -0,0 +1,132 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package openai
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// streamChunk is the part of an OpenAI chat.completion.chunk read into events
+type streamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Role      string `json:"role"`
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int    `json:"index"`
+				ID       string `json:"id"`
+				Function struct {
+					Name      string `json:"name"`
+					Arguments string `json:"arguments"`
+				} `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason *string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+		TotalTokens      int `json:"total_tokens"`
+	} `json:"usage"`
+}
+
+// InvokeChatStream sends a streaming chat completion request and parses
+// the SSE response into events. Only the first choice is streamed.
+func (p *OpenAIProvider) InvokeChatStream(ctx context.Context, request *providers.ChatRequest) (<-chan providers.ChatEvent, error) {
+	resp, err := p.do(ctx, request.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make(chan providers.ChatEvent)
+	go func() {
+		defer close(events)
+		defer resp.Body.Close()
+		readChatStream(ctx, resp.Body, events)
+	}()
+	return events, nil
+}
+
+// readChatStream sends the events of an OpenAI SSE stream, ending with
+// ChatEventDone at [DONE] or ChatEventError
+func readChatStream(ctx context.Context, body io.Reader, events chan<- providers.ChatEvent) {
+	send := func(event providers.ChatEvent) bool {
+		return providers.SendChatEvent(ctx, events, event)
+	}
+	fail := func(err error) {
+		send(providers.ChatEvent{Type: providers.ChatEventError, Err: &providers.ProviderError{
+			Provider:   "openai",
+			StatusCode: http.StatusBadGateway,
+			Code:       providers.ErrCodeInternalError,
+			Message:    "Failed to read stream",
+			Err:        err,
+		}})
+	}
+
+	finishReason := ""
+	reader := bufio.NewReader(body)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if data, ok := bytes.CutPrefix(bytes.TrimSpace(line), []byte("data:")); ok {
+			data = bytes.TrimSpace(data)
+			if string(data) == "[DONE]" {
+				send(providers.ChatEvent{Type: providers.ChatEventDone, FinishReason: finishReason})
+				return
+			}
+
+			var chunk streamChunk
+			if err := json.Unmarshal(data, &chunk); err != nil {
+				fail(fmt.Errorf("invalid chunk: %w", err))
+				return
+			}
+			if len(chunk.Choices) > 0 {
+				choice := chunk.Choices[0]
+				if choice.Delta.Role != "" || choice.Delta.Content != "" {
+					if !send(providers.ChatEvent{Type: providers.ChatEventDelta, Role: choice.Delta.Role, Text: choice.Delta.Content}) {
+						return
+					}
+				}
+				for _, call := range choice.Delta.ToolCalls {
+					if !send(providers.ChatEvent{Type: providers.ChatEventToolCallDelta, ToolCall: &providers.ToolCallDelta{
+						Index:     call.Index,
+						ID:        call.ID,
+						Name:      call.Function.Name,
+						Arguments: call.Function.Arguments,
+					}}) {
+						return
+					}
+				}
+				if choice.FinishReason != nil {
+					finishReason = *choice.FinishReason
+				}
+			}
+			if chunk.Usage != nil {
+				if !send(providers.ChatEvent{Type: providers.ChatEventUsage, Usage: &providers.ChatUsage{
+					InputTokens:  chunk.Usage.PromptTokens,
+					OutputTokens: chunk.Usage.CompletionTokens,
+					TotalTokens:  chunk.Usage.TotalTokens,
+				}}) {
+					return
+				}
+			}
+		}
+
+		if readErr == io.EOF {
+			fail(io.ErrUnexpectedEOF)
+			return
+		}
+		if readErr != nil {
+			fail(readErr)
+			return
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package openai
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+func TestInvokeChatStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range []string{
+			`{"choices":[{"index":0,"delta":{"role":"assistant","content":""},"finish_reason":null}]}`,
+			`{"choices":[{"index":0,"delta":{"content":"Hi"},"finish_reason":null}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"weather","arguments":""}}]},"finish_reason":null}]}`,
+			`{"choices":[{"index":0,"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{}"}}]},"finish_reason":null}]}`,
+			`{"choices":[{"index":0,"delta":{},"finish_reason":"tool_calls"}]}`,
+			`{"choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2,"total_tokens":5}}`,
+			`[DONE]`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", chunk)
+		}
+	}))
+	defer server.Close()
+
+	p, err := NewOpenAIProvider(OpenAIConfig{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := p.InvokeChatStream(context.Background(), &providers.ChatRequest{
+		Request: &providers.ProviderRequest{Method: http.MethodPost, Path: "/chat/completions", Body: []byte(`{}`)},
+	})
+	if err != nil {
+		t.Fatalf("InvokeChatStream: %v", err)
+	}
+
+	var got []providers.ChatEvent
+	for event := range events {
+		got = append(got, event)
+	}
+	if len(got) != 6 {
+		t.Fatalf("got %d events: %+v", len(got), got)
+	}
+	if got[0].Type != providers.ChatEventDelta || got[0].Role != "assistant" || got[1].Text != "Hi" {
+		t.Errorf("deltas = %+v, %+v", got[0], got[1])
+	}
+	if call := got[2].ToolCall; got[2].Type != providers.ChatEventToolCallDelta || call.ID != "call_1" || call.Name != "weather" {
+		t.Errorf("tool call start = %+v", got[2])
+	}
+	if call := got[3].ToolCall; call.Index != 0 || call.Arguments != "{}" {
+		t.Errorf("tool call arguments = %+v", got[3])
+	}
+	if got[4].Type != providers.ChatEventUsage || got[4].Usage.TotalTokens != 5 {
+		t.Errorf("usage = %+v", got[4])
+	}
+	if got[5].Type != providers.ChatEventDone || got[5].FinishReason != "tool_calls" {
+		t.Errorf("done = %+v", got[5])
+	}
+}
+
+func TestInvokeChatStreamTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `data: {"choices":[{"index":0,"delta":{"content":"Hi"}}]}`+"\n\n")
+	}))
+	defer server.Close()
+
+	p, _ := NewOpenAIProvider(OpenAIConfig{APIKey: "key", BaseURL: server.URL})
+	events, err := p.InvokeChatStream(context.Background(), &providers.ChatRequest{
+		Request: &providers.ProviderRequest{Method: http.MethodPost, Path: "/chat/completions"},
+	})
+	if err != nil {
+		t.Fatalf("InvokeChatStream: %v", err)
+	}
+
+	var last providers.ChatEvent
+	for event := range events {
+		last = event
+	}
+	if last.Type != providers.ChatEventError || last.Err == nil {
+		t.Errorf("last event = %+v, want an error for the missing [DONE]", last)
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// CountTokens counts prompt tokens by sending the request with max_tokens=1
+// and reading usage.prompt_tokens. OpenAI has no dedicated counting endpoint,
+// so this costs one output token.
+func (p *OpenAIProvider) CountTokens(ctx context.Context, request *providers.ProviderRequest) (int, error) {
+	var body map[string]interface{}
+	if err := json.Unmarshal(request.Body, &body); err != nil {
+		return 0, fmt.Errorf("failed to parse request: %w", err)
+	}
+	body["max_tokens"] = 1
+	body["stream"] = false
+	delete(body, "stream_options")
+	delete(body, "max_completion_tokens")
+	delete(body, "n")
+
+	countBody, err := json.Marshal(body)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	countReq := *request
+	countReq.Method = "POST"
+	countReq.Path = "/chat/completions"
+	countReq.Body = countBody
+
+	resp, err := p.Invoke(ctx, &countReq)
+	if err != nil {
+		return 0, err
+	}
+
+	var result struct {
+		Usage *struct {
+			PromptTokens int `json:"prompt_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		return 0, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Usage == nil {
+		return 0, fmt.Errorf("response did not include usage")
+	}
+	return result.Usage.PromptTokens, nil
+}
@@ -4,13 +4,11 @@
 package openai
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"time"
 
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
 )
@@ -24,8 +22,9 @@ type OpenAIProvider struct {
 
 // Config for OpenAI provider
 type OpenAIConfig struct {
-	APIKey  string `yaml:"api_key"`
-	BaseURL string `yaml:"base_url"` // Optional, defaults to https://api.openai.com/v1
+	APIKey   string                 `yaml:"api_key"`
+	BaseURL  string                 `yaml:"base_url"` // Optional, defaults to https://api.openai.com/v1
+	Timeouts providers.HTTPTimeouts // Optional, defaults to 10s connect and 120s request
 }
 
 // NewOpenAIProvider creates a new OpenAI provider
@@ -40,11 +39,9 @@ func NewOpenAIProvider(config OpenAIConfig) (*OpenAIProvider, error) {
 	}
 
 	return &OpenAIProvider{
-		apiKey:  config.APIKey,
-		baseURL: baseURL,
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		apiKey:     config.APIKey,
+		baseURL:    baseURL,
+		httpClient: providers.NewHTTPClient(config.Timeouts),
 	}, nil
 }
 
@@ -53,6 +50,34 @@ func (p *OpenAIProvider) Name() string {
 	return "openai"
 }
 
+// Capabilities reports what OpenAI's chat models support
+func (p *OpenAIProvider) Capabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Streaming:         true,
+		Vision:            true,
+		Tools:             true,
+		ParallelToolCalls: true,
+		MaxContextTokens:  128000,
+		ServiceTiers:      []string{"auto", "default", "flex", "priority"},
+	}
+}
+
+// BaseURL returns the endpoint requests are sent to
+func (p *OpenAIProvider) BaseURL() string {
+	return p.baseURL
+}
+
+// SignsPayload reports false: OpenAI uses a bearer token
+func (p *OpenAIProvider) SignsPayload() bool {
+	return false
+}
+
+// Authorize adds the API key to a forwarded request
+func (p *OpenAIProvider) Authorize(req *http.Request, payloadHash string) error {
+	req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	return nil
+}
+
 // HealthCheck checks if the provider is accessible
 func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
 	req, err := http.NewRequestWithContext(ctx, "GET", p.baseURL+"/models", nil)
@@ -78,36 +103,9 @@ func (p *OpenAIProvider) HealthCheck(ctx context.Context) error {
 
 // Invoke sends a request to OpenAI
 func (p *OpenAIProvider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
-	// Build full URL
-	url := p.baseURL + request.Path
-
-	// Create HTTP request
-	httpReq, err := http.NewRequestWithContext(ctx, request.Method, url, bytes.NewReader(request.Body))
-	if err != nil {
-		return nil, &providers.ProviderError{
-			StatusCode: http.StatusInternalServerError,
-			Message:    fmt.Sprintf("failed to create request: %v", err),
-			Provider:   "openai",
-		}
-	}
-
-	// Set headers
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
-
-	// Add custom headers from request
-	for k, v := range request.Headers {
-		httpReq.Header.Set(k, v)
-	}
-
-	// Send request
-	resp, err := p.httpClient.Do(httpReq)
+	resp, err := p.do(ctx, request)
 	if err != nil {
-		return nil, &providers.ProviderError{
-			StatusCode: http.StatusServiceUnavailable,
-			Message:    fmt.Sprintf("request failed: %v", err),
-			Provider:   "openai",
-		}
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -121,22 +119,8 @@ func (p *OpenAIProvider) Invoke(ctx context.Context, request *providers.Provider
 		}
 	}
 
-	// Check for errors
-	if resp.StatusCode != http.StatusOK {
-		return nil, &providers.ProviderError{
-			StatusCode: resp.StatusCode,
-			Message:    string(body),
-			Provider:   "openai",
-		}
-	}
-
 	// Build provider response
-	headers := make(map[string]string)
-	for k, v := range resp.Header {
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
-	}
+	headers := resp.Header.Clone()
 
 	return &providers.ProviderResponse{
 		StatusCode: resp.StatusCode,
@@ -147,9 +131,34 @@ func (p *OpenAIProvider) Invoke(ctx context.Context, request *providers.Provider
 
 // InvokeStreaming sends a streaming request to OpenAI
 func (p *OpenAIProvider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return providers.NewHeaderStream(resp.Body, resp.Header.Clone()), nil
+}
+
+// InvokeStream sends a request to OpenAI without buffering either body
+func (p *OpenAIProvider) InvokeStream(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderStreamResponse, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &providers.ProviderStreamResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       resp.Body,
+	}, nil
+}
+
+// do sends a request to OpenAI. Error statuses are returned as a
+// ProviderError; otherwise the caller must close the response body.
+func (p *OpenAIProvider) do(ctx context.Context, request *providers.ProviderRequest) (*http.Response, error) {
+	// Build full URL
 	url := p.baseURL + request.Path
 
-	httpReq, err := http.NewRequestWithContext(ctx, request.Method, url, bytes.NewReader(request.Body))
+	// Create HTTP request
+	httpReq, err := providers.NewBodyRequest(ctx, request.Method, url, request)
 	if err != nil {
 		return nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
@@ -158,13 +167,14 @@ func (p *OpenAIProvider) InvokeStreaming(ctx context.Context, request *providers
 		}
 	}
 
+	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.apiKey)
+	providers.SetCredentials(httpReq, request, "Authorization", "Bearer "+p.apiKey)
 
-	for k, v := range request.Headers {
-		httpReq.Header.Set(k, v)
-	}
+	// Add custom headers from request
+	providers.ApplyHeaders(httpReq.Header, request.Headers)
 
+	// Send request
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
 		return nil, &providers.ProviderError{
@@ -174,6 +184,7 @@ func (p *OpenAIProvider) InvokeStreaming(ctx context.Context, request *providers
 		}
 	}
 
+	// Check for errors
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 		body, _ := io.ReadAll(resp.Body)
@@ -181,10 +192,11 @@ func (p *OpenAIProvider) InvokeStreaming(ctx context.Context, request *providers
 			StatusCode: resp.StatusCode,
 			Message:    string(body),
 			Provider:   "openai",
+			Headers:    resp.Header.Clone(),
 		}
 	}
 
-	return resp.Body, nil
+	return resp, nil
 }
 
 // ListModels lists available OpenAI models
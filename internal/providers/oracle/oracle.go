@@ -29,6 +29,7 @@ type OracleConfig struct {
 	Endpoint      string `yaml:"endpoint"`       // OCI endpoint URL
 	AuthToken     string `yaml:"auth_token"`     // Auth token
 	CompartmentID string `yaml:"compartment_id"` // OCI compartment ID
+	Timeouts      providers.HTTPTimeouts           // Optional, defaults to 10s connect and 120s request
 }
 
 // Oracle Generative AI request/response types
@@ -98,9 +99,7 @@ func NewOracleProvider(config OracleConfig) (*OracleProvider, error) {
 		endpoint:      config.Endpoint,
 		authToken:     config.AuthToken,
 		compartmentID: config.CompartmentID,
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		httpClient: providers.NewHTTPClient(config.Timeouts),
 	}, nil
 }
 
@@ -109,6 +108,15 @@ func (p *OracleProvider) Name() string {
 	return "oracle"
 }
 
+// Capabilities reports what OCI Generative AI models support
+func (p *OracleProvider) Capabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Streaming:        true,
+		Tools:            true,
+		MaxContextTokens: 4096,
+	}
+}
+
 // HealthCheck checks if the provider is accessible
 func (p *OracleProvider) HealthCheck(ctx context.Context) error {
 	// Could check API availability, but skip for now
@@ -153,7 +161,7 @@ func (p *OracleProvider) Invoke(ctx context.Context, request *providers.Provider
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+p.authToken)
+	providers.SetCredentials(httpReq, request, "Authorization", "Bearer "+p.authToken)
 
 	// Send request
 	resp, err := p.httpClient.Do(httpReq)
@@ -209,12 +217,7 @@ func (p *OracleProvider) Invoke(ctx context.Context, request *providers.Provider
 	}
 
 	// Build provider response
-	headers := make(map[string]string)
-	for k, v := range resp.Header {
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
-	}
+	headers := resp.Header.Clone()
 
 	return &providers.ProviderResponse{
 		StatusCode: resp.StatusCode,
@@ -299,7 +302,7 @@ func translateOpenAIToOracle(req *translator.ChatCompletionRequest, compartmentI
 			role = "SYSTEM"
 		}
 
-		text := extractTextContent(msg.Content)
+		text := msg.Content.Text()
 		oracleReq.ChatRequest.Messages = append(oracleReq.ChatRequest.Messages, OracleMessage{
 			Role: role,
 			Content: []OracleContent{
@@ -347,7 +350,7 @@ func translateOracleToOpenAI(resp *OracleResponse, model string) *translator.Cha
 				Index: 0,
 				Message: translator.ChatMessage{
 					Role:    "assistant",
-					Content: content,
+					Content: translator.TextContent(content),
 				},
 				FinishReason: finishReason,
 			},
@@ -359,25 +362,3 @@ func translateOracleToOpenAI(resp *OracleResponse, model string) *translator.Cha
 		},
 	}
 }
-
-// extractTextContent extracts text from content interface
-func extractTextContent(content interface{}) string {
-	switch c := content.(type) {
-	case string:
-		return c
-	case []interface{}:
-		var text string
-		for _, part := range c {
-			if partMap, ok := part.(map[string]interface{}); ok {
-				if partType, ok := partMap["type"].(string); ok && partType == "text" {
-					if textVal, ok := partMap["text"].(string); ok {
-						text += textVal
-					}
-				}
-			}
-		}
-		return text
-	default:
-		return fmt.Sprintf("%v", content)
-	}
-}
@@ -0,0 +1,22 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import "net/http"
+
+// Passthrough is implemented by providers whose native API can be forwarded
+// without Invoke. The transparent handler proxies their requests with
+// httputil.ReverseProxy, streaming both bodies.
+type Passthrough interface {
+	// BaseURL returns the endpoint requests are forwarded to
+	BaseURL() string
+
+	// SignsPayload reports whether Authorize needs the body hash (SigV4)
+	SignsPayload() bool
+
+	// Authorize adds the provider's credentials to an outbound request.
+	// payloadHash is the hex SHA-256 of the body, or UNSIGNED-PAYLOAD, for
+	// providers that sign the payload; others ignore it.
+	Authorize(req *http.Request, payloadHash string) error
+}
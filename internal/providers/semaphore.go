@@ -0,0 +1,148 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// ErrQueueTimeout is returned by Acquire when no slot frees up within the queue timeout
+var ErrQueueTimeout = errors.New("timed out waiting for a provider concurrency slot")
+
+// ProviderSemaphore bounds the number of in-flight requests to a provider
+// instance. Requests beyond MaxConcurrency wait up to QueueTimeout for a
+// slot.
+type ProviderSemaphore struct {
+	provider     string // the instance's provider type
+	instance     string
+	slots        chan struct{}
+	queueTimeout time.Duration
+	mode         ratelimit.Mode
+
+	waiting    atomic.Int64
+	queueDepth prometheus.Gauge
+}
+
+// NewProviderSemaphore creates a semaphore allowing maxConcurrency in-flight
+// requests to an instance of a provider type. In ratelimit.ModeReportOnly,
+// requests that would wait or be rejected are counted in
+// ai_limit_would_block_total and let through.
+func NewProviderSemaphore(provider, instance string, maxConcurrency int, queueTimeout time.Duration, mode ratelimit.Mode) *ProviderSemaphore {
+	return &ProviderSemaphore{
+		provider:     provider,
+		instance:     instance,
+		slots:        make(chan struct{}, maxConcurrency),
+		queueTimeout: queueTimeout,
+		mode:         mode,
+		queueDepth:   metrics.QueueDepth.WithLabelValues(provider, instance),
+	}
+}
+
+// Acquire takes a concurrency slot, waiting up to the queue timeout. The
+// returned release function must be called when the request completes.
+func (s *ProviderSemaphore) Acquire(ctx context.Context) (func(), error) {
+	select {
+	case s.slots <- struct{}{}:
+		return s.release, nil
+	default:
+	}
+
+	if s.mode == ratelimit.ModeReportOnly {
+		ratelimit.RecordWouldBlock("concurrency", "max_concurrency")
+		return func() {}, nil
+	}
+
+	s.waiting.Add(1)
+	s.queueDepth.Inc()
+	defer func() {
+		s.waiting.Add(-1)
+		s.queueDepth.Dec()
+	}()
+
+	timer := time.NewTimer(s.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case s.slots <- struct{}{}:
+		return s.release, nil
+	case <-timer.C:
+		return nil, ErrQueueTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (s *ProviderSemaphore) release() {
+	<-s.slots
+}
+
+// QueueTimeout returns how long requests wait for a slot
+func (s *ProviderSemaphore) QueueTimeout() time.Duration {
+	return s.queueTimeout
+}
+
+// SemaphoreStatus reports the state of one provider instance semaphore
+type SemaphoreStatus struct {
+	Provider       string `json:"provider"`
+	Instance       string `json:"instance"`
+	MaxConcurrency int    `json:"max_concurrency"`
+	InFlight       int    `json:"in_flight"`
+	Waiting        int64  `json:"waiting"`
+}
+
+// Status returns the current semaphore state
+func (s *ProviderSemaphore) Status() SemaphoreStatus {
+	return SemaphoreStatus{
+		Provider:       s.provider,
+		Instance:       s.instance,
+		MaxConcurrency: cap(s.slots),
+		InFlight:       len(s.slots),
+		Waiting:        s.waiting.Load(),
+	}
+}
+
+// SemaphoreRegistry holds provider semaphores by instance name. It is built
+// at startup and read-only afterwards. A nil registry has no limits.
+type SemaphoreRegistry struct {
+	semaphores map[string]*ProviderSemaphore
+}
+
+// NewSemaphoreRegistry creates an empty semaphore registry
+func NewSemaphoreRegistry() *SemaphoreRegistry {
+	return &SemaphoreRegistry{semaphores: make(map[string]*ProviderSemaphore)}
+}
+
+// Register adds a semaphore under name
+func (r *SemaphoreRegistry) Register(name string, semaphore *ProviderSemaphore) {
+	r.semaphores[name] = semaphore
+}
+
+// Get returns the semaphore for name, or nil if it has no concurrency limit
+func (r *SemaphoreRegistry) Get(name string) *ProviderSemaphore {
+	if r == nil {
+		return nil
+	}
+	return r.semaphores[name]
+}
+
+// Statuses returns the state of all semaphores sorted by instance
+func (r *SemaphoreRegistry) Statuses() []SemaphoreStatus {
+	if r == nil {
+		return nil
+	}
+	statuses := make([]SemaphoreStatus, 0, len(r.semaphores))
+	for _, semaphore := range r.semaphores {
+		statuses = append(statuses, semaphore.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Instance < statuses[j].Instance })
+	return statuses
+}
@@ -0,0 +1,88 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
+)
+
+func TestProviderSemaphoreQueueTimeout(t *testing.T) {
+	s := NewProviderSemaphore("test", "test_timeout", 1, 10*time.Millisecond, ratelimit.ModeEnforce)
+
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+	defer release()
+
+	if _, err := s.Acquire(context.Background()); !errors.Is(err, ErrQueueTimeout) {
+		t.Fatalf("Acquire at capacity: error = %v, want ErrQueueTimeout", err)
+	}
+	if status := s.Status(); status.InFlight != 1 || status.Waiting != 0 {
+		t.Errorf("Status() = %+v, want 1 in flight and none waiting", status)
+	}
+	if status := s.Status(); status.Provider != "test" || status.Instance != "test_timeout" {
+		t.Errorf("Status() = %+v, want provider test and instance test_timeout", status)
+	}
+}
+
+func TestProviderSemaphoreQueued(t *testing.T) {
+	s := NewProviderSemaphore("test", "test_queued", 1, time.Second, ratelimit.ModeEnforce)
+
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		queuedRelease, err := s.Acquire(context.Background())
+		if err == nil {
+			queuedRelease()
+		}
+		done <- err
+	}()
+
+	// Wait until the second request is queued, then free the slot
+	for s.Status().Waiting == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	release()
+
+	if err := <-done; err != nil {
+		t.Fatalf("queued Acquire: %v", err)
+	}
+	if status := s.Status(); status.InFlight != 0 || status.Waiting != 0 {
+		t.Errorf("Status() = %+v, want idle", status)
+	}
+}
+
+func TestProviderSemaphoreReportOnly(t *testing.T) {
+	s := NewProviderSemaphore("test", "test_report_only", 1, time.Millisecond, ratelimit.ModeReportOnly)
+
+	release, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("first Acquire: %v", err)
+	}
+	defer release()
+
+	extra, err := s.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire over capacity in report-only mode: %v", err)
+	}
+	extra()
+
+	if status := s.Status(); status.InFlight != 1 {
+		t.Errorf("InFlight = %d, want 1 (report-only requests do not take slots)", status.InFlight)
+	}
+}
+
+func TestSemaphoreRegistryNil(t *testing.T) {
+	var r *SemaphoreRegistry
+	if r.Get("any") != nil || r.Statuses() != nil {
+		t.Error("nil registry should have no semaphores")
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package sigv4
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/tosharewith/llmproxy_auth/internal/auth"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// SigV4Provider forwards requests to any AWS service endpoint, signing them
+// with SigV4 for the configured service and region using SDK credentials
+// (IRSA, instance profile, environment). Requests and responses are passed
+// through unchanged, so it is only used for transparent mode.
+type SigV4Provider struct {
+	service    string
+	region     string
+	baseURL    string
+	signer     *auth.AWSSigner
+	httpClient *http.Client
+}
+
+// SigV4Config configures a generic SigV4 provider
+type SigV4Config struct {
+	// Service is the SigV4 signing name (s3, sagemaker, bedrock-runtime, ...)
+	Service string
+
+	// Region to sign for
+	Region string
+
+	// Endpoint overrides the default https://{service}.{region}.amazonaws.com
+	// (e.g. a VPC endpoint)
+	Endpoint string
+
+	// Timeouts bound requests (default: 10s connect, 120s request)
+	Timeouts providers.HTTPTimeouts
+}
+
+// NewSigV4Provider creates a new generic SigV4 provider
+func NewSigV4Provider(config SigV4Config) (*SigV4Provider, error) {
+	if config.Service == "" {
+		return nil, fmt.Errorf("SigV4 service is required")
+	}
+	if config.Region == "" {
+		return nil, fmt.Errorf("SigV4 region is required")
+	}
+
+	signer, err := auth.NewAWSSigner(config.Region, config.Service)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS signer: %w", err)
+	}
+
+	baseURL := strings.TrimSuffix(config.Endpoint, "/")
+	if baseURL == "" {
+		baseURL = fmt.Sprintf("https://%s.%s.amazonaws.com", config.Service, config.Region)
+	}
+
+	return &SigV4Provider{
+		service:    config.Service,
+		region:     config.Region,
+		baseURL:    baseURL,
+		signer:     signer,
+		httpClient: providers.NewHTTPClient(config.Timeouts),
+	}, nil
+}
+
+// Name returns the provider name
+func (p *SigV4Provider) Name() string {
+	return "aws_sigv4"
+}
+
+// BaseURL returns the endpoint requests are sent to
+func (p *SigV4Provider) BaseURL() string {
+	return p.baseURL
+}
+
+// SignsPayload reports true: SigV4 signatures cover the body hash
+func (p *SigV4Provider) SignsPayload() bool {
+	return true
+}
+
+// Authorize signs a forwarded request for the configured service
+func (p *SigV4Provider) Authorize(req *http.Request, payloadHash string) error {
+	return p.signer.SignRequestWithPayloadHash(req, payloadHash)
+}
+
+// HealthCheck is a no-op: there is no service-independent health endpoint
+func (p *SigV4Provider) HealthCheck(ctx context.Context) error {
+	return nil
+}
+
+// Invoke signs and forwards a request, returning the response unchanged,
+// including error statuses
+func (p *SigV4Provider) Invoke(ctx context.Context, request *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusBadGateway,
+			Code:       providers.ErrCodeInternalError,
+			Message:    "Failed to read response",
+			Err:        err,
+		}
+	}
+
+	return &providers.ProviderResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       body,
+	}, nil
+}
+
+// InvokeStreaming signs and forwards a request, returning the response body
+func (p *SigV4Provider) InvokeStreaming(ctx context.Context, request *providers.ProviderRequest) (io.ReadCloser, error) {
+	resp, err := p.do(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: resp.StatusCode,
+			Message:    string(body),
+		}
+	}
+	return resp.Body, nil
+}
+
+// ListModels returns no models: the target service is not a model provider
+func (p *SigV4Provider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	return nil, nil
+}
+
+// GetModelInfo is not supported
+func (p *SigV4Provider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return nil, fmt.Errorf("model info not available for %s", p.service)
+}
+
+// do builds, signs and sends a request
+func (p *SigV4Provider) do(ctx context.Context, request *providers.ProviderRequest) (*http.Response, error) {
+	req, err := p.newRequest(ctx, request)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusInternalServerError,
+			Code:       providers.ErrCodeInternalError,
+			Message:    "Failed to create request",
+			Err:        err,
+		}
+	}
+
+	if err := p.signer.SignRequest(req, request.Body); err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusInternalServerError,
+			Code:       providers.ErrCodeAuthenticationFail,
+			Message:    "Failed to sign request",
+			Err:        err,
+		}
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			Provider:   p.Name(),
+			StatusCode: http.StatusBadGateway,
+			Code:       providers.ErrCodeServiceUnavailable,
+			Message:    "Request failed",
+			Err:        err,
+		}
+	}
+	return resp, nil
+}
+
+// newRequest builds the outbound request. Client headers are copied as-is
+// so the signature covers exactly what is sent.
+func (p *SigV4Provider) newRequest(ctx context.Context, request *providers.ProviderRequest) (*http.Request, error) {
+	var body io.Reader
+	if len(request.Body) > 0 {
+		body = bytes.NewReader(request.Body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, request.Method, p.baseURL+request.Path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	providers.ApplyHeaders(req.Header, request.Headers)
+
+	if len(request.QueryParams) > 0 {
+		q := req.URL.Query()
+		for key, value := range request.QueryParams {
+			q.Set(key, value)
+		}
+		req.URL.RawQuery = q.Encode()
+	}
+
+	return req, nil
+}
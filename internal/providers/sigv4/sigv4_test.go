@@ -0,0 +1,81 @@
+package sigv4
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+func TestSigV4ProviderSignsForService(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIDEXAMPLE")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "secret")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_CONFIG_FILE", "/nonexistent")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/nonexistent")
+
+	var got *http.Request
+	var gotBody string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		body, _ := io.ReadAll(r.Body)
+		gotBody = string(body)
+		w.Header().Set("ETag", `"abc"`)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("<Error><Code>NoSuchKey</Code></Error>"))
+	}))
+	defer upstream.Close()
+
+	p, err := NewSigV4Provider(SigV4Config{Service: "s3", Region: "eu-west-1", Endpoint: upstream.URL + "/"})
+	if err != nil {
+		t.Fatalf("NewSigV4Provider: %v", err)
+	}
+
+	resp, err := p.Invoke(context.Background(), &providers.ProviderRequest{
+		Method:      http.MethodPut,
+		Path:        "/bucket/key.txt",
+		Headers:     http.Header{"Content-Type": {"text/plain"}},
+		Body:        []byte("hello"),
+		QueryParams: map[string]string{"versionId": "1"},
+	})
+	if err != nil {
+		t.Fatalf("Invoke: %v", err)
+	}
+
+	// Error statuses are passed through, not converted
+	if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(resp.Body), "NoSuchKey") {
+		t.Errorf("response %d %q, want upstream 404", resp.StatusCode, resp.Body)
+	}
+	if resp.Headers.Get("Etag") != `"abc"` {
+		t.Errorf("response headers %v, want ETag passed through", resp.Headers)
+	}
+
+	if got.Method != http.MethodPut || got.URL.Path != "/bucket/key.txt" || got.URL.Query().Get("versionId") != "1" || gotBody != "hello" {
+		t.Errorf("upstream got %s %s body %q", got.Method, got.URL, gotBody)
+	}
+	authz := got.Header.Get("Authorization")
+	if !strings.HasPrefix(authz, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") || !strings.Contains(authz, "/eu-west-1/s3/aws4_request") {
+		t.Errorf("Authorization = %q, want SigV4 for eu-west-1/s3", authz)
+	}
+	if got.Header.Get("X-Amz-Content-Sha256") == "" {
+		t.Error("missing X-Amz-Content-Sha256 for s3")
+	}
+}
+
+func TestNewSigV4ProviderDefaultEndpoint(t *testing.T) {
+	p, err := NewSigV4Provider(SigV4Config{Service: "sagemaker", Region: "us-west-2"})
+	if err != nil {
+		t.Fatalf("NewSigV4Provider: %v", err)
+	}
+	if p.BaseURL() != "https://sagemaker.us-west-2.amazonaws.com" {
+		t.Errorf("BaseURL = %q", p.BaseURL())
+	}
+
+	if _, err := NewSigV4Provider(SigV4Config{Region: "us-west-2"}); err == nil {
+		t.Error("NewSigV4Provider without service succeeded")
+	}
+}
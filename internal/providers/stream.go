@@ -0,0 +1,100 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+)
+
+// StreamingInvoker is implemented by providers that can forward a request
+// and its response without holding either body in memory
+type StreamingInvoker interface {
+	// InvokeStream sends request, reading the body from BodyReader when set,
+	// and returns the response with its body unread. Error statuses are
+	// reported as by Invoke. The caller must close the response body.
+	InvokeStream(ctx context.Context, request *ProviderRequest) (*ProviderStreamResponse, error)
+}
+
+// ProviderStreamResponse is a provider response whose body is read as it
+// arrives from the upstream
+type ProviderStreamResponse struct {
+	// HTTP status code
+	StatusCode int
+
+	// Response headers
+	Headers http.Header
+
+	// Response body; the caller must close it
+	Body io.ReadCloser
+}
+
+// InvokeStream sends request with the provider's InvokeStream when it
+// implements StreamingInvoker. Other providers get a buffering adapter:
+// BodyReader is read into Body, the request is sent with Invoke and the
+// buffered response body is returned as a reader.
+func InvokeStream(ctx context.Context, provider Provider, request *ProviderRequest) (*ProviderStreamResponse, error) {
+	if streaming, ok := provider.(StreamingInvoker); ok {
+		return streaming.InvokeStream(ctx, request)
+	}
+
+	if err := request.ReadBody(); err != nil {
+		return nil, &ProviderError{
+			Provider:   provider.Name(),
+			StatusCode: http.StatusBadRequest,
+			Code:       ErrCodeInvalidRequest,
+			Message:    "Failed to read request body",
+			Err:        err,
+		}
+	}
+	resp, err := provider.Invoke(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+	return &ProviderStreamResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Headers,
+		Body:       io.NopCloser(bytes.NewReader(resp.Body)),
+	}, nil
+}
+
+// BodyStream returns the body to send: BodyReader when set, otherwise Body
+func (r *ProviderRequest) BodyStream() io.Reader {
+	if r.BodyReader != nil {
+		return r.BodyReader
+	}
+	return bytes.NewReader(r.Body)
+}
+
+// ReadBody buffers BodyReader into Body, for providers that need the whole
+// body to translate or sign it. BodyReader is cleared so that Body is used
+// from then on.
+func (r *ProviderRequest) ReadBody() error {
+	if r.BodyReader == nil {
+		return nil
+	}
+	body, err := io.ReadAll(r.BodyReader)
+	if err != nil {
+		return err
+	}
+	r.Body = body
+	r.BodyReader = nil
+	r.ContentLength = 0
+	return nil
+}
+
+// NewBodyRequest creates an outbound HTTP request carrying the body of
+// request, streaming BodyReader when it is set
+func NewBodyRequest(ctx context.Context, method, url string, request *ProviderRequest) (*http.Request, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, method, url, request.BodyStream())
+	if err != nil {
+		return nil, err
+	}
+	if request.BodyReader != nil && request.ContentLength > 0 {
+		httpReq.ContentLength = request.ContentLength
+	}
+	return httpReq, nil
+}
@@ -0,0 +1,104 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// bufferedProvider is a Provider without StreamingInvoker that echoes the
+// request body it was given
+type bufferedProvider struct {
+	got []byte
+}
+
+func (p *bufferedProvider) Name() string                          { return "buffered" }
+func (p *bufferedProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (p *bufferedProvider) Invoke(ctx context.Context, req *ProviderRequest) (*ProviderResponse, error) {
+	p.got = req.Body
+	return &ProviderResponse{
+		StatusCode: http.StatusOK,
+		Headers:    http.Header{"Content-Type": {"text/plain"}},
+		Body:       req.Body,
+	}, nil
+}
+
+func (p *bufferedProvider) InvokeStreaming(ctx context.Context, req *ProviderRequest) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *bufferedProvider) ListModels(ctx context.Context) ([]Model, error) {
+	return nil, nil
+}
+
+func (p *bufferedProvider) GetModelInfo(ctx context.Context, modelID string) (*Model, error) {
+	return nil, errors.New("not found")
+}
+
+func TestBodyStreamPrecedence(t *testing.T) {
+	req := &ProviderRequest{Body: []byte("bytes"), BodyReader: strings.NewReader("reader")}
+	if got, _ := io.ReadAll(req.BodyStream()); string(got) != "reader" {
+		t.Errorf("BodyStream = %q, want BodyReader to take precedence", got)
+	}
+
+	req = &ProviderRequest{Body: []byte("bytes")}
+	if got, _ := io.ReadAll(req.BodyStream()); string(got) != "bytes" {
+		t.Errorf("BodyStream = %q, want Body", got)
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	req := &ProviderRequest{Body: []byte("stale"), BodyReader: strings.NewReader("streamed"), ContentLength: 8}
+	if err := req.ReadBody(); err != nil {
+		t.Fatalf("ReadBody: %v", err)
+	}
+	if string(req.Body) != "streamed" || req.BodyReader != nil || req.ContentLength != 0 {
+		t.Errorf("after ReadBody: Body %q, BodyReader %v, ContentLength %d", req.Body, req.BodyReader, req.ContentLength)
+	}
+}
+
+// TestInvokeStreamBuffered tests the adapter for providers without
+// StreamingInvoker: BodyReader is buffered into Body and the response body
+// is returned as a reader
+func TestInvokeStreamBuffered(t *testing.T) {
+	provider := &bufferedProvider{}
+	req := &ProviderRequest{Method: http.MethodPost, BodyReader: bytes.NewBufferString("payload")}
+
+	resp, err := InvokeStream(context.Background(), provider, req)
+	if err != nil {
+		t.Fatalf("InvokeStream: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if string(provider.got) != "payload" {
+		t.Errorf("provider got body %q, want payload", provider.got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != "payload" || resp.Headers.Get("Content-Type") != "text/plain" {
+		t.Errorf("response = %d %v %q", resp.StatusCode, resp.Headers, body)
+	}
+}
+
+func TestNewBodyRequest(t *testing.T) {
+	req := &ProviderRequest{BodyReader: io.NopCloser(strings.NewReader("streamed")), ContentLength: 8}
+	httpReq, err := NewBodyRequest(context.Background(), http.MethodPost, "http://upstream/v1", req)
+	if err != nil {
+		t.Fatalf("NewBodyRequest: %v", err)
+	}
+	if httpReq.ContentLength != 8 {
+		t.Errorf("ContentLength = %d, want 8", httpReq.ContentLength)
+	}
+
+	httpReq, err = NewBodyRequest(context.Background(), http.MethodGet, "http://upstream/v1", &ProviderRequest{})
+	if err != nil {
+		t.Fatalf("NewBodyRequest: %v", err)
+	}
+	if httpReq.Body != http.NoBody {
+		t.Errorf("Body = %v, want NoBody for an empty request", httpReq.Body)
+	}
+}
@@ -0,0 +1,46 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package providers
+
+import (
+	"context"
+	"unicode"
+)
+
+// TokenCounter is implemented by providers that can count the prompt tokens
+// of a request before it is sent (pre-flight checks, quota management). It is
+// optional: callers should type-assert a Provider to TokenCounter before use.
+type TokenCounter interface {
+	// CountTokens returns the number of prompt tokens in request, which is
+	// the same provider request that would be passed to Invoke
+	CountTokens(ctx context.Context, request *ProviderRequest) (int, error)
+}
+
+// ApproximateTokens estimates the token count of text without a tokenizer.
+// It approximates BPE tokenizers such as tiktoken's cl100k: each run of
+// letters or digits costs one token per 6 characters (common words are a
+// single token), each other symbol one token, and whitespace is folded into
+// the following token.
+func ApproximateTokens(text string) int {
+	tokens := 0
+	run := 0
+	flush := func() {
+		tokens += (run + 5) / 6
+		run = 0
+	}
+
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			run++
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			flush()
+			tokens++
+		}
+	}
+	flush()
+	return tokens
+}
@@ -0,0 +1,22 @@
+package providers
+
+import "testing"
+
+func TestApproximateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"hello", 1},
+		{"Hello, world!", 4},
+		{"The quick brown fox", 4},
+		{"internationalization", 4},
+		{"   spaced   out   ", 2},
+	}
+	for _, tt := range tests {
+		if got := ApproximateTokens(tt.text); got != tt.want {
+			t.Errorf("ApproximateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vertex
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/anthropic"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// Claude models on Vertex AI take Anthropic Messages bodies at
+// publishers/anthropic/models/{model}:rawPredict, or :streamRawPredict for
+// Anthropic SSE. The model is named by the URL and the API version by the
+// body.
+
+// claudeAPIVersion is the anthropic_version Vertex AI accepts
+const claudeAPIVersion = "vertex-2023-10-16"
+
+// claudeDefaultMaxTokens is sent when the client omits max_tokens, which
+// the Messages API requires
+const claudeDefaultMaxTokens = 4096
+
+// claudeModelSuffix marks gateway model IDs routed to Claude on Vertex AI,
+// such as claude-3-5-sonnet-vertex; it is not part of the Vertex model ID
+const claudeModelSuffix = "-vertex"
+
+// claudeRequest is an Anthropic Messages request as Vertex AI takes it
+type claudeRequest struct {
+	AnthropicVersion string `json:"anthropic_version"`
+	*anthropic.AnthropicRequest
+}
+
+// isClaudeModel reports whether a model is served by Anthropic on Vertex AI
+func isClaudeModel(model string) bool {
+	return strings.HasPrefix(model, "claude-")
+}
+
+// claudeURL returns the rawPredict or streamRawPredict URL of a Claude model
+func (p *VertexProvider) claudeURL(model, method string) string {
+	return fmt.Sprintf("%s/publishers/anthropic/models/%s:%s",
+		p.baseURL, strings.TrimSuffix(model, claudeModelSuffix), method)
+}
+
+// claudeBody translates an OpenAI request to the rawPredict body
+func claudeBody(openaiReq *translator.ChatCompletionRequest, stream bool) ([]byte, error) {
+	messagesReq := anthropic.TranslateRequest(openaiReq)
+	messagesReq.Model = ""
+	messagesReq.Stream = stream
+	if messagesReq.MaxTokens == 0 {
+		messagesReq.MaxTokens = claudeDefaultMaxTokens
+	}
+	return json.Marshal(claudeRequest{
+		AnthropicVersion: claudeAPIVersion,
+		AnthropicRequest: messagesReq,
+	})
+}
+
+// doClaude sends a rawPredict or streamRawPredict request. Error statuses
+// are returned as a ProviderError; otherwise the caller must close the
+// response body.
+func (p *VertexProvider) doClaude(ctx context.Context, request *providers.ProviderRequest, openaiReq *translator.ChatCompletionRequest, stream bool) (*http.Response, error) {
+	body, err := claudeBody(openaiReq, stream)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("failed to marshal request: %v", err),
+			Provider:   "vertex",
+		}
+	}
+
+	method := "rawPredict"
+	if stream {
+		method = "streamRawPredict"
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", p.claudeURL(openaiReq.Model, method), bytes.NewReader(body))
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("failed to create request: %v", err),
+			Provider:   "vertex",
+		}
+	}
+
+	httpReq.Header.Set("Content-Type", "application/json")
+	if err := p.setCredentials(httpReq, request); err != nil {
+		return nil, err
+	}
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    fmt.Sprintf("request failed: %v", err),
+			Provider:   "vertex",
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, &providers.ProviderError{
+			StatusCode: resp.StatusCode,
+			Message:    string(respBody),
+			Provider:   "vertex",
+			Headers:    resp.Header.Clone(),
+		}
+	}
+	return resp, nil
+}
+
+// invokeClaude sends a chat request to Claude on Vertex AI and translates
+// the response to the OpenAI format
+func (p *VertexProvider) invokeClaude(ctx context.Context, request *providers.ProviderRequest, openaiReq *translator.ChatCompletionRequest) (*providers.ProviderResponse, error) {
+	resp, err := p.doClaude(ctx, request, openaiReq, false)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var messagesResp anthropic.AnthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&messagesResp); err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("failed to parse response: %v", err),
+			Provider:   "vertex",
+		}
+	}
+
+	openaiBody, err := json.Marshal(anthropic.TranslateResponse(&messagesResp, openaiReq.Model))
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("failed to marshal response: %v", err),
+			Provider:   "vertex",
+		}
+	}
+
+	return &providers.ProviderResponse{
+		StatusCode: resp.StatusCode,
+		Headers:    resp.Header.Clone(),
+		Body:       openaiBody,
+	}, nil
+}
+
+// streamClaude sends a streaming chat request to Claude on Vertex AI and
+// returns its Anthropic SSE stream
+func (p *VertexProvider) streamClaude(ctx context.Context, request *providers.ProviderRequest, openaiReq *translator.ChatCompletionRequest) (io.ReadCloser, error) {
+	resp, err := p.doClaude(ctx, request, openaiReq, true)
+	if err != nil {
+		return nil, err
+	}
+	return providers.NewHeaderStream(resp.Body, resp.Header.Clone()), nil
+}
@@ -0,0 +1,108 @@
+package vertex
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+// newClaudeTestProvider returns a provider sending requests to a server
+// that records the request path and body and answers with response
+func newClaudeTestProvider(t *testing.T, response string) (*VertexProvider, *string, *map[string]interface{}) {
+	t.Helper()
+	var path string
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("request body: %v", err)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(server.Close)
+
+	p, err := NewVertexProvider(VertexConfig{ProjectID: "proj", Location: "us-east5", AccessToken: "token"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.baseURL = server.URL + "/v1/projects/proj/locations/us-east5"
+	return p, &path, &body
+}
+
+func claudeProviderRequest(t *testing.T, model string) *providers.ProviderRequest {
+	t.Helper()
+	body, err := json.Marshal(translator.ChatCompletionRequest{
+		Model: model,
+		Messages: []translator.ChatMessage{
+			{Role: "system", Content: translator.TextContent("Be brief.")},
+			{Role: "user", Content: translator.TextContent("Hi")},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &providers.ProviderRequest{Method: "POST", Path: "/chat/completions", Body: body}
+}
+
+func TestInvokeClaude(t *testing.T) {
+	p, path, body := newClaudeTestProvider(t, `{"id":"msg_1","type":"message","role":"assistant",
+		"content":[{"type":"text","text":"Hello."}],"model":"claude-3-5-sonnet-20240620",
+		"stop_reason":"end_turn","usage":{"input_tokens":9,"output_tokens":3}}`)
+
+	resp, err := p.Invoke(context.Background(), claudeProviderRequest(t, "claude-3-5-sonnet@20240620"))
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+
+	if want := "/v1/projects/proj/locations/us-east5/publishers/anthropic/models/claude-3-5-sonnet@20240620:rawPredict"; *path != want {
+		t.Errorf("path = %s, want %s", *path, want)
+	}
+	if (*body)["anthropic_version"] != claudeAPIVersion || (*body)["system"] != "Be brief." ||
+		(*body)["max_tokens"] != float64(claudeDefaultMaxTokens) {
+		t.Errorf("request body = %v, want anthropic_version, system and default max_tokens", *body)
+	}
+	if _, ok := (*body)["model"]; ok {
+		t.Errorf("request body = %v, want no model", *body)
+	}
+
+	var openaiResp translator.ChatCompletionResponse
+	if err := json.Unmarshal(resp.Body, &openaiResp); err != nil {
+		t.Fatalf("response %s: %v", resp.Body, err)
+	}
+	if len(openaiResp.Choices) != 1 || openaiResp.Choices[0].Message.Content.Text() != "Hello." ||
+		openaiResp.Usage == nil || openaiResp.Usage.TotalTokens != 12 {
+		t.Errorf("response = %s, want the translated message and usage", resp.Body)
+	}
+}
+
+func TestInvokeStreamingClaude(t *testing.T) {
+	const events = "event: message_start\ndata: {\"type\":\"message_start\"}\n\n"
+	p, path, body := newClaudeTestProvider(t, events)
+
+	stream, err := p.InvokeStreaming(context.Background(), claudeProviderRequest(t, "claude-3-haiku-vertex"))
+	if err != nil {
+		t.Fatalf("InvokeStreaming() error = %v", err)
+	}
+	defer stream.Close()
+	data, _ := io.ReadAll(stream)
+
+	if !strings.HasSuffix(*path, "/publishers/anthropic/models/claude-3-haiku:streamRawPredict") {
+		t.Errorf("path = %s, want the streamRawPredict path without the -vertex suffix", *path)
+	}
+	if (*body)["stream"] != true {
+		t.Errorf("request body = %v, want stream: true", *body)
+	}
+	if string(data) != events {
+		t.Errorf("stream = %q, want the Anthropic events unchanged", data)
+	}
+}
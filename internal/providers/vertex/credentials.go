@@ -0,0 +1,66 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package vertex
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// cloudPlatformScope is the OAuth2 scope of Vertex AI requests
+const cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// newTokenSource returns the source of the access tokens sent to Vertex AI:
+// the configured access token, or else Application Default Credentials.
+// These cover GOOGLE_APPLICATION_CREDENTIALS, including Workload Identity
+// Federation credential configs, and on GKE with Workload Identity the pod's
+// service account from the metadata server. Without any credentials it
+// returns nil, and requests carry no token unless auth is passed through.
+func newTokenSource(config VertexConfig) oauth2.TokenSource {
+	if config.AccessToken != "" {
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: config.AccessToken})
+	}
+	if config.TokenSource != nil {
+		return oauth2.ReuseTokenSource(nil, config.TokenSource)
+	}
+
+	creds, err := google.FindDefaultCredentials(context.Background(), cloudPlatformScope)
+	if err != nil {
+		log.Printf("Vertex AI: no access token or Application Default Credentials: %v", err)
+		return nil
+	}
+	log.Printf("✓ Vertex AI using Application Default Credentials")
+	return oauth2.ReuseTokenSource(nil, creds.TokenSource)
+}
+
+// setCredentials sets the Authorization header of a request to Vertex AI:
+// the client's with pass-through auth, otherwise a token from the
+// provider's credentials. Tokens are cached and refreshed before expiry.
+func (p *VertexProvider) setCredentials(httpReq *http.Request, request *providers.ProviderRequest) error {
+	if request != nil && request.PassThroughAuth {
+		providers.SetCredentials(httpReq, request, "Authorization", "")
+		return nil
+	}
+	if p.tokenSource == nil {
+		return nil
+	}
+	token, err := p.tokenSource.Token()
+	if err != nil {
+		return &providers.ProviderError{
+			StatusCode: http.StatusBadGateway,
+			Code:       providers.ErrCodeAuthenticationFail,
+			Message:    fmt.Sprintf("failed to get access token: %v", err),
+			Provider:   "vertex",
+			Err:        err,
+		}
+	}
+	token.SetAuthHeader(httpReq)
+	return nil
+}
@@ -0,0 +1,114 @@
+package vertex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"golang.org/x/oauth2"
+)
+
+// TestWorkloadIdentityFederation tests a provider without an access token
+// exchanging a Kubernetes service account token through the credential
+// config in GOOGLE_APPLICATION_CREDENTIALS, and reusing the token it gets
+func TestWorkloadIdentityFederation(t *testing.T) {
+	exchanges := 0
+	sts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		exchanges++
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("token exchange: %v", err)
+		}
+		if got := r.PostForm.Get("subject_token"); got != "k8s-sa-token" {
+			t.Errorf("subject_token = %q, want the projected service account token", got)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, `{"access_token":"federated-token","issued_token_type":"urn:ietf:params:oauth:token-type:access_token","token_type":"Bearer","expires_in":3600}`)
+	}))
+	defer sts.Close()
+
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("k8s-sa-token"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	credentialConfig := filepath.Join(dir, "credential-config.json")
+	if err := os.WriteFile(credentialConfig, []byte(fmt.Sprintf(`{
+		"type": "external_account",
+		"audience": "//iam.googleapis.com/projects/123/locations/global/workloadIdentityPools/k8s/providers/cluster",
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url": %q,
+		"credential_source": {"file": %q}
+	}`, sts.URL+"/v1/token", tokenFile)), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GOOGLE_APPLICATION_CREDENTIALS", credentialConfig)
+
+	vertexServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer federated-token" {
+			t.Errorf("Authorization = %q", got)
+		}
+		io.WriteString(w, `{"candidates":[{"content":{"role":"model","parts":[{"text":"Hello."}]},"finishReason":"STOP"}]}`)
+	}))
+	defer vertexServer.Close()
+
+	p, err := NewVertexProvider(VertexConfig{ProjectID: "proj"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.baseURL = vertexServer.URL + "/v1/projects/proj/locations/us-central1"
+
+	for i := 0; i < 2; i++ {
+		resp, err := p.Invoke(context.Background(), &providers.ProviderRequest{
+			Method: "POST",
+			Path:   "/chat/completions",
+			Body:   []byte(`{"model":"gemini-1.5-pro","messages":[{"role":"user","content":"Hi"}]}`),
+		})
+		if err != nil || resp.StatusCode != http.StatusOK {
+			t.Fatalf("Invoke() = %v, %v", resp, err)
+		}
+	}
+	if exchanges != 1 {
+		t.Errorf("token exchanged %d times, want 1", exchanges)
+	}
+	if err := p.HealthCheck(context.Background()); err != nil {
+		t.Errorf("HealthCheck() error = %v", err)
+	}
+}
+
+// failingTokenSource fails to supply a token
+type failingTokenSource struct{}
+
+func (failingTokenSource) Token() (*oauth2.Token, error) {
+	return nil, errors.New("metadata server unavailable")
+}
+
+// TestTokenSourceError tests that a failure to get a token fails the
+// request before it is sent, and fails the health check
+func TestTokenSourceError(t *testing.T) {
+	p, err := NewVertexProvider(VertexConfig{ProjectID: "proj", TokenSource: failingTokenSource{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.baseURL = "http://127.0.0.1:0"
+
+	_, err = p.Invoke(context.Background(), &providers.ProviderRequest{
+		Method: "POST",
+		Path:   "/chat/completions",
+		Body:   []byte(`{"model":"gemini-1.5-pro","messages":[{"role":"user","content":"Hi"}]}`),
+	})
+	var providerErr *providers.ProviderError
+	if !errors.As(err, &providerErr) || providerErr.StatusCode != http.StatusBadGateway ||
+		providerErr.Code != providers.ErrCodeAuthenticationFail {
+		t.Errorf("Invoke() error = %v, want a 502 authentication failure", err)
+	}
+	if err := p.HealthCheck(context.Background()); err == nil {
+		t.Error("HealthCheck() succeeded without a token")
+	}
+}
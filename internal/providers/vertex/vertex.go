@@ -10,17 +10,19 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
 	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"golang.org/x/oauth2"
 )
 
 // VertexProvider implements the Provider interface for Google Vertex AI
 type VertexProvider struct {
 	projectID   string
 	location    string
-	accessToken string // OAuth2 access token
+	tokenSource oauth2.TokenSource // nil without credentials
 	baseURL     string
 	httpClient  *http.Client
 }
@@ -30,6 +32,15 @@ type VertexConfig struct {
 	ProjectID   string `yaml:"project_id"`
 	Location    string `yaml:"location"` // e.g., us-central1
 	AccessToken string `yaml:"access_token"` // OAuth2 token (or use Application Default Credentials)
+	Timeouts    providers.HTTPTimeouts // Optional, defaults to 10s connect and 120s request
+
+	// TokenSource supplies access tokens when AccessToken is empty; nil
+	// uses Application Default Credentials
+	TokenSource oauth2.TokenSource `yaml:"-"`
+
+	// Endpoint replaces https://{location}-aiplatform.googleapis.com, such
+	// as for a Private Service Connect endpoint (optional)
+	Endpoint string `yaml:"endpoint"`
 }
 
 // Vertex AI Gemini API request/response types
@@ -41,7 +52,7 @@ type VertexGeminiRequest struct {
 }
 
 type VertexContent struct {
-	Role  string        `json:"role"` // user, model
+	Role  string        `json:"role,omitempty"` // user, model; unset for systemInstruction
 	Parts []VertexPart  `json:"parts"`
 }
 
@@ -105,17 +116,18 @@ func NewVertexProvider(config VertexConfig) (*VertexProvider, error) {
 		config.Location = "us-central1" // Default location
 	}
 
-	baseURL := fmt.Sprintf("https://%s-aiplatform.googleapis.com/v1/projects/%s/locations/%s",
-		config.Location, config.ProjectID, config.Location)
+	endpoint := fmt.Sprintf("https://%s-aiplatform.googleapis.com", config.Location)
+	if config.Endpoint != "" {
+		endpoint = strings.TrimSuffix(config.Endpoint, "/")
+	}
+	baseURL := fmt.Sprintf("%s/v1/projects/%s/locations/%s", endpoint, config.ProjectID, config.Location)
 
 	return &VertexProvider{
 		projectID:   config.ProjectID,
 		location:    config.Location,
-		accessToken: config.AccessToken,
+		tokenSource: newTokenSource(config),
 		baseURL:     baseURL,
-		httpClient: &http.Client{
-			Timeout: 120 * time.Second,
-		},
+		httpClient: providers.NewHTTPClient(config.Timeouts),
 	}, nil
 }
 
@@ -124,9 +136,25 @@ func (p *VertexProvider) Name() string {
 	return "vertex"
 }
 
-// HealthCheck checks if the provider is accessible
+// Capabilities reports what Vertex AI's chat models support
+func (p *VertexProvider) Capabilities() providers.Capabilities {
+	return providers.Capabilities{
+		Streaming:        true,
+		Vision:           true,
+		Tools:            true,
+		MaxContextTokens: 32000,
+	}
+}
+
+// HealthCheck checks that an access token can be obtained
 func (p *VertexProvider) HealthCheck(ctx context.Context) error {
 	// Could list models or endpoints, but skip for now
+	if p.tokenSource == nil {
+		return nil
+	}
+	if _, err := p.tokenSource.Token(); err != nil {
+		return fmt.Errorf("failed to get access token: %w", err)
+	}
 	return nil
 }
 
@@ -142,13 +170,44 @@ func (p *VertexProvider) Invoke(ctx context.Context, request *providers.Provider
 		}
 	}
 
-	// Translate to Vertex format
+	if isClaudeModel(openaiReq.Model) {
+		return p.invokeClaude(ctx, request, &openaiReq)
+	}
+
+	// Translate to Vertex format and send it
 	vertexReq := translateOpenAIToVertex(&openaiReq)
+	vertexResp, headers, err := p.GenerateContent(ctx, request, openaiReq.Model, vertexReq)
+	if err != nil {
+		return nil, err
+	}
+
+	// Translate back to OpenAI format
+	openaiResp := translateVertexToOpenAI(vertexResp, openaiReq.Model)
+
+	// Marshal OpenAI response
+	openaiBody, err := json.Marshal(openaiResp)
+	if err != nil {
+		return nil, &providers.ProviderError{
+			StatusCode: http.StatusInternalServerError,
+			Message:    fmt.Sprintf("failed to marshal response: %v", err),
+			Provider:   "vertex",
+		}
+	}
+
+	return &providers.ProviderResponse{
+		StatusCode: http.StatusOK,
+		Headers:    headers,
+		Body:       openaiBody,
+	}, nil
+}
 
+// GenerateContent sends a Gemini generateContent request for model and
+// returns its response and headers. Credentials are set as for Invoke.
+func (p *VertexProvider) GenerateContent(ctx context.Context, request *providers.ProviderRequest, model string, vertexReq *VertexGeminiRequest) (*VertexResponse, http.Header, error) {
 	// Marshal request
 	body, err := json.Marshal(vertexReq)
 	if err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
 			Message:    fmt.Sprintf("failed to marshal request: %v", err),
 			Provider:   "vertex",
@@ -157,13 +216,12 @@ func (p *VertexProvider) Invoke(ctx context.Context, request *providers.Provider
 
 	// Build URL based on model
 	// For Gemini: /publishers/google/models/{model}:generateContent
-	modelID := openaiReq.Model
-	url := fmt.Sprintf("%s/publishers/google/models/%s:generateContent", p.baseURL, modelID)
+	url := fmt.Sprintf("%s/publishers/google/models/%s:generateContent", p.baseURL, model)
 
 	// Create HTTP request
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(body))
 	if err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
 			Message:    fmt.Sprintf("failed to create request: %v", err),
 			Provider:   "vertex",
@@ -172,14 +230,14 @@ func (p *VertexProvider) Invoke(ctx context.Context, request *providers.Provider
 
 	// Set headers
 	httpReq.Header.Set("Content-Type", "application/json")
-	if p.accessToken != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+p.accessToken)
+	if err := p.setCredentials(httpReq, request); err != nil {
+		return nil, nil, err
 	}
 
 	// Send request
 	resp, err := p.httpClient.Do(httpReq)
 	if err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusServiceUnavailable,
 			Message:    fmt.Sprintf("request failed: %v", err),
 			Provider:   "vertex",
@@ -190,7 +248,7 @@ func (p *VertexProvider) Invoke(ctx context.Context, request *providers.Provider
 	// Read response
 	respBody, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
 			Message:    fmt.Sprintf("failed to read response: %v", err),
 			Provider:   "vertex",
@@ -199,7 +257,7 @@ func (p *VertexProvider) Invoke(ctx context.Context, request *providers.Provider
 
 	// Check for errors
 	if resp.StatusCode != http.StatusOK {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: resp.StatusCode,
 			Message:    string(respBody),
 			Provider:   "vertex",
@@ -209,39 +267,14 @@ func (p *VertexProvider) Invoke(ctx context.Context, request *providers.Provider
 	// Parse Vertex response
 	var vertexResp VertexResponse
 	if err := json.Unmarshal(respBody, &vertexResp); err != nil {
-		return nil, &providers.ProviderError{
+		return nil, nil, &providers.ProviderError{
 			StatusCode: http.StatusInternalServerError,
 			Message:    fmt.Sprintf("failed to parse response: %v", err),
 			Provider:   "vertex",
 		}
 	}
 
-	// Translate back to OpenAI format
-	openaiResp := translateVertexToOpenAI(&vertexResp, openaiReq.Model)
-
-	// Marshal OpenAI response
-	openaiBody, err := json.Marshal(openaiResp)
-	if err != nil {
-		return nil, &providers.ProviderError{
-			StatusCode: http.StatusInternalServerError,
-			Message:    fmt.Sprintf("failed to marshal response: %v", err),
-			Provider:   "vertex",
-		}
-	}
-
-	// Build provider response
-	headers := make(map[string]string)
-	for k, v := range resp.Header {
-		if len(v) > 0 {
-			headers[k] = v[0]
-		}
-	}
-
-	return &providers.ProviderResponse{
-		StatusCode: resp.StatusCode,
-		Headers:    headers,
-		Body:       openaiBody,
-	}, nil
+	return &vertexResp, resp.Header.Clone(), nil
 }
 
 // InvokeStreaming sends a streaming request to Vertex AI
@@ -255,6 +288,10 @@ func (p *VertexProvider) InvokeStreaming(ctx context.Context, request *providers
 		}
 	}
 
+	if isClaudeModel(openaiReq.Model) {
+		return p.streamClaude(ctx, request, &openaiReq)
+	}
+
 	vertexReq := translateOpenAIToVertex(&openaiReq)
 	body, err := json.Marshal(vertexReq)
 	if err != nil {
@@ -278,8 +315,8 @@ func (p *VertexProvider) InvokeStreaming(ctx context.Context, request *providers
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
-	if p.accessToken != "" {
-		httpReq.Header.Set("Authorization", "Bearer "+p.accessToken)
+	if err := p.setCredentials(httpReq, request); err != nil {
+		return nil, err
 	}
 
 	resp, err := p.httpClient.Do(httpReq)
@@ -315,6 +352,11 @@ func (p *VertexProvider) ListModels(ctx context.Context) ([]providers.Model, err
 		{ID: "gemini-pro-vision", Name: "Gemini Pro Vision", Provider: "vertex"},
 		{ID: "text-bison", Name: "PaLM 2 Text Bison", Provider: "vertex"},
 		{ID: "chat-bison", Name: "PaLM 2 Chat Bison", Provider: "vertex"},
+		{ID: "claude-3-5-sonnet-v2@20241022", Name: "Claude 3.5 Sonnet v2", Provider: "vertex"},
+		{ID: "claude-3-5-sonnet@20240620", Name: "Claude 3.5 Sonnet", Provider: "vertex"},
+		{ID: "claude-3-opus@20240229", Name: "Claude 3 Opus", Provider: "vertex"},
+		{ID: "claude-3-sonnet@20240229", Name: "Claude 3 Sonnet", Provider: "vertex"},
+		{ID: "claude-3-haiku@20240307", Name: "Claude 3 Haiku", Provider: "vertex"},
 	}
 
 	return models, nil
@@ -331,6 +373,18 @@ func (p *VertexProvider) GetModelInfo(ctx context.Context, modelID string) (*pro
 	return nil, fmt.Errorf("model not found: %s", modelID)
 }
 
+// TranslateRequest converts an OpenAI chat request to a Gemini
+// generateContent request
+func TranslateRequest(req *translator.ChatCompletionRequest) *VertexGeminiRequest {
+	return translateOpenAIToVertex(req)
+}
+
+// TranslateResponse converts a Gemini generateContent response to an
+// OpenAI chat completion
+func TranslateResponse(resp *VertexResponse, model string) *translator.ChatCompletionResponse {
+	return translateVertexToOpenAI(resp, model)
+}
+
 // translateOpenAIToVertex converts OpenAI format to Vertex AI format
 func translateOpenAIToVertex(req *translator.ChatCompletionRequest) *VertexGeminiRequest {
 	vertexReq := &VertexGeminiRequest{
@@ -354,13 +408,14 @@ func translateOpenAIToVertex(req *translator.ChatCompletionRequest) *VertexGemin
 	// Convert messages
 	for _, msg := range req.Messages {
 		if msg.Role == "system" {
-			// System message goes to systemInstruction
-			vertexReq.SystemInstruction = &VertexContent{
-				Role: "user", // Vertex uses "user" for system instructions
-				Parts: []VertexPart{
-					{Text: extractTextContent(msg.Content)},
-				},
+			// Gemini takes system prompts in the top-level systemInstruction
+			// rather than contents; further system messages become extra parts
+			if vertexReq.SystemInstruction == nil {
+				vertexReq.SystemInstruction = &VertexContent{}
 			}
+			vertexReq.SystemInstruction.Parts = append(vertexReq.SystemInstruction.Parts, VertexPart{
+				Text: msg.Content.Text(),
+			})
 		} else {
 			// Map roles: assistant -> model
 			role := msg.Role
@@ -371,7 +426,7 @@ func translateOpenAIToVertex(req *translator.ChatCompletionRequest) *VertexGemin
 			vertexReq.Contents = append(vertexReq.Contents, VertexContent{
 				Role: role,
 				Parts: []VertexPart{
-					{Text: extractTextContent(msg.Content)},
+					{Text: msg.Content.Text()},
 				},
 			})
 		}
@@ -443,7 +498,7 @@ func translateVertexToOpenAI(resp *VertexResponse, model string) *translator.Cha
 
 	message := translator.ChatMessage{
 		Role:    "assistant",
-		Content: content,
+		Content: translator.TextContent(content),
 	}
 
 	if len(toolCalls) > 0 {
@@ -473,25 +528,3 @@ func translateVertexToOpenAI(resp *VertexResponse, model string) *translator.Cha
 		Usage: usage,
 	}
 }
-
-// extractTextContent extracts text from content interface
-func extractTextContent(content interface{}) string {
-	switch c := content.(type) {
-	case string:
-		return c
-	case []interface{}:
-		var text string
-		for _, part := range c {
-			if partMap, ok := part.(map[string]interface{}); ok {
-				if partType, ok := partMap["type"].(string); ok && partType == "text" {
-					if textVal, ok := partMap["text"].(string); ok {
-						text += textVal
-					}
-				}
-			}
-		}
-		return text
-	default:
-		return fmt.Sprintf("%v", content)
-	}
-}
@@ -0,0 +1,74 @@
+package vertex
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+)
+
+func TestTranslateOpenAIToVertexSystemInstruction(t *testing.T) {
+	req := &translator.ChatCompletionRequest{
+		Model: "gemini-1.5-pro",
+		Messages: []translator.ChatMessage{
+			{Role: "system", Content: translator.TextContent("You are a terse assistant.")},
+			{Role: "user", Content: translator.TextContent("Hi")},
+			{Role: "assistant", Content: translator.TextContent("Hello.")},
+			{Role: "user", Content: translator.TextContent("Bye")},
+		},
+	}
+
+	body, err := json.Marshal(translateOpenAIToVertex(req))
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+
+	var wire struct {
+		SystemInstruction *struct {
+			Role  *string `json:"role"`
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"systemInstruction"`
+		Contents []struct {
+			Role  string `json:"role"`
+			Parts []struct {
+				Text string `json:"text"`
+			} `json:"parts"`
+		} `json:"contents"`
+	}
+	if err := json.Unmarshal(body, &wire); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if wire.SystemInstruction == nil || len(wire.SystemInstruction.Parts) != 1 ||
+		wire.SystemInstruction.Parts[0].Text != "You are a terse assistant." {
+		t.Fatalf("systemInstruction = %s, want the system message text", body)
+	}
+	if wire.SystemInstruction.Role != nil {
+		t.Errorf("systemInstruction has role %q", *wire.SystemInstruction.Role)
+	}
+
+	wantRoles := []string{"user", "model", "user"}
+	if len(wire.Contents) != len(wantRoles) {
+		t.Fatalf("contents = %s, want %d non-system messages", body, len(wantRoles))
+	}
+	for i, content := range wire.Contents {
+		if content.Role != wantRoles[i] {
+			t.Errorf("contents[%d].role = %q, want %q", i, content.Role, wantRoles[i])
+		}
+	}
+	if wire.Contents[0].Parts[0].Text != "Hi" {
+		t.Errorf("contents[0] text = %q", wire.Contents[0].Parts[0].Text)
+	}
+}
+
+func TestTranslateOpenAIToVertexNoSystem(t *testing.T) {
+	req := &translator.ChatCompletionRequest{
+		Model:    "gemini-1.5-pro",
+		Messages: []translator.ChatMessage{{Role: "user", Content: translator.TextContent("Hi")}},
+	}
+	if got := translateOpenAIToVertex(req).SystemInstruction; got != nil {
+		t.Errorf("SystemInstruction = %+v, want nil", got)
+	}
+}
@@ -103,9 +103,9 @@ func (bp *BedrockProxy) Handler() gin.HandlerFunc {
 
 		// Update health checker
 		if recorder.statusCode >= 500 {
-			bp.healthChecker.RecordError()
+			bp.healthChecker.RecordError("bedrock")
 		} else {
-			bp.healthChecker.RecordSuccess()
+			bp.healthChecker.RecordSuccess("bedrock")
 		}
 	}
 }
@@ -186,7 +186,7 @@ func (bp *BedrockProxy) errorHandler(rw http.ResponseWriter, req *http.Request,
 	rw.Write([]byte(`{"error":"Bedrock service unavailable","message":"The Bedrock service is currently unavailable. Please try again later."}`))
 
 	// Record error in health checker
-	bp.healthChecker.RecordError()
+	bp.healthChecker.RecordError("bedrock")
 }
 
 // modifyResponse modifies the response from Bedrock
@@ -0,0 +1,281 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package ratelimit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// UsageTokensKey is the gin context key handlers set to the total tokens a
+// request consumed, so the limiter can charge them to the caller's bucket
+const UsageTokensKey = "usage_total_tokens"
+
+// Unlimited is reported as the remaining count when a limit is not configured
+const Unlimited = -1
+
+// Mode selects whether limiters reject requests or only report them
+type Mode string
+
+const (
+	// ModeEnforce rejects requests over the limit
+	ModeEnforce Mode = "enforce"
+
+	// ModeReportOnly never rejects; would-block events are counted in
+	// ai_limit_would_block_total so limits can be sized before enforcing them
+	ModeReportOnly Mode = "report_only"
+)
+
+// ParseMode parses a limiter mode; an empty string means ModeEnforce
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(s))) {
+	case "", ModeEnforce:
+		return ModeEnforce, nil
+	case ModeReportOnly, "shadow":
+		return ModeReportOnly, nil
+	default:
+		return "", fmt.Errorf("invalid limit mode %q (want %s or %s)", s, ModeEnforce, ModeReportOnly)
+	}
+}
+
+// RecordWouldBlock counts a request that a report-only limiter let through
+func RecordWouldBlock(limiter, reason string) {
+	metrics.LimitWouldBlock.WithLabelValues(limiter, reason).Inc()
+}
+
+// Config configures per-key, per-model fixed-window limits. A zero limit
+// disables that dimension.
+type Config struct {
+	RequestsPerWindow int
+	TokensPerWindow   int
+	Window            time.Duration
+	Mode              Mode
+}
+
+// RateLimitStatus reports the state of one active bucket
+type RateLimitStatus struct {
+	KeyPrefix         string    `json:"key_prefix"`
+	Model             string    `json:"model"`
+	RequestsRemaining int       `json:"requests_remaining"`
+	TokensRemaining   int       `json:"tokens_remaining"`
+	ResetsAt          time.Time `json:"resets_at"`
+}
+
+type bucketKey struct {
+	keyPrefix string
+	model     string
+}
+
+type bucket struct {
+	windowStart time.Time
+	lastSeen    time.Time
+	requests    int
+	tokens      int
+}
+
+// Limiter is an in-memory rate limiter keyed by API key and model
+type Limiter struct {
+	config Config
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+
+	// notifier is told when a key is rejected
+	notifier *notify.Notifier
+}
+
+// NewLimiter creates a new rate limiter
+func NewLimiter(config Config) *Limiter {
+	if config.Window <= 0 {
+		config.Window = time.Minute
+	}
+	if config.Mode == "" {
+		config.Mode = ModeEnforce
+	}
+	return &Limiter{
+		config:  config,
+		now:     time.Now,
+		buckets: make(map[bucketKey]*bucket),
+	}
+}
+
+// SetNotifier sends a quota exhaustion event to webhooks when a key is
+// rejected
+func (l *Limiter) SetNotifier(notifier *notify.Notifier) {
+	l.notifier = notifier
+}
+
+// KeyPrefix returns the first 8 hex characters of the key's SHA-256, so
+// buckets and metrics never carry the raw key
+func KeyPrefix(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// Allow counts a request against the key's bucket for model and reports
+// whether it is within limits. In report-only mode requests over the limit
+// are counted as would-block and allowed.
+func (l *Limiter) Allow(key, model string) (RateLimitStatus, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	k := bucketKey{keyPrefix: KeyPrefix(key), model: model}
+	b := l.bucket(k)
+
+	reason := ""
+	if l.config.RequestsPerWindow > 0 && b.requests >= l.config.RequestsPerWindow {
+		reason = "requests"
+	} else if l.config.TokensPerWindow > 0 && b.tokens >= l.config.TokensPerWindow {
+		reason = "tokens"
+	}
+
+	allowed := reason == ""
+	if !allowed && l.config.Mode == ModeReportOnly {
+		RecordWouldBlock("rate", reason)
+		allowed = true
+	}
+
+	if allowed {
+		b.requests++
+	} else {
+		metrics.RateLimitRejected.WithLabelValues(model, k.keyPrefix).Inc()
+		l.notifier.Notify(notify.Event{
+			Type:    notify.EventQuotaExhausted,
+			Subject: k.keyPrefix,
+			State:   reason,
+			Message: fmt.Sprintf("%s limit reached for model %s", reason, model),
+			Details: map[string]string{"model": model, "resets_at": b.windowStart.Add(l.config.Window).UTC().Format(time.RFC3339)},
+		})
+	}
+
+	status := l.status(k, b)
+	metrics.RateLimitRemaining.WithLabelValues(model, k.keyPrefix).Set(float64(status.RequestsRemaining))
+	return status, allowed
+}
+
+// RecordTokens charges tokens consumed by a completed request to the key's bucket
+func (l *Limiter) RecordTokens(key, model string, tokens int) {
+	if tokens <= 0 {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	b := l.bucket(bucketKey{keyPrefix: KeyPrefix(key), model: model})
+	b.tokens += tokens
+}
+
+// Statuses returns all active buckets sorted by key prefix and model. Buckets
+// inactive for longer than the window are purged along with their metrics.
+func (l *Limiter) Statuses() []RateLimitStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	statuses := make([]RateLimitStatus, 0, len(l.buckets))
+	for k, b := range l.buckets {
+		if now.Sub(b.lastSeen) > l.config.Window {
+			delete(l.buckets, k)
+			metrics.RateLimitRemaining.DeleteLabelValues(k.model, k.keyPrefix)
+			continue
+		}
+		l.rollWindow(b, now)
+		statuses = append(statuses, l.status(k, b))
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].KeyPrefix != statuses[j].KeyPrefix {
+			return statuses[i].KeyPrefix < statuses[j].KeyPrefix
+		}
+		return statuses[i].Model < statuses[j].Model
+	})
+	return statuses
+}
+
+// bucket returns the current-window bucket for k; callers hold l.mu
+func (l *Limiter) bucket(k bucketKey) *bucket {
+	now := l.now()
+	b, ok := l.buckets[k]
+	if !ok {
+		b = &bucket{windowStart: now}
+		l.buckets[k] = b
+	}
+	l.rollWindow(b, now)
+	b.lastSeen = now
+	return b
+}
+
+// rollWindow resets a bucket whose window has elapsed
+func (l *Limiter) rollWindow(b *bucket, now time.Time) {
+	if now.Sub(b.windowStart) >= l.config.Window {
+		b.windowStart = now
+		b.requests = 0
+		b.tokens = 0
+	}
+}
+
+func (l *Limiter) status(k bucketKey, b *bucket) RateLimitStatus {
+	return RateLimitStatus{
+		KeyPrefix:         k.keyPrefix,
+		Model:             k.model,
+		RequestsRemaining: remaining(l.config.RequestsPerWindow, b.requests),
+		TokensRemaining:   remaining(l.config.TokensPerWindow, b.tokens),
+		ResetsAt:          b.windowStart.Add(l.config.Window),
+	}
+}
+
+func remaining(limit, used int) int {
+	if limit == 0 {
+		return Unlimited
+	}
+	if used >= limit {
+		return 0
+	}
+	return limit - used
+}
+
+// SetHeaders sets OpenAI-style x-ratelimit-* headers for the configured
+// limits from a bucket status
+func (l *Limiter) SetHeaders(h http.Header, status RateLimitStatus) {
+	reset := formatReset(status.ResetsAt.Sub(l.now()))
+	if l.config.RequestsPerWindow > 0 {
+		h.Set("X-Ratelimit-Limit-Requests", strconv.Itoa(l.config.RequestsPerWindow))
+		h.Set("X-Ratelimit-Remaining-Requests", strconv.Itoa(status.RequestsRemaining))
+		h.Set("X-Ratelimit-Reset-Requests", reset)
+	}
+	if l.config.TokensPerWindow > 0 {
+		h.Set("X-Ratelimit-Limit-Tokens", strconv.Itoa(l.config.TokensPerWindow))
+		h.Set("X-Ratelimit-Remaining-Tokens", strconv.Itoa(status.TokensRemaining))
+		h.Set("X-Ratelimit-Reset-Tokens", reset)
+	}
+}
+
+// formatReset formats a time until reset as a duration rounded up to the
+// second ("1m30s"), the format OpenAI uses
+func formatReset(d time.Duration) string {
+	if d < 0 {
+		d = 0
+	}
+	return (d + time.Second - 1).Truncate(time.Second).String()
+}
+
+// Handler serves GET /admin/ratelimits: a JSON array of active buckets
+func (l *Limiter) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, l.Statuses())
+	}
+}
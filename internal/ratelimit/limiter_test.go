@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(config Config) (*Limiter, *time.Time) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(config)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiterRequests(t *testing.T) {
+	l, now := newTestLimiter(Config{RequestsPerWindow: 2, Window: time.Minute})
+
+	for i := 0; i < 2; i++ {
+		if _, ok := l.Allow("key-a", "gpt-4"); !ok {
+			t.Fatalf("request %d rejected, want allowed", i+1)
+		}
+	}
+	status, ok := l.Allow("key-a", "gpt-4")
+	if ok {
+		t.Fatal("third request allowed, want rejected")
+	}
+	if status.RequestsRemaining != 0 {
+		t.Errorf("RequestsRemaining = %d, want 0", status.RequestsRemaining)
+	}
+	if status.TokensRemaining != Unlimited {
+		t.Errorf("TokensRemaining = %d, want %d", status.TokensRemaining, Unlimited)
+	}
+
+	// Other keys and models have their own buckets
+	if _, ok := l.Allow("key-b", "gpt-4"); !ok {
+		t.Error("other key rejected")
+	}
+	if _, ok := l.Allow("key-a", "claude-3"); !ok {
+		t.Error("other model rejected")
+	}
+
+	*now = now.Add(time.Minute)
+	if _, ok := l.Allow("key-a", "gpt-4"); !ok {
+		t.Error("request after window reset rejected")
+	}
+}
+
+func TestLimiterTokens(t *testing.T) {
+	l, _ := newTestLimiter(Config{TokensPerWindow: 100, Window: time.Minute})
+
+	if _, ok := l.Allow("key-a", "gpt-4"); !ok {
+		t.Fatal("first request rejected")
+	}
+	l.RecordTokens("key-a", "gpt-4", 60)
+
+	status, ok := l.Allow("key-a", "gpt-4")
+	if !ok {
+		t.Fatal("second request rejected with tokens remaining")
+	}
+	if status.TokensRemaining != 40 {
+		t.Errorf("TokensRemaining = %d, want 40", status.TokensRemaining)
+	}
+
+	l.RecordTokens("key-a", "gpt-4", 50)
+	if _, ok := l.Allow("key-a", "gpt-4"); ok {
+		t.Error("request allowed after token budget exhausted")
+	}
+}
+
+func TestLimiterStatusesPurgesInactive(t *testing.T) {
+	l, now := newTestLimiter(Config{RequestsPerWindow: 10, Window: time.Minute})
+
+	l.Allow("key-a", "gpt-4")
+	*now = now.Add(30 * time.Second)
+	l.Allow("key-b", "gpt-4")
+
+	statuses := l.Statuses()
+	if len(statuses) != 2 {
+		t.Fatalf("got %d statuses, want 2", len(statuses))
+	}
+	for _, s := range statuses {
+		if len(s.KeyPrefix) != 8 {
+			t.Errorf("KeyPrefix %q, want 8 characters", s.KeyPrefix)
+		}
+		if s.KeyPrefix == "key-a" || s.KeyPrefix == "key-b" {
+			t.Errorf("KeyPrefix exposes raw key %q", s.KeyPrefix)
+		}
+	}
+
+	*now = now.Add(45 * time.Second)
+	statuses = l.Statuses()
+	if len(statuses) != 1 {
+		t.Fatalf("got %d statuses after purge, want 1", len(statuses))
+	}
+	if statuses[0].KeyPrefix != KeyPrefix("key-b") {
+		t.Errorf("remaining bucket = %q, want key-b", statuses[0].KeyPrefix)
+	}
+}
+
+func TestLimiterReportOnly(t *testing.T) {
+	l, _ := newTestLimiter(Config{RequestsPerWindow: 1, Window: time.Minute, Mode: ModeReportOnly})
+
+	for i := 0; i < 3; i++ {
+		if _, ok := l.Allow("key-a", "gpt-4"); !ok {
+			t.Fatalf("request %d rejected in report-only mode", i+1)
+		}
+	}
+
+	statuses := l.Statuses()
+	if len(statuses) != 1 || statuses[0].RequestsRemaining != 0 {
+		t.Errorf("statuses = %+v, want one bucket with 0 requests remaining", statuses)
+	}
+}
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Mode
+		wantErr bool
+	}{
+		{"", ModeEnforce, false},
+		{"enforce", ModeEnforce, false},
+		{"report_only", ModeReportOnly, false},
+		{"Shadow", ModeReportOnly, false},
+		{"off", "", true},
+	}
+	for _, tt := range tests {
+		got, err := ParseMode(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseMode(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestLimiterSetHeaders(t *testing.T) {
+	l, now := newTestLimiter(Config{RequestsPerWindow: 10, Window: time.Minute})
+
+	l.Allow("key-a", "gpt-4")
+	*now = now.Add(20500 * time.Millisecond)
+	status, _ := l.Allow("key-a", "gpt-4")
+
+	h := make(http.Header)
+	l.SetHeaders(h, status)
+	want := map[string]string{
+		"X-Ratelimit-Limit-Requests":     "10",
+		"X-Ratelimit-Remaining-Requests": "8",
+		"X-Ratelimit-Reset-Requests":     "40s",
+	}
+	for key, value := range want {
+		if got := h.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	if h.Get("X-Ratelimit-Limit-Tokens") != "" {
+		t.Error("token headers set without a token limit")
+	}
+}
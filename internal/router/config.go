@@ -10,6 +10,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/pricing"
 	"gopkg.in/yaml.v3"
 )
 
@@ -19,12 +21,25 @@ type Config struct {
 	Routing       RoutingConfig           `yaml:"routing"`
 	Providers     map[string]ProviderConfig `yaml:"providers"`
 	Features      FeatureFlags            `yaml:"features"`
+
+	// FinishReasons overrides provider stop reason -> OpenAI finish_reason mappings
+	FinishReasons map[string]string `yaml:"finish_reasons,omitempty"`
+
+	// LegacyRoutes configures model checks on the legacy Bedrock routes
+	LegacyRoutes LegacyRoutesConfig `yaml:"legacy_routes,omitempty"`
+
+	// Pricing prices responses for the cost metrics and cost headers
+	Pricing pricing.Config `yaml:"pricing,omitempty"`
 }
 
 // ModelMapping defines how a model name maps to different providers
 type ModelMapping struct {
 	DefaultProvider string                       `yaml:"default_provider"`
 	Providers       map[string]ProviderModelInfo `yaml:"providers"`
+
+	// Requires lists capabilities every provider of the model must have:
+	// vision, video, tools or streaming (checked by ValidateAgainstProviders)
+	Requires []string `yaml:"requires,omitempty"`
 }
 
 // ProviderModelInfo contains provider-specific model information
@@ -35,6 +50,14 @@ type ProviderModelInfo struct {
 	Deployment string            `yaml:"deployment,omitempty"`
 	APIVersion string            `yaml:"api_version,omitempty"`
 	Metadata   map[string]string `yaml:"metadata,omitempty"`
+
+	// MaxOutputTokens is the model's max_tokens cap; larger requests are
+	// rejected (0 = not checked)
+	MaxOutputTokens int `yaml:"max_output_tokens,omitempty"`
+
+	// MaxContextTokens is the model's context window, reported by token
+	// estimates (0 = the provider's largest context window)
+	MaxContextTokens int `yaml:"max_context_tokens,omitempty"`
 }
 
 // RoutingConfig defines routing rules and fallback behavior
@@ -68,6 +91,7 @@ type LoadBalancingConfig struct {
 // ProviderConfig contains provider-specific configuration
 type ProviderConfig struct {
 	Enabled     bool          `yaml:"enabled"`
+	Required    *bool         `yaml:"required,omitempty"` // Readiness fails when unhealthy (default: referenced by routing defaults)
 	Region      string        `yaml:"region,omitempty"`
 	Location    string        `yaml:"location,omitempty"`
 	ProjectID   string        `yaml:"project_id,omitempty"`
@@ -96,8 +120,11 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
-	// Expand environment variables
-	expanded := os.ExpandEnv(string(data))
+	// Expand environment variables and secret file references
+	expanded, err := instance.ExpandEnv(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to expand config: %w", err)
+	}
 
 	// Parse YAML
 	var config Config
@@ -120,6 +147,10 @@ func LoadConfig(path string) (*Config, error) {
 		config.Routing.Fallback.MaxAttempts = 2
 	}
 
+	if err := config.Pricing.Validate(); err != nil {
+		return nil, err
+	}
+
 	return &config, nil
 }
 
@@ -177,6 +208,37 @@ func (c *Config) IsProviderEnabled(providerName string) bool {
 	return config.Enabled
 }
 
+// IsProviderRequired reports whether a provider's health gates readiness.
+// An explicit `required` setting wins; otherwise a provider is required only
+// when a model mapping or routing pattern uses it as the default provider.
+func (c *Config) IsProviderRequired(providerName string) bool {
+	if required, ok := c.ExplicitlyRequired(providerName); ok {
+		return required
+	}
+
+	for _, mapping := range c.ModelMappings {
+		if mapping.DefaultProvider == providerName {
+			return true
+		}
+	}
+	for _, pattern := range c.Routing.Patterns {
+		if pattern.DefaultProvider == providerName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ExplicitlyRequired returns a provider's `required` setting, and whether
+// it is set
+func (c *Config) ExplicitlyRequired(providerName string) (required, ok bool) {
+	if config, exists := c.Providers[providerName]; exists && config.Required != nil {
+		return *config.Required, true
+	}
+	return false, false
+}
+
 // GetFallbackProviders returns the list of fallback providers
 func (c *Config) GetFallbackProviders() []string {
 	if !c.Routing.Fallback.Enabled {
@@ -0,0 +1,50 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"errors"
+	"log"
+	"sync/atomic"
+)
+
+// ErrProviderDraining is returned when the only provider for a request is
+// draining
+var ErrProviderDraining = errors.New("provider is draining")
+
+// Drain stops routing new requests to a provider. Requests already routed
+// to it are unaffected. It reports false if the provider is not registered.
+func (r *Router) Drain(name string) bool {
+	if _, exists := r.providers[name]; !exists {
+		return false
+	}
+	if !r.drainFlag(name).Swap(true) {
+		log.Printf("Draining provider %q: no new requests will be routed to it", name)
+	}
+	return true
+}
+
+// Restore returns a drained provider to routing. It reports false if the
+// provider is not registered.
+func (r *Router) Restore(name string) bool {
+	if _, exists := r.providers[name]; !exists {
+		return false
+	}
+	if r.drainFlag(name).Swap(false) {
+		log.Printf("Provider %q restored to routing", name)
+	}
+	return true
+}
+
+// IsDraining reports whether a provider has been drained
+func (r *Router) IsDraining(name string) bool {
+	flag, ok := r.draining.Load(name)
+	return ok && flag.(*atomic.Bool).Load()
+}
+
+// drainFlag returns the drain flag of a provider, creating it if needed
+func (r *Router) drainFlag(name string) *atomic.Bool {
+	flag, _ := r.draining.LoadOrStore(name, new(atomic.Bool))
+	return flag.(*atomic.Bool)
+}
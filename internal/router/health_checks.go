@@ -0,0 +1,29 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"context"
+	"time"
+)
+
+// RunHealthChecks health-checks every enabled provider each interval until
+// ctx is done, each round bounded by timeout. Results eject and restore
+// providers as health checks on probes do, so probes can report the last
+// known state (ProviderStates) instead of checking synchronously.
+func (r *Router) RunHealthChecks(ctx context.Context, interval, timeout time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			r.HealthCheck(checkCtx)
+			cancel()
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrModelNotFound is returned by ResolveBedrockModel for models that are
+// not mapped to Bedrock
+var ErrModelNotFound = errors.New("model not found")
+
+// ErrModelDenied is returned by ResolveBedrockModel for denied models
+var ErrModelDenied = errors.New("model not allowed")
+
+// LegacyRoutesConfig configures model checks on the legacy Bedrock routes
+// (/model/*, /bedrock/*, /v1/bedrock/*), which forward the model ID in the
+// path to Bedrock
+type LegacyRoutesConfig struct {
+	// ValidateModels resolves the path's model ID with ResolveBedrockModel
+	// before forwarding; off, paths are forwarded unchanged
+	ValidateModels bool `yaml:"validate_models"`
+
+	// DeniedModels are rejected even when mapped, by alias or Bedrock ID
+	DeniedModels []string `yaml:"denied_models,omitempty"`
+
+	// Passthrough lists route prefixes (e.g. /v1/bedrock) forwarded
+	// unchanged even when ValidateModels is set
+	Passthrough []string `yaml:"passthrough,omitempty"`
+}
+
+// IsPassthrough reports whether a legacy route prefix skips model checks
+func (l LegacyRoutesConfig) IsPassthrough(prefix string) bool {
+	if !l.ValidateModels {
+		return true
+	}
+	for _, passthrough := range l.Passthrough {
+		if strings.TrimSuffix(passthrough, "/") == prefix {
+			return true
+		}
+	}
+	return false
+}
+
+// ResolveBedrockModel returns the Bedrock model ID for a model named in a
+// legacy route: a model_mappings alias with a bedrock provider resolves to
+// its Bedrock model, and a Bedrock model ID used by any mapping to itself.
+// Other models return ErrModelNotFound, and models in
+// legacy_routes.denied_models ErrModelDenied.
+func (c *Config) ResolveBedrockModel(model string) (string, error) {
+	resolved := ""
+	if mapping, ok := c.ModelMappings[model]; ok {
+		if info, ok := mapping.Providers["bedrock"]; ok && info.Model != "" {
+			resolved = info.Model
+		}
+	}
+	if resolved == "" {
+		for _, mapping := range c.ModelMappings {
+			if info, ok := mapping.Providers["bedrock"]; ok && info.Model == model {
+				resolved = model
+				break
+			}
+		}
+	}
+	if resolved == "" {
+		return "", fmt.Errorf("%w: %q is not mapped to bedrock", ErrModelNotFound, model)
+	}
+
+	for _, denied := range c.LegacyRoutes.DeniedModels {
+		if denied == model || denied == resolved {
+			return "", fmt.Errorf("%w: %q", ErrModelDenied, model)
+		}
+	}
+	return resolved, nil
+}
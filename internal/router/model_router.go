@@ -4,13 +4,16 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
 )
 
 // ModelRouter routes models to their appropriate providers
 type ModelRouter struct {
 	providers map[string]providers.Provider
-	modelMap  map[string]string // model -> provider name mapping
+	modelMap  map[string]string             // model -> provider name mapping
+	instances map[string]providers.Provider // instance name -> provider
+	modelPins map[string]string             // model -> instance name (takes precedence)
 }
 
 // NewModelRouter creates a new model router
@@ -18,6 +21,8 @@ func NewModelRouter() *ModelRouter {
 	return &ModelRouter{
 		providers: make(map[string]providers.Provider),
 		modelMap:  make(map[string]string),
+		instances: make(map[string]providers.Provider),
+		modelPins: make(map[string]string),
 	}
 }
 
@@ -42,8 +47,65 @@ func (r *ModelRouter) RegisterModelMapping(model, providerName string) error {
 	return nil
 }
 
+// RegisterInstance registers the provider serving a named instance
+func (r *ModelRouter) RegisterInstance(name string, provider providers.Provider) error {
+	if _, exists := r.instances[name]; exists {
+		return fmt.Errorf("instance already registered: %s", name)
+	}
+
+	r.instances[name] = provider
+	return nil
+}
+
+// PinModel pins a model to a registered instance, overriding provider-type routing
+func (r *ModelRouter) PinModel(model, instanceName string) error {
+	if _, exists := r.instances[instanceName]; !exists {
+		return fmt.Errorf("instance not found: %s", instanceName)
+	}
+
+	r.modelPins[model] = instanceName
+	return nil
+}
+
+// ApplyModelPins registers the instances referenced by routing.model_pins,
+// backed by the registered provider for each instance's type, and pins the models
+func (r *ModelRouter) ApplyModelPins(config *instance.Config) error {
+	for model, name := range config.Routing.ModelPins {
+		inst, err := config.GetInstanceByName(name)
+		if err != nil {
+			return fmt.Errorf("model %s: %w", model, err)
+		}
+
+		if _, exists := r.instances[name]; !exists {
+			provider, ok := r.providers[inst.Type]
+			if !ok {
+				return fmt.Errorf("model %s: provider %s for instance %s not registered", model, inst.Type, name)
+			}
+			if err := r.RegisterInstance(name, provider); err != nil {
+				return err
+			}
+		}
+
+		if err := r.PinModel(model, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPinnedInstance returns the instance a model is pinned to, if any
+func (r *ModelRouter) GetPinnedInstance(model string) (string, bool) {
+	name, ok := r.modelPins[model]
+	return name, ok
+}
+
 // RouteModel routes a model to its provider
 func (r *ModelRouter) RouteModel(model string) (providers.Provider, error) {
+	// Model pins take precedence over provider-type routing
+	if instanceName, ok := r.modelPins[model]; ok {
+		return r.instances[instanceName], nil
+	}
+
 	// Try exact match first
 	if providerName, ok := r.modelMap[model]; ok {
 		return r.providers[providerName], nil
@@ -90,6 +152,7 @@ func (r *ModelRouter) matchModelPattern(model string) string {
 	bedrockPrefixes := []string{
 		"claude-",
 		"amazon.titan-",
+		"amazon.nova-",
 		"ai21.j2-",
 		"meta.llama",
 		"mistral.",
@@ -152,6 +215,11 @@ func (r *ModelRouter) ListProviders() []string {
 
 // GetProviderForModel returns the provider name for a model
 func (r *ModelRouter) GetProviderForModel(model string) string {
+	// Check model pins
+	if instanceName, ok := r.modelPins[model]; ok {
+		return r.instances[instanceName].Name()
+	}
+
 	// Check exact match
 	if providerName, ok := r.modelMap[model]; ok {
 		return providerName
@@ -1,7 +1,18 @@
 package router
 
 import (
+	"reflect"
 	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/anthropic"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/azure"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/bedrock"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/ibm"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/openai"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/oracle"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/vertex"
 )
 
 // TestMultiProviderRouting tests routing different models to their providers
@@ -35,6 +46,16 @@ func TestMultiProviderRouting(t *testing.T) {
 			model:            "amazon.titan-text-express-v1",
 			expectedProvider: "bedrock",
 		},
+		{
+			name:             "Amazon Nova Pro → Bedrock",
+			model:            "amazon.nova-pro-v1:0",
+			expectedProvider: "bedrock",
+		},
+		{
+			name:             "Amazon Nova Micro → Bedrock",
+			model:            "amazon.nova-micro-v1:0",
+			expectedProvider: "bedrock",
+		},
 
 		// OpenAI models
 		{
@@ -118,112 +139,109 @@ func TestModelRouterRegistration(t *testing.T) {
 	// Note: Actual provider registration will be tested with real provider implementations
 }
 
-// Capability constants for testing
-type ProviderCapabilities struct {
-	SupportsStreaming bool
-	SupportsVision    bool
-	SupportsTools     bool
-	MaxTokens         int
+// TestModelPins tests that model pins override provider-type routing
+func TestModelPins(t *testing.T) {
+	router := NewModelRouter()
+	bedrock := &stubProvider{name: "bedrock"}
+	if err := router.RegisterProvider(bedrock); err != nil {
+		t.Fatalf("RegisterProvider: %v", err)
+	}
+
+	east := &stubProvider{name: "bedrock"}
+	west := &stubProvider{name: "bedrock"}
+	if err := router.RegisterInstance("bedrock_us_east_1", east); err != nil {
+		t.Fatalf("RegisterInstance: %v", err)
+	}
+	if err := router.RegisterInstance("bedrock_us_west_2", west); err != nil {
+		t.Fatalf("RegisterInstance: %v", err)
+	}
+
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"bedrock_us_east_1": {Type: "bedrock"},
+			"bedrock_us_west_2": {Type: "bedrock"},
+			"bedrock_eu_west_1": {Type: "bedrock"},
+		},
+		Routing: instance.RoutingConfig{
+			ModelPins: map[string]string{
+				"claude-3-5-sonnet": "bedrock_us_east_1",
+				"claude-3-haiku":    "bedrock_us_west_2",
+				"claude-3-opus":     "bedrock_eu_west_1",
+			},
+		},
+	}
+	if err := router.ApplyModelPins(config); err != nil {
+		t.Fatalf("ApplyModelPins: %v", err)
+	}
+
+	tests := []struct {
+		model string
+		want  *stubProvider
+	}{
+		{"claude-3-5-sonnet", east},
+		{"claude-3-haiku", west},
+		{"claude-3-opus", bedrock},            // pinned instance backed by the type's provider
+		{"claude-3-sonnet-20240229", bedrock}, // unpinned falls back to pattern routing
+	}
+	for _, tt := range tests {
+		got, err := router.RouteModel(tt.model)
+		if err != nil {
+			t.Fatalf("RouteModel(%s): %v", tt.model, err)
+		}
+		if got != tt.want {
+			t.Errorf("RouteModel(%s): got wrong provider instance", tt.model)
+		}
+	}
+
+	if name, ok := router.GetPinnedInstance("claude-3-haiku"); !ok || name != "bedrock_us_west_2" {
+		t.Errorf("GetPinnedInstance: got %q, %v", name, ok)
+	}
+	if err := router.PinModel("gpt-4o", "missing"); err == nil {
+		t.Error("expected error pinning to an unregistered instance")
+	}
 }
 
-func getExpectedCapabilities(provider string) ProviderCapabilities {
-	// Expected capabilities for each provider
-	capabilities := map[string]ProviderCapabilities{
-		"bedrock": {
-			SupportsStreaming: true,
-			SupportsVision:    true,
-			SupportsTools:     true,
-			MaxTokens:         200000,
-		},
-		"openai": {
-			SupportsStreaming: true,
-			SupportsVision:    true,
-			SupportsTools:     true,
-			MaxTokens:         128000,
-		},
-		"anthropic": {
-			SupportsStreaming: true,
-			SupportsVision:    true,
-			SupportsTools:     true,
-			MaxTokens:         200000,
-		},
-		"vertex": {
-			SupportsStreaming: true,
-			SupportsVision:    true,
-			SupportsTools:     true,
-			MaxTokens:         32000,
-		},
-		"azure": {
-			SupportsStreaming: true,
-			SupportsVision:    true,
-			SupportsTools:     true,
-			MaxTokens:         128000,
-		},
-		"ibm": {
-			SupportsStreaming: false,
-			SupportsVision:    false,
-			SupportsTools:     false,
-			MaxTokens:         8192,
-		},
-		"oracle": {
-			SupportsStreaming: true,
-			SupportsVision:    false,
-			SupportsTools:     true,
-			MaxTokens:         4096,
-		},
-	}
-
-	if caps, ok := capabilities[provider]; ok {
-		return caps
-	}
-
-	// Default capabilities
-	return ProviderCapabilities{
-		SupportsStreaming: false,
-		SupportsVision:    false,
-		SupportsTools:     false,
-		MaxTokens:         4096,
+// TestValidateModelPins tests that unknown pinned instances fail validation
+func TestValidateModelPins(t *testing.T) {
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"bedrock_us_east_1": {Type: "bedrock"},
+		},
+		Routing: instance.RoutingConfig{
+			ModelPins: map[string]string{"claude-3-haiku": "bedrock_us_west_2"},
+		},
+	}
+	if err := config.Validate(); err == nil {
+		t.Error("expected error for pin to unknown instance")
+	}
+
+	config.Routing.ModelPins = map[string]string{"claude-3-haiku": "bedrock_us_east_1"}
+	if err := config.Validate(); err != nil {
+		t.Errorf("valid pins rejected: %v", err)
 	}
 }
 
-// TestProviderCapabilities tests expected provider capabilities
+// TestProviderCapabilities pins the capabilities providers report
 func TestProviderCapabilities(t *testing.T) {
 	tests := []struct {
-		provider string
-		check    func(caps ProviderCapabilities) bool
-		desc     string
+		name     string
+		provider providers.Provider
+		want     providers.Capabilities
 	}{
-		{
-			provider: "bedrock",
-			check: func(caps ProviderCapabilities) bool {
-				return caps.SupportsStreaming && caps.SupportsVision &&
-					caps.SupportsTools && caps.MaxTokens == 200000
-			},
-			desc: "Bedrock should support streaming, vision, tools with 200k tokens",
-		},
-		{
-			provider: "openai",
-			check: func(caps ProviderCapabilities) bool {
-				return caps.SupportsStreaming && caps.SupportsVision &&
-					caps.SupportsTools && caps.MaxTokens == 128000
-			},
-			desc: "OpenAI should support streaming, vision, tools with 128k tokens",
-		},
-		{
-			provider: "ibm",
-			check: func(caps ProviderCapabilities) bool {
-				return !caps.SupportsStreaming && !caps.SupportsVision &&
-					!caps.SupportsTools && caps.MaxTokens == 8192
-			},
-			desc: "IBM should not support streaming/vision/tools, 8k tokens",
-		},
+		{"bedrock", &bedrock.BedrockProvider{}, providers.Capabilities{Streaming: true, Vision: true, Video: true, Tools: true, ParallelToolCalls: true, MaxContextTokens: 200000, ServiceTiers: []string{"default", "priority"}}},
+		{"openai", &openai.OpenAIProvider{}, providers.Capabilities{Streaming: true, Vision: true, Tools: true, ParallelToolCalls: true, MaxContextTokens: 128000, ServiceTiers: []string{"auto", "default", "flex", "priority"}}},
+		{"anthropic", &anthropic.AnthropicProvider{}, providers.Capabilities{Streaming: true, Vision: true, Tools: true, ParallelToolCalls: true, MaxContextTokens: 200000, ServiceTiers: []string{"auto", "default"}}},
+		{"vertex", &vertex.VertexProvider{}, providers.Capabilities{Streaming: true, Vision: true, Tools: true, MaxContextTokens: 32000}},
+		{"azure", &azure.AzureProvider{}, providers.Capabilities{Streaming: true, Vision: true, Tools: true, ParallelToolCalls: true, MaxContextTokens: 128000}},
+		{"ibm", &ibm.IBMProvider{}, providers.Capabilities{MaxContextTokens: 8192}},
+		{"oracle", &oracle.OracleProvider{}, providers.Capabilities{Streaming: true, Tools: true, MaxContextTokens: 4096}},
+		{"default", &stubProvider{name: "custom"}, providers.DefaultCapabilities},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.provider, func(t *testing.T) {
-			caps := getExpectedCapabilities(tt.provider)
-			if !tt.check(caps) {
-				t.Errorf("%s: capabilities check failed", tt.desc)
+		t.Run(tt.name, func(t *testing.T) {
+			if got := providers.CapabilitiesOf(tt.provider); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("capabilities = %+v, want %+v", got, tt.want)
 			}
 		})
 	}
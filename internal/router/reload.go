@@ -0,0 +1,102 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
+)
+
+// Reload replaces the config in effect with cfg, after checking it with
+// ValidateConfig and ValidateAgainstProviders. A config that fails either
+// is rejected and the config in effect is kept. Model mappings, routing
+// and provider enablement take effect for the next request; providers are
+// not created or removed, and sections read at startup, such as pricing,
+// are unchanged until restart.
+func (r *Router) Reload(cfg *Config) error {
+	if err := cfg.ValidateConfig(); err != nil {
+		return err
+	}
+	if errs := ValidateAgainstProviders(cfg, r.providers); len(errs) > 0 {
+		return fmt.Errorf("configuration does not match the providers: %w", errors.Join(errs...))
+	}
+	r.config.Store(cfg)
+	return nil
+}
+
+// LoadConfigWatcher reloads the config file at path each time the process
+// receives SIGHUP, when onSignal is set, and, when interval is positive,
+// when the file's modification time changes, polled every interval. It
+// stops when ctx is cancelled. Failed reloads are logged and sent to the
+// notifier as config_reload_failed events; the config in effect is then
+// kept.
+func (r *Router) LoadConfigWatcher(ctx context.Context, path string, interval time.Duration, onSignal bool) {
+	var signals <-chan os.Signal
+	stop := func() {}
+	if onSignal {
+		signals, stop = reloadSignals()
+	}
+	var ticks <-chan time.Time
+	var ticker *time.Ticker
+	if interval > 0 {
+		ticker = time.NewTicker(interval)
+		ticks = ticker.C
+	}
+	modTime := fileModTime(path)
+
+	go func() {
+		defer stop()
+		if ticker != nil {
+			defer ticker.Stop()
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-signals:
+			case <-ticks:
+				current := fileModTime(path)
+				if current.Equal(modTime) {
+					continue
+				}
+				modTime = current
+			}
+			r.reloadFile(path)
+		}
+	}()
+}
+
+// reloadFile loads and applies the config file at path
+func (r *Router) reloadFile(path string) {
+	cfg, err := LoadConfig(path)
+	if err == nil {
+		err = r.Reload(cfg)
+	}
+	if err != nil {
+		log.Printf("Router config reload from %s failed, keeping the current config: %v", path, err)
+		r.notifier.Notify(notify.Event{
+			Type:    notify.EventConfigReloadFailed,
+			Subject: path,
+			Message: fmt.Sprintf("router config reload failed: %v", err),
+		})
+		return
+	}
+	log.Printf("Router config reloaded from %s (%d models)", path, len(cfg.ModelMappings))
+}
+
+// fileModTime returns a file's modification time, or the zero time if it
+// cannot be read
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,14 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !unix
+
+package router
+
+import "os"
+
+// reloadSignals returns a nil channel on platforms without SIGHUP; reloads
+// are then only triggered by file changes
+func reloadSignals() (<-chan os.Signal, func()) {
+	return nil, func() {}
+}
@@ -0,0 +1,20 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build unix
+
+package router
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// reloadSignals returns a channel receiving SIGHUP and a function to stop
+// receiving it
+func reloadSignals() (<-chan os.Signal, func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	return signals, func() { signal.Stop(signals) }
+}
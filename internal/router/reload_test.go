@@ -0,0 +1,132 @@
+package router
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// capableProvider is a stubProvider reporting capabilities
+type capableProvider struct {
+	stubProvider
+	capabilities providers.Capabilities
+}
+
+func (p *capableProvider) Capabilities() providers.Capabilities { return p.capabilities }
+
+func reloadTestRegistry() map[string]providers.Provider {
+	return map[string]providers.Provider{
+		"openai": &capableProvider{stubProvider: stubProvider{name: "openai"},
+			capabilities: providers.Capabilities{Vision: true, Tools: true, Streaming: true, MaxContextTokens: 128000}},
+		"ibm": &stubProvider{name: "ibm"},
+	}
+}
+
+func TestValidateAgainstProviders(t *testing.T) {
+	cfg := &Config{
+		ModelMappings: map[string]ModelMapping{
+			"gpt-4o": {DefaultProvider: "openai", Requires: []string{"vision", "tools"},
+				Providers: map[string]ProviderModelInfo{"openai": {Model: "gpt-4o", MaxOutputTokens: 16384}}},
+			"granite": {DefaultProvider: "ibm", Requires: []string{"tools", "telepathy"},
+				Providers: map[string]ProviderModelInfo{"ibm": {Model: "granite-13b", MaxOutputTokens: 8192}}},
+			"claude": {DefaultProvider: "anthropic",
+				Providers: map[string]ProviderModelInfo{"anthropic": {Model: "claude-3-haiku"}, "vertex": {Model: "claude-3-haiku@20240307"}}},
+		},
+		Providers: map[string]ProviderConfig{
+			"openai":    {Enabled: true},
+			"ibm":       {Enabled: true},
+			"anthropic": {Enabled: true},
+			"vertex":    {Enabled: false},
+		},
+	}
+
+	var got []string
+	for _, err := range ValidateAgainstProviders(cfg, reloadTestRegistry()) {
+		got = append(got, err.Error())
+	}
+	want := []string{
+		`model "claude" is mapped to provider "anthropic", which is not registered`,
+		`model "granite" requires unknown capability "telepathy"`,
+		`model "granite" max_output_tokens 8192 exceeds provider "ibm"'s 4096 token context window`,
+		`model "granite" requires tools, which provider "ibm" does not support`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("errors:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReload(t *testing.T) {
+	mapping := func(provider string) *Config {
+		return &Config{
+			ModelMappings: map[string]ModelMapping{
+				"chat": {DefaultProvider: provider, Requires: []string{"vision"},
+					Providers: map[string]ProviderModelInfo{provider: {Model: "chat-" + provider}}},
+			},
+			Providers: map[string]ProviderConfig{"openai": {Enabled: true}, "ibm": {Enabled: true}},
+		}
+	}
+	r, err := NewRouter(mapping("openai"), reloadTestRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ibm cannot take images, so the reload is rejected
+	if err := r.Reload(mapping("ibm")); err == nil || !strings.Contains(err.Error(), "requires vision") {
+		t.Errorf("Reload() error = %v, want a vision error", err)
+	}
+	if _, info, err := r.RouteRequest(context.Background(), "chat", ""); err != nil || info.Model != "chat-openai" {
+		t.Errorf("after a rejected reload, chat routes to %v (%v), want the current config", info, err)
+	}
+
+	valid := mapping("openai")
+	valid.ModelMappings["chat"].Providers["openai"] = ProviderModelInfo{Model: "gpt-4o"}
+	if err := r.Reload(valid); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if _, info, err := r.RouteRequest(context.Background(), "chat", ""); err != nil || info.Model != "gpt-4o" {
+		t.Errorf("after reload, chat routes to %v (%v), want gpt-4o", info, err)
+	}
+}
+
+func TestLoadConfigWatcher(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model-mapping.yaml")
+	write := func(model string, modTime time.Time) {
+		t.Helper()
+		config := "model_mappings:\n  chat:\n    default_provider: openai\n    providers:\n      openai:\n        model: " + model +
+			"\nproviders:\n  openai:\n    enabled: true\n"
+		if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	start := time.Now().Add(-time.Hour)
+	write("gpt-4o", start)
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewRouter(cfg, reloadTestRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r.LoadConfigWatcher(ctx, path, 5*time.Millisecond, false)
+
+	write("gpt-4o-mini", start.Add(time.Minute))
+	deadline := time.Now().Add(2 * time.Second)
+	for r.GetConfig().ModelMappings["chat"].Providers["openai"].Model != "gpt-4o-mini" {
+		if time.Now().After(deadline) {
+			t.Fatal("config was not reloaded after the file changed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
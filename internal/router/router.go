@@ -7,14 +7,42 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sort"
+	"sync"
+	"sync/atomic"
 
+	"github.com/tosharewith/llmproxy_auth/internal/health"
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
 )
 
 // Router handles routing requests to appropriate providers
 type Router struct {
-	config    *Config
+	config    atomic.Pointer[Config] // swapped by Reload
 	providers map[string]providers.Provider
+
+	mu       sync.RWMutex
+	ejected  map[string]error // providers removed from routing by health checks
+	required map[string]bool  // providers marked required outside the router config
+
+	// checks debounces health check results before providers are ejected
+	// or restored
+	checks *health.CheckTracker
+
+	// notifier is told when providers are ejected and restored
+	notifier *notify.Notifier
+
+	draining sync.Map // provider name -> *atomic.Bool, set by Drain
+}
+
+// ProviderHealth reports the health of a single provider
+type ProviderHealth struct {
+	Name     string `json:"name"`
+	Healthy  bool   `json:"healthy"`
+	Required bool   `json:"required"`
+	Draining bool   `json:"draining"`
+	Error    string `json:"error,omitempty"`
+	Latency  string `json:"latency,omitempty"` // Health check duration (warm-up only)
 }
 
 // NewRouter creates a new router with the given configuration
@@ -24,10 +52,27 @@ func NewRouter(config *Config, providerRegistry map[string]providers.Provider) (
 		return nil, fmt.Errorf("invalid configuration: %w", err)
 	}
 
-	return &Router{
-		config:    config,
+	r := &Router{
 		providers: providerRegistry,
-	}, nil
+		ejected:   make(map[string]error),
+		required:  make(map[string]bool),
+		checks:    health.NewCheckTracker(health.DefaultThresholds()),
+	}
+	r.config.Store(config)
+	return r, nil
+}
+
+// SetHealthThresholds sets how many consecutive failed health checks eject
+// a provider and how many successful ones restore it. It must be called
+// before health checks run.
+func (r *Router) SetHealthThresholds(thresholds health.Thresholds) {
+	r.checks = health.NewCheckTracker(thresholds)
+}
+
+// SetNotifier sends circuit open and close events to webhooks when
+// providers are ejected from routing and restored
+func (r *Router) SetNotifier(notifier *notify.Notifier) {
+	r.notifier = notifier
 }
 
 // RouteRequest determines which provider should handle a request
@@ -41,7 +86,8 @@ func (r *Router) RouteRequest(ctx context.Context, modelName string, preferredPr
 	}
 
 	// Get default provider for the model
-	defaultProvider := r.config.GetDefaultProvider(modelName)
+	config := r.GetConfig()
+	defaultProvider := config.GetDefaultProvider(modelName)
 	if defaultProvider == "" {
 		return nil, nil, fmt.Errorf("no provider found for model %q", modelName)
 	}
@@ -53,19 +99,24 @@ func (r *Router) RouteRequest(ctx context.Context, modelName string, preferredPr
 	}
 
 	// If auto-fallback is disabled, return the error
-	if !r.config.Features.AutoFallback || !r.config.Routing.Fallback.Enabled {
+	if !config.Features.AutoFallback || !config.Routing.Fallback.Enabled {
 		return nil, nil, fmt.Errorf("provider %q failed for model %q: %w", defaultProvider, modelName, err)
 	}
 
 	// Try fallback providers
 	log.Printf("Default provider %q failed for model %q, attempting fallback", defaultProvider, modelName)
-	return r.tryFallbackProviders(ctx, modelName, defaultProvider)
+	provider, modelInfo, fallbackErr := r.tryFallbackProviders(ctx, modelName, defaultProvider)
+	if fallbackErr != nil {
+		// Keep the default provider's error so callers can tell why it was skipped
+		return nil, nil, fmt.Errorf("%v (provider %q: %w)", fallbackErr, defaultProvider, err)
+	}
+	return provider, modelInfo, nil
 }
 
 // getProviderForModel gets a specific provider for a model
 func (r *Router) getProviderForModel(modelName, providerName string) (providers.Provider, *ProviderModelInfo, error) {
 	// Check if provider is enabled
-	if !r.config.IsProviderEnabled(providerName) {
+	if !r.GetConfig().IsProviderEnabled(providerName) {
 		return nil, nil, fmt.Errorf("provider %q is disabled", providerName)
 	}
 
@@ -75,8 +126,18 @@ func (r *Router) getProviderForModel(modelName, providerName string) (providers.
 		return nil, nil, fmt.Errorf("provider %q not registered", providerName)
 	}
 
+	// Skip providers ejected by the last health check
+	if err := r.ejectedErr(providerName); err != nil {
+		return nil, nil, fmt.Errorf("provider %q is unhealthy: %w", providerName, err)
+	}
+
+	// Skip providers drained by an operator
+	if r.IsDraining(providerName) {
+		return nil, nil, fmt.Errorf("provider %q: %w", providerName, ErrProviderDraining)
+	}
+
 	// Get model info for this provider
-	modelInfo, err := r.config.GetProviderModelInfo(modelName, providerName)
+	modelInfo, err := r.GetConfig().GetProviderModelInfo(modelName, providerName)
 	if err != nil {
 		return nil, nil, fmt.Errorf("model %q not available on provider %q: %w", modelName, providerName, err)
 	}
@@ -86,9 +147,10 @@ func (r *Router) getProviderForModel(modelName, providerName string) (providers.
 
 // tryFallbackProviders attempts to find an alternative provider
 func (r *Router) tryFallbackProviders(ctx context.Context, modelName, excludeProvider string) (providers.Provider, *ProviderModelInfo, error) {
-	fallbackProviders := r.config.GetFallbackProviders()
+	config := r.GetConfig()
+	fallbackProviders := config.GetFallbackProviders()
 	attempts := 0
-	maxAttempts := r.config.Routing.Fallback.MaxAttempts
+	maxAttempts := config.Routing.Fallback.MaxAttempts
 
 	for _, providerName := range fallbackProviders {
 		// Skip the failed provider
@@ -117,7 +179,7 @@ func (r *Router) tryFallbackProviders(ctx context.Context, modelName, excludePro
 
 // GetProvider gets a provider by name
 func (r *Router) GetProvider(providerName string) (providers.Provider, error) {
-	if !r.config.IsProviderEnabled(providerName) {
+	if !r.GetConfig().IsProviderEnabled(providerName) {
 		return nil, fmt.Errorf("provider %q is disabled", providerName)
 	}
 
@@ -134,9 +196,10 @@ func (r *Router) ListModels(ctx context.Context) ([]providers.Model, error) {
 	var allModels []providers.Model
 
 	// Get models from configuration
-	for modelName, mapping := range r.config.ModelMappings {
+	config := r.GetConfig()
+	for modelName, mapping := range config.ModelMappings {
 		// Only include models whose default provider is enabled
-		if !r.config.IsProviderEnabled(mapping.DefaultProvider) {
+		if !config.IsProviderEnabled(mapping.DefaultProvider) {
 			continue
 		}
 
@@ -168,7 +231,7 @@ func (r *Router) ListModels(ctx context.Context) ([]providers.Model, error) {
 // GetModelInfo gets information about a specific model
 func (r *Router) GetModelInfo(ctx context.Context, modelName string) (*providers.Model, error) {
 	// Get default provider for the model
-	defaultProvider := r.config.GetDefaultProvider(modelName)
+	defaultProvider := r.GetConfig().GetDefaultProvider(modelName)
 	if defaultProvider == "" {
 		return nil, fmt.Errorf("model %q not found", modelName)
 	}
@@ -188,7 +251,7 @@ func (r *Router) HealthCheck(ctx context.Context) map[string]error {
 	results := make(map[string]error)
 
 	for name, provider := range r.providers {
-		if !r.config.IsProviderEnabled(name) {
+		if !r.GetConfig().IsProviderEnabled(name) {
 			continue
 		}
 
@@ -196,12 +259,174 @@ func (r *Router) HealthCheck(ctx context.Context) map[string]error {
 		results[name] = err
 	}
 
+	r.updateEjected(results)
+
 	return results
 }
 
-// GetConfig returns the router configuration
+// CheckProviders health-checks all enabled providers, ejecting unhealthy ones
+// from routing, and returns per-provider status sorted by name
+func (r *Router) CheckProviders(ctx context.Context) []ProviderHealth {
+	results := r.HealthCheck(ctx)
+
+	statuses := make([]ProviderHealth, 0, len(results))
+	for name, err := range results {
+		statuses = append(statuses, r.providerHealth(name, err))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+	return statuses
+}
+
+// CheckProvider health-checks a single enabled provider, ejecting it from
+// routing if unhealthy. It reports false if the provider is not registered
+// or is disabled.
+func (r *Router) CheckProvider(ctx context.Context, name string) (ProviderHealth, bool) {
+	provider, exists := r.providers[name]
+	if !exists || !r.GetConfig().IsProviderEnabled(name) {
+		return ProviderHealth{}, false
+	}
+
+	err := provider.HealthCheck(ctx)
+	r.updateEjected(map[string]error{name: err})
+	return r.providerHealth(name, err), true
+}
+
+// providerHealth reports a provider's state after a health check: healthy
+// unless ejected, with the error of the check or, if it passed while the
+// provider is still ejected, of the check that ejected it
+func (r *Router) providerHealth(name string, err error) ProviderHealth {
+	ejectedErr := r.ejectedErr(name)
+	status := ProviderHealth{
+		Name:     name,
+		Healthy:  ejectedErr == nil,
+		Required: r.IsProviderRequired(name),
+		Draining: r.IsDraining(name),
+	}
+	if err == nil {
+		err = ejectedErr
+	}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	return status
+}
+
+// IsReady reports whether every required provider in statuses is healthy.
+// Optional provider failures do not affect readiness.
+func IsReady(statuses []ProviderHealth) bool {
+	for _, status := range statuses {
+		if status.Required && !status.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// RequireProvider marks a provider as required for readiness (e.g. because
+// a routing default instance uses it), unless the router config sets its
+// `required` explicitly
+func (r *Router) RequireProvider(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.required[name] = true
+}
+
+// IsProviderRequired reports whether a provider's health gates readiness
+func (r *Router) IsProviderRequired(name string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.isRequired(r.GetConfig(), name)
+}
+
+// isRequired reports whether a provider is required: by an explicit
+// `required` in config, else by RequireProvider or config's defaults.
+// Callers hold r.mu.
+func (r *Router) isRequired(config *Config, name string) bool {
+	if required, ok := config.ExplicitlyRequired(name); ok {
+		return required
+	}
+	return r.required[name] || config.IsProviderRequired(name)
+}
+
+// updateEjected records health check results, ejecting providers that
+// failed enough consecutive checks and restoring those that passed enough
+func (r *Router) updateEjected(results map[string]error) {
+	r.setEjected(results, r.checks.Observe)
+}
+
+// seedEjected ejects or restores providers on a single check result each
+func (r *Router) seedEjected(results map[string]error) {
+	r.setEjected(results, r.checks.Seed)
+}
+
+func (r *Router) setEjected(results map[string]error, observe func(string, error) health.CheckState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for name, err := range results {
+		state := observe(name, err)
+		if !state.Healthy {
+			if _, already := r.ejected[name]; !already {
+				log.Printf("Ejecting provider %q from routing after %d failed health checks: %v",
+					name, state.ConsecutiveFailures, state.LastError)
+				r.notifier.Notify(notify.Event{
+					Type:    notify.EventCircuitOpen,
+					Subject: name,
+					Message: fmt.Sprintf("ejected from routing after %d failed health checks: %v",
+						state.ConsecutiveFailures, state.LastError),
+				})
+			}
+			r.ejected[name] = state.LastError
+		} else if _, wasEjected := r.ejected[name]; wasEjected {
+			log.Printf("Provider %q is healthy again, restoring to routing", name)
+			delete(r.ejected, name)
+			r.notifier.Notify(notify.Event{
+				Type:    notify.EventCircuitClose,
+				Subject: name,
+				Message: "healthy again, restored to routing",
+			})
+		}
+	}
+}
+
+// ejectedErr returns the health check error for an ejected provider, or nil
+func (r *Router) ejectedErr(name string) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.ejected[name]
+}
+
+// ProviderStates returns the last known health of each enabled provider
+// without running health checks (ejected providers are unhealthy)
+func (r *Router) ProviderStates() []ProviderHealth {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	config := r.GetConfig()
+	states := make([]ProviderHealth, 0, len(r.providers))
+	for name := range r.providers {
+		if !config.IsProviderEnabled(name) {
+			continue
+		}
+		state := ProviderHealth{
+			Name:     name,
+			Healthy:  r.ejected[name] == nil,
+			Required: r.isRequired(config, name),
+			Draining: r.IsDraining(name),
+		}
+		if err := r.ejected[name]; err != nil {
+			state.Error = err.Error()
+		}
+		states = append(states, state)
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states
+}
+
+// GetConfig returns the router configuration in effect
 func (r *Router) GetConfig() *Config {
-	return r.config
+	return r.config.Load()
 }
 
 // RegisterProvider registers a new provider (useful for testing)
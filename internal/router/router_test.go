@@ -0,0 +1,316 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tosharewith/llmproxy_auth/internal/health"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// stubProvider is a minimal provider whose health check result is configurable
+type stubProvider struct {
+	name      string
+	healthErr error
+}
+
+func (p *stubProvider) Name() string { return p.name }
+
+func (p *stubProvider) HealthCheck(ctx context.Context) error { return p.healthErr }
+
+func (p *stubProvider) Invoke(ctx context.Context, req *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	return &providers.ProviderResponse{StatusCode: 200}, nil
+}
+
+func (p *stubProvider) InvokeStreaming(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *stubProvider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	return nil, nil
+}
+
+func (p *stubProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return nil, errors.New("not found")
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// newReadinessTestRouter builds a router where bedrock is a routing default,
+// anthropic is only a fallback, and vertex is explicitly optional
+func newReadinessTestRouter(t *testing.T, health map[string]error) *Router {
+	t.Helper()
+
+	config := &Config{
+		ModelMappings: map[string]ModelMapping{
+			"claude-3-sonnet": {
+				DefaultProvider: "bedrock",
+				Providers: map[string]ProviderModelInfo{
+					"bedrock":   {Model: "anthropic.claude-3-sonnet-20240229-v1:0"},
+					"anthropic": {Model: "claude-3-sonnet-20240229"},
+				},
+			},
+			"gemini-pro": {
+				DefaultProvider: "vertex",
+				Providers: map[string]ProviderModelInfo{
+					"vertex": {Model: "gemini-1.5-pro"},
+				},
+			},
+		},
+		Routing: RoutingConfig{
+			Fallback: FallbackConfig{Enabled: true, Providers: []string{"anthropic"}, MaxAttempts: 2},
+		},
+		Providers: map[string]ProviderConfig{
+			"bedrock":   {Enabled: true},
+			"anthropic": {Enabled: true},
+			"vertex":    {Enabled: true, Required: boolPtr(false)},
+		},
+		Features: FeatureFlags{AutoFallback: true},
+	}
+
+	registry := make(map[string]providers.Provider)
+	for _, name := range []string{"bedrock", "anthropic", "vertex"} {
+		registry[name] = &stubProvider{name: name, healthErr: health[name]}
+	}
+
+	r, err := NewRouter(config, registry)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	return r
+}
+
+// TestIsProviderRequired tests the default and explicit required flags
+func TestIsProviderRequired(t *testing.T) {
+	r := newReadinessTestRouter(t, nil)
+
+	tests := []struct {
+		provider string
+		required bool
+	}{
+		{"bedrock", true},    // routing default
+		{"anthropic", false}, // fallback only
+		{"vertex", false},    // routing default, but explicitly optional
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			if got := r.IsProviderRequired(tt.provider); got != tt.required {
+				t.Errorf("IsProviderRequired(%q): got %v, want %v", tt.provider, got, tt.required)
+			}
+		})
+	}
+
+	r.RequireProvider("anthropic")
+	if !r.IsProviderRequired("anthropic") {
+		t.Error("anthropic should be required after RequireProvider")
+	}
+
+	// An explicit required: false in the router config wins
+	r.RequireProvider("vertex")
+	if r.IsProviderRequired("vertex") {
+		t.Error("vertex is explicitly optional, but RequireProvider made it required")
+	}
+	for _, state := range r.ProviderStates() {
+		if state.Name == "vertex" && state.Required {
+			t.Error("ProviderStates reports the explicitly optional vertex as required")
+		}
+	}
+}
+
+// TestReadinessMixedProviders tests that only required provider failures fail readiness
+func TestReadinessMixedProviders(t *testing.T) {
+	tests := []struct {
+		name   string
+		health map[string]error
+		ready  bool
+	}{
+		{
+			name:  "all healthy",
+			ready: true,
+		},
+		{
+			name:   "optional providers unhealthy",
+			health: map[string]error{"anthropic": errors.New("timeout"), "vertex": errors.New("403")},
+			ready:  true,
+		},
+		{
+			name:   "required provider unhealthy",
+			health: map[string]error{"bedrock": errors.New("throttled")},
+			ready:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newReadinessTestRouter(t, tt.health)
+			statuses := r.CheckProviders(context.Background())
+
+			if len(statuses) != 3 {
+				t.Fatalf("expected 3 provider statuses, got %d", len(statuses))
+			}
+			if got := IsReady(statuses); got != tt.ready {
+				t.Errorf("IsReady: got %v, want %v (statuses: %+v)", got, tt.ready, statuses)
+			}
+		})
+	}
+}
+
+// TestUnhealthyProviderEjection tests that unhealthy providers are skipped by routing
+func TestUnhealthyProviderEjection(t *testing.T) {
+	r := newReadinessTestRouter(t, map[string]error{"bedrock": errors.New("throttled")})
+	r.CheckProviders(context.Background())
+
+	provider, _, err := r.RouteRequest(context.Background(), "claude-3-sonnet", "")
+	if err != nil {
+		t.Fatalf("RouteRequest: %v", err)
+	}
+	if provider.Name() != "anthropic" {
+		t.Errorf("expected fallback to anthropic, got %q", provider.Name())
+	}
+
+	// Provider recovers: next health check restores it
+	r.providers["bedrock"].(*stubProvider).healthErr = nil
+	r.CheckProviders(context.Background())
+
+	provider, _, err = r.RouteRequest(context.Background(), "claude-3-sonnet", "")
+	if err != nil {
+		t.Fatalf("RouteRequest after recovery: %v", err)
+	}
+	if provider.Name() != "bedrock" {
+		t.Errorf("expected bedrock after recovery, got %q", provider.Name())
+	}
+}
+
+// TestWarmUpSeedsEjection tests that warm-up results are reported and seed routing
+func TestWarmUpSeedsEjection(t *testing.T) {
+	r := newReadinessTestRouter(t, map[string]error{"bedrock": errors.New("no credentials")})
+
+	statuses := r.WarmUp(context.Background(), time.Second)
+	if len(statuses) != 3 {
+		t.Fatalf("expected 3 provider statuses, got %d", len(statuses))
+	}
+	for _, status := range statuses {
+		if status.Latency == "" {
+			t.Errorf("%s: expected latency to be reported", status.Name)
+		}
+	}
+	if IsReady(statuses) {
+		t.Error("expected not ready when required bedrock fails warm-up")
+	}
+
+	provider, _, err := r.RouteRequest(context.Background(), "claude-3-sonnet", "")
+	if err != nil {
+		t.Fatalf("RouteRequest: %v", err)
+	}
+	if provider.Name() != "anthropic" {
+		t.Errorf("expected bedrock to be ejected after warm-up, routed to %q", provider.Name())
+	}
+}
+
+func TestDrainProvider(t *testing.T) {
+	r := newReadinessTestRouter(t, nil)
+	ctx := context.Background()
+
+	if !r.Drain("bedrock") {
+		t.Fatal("Drain(bedrock) = false")
+	}
+	if r.Drain("unknown") {
+		t.Error("Drain(unknown) = true, want false")
+	}
+
+	// New requests fail over to the fallback provider
+	provider, _, err := r.RouteRequest(ctx, "claude-3-sonnet", "")
+	if err != nil || provider.Name() != "anthropic" {
+		t.Fatalf("RouteRequest = %v, %v, want anthropic fallback", provider, err)
+	}
+
+	// A drained provider with no alternate reports why
+	r.Drain("vertex")
+	if _, _, err := r.RouteRequest(ctx, "gemini-pro", ""); !errors.Is(err, ErrProviderDraining) {
+		t.Errorf("RouteRequest(gemini-pro) error = %v, want ErrProviderDraining", err)
+	}
+
+	for _, state := range r.ProviderStates() {
+		if want := state.Name != "anthropic"; state.Draining != want {
+			t.Errorf("%s draining = %v, want %v", state.Name, state.Draining, want)
+		}
+	}
+
+	r.Restore("bedrock")
+	provider, _, err = r.RouteRequest(ctx, "claude-3-sonnet", "")
+	if err != nil || provider.Name() != "bedrock" {
+		t.Errorf("after Restore, RouteRequest = %v, %v, want bedrock", provider, err)
+	}
+	if r.IsDraining("bedrock") {
+		t.Error("bedrock still draining after Restore")
+	}
+}
+
+func TestLoadConfigExpandsEnv(t *testing.T) {
+	t.Setenv("PROXY_AZURE_ENDPOINT", "https://example.openai.azure.com")
+	path := filepath.Join(t.TempDir(), "router.yaml")
+	config := `providers:
+  bedrock:
+    enabled: true
+    region: ${PROXY_BEDROCK_REGION:-us-east-1}
+  azure:
+    enabled: true
+    endpoint: ${PROXY_AZURE_ENDPOINT:?Azure endpoint}
+`
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+	if got := cfg.Providers["bedrock"].Region; got != "us-east-1" {
+		t.Errorf("bedrock region = %q, want default us-east-1", got)
+	}
+	if got := cfg.Providers["azure"].Endpoint; got != "https://example.openai.azure.com" {
+		t.Errorf("azure endpoint = %q", got)
+	}
+
+	os.Unsetenv("PROXY_AZURE_ENDPOINT")
+	_, err = LoadConfig(path)
+	var missing *instance.MissingEnvError
+	if !errors.As(err, &missing) || missing.Name != "PROXY_AZURE_ENDPOINT" {
+		t.Errorf("LoadConfig() error = %v, want a MissingEnvError for PROXY_AZURE_ENDPOINT", err)
+	}
+}
+
+// TestHealthThresholds tests that ejection and recovery wait for the
+// configured runs of failed and successful health checks
+func TestHealthThresholds(t *testing.T) {
+	r := newReadinessTestRouter(t, nil)
+	r.SetHealthThresholds(health.Thresholds{FailureThreshold: 3, SuccessThreshold: 2})
+	bedrock := r.providers["bedrock"].(*stubProvider)
+
+	bedrock.healthErr = errors.New("throttled")
+	for i := 1; i <= 3; i++ {
+		status, _ := r.CheckProvider(context.Background(), "bedrock")
+		if status.Healthy != (i < 3) {
+			t.Errorf("after %d failed checks: healthy = %v", i, status.Healthy)
+		}
+		if status.Error == "" {
+			t.Errorf("after %d failed checks: error not reported", i)
+		}
+	}
+
+	bedrock.healthErr = nil
+	status, _ := r.CheckProvider(context.Background(), "bedrock")
+	if status.Healthy || status.Error != "throttled" {
+		t.Errorf("after 1 successful check: %+v, want still ejected with the ejecting error", status)
+	}
+	if status, _ := r.CheckProvider(context.Background(), "bedrock"); !status.Healthy {
+		t.Errorf("after 2 successful checks: %+v, want restored", status)
+	}
+}
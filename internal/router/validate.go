@@ -0,0 +1,74 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// requiredCapabilities are the capabilities model mappings may require,
+// with how to check a provider for them
+var requiredCapabilities = map[string]func(providers.Capabilities) bool{
+	"vision":    func(c providers.Capabilities) bool { return c.Vision },
+	"video":     func(c providers.Capabilities) bool { return c.Video },
+	"tools":     func(c providers.Capabilities) bool { return c.Tools },
+	"streaming": func(c providers.Capabilities) bool { return c.Streaming },
+}
+
+// ValidateAgainstProviders checks a config against the providers it would
+// route to, so a reload cannot introduce routes that fail on every
+// request. For each model, the default provider and every enabled mapped
+// provider must be in the registry, max_output_tokens must fit the
+// provider's context window, and the provider must have the capabilities
+// the model requires. Errors are sorted by model.
+func ValidateAgainstProviders(cfg *Config, registry map[string]providers.Provider) []error {
+	models := make([]string, 0, len(cfg.ModelMappings))
+	for model := range cfg.ModelMappings {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+
+	var errs []error
+	for _, model := range models {
+		mapping := cfg.ModelMappings[model]
+		for _, capability := range mapping.Requires {
+			if _, ok := requiredCapabilities[capability]; !ok {
+				errs = append(errs, fmt.Errorf("model %q requires unknown capability %q", model, capability))
+			}
+		}
+
+		names := make([]string, 0, len(mapping.Providers)+1)
+		if _, mapped := mapping.Providers[mapping.DefaultProvider]; !mapped && mapping.DefaultProvider != "" {
+			names = append(names, mapping.DefaultProvider)
+		}
+		for name := range mapping.Providers {
+			if name == mapping.DefaultProvider || cfg.IsProviderEnabled(name) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			provider, ok := registry[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("model %q is mapped to provider %q, which is not registered", model, name))
+				continue
+			}
+			capabilities := providers.CapabilitiesOf(provider)
+			if info, ok := mapping.Providers[name]; ok && info.MaxOutputTokens > capabilities.MaxContextTokens {
+				errs = append(errs, fmt.Errorf("model %q max_output_tokens %d exceeds provider %q's %d token context window",
+					model, info.MaxOutputTokens, name, capabilities.MaxContextTokens))
+			}
+			for _, capability := range mapping.Requires {
+				if supports, ok := requiredCapabilities[capability]; ok && !supports(capabilities) {
+					errs = append(errs, fmt.Errorf("model %q requires %s, which provider %q does not support", model, capability, name))
+				}
+			}
+		}
+	}
+	return errs
+}
@@ -0,0 +1,86 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package router
+
+import (
+	"context"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// WarmUp health-checks every enabled provider concurrently, each bounded by
+// timeout. A provider health check acquires credentials (STS/IAM/OAuth token
+// fetch) and makes a lightweight API call, so this primes DNS, TLS and
+// credential caches before traffic arrives. Results seed the ejection state
+// used by routing, regardless of the health check thresholds, and are
+// logged per provider.
+func (r *Router) WarmUp(ctx context.Context, timeout time.Duration) []ProviderHealth {
+	type result struct {
+		err     error
+		latency time.Duration
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]result)
+
+	for name, provider := range r.providers {
+		if !r.GetConfig().IsProviderEnabled(name) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(name string, check func(context.Context) error) {
+			defer wg.Done()
+
+			checkCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := check(checkCtx)
+
+			mu.Lock()
+			results[name] = result{err: err, latency: time.Since(start)}
+			mu.Unlock()
+		}(name, provider.HealthCheck)
+	}
+	wg.Wait()
+
+	errs := make(map[string]error, len(results))
+	statuses := make([]ProviderHealth, 0, len(results))
+	for name, res := range results {
+		errs[name] = res.err
+
+		status := ProviderHealth{
+			Name:     name,
+			Healthy:  res.err == nil,
+			Required: r.IsProviderRequired(name),
+			Draining: r.IsDraining(name),
+			Latency:  res.latency.Round(time.Millisecond).String(),
+		}
+		if res.err != nil {
+			status.Error = res.err.Error()
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+
+	r.seedEjected(errs)
+
+	for _, status := range statuses {
+		requirement := "optional"
+		if status.Required {
+			requirement = "required"
+		}
+		if status.Healthy {
+			log.Printf("✓ Warm-up %s (%s): reachable in %s", status.Name, requirement, status.Latency)
+		} else {
+			log.Printf("✗ Warm-up %s (%s): failed after %s: %s", status.Name, requirement, status.Latency, status.Error)
+		}
+	}
+
+	return statuses
+}
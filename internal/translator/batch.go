@@ -0,0 +1,240 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// BatchRequestLine is one line of an OpenAI batch input file
+type BatchRequestLine struct {
+	CustomID string                `json:"custom_id"`
+	Method   string                `json:"method"`
+	URL      string                `json:"url"`
+	Body     ChatCompletionRequest `json:"body"`
+}
+
+// BedrockBatchRecord is one line of a Bedrock batch inference input file
+type BedrockBatchRecord struct {
+	RecordID   string          `json:"recordId"`
+	ModelInput json.RawMessage `json:"modelInput"`
+}
+
+// BedrockBatchOutputRecord is one line of a Bedrock batch inference output
+// file: the input record with either the model output or an error
+type BedrockBatchOutputRecord struct {
+	RecordID    string           `json:"recordId"`
+	ModelInput  json.RawMessage  `json:"modelInput,omitempty"`
+	ModelOutput *BedrockResponse `json:"modelOutput,omitempty"`
+	Error       *struct {
+		ErrorCode    int    `json:"errorCode"`
+		ErrorMessage string `json:"errorMessage"`
+	} `json:"error,omitempty"`
+}
+
+// BatchResult is the outcome of one record of a batch job. Records that
+// failed, or whose output line could not be read, carry Error and no
+// Response.
+type BatchResult struct {
+	RecordID string                  `json:"record_id"`
+	Index    int                     `json:"index"` // Position in the input, -1 if the record ID is not one BatchTranslator assigned
+	Response *ChatCompletionResponse `json:"response,omitempty"`
+	Error    *BatchError             `json:"error,omitempty"`
+}
+
+// batchRecordIDFormat names records by input position. Bedrock record IDs
+// are 11 alphanumeric characters.
+const batchRecordIDFormat = "REC%08d"
+
+// BatchTranslator converts chat completion requests to a Bedrock batch
+// inference input file and the job's output file back to chat completion
+// responses. Records are correlated by recordId, assigned from each
+// request's position in the input.
+type BatchTranslator struct{}
+
+// TranslateOpenAIBatchToBedrockJSONL encodes requests as Bedrock batch
+// input records. A batch job runs one model, so every request must name
+// the same Bedrock model; aliases and full model IDs are equivalent.
+func (BatchTranslator) TranslateOpenAIBatchToBedrockJSONL(requests []ChatCompletionRequest) ([]byte, error) {
+	if len(requests) == 0 {
+		return nil, fmt.Errorf("batch input is empty")
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	var model string
+	for i := range requests {
+		req := requests[i]
+		req.Stream = false
+		providerReq, modelID, err := TranslateOpenAIToBedrock(&req)
+		if err != nil {
+			return nil, fmt.Errorf("request %d: %w", i, err)
+		}
+		if model == "" {
+			model = modelID
+		} else if modelID != model {
+			return nil, fmt.Errorf("request %d: all requests in a batch must use the same model (%q != %q)", i, modelID, model)
+		}
+
+		record := BedrockBatchRecord{
+			RecordID:   fmt.Sprintf(batchRecordIDFormat, i),
+			ModelInput: providerReq.Body,
+		}
+		if err := encoder.Encode(record); err != nil {
+			return nil, fmt.Errorf("failed to encode batch record %d: %w", i, err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// TranslateBedrockJSONLToOpenAIBatch decodes a Bedrock batch output file.
+// Failed records and unreadable lines become results with Error set; the
+// rest of the file is still translated. Only a file that cannot be read at
+// all returns an error.
+func (BatchTranslator) TranslateBedrockJSONLToOpenAIBatch(raw []byte) ([]BatchResult, error) {
+	var results []BatchResult
+
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var record BedrockBatchOutputRecord
+		if err := json.Unmarshal(line, &record); err != nil {
+			results = append(results, BatchResult{
+				Index: -1,
+				Error: &BatchError{Code: "invalid_output", Message: fmt.Sprintf("line %d: invalid JSON: %v", lineNum, err)},
+			})
+			continue
+		}
+
+		result := BatchResult{RecordID: record.RecordID, Index: -1}
+		var index int
+		if n, err := fmt.Sscanf(record.RecordID, batchRecordIDFormat, &index); err == nil && n == 1 {
+			result.Index = index
+		}
+
+		switch {
+		case record.Error != nil:
+			result.Error = &BatchError{
+				Code:    fmt.Sprintf("%d", record.Error.ErrorCode),
+				Message: record.Error.ErrorMessage,
+			}
+		case record.ModelOutput == nil:
+			result.Error = &BatchError{Code: "missing_output", Message: fmt.Sprintf("line %d: record has neither modelOutput nor error", lineNum)}
+		default:
+			result.Response = TranslateBedrockToOpenAI(record.ModelOutput, record.ModelOutput.Model, record.ModelOutput.ID)
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read batch output: %w", err)
+	}
+	return results, nil
+}
+
+// ParseBatchJSONL parses an OpenAI batch input file.
+// Every line must target the same model; that model is returned.
+func ParseBatchJSONL(data []byte) ([]BatchRequestLine, string, error) {
+	var lines []BatchRequestLine
+	var model string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		var line BatchRequestLine
+		if err := json.Unmarshal(raw, &line); err != nil {
+			return nil, "", fmt.Errorf("line %d: invalid JSON: %w", lineNum, err)
+		}
+		if line.CustomID == "" {
+			return nil, "", fmt.Errorf("line %d: custom_id is required", lineNum)
+		}
+		if line.Body.Model == "" {
+			return nil, "", fmt.Errorf("line %d: body.model is required", lineNum)
+		}
+		if model == "" {
+			model = line.Body.Model
+		} else if line.Body.Model != model {
+			return nil, "", fmt.Errorf("line %d: all requests in a batch must use the same model (%q != %q)", lineNum, line.Body.Model, model)
+		}
+
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read batch input: %w", err)
+	}
+	if len(lines) == 0 {
+		return nil, "", fmt.Errorf("batch input is empty")
+	}
+
+	return lines, model, nil
+}
+
+// TranslateBatchToOpenAI re-encodes batch lines with the provider model name
+func TranslateBatchToOpenAI(lines []BatchRequestLine, providerModel string) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, line := range lines {
+		line.Body.Model = providerModel
+		if line.Method == "" {
+			line.Method = "POST"
+		}
+		if line.URL == "" {
+			line.URL = "/v1/chat/completions"
+		}
+		if err := encoder.Encode(line); err != nil {
+			return nil, fmt.Errorf("failed to encode batch line %q: %w", line.CustomID, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+// TranslateBatchToBedrock converts batch lines to Bedrock batch inference records.
+// Bedrock batch jobs take InvokeModel bodies, so only Anthropic models are supported.
+func TranslateBatchToBedrock(lines []BatchRequestLine, providerModel string) ([]byte, error) {
+	if !strings.HasPrefix(providerModel, "anthropic.") {
+		return nil, fmt.Errorf("batch inference on Bedrock is only supported for Anthropic models, got %q", providerModel)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+
+	for _, line := range lines {
+		line.Body.Model = providerModel
+		providerReq, _, err := TranslateOpenAIToBedrock(&line.Body)
+		if err != nil {
+			return nil, fmt.Errorf("record %q: %w", line.CustomID, err)
+		}
+
+		record := BedrockBatchRecord{
+			RecordID:   line.CustomID,
+			ModelInput: providerReq.Body,
+		}
+		if err := encoder.Encode(record); err != nil {
+			return nil, fmt.Errorf("failed to encode batch record %q: %w", line.CustomID, err)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,82 @@
+package translator
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestBatchTranslatorInput(t *testing.T) {
+	requests := []ChatCompletionRequest{
+		{Model: "claude-3-haiku", Messages: []ChatMessage{{Role: "user", Content: TextContent("one")}}},
+		{Model: "anthropic.claude-3-haiku-20240307-v1:0", Messages: []ChatMessage{{Role: "user", Content: TextContent("two")}}, Stream: true},
+	}
+	out, err := BatchTranslator{}.TranslateOpenAIBatchToBedrockJSONL(requests)
+	if err != nil {
+		t.Fatalf("TranslateOpenAIBatchToBedrockJSONL: %v", err)
+	}
+
+	var records []BedrockBatchRecord
+	scanner := bufio.NewScanner(bytes.NewReader(out))
+	for scanner.Scan() {
+		var record BedrockBatchRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("invalid record %s: %v", scanner.Bytes(), err)
+		}
+		records = append(records, record)
+	}
+	if len(records) != 2 || records[0].RecordID != "REC00000000" || records[1].RecordID != "REC00000001" {
+		t.Fatalf("records = %+v", records)
+	}
+	var input BedrockRequest
+	if err := json.Unmarshal(records[1].ModelInput, &input); err != nil {
+		t.Fatalf("modelInput: %v", err)
+	}
+	if input.AnthropicVersion == "" || input.Messages[0].Content != "two" {
+		t.Errorf("modelInput = %+v", input)
+	}
+
+	mixed := append(requests, ChatCompletionRequest{Model: "claude-3-sonnet", Messages: requests[0].Messages})
+	if _, err := (BatchTranslator{}).TranslateOpenAIBatchToBedrockJSONL(mixed); err == nil {
+		t.Error("batch with two models was accepted")
+	}
+	if _, err := (BatchTranslator{}).TranslateOpenAIBatchToBedrockJSONL(nil); err == nil {
+		t.Error("empty batch was accepted")
+	}
+}
+
+func TestBatchTranslatorOutput(t *testing.T) {
+	raw := []byte(`{"recordId":"REC00000000","modelInput":{},"modelOutput":{"id":"msg_1","model":"claude-3-haiku","content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}}
+{"recordId":"REC00000001","modelInput":{},"error":{"errorCode":400,"errorMessage":"messages: too long"}}
+not json
+
+{"recordId":"custom-id","modelInput":{}}
+`)
+	results, err := BatchTranslator{}.TranslateBedrockJSONLToOpenAIBatch(raw)
+	if err != nil {
+		t.Fatalf("TranslateBedrockJSONLToOpenAIBatch: %v", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4: %+v", len(results), results)
+	}
+
+	ok := results[0]
+	if ok.Index != 0 || ok.Error != nil || ok.Response == nil {
+		t.Fatalf("result 0 = %+v", ok)
+	}
+	if ok.Response.Choices[0].Message.Content.Text() != "hi" || ok.Response.Choices[0].FinishReason != "stop" || ok.Response.Usage.TotalTokens != 4 {
+		t.Errorf("response = %+v", ok.Response)
+	}
+
+	failed := results[1]
+	if failed.Index != 1 || failed.Response != nil || failed.Error == nil || failed.Error.Code != "400" || failed.Error.Message != "messages: too long" {
+		t.Errorf("result 1 = %+v", failed)
+	}
+	if results[2].Error == nil || results[2].Index != -1 {
+		t.Errorf("unreadable line = %+v", results[2])
+	}
+	if results[3].RecordID != "custom-id" || results[3].Index != -1 || results[3].Error == nil {
+		t.Errorf("record without output = %+v", results[3])
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers/bedrock"
+)
+
+// datedClaudeModel matches Anthropic API model names such as
+// claude-3-sonnet-20240229, optionally already carrying the anthropic.
+// prefix but not the Bedrock version suffix
+var datedClaudeModel = regexp.MustCompile(`^(anthropic\.)?(claude-[a-z0-9.-]+-\d{8})$`)
+
+// NormaliseBedrockModelID returns the full Bedrock model ID for a model
+// name, so that short aliases (claude-3-sonnet), Anthropic API names
+// (claude-3-sonnet-20240229) and Bedrock IDs
+// (anthropic.claude-3-sonnet-20240229-v1:0) name the same model. Names it
+// does not recognise are returned unchanged.
+func NormaliseBedrockModelID(id string) string {
+	id = strings.TrimSpace(id)
+	if modelID, ok := bedrock.BedrockModelIDMap[id]; ok {
+		return modelID
+	}
+	if m := datedClaudeModel.FindStringSubmatch(id); m != nil {
+		// Anthropic models on Bedrock are published as -v1:0 first
+		return "anthropic." + m[2] + "-v1:0"
+	}
+	return id
+}
@@ -6,6 +6,8 @@ package translator
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
@@ -21,6 +23,13 @@ type ConverseRequest struct {
 	InferenceConfig  *InferenceConfig          `json:"inferenceConfig,omitempty"`
 	ToolConfig       *ToolConfig               `json:"toolConfig,omitempty"`
 	AdditionalModelRequestFields map[string]interface{} `json:"additionalModelRequestFields,omitempty"`
+	RequestMetadata  map[string]string         `json:"requestMetadata,omitempty"`
+	PerformanceConfig *PerformanceConfig       `json:"performanceConfig,omitempty"`
+}
+
+// PerformanceConfig selects latency-optimized inference
+type PerformanceConfig struct {
+	Latency string `json:"latency"` // standard or optimized
 }
 
 // ConverseMessage represents a message in Converse API
@@ -33,9 +42,16 @@ type ConverseMessage struct {
 type ContentBlock struct {
 	Text     *string      `json:"text,omitempty"`
 	Image    *ImageBlock  `json:"image,omitempty"`
+	Video    *VideoBlock  `json:"video,omitempty"`
 	Document *DocumentBlock `json:"document,omitempty"`
 	ToolUse  *ToolUseBlock `json:"toolUse,omitempty"`
 	ToolResult *ToolResultBlock `json:"toolResult,omitempty"`
+	CachePoint *CachePointBlock `json:"cachePoint,omitempty"`
+}
+
+// CachePointBlock marks the end of a prompt prefix for Bedrock to cache
+type CachePointBlock struct {
+	Type string `json:"type"` // default
 }
 
 // ImageBlock represents an image
@@ -49,6 +65,24 @@ type ImageSource struct {
 	Bytes string `json:"bytes,omitempty"` // base64 encoded
 }
 
+// VideoBlock represents a video (Amazon Nova Pro and Lite)
+type VideoBlock struct {
+	Format string      `json:"format"` // mkv, mov, mp4, webm, flv, mpeg, mpg, wmv, three_gp
+	Source VideoSource `json:"source"`
+}
+
+// VideoSource represents video source, either inline bytes or an S3 location
+type VideoSource struct {
+	Bytes      string      `json:"bytes,omitempty"` // base64 encoded
+	S3Location *S3Location `json:"s3Location,omitempty"`
+}
+
+// S3Location references an object in S3
+type S3Location struct {
+	URI         string `json:"uri"`
+	BucketOwner string `json:"bucketOwner,omitempty"`
+}
+
 // DocumentBlock represents a document
 type DocumentBlock struct {
 	Format string         `json:"format"` // pdf, csv, doc, docx, xls, xlsx, html, txt, md
@@ -77,7 +111,8 @@ type ToolResultBlock struct {
 
 // SystemContentBlock represents system content
 type SystemContentBlock struct {
-	Text string `json:"text,omitempty"`
+	Text       string           `json:"text,omitempty"`
+	CachePoint *CachePointBlock `json:"cachePoint,omitempty"`
 }
 
 // InferenceConfig represents inference configuration
@@ -113,11 +148,16 @@ type ToolInputSchema struct {
 
 // ToolChoice represents how the model should use tools
 type ToolChoice struct {
-	Auto *struct{} `json:"auto,omitempty"`
-	Any  *struct{} `json:"any,omitempty"`
+	Auto *ToolChoiceMode `json:"auto,omitempty"`
+	Any  *ToolChoiceMode `json:"any,omitempty"`
 	Tool *ToolChoiceTool `json:"tool,omitempty"`
 }
 
+// ToolChoiceMode holds the options of the auto and any tool choices
+type ToolChoiceMode struct {
+	DisableParallelToolUse bool `json:"disableParallelToolUse,omitempty"`
+}
+
 // ToolChoiceTool represents a specific tool choice
 type ToolChoiceTool struct {
 	Name string `json:"name"`
@@ -138,9 +178,11 @@ type ConverseOutput struct {
 
 // ConverseUsage represents token usage
 type ConverseUsage struct {
-	InputTokens  int `json:"inputTokens"`
-	OutputTokens int `json:"outputTokens"`
-	TotalTokens  int `json:"totalTokens"`
+	InputTokens               int `json:"inputTokens"`
+	OutputTokens              int `json:"outputTokens"`
+	TotalTokens               int `json:"totalTokens"`
+	CacheReadInputTokenCount  int `json:"cacheReadInputTokenCount,omitempty"`
+	CacheWriteInputTokenCount int `json:"cacheWriteInputTokenCount,omitempty"`
 }
 
 // ConverseMetrics represents performance metrics
@@ -156,29 +198,57 @@ func TranslateOpenAIToConverseAPI(openaiReq *ChatCompletionRequest) (*providers.
 		return nil, "", fmt.Errorf("model %q not supported on Bedrock", openaiReq.Model)
 	}
 
+	if cp := openaiReq.CachePoint; cp != nil && (*cp < 0 || *cp >= len(openaiReq.Messages)) {
+		return nil, "", fmt.Errorf("cache point %d is outside the %d messages", *cp, len(openaiReq.Messages))
+	}
+	if err := checkCachePoints(openaiReq); err != nil {
+		return nil, "", err
+	}
+
 	// Convert messages
 	converseMessages := []ConverseMessage{}
 	var systemBlocks []SystemContentBlock
 
-	for _, msg := range openaiReq.Messages {
+	for i, msg := range openaiReq.Messages {
+		// A cache point follows messages marked with cache_control and
+		// the message named by the X-Bedrock-Cache-Point header
+		cachePoint := msg.CacheControl != nil || (openaiReq.CachePoint != nil && *openaiReq.CachePoint == i)
+
 		// Handle system messages separately
 		if msg.Role == "system" {
 			systemBlocks = append(systemBlocks, SystemContentBlock{
-				Text: extractTextContent(msg.Content),
+				Text: msg.Content.Text(),
 			})
+			if cachePoint {
+				systemBlocks = append(systemBlocks, SystemContentBlock{CachePoint: newCachePoint()})
+			}
 			continue
 		}
 
 		// Skip function/tool messages for now (can be added later)
 		if msg.Role == "function" || msg.Role == "tool" {
+			if cachePoint {
+				return nil, "", fmt.Errorf("cache point %d is on a %s message, which is not sent to Bedrock", i, msg.Role)
+			}
 			continue
 		}
 
 		// Convert message content
 		contentBlocks := convertToContentBlocks(msg.Content)
 		if len(contentBlocks) == 0 {
+			if cachePoint {
+				return nil, "", fmt.Errorf("cache point %d is on an empty message", i)
+			}
 			continue
 		}
+		if cachePoint {
+			contentBlocks = append(contentBlocks, ContentBlock{CachePoint: newCachePoint()})
+		}
+
+		// Reject video input for models that cannot process it
+		if hasVideoBlock(contentBlocks) && !bedrock.SupportsVideo(bedrockModelID) {
+			return nil, "", fmt.Errorf("model %q does not support video content", openaiReq.Model)
+		}
 
 		converseMessages = append(converseMessages, ConverseMessage{
 			Role:    msg.Role,
@@ -213,6 +283,15 @@ func TranslateOpenAIToConverseAPI(openaiReq *ChatCompletionRequest) (*providers.
 		System:          systemBlocks,
 		InferenceConfig: inferenceConfig,
 		ToolConfig:      toolConfig,
+		RequestMetadata: openaiReq.Metadata,
+	}
+	if latency := converseLatency(converseRequestLatency(openaiReq), bedrockModelID); latency != "" {
+		converseReq.PerformanceConfig = &PerformanceConfig{Latency: latency}
+	}
+	if openaiReq.AnthropicVersion != "" {
+		converseReq.AdditionalModelRequestFields = map[string]interface{}{
+			"anthropic_version": openaiReq.AnthropicVersion,
+		}
 	}
 
 	// Marshal to JSON
@@ -230,9 +309,9 @@ func TranslateOpenAIToConverseAPI(openaiReq *ChatCompletionRequest) (*providers.
 	providerReq := &providers.ProviderRequest{
 		Method: "POST",
 		Path:   path,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-			"Accept":       "application/json",
+		Headers: http.Header{
+			"Content-Type": {"application/json"},
+			"Accept":       {"application/json"},
 		},
 		Body: body,
 	}
@@ -267,7 +346,7 @@ func TranslateConverseToOpenAI(converseResp *ConverseResponse, openaiModel strin
 	}
 
 	// Map stop reason
-	finishReason := mapConverseStopReason(converseResp.StopReason)
+	finishReason := NormalizeFinishReason(converseResp.StopReason)
 
 	// Build message
 	message := ChatMessage{
@@ -275,7 +354,7 @@ func TranslateConverseToOpenAI(converseResp *ConverseResponse, openaiModel strin
 	}
 
 	if content != "" {
-		message.Content = content
+		message.Content = TextContent(content)
 	}
 
 	if len(toolCalls) > 0 {
@@ -295,87 +374,204 @@ func TranslateConverseToOpenAI(converseResp *ConverseResponse, openaiModel strin
 				FinishReason: finishReason,
 			},
 		},
-		Usage: &Usage{
-			PromptTokens:     converseResp.Usage.InputTokens,
-			CompletionTokens: converseResp.Usage.OutputTokens,
-			TotalTokens:      converseResp.Usage.TotalTokens,
-		},
+		Usage: converseUsage(converseResp.Usage),
 	}
 }
 
-// convertToContentBlocks converts OpenAI content to Converse content blocks
-func convertToContentBlocks(content interface{}) []ContentBlock {
-	var blocks []ContentBlock
+// converseUsage translates Converse usage, whose total includes the
+// cached prompt tokens
+func converseUsage(usage ConverseUsage) *Usage {
+	out := NewCacheUsage(usage.InputTokens, usage.OutputTokens, usage.CacheReadInputTokenCount, usage.CacheWriteInputTokenCount)
+	out.TotalTokens = usage.TotalTokens
+	return out
+}
 
-	switch c := content.(type) {
-	case string:
-		// Simple text content
-		blocks = append(blocks, ContentBlock{
-			Text: &c,
-		})
+// CachePointHeader names the request header carrying the index of the
+// message through which Bedrock should cache the prompt prefix
+const CachePointHeader = "X-Bedrock-Cache-Point"
 
-	case []interface{}:
-		// Multimodal content (array of content parts)
-		for _, part := range c {
-			if partMap, ok := part.(map[string]interface{}); ok {
-				block := convertContentPartToBlock(partMap)
-				if block != nil {
-					blocks = append(blocks, *block)
-				}
-			}
-		}
+// ParseCachePoint reads the cache point message index from the request
+// headers. It returns nil when the header is absent.
+func ParseCachePoint(h http.Header) (*int, error) {
+	value := strings.TrimSpace(h.Get(CachePointHeader))
+	if value == "" {
+		return nil, nil
+	}
+	index, err := strconv.Atoi(value)
+	if err != nil || index < 0 {
+		return nil, fmt.Errorf("invalid %s header %q: must be a message index", CachePointHeader, value)
+	}
+	return &index, nil
+}
+
+// LatencyHeader names the request header selecting Bedrock's
+// latency-optimized inference ("optimized") or the default ("standard")
+const LatencyHeader = "X-Bedrock-Latency"
 
+// Bedrock performanceConfig latency values
+const (
+	LatencyStandard  = "standard"
+	LatencyOptimized = "optimized"
+)
+
+// ParseLatency reads the performanceConfig latency from the request
+// headers. It returns "" when the header is absent.
+func ParseLatency(h http.Header) (string, error) {
+	value := strings.ToLower(strings.TrimSpace(h.Get(LatencyHeader)))
+	if err := ValidateLatency(value); err != nil {
+		return "", fmt.Errorf("invalid %s header: %w", LatencyHeader, err)
+	}
+	return value, nil
+}
+
+// ValidateLatency checks a performanceConfig latency; "" means unset
+func ValidateLatency(latency string) error {
+	switch latency {
+	case "", LatencyStandard, LatencyOptimized:
+		return nil
 	default:
-		// Fallback: convert to string
-		text := fmt.Sprintf("%v", content)
-		blocks = append(blocks, ContentBlock{
-			Text: &text,
-		})
+		return fmt.Errorf("latency %q must be %s or %s", latency, LatencyStandard, LatencyOptimized)
 	}
+}
 
-	return blocks
+// converseLatency returns the performanceConfig latency to send for a
+// model. Optimized latency is dropped for models that do not support it,
+// which then run with standard latency rather than fail.
+func converseLatency(latency, modelID string) string {
+	if latency == LatencyOptimized && !bedrock.SupportsLatencyOptimized(modelID) {
+		return ""
+	}
+	return latency
 }
 
-// convertContentPartToBlock converts an OpenAI content part to Converse content block
-func convertContentPartToBlock(part map[string]interface{}) *ContentBlock {
-	partType, ok := part["type"].(string)
-	if !ok {
-		return nil
+// newCachePoint returns a default cache point block
+func newCachePoint() *CachePointBlock {
+	return &CachePointBlock{Type: "default"}
+}
+
+// convertToContentBlocks converts OpenAI message content to Converse content blocks
+func convertToContentBlocks(content MessageContent) []ContentBlock {
+	var blocks []ContentBlock
+	for _, part := range content {
+		if block := convertContentPartToBlock(part); block != nil {
+			blocks = append(blocks, *block)
+		}
 	}
+	return blocks
+}
 
-	switch partType {
+// convertContentPartToBlock converts an OpenAI content part to Converse content block
+func convertContentPartToBlock(part ContentPart) *ContentBlock {
+	switch part.Type {
 	case "text":
-		if text, ok := part["text"].(string); ok {
-			return &ContentBlock{
-				Text: &text,
-			}
+		text := part.Text
+		return &ContentBlock{
+			Text: &text,
 		}
 
 	case "image_url":
-		if imageURL, ok := part["image_url"].(map[string]interface{}); ok {
-			if url, ok := imageURL["url"].(string); ok {
-				// Extract base64 data from data URL
-				if strings.HasPrefix(url, "data:image/") {
-					parts := strings.SplitN(url, ",", 2)
-					if len(parts) == 2 {
-						format := extractImageFormat(parts[0])
-						return &ContentBlock{
-							Image: &ImageBlock{
-								Format: format,
-								Source: ImageSource{
-									Bytes: parts[1],
-								},
-							},
-						}
-					}
+		if part.ImageURL == nil {
+			return nil
+		}
+		// Extract base64 data from data URL
+		if url := part.ImageURL.URL; strings.HasPrefix(url, "data:image/") {
+			parts := strings.SplitN(url, ",", 2)
+			if len(parts) == 2 {
+				format := extractImageFormat(parts[0])
+				return &ContentBlock{
+					Image: &ImageBlock{
+						Format: format,
+						Source: ImageSource{
+							Bytes: parts[1],
+						},
+					},
 				}
 			}
 		}
+
+	case "video_url", "video":
+		// Both {"video_url": {"url": ...}} and {"video": {"url": ...}} decode to VideoURL
+		if part.VideoURL == nil {
+			return nil
+		}
+		return convertVideoURLToBlock(part.VideoURL)
 	}
 
 	return nil
 }
 
+// convertVideoURLToBlock converts a video data URL or s3:// URI to a Converse video block
+func convertVideoURLToBlock(video *VideoURL) *ContentBlock {
+	url := video.URL
+	// Explicit format wins over the one inferred from the URL
+	format := video.Format
+
+	switch {
+	case strings.HasPrefix(url, "data:video/"):
+		parts := strings.SplitN(url, ",", 2)
+		if len(parts) != 2 {
+			return nil
+		}
+		if format == "" {
+			format = extractVideoFormat(parts[0])
+		}
+		return &ContentBlock{
+			Video: &VideoBlock{
+				Format: format,
+				Source: VideoSource{Bytes: parts[1]},
+			},
+		}
+
+	case strings.HasPrefix(url, "s3://"):
+		if format == "" {
+			format = extractVideoFormat(url)
+		}
+		location := &S3Location{URI: url, BucketOwner: video.BucketOwner}
+		return &ContentBlock{
+			Video: &VideoBlock{
+				Format: format,
+				Source: VideoSource{S3Location: location},
+			},
+		}
+	}
+
+	return nil
+}
+
+// extractVideoFormat extracts the Converse video format from a data URL prefix or file name
+func extractVideoFormat(s string) string {
+	s = strings.ToLower(s)
+	switch {
+	case strings.Contains(s, "video/quicktime"), strings.HasSuffix(s, ".mov"):
+		return "mov"
+	case strings.Contains(s, "video/x-matroska"), strings.HasSuffix(s, ".mkv"):
+		return "mkv"
+	case strings.Contains(s, "video/webm"), strings.HasSuffix(s, ".webm"):
+		return "webm"
+	case strings.Contains(s, "video/x-flv"), strings.HasSuffix(s, ".flv"):
+		return "flv"
+	case strings.Contains(s, "video/mpeg"), strings.HasSuffix(s, ".mpeg"):
+		return "mpeg"
+	case strings.HasSuffix(s, ".mpg"):
+		return "mpg"
+	case strings.Contains(s, "video/x-ms-wmv"), strings.HasSuffix(s, ".wmv"):
+		return "wmv"
+	case strings.Contains(s, "video/3gpp"), strings.HasSuffix(s, ".3gp"):
+		return "three_gp"
+	}
+	return "mp4" // default
+}
+
+// hasVideoBlock reports whether any content block carries video
+func hasVideoBlock(blocks []ContentBlock) bool {
+	for _, block := range blocks {
+		if block.Video != nil {
+			return true
+		}
+	}
+	return false
+}
+
 // extractImageFormat extracts image format from data URL prefix
 func extractImageFormat(prefix string) string {
 	// prefix format: "data:image/jpeg;base64"
@@ -391,24 +587,6 @@ func extractImageFormat(prefix string) string {
 	return "jpeg" // default
 }
 
-// mapConverseStopReason maps Converse stop reason to OpenAI finish reason
-func mapConverseStopReason(converseReason string) string {
-	switch converseReason {
-	case "end_turn":
-		return "stop"
-	case "max_tokens":
-		return "length"
-	case "stop_sequence":
-		return "stop"
-	case "tool_use":
-		return "tool_calls"
-	case "content_filtered":
-		return "content_filter"
-	default:
-		return "stop"
-	}
-}
-
 // currentTimestampUnix returns current Unix timestamp
 func currentTimestampUnix() int64 {
 	return 0 // Will be set by handler
@@ -459,6 +637,22 @@ func convertToolsToConverseFormat(req *ChatCompletionRequest) *ToolConfig {
 		toolConfig.ToolChoice = convertToolChoice(req.ToolChoice)
 	}
 
+	// parallel_tool_calls: false applies to the default auto choice too,
+	// but not to tool_choice "none", which sends no tool choice
+	if req.ParallelToolCalls != nil && !*req.ParallelToolCalls {
+		if req.ToolChoice == nil {
+			toolConfig.ToolChoice = &ToolChoice{Auto: &ToolChoiceMode{}}
+		}
+		if choice := toolConfig.ToolChoice; choice != nil {
+			if choice.Auto != nil {
+				choice.Auto.DisableParallelToolUse = true
+			}
+			if choice.Any != nil {
+				choice.Any.DisableParallelToolUse = true
+			}
+		}
+	}
+
 	return toolConfig
 }
 
@@ -468,9 +662,9 @@ func convertToolChoice(toolChoice interface{}) *ToolChoice {
 	case string:
 		switch tc {
 		case "auto":
-			return &ToolChoice{Auto: &struct{}{}}
+			return &ToolChoice{Auto: &ToolChoiceMode{}}
 		case "required", "any":
-			return &ToolChoice{Any: &struct{}{}}
+			return &ToolChoice{Any: &ToolChoiceMode{}}
 		case "none":
 			return nil
 		}
@@ -485,5 +679,5 @@ func convertToolChoice(toolChoice interface{}) *ToolChoice {
 			}
 		}
 	}
-	return &ToolChoice{Auto: &struct{}{}} // Default to auto
+	return &ToolChoice{Auto: &ToolChoiceMode{}} // Default to auto
 }
@@ -0,0 +1,384 @@
+package translator
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func cachePointRequest(index int) *ChatCompletionRequest {
+	return &ChatCompletionRequest{
+		Model: "claude-3-haiku",
+		Messages: []ChatMessage{
+			{Role: "system", Content: TextContent("You are a contract reviewer.")},
+			{Role: "user", Content: TextContent("Here is the contract: ...")},
+			{Role: "assistant", Content: TextContent("Understood.")},
+			{Role: "user", Content: TextContent("Summarise clause 4.")},
+		},
+		CachePoint: &index,
+	}
+}
+
+func TestConverseCachePoint(t *testing.T) {
+	providerReq, _, err := TranslateOpenAIToConverseAPI(cachePointRequest(1))
+	if err != nil {
+		t.Fatalf("TranslateOpenAIToConverseAPI: %v", err)
+	}
+
+	var converseReq ConverseRequest
+	if err := json.Unmarshal(providerReq.Body, &converseReq); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	// Message 1 is the first non-system message
+	content := converseReq.Messages[0].Content
+	if len(content) != 2 || content[1].CachePoint == nil || content[1].CachePoint.Type != "default" {
+		t.Errorf("first message content %+v, want text followed by a cache point", content)
+	}
+	for _, msg := range converseReq.Messages[1:] {
+		for _, block := range msg.Content {
+			if block.CachePoint != nil {
+				t.Errorf("unexpected cache point in %s message", msg.Role)
+			}
+		}
+	}
+
+	// A cache point on the system message goes into the system blocks
+	providerReq, _, err = TranslateOpenAIToConverseAPI(cachePointRequest(0))
+	if err != nil {
+		t.Fatalf("TranslateOpenAIToConverseAPI: %v", err)
+	}
+	converseReq = ConverseRequest{}
+	if err := json.Unmarshal(providerReq.Body, &converseReq); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(converseReq.System) != 2 || converseReq.System[1].CachePoint == nil {
+		t.Errorf("system blocks %+v, want text followed by a cache point", converseReq.System)
+	}
+
+	if _, _, err := TranslateOpenAIToConverseAPI(cachePointRequest(4)); err == nil {
+		t.Error("out of range cache point accepted")
+	}
+}
+
+func TestConverseLatency(t *testing.T) {
+	tests := []struct {
+		model   string
+		latency string
+		want    *PerformanceConfig
+	}{
+		{"amazon.nova-pro-v1:0", LatencyOptimized, &PerformanceConfig{Latency: LatencyOptimized}},
+		{"anthropic.claude-3-5-haiku-20241022-v1:0", LatencyOptimized, &PerformanceConfig{Latency: LatencyOptimized}},
+		{"amazon.nova-lite-v1:0", LatencyOptimized, nil},
+		{"amazon.nova-lite-v1:0", LatencyStandard, &PerformanceConfig{Latency: LatencyStandard}},
+		{"amazon.nova-pro-v1:0", "", nil},
+	}
+	for _, tt := range tests {
+		req := &ChatCompletionRequest{
+			Model:    tt.model,
+			Messages: []ChatMessage{{Role: "user", Content: TextContent("Hi")}},
+			Latency:  tt.latency,
+		}
+		providerReq, _, err := TranslateOpenAIToConverseAPI(req)
+		if err != nil {
+			t.Fatalf("TranslateOpenAIToConverseAPI(%s): %v", tt.model, err)
+		}
+		var converseReq ConverseRequest
+		if err := json.Unmarshal(providerReq.Body, &converseReq); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		got := converseReq.PerformanceConfig
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("%s with latency %q: performanceConfig %+v, want %+v", tt.model, tt.latency, got, tt.want)
+		}
+	}
+}
+
+// TestConverseServiceTier tests service tiers mapped to performanceConfig
+// latency, and the tier echoed for them
+func TestConverseServiceTier(t *testing.T) {
+	tests := []struct {
+		model, tier, latency string
+		wantLatency          string
+		wantTier             string
+	}{
+		{"amazon.nova-pro-v1:0", ServiceTierPriority, "", LatencyOptimized, ServiceTierPriority},
+		{"amazon.nova-lite-v1:0", ServiceTierPriority, "", "", ServiceTierDefault},
+		{"amazon.nova-pro-v1:0", ServiceTierDefault, "", LatencyStandard, ServiceTierDefault},
+		{"amazon.nova-pro-v1:0", ServiceTierPriority, LatencyStandard, LatencyStandard, ServiceTierDefault},
+	}
+	for _, tt := range tests {
+		req := &ChatCompletionRequest{
+			Model:       tt.model,
+			Messages:    []ChatMessage{{Role: "user", Content: TextContent("Hi")}},
+			ServiceTier: tt.tier,
+			Latency:     tt.latency,
+		}
+		providerReq, _, err := TranslateOpenAIToConverseAPI(req)
+		if err != nil {
+			t.Fatalf("TranslateOpenAIToConverseAPI(%s): %v", tt.model, err)
+		}
+		var converseReq ConverseRequest
+		if err := json.Unmarshal(providerReq.Body, &converseReq); err != nil {
+			t.Fatalf("unmarshal: %v", err)
+		}
+		latency := ""
+		if converseReq.PerformanceConfig != nil {
+			latency = converseReq.PerformanceConfig.Latency
+		}
+		if latency != tt.wantLatency {
+			t.Errorf("%s tier %s: latency %q, want %q", tt.model, tt.tier, latency, tt.wantLatency)
+		}
+		if tier := ConverseServiceTier(req); tier != tt.wantTier {
+			t.Errorf("%s tier %s: ConverseServiceTier = %q, want %q", tt.model, tt.tier, tier, tt.wantTier)
+		}
+	}
+}
+
+func TestParseLatency(t *testing.T) {
+	if latency, err := ParseLatency(http.Header{LatencyHeader: {" Optimized "}}); err != nil || latency != LatencyOptimized {
+		t.Errorf("header optimized: got %q, %v", latency, err)
+	}
+	if latency, err := ParseLatency(http.Header{}); err != nil || latency != "" {
+		t.Errorf("no header: got %q, %v", latency, err)
+	}
+	if _, err := ParseLatency(http.Header{LatencyHeader: {"fast"}}); err == nil {
+		t.Error("header fast accepted")
+	}
+}
+
+func TestParseCachePoint(t *testing.T) {
+	if cp, err := ParseCachePoint(http.Header{}); cp != nil || err != nil {
+		t.Errorf("no header: got %v, %v", cp, err)
+	}
+	if cp, err := ParseCachePoint(http.Header{CachePointHeader: {" 3 "}}); err != nil || cp == nil || *cp != 3 {
+		t.Errorf("header 3: got %v, %v", cp, err)
+	}
+	for _, value := range []string{"-1", "first"} {
+		if _, err := ParseCachePoint(http.Header{CachePointHeader: {value}}); err == nil {
+			t.Errorf("header %q accepted", value)
+		}
+	}
+}
+
+func TestConverseCacheUsage(t *testing.T) {
+	var converseResp ConverseResponse
+	body := `{"output":{"message":{"role":"assistant","content":[{"text":"ok"}]}},"stopReason":"end_turn",
+		"usage":{"inputTokens":10,"outputTokens":2,"totalTokens":12,"cacheReadInputTokenCount":1024,"cacheWriteInputTokenCount":16}}`
+	if err := json.Unmarshal([]byte(body), &converseResp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	usage := TranslateConverseToOpenAI(&converseResp, "claude-3-haiku", "chatcmpl-test").Usage
+	if usage.CacheReadInputTokens != 1024 || usage.CacheWriteInputTokens != 16 {
+		t.Errorf("usage %+v, want cache read 1024 and write 16", usage)
+	}
+	if d := usage.PromptTokensDetails; d == nil || d.CachedTokens != 1024 || d.CacheCreationTokens != 16 {
+		t.Errorf("prompt_tokens_details %+v, want 1024 cached and 16 created", d)
+	}
+	if usage.TotalTokens != 12 {
+		t.Errorf("total_tokens %d, want Bedrock's 12", usage.TotalTokens)
+	}
+}
+
+// TestConverseCacheControl tests cache points placed by cache_control
+// markers, alongside the X-Bedrock-Cache-Point header
+func TestConverseCacheControl(t *testing.T) {
+	ephemeral := &CacheControl{Type: CacheControlEphemeral}
+	req := &ChatCompletionRequest{
+		Model: "claude-3-haiku",
+		Messages: []ChatMessage{
+			{Role: "system", Content: TextContent("Long instructions"), CacheControl: ephemeral},
+			{Role: "user", Content: TextContent("Document")},
+			{Role: "assistant", Content: TextContent("Read it.")},
+			{Role: "user", Content: TextContent("Summarize"), CacheControl: ephemeral},
+		},
+	}
+	header := 1
+	req.CachePoint = &header
+	providerReq, _, err := TranslateOpenAIToConverseAPI(req)
+	if err != nil {
+		t.Fatalf("TranslateOpenAIToConverseAPI: %v", err)
+	}
+	var converseReq ConverseRequest
+	if err := json.Unmarshal(providerReq.Body, &converseReq); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if len(converseReq.System) != 2 || converseReq.System[1].CachePoint == nil {
+		t.Errorf("system blocks %+v, want text followed by a cache point", converseReq.System)
+	}
+	for i, want := range []bool{true, false, true} {
+		content := converseReq.Messages[i].Content
+		if got := content[len(content)-1].CachePoint != nil; got != want {
+			t.Errorf("message %d cache point = %v, want %v", i, got, want)
+		}
+	}
+
+	// Bedrock accepts at most four cache points
+	for i := 0; i < 3; i++ {
+		req.Messages = append(req.Messages, ChatMessage{Role: "user", Content: TextContent("More"), CacheControl: ephemeral})
+	}
+	if _, _, err := TranslateOpenAIToConverseAPI(req); err == nil {
+		t.Error("request with six cache points accepted")
+	}
+}
+
+// TestConverseParallelToolCalls tests the toolConfig wire format for
+// parallel_tool_calls
+func TestConverseParallelToolCalls(t *testing.T) {
+	disabled, enabled := false, true
+	tests := []struct {
+		name       string
+		toolChoice interface{}
+		parallel   *bool
+		want       string
+	}{
+		{"unset", nil, nil, ``},
+		{"enabled", nil, &enabled, ``},
+		{"disabled", nil, &disabled, `{"auto":{"disableParallelToolUse":true}}`},
+		{"disabled with required", "required", &disabled, `{"any":{"disableParallelToolUse":true}}`},
+		{"disabled with named tool", map[string]interface{}{"type": "function", "function": map[string]interface{}{"name": "lookup"}}, &disabled, `{"tool":{"name":"lookup"}}`},
+		{"disabled with none", "none", &disabled, ``},
+		{"auto", "auto", nil, `{"auto":{}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &ChatCompletionRequest{
+				Model:    "claude-3-haiku",
+				Messages: []ChatMessage{{Role: "user", Content: TextContent("Weather in Paris and Rome?")}},
+				Tools: []Tool{{Type: "function", Function: Function{
+					Name:       "lookup",
+					Parameters: map[string]interface{}{"type": "object"},
+				}}},
+				ToolChoice:        tt.toolChoice,
+				ParallelToolCalls: tt.parallel,
+			}
+			providerReq, _, err := TranslateOpenAIToConverseAPI(req)
+			if err != nil {
+				t.Fatalf("TranslateOpenAIToConverseAPI: %v", err)
+			}
+
+			var body struct {
+				ToolConfig struct {
+					ToolChoice json.RawMessage `json:"toolChoice"`
+				} `json:"toolConfig"`
+			}
+			if err := json.Unmarshal(providerReq.Body, &body); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			if got := string(body.ToolConfig.ToolChoice); got != tt.want {
+				t.Errorf("toolChoice = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// videoRequest decodes an OpenAI chat request for model whose user message
+// is a text part followed by content part video
+func videoRequest(t *testing.T, model, video string) *ChatCompletionRequest {
+	t.Helper()
+	var req ChatCompletionRequest
+	body := `{"model":"` + model + `","messages":[{"role":"user","content":[{"type":"text","text":"What happens?"},` + video + `]}]}`
+	if err := json.Unmarshal([]byte(body), &req); err != nil {
+		t.Fatalf("unmarshal request: %v", err)
+	}
+	return &req
+}
+
+func TestConverseVideo(t *testing.T) {
+	tests := []struct {
+		name  string
+		video string
+		want  VideoBlock
+	}{
+		{
+			"data URL",
+			`{"type":"video_url","video_url":{"url":"data:video/webm;base64,AAAA"}}`,
+			VideoBlock{Format: "webm", Source: VideoSource{Bytes: "AAAA"}},
+		},
+		{
+			"data URL with explicit format",
+			`{"type":"video_url","video_url":{"url":"data:video/mp4;base64,AAAA","format":"mov"}}`,
+			VideoBlock{Format: "mov", Source: VideoSource{Bytes: "AAAA"}},
+		},
+		{
+			"s3 URI",
+			`{"type":"video","video":{"url":"s3://clips/match.MKV","bucket_owner":"111122223333"}}`,
+			VideoBlock{Format: "mkv", Source: VideoSource{S3Location: &S3Location{URI: "s3://clips/match.MKV", BucketOwner: "111122223333"}}},
+		},
+		{
+			"s3 URI without extension",
+			`{"type":"video_url","video_url":{"url":"s3://clips/match"}}`,
+			VideoBlock{Format: "mp4", Source: VideoSource{S3Location: &S3Location{URI: "s3://clips/match"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			providerReq, _, err := TranslateOpenAIToConverseAPI(videoRequest(t, "amazon.nova-pro-v1:0", tt.video))
+			if err != nil {
+				t.Fatalf("TranslateOpenAIToConverseAPI: %v", err)
+			}
+			var converseReq ConverseRequest
+			if err := json.Unmarshal(providerReq.Body, &converseReq); err != nil {
+				t.Fatalf("unmarshal: %v", err)
+			}
+			content := converseReq.Messages[0].Content
+			if len(content) != 2 || content[1].Video == nil {
+				t.Fatalf("content %+v, want text followed by a video", content)
+			}
+			got := *content[1].Video
+			if got.Format != tt.want.Format || got.Source.Bytes != tt.want.Source.Bytes ||
+				(got.Source.S3Location == nil) != (tt.want.Source.S3Location == nil) ||
+				(got.Source.S3Location != nil && *got.Source.S3Location != *tt.want.Source.S3Location) {
+				t.Errorf("video = %+v (s3 %+v), want %+v (s3 %+v)", got, got.Source.S3Location, tt.want, tt.want.Source.S3Location)
+			}
+		})
+	}
+
+	// Videos that are neither data URLs nor s3:// URIs are dropped
+	providerReq, _, err := TranslateOpenAIToConverseAPI(videoRequest(t, "amazon.nova-lite-v1:0",
+		`{"type":"video_url","video_url":{"url":"https://example.com/clip.mp4"}}`))
+	if err != nil {
+		t.Fatalf("TranslateOpenAIToConverseAPI: %v", err)
+	}
+	var converseReq ConverseRequest
+	if err := json.Unmarshal(providerReq.Body, &converseReq); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if content := converseReq.Messages[0].Content; len(content) != 1 || content[0].Text == nil {
+		t.Errorf("content %+v, want the text only", content)
+	}
+}
+
+func TestConverseVideoUnsupportedModel(t *testing.T) {
+	video := `{"type":"video_url","video_url":{"url":"s3://clips/match.mp4"}}`
+	_, _, err := TranslateOpenAIToConverseAPI(videoRequest(t, "anthropic.claude-3-haiku-20240307-v1:0", video))
+	if err == nil || !strings.Contains(err.Error(), "does not support video content") {
+		t.Errorf("error = %v, want video rejected", err)
+	}
+	if _, _, err := TranslateOpenAIToConverseAPI(videoRequest(t, "amazon.nova-lite-v1:0", video)); err != nil {
+		t.Errorf("Nova Lite: %v", err)
+	}
+}
+
+func TestExtractVideoFormat(t *testing.T) {
+	tests := map[string]string{
+		"data:video/quicktime;base64":  "mov",
+		"data:video/x-matroska;base64": "mkv",
+		"data:video/webm;base64":       "webm",
+		"data:video/x-flv;base64":      "flv",
+		"data:video/mpeg;base64":       "mpeg",
+		"data:video/x-ms-wmv;base64":   "wmv",
+		"data:video/3gpp;base64":       "three_gp",
+		"data:video/mp4;base64":        "mp4",
+		"s3://clips/intro.MOV":         "mov",
+		"s3://clips/intro.mpg":         "mpg",
+		"s3://clips/intro.3gp":         "three_gp",
+		"s3://clips/intro.unknown":     "mp4",
+	}
+	for input, want := range tests {
+		if got := extractVideoFormat(input); got != want {
+			t.Errorf("extractVideoFormat(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package translator
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNormaliseBedrockModelID(t *testing.T) {
+	for id, want := range map[string]string{
+		"claude-3-sonnet":                           "anthropic.claude-3-sonnet-20240229-v1:0",
+		"claude-3-sonnet-20240229":                  "anthropic.claude-3-sonnet-20240229-v1:0",
+		"anthropic.claude-3-sonnet-20240229-v1:0":   "anthropic.claude-3-sonnet-20240229-v1:0",
+		"anthropic.claude-3-sonnet-20240229":        "anthropic.claude-3-sonnet-20240229-v1:0",
+		"claude-3-5-haiku-20241022":                 "anthropic.claude-3-5-haiku-20241022-v1:0",
+		"us.anthropic.claude-3-haiku-20240307-v1:0": "us.anthropic.claude-3-haiku-20240307-v1:0",
+		"nova-pro": "amazon.nova-pro-v1:0",
+		"gpt-4o":   "gpt-4o",
+	} {
+		if got := NormaliseBedrockModelID(id); got != want {
+			t.Errorf("NormaliseBedrockModelID(%q) = %q, want %q", id, got, want)
+		}
+	}
+}
+
+// TestTranslateOpenAIToBedrockModelForms checks an alias and the full
+// Bedrock ID produce the same Bedrock request
+func TestTranslateOpenAIToBedrockModelForms(t *testing.T) {
+	translate := func(model string) (path string, body []byte, modelID string) {
+		t.Helper()
+		req := &ChatCompletionRequest{
+			Model:     model,
+			Messages:  []ChatMessage{{Role: "user", Content: TextContent("Hi")}},
+			MaxTokens: 100,
+		}
+		providerReq, modelID, err := TranslateOpenAIToBedrock(req)
+		if err != nil {
+			t.Fatalf("TranslateOpenAIToBedrock(%q): %v", model, err)
+		}
+		return providerReq.Path, providerReq.Body, modelID
+	}
+
+	aliasPath, aliasBody, aliasID := translate("claude-3-sonnet-20240229")
+	fullPath, fullBody, fullID := translate("anthropic.claude-3-sonnet-20240229-v1:0")
+	if aliasID != fullID || aliasPath != fullPath {
+		t.Errorf("alias routes to %s (%s), full ID to %s (%s)", aliasID, aliasPath, fullID, fullPath)
+	}
+	if !bytes.Equal(aliasBody, fullBody) {
+		t.Errorf("bodies differ:\n%s\n%s", aliasBody, fullBody)
+	}
+	if fullPath != "/model/anthropic.claude-3-sonnet-20240229-v1:0/invoke" {
+		t.Errorf("path = %s", fullPath)
+	}
+}
@@ -0,0 +1,52 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import "github.com/tosharewith/llmproxy_auth/internal/providers"
+
+// ChatEventChunk translates a typed provider stream event to an OpenAI
+// chat.completion.chunk. It returns nil for events that are not sent as
+// chunks: usage, which is reported in the final usage chunk, and errors.
+func ChatEventChunk(event providers.ChatEvent, id, model string, created int64) *ChatCompletionStreamResponse {
+	choice := ChatCompletionStreamChoice{}
+	switch event.Type {
+	case providers.ChatEventDelta:
+		choice.Delta = ChatMessageDelta{Role: event.Role, Content: event.Text}
+	case providers.ChatEventToolCallDelta:
+		call := ToolCallDelta{
+			Index: event.ToolCall.Index,
+			ID:    event.ToolCall.ID,
+			Function: FunctionCallDelta{
+				Name:      event.ToolCall.Name,
+				Arguments: event.ToolCall.Arguments,
+			},
+		}
+		if call.ID != "" {
+			call.Type = "function"
+		}
+		choice.Delta = ChatMessageDelta{ToolCalls: []ToolCallDelta{call}}
+	case providers.ChatEventDone:
+		finishReason := NormalizeFinishReason(event.FinishReason)
+		choice.FinishReason = &finishReason
+	default:
+		return nil
+	}
+
+	return &ChatCompletionStreamResponse{
+		ID:      id,
+		Object:  "chat.completion.chunk",
+		Created: created,
+		Model:   model,
+		Choices: []ChatCompletionStreamChoice{choice},
+	}
+}
+
+// ChatEventUsage translates the usage of a ChatEventUsage event
+func ChatEventUsage(usage *providers.ChatUsage) Usage {
+	out := NewCacheUsage(usage.InputTokens, usage.OutputTokens, usage.CacheReadTokens, usage.CacheWriteTokens)
+	if usage.TotalTokens != 0 {
+		out.TotalTokens = usage.TotalTokens
+	}
+	return *out
+}
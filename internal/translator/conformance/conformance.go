@@ -0,0 +1,263 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package conformance loads the recorded provider fixtures under
+// internal/translator/testdata/conformance and checks translation output
+// against their golden files. Each fixture is an OpenAI chat request and
+// the provider's recorded (sanitized) response to it: a JSON body, an
+// error status, or a sequence of stream events.
+//
+// The translator tests run fixtures through request and response
+// translation; the handler tests replay them from a fake provider server
+// through the whole Gin pipeline. Run either with -update to regenerate
+// the goldens:
+//
+//	go test ./internal/translator/ ./internal/handlers/ -run Conformance -update
+package conformance
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws/protocol/eventstream"
+)
+
+// Update makes CheckGolden write goldens instead of comparing them
+var Update = flag.Bool("update", false, "regenerate conformance golden files")
+
+// Fixture is a recorded exchange with a provider
+type Fixture struct {
+	// Name is the fixture's path under the fixture directory without its
+	// extension, such as bedrock/tools
+	Name string `json:"-"`
+
+	// Provider is the provider the response was recorded from: openai,
+	// anthropic, bedrock or vertex (Gemini)
+	Provider string `json:"provider"`
+
+	// Request is the OpenAI chat completion request
+	Request json.RawMessage `json:"request"`
+
+	// Response is what the provider answered
+	Response Recorded `json:"response"`
+}
+
+// Recorded is a provider response
+type Recorded struct {
+	// Status is the HTTP status (default: 200)
+	Status int `json:"status,omitempty"`
+
+	// Headers are response headers, besides Content-Type
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Body is the response body of a non-streaming response or an error
+	Body json.RawMessage `json:"body,omitempty"`
+
+	// Events are the events of a streamed response, in order
+	Events []Event `json:"events,omitempty"`
+
+	// Truncated marks a stream cut off without its terminating event
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// Event is one streamed event. For Bedrock, Type is the :event-type, or
+// the :exception-type prefixed with "!"; SSE providers leave it empty.
+type Event struct {
+	Type string          `json:"type,omitempty"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Stream reports whether the fixture's response is streamed
+func (f Fixture) Stream() bool {
+	return len(f.Response.Events) > 0
+}
+
+// StatusCode returns the recorded HTTP status
+func (r Recorded) StatusCode() int {
+	if r.Status == 0 {
+		return http.StatusOK
+	}
+	return r.Status
+}
+
+// Dir returns the fixture directory
+func Dir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "testdata", "conformance")
+}
+
+// Load reads every fixture under Dir, sorted by name. Golden files, named
+// *.golden.json, are skipped.
+func Load() ([]Fixture, error) {
+	root := Dir()
+	var fixtures []Fixture
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() || !strings.HasSuffix(path, ".json") || strings.HasSuffix(path, ".golden.json") {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		var fixture Fixture
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&fixture); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		fixture.Name = filepath.ToSlash(strings.TrimSuffix(rel, ".json"))
+		fixtures = append(fixtures, fixture)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures under %s", root)
+	}
+	sort.Slice(fixtures, func(i, j int) bool { return fixtures[i].Name < fixtures[j].Name })
+	return fixtures, nil
+}
+
+// GoldenPath returns the path of a fixture's golden file with a suffix,
+// such as bedrock/tools.handler.golden.json for "handler"
+func (f Fixture) GoldenPath(suffix string) string {
+	return filepath.Join(Dir(), filepath.FromSlash(f.Name)+"."+suffix+".golden.json")
+}
+
+// CheckGolden compares got, encoded as indented JSON, with a golden file,
+// or writes the file when -update is set
+func CheckGolden(path string, got interface{}) error {
+	data, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	if *Update {
+		return os.WriteFile(path, data, 0644)
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("%w (run with -update to create it)", err)
+	}
+	if !bytes.Equal(data, want) {
+		return fmt.Errorf("output differs from %s (run with -update to accept it):\n%s", filepath.Base(path), data)
+	}
+	return nil
+}
+
+// Normalize decodes a JSON document for comparison, zeroing the fields
+// that change on every run: created timestamps
+func Normalize(data []byte) (interface{}, error) {
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	if object, ok := v.(map[string]interface{}); ok {
+		if _, ok := object["created"]; ok {
+			object["created"] = 0
+		}
+	}
+	return v, nil
+}
+
+// Encode returns the recorded response body as the provider sends it: the
+// JSON body, an OpenAI, Anthropic or Gemini SSE stream, or a Bedrock event
+// stream
+func (f Fixture) Encode() ([]byte, string, error) {
+	if !f.Stream() {
+		return f.Response.Body, "application/json", nil
+	}
+
+	var buf bytes.Buffer
+	if f.Provider == "bedrock" {
+		encoder := eventstream.NewEncoder()
+		for _, event := range f.Response.Events {
+			var headers eventstream.Headers
+			if exceptionType, ok := strings.CutPrefix(event.Type, "!"); ok {
+				headers.Set(":message-type", eventstream.StringValue("exception"))
+				headers.Set(":exception-type", eventstream.StringValue(exceptionType))
+			} else {
+				headers.Set(":message-type", eventstream.StringValue("event"))
+				headers.Set(":event-type", eventstream.StringValue(event.Type))
+			}
+			if err := encoder.Encode(&buf, eventstream.Message{Headers: headers, Payload: event.Data}); err != nil {
+				return nil, "", err
+			}
+		}
+		return buf.Bytes(), "application/vnd.amazon.eventstream", nil
+	}
+
+	for _, event := range f.Response.Events {
+		if event.Type != "" {
+			fmt.Fprintf(&buf, "event: %s\n", event.Type)
+		}
+		fmt.Fprintf(&buf, "data: %s\n\n", event.Data)
+	}
+	// Anthropic and Gemini streams end without [DONE]
+	if !f.Response.Truncated && f.Provider != "anthropic" && f.Provider != "vertex" {
+		buf.WriteString("data: [DONE]\n\n")
+	}
+	return buf.Bytes(), "text/event-stream", nil
+}
+
+// Server is a fake provider replaying a fixture's response to every
+// request, recording the requests it receives
+type Server struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests []Request
+}
+
+// Request is a request received by a Server
+type Request struct {
+	Path string          `json:"path"`
+	Body json.RawMessage `json:"body"`
+}
+
+// NewServer starts a fake provider for a fixture
+func NewServer(f Fixture) (*Server, error) {
+	body, contentType, err := f.Encode()
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.requests = append(s.requests, Request{Path: r.URL.Path, Body: received})
+		s.mu.Unlock()
+
+		for name, value := range f.Response.Headers {
+			w.Header().Set(name, value)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(f.Response.StatusCode())
+		w.Write(body)
+	}))
+	return s, nil
+}
+
+// Requests returns the requests received so far
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
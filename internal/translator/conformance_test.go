@@ -0,0 +1,124 @@
+package translator_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers/anthropic"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/vertex"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"github.com/tosharewith/llmproxy_auth/internal/translator/conformance"
+)
+
+// translationGolden is the golden of a fixture's translation: the request
+// sent to the provider and, for successful non-streaming responses, the
+// translated OpenAI response. Streams and errors are decoded by providers
+// and handlers, so their responses are checked by the handler tests.
+type translationGolden struct {
+	ProviderRequest conformance.Request `json:"provider_request"`
+	Response        interface{}         `json:"response,omitempty"`
+}
+
+func TestConformanceTranslation(t *testing.T) {
+	fixtures, err := conformance.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fixture := range fixtures {
+		t.Run(fixture.Name, func(t *testing.T) {
+			var req translator.ChatCompletionRequest
+			if err := json.Unmarshal(fixture.Request, &req); err != nil {
+				t.Fatalf("request: %v", err)
+			}
+
+			golden := translationGolden{}
+			golden.ProviderRequest, err = translateFixtureRequest(fixture.Provider, &req)
+			if err != nil {
+				t.Fatalf("translate request: %v", err)
+			}
+			if !fixture.Stream() && fixture.Response.StatusCode() == 200 {
+				resp, err := translateFixtureResponse(fixture.Provider, fixture.Response.Body, req.Model)
+				if err != nil {
+					t.Fatalf("translate response: %v", err)
+				}
+				data, err := json.Marshal(resp)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if golden.Response, err = conformance.Normalize(data); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := conformance.CheckGolden(fixture.GoldenPath("translation"), golden); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// translateFixtureRequest translates an OpenAI request to what is sent to
+// a provider
+func translateFixtureRequest(provider string, req *translator.ChatCompletionRequest) (conformance.Request, error) {
+	var (
+		path string
+		body []byte
+		err  error
+	)
+	switch provider {
+	case "bedrock":
+		providerReq, _, translateErr := translator.TranslateOpenAIToConverseAPI(req)
+		if translateErr != nil {
+			return conformance.Request{}, translateErr
+		}
+		path, body = providerReq.Path, providerReq.Body
+	case "anthropic":
+		path = "/messages"
+		body, err = json.Marshal(anthropic.TranslateRequest(req))
+	case "vertex":
+		method := ":generateContent"
+		if req.Stream {
+			method = ":streamGenerateContent"
+		}
+		path = "/publishers/google/models/" + req.Model + method
+		body, err = json.Marshal(vertex.TranslateRequest(req))
+	default:
+		path = "/chat/completions"
+		body, err = json.Marshal(req)
+	}
+	return conformance.Request{Path: path, Body: body}, err
+}
+
+// translateFixtureResponse translates a provider response to OpenAI format
+func translateFixtureResponse(provider string, body []byte, model string) (*translator.ChatCompletionResponse, error) {
+	switch provider {
+	case "bedrock":
+		var resp translator.ConverseResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		return translator.TranslateConverseToOpenAI(&resp, model, "chatcmpl-conformance"), nil
+	case "anthropic":
+		var resp anthropic.AnthropicResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		return anthropic.TranslateResponse(&resp, model), nil
+	case "vertex":
+		var resp vertex.VertexResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		// Gemini responses have no ID; the translation makes one from the time
+		openaiResp := vertex.TranslateResponse(&resp, model)
+		openaiResp.ID = "chatcmpl-conformance"
+		return openaiResp, nil
+	default:
+		var resp translator.ChatCompletionResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, err
+		}
+		translator.NormalizeFinishReasons(&resp)
+		return &resp, nil
+	}
+}
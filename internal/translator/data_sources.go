@@ -0,0 +1,31 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+// StripDataSources returns req without Azure On Your Data sources or the
+// citation context of earlier replies, for providers other than Azure. req
+// itself is not modified.
+func StripDataSources(req *ChatCompletionRequest) *ChatCompletionRequest {
+	hasContext := false
+	for _, msg := range req.Messages {
+		if msg.Context != nil {
+			hasContext = true
+			break
+		}
+	}
+	if len(req.DataSources) == 0 && !hasContext {
+		return req
+	}
+
+	stripped := *req
+	stripped.DataSources = nil
+	if hasContext {
+		stripped.Messages = make([]ChatMessage, len(req.Messages))
+		for i, msg := range req.Messages {
+			msg.Context = nil
+			stripped.Messages[i] = msg
+		}
+	}
+	return &stripped
+}
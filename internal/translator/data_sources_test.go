@@ -0,0 +1,61 @@
+package translator
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers/azure"
+)
+
+// TestStripDataSources tests that data sources and reply context are
+// removed without modifying the caller's request
+func TestStripDataSources(t *testing.T) {
+	req := &ChatCompletionRequest{
+		Model: "gpt-4o",
+		Messages: []ChatMessage{
+			{Role: "user", Content: TextContent("What is our leave policy?")},
+			{Role: "assistant", Content: TextContent("Twenty days [doc1]."), Context: &azure.AzureContext{Intent: `["leave policy"]`}},
+		},
+		DataSources: []azure.AzureDataSource{{Type: "azure_search", Parameters: map[string]interface{}{"index_name": "hr-docs"}}},
+	}
+
+	stripped := StripDataSources(req)
+	body, err := json.Marshal(stripped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fields map[string]json.RawMessage
+	json.Unmarshal(body, &fields)
+	if _, ok := fields["data_sources"]; ok || stripped.Messages[1].Context != nil {
+		t.Errorf("stripped request = %s", body)
+	}
+	if len(req.DataSources) != 1 || req.Messages[1].Context == nil {
+		t.Error("caller's request was modified")
+	}
+
+	plain := &ChatCompletionRequest{Model: "gpt-4o", Messages: req.Messages[:1]}
+	if StripDataSources(plain) != plain {
+		t.Error("request without data sources was copied")
+	}
+}
+
+// TestResponseContext tests that the citations of an Azure reply survive
+// parsing and re-encoding a response
+func TestResponseContext(t *testing.T) {
+	body := `{"id":"chatcmpl-1","object":"chat.completion","choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":"Twenty days [doc1].",` +
+		`"context":{"citations":[{"content":"Employees get twenty days.","title":"Leave","url":"https://hr.example.com/leave"}],"all_retrieved_documents":[]}}}]}`
+	var resp ChatCompletionResponse
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := json.Marshal(resp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ChatCompletionResponse
+	json.Unmarshal(encoded, &decoded)
+	context := decoded.Choices[0].Message.Context
+	if context == nil || len(context.Citations) != 1 || context.Citations[0].URL != "https://hr.example.com/leave" {
+		t.Errorf("re-encoded response = %s", encoded)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// OpenAI finish_reason values
+const (
+	FinishReasonStop          = "stop"
+	FinishReasonLength        = "length"
+	FinishReasonToolCalls     = "tool_calls"
+	FinishReasonContentFilter = "content_filter"
+	FinishReasonFunctionCall  = "function_call"
+)
+
+// defaultFinishReasons maps provider stop reasons to OpenAI finish reasons.
+// Keys are matched case-insensitively.
+var defaultFinishReasons = map[string]string{
+	// OpenAI (identity)
+	"stop":           FinishReasonStop,
+	"length":         FinishReasonLength,
+	"tool_calls":     FinishReasonToolCalls,
+	"content_filter": FinishReasonContentFilter,
+	"function_call":  FinishReasonFunctionCall,
+
+	// Anthropic / Bedrock Converse
+	"end_turn":                      FinishReasonStop,
+	"stop_sequence":                 FinishReasonStop,
+	"max_tokens":                    FinishReasonLength,
+	"model_context_window_exceeded": FinishReasonLength,
+	"tool_use":                      FinishReasonToolCalls,
+	"content_filtered":              FinishReasonContentFilter,
+	"guardrail_intervened":          FinishReasonContentFilter,
+	"refusal":                       FinishReasonContentFilter,
+
+	// Vertex AI / Gemini
+	"safety":             FinishReasonContentFilter,
+	"recitation":         FinishReasonContentFilter,
+	"blocklist":          FinishReasonContentFilter,
+	"prohibited_content": FinishReasonContentFilter,
+	"spii":               FinishReasonContentFilter,
+
+	// Oracle / Cohere / IBM
+	"complete":    FinishReasonStop,
+	"eos_token":   FinishReasonStop,
+	"max_length":  FinishReasonLength,
+	"token_limit": FinishReasonLength,
+	"error_toxic": FinishReasonContentFilter,
+}
+
+var (
+	finishReasonsMu sync.RWMutex
+	finishReasons   = copyFinishReasons(defaultFinishReasons)
+)
+
+// NormalizeFinishReason maps a provider stop reason to OpenAI's vocabulary
+// (stop, length, tool_calls, content_filter, function_call). Unknown and
+// empty reasons map to "stop".
+func NormalizeFinishReason(reason string) string {
+	finishReasonsMu.RLock()
+	defer finishReasonsMu.RUnlock()
+
+	if mapped, ok := finishReasons[strings.ToLower(reason)]; ok {
+		return mapped
+	}
+	return FinishReasonStop
+}
+
+// SetFinishReasonMappings overrides or extends the default finish reason
+// mappings. Targets must be valid OpenAI finish reasons.
+func SetFinishReasonMappings(overrides map[string]string) error {
+	merged := copyFinishReasons(defaultFinishReasons)
+	for from, to := range overrides {
+		if !isOpenAIFinishReason(to) {
+			return fmt.Errorf("invalid finish reason mapping %q -> %q: target must be one of stop, length, tool_calls, content_filter, function_call", from, to)
+		}
+		merged[strings.ToLower(from)] = to
+	}
+
+	finishReasonsMu.Lock()
+	finishReasons = merged
+	finishReasonsMu.Unlock()
+	return nil
+}
+
+// NormalizeFinishReasons rewrites the finish reasons of a response in place
+func NormalizeFinishReasons(resp *ChatCompletionResponse) {
+	if resp == nil {
+		return
+	}
+	for i := range resp.Choices {
+		resp.Choices[i].FinishReason = NormalizeFinishReason(resp.Choices[i].FinishReason)
+	}
+}
+
+func isOpenAIFinishReason(reason string) bool {
+	switch reason {
+	case FinishReasonStop, FinishReasonLength, FinishReasonToolCalls, FinishReasonContentFilter, FinishReasonFunctionCall:
+		return true
+	}
+	return false
+}
+
+func copyFinishReasons(src map[string]string) map[string]string {
+	dst := make(map[string]string, len(src))
+	for k, v := range src {
+		dst[k] = v
+	}
+	return dst
+}
@@ -0,0 +1,76 @@
+package translator
+
+import "testing"
+
+// TestConverseStopReasonMapping tests that Bedrock Converse stop reasons map to OpenAI values
+func TestConverseStopReasonMapping(t *testing.T) {
+	tests := []struct {
+		stopReason string
+		want       string
+	}{
+		{"end_turn", "stop"},
+		{"stop_sequence", "stop"},
+		{"max_tokens", "length"},
+		{"model_context_window_exceeded", "length"},
+		{"tool_use", "tool_calls"},
+		{"content_filtered", "content_filter"},
+		{"guardrail_intervened", "content_filter"},
+		{"", "stop"},
+		{"something_new", "stop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stopReason, func(t *testing.T) {
+			resp := TranslateConverseToOpenAI(&ConverseResponse{StopReason: tt.stopReason}, "claude-3-sonnet", "chatcmpl-test")
+			if len(resp.Choices) != 1 {
+				t.Fatalf("expected 1 choice, got %d", len(resp.Choices))
+			}
+			if got := resp.Choices[0].FinishReason; got != tt.want {
+				t.Errorf("finish_reason for %q: got %q, want %q", tt.stopReason, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNormalizeFinishReasonProviders tests non-Bedrock provider vocabularies
+func TestNormalizeFinishReasonProviders(t *testing.T) {
+	tests := map[string]string{
+		"STOP":       "stop",           // Vertex
+		"MAX_TOKENS": "length",         // Vertex
+		"SAFETY":     "content_filter", // Vertex
+		"COMPLETE":   "stop",           // Oracle
+		"length":     "length",         // OpenAI passthrough
+		"tool_calls": "tool_calls",     // OpenAI passthrough
+	}
+
+	for reason, want := range tests {
+		if got := NormalizeFinishReason(reason); got != want {
+			t.Errorf("NormalizeFinishReason(%q): got %q, want %q", reason, got, want)
+		}
+	}
+}
+
+// TestSetFinishReasonMappings tests configurable overrides
+func TestSetFinishReasonMappings(t *testing.T) {
+	defer SetFinishReasonMappings(nil)
+
+	if err := SetFinishReasonMappings(map[string]string{"pause_turn": "length", "guardrail_intervened": "stop"}); err != nil {
+		t.Fatalf("SetFinishReasonMappings: %v", err)
+	}
+	if got := NormalizeFinishReason("pause_turn"); got != "length" {
+		t.Errorf("pause_turn: got %q, want length", got)
+	}
+	if got := NormalizeFinishReason("guardrail_intervened"); got != "stop" {
+		t.Errorf("guardrail_intervened override: got %q, want stop", got)
+	}
+	if got := NormalizeFinishReason("max_tokens"); got != "length" {
+		t.Errorf("defaults should be kept: got %q, want length", got)
+	}
+
+	if err := SetFinishReasonMappings(map[string]string{"end_turn": "done"}); err == nil {
+		t.Error("expected error for non-OpenAI target")
+	}
+	if got := NormalizeFinishReason("pause_turn"); got != "length" {
+		t.Error("invalid mapping should leave the previous configuration in place")
+	}
+}
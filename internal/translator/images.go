@@ -0,0 +1,225 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // registers GIF decoding
+	"image/jpeg"
+	_ "image/png" // registers PNG decoding
+	"math"
+	"strings"
+)
+
+// ErrImageTooLarge is returned by ImageLimits.Apply when an image cannot be
+// re-encoded within the byte limit
+var ErrImageTooLarge = errors.New("image exceeds the configured size limit")
+
+// defaultJPEGQuality is used to re-encode images when no quality is configured
+const defaultJPEGQuality = 85
+
+// maxDownscaleAttempts bounds how often an image is shrunk to fit MaxBytes
+const maxDownscaleAttempts = 6
+
+// ImageLimits bounds the inline (data URL) images sent to a provider. Images
+// over a limit are downscaled and re-encoded as JPEG; images within the
+// limits and remote image URLs are sent unchanged.
+type ImageLimits struct {
+	MaxBytes     int `yaml:"max_bytes,omitempty"`     // Decoded image size (0 = unlimited)
+	MaxDimension int `yaml:"max_dimension,omitempty"` // Longest side in pixels (0 = unlimited)
+	JPEGQuality  int `yaml:"jpeg_quality,omitempty"`  // Re-encoding quality, 1-100 (default 85)
+}
+
+// ImageResize describes one downscaled image, for logging
+type ImageResize struct {
+	OriginalBytes  int
+	OriginalWidth  int
+	OriginalHeight int
+	Bytes          int
+	Width          int
+	Height         int
+}
+
+// Enabled reports whether any limit is set
+func (l ImageLimits) Enabled() bool {
+	return l.MaxBytes > 0 || l.MaxDimension > 0
+}
+
+// Validate checks the limits are usable
+func (l ImageLimits) Validate() error {
+	if l.MaxBytes < 0 || l.MaxDimension < 0 {
+		return fmt.Errorf("image_limits must not be negative")
+	}
+	if l.JPEGQuality < 0 || l.JPEGQuality > 100 {
+		return fmt.Errorf("image_limits.jpeg_quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// Apply returns the request with inline images over the limits downscaled,
+// and the resizes made. The request is returned unchanged when no image
+// needs resizing; otherwise messages are copied, not modified. Images in
+// formats that cannot be decoded (such as WebP) are left as they are.
+func (l ImageLimits) Apply(req *ChatCompletionRequest) (*ChatCompletionRequest, []ImageResize, error) {
+	if !l.Enabled() {
+		return req, nil, nil
+	}
+
+	var messages []ChatMessage
+	var resizes []ImageResize
+	for i, msg := range req.Messages {
+		var content MessageContent
+		for j, part := range msg.Content {
+			if part.Type != "image_url" || part.ImageURL == nil {
+				continue
+			}
+			url, resize, err := l.fit(part.ImageURL.URL)
+			if err != nil {
+				return nil, nil, err
+			}
+			if resize == nil {
+				continue
+			}
+			resizes = append(resizes, *resize)
+
+			if content == nil {
+				content = append(MessageContent(nil), msg.Content...)
+			}
+			imageURL := *part.ImageURL
+			imageURL.URL = url
+			content[j].ImageURL = &imageURL
+		}
+		if content == nil {
+			continue
+		}
+		if messages == nil {
+			messages = append([]ChatMessage(nil), req.Messages...)
+		}
+		messages[i].Content = content
+	}
+
+	if messages == nil {
+		return req, nil, nil
+	}
+	out := *req
+	out.Messages = messages
+	return &out, resizes, nil
+}
+
+// fit returns a data URL re-encoded within the limits, or a nil resize if
+// the image is within the limits or cannot be processed
+func (l ImageLimits) fit(url string) (string, *ImageResize, error) {
+	header, payload, ok := strings.Cut(url, ",")
+	if !ok || !strings.HasPrefix(header, "data:image/") || !strings.HasSuffix(header, ";base64") {
+		return url, nil, nil
+	}
+	data, err := base64.StdEncoding.DecodeString(payload)
+	if err != nil {
+		return url, nil, nil
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return url, nil, nil
+	}
+	if l.within(len(data), config.Width, config.Height) {
+		return url, nil, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return url, nil, nil
+	}
+
+	quality := l.JPEGQuality
+	if quality == 0 {
+		quality = defaultJPEGQuality
+	}
+
+	scale := 1.0
+	if l.MaxDimension > 0 {
+		if longest := max(config.Width, config.Height); longest > l.MaxDimension {
+			scale = float64(l.MaxDimension) / float64(longest)
+		}
+	}
+
+	for attempt := 0; attempt < maxDownscaleAttempts; attempt++ {
+		width := max(1, int(math.Round(float64(config.Width)*scale)))
+		height := max(1, int(math.Round(float64(config.Height)*scale)))
+
+		var encoded bytes.Buffer
+		if err := jpeg.Encode(&encoded, downscale(img, width, height), &jpeg.Options{Quality: quality}); err != nil {
+			return "", nil, fmt.Errorf("failed to encode image: %w", err)
+		}
+		if l.MaxBytes == 0 || encoded.Len() <= l.MaxBytes {
+			return "data:image/jpeg;base64," + base64.StdEncoding.EncodeToString(encoded.Bytes()), &ImageResize{
+				OriginalBytes:  len(data),
+				OriginalWidth:  config.Width,
+				OriginalHeight: config.Height,
+				Bytes:          encoded.Len(),
+				Width:          width,
+				Height:         height,
+			}, nil
+		}
+
+		// JPEG size is roughly proportional to the pixel count
+		scale *= math.Sqrt(float64(l.MaxBytes)/float64(encoded.Len())) * 0.9
+	}
+	return "", nil, fmt.Errorf("%w: %dx%d image of %d bytes does not fit in %d bytes",
+		ErrImageTooLarge, config.Width, config.Height, len(data), l.MaxBytes)
+}
+
+// within reports whether an image's size and dimensions are within the limits
+func (l ImageLimits) within(size, width, height int) bool {
+	if l.MaxBytes > 0 && size > l.MaxBytes {
+		return false
+	}
+	if l.MaxDimension > 0 && max(width, height) > l.MaxDimension {
+		return false
+	}
+	return true
+}
+
+// downscale resizes img to width x height by averaging the source pixels
+// under each destination pixel. Transparent areas are flattened onto white,
+// as JPEG has no alpha channel.
+func downscale(img image.Image, width, height int) *image.RGBA {
+	bounds := img.Bounds()
+	src := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(src, src.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.Draw(src, src.Bounds(), img, bounds.Min, draw.Over)
+
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcH / height
+		y1 := max(y0+1, (y+1)*srcH/height)
+		for x := 0; x < width; x++ {
+			x0 := x * srcW / width
+			x1 := max(x0+1, (x+1)*srcW/width)
+
+			var r, g, b, n int
+			for sy := y0; sy < y1; sy++ {
+				row := src.Pix[sy*src.Stride:]
+				for sx := x0; sx < x1; sx++ {
+					r += int(row[sx*4])
+					g += int(row[sx*4+1])
+					b += int(row[sx*4+2])
+					n++
+				}
+			}
+			offset := y*dst.Stride + x*4
+			dst.Pix[offset] = uint8(r / n)
+			dst.Pix[offset+1] = uint8(g / n)
+			dst.Pix[offset+2] = uint8(b / n)
+			dst.Pix[offset+3] = 0xff
+		}
+	}
+	return dst
+}
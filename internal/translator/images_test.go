@@ -0,0 +1,120 @@
+package translator
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// pngDataURL returns a width x height PNG of noise, which compresses
+// poorly, as a data URL
+func pngDataURL(t *testing.T, width, height int) string {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	seed := uint32(1)
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			seed = seed*1664525 + 1013904223
+			img.Set(x, y, color.RGBA{uint8(seed >> 24), uint8(seed >> 16), uint8(seed >> 8), 0xff})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+func imageRequest(urls ...string) *ChatCompletionRequest {
+	content := MessageContent{{Type: "text", Text: "Describe these"}}
+	for _, url := range urls {
+		content = append(content, ContentPart{Type: "image_url", ImageURL: &ImageURL{URL: url, Detail: "high"}})
+	}
+	return &ChatCompletionRequest{
+		Model:    "claude-3-sonnet",
+		Messages: []ChatMessage{{Role: "user", Content: content}},
+	}
+}
+
+func TestImageLimitsDownscale(t *testing.T) {
+	original := pngDataURL(t, 400, 200)
+	req := imageRequest(original, "https://example.com/cat.png")
+
+	out, resizes, err := ImageLimits{MaxDimension: 100}.Apply(req)
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(resizes) != 1 {
+		t.Fatalf("resizes = %+v, want one", resizes)
+	}
+	if r := resizes[0]; r.OriginalWidth != 400 || r.OriginalHeight != 200 || r.Width != 100 || r.Height != 50 {
+		t.Errorf("resize = %+v, want 400x200 -> 100x50", r)
+	}
+
+	part := out.Messages[0].Content[1]
+	header, payload, _ := strings.Cut(part.ImageURL.URL, ",")
+	if header != "data:image/jpeg;base64" || part.ImageURL.Detail != "high" {
+		t.Errorf("image part = %s..., detail %q, want a JPEG data URL with detail kept", header, part.ImageURL.Detail)
+	}
+	data, _ := base64.StdEncoding.DecodeString(payload)
+	img, err := jpeg.Decode(bytes.NewReader(data))
+	if err != nil || img.Bounds().Dx() != 100 || img.Bounds().Dy() != 50 {
+		t.Errorf("downscaled image: %v, err %v; want 100x50 JPEG", img.Bounds(), err)
+	}
+
+	if got := out.Messages[0].Content[2].ImageURL.URL; got != "https://example.com/cat.png" {
+		t.Errorf("remote image URL = %q, want unchanged", got)
+	}
+	if req.Messages[0].Content[1].ImageURL.URL != original {
+		t.Error("Apply modified the original request")
+	}
+}
+
+func TestImageLimitsMaxBytes(t *testing.T) {
+	original := pngDataURL(t, 256, 256)
+	data, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(original, "data:image/png;base64,"))
+	limit := len(data) / 4
+
+	out, resizes, err := ImageLimits{MaxBytes: limit}.Apply(imageRequest(original))
+	if err != nil {
+		t.Fatalf("Apply() error = %v", err)
+	}
+	if len(resizes) != 1 || resizes[0].Bytes > limit || resizes[0].OriginalBytes != len(data) {
+		t.Fatalf("resizes = %+v, want one under %d bytes", resizes, limit)
+	}
+	if !strings.HasPrefix(out.Messages[0].Content[1].ImageURL.URL, "data:image/jpeg;base64,") {
+		t.Error("image was not re-encoded as JPEG")
+	}
+
+	if _, _, err := (ImageLimits{MaxBytes: 10}).Apply(imageRequest(original)); !errors.Is(err, ErrImageTooLarge) {
+		t.Errorf("Apply(MaxBytes 10) error = %v, want ErrImageTooLarge", err)
+	}
+}
+
+func TestImageLimitsWithinLimits(t *testing.T) {
+	req := imageRequest(pngDataURL(t, 64, 64), "data:image/webp;base64,UklGRg==")
+
+	for _, limits := range []ImageLimits{{}, {MaxDimension: 64, MaxBytes: 1 << 20}} {
+		out, resizes, err := limits.Apply(req)
+		if err != nil || out != req || len(resizes) != 0 {
+			t.Errorf("Apply(%+v) = %p, %+v, %v; want the request unchanged", limits, out, resizes, err)
+		}
+	}
+}
+
+func TestImageLimitsValidate(t *testing.T) {
+	for _, limits := range []ImageLimits{{MaxBytes: -1}, {MaxDimension: -1}, {JPEGQuality: 101}} {
+		if err := limits.Validate(); err == nil {
+			t.Errorf("Validate(%+v) = nil, want an error", limits)
+		}
+	}
+	if err := (ImageLimits{MaxBytes: 5 << 20, MaxDimension: 2048, JPEGQuality: 80}).Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// Bedrock Knowledge Base (bedrock-agent-runtime RetrieveAndGenerate) types
+
+// RetrieveAndGenerateRequest represents a RetrieveAndGenerate API request
+type RetrieveAndGenerateRequest struct {
+	Input                            RetrieveAndGenerateInput         `json:"input"`
+	RetrieveAndGenerateConfiguration RetrieveAndGenerateConfiguration `json:"retrieveAndGenerateConfiguration"`
+	SessionID                        string                           `json:"sessionId,omitempty"`
+}
+
+// RetrieveAndGenerateInput represents the user query
+type RetrieveAndGenerateInput struct {
+	Text string `json:"text"`
+}
+
+// RetrieveAndGenerateConfiguration selects the knowledge base and model
+type RetrieveAndGenerateConfiguration struct {
+	Type                       string                     `json:"type"` // KNOWLEDGE_BASE
+	KnowledgeBaseConfiguration KnowledgeBaseConfiguration `json:"knowledgeBaseConfiguration"`
+}
+
+// KnowledgeBaseConfiguration identifies the knowledge base and generation model
+type KnowledgeBaseConfiguration struct {
+	KnowledgeBaseID         string                     `json:"knowledgeBaseId"`
+	ModelArn                string                     `json:"modelArn"`
+	GenerationConfiguration *KBGenerationConfiguration `json:"generationConfiguration,omitempty"`
+}
+
+// KBGenerationConfiguration configures the generation step
+type KBGenerationConfiguration struct {
+	InferenceConfig *KBInferenceConfig `json:"inferenceConfig,omitempty"`
+}
+
+// KBInferenceConfig wraps text inference parameters
+type KBInferenceConfig struct {
+	TextInferenceConfig *KBTextInferenceConfig `json:"textInferenceConfig,omitempty"`
+}
+
+// KBTextInferenceConfig represents text inference parameters
+type KBTextInferenceConfig struct {
+	MaxTokens     int      `json:"maxTokens,omitempty"`
+	Temperature   float64  `json:"temperature,omitempty"`
+	TopP          float64  `json:"topP,omitempty"`
+	StopSequences []string `json:"stopSequences,omitempty"`
+}
+
+// RetrieveAndGenerateResponse represents a RetrieveAndGenerate API response
+type RetrieveAndGenerateResponse struct {
+	Output struct {
+		Text string `json:"text"`
+	} `json:"output"`
+	Citations []KBCitation `json:"citations,omitempty"`
+	SessionID string       `json:"sessionId"`
+}
+
+// KBCitation represents a citation to retrieved sources
+type KBCitation struct {
+	RetrievedReferences []struct {
+		Content struct {
+			Text string `json:"text"`
+		} `json:"content"`
+		Location map[string]interface{} `json:"location,omitempty"`
+	} `json:"retrievedReferences,omitempty"`
+}
+
+// TranslateOpenAIToKnowledgeBase converts an OpenAI chat completion request to
+// a RetrieveAndGenerate request. The last user message becomes the query;
+// earlier turns are not sent (RetrieveAndGenerate keeps history by session).
+// If modelARN is empty, the request model is used when it is an ARN.
+func TranslateOpenAIToKnowledgeBase(openaiReq *ChatCompletionRequest, knowledgeBaseID, modelARN string) (*providers.ProviderRequest, error) {
+	if knowledgeBaseID == "" {
+		return nil, fmt.Errorf("knowledge base ID is required")
+	}
+	if modelARN == "" {
+		if !strings.HasPrefix(openaiReq.Model, "arn:") {
+			return nil, fmt.Errorf("model ARN is required for knowledge base requests (got model %q)", openaiReq.Model)
+		}
+		modelARN = openaiReq.Model
+	}
+
+	var query string
+	for i := len(openaiReq.Messages) - 1; i >= 0; i-- {
+		if msg := openaiReq.Messages[i]; msg.Role == "user" {
+			query = msg.Content.Text()
+			break
+		}
+	}
+	if query == "" {
+		return nil, fmt.Errorf("a user message is required for knowledge base requests")
+	}
+
+	kbConfig := KnowledgeBaseConfiguration{
+		KnowledgeBaseID: knowledgeBaseID,
+		ModelArn:        modelARN,
+	}
+	if openaiReq.MaxTokens > 0 || openaiReq.Temperature > 0 || openaiReq.TopP > 0 || len(openaiReq.Stop) > 0 {
+		kbConfig.GenerationConfiguration = &KBGenerationConfiguration{
+			InferenceConfig: &KBInferenceConfig{
+				TextInferenceConfig: &KBTextInferenceConfig{
+					MaxTokens:     openaiReq.MaxTokens,
+					Temperature:   openaiReq.Temperature,
+					TopP:          openaiReq.TopP,
+					StopSequences: openaiReq.Stop,
+				},
+			},
+		}
+	}
+
+	kbReq := RetrieveAndGenerateRequest{
+		Input: RetrieveAndGenerateInput{Text: query},
+		RetrieveAndGenerateConfiguration: RetrieveAndGenerateConfiguration{
+			Type:                       "KNOWLEDGE_BASE",
+			KnowledgeBaseConfiguration: kbConfig,
+		},
+	}
+
+	body, err := json.Marshal(kbReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal knowledge base request: %w", err)
+	}
+
+	return &providers.ProviderRequest{
+		Method: "POST",
+		Path:   "/retrieveAndGenerate",
+		Headers: http.Header{
+			"Content-Type": {"application/json"},
+		},
+		Body: body,
+	}, nil
+}
+
+// TranslateKnowledgeBaseToOpenAI converts a RetrieveAndGenerate response to OpenAI format
+func TranslateKnowledgeBaseToOpenAI(kbResp *RetrieveAndGenerateResponse, openaiModel string, requestID string) *ChatCompletionResponse {
+	return &ChatCompletionResponse{
+		ID:      requestID,
+		Object:  "chat.completion",
+		Created: currentTimestampUnix(),
+		Model:   openaiModel,
+		Choices: []ChatCompletionChoice{
+			{
+				Index: 0,
+				Message: ChatMessage{
+					Role:    "assistant",
+					Content: TextContent(kbResp.Output.Text),
+				},
+				FinishReason: FinishReasonStop,
+			},
+		},
+	}
+}
@@ -0,0 +1,64 @@
+package translator
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTranslateOpenAIToKnowledgeBase(t *testing.T) {
+	req := &ChatCompletionRequest{
+		Model: "kb",
+		Messages: []ChatMessage{
+			{Role: "system", Content: TextContent("Answer from the handbook.")},
+			{Role: "user", Content: TextContent("What is the PTO policy?")},
+			{Role: "assistant", Content: TextContent("20 days.")},
+			{Role: "user", Content: TextContent("And for contractors?")},
+		},
+		MaxTokens:   256,
+		Temperature: 0.2,
+	}
+
+	providerReq, err := TranslateOpenAIToKnowledgeBase(req, "KB123", "arn:aws:bedrock:us-east-1::foundation-model/anthropic.claude-3-haiku-20240307-v1:0")
+	if err != nil {
+		t.Fatalf("TranslateOpenAIToKnowledgeBase: %v", err)
+	}
+	if providerReq.Path != "/retrieveAndGenerate" {
+		t.Errorf("path: got %q", providerReq.Path)
+	}
+
+	var kbReq RetrieveAndGenerateRequest
+	if err := json.Unmarshal(providerReq.Body, &kbReq); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if kbReq.Input.Text != "And for contractors?" {
+		t.Errorf("input: got %q, want last user message", kbReq.Input.Text)
+	}
+	config := kbReq.RetrieveAndGenerateConfiguration
+	if config.Type != "KNOWLEDGE_BASE" || config.KnowledgeBaseConfiguration.KnowledgeBaseID != "KB123" {
+		t.Errorf("unexpected configuration: %+v", config)
+	}
+	inference := config.KnowledgeBaseConfiguration.GenerationConfiguration.InferenceConfig.TextInferenceConfig
+	if inference.MaxTokens != 256 || inference.Temperature != 0.2 {
+		t.Errorf("unexpected inference config: %+v", inference)
+	}
+
+	if _, err := TranslateOpenAIToKnowledgeBase(req, "KB123", ""); err == nil {
+		t.Error("expected error without a model ARN")
+	}
+	if _, err := TranslateOpenAIToKnowledgeBase(&ChatCompletionRequest{Model: "kb"}, "KB123", "arn:x"); err == nil {
+		t.Error("expected error without a user message")
+	}
+}
+
+func TestTranslateKnowledgeBaseToOpenAI(t *testing.T) {
+	var kbResp RetrieveAndGenerateResponse
+	body := `{"output":{"text":"Contractors accrue 10 days."},"citations":[{"retrievedReferences":[{"content":{"text":"..."}}]}],"sessionId":"s1"}`
+	if err := json.Unmarshal([]byte(body), &kbResp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	resp := TranslateKnowledgeBaseToOpenAI(&kbResp, "kb", "req-1")
+	if len(resp.Choices) != 1 || resp.Choices[0].Message.Content.Text() != "Contractors accrue 10 days." || resp.Choices[0].FinishReason != "stop" {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
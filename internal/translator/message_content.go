@@ -0,0 +1,132 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// MessageContent is the content of a chat message. On the wire it is either
+// a plain string or an array of content parts; both are normalized to parts
+// so translation code has a single representation. A string becomes one text
+// part, and null or absent content is empty.
+type MessageContent []ContentPart
+
+// TextContent returns content holding a single text part
+func TextContent(text string) MessageContent {
+	return MessageContent{{Type: "text", Text: text}}
+}
+
+// Text returns the text parts joined by newlines. Non-text parts are skipped.
+func (m MessageContent) Text() string {
+	var texts []string
+	for _, part := range m {
+		if part.Type == "text" {
+			texts = append(texts, part.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// IsText reports whether the content is a single text part, which is
+// marshaled as a plain string
+func (m MessageContent) IsText() bool {
+	return len(m) == 1 && m[0].Type == "text"
+}
+
+// UnmarshalJSON accepts a string, an array of content parts or null
+func (m *MessageContent) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		*m = nil
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var text string
+		if err := json.Unmarshal(data, &text); err != nil {
+			return err
+		}
+		*m = TextContent(text)
+		return nil
+	case len(data) > 0 && data[0] == '[':
+		var parts []ContentPart
+		if err := json.Unmarshal(data, &parts); err != nil {
+			return err
+		}
+		*m = parts
+		return nil
+	default:
+		return fmt.Errorf("message content must be a string or an array of content parts")
+	}
+}
+
+// MarshalJSON writes a single text part as a plain string and anything else
+// as an array of content parts
+func (m MessageContent) MarshalJSON() ([]byte, error) {
+	if m == nil {
+		return []byte("null"), nil
+	}
+	if m.IsText() {
+		return json.Marshal(m[0].Text)
+	}
+	return json.Marshal([]ContentPart(m))
+}
+
+// contentPartFields is ContentPart without its JSON methods
+type contentPartFields struct {
+	Type     string    `json:"type"`
+	Text     string    `json:"text,omitempty"`
+	ImageURL *ImageURL `json:"image_url,omitempty"`
+	VideoURL *VideoURL `json:"video_url,omitempty"`
+	Video    *VideoURL `json:"video,omitempty"`
+}
+
+// UnmarshalJSON decodes the known part fields and keeps the raw JSON so
+// part types the gateway does not model are forwarded unchanged
+func (p *ContentPart) UnmarshalJSON(data []byte) error {
+	var fields contentPartFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	*p = ContentPart{
+		Type:     fields.Type,
+		Text:     fields.Text,
+		ImageURL: fields.ImageURL,
+		VideoURL: fields.VideoURL,
+	}
+	// {"type": "video", "video": {...}} is an alias for video_url
+	if p.VideoURL == nil {
+		p.VideoURL = fields.Video
+	}
+	if !p.known() {
+		p.raw = append([]byte(nil), data...)
+	}
+	return nil
+}
+
+// MarshalJSON writes known part types from their fields and others as
+// they were received
+func (p ContentPart) MarshalJSON() ([]byte, error) {
+	if !p.known() && p.raw != nil {
+		return p.raw, nil
+	}
+	return json.Marshal(contentPartFields{
+		Type:     p.Type,
+		Text:     p.Text,
+		ImageURL: p.ImageURL,
+		VideoURL: p.VideoURL,
+	})
+}
+
+// known reports whether the part type is modeled by ContentPart's fields
+func (p ContentPart) known() bool {
+	switch p.Type {
+	case "text", "image_url", "video_url", "video":
+		return true
+	}
+	return false
+}
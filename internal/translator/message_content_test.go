@@ -0,0 +1,156 @@
+package translator
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestMessageContentUnmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want MessageContent
+		text string
+	}{
+		{
+			name: "string",
+			json: `{"role":"user","content":"Hello"}`,
+			want: MessageContent{{Type: "text", Text: "Hello"}},
+			text: "Hello",
+		},
+		{
+			name: "empty string",
+			json: `{"role":"assistant","content":""}`,
+			want: MessageContent{{Type: "text"}},
+		},
+		{
+			name: "null",
+			json: `{"role":"assistant","content":null}`,
+		},
+		{
+			name: "absent",
+			json: `{"role":"assistant"}`,
+		},
+		{
+			name: "text parts",
+			json: `{"role":"user","content":[{"type":"text","text":"one"},{"type":"text","text":"two"}]}`,
+			want: MessageContent{{Type: "text", Text: "one"}, {Type: "text", Text: "two"}},
+			text: "one\ntwo",
+		},
+		{
+			name: "text and image",
+			json: `{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA","detail":"low"}}]}`,
+			want: MessageContent{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &ImageURL{URL: "data:image/png;base64,AAAA", Detail: "low"}},
+			},
+			text: "What is this?",
+		},
+		{
+			name: "video alias",
+			json: `{"role":"user","content":[{"type":"video","video":{"url":"s3://bucket/clip.mp4"}}]}`,
+			want: MessageContent{{Type: "video", VideoURL: &VideoURL{URL: "s3://bucket/clip.mp4"}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var msg ChatMessage
+			if err := json.Unmarshal([]byte(tt.json), &msg); err != nil {
+				t.Fatalf("Unmarshal: %v", err)
+			}
+			if !reflect.DeepEqual(msg.Content, tt.want) {
+				t.Errorf("Content = %#v, want %#v", msg.Content, tt.want)
+			}
+			if got := msg.Content.Text(); got != tt.text {
+				t.Errorf("Text() = %q, want %q", got, tt.text)
+			}
+		})
+	}
+}
+
+func TestMessageContentUnmarshalInvalid(t *testing.T) {
+	var msg ChatMessage
+	if err := json.Unmarshal([]byte(`{"role":"user","content":42}`), &msg); err == nil {
+		t.Error("Unmarshal accepted numeric content")
+	}
+}
+
+func TestMessageContentMarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  ChatMessage
+		want string
+	}{
+		{
+			name: "text",
+			msg:  ChatMessage{Role: "user", Content: TextContent("Hello")},
+			want: `{"role":"user","content":"Hello"}`,
+		},
+		{
+			name: "no content",
+			msg:  ChatMessage{Role: "assistant", ToolCallID: "call_1"},
+			want: `{"role":"assistant","tool_call_id":"call_1"}`,
+		},
+		{
+			name: "text and image",
+			msg: ChatMessage{Role: "user", Content: MessageContent{
+				{Type: "text", Text: "What is this?"},
+				{Type: "image_url", ImageURL: &ImageURL{URL: "https://example.com/cat.png"}},
+			}},
+			want: `{"role":"user","content":[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"https://example.com/cat.png"}}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := json.Marshal(tt.msg)
+			if err != nil {
+				t.Fatalf("Marshal: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Marshal = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMessageContentRoundTrip tests that both wire formats, including part
+// types without fields in ContentPart, are forwarded unchanged
+func TestMessageContentRoundTrip(t *testing.T) {
+	for _, wire := range []string{
+		`"Hello"`,
+		`[{"type":"text","text":"What is this?"},{"type":"image_url","image_url":{"url":"data:image/png;base64,AAAA"}}]`,
+		`[{"type":"text","text":"Transcribe"},{"type":"input_audio","input_audio":{"data":"UklG","format":"wav"}}]`,
+	} {
+		var content MessageContent
+		if err := json.Unmarshal([]byte(wire), &content); err != nil {
+			t.Fatalf("Unmarshal(%s): %v", wire, err)
+		}
+		got, err := json.Marshal(content)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		if string(got) != wire {
+			t.Errorf("round trip = %s, want %s", got, wire)
+		}
+	}
+}
+
+func TestConvertToContentBlocksMixed(t *testing.T) {
+	var msg ChatMessage
+	body := `{"role":"user","content":[{"type":"text","text":"Describe"},{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,AAAA"}}]}`
+	if err := json.Unmarshal([]byte(body), &msg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	blocks := convertToContentBlocks(msg.Content)
+	if len(blocks) != 2 {
+		t.Fatalf("got %d blocks, want 2", len(blocks))
+	}
+	if blocks[0].Text == nil || *blocks[0].Text != "Describe" {
+		t.Errorf("block 0 = %+v, want text", blocks[0])
+	}
+	if blocks[1].Image == nil || blocks[1].Image.Format != "jpeg" || blocks[1].Image.Source.Bytes != "AAAA" {
+		t.Errorf("block 1 = %+v, want jpeg image", blocks[1])
+	}
+}
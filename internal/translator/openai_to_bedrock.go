@@ -6,6 +6,7 @@ package translator
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"strings"
 
 	"github.com/tosharewith/llmproxy_auth/internal/providers"
@@ -64,8 +65,8 @@ type BedrockUsage struct {
 
 // TranslateOpenAIToBedrock converts an OpenAI chat completion request to Bedrock format
 func TranslateOpenAIToBedrock(openaiReq *ChatCompletionRequest) (*providers.ProviderRequest, string, error) {
-	// Get the Bedrock model ID
-	bedrockModelID, exists := bedrock.GetBedrockModelID(openaiReq.Model)
+	// Get the Bedrock model ID; aliases and full IDs name the same model
+	bedrockModelID, exists := bedrock.GetBedrockModelID(NormaliseBedrockModelID(openaiReq.Model))
 	if !exists {
 		return nil, "", fmt.Errorf("model %q not supported on Bedrock", openaiReq.Model)
 	}
@@ -77,7 +78,7 @@ func TranslateOpenAIToBedrock(openaiReq *ChatCompletionRequest) (*providers.Prov
 	for _, msg := range openaiReq.Messages {
 		// Handle system messages separately for Claude
 		if msg.Role == "system" {
-			systemPrompt = extractTextContent(msg.Content)
+			systemPrompt = msg.Content.Text()
 			continue
 		}
 
@@ -92,25 +93,19 @@ func TranslateOpenAIToBedrock(openaiReq *ChatCompletionRequest) (*providers.Prov
 		}
 
 		// Handle content
-		switch content := msg.Content.(type) {
-		case string:
-			bedrockMsg.Content = content
-		case []interface{}:
+		if msg.Content.IsText() {
+			bedrockMsg.Content = msg.Content.Text()
+		} else {
 			// Multimodal content
 			blocks := []BedrockContentBlock{}
-			for _, part := range content {
-				if partMap, ok := part.(map[string]interface{}); ok {
-					block := convertContentPart(partMap)
-					if block != nil {
-						blocks = append(blocks, *block)
-					}
+			for _, part := range msg.Content {
+				if block := convertContentPart(part); block != nil {
+					blocks = append(blocks, *block)
 				}
 			}
 			if len(blocks) > 0 {
 				bedrockMsg.Content = blocks
 			}
-		default:
-			bedrockMsg.Content = fmt.Sprintf("%v", content)
 		}
 
 		bedrockMessages = append(bedrockMessages, bedrockMsg)
@@ -150,9 +145,9 @@ func TranslateOpenAIToBedrock(openaiReq *ChatCompletionRequest) (*providers.Prov
 	providerReq := &providers.ProviderRequest{
 		Method: "POST",
 		Path:   path,
-		Headers: map[string]string{
-			"Content-Type": "application/json",
-			"Accept":       "application/json",
+		Headers: http.Header{
+			"Content-Type": {"application/json"},
+			"Accept":       {"application/json"},
 		},
 		Body: body,
 	}
@@ -171,7 +166,7 @@ func TranslateBedrockToOpenAI(bedrockResp *BedrockResponse, openaiModel string,
 	}
 
 	// Map stop reason
-	finishReason := mapStopReason(bedrockResp.StopReason)
+	finishReason := NormalizeFinishReason(bedrockResp.StopReason)
 
 	// Build OpenAI response
 	return &ChatCompletionResponse{
@@ -184,7 +179,7 @@ func TranslateBedrockToOpenAI(bedrockResp *BedrockResponse, openaiModel string,
 				Index: 0,
 				Message: ChatMessage{
 					Role:    "assistant",
-					Content: content,
+					Content: TextContent(content),
 				},
 				FinishReason: finishReason,
 			},
@@ -197,59 +192,31 @@ func TranslateBedrockToOpenAI(bedrockResp *BedrockResponse, openaiModel string,
 	}
 }
 
-// extractTextContent extracts text from content (handles string or array)
-func extractTextContent(content interface{}) string {
-	switch c := content.(type) {
-	case string:
-		return c
-	case []interface{}:
-		var texts []string
-		for _, part := range c {
-			if partMap, ok := part.(map[string]interface{}); ok {
-				if text, ok := partMap["text"].(string); ok {
-					texts = append(texts, text)
-				}
-			}
-		}
-		return strings.Join(texts, "\n")
-	default:
-		return fmt.Sprintf("%v", content)
-	}
-}
-
 // convertContentPart converts an OpenAI content part to Bedrock format
-func convertContentPart(part map[string]interface{}) *BedrockContentBlock {
-	partType, ok := part["type"].(string)
-	if !ok {
-		return nil
-	}
-
-	switch partType {
+func convertContentPart(part ContentPart) *BedrockContentBlock {
+	switch part.Type {
 	case "text":
-		if text, ok := part["text"].(string); ok {
-			return &BedrockContentBlock{
-				Type: "text",
-				Text: text,
-			}
+		return &BedrockContentBlock{
+			Type: "text",
+			Text: part.Text,
 		}
 
 	case "image_url":
-		if imageURL, ok := part["image_url"].(map[string]interface{}); ok {
-			if url, ok := imageURL["url"].(string); ok {
-				// Extract base64 data from data URL
-				if strings.HasPrefix(url, "data:image/") {
-					parts := strings.SplitN(url, ",", 2)
-					if len(parts) == 2 {
-						mediaType := extractMediaType(parts[0])
-						return &BedrockContentBlock{
-							Type: "image",
-							Source: &BedrockImageSource{
-								Type:      "base64",
-								MediaType: mediaType,
-								Data:      parts[1],
-							},
-						}
-					}
+		if part.ImageURL == nil {
+			return nil
+		}
+		// Extract base64 data from data URL
+		if url := part.ImageURL.URL; strings.HasPrefix(url, "data:image/") {
+			parts := strings.SplitN(url, ",", 2)
+			if len(parts) == 2 {
+				mediaType := extractMediaType(parts[0])
+				return &BedrockContentBlock{
+					Type: "image",
+					Source: &BedrockImageSource{
+						Type:      "base64",
+						MediaType: mediaType,
+						Data:      parts[1],
+					},
 				}
 			}
 		}
@@ -269,20 +236,6 @@ func extractMediaType(prefix string) string {
 	return "image/jpeg" // default
 }
 
-// mapStopReason maps Bedrock stop reason to OpenAI finish reason
-func mapStopReason(bedrockReason string) string {
-	switch bedrockReason {
-	case "end_turn":
-		return "stop"
-	case "max_tokens":
-		return "length"
-	case "stop_sequence":
-		return "stop"
-	default:
-		return "stop"
-	}
-}
-
 // currentTimestamp returns current Unix timestamp
 func currentTimestamp() int64 {
 	return 0 // TODO: implement proper timestamp
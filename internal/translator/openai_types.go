@@ -3,6 +3,13 @@
 
 package translator
 
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers/azure"
+)
+
 // OpenAI API request/response types
 
 // ChatCompletionRequest represents an OpenAI chat completion request
@@ -14,6 +21,7 @@ type ChatCompletionRequest struct {
 	TopP             float64                `json:"top_p,omitempty"`
 	N                int                    `json:"n,omitempty"`
 	Stream           bool                   `json:"stream,omitempty"`
+	StreamOptions    *StreamOptions         `json:"stream_options,omitempty"`
 	Stop             []string               `json:"stop,omitempty"`
 	PresencePenalty  float64                `json:"presence_penalty,omitempty"`
 	FrequencyPenalty float64                `json:"frequency_penalty,omitempty"`
@@ -24,23 +32,67 @@ type ChatCompletionRequest struct {
 	Tools            []Tool                 `json:"tools,omitempty"`
 	ToolChoice       interface{}            `json:"tool_choice,omitempty"`
 	ResponseFormat   *ResponseFormat        `json:"response_format,omitempty"`
+
+	// ParallelToolCalls set to false asks the model to call at most one
+	// tool per turn. Unset leaves the provider default (parallel allowed).
+	ParallelToolCalls *bool `json:"parallel_tool_calls,omitempty"`
+
+	// Metadata is forwarded to providers that accept request metadata
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// ServiceTier selects OpenAI's processing tier (auto, default, flex or
+	// priority). Other providers map it to their own options or ignore it.
+	ServiceTier string `json:"service_tier,omitempty"`
+
+	// DataSources ground the reply in the caller's data with Azure OpenAI
+	// On Your Data. They are dropped for other providers.
+	DataSources []azure.AzureDataSource `json:"data_sources,omitempty"`
+
+	// CachePoint is the index of the message through which Bedrock should
+	// cache the prompt, taken from the X-Bedrock-Cache-Point header
+	CachePoint *int `json:"-"`
+
+	// Latency is the Bedrock performanceConfig latency, "standard" or
+	// "optimized", from the X-Bedrock-Latency header or the instance's
+	// bedrock_latency option
+	Latency string `json:"-"`
+
+	// AnthropicVersion is sent to Claude models on Bedrock, taken from the
+	// instance's anthropic_version transformation option
+	AnthropicVersion string `json:"-"`
+}
+
+// StreamOptions represents options for streaming responses
+type StreamOptions struct {
+	IncludeUsage bool `json:"include_usage,omitempty"` // send a final chunk with usage and empty choices
 }
 
 // ChatMessage represents a message in the conversation
 type ChatMessage struct {
 	Role       string       `json:"role"` // system, user, assistant, function, tool
-	Content    interface{}  `json:"content,omitempty"` // string or array of content parts
+	Content    MessageContent `json:"content,omitempty"` // string or array of content parts on the wire
 	Name       string       `json:"name,omitempty"`
 	FunctionCall *FunctionCall `json:"function_call,omitempty"`
 	ToolCalls  []ToolCall   `json:"tool_calls,omitempty"`
 	ToolCallID string       `json:"tool_call_id,omitempty"`
+
+	// CacheControl marks the message as the end of a prompt prefix to
+	// cache, for Claude on Anthropic, Vertex AI and Bedrock
+	CacheControl *CacheControl `json:"cache_control,omitempty"`
+
+	// Context holds the citations of an Azure On Your Data reply
+	Context *azure.AzureContext `json:"context,omitempty"`
 }
 
 // ContentPart represents a part of message content (for multimodal)
 type ContentPart struct {
-	Type     string    `json:"type"` // text or image_url
+	Type     string    `json:"type"` // text, image_url or video_url
 	Text     string    `json:"text,omitempty"`
 	ImageURL *ImageURL `json:"image_url,omitempty"`
+	VideoURL *VideoURL `json:"video_url,omitempty"`
+
+	// raw holds the received JSON of part types without fields here
+	raw []byte
 }
 
 // ImageURL represents an image URL in content
@@ -49,6 +101,13 @@ type ImageURL struct {
 	Detail string `json:"detail,omitempty"` // low, high, auto
 }
 
+// VideoURL represents a video in content (data URL or s3:// URI)
+type VideoURL struct {
+	URL         string `json:"url"`
+	Format      string `json:"format,omitempty"`       // mp4, mov, webm, ...
+	BucketOwner string `json:"bucket_owner,omitempty"` // for s3:// URIs in another account
+}
+
 // Function represents a function definition
 type Function struct {
 	Name        string                 `json:"name"`
@@ -89,6 +148,7 @@ type ChatCompletionResponse struct {
 	SystemFingerprint string                 `json:"system_fingerprint,omitempty"`
 	Choices           []ChatCompletionChoice `json:"choices"`
 	Usage             *Usage                 `json:"usage,omitempty"`
+	ServiceTier       string                 `json:"service_tier,omitempty"` // tier the request was processed with
 }
 
 // ChatCompletionChoice represents a completion choice
@@ -117,16 +177,54 @@ type Usage struct {
 	PromptTokens     int `json:"prompt_tokens"`
 	CompletionTokens int `json:"completion_tokens"`
 	TotalTokens      int `json:"total_tokens"`
+
+	// Prompt cache usage (Bedrock cache points)
+	CacheReadInputTokens  int `json:"cache_read_input_tokens,omitempty"`
+	CacheWriteInputTokens int `json:"cache_write_input_tokens,omitempty"`
+
+	// PromptTokensDetails breaks down the prompt tokens read from and
+	// written to the prompt cache
+	PromptTokensDetails *PromptTokensDetails `json:"prompt_tokens_details,omitempty"`
+}
+
+// PromptTokensDetails is OpenAI's breakdown of prompt tokens. Claude
+// reports cached tokens apart from prompt_tokens, so CachedTokens and
+// CacheCreationTokens are not included in it.
+type PromptTokensDetails struct {
+	CachedTokens        int `json:"cached_tokens"`
+	CacheCreationTokens int `json:"cache_creation_tokens,omitempty"`
+}
+
+// NewCacheUsage returns usage with the prompt cache token counts of Claude
+// on Anthropic or Bedrock set, and prompt_tokens_details when the cache
+// was used
+func NewCacheUsage(promptTokens, completionTokens, cacheRead, cacheWrite int) *Usage {
+	usage := &Usage{
+		PromptTokens:          promptTokens,
+		CompletionTokens:      completionTokens,
+		TotalTokens:           promptTokens + completionTokens,
+		CacheReadInputTokens:  cacheRead,
+		CacheWriteInputTokens: cacheWrite,
+	}
+	if cacheRead > 0 || cacheWrite > 0 {
+		usage.PromptTokensDetails = &PromptTokensDetails{
+			CachedTokens:        cacheRead,
+			CacheCreationTokens: cacheWrite,
+		}
+	}
+	return usage
 }
 
 // ChatCompletionStreamResponse represents a chunk in the stream
 type ChatCompletionStreamResponse struct {
-	ID                string                      `json:"id"`
-	Object            string                      `json:"object"` // chat.completion.chunk
-	Created           int64                       `json:"created"`
-	Model             string                      `json:"model"`
-	SystemFingerprint string                      `json:"system_fingerprint,omitempty"`
+	ID                string                       `json:"id"`
+	Object            string                       `json:"object"` // chat.completion.chunk
+	Created           int64                        `json:"created"`
+	Model             string                       `json:"model"`
+	SystemFingerprint string                       `json:"system_fingerprint,omitempty"`
 	Choices           []ChatCompletionStreamChoice `json:"choices"`
+	Usage             *Usage                       `json:"usage,omitempty"` // final chunk only, with stream_options.include_usage
+	ServiceTier       string                       `json:"service_tier,omitempty"`
 }
 
 // ChatCompletionStreamChoice represents a choice in a streaming response
@@ -139,10 +237,26 @@ type ChatCompletionStreamChoice struct {
 
 // ChatMessageDelta represents a delta in streaming
 type ChatMessageDelta struct {
-	Role         string        `json:"role,omitempty"`
-	Content      string        `json:"content,omitempty"`
-	FunctionCall *FunctionCall `json:"function_call,omitempty"`
-	ToolCalls    []ToolCall    `json:"tool_calls,omitempty"`
+	Role         string          `json:"role,omitempty"`
+	Content      string          `json:"content,omitempty"`
+	FunctionCall *FunctionCall   `json:"function_call,omitempty"`
+	ToolCalls    []ToolCallDelta `json:"tool_calls,omitempty"`
+}
+
+// ToolCallDelta is a fragment of a tool call in a streaming response.
+// Fragments with the same index belong to one call; only the first carries
+// its ID, type and function name.
+type ToolCallDelta struct {
+	Index    int               `json:"index"`
+	ID       string            `json:"id,omitempty"`
+	Type     string            `json:"type,omitempty"`
+	Function FunctionCallDelta `json:"function"`
+}
+
+// FunctionCallDelta is a fragment of a function call in a streaming response
+type FunctionCallDelta struct {
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
 }
 
 // ErrorResponse represents an OpenAI API error
@@ -160,14 +274,137 @@ type ErrorDetail struct {
 
 // ModelsResponse represents a list of models
 type ModelsResponse struct {
-	Object string  `json:"object"` // list
-	Data   []Model `json:"data"`
+	Object string  `json:"object" yaml:"object"` // list
+	Data   []Model `json:"data" yaml:"data"`
 }
 
 // Model represents a model object
 type Model struct {
+	ID      string `json:"id" yaml:"id"`
+	Object  string `json:"object" yaml:"object"` // model
+	Created int64  `json:"created" yaml:"created"`
+	OwnedBy string `json:"owned_by" yaml:"owned_by"`
+}
+
+// Batch represents an OpenAI batch object
+type Batch struct {
+	ID               string             `json:"id"`
+	Object           string             `json:"object"` // batch
+	Endpoint         string             `json:"endpoint"`
+	Errors           *BatchErrors       `json:"errors"`
+	InputFileID      string             `json:"input_file_id"`
+	CompletionWindow string             `json:"completion_window"`
+	Status           string             `json:"status"`
+	OutputFileID     *string            `json:"output_file_id"`
+	ErrorFileID      *string            `json:"error_file_id"`
+	CreatedAt        int64              `json:"created_at"`
+	InProgressAt     *int64             `json:"in_progress_at"`
+	ExpiresAt        *int64             `json:"expires_at"`
+	CompletedAt      *int64             `json:"completed_at"`
+	FailedAt         *int64             `json:"failed_at"`
+	RequestCounts    BatchRequestCounts `json:"request_counts"`
+	Metadata         map[string]string  `json:"metadata"`
+}
+
+// BatchErrors lists errors for a failed batch
+type BatchErrors struct {
+	Object string       `json:"object"` // list
+	Data   []BatchError `json:"data"`
+}
+
+// BatchError is a single batch error
+type BatchError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// BatchRequestCounts contains request counts for a batch
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+// File represents an OpenAI file object
+type File struct {
+	ID        string `json:"id"`
+	Object    string `json:"object"` // file
+	Bytes     int64  `json:"bytes"`
+	CreatedAt int64  `json:"created_at"`
+	Filename  string `json:"filename"`
+	Purpose   string `json:"purpose"`
+}
+
+// FileList is the response of GET /v1/files
+type FileList struct {
+	Object string `json:"object"` // list
+	Data   []File `json:"data"`
+}
+
+// FileDeleted is the response of DELETE /v1/files/{id}
+type FileDeleted struct {
 	ID      string `json:"id"`
-	Object  string `json:"object"` // model
-	Created int64  `json:"created"`
-	OwnedBy string `json:"owned_by"`
+	Object  string `json:"object"` // file
+	Deleted bool   `json:"deleted"`
+}
+
+// EmbeddingsRequest represents an OpenAI embeddings request.
+//
+// InputType is a gateway extension for Cohere Embed v3 models (such as
+// embed-multilingual-v3.0), which require Cohere's input_type:
+// search_document, search_query, classification or clustering. It is sent
+// as input_type to Cohere, natively or on Bedrock, and ignored by other
+// providers.
+//
+// Partial is a gateway extension for inputs split into several provider
+// calls: when set, a failed call fails only its inputs, which are listed in
+// the response's errors, instead of the whole request. It is not sent to
+// providers.
+type EmbeddingsRequest struct {
+	Model          string          `json:"model"`
+	Input          json.RawMessage `json:"input"` // string, []string, or token arrays (OpenAI only)
+	EncodingFormat string          `json:"encoding_format,omitempty"`
+	Dimensions     int             `json:"dimensions,omitempty"`
+	User           string          `json:"user,omitempty"`
+	InputType      string          `json:"input_type,omitempty"`
+	Partial        bool            `json:"partial,omitempty"`
+}
+
+// Texts returns the input as a list of strings; token array inputs return
+// an error
+func (r *EmbeddingsRequest) Texts() ([]string, error) {
+	var text string
+	if err := json.Unmarshal(r.Input, &text); err == nil {
+		return []string{text}, nil
+	}
+	var texts []string
+	if err := json.Unmarshal(r.Input, &texts); err != nil {
+		return nil, fmt.Errorf("input must be a string or an array of strings")
+	}
+	return texts, nil
+}
+
+// EmbeddingsResponse represents an OpenAI embeddings response. Errors
+// lists the inputs that failed in a partial request; Data then has no
+// entries for them.
+type EmbeddingsResponse struct {
+	Object string           `json:"object"` // list
+	Data   []Embedding      `json:"data"`
+	Model  string           `json:"model"`
+	Usage  Usage            `json:"usage"`
+	Errors []EmbeddingError `json:"errors,omitempty"`
+}
+
+// Embedding is the embedding of one input: an array of floats, or a
+// base64 string for encoding_format base64
+type Embedding struct {
+	Object    string          `json:"object"` // embedding
+	Index     int             `json:"index"`
+	Embedding json.RawMessage `json:"embedding"`
+}
+
+// EmbeddingError is the error of one input of a partial embeddings request
+type EmbeddingError struct {
+	Index int         `json:"index"`
+	Error ErrorDetail `json:"error"`
 }
@@ -0,0 +1,102 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import "strings"
+
+// RequestOptions adjust how a request is translated. They are configured
+// per instance under transformation.options.
+type RequestOptions struct {
+	// DefaultMaxTokens is used when the client sends no max_tokens
+	DefaultMaxTokens int `yaml:"default_max_tokens"`
+
+	// AnthropicVersion is sent as anthropic_version to Claude models on
+	// Bedrock and as the anthropic-version header to Anthropic
+	AnthropicVersion string `yaml:"anthropic_version"`
+
+	// ForceSystemMerge folds all system messages into one leading system
+	// message, for targets that accept a single system prompt
+	ForceSystemMerge bool `yaml:"force_system_merge"`
+
+	// DropUnsupportedParams removes the OpenAI-only parameters that
+	// OpenAI-compatible servers commonly reject (n, logit_bias,
+	// presence_penalty, frequency_penalty, parallel_tool_calls)
+	DropUnsupportedParams bool `yaml:"drop_unsupported_params"`
+
+	// ModelOverride replaces the model requested by the client
+	ModelOverride string `yaml:"model_override"`
+
+	// InjectMetadata is merged into the request metadata, overriding keys
+	// sent by the client
+	InjectMetadata map[string]string `yaml:"inject_metadata"`
+
+	// BedrockLatency is the Bedrock performanceConfig latency ("standard"
+	// or "optimized") for requests without an X-Bedrock-Latency header
+	BedrockLatency string `yaml:"bedrock_latency"`
+
+	// AutoCacheSystemPrompt marks long system prompts for prompt caching,
+	// unless the request places its own cache_control markers
+	AutoCacheSystemPrompt bool `yaml:"auto_cache_system_prompt"`
+}
+
+// Apply returns req with the options applied. req itself is not modified.
+func (o RequestOptions) Apply(req *ChatCompletionRequest) *ChatCompletionRequest {
+	out := *req
+
+	if o.ModelOverride != "" {
+		out.Model = o.ModelOverride
+	}
+	if out.MaxTokens == 0 && o.DefaultMaxTokens > 0 {
+		out.MaxTokens = o.DefaultMaxTokens
+	}
+	if o.AnthropicVersion != "" {
+		out.AnthropicVersion = o.AnthropicVersion
+	}
+	if out.Latency == "" && o.BedrockLatency != "" {
+		out.Latency = o.BedrockLatency
+	}
+	if o.ForceSystemMerge {
+		out.Messages = mergeSystemMessages(out.Messages)
+	}
+	if o.AutoCacheSystemPrompt {
+		out.Messages = markSystemPromptCache(out.Messages)
+	}
+	if o.DropUnsupportedParams {
+		out.N = 0
+		out.LogitBias = nil
+		out.PresencePenalty = 0
+		out.FrequencyPenalty = 0
+		out.ParallelToolCalls = nil
+	}
+	if len(o.InjectMetadata) > 0 {
+		metadata := make(map[string]string, len(out.Metadata)+len(o.InjectMetadata))
+		for key, value := range out.Metadata {
+			metadata[key] = value
+		}
+		for key, value := range o.InjectMetadata {
+			metadata[key] = value
+		}
+		out.Metadata = metadata
+	}
+	return &out
+}
+
+// mergeSystemMessages moves the text of all system messages into a single
+// system message at the start of messages
+func mergeSystemMessages(messages []ChatMessage) []ChatMessage {
+	var system []string
+	merged := make([]ChatMessage, 1, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content.Text())
+			continue
+		}
+		merged = append(merged, msg)
+	}
+	if len(system) == 0 {
+		return merged[1:]
+	}
+	merged[0] = ChatMessage{Role: "system", Content: TextContent(strings.Join(system, "\n\n"))}
+	return merged
+}
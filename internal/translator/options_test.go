@@ -0,0 +1,105 @@
+package translator
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRequestOptionsApply(t *testing.T) {
+	disabled := false
+	req := &ChatCompletionRequest{
+		Model: "gpt-4o",
+		Messages: []ChatMessage{
+			{Role: "system", Content: TextContent("Be brief.")},
+			{Role: "user", Content: TextContent("Hi")},
+			{Role: "system", Content: TextContent("Answer in French.")},
+		},
+		N:                 2,
+		LogitBias:         map[string]int{"50256": -100},
+		PresencePenalty:   0.5,
+		FrequencyPenalty:  0.5,
+		ParallelToolCalls: &disabled,
+		Metadata:          map[string]string{"team": "client", "trace": "t1"},
+	}
+	options := RequestOptions{
+		DefaultMaxTokens:      1024,
+		AnthropicVersion:      "bedrock-2023-05-31",
+		ForceSystemMerge:      true,
+		DropUnsupportedParams: true,
+		ModelOverride:         "llama3",
+		InjectMetadata:        map[string]string{"team": "search"},
+	}
+
+	got := options.Apply(req)
+	if got.Model != "llama3" || got.MaxTokens != 1024 || got.AnthropicVersion != "bedrock-2023-05-31" {
+		t.Errorf("model %q, max_tokens %d, anthropic_version %q", got.Model, got.MaxTokens, got.AnthropicVersion)
+	}
+	if len(got.Messages) != 2 || got.Messages[0].Role != "system" || got.Messages[0].Content.Text() != "Be brief.\n\nAnswer in French." || got.Messages[1].Role != "user" {
+		t.Errorf("messages = %+v, want one merged system message then the user message", got.Messages)
+	}
+	if got.N != 0 || got.LogitBias != nil || got.PresencePenalty != 0 || got.FrequencyPenalty != 0 || got.ParallelToolCalls != nil {
+		t.Errorf("unsupported params kept: %+v", got)
+	}
+	if want := map[string]string{"team": "search", "trace": "t1"}; !reflect.DeepEqual(got.Metadata, want) {
+		t.Errorf("metadata = %v, want %v", got.Metadata, want)
+	}
+
+	if req.Model != "gpt-4o" || len(req.Messages) != 3 || req.N != 2 || req.Metadata["team"] != "client" {
+		t.Error("Apply modified the original request")
+	}
+
+	// The client's max_tokens wins over the default
+	req.MaxTokens = 10
+	if got := options.Apply(req); got.MaxTokens != 10 {
+		t.Errorf("max_tokens = %d, want the client's 10", got.MaxTokens)
+	}
+}
+
+func TestMergeSystemMessagesNone(t *testing.T) {
+	messages := []ChatMessage{{Role: "user", Content: TextContent("Hi")}}
+	if got := mergeSystemMessages(messages); len(got) != 1 || got[0].Role != "user" {
+		t.Errorf("mergeSystemMessages = %+v, want messages unchanged", got)
+	}
+}
+
+// TestAutoCacheSystemPrompt tests the system prompt marking of
+// auto_cache_system_prompt: long system prompts are marked on their last
+// system message, short ones and requests with their own markers are not
+func TestAutoCacheSystemPrompt(t *testing.T) {
+	long := strings.Repeat("Follow the style guide closely. ", 200)
+	options := RequestOptions{AutoCacheSystemPrompt: true}
+
+	req := &ChatCompletionRequest{Messages: []ChatMessage{
+		{Role: "system", Content: TextContent(long)},
+		{Role: "system", Content: TextContent(long)},
+		{Role: "user", Content: TextContent("Hi")},
+	}}
+	got := options.Apply(req)
+	if got.Messages[0].CacheControl != nil || got.Messages[1].CacheControl == nil || got.Messages[1].CacheControl.Type != CacheControlEphemeral {
+		t.Errorf("messages = %+v, want the last system message marked", got.Messages)
+	}
+	if req.Messages[1].CacheControl != nil {
+		t.Error("Apply modified the original messages")
+	}
+
+	short := &ChatCompletionRequest{Messages: []ChatMessage{
+		{Role: "system", Content: TextContent("Be brief.")},
+		{Role: "user", Content: TextContent("Hi")},
+	}}
+	if got := options.Apply(short); hasCacheControl(got.Messages) {
+		t.Errorf("short system prompt marked: %+v", got.Messages)
+	}
+
+	own := &ChatCompletionRequest{Messages: []ChatMessage{
+		{Role: "system", Content: TextContent(long)},
+		{Role: "user", Content: TextContent("Document"), CacheControl: &CacheControl{Type: CacheControlEphemeral}},
+	}}
+	if got := options.Apply(own); got.Messages[0].CacheControl != nil {
+		t.Error("system prompt marked in a request with its own cache_control")
+	}
+
+	if got := options.Apply(&ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", Content: TextContent(long)}}}); hasCacheControl(got.Messages) {
+		t.Error("request without a system prompt marked")
+	}
+}
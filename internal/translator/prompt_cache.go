@@ -0,0 +1,117 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"fmt"
+
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+)
+
+// CacheControl marks a message as the end of a prompt prefix to cache. It
+// is Anthropic's cache_control, accepted on OpenAI messages as an
+// extension and sent to Bedrock as a cachePoint block after the message.
+type CacheControl struct {
+	Type string `json:"type"`          // ephemeral
+	TTL  string `json:"ttl,omitempty"` // 5m or 1h; Anthropic only
+}
+
+// CacheControlEphemeral is the only cache_control type
+const CacheControlEphemeral = "ephemeral"
+
+// validCacheTTLs are the cache_control ttl values Anthropic accepts
+var validCacheTTLs = map[string]bool{"": true, "5m": true, "1h": true}
+
+// autoCacheMinTokens is the smallest system prompt auto_cache_system_prompt
+// marks. Claude does not cache shorter prefixes, and for them the marker
+// would only cost a cache write.
+const autoCacheMinTokens = 1024
+
+// validateCacheControl checks the cache_control of a message
+func validateCacheControl(param string, cc *CacheControl) error {
+	if cc == nil {
+		return nil
+	}
+	if cc.Type != CacheControlEphemeral {
+		return invalidValue(param+".type", "cache_control type must be \"ephemeral\", got %q", cc.Type)
+	}
+	if !validCacheTTLs[cc.TTL] {
+		return invalidValue(param+".ttl", "%q is not a valid cache_control ttl; expected 5m or 1h", cc.TTL)
+	}
+	return nil
+}
+
+// hasCacheControl reports whether any message is marked for caching
+func hasCacheControl(messages []ChatMessage) bool {
+	for _, msg := range messages {
+		if msg.CacheControl != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// markSystemPromptCache marks the last system message for caching when the
+// system prompt is long enough for Claude to cache. Requests that place
+// their own cache markers are left as they are. The returned slice is a
+// copy when a message is marked.
+func markSystemPromptCache(messages []ChatMessage) []ChatMessage {
+	if hasCacheControl(messages) {
+		return messages
+	}
+	last, tokens := -1, 0
+	for i, msg := range messages {
+		if msg.Role == "system" {
+			last = i
+			tokens += providers.ApproximateTokens(msg.Content.Text())
+		}
+	}
+	if last < 0 || tokens < autoCacheMinTokens {
+		return messages
+	}
+	marked := make([]ChatMessage, len(messages))
+	copy(marked, messages)
+	marked[last].CacheControl = &CacheControl{Type: CacheControlEphemeral}
+	return marked
+}
+
+// StripCacheControl returns req without cache_control markers, for
+// providers without prompt caching. req itself is not modified.
+func StripCacheControl(req *ChatCompletionRequest) *ChatCompletionRequest {
+	if !hasCacheControl(req.Messages) {
+		return req
+	}
+	stripped := *req
+	stripped.Messages = make([]ChatMessage, len(req.Messages))
+	for i, msg := range req.Messages {
+		msg.CacheControl = nil
+		stripped.Messages[i] = msg
+	}
+	return &stripped
+}
+
+// maxCachePoints is the most cache points Bedrock and Anthropic accept in
+// one request
+const maxCachePoints = 4
+
+// countCachePoints returns the number of cache points of a request: its
+// marked messages and the X-Bedrock-Cache-Point message, counted once
+func countCachePoints(req *ChatCompletionRequest) int {
+	count := 0
+	for i, msg := range req.Messages {
+		if msg.CacheControl != nil || (req.CachePoint != nil && *req.CachePoint == i) {
+			count++
+		}
+	}
+	return count
+}
+
+// checkCachePoints rejects requests with more cache points than providers
+// accept
+func checkCachePoints(req *ChatCompletionRequest) error {
+	if n := countCachePoints(req); n > maxCachePoints {
+		return fmt.Errorf("%d messages are marked for prompt caching; at most %d are allowed", n, maxCachePoints)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import "github.com/tosharewith/llmproxy_auth/internal/providers/bedrock"
+
+// OpenAI service_tier values
+const (
+	ServiceTierAuto     = "auto"
+	ServiceTierDefault  = "default"
+	ServiceTierFlex     = "flex"
+	ServiceTierPriority = "priority"
+)
+
+// validServiceTiers are the service_tier values accepted in chat requests
+var validServiceTiers = map[string]bool{
+	ServiceTierAuto:     true,
+	ServiceTierDefault:  true,
+	ServiceTierFlex:     true,
+	ServiceTierPriority: true,
+}
+
+// converseServiceTierLatency maps the service tiers Bedrock honours to
+// performanceConfig latencies: priority runs latency-optimized
+var converseServiceTierLatency = map[string]string{
+	ServiceTierDefault:  LatencyStandard,
+	ServiceTierPriority: LatencyOptimized,
+}
+
+// ConverseServiceTier returns the service tier a Bedrock request runs
+// with: priority when it is sent latency-optimized, else default. An
+// explicit latency wins over the tier, and optimized latency is dropped for
+// models without it.
+func ConverseServiceTier(req *ChatCompletionRequest) string {
+	bedrockModelID, _ := bedrock.GetBedrockModelID(req.Model)
+	if converseLatency(converseRequestLatency(req), bedrockModelID) == LatencyOptimized {
+		return ServiceTierPriority
+	}
+	return ServiceTierDefault
+}
+
+// converseRequestLatency returns the latency a request asks for, from its
+// Latency or else its service tier
+func converseRequestLatency(req *ChatCompletionRequest) string {
+	if req.Latency != "" {
+		return req.Latency
+	}
+	return converseServiceTierLatency[req.ServiceTier]
+}
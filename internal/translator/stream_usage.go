@@ -0,0 +1,132 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+)
+
+// charsPerToken is a rough average used when a provider does not report usage
+const charsPerToken = 4
+
+// StreamDone is the data payload of the final SSE event in an OpenAI stream
+const StreamDone = "[DONE]"
+
+// StreamUsageTracker accumulates token usage across an OpenAI-format chat
+// completion stream so a final usage chunk can be emitted when the client
+// asks for stream_options.include_usage. Usage reported by the provider
+// in-stream is preferred; otherwise tokens are estimated from text length.
+type StreamUsageTracker struct {
+	id              string
+	model           string
+	created         int64
+	promptTokens    int
+	completionChars int
+	nativeUsage     *Usage
+}
+
+// NewStreamUsageTracker creates a tracker for a streaming request
+func NewStreamUsageTracker(req *ChatCompletionRequest) *StreamUsageTracker {
+	promptChars := 0
+	for _, msg := range req.Messages {
+		if msg.Content != nil {
+			promptChars += len(msg.Content.Text())
+		}
+	}
+
+	return &StreamUsageTracker{
+		model:        req.Model,
+		created:      time.Now().Unix(),
+		promptTokens: EstimateTokens(promptChars),
+	}
+}
+
+// IncludeUsage reports whether the request asked for a final usage chunk
+func IncludeUsage(req *ChatCompletionRequest) bool {
+	return req.Stream && req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+}
+
+// EstimateTokens estimates a token count from a character count
+func EstimateTokens(chars int) int {
+	if chars <= 0 {
+		return 0
+	}
+	return (chars + charsPerToken - 1) / charsPerToken
+}
+
+// Observe records the data payload of one SSE event
+func (t *StreamUsageTracker) Observe(data []byte) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == StreamDone {
+		return
+	}
+
+	var chunk ChatCompletionStreamResponse
+	if err := json.Unmarshal(data, &chunk); err != nil {
+		return
+	}
+
+	if chunk.ID != "" {
+		t.id = chunk.ID
+	}
+	if chunk.Model != "" {
+		t.model = chunk.Model
+	}
+	if chunk.Created != 0 {
+		t.created = chunk.Created
+	}
+	if chunk.Usage != nil {
+		t.nativeUsage = chunk.Usage
+	}
+
+	for _, choice := range chunk.Choices {
+		t.completionChars += len(choice.Delta.Content)
+		if choice.Delta.FunctionCall != nil {
+			t.completionChars += len(choice.Delta.FunctionCall.Arguments)
+		}
+		for _, call := range choice.Delta.ToolCalls {
+			t.completionChars += len(call.Function.Arguments)
+		}
+	}
+}
+
+// SetUsage records usage reported by the provider outside the chunks, as
+// though it had been sent in-stream
+func (t *StreamUsageTracker) SetUsage(usage Usage) {
+	t.nativeUsage = &usage
+}
+
+// HasNativeUsage reports whether the provider already sent usage in-stream
+func (t *StreamUsageTracker) HasNativeUsage() bool {
+	return t.nativeUsage != nil
+}
+
+// Usage returns the provider-reported usage, or an estimate if none was sent
+func (t *StreamUsageTracker) Usage() Usage {
+	if t.nativeUsage != nil {
+		return *t.nativeUsage
+	}
+
+	completionTokens := EstimateTokens(t.completionChars)
+	return Usage{
+		PromptTokens:     t.promptTokens,
+		CompletionTokens: completionTokens,
+		TotalTokens:      t.promptTokens + completionTokens,
+	}
+}
+
+// UsageChunk builds the terminal usage chunk: empty choices plus usage
+func (t *StreamUsageTracker) UsageChunk() *ChatCompletionStreamResponse {
+	usage := t.Usage()
+	return &ChatCompletionStreamResponse{
+		ID:      t.id,
+		Object:  "chat.completion.chunk",
+		Created: t.created,
+		Model:   t.model,
+		Choices: []ChatCompletionStreamChoice{},
+		Usage:   &usage,
+	}
+}
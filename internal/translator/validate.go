@@ -0,0 +1,224 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package translator
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidationError is a request field that fails validation. Param names the
+// field the way OpenAI's error.param does, e.g. messages[1].role.
+type ValidationError struct {
+	Param   string
+	Code    string
+	Message string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Message
+}
+
+// validRoles are the message roles accepted in chat requests
+var validRoles = map[string]bool{
+	"system":    true,
+	"developer": true,
+	"user":      true,
+	"assistant": true,
+	"tool":      true,
+	"function":  true,
+}
+
+// functionNamePattern is the function name format OpenAI accepts
+var functionNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// jsonSchemaTypes are the JSON Schema primitive type names
+var jsonSchemaTypes = map[string]bool{
+	"object":  true,
+	"array":   true,
+	"string":  true,
+	"number":  true,
+	"integer": true,
+	"boolean": true,
+	"null":    true,
+}
+
+// ValidateChatRequest checks a chat request before it is routed: messages
+// are present with known roles, tool and function definitions are named and
+// carry a usable JSON schema, and sampling parameters are in range. It
+// returns the first *ValidationError found.
+func ValidateChatRequest(req *ChatCompletionRequest) error {
+	if len(req.Messages) == 0 {
+		return &ValidationError{Param: "messages", Code: "missing_required_parameter", Message: "messages must contain at least one message"}
+	}
+	for i, msg := range req.Messages {
+		if !validRoles[msg.Role] {
+			return invalidValue(fmt.Sprintf("messages[%d].role", i),
+				"%q is not a valid role; expected system, developer, user, assistant, tool or function", msg.Role)
+		}
+		if err := validateCacheControl(fmt.Sprintf("messages[%d].cache_control", i), msg.CacheControl); err != nil {
+			return err
+		}
+	}
+	if err := checkCachePoints(req); err != nil {
+		return invalidValue("messages", "%v", err)
+	}
+
+	for i, tool := range req.Tools {
+		param := fmt.Sprintf("tools[%d]", i)
+		if tool.Type != "function" {
+			return invalidValue(param+".type", "tool type must be \"function\", got %q", tool.Type)
+		}
+		if err := validateFunction(param+".function", tool.Function); err != nil {
+			return err
+		}
+	}
+	for i, function := range req.Functions {
+		if err := validateFunction(fmt.Sprintf("functions[%d]", i), function); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case req.Temperature < 0 || req.Temperature > 2:
+		return invalidValue("temperature", "temperature must be between 0 and 2, got %g", req.Temperature)
+	case req.TopP < 0 || req.TopP > 1:
+		return invalidValue("top_p", "top_p must be between 0 and 1, got %g", req.TopP)
+	case req.PresencePenalty < -2 || req.PresencePenalty > 2:
+		return invalidValue("presence_penalty", "presence_penalty must be between -2 and 2, got %g", req.PresencePenalty)
+	case req.FrequencyPenalty < -2 || req.FrequencyPenalty > 2:
+		return invalidValue("frequency_penalty", "frequency_penalty must be between -2 and 2, got %g", req.FrequencyPenalty)
+	case req.MaxTokens < 0:
+		return invalidValue("max_tokens", "max_tokens must be a positive integer, got %d", req.MaxTokens)
+	case req.N < 0:
+		return invalidValue("n", "n must be a positive integer, got %d", req.N)
+	case req.ServiceTier != "" && !validServiceTiers[req.ServiceTier]:
+		return invalidValue("service_tier", "%q is not a valid service_tier; expected auto, default, flex or priority", req.ServiceTier)
+	}
+	return nil
+}
+
+// ValidateMaxTokens checks max_tokens against a model's output token cap;
+// a zero cap is not checked
+func ValidateMaxTokens(req *ChatCompletionRequest, maxOutputTokens int) error {
+	if maxOutputTokens > 0 && req.MaxTokens > maxOutputTokens {
+		return invalidValue("max_tokens", "max_tokens is %d, but model %s supports at most %d output tokens", req.MaxTokens, req.Model, maxOutputTokens)
+	}
+	return nil
+}
+
+// UnknownChatFields returns the top-level fields of a JSON chat request that
+// ChatCompletionRequest does not define, sorted
+func UnknownChatFields(body []byte) []string {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil
+	}
+	var unknown []string
+	for field := range fields {
+		if !chatRequestFields[field] {
+			unknown = append(unknown, field)
+		}
+	}
+	sort.Strings(unknown)
+	return unknown
+}
+
+// chatRequestFields are the JSON names of ChatCompletionRequest's fields
+var chatRequestFields = func() map[string]bool {
+	fields := make(map[string]bool)
+	t := reflect.TypeOf(ChatCompletionRequest{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			fields[name] = true
+		}
+	}
+	return fields
+}()
+
+// validateFunction checks a function definition's name and parameters
+func validateFunction(param string, function Function) error {
+	if function.Name == "" {
+		return &ValidationError{Param: param + ".name", Code: "missing_required_parameter", Message: "function name is required"}
+	}
+	if !functionNamePattern.MatchString(function.Name) {
+		return invalidValue(param+".name", "function name %q must be 1-64 letters, digits, underscores or dashes", function.Name)
+	}
+	if function.Parameters == nil {
+		return nil
+	}
+	if schemaType, _ := function.Parameters["type"].(string); schemaType != "object" {
+		return invalidValue(param+".parameters", "function parameters must be a JSON schema of type \"object\"")
+	}
+	if err := validateSchema(function.Parameters); err != nil {
+		return invalidValue(param+".parameters", "invalid JSON schema: %v", err)
+	}
+	return nil
+}
+
+// validateSchema checks the structural keywords of a JSON schema: type
+// names, properties and items are schemas, and required lists strings
+func validateSchema(schema map[string]interface{}) error {
+	switch schemaType := schema["type"].(type) {
+	case nil:
+	case string:
+		if !jsonSchemaTypes[schemaType] {
+			return fmt.Errorf("unknown type %q", schemaType)
+		}
+	case []interface{}:
+		for _, t := range schemaType {
+			if name, ok := t.(string); !ok || !jsonSchemaTypes[name] {
+				return fmt.Errorf("unknown type %v", t)
+			}
+		}
+	default:
+		return fmt.Errorf("type must be a string or a list of strings")
+	}
+
+	if properties, ok := schema["properties"]; ok {
+		props, ok := properties.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("properties must be an object")
+		}
+		for name, property := range props {
+			propSchema, ok := property.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("property %q must be a schema object", name)
+			}
+			if err := validateSchema(propSchema); err != nil {
+				return fmt.Errorf("property %q: %w", name, err)
+			}
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		itemSchema, ok := items.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("items must be a schema object")
+		}
+		if err := validateSchema(itemSchema); err != nil {
+			return fmt.Errorf("items: %w", err)
+		}
+	}
+	if required, ok := schema["required"]; ok {
+		names, ok := required.([]interface{})
+		if !ok {
+			return fmt.Errorf("required must be a list of property names")
+		}
+		for _, name := range names {
+			if _, ok := name.(string); !ok {
+				return fmt.Errorf("required must be a list of property names")
+			}
+		}
+	}
+	return nil
+}
+
+func invalidValue(param, format string, args ...interface{}) *ValidationError {
+	return &ValidationError{Param: param, Code: "invalid_value", Message: fmt.Sprintf(format, args...)}
+}
@@ -0,0 +1,91 @@
+package translator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestValidateChatRequest(t *testing.T) {
+	user := []ChatMessage{{Role: "user", Content: TextContent("Hi")}}
+	object := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"city": map[string]interface{}{"type": "string"}},
+		"required":   []interface{}{"city"},
+	}
+
+	tests := []struct {
+		name      string
+		req       ChatCompletionRequest
+		wantParam string
+	}{
+		{"valid", ChatCompletionRequest{Messages: user, Temperature: 2, TopP: 1, MaxTokens: 10}, ""},
+		{"no messages", ChatCompletionRequest{}, "messages"},
+		{"bad role", ChatCompletionRequest{Messages: append(user, ChatMessage{Role: "bot"})}, "messages[1].role"},
+		{"developer role", ChatCompletionRequest{Messages: []ChatMessage{{Role: "developer"}, user[0]}}, ""},
+		{"tool without name", ChatCompletionRequest{Messages: user, Tools: []Tool{{Type: "function"}}}, "tools[0].function.name"},
+		{"tool bad name", ChatCompletionRequest{Messages: user, Tools: []Tool{{Type: "function", Function: Function{Name: "get weather"}}}}, "tools[0].function.name"},
+		{"tool bad type", ChatCompletionRequest{Messages: user, Tools: []Tool{{Type: "retrieval", Function: Function{Name: "f"}}}}, "tools[0].type"},
+		{"tool schema", ChatCompletionRequest{Messages: user, Tools: []Tool{{Type: "function", Function: Function{Name: "get_weather", Parameters: object}}}}, ""},
+		{"tool schema not object", ChatCompletionRequest{Messages: user, Tools: []Tool{{Type: "function", Function: Function{Name: "f", Parameters: map[string]interface{}{"type": "string"}}}}}, "tools[0].function.parameters"},
+		{"tool schema bad property", ChatCompletionRequest{Messages: user, Tools: []Tool{{Type: "function", Function: Function{Name: "f", Parameters: map[string]interface{}{
+			"type": "object", "properties": map[string]interface{}{"n": map[string]interface{}{"type": "int"}},
+		}}}}}, "tools[0].function.parameters"},
+		{"tool schema bad required", ChatCompletionRequest{Messages: user, Tools: []Tool{{Type: "function", Function: Function{Name: "f", Parameters: map[string]interface{}{
+			"type": "object", "required": "city",
+		}}}}}, "tools[0].function.parameters"},
+		{"function without name", ChatCompletionRequest{Messages: user, Functions: []Function{{}}}, "functions[0].name"},
+		{"temperature high", ChatCompletionRequest{Messages: user, Temperature: 2.5}, "temperature"},
+		{"temperature negative", ChatCompletionRequest{Messages: user, Temperature: -1}, "temperature"},
+		{"top_p high", ChatCompletionRequest{Messages: user, TopP: 1.5}, "top_p"},
+		{"presence_penalty", ChatCompletionRequest{Messages: user, PresencePenalty: 3}, "presence_penalty"},
+		{"frequency_penalty", ChatCompletionRequest{Messages: user, FrequencyPenalty: -3}, "frequency_penalty"},
+		{"service_tier", ChatCompletionRequest{Messages: user, ServiceTier: "flex"}, ""},
+		{"bad service_tier", ChatCompletionRequest{Messages: user, ServiceTier: "scale"}, "service_tier"},
+		{"cache_control", ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", CacheControl: &CacheControl{Type: "ephemeral", TTL: "1h"}}}}, ""},
+		{"cache_control type", ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", CacheControl: &CacheControl{Type: "persistent"}}}}, "messages[0].cache_control.type"},
+		{"cache_control ttl", ChatCompletionRequest{Messages: []ChatMessage{{Role: "user", CacheControl: &CacheControl{Type: "ephemeral", TTL: "1d"}}}}, "messages[0].cache_control.ttl"},
+		{"max_tokens negative", ChatCompletionRequest{Messages: user, MaxTokens: -1}, "max_tokens"},
+		{"n negative", ChatCompletionRequest{Messages: user, N: -2}, "n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateChatRequest(&tt.req)
+			if tt.wantParam == "" {
+				if err != nil {
+					t.Fatalf("ValidateChatRequest() = %v, want nil", err)
+				}
+				return
+			}
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("ValidateChatRequest() = %v, want a *ValidationError", err)
+			}
+			if validationErr.Param != tt.wantParam {
+				t.Errorf("param = %q, want %q (%v)", validationErr.Param, tt.wantParam, err)
+			}
+		})
+	}
+}
+
+func TestValidateMaxTokens(t *testing.T) {
+	req := &ChatCompletionRequest{Model: "claude-3-opus", MaxTokens: 8192}
+	if err := ValidateMaxTokens(req, 0); err != nil {
+		t.Errorf("uncapped model: %v", err)
+	}
+	if err := ValidateMaxTokens(req, 8192); err != nil {
+		t.Errorf("at the cap: %v", err)
+	}
+	var validationErr *ValidationError
+	if err := ValidateMaxTokens(req, 4096); !errors.As(err, &validationErr) || validationErr.Param != "max_tokens" {
+		t.Errorf("over the cap: %v", err)
+	}
+}
+
+func TestUnknownChatFields(t *testing.T) {
+	got := UnknownChatFields([]byte(`{"model":"m","messages":[],"seed":1,"parallel_tool_calls":false,"logprobs":true,"CachePoint":1}`))
+	if want := []string{"CachePoint", "logprobs", "seed"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UnknownChatFields = %v, want %v", got, want)
+	}
+}
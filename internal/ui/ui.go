@@ -0,0 +1,31 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package ui serves a minimal status page for operators. The page polls the
+// gateway's health and metrics endpoints from the browser; it has no server
+// side state of its own.
+package ui
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+)
+
+//go:embed ui/*
+var embeddedFS embed.FS
+
+// FS returns the UI files, rooted at the ui directory
+func FS() fs.FS {
+	files, err := fs.Sub(embeddedFS, "ui")
+	if err != nil {
+		// The directory is embedded at build time
+		panic(err)
+	}
+	return files
+}
+
+// Handler serves the UI files for requests under prefix, such as /ui
+func Handler(prefix string) http.Handler {
+	return http.StripPrefix(prefix, http.FileServer(http.FS(FS())))
+}
@@ -0,0 +1,32 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHandlerServesIndex(t *testing.T) {
+	handler := Handler("/ui")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ui/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /ui/ status = %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("Content-Type = %q, want text/html", ct)
+	}
+	for _, endpoint := range []string{"/health/providers", "/metrics"} {
+		if !strings.Contains(w.Body.String(), endpoint) {
+			t.Errorf("index.html does not poll %s", endpoint)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ui/missing.js", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /ui/missing.js status = %d, want 404", w.Code)
+	}
+}
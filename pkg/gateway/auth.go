@@ -0,0 +1,68 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package gateway
+
+import (
+	"errors"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+)
+
+// AuthConfig selects how the gateway authenticates its API, admin and
+// provider routes. The zero value serves them without authentication, for
+// services that authenticate requests before they reach Handler.
+type AuthConfig struct {
+	// Mode is api_key, basic or service_account; empty disables
+	// authentication
+	Mode string
+
+	// APIKeys maps each accepted API key to its name (api_key)
+	APIKeys map[string]string
+
+	// BasicCredentials maps usernames to passwords (basic)
+	BasicCredentials map[string]string
+
+	// ServiceAccounts are the accepted namespace/name service accounts
+	// (service_account)
+	ServiceAccounts []string
+}
+
+// Enabled reports whether requests are authenticated
+func (a AuthConfig) Enabled() bool {
+	return a.Mode != ""
+}
+
+// newAuthMiddleware returns the middleware of the configured auth mode, or
+// nil when authentication is disabled
+func newAuthMiddleware(auth AuthConfig) (gin.HandlerFunc, error) {
+	switch auth.Mode {
+	case "":
+		return nil, nil
+
+	case "api_key":
+		if len(auth.APIKeys) == 0 {
+			return nil, errors.New("API key auth enabled but no API keys configured")
+		}
+		log.Printf("Loaded %d API keys", len(auth.APIKeys))
+		return middleware.APIKeyAuth(auth.APIKeys), nil
+
+	case "basic":
+		if len(auth.BasicCredentials) == 0 {
+			return nil, errors.New("basic auth enabled but no credentials found")
+		}
+		return middleware.BasicAuth(auth.BasicCredentials), nil
+
+	case "service_account":
+		if len(auth.ServiceAccounts) == 0 {
+			return nil, errors.New("service account auth enabled but no allowed accounts found")
+		}
+		return middleware.ServiceAccountAuth(auth.ServiceAccounts), nil
+
+	default:
+		log.Printf("Unknown auth mode: %s, running without auth", auth.Mode)
+		return nil, nil
+	}
+}
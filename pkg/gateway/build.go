@@ -0,0 +1,465 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/tosharewith/llmproxy_auth/internal/audit"
+	"github.com/tosharewith/llmproxy_auth/internal/batch"
+	"github.com/tosharewith/llmproxy_auth/internal/chaos"
+	"github.com/tosharewith/llmproxy_auth/internal/diagnostics"
+	"github.com/tosharewith/llmproxy_auth/internal/handlers"
+	"github.com/tosharewith/llmproxy_auth/internal/health"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
+	"github.com/tosharewith/llmproxy_auth/internal/pricing"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/anthropic"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/bedrock"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/bootstrap"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+	"github.com/tosharewith/llmproxy_auth/internal/translator"
+	"github.com/tosharewith/llmproxy_auth/internal/ui"
+)
+
+// build creates the gateway's components and registers its routes
+func (g *Gateway) build() error {
+	config := g.config
+
+	authMiddleware, err := newAuthMiddleware(config.Auth)
+	if err != nil {
+		return err
+	}
+
+	// Initialize components
+	healthChecker := health.NewCheckerWithConfig(config.Health)
+
+	// Webhook notifications of provider failures and other operational events
+	var notifier *notify.Notifier
+	if config.Notify.Enabled() {
+		notifier, err = notify.New(config.Notify)
+		if err != nil {
+			return fmt.Errorf("invalid notification configuration: %w", err)
+		}
+		healthChecker.SetNotifier(notifier)
+		log.Printf("✓ Webhook notifications enabled: %d webhooks", len(config.Notify.URLs))
+	}
+
+	// Load router configuration
+	log.Printf("Loading model mapping configuration from: %s", config.ModelMappingFile)
+	routerConfig, err := router.LoadConfig(config.ModelMappingFile)
+	if err != nil {
+		return fmt.Errorf("failed to load router config: %w", err)
+	}
+	log.Println("✓ Model mapping configuration loaded")
+
+	if err := translator.SetFinishReasonMappings(routerConfig.FinishReasons); err != nil {
+		return fmt.Errorf("invalid finish_reasons configuration: %w", err)
+	}
+
+	// Load provider instances configuration for transparent and protocol modes
+	var instanceConfig *instance.Config
+	if config.ProviderInstancesFile != "" {
+		log.Printf("Loading provider instances configuration from: %s", config.ProviderInstancesFile)
+		instanceConfig, err = instance.LoadConfig(config.ProviderInstancesFile)
+		if err != nil {
+			log.Printf("Warning: Failed to load provider instances config: %v", err)
+			log.Println("Continuing without transparent/protocol mode support")
+			instanceConfig = nil
+		} else {
+			log.Println("✓ Provider instances configuration loaded")
+		}
+	}
+	// Handlers load the config in effect from g.instances on each request,
+	// as feature flag reloads replace it
+	g.instances.Store(instanceConfig)
+
+	// Initialize the providers of every configured instance type and every
+	// provider enabled for model routing
+	log.Println("Initializing providers...")
+	providerTypes := routerConfig.ListEnabledProviders()
+	var instances map[string]instance.InstanceConfig
+	if instanceConfig != nil {
+		providerTypes = append(providerTypes, instanceConfig.ProviderTypes()...)
+		instances = instanceConfig.Instances
+	}
+	factories := bootstrap.Default()
+	providerRegistry := factories.Build(providerTypes, instances)
+	instanceProviders := factories.BuildInstances(instances)
+	for name, provider := range config.Providers {
+		providerRegistry[name] = provider
+		log.Printf("✓ Provider %s supplied by the embedding service", name)
+	}
+
+	// Batch inference needs an S3 location and a service role
+	if bedrockProvider, ok := providerRegistry["bedrock"].(*bedrock.BedrockProvider); ok && config.BedrockBatchS3URI != "" {
+		enableBedrockBatch(bedrockProvider, config.AWSRegion, config.BedrockBatchS3URI, config.BedrockBatchRoleARN)
+	}
+
+	if len(providerRegistry) == 0 {
+		return errors.New("no providers initialized, please configure at least one provider")
+	}
+	log.Printf("Total providers initialized: %d", len(providerRegistry))
+
+	// Initialize router. The config is checked against the providers as a
+	// reload is, so a config accepted at startup is accepted again on reload
+	aiRouter, err := router.NewRouter(routerConfig, providerRegistry)
+	if err != nil {
+		return fmt.Errorf("failed to create router: %w", err)
+	}
+	if errs := router.ValidateAgainstProviders(routerConfig, providerRegistry); len(errs) > 0 {
+		return fmt.Errorf("configuration does not match the providers: %w", errors.Join(errs...))
+	}
+	aiRouter.SetHealthThresholds(config.HealthThresholds)
+	aiRouter.SetNotifier(notifier)
+	// Model mappings are reloaded on SIGHUP with HandleSignals and, if
+	// polled, when the file changes; configs that do not match the
+	// providers are rejected
+	if config.HandleSignals || config.ConfigWatchInterval > 0 {
+		aiRouter.LoadConfigWatcher(g.ctx, config.ModelMappingFile, config.ConfigWatchInterval, config.HandleSignals)
+	}
+	g.router = aiRouter
+	log.Println("✓ Router initialized")
+
+	// Validate configuration
+	g.enabledProviders = routerConfig.ListEnabledProviders()
+	log.Printf("Enabled providers: %s", strings.Join(g.enabledProviders, ", "))
+
+	if instanceConfig != nil {
+		for _, providerType := range instanceConfig.RequiredProviderTypes() {
+			aiRouter.RequireProvider(providerType)
+		}
+		transparentInstances := instanceConfig.ListInstancesByMode("transparent")
+		protocolInstances := instanceConfig.ListInstancesByMode("protocol")
+		log.Printf("  - Transparent mode instances: %d", len(transparentInstances))
+		log.Printf("  - Protocol mode instances: %d", len(protocolInstances))
+	}
+
+	// Optional warm-up: acquire credentials and reach each provider before serving
+	if config.StrictStartup || config.WarmUp {
+		log.Println("Warming up providers...")
+		statuses := aiRouter.WarmUp(g.ctx, config.WarmUpTimeout)
+		if config.StrictStartup && !router.IsReady(statuses) {
+			return errors.New("strict startup: one or more required providers are unreachable")
+		}
+	}
+
+	// Optional background health checks; probes then report their results
+	// instead of health-checking every provider on each request
+	backgroundChecks := config.HealthCheckInterval > 0
+	if backgroundChecks {
+		go aiRouter.RunHealthChecks(g.ctx, config.HealthCheckInterval, config.HealthCheckTimeout)
+		log.Printf("Background provider health checks every %s", config.HealthCheckInterval)
+	}
+
+	// Responses are priced when the model mapping config has a pricing table
+	var priceTable *pricing.Table
+	if routerConfig.Pricing.Enabled() {
+		priceTable, err = pricing.New(routerConfig.Pricing)
+		if err != nil {
+			return fmt.Errorf("invalid pricing configuration: %w", err)
+		}
+		priceTable.SetNotifier(notifier)
+		log.Printf("✓ Pricing for %d models and %d providers", len(routerConfig.Pricing.Models), len(routerConfig.Pricing.Providers))
+	}
+
+	// Initialize handlers
+	openaiHandler := handlers.NewOpenAIHandler(aiRouter)
+	openaiHandler.SetPricing(priceTable)
+	openaiHandler.SetStreamBackpressure(config.StreamBackpressure)
+	if instanceConfig != nil {
+		openaiHandler.SetOutputTokenLimit(instanceConfig.Global.OutputTokenLimit)
+	}
+	rerankHandler := handlers.NewRerankHandler(providerRegistry)
+	rerankHandler.SetDrainState(aiRouter)
+	tokenizeHandler := handlers.NewTokenizeHandler(aiRouter)
+	embeddingsHandler := handlers.NewEmbeddingsHandler(providerRegistry)
+	embeddingsHandler.SetConcurrency(config.EmbeddingsConcurrency)
+	embeddingsHandler.SetDrainState(aiRouter)
+	routeHandler := handlers.NewRouteHandler(aiRouter, &g.instances)
+	adminHandler := handlers.NewAdminHandler(aiRouter)
+	adminHandler.SetNotifier(notifier)
+	if instanceConfig != nil {
+		adminHandler.SetInstanceConfig(&g.instances, config.ProviderInstancesFile)
+	}
+	batchHandler := handlers.NewBatchHandler(aiRouter, batch.NewMemoryStore())
+
+	// The Files API is served by Anthropic's Files API
+	var filesHandler *handlers.FilesHandler
+	if anthropicProvider, ok := providerRegistry["anthropic"].(*anthropic.AnthropicProvider); ok {
+		filesHandler = handlers.NewFilesHandler(anthropicProvider)
+	}
+
+	// Request limiters either reject (enforce) or only count (report_only)
+	limitMode, err := ratelimit.ParseMode(config.LimitMode)
+	if err != nil {
+		return err
+	}
+
+	// Initialize transparent and protocol handlers if config is available
+	var transparentHandler *handlers.TransparentHandler
+	var protocolHandler *handlers.ProtocolHandler
+	var semaphores *providers.SemaphoreRegistry
+	if instanceConfig != nil {
+		transparentHandler = handlers.NewTransparentHandler(providerRegistry, &g.instances)
+		protocolHandler = handlers.NewProtocolHandler(providerRegistry, &g.instances, healthChecker)
+		semaphores = newInstanceSemaphores(instanceConfig, limitMode)
+		transparentHandler.SetSemaphores(semaphores)
+		protocolHandler.SetSemaphores(semaphores)
+		// Routed requests take the slots of the instances serving their
+		// models, shared with the requests to those instances
+		openaiHandler.SetSemaphores(semaphores, &g.instances)
+		embeddingsHandler.SetSemaphores(semaphores, &g.instances)
+		rerankHandler.SetSemaphores(semaphores, &g.instances)
+		transparentHandler.SetDrainState(aiRouter)
+		protocolHandler.SetDrainState(aiRouter)
+		transparentHandler.SetInstanceProviders(instanceProviders)
+		protocolHandler.SetInstanceProviders(instanceProviders)
+		protocolHandler.SetPricing(priceTable)
+		protocolHandler.SetStreamBackpressure(config.StreamBackpressure)
+		log.Println("✓ Transparent and protocol handlers initialized")
+	}
+
+	// Runtime state dumps (SIGUSR1 and GET /admin/state)
+	requestTracker := diagnostics.NewTracker()
+	stateDumper := diagnostics.NewDumper(requestTracker)
+	stateDumper.AddConfigFile("model_mapping", config.ModelMappingFile)
+	if instanceConfig != nil {
+		for i, file := range instanceConfig.Files() {
+			name := "provider_instances"
+			if i > 0 {
+				name += ":" + file
+			}
+			stateDumper.AddConfigFile(name, file)
+		}
+	}
+	stateDumper.Register("providers", func() interface{} { return aiRouter.ProviderStates() })
+	stateDumper.Register("traffic", func() interface{} { return healthChecker.ProviderStats() })
+	stateDumper.Register("queues", func() interface{} { return semaphores.Statuses() })
+	if priceTable != nil {
+		stateDumper.Register("budgets", func() interface{} { return priceTable.BudgetStatuses() })
+	}
+
+	// Per-key rate limiting (disabled unless a limit is configured)
+	var rateLimiter *ratelimit.Limiter
+	rateLimitConfig := config.RateLimit
+	rateLimitConfig.Mode = limitMode
+	if rateLimitConfig.RequestsPerWindow > 0 || rateLimitConfig.TokensPerWindow > 0 {
+		rateLimiter = ratelimit.NewLimiter(rateLimitConfig)
+		rateLimiter.SetNotifier(notifier)
+		stateDumper.Register("rate_limits", func() interface{} { return rateLimiter.Statuses() })
+		log.Printf("✓ Rate limiting enabled (%s): %d requests, %d tokens per %s",
+			limitMode, rateLimitConfig.RequestsPerWindow, rateLimitConfig.TokensPerWindow, rateLimitConfig.Window)
+	}
+
+	// Fault injection for resilience testing (disabled unless Chaos is set)
+	var faults *chaos.Injector
+	if config.Chaos && instanceConfig != nil {
+		faults = chaos.NewInjector()
+		transparentHandler.SetFaults(faults)
+		protocolHandler.SetFaults(faults)
+		adminHandler.SetFaults(faults)
+		stateDumper.Register("faults", func() interface{} { return faults.Faults() })
+		log.Println("⚠️  Chaos fault injection enabled: POST /admin/faults")
+	} else if config.Chaos {
+		log.Println("Chaos ignored: faults are injected into provider instances, and none are configured")
+	}
+
+	if config.HandleSignals {
+		stateDumper.DumpOnSignal(g.ctx)
+	}
+
+	// Audit log of who requested what, reopened on SIGHUP for rotation
+	if config.Audit.Enabled() {
+		g.auditLogger, err = audit.New(config.Audit)
+		if err != nil {
+			return fmt.Errorf("failed to open audit log: %w", err)
+		}
+		if config.HandleSignals {
+			g.auditLogger.ReopenOnSignal(g.ctx)
+		}
+		log.Printf("✓ Audit log enabled: %s", config.Audit.Path)
+	}
+
+	// CORS is checked before any route is registered
+	if err := config.CORS.Validate(); err != nil {
+		return fmt.Errorf("invalid CORS configuration: %w", err)
+	}
+
+	// Initialize Gin router
+	ginRouter := gin.New()
+	g.engine = ginRouter
+
+	// Global middleware
+	ginRouter.Use(middleware.Recovery())
+	ginRouter.Use(middleware.RequestIDWithConfig(config.RequestID))
+	ginRouter.Use(middleware.RequestTagging(config.RequestTags))
+	ginRouter.Use(requestTracker.Middleware())
+	if g.auditLogger != nil {
+		ginRouter.Use(g.auditLogger.Middleware())
+	}
+	ginRouter.Use(middleware.Logger())
+	ginRouter.Use(middleware.Security())
+	if len(config.CORS.AllowedOrigins) > 0 {
+		ginRouter.Use(middleware.CORS(config.CORS))
+	}
+	ginRouter.Use(middleware.Metrics())
+	ginRouter.Use(middleware.NormaliseHeaders())
+	if config.Confidence.Enabled {
+		ginRouter.Use(middleware.ConfidenceHeader(config.Confidence))
+	}
+
+	// Health endpoints (no auth required)
+	ginRouter.GET("/health", healthHandler(healthChecker))
+	ginRouter.GET("/ready", readyHandler(healthChecker, aiRouter, backgroundChecks))
+	ginRouter.GET("/health/providers", providersHealthHandler(aiRouter, healthChecker, backgroundChecks))
+	ginRouter.GET("/health/:provider", adminHandler.ProviderHealth)
+	ginRouter.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
+	// Status page (no auth required: it only reads the endpoints above)
+	if config.UI {
+		ginRouter.GET("/ui/*filepath", gin.WrapH(ui.Handler("/ui")))
+		log.Printf("Status UI enabled at /ui/")
+	}
+
+	// Admin endpoints
+	adminGroup := ginRouter.Group("/admin")
+	if authMiddleware != nil {
+		adminGroup.Use(authMiddleware)
+	}
+	{
+		adminGroup.GET("/state", stateDumper.Handler())
+		adminGroup.GET("/providers", adminHandler.ListProviders)
+		adminGroup.POST("/providers/:name/drain", adminHandler.DrainProvider)
+		adminGroup.POST("/providers/:name/restore", adminHandler.RestoreProvider)
+		adminGroup.GET("/instances/:name/features", adminHandler.InstanceFeatures)
+		adminGroup.POST("/features/reload", adminHandler.ReloadFeatures)
+		if rateLimiter != nil {
+			adminGroup.GET("/ratelimits", rateLimiter.Handler())
+		}
+		if faults != nil {
+			adminGroup.GET("/faults", adminHandler.ListFaults)
+			adminGroup.POST("/faults", adminHandler.SetFault)
+			adminGroup.DELETE("/faults/:instance", adminHandler.ClearFault)
+		}
+	}
+
+	// Compressed bodies are decoded for the handlers that parse them, after
+	// authentication; transparent and native routes forward them as sent
+	decompress := middleware.Decompress(config.MaxRequestBytes)
+
+	// OpenAI-compatible API endpoints
+	openaiGroup := ginRouter.Group("/v1")
+	if authMiddleware != nil {
+		log.Printf("Authentication enabled for OpenAI API: mode=%s", config.Auth.Mode)
+		openaiGroup.Use(authMiddleware)
+	}
+	if rateLimiter != nil {
+		openaiGroup.Use(middleware.RateLimit(rateLimiter))
+	}
+	openaiGroup.Use(decompress)
+	{
+		openaiGroup.POST("/chat/completions", middleware.LegacyMigrator(), openaiHandler.ChatCompletions)
+		openaiGroup.POST("/chat/completions/:stream_id/cancel", openaiHandler.CancelStream)
+		openaiGroup.GET("/jobs/:id", openaiHandler.GetJob)
+		openaiGroup.GET("/models", openaiHandler.ListModels)
+		openaiGroup.GET("/models/:model", openaiHandler.GetModel)
+		openaiGroup.GET("/route", routeHandler.GetRoute)
+		openaiGroup.POST("/rerank", rerankHandler.Rerank)
+		openaiGroup.POST("/tokenize", tokenizeHandler.Tokenize)
+		openaiGroup.POST("/embeddings", embeddingsHandler.Embeddings)
+		openaiGroup.POST("/batches", batchHandler.CreateBatch)
+		openaiGroup.GET("/batches/:id", batchHandler.GetBatch)
+		if filesHandler != nil {
+			openaiGroup.POST("/files", filesHandler.UploadFile)
+			openaiGroup.GET("/files", filesHandler.ListFiles)
+			openaiGroup.GET("/files/:id", filesHandler.GetFile)
+			openaiGroup.DELETE("/files/:id", filesHandler.DeleteFile)
+			log.Println("✓ Files API endpoints registered: /v1/files (anthropic)")
+		}
+	}
+	// Preflight requests are answered without authentication
+	middleware.RegisterOptionsRoutes(ginRouter, "/v1/")
+
+	// Transparent mode endpoints (/transparent/{provider}/*). They are
+	// registered when the transparent_mode flag is configured; the handler
+	// checks the flag per instance and key, so reloads can change it.
+	if transparentHandler != nil && instanceConfig.HasFeature("transparent_mode") {
+		transparentGroup := ginRouter.Group("/transparent")
+		if authMiddleware != nil {
+			log.Printf("Authentication enabled for transparent mode: mode=%s", config.Auth.Mode)
+			transparentGroup.Use(authMiddleware)
+		}
+		{
+			transparentGroup.Any("/*path", transparentHandler.HandleRequest)
+		}
+		log.Println("✓ Transparent mode endpoints registered: /transparent/*")
+	}
+
+	// Protocol mode endpoints (/{protocol}/{instance_name}/*), gated like
+	// transparent mode by the protocol_mode flag
+	if protocolHandler != nil && instanceConfig.HasFeature("protocol_mode") {
+		protocolGroup := ginRouter.Group("/")
+		if authMiddleware != nil {
+			log.Printf("Authentication enabled for protocol mode: mode=%s", config.Auth.Mode)
+			protocolGroup.Use(authMiddleware)
+		}
+		if rateLimiter != nil {
+			protocolGroup.Use(middleware.RateLimit(rateLimiter))
+		}
+		protocolGroup.Use(decompress)
+		{
+			// Register protocol endpoints (e.g., /openai/bedrock_us1_openai/*)
+			// with the methods their instances accept
+			for _, prefix := range []string{"/openai", "/anthropic", "/gemini"} {
+				registerProtocolRoute(protocolGroup, prefix+"/*path",
+					instanceConfig.EndpointMethods("protocol", prefix+"/"), protocolHandler.HandleRequest)
+			}
+		}
+		log.Println("✓ Protocol mode endpoints registered: /{protocol}/*")
+	}
+
+	// Native provider API endpoints
+	providersGroup := ginRouter.Group("/providers")
+	if authMiddleware != nil {
+		log.Printf("Authentication enabled for provider APIs: mode=%s", config.Auth.Mode)
+		providersGroup.Use(authMiddleware)
+	}
+	registerProviderRoutes(providersGroup, providerRegistry, healthChecker, aiRouter)
+
+	// Legacy endpoints (backward compatibility - Bedrock only)
+	if bedrockProvider, ok := providerRegistry["bedrock"]; ok {
+		legacyGroup := ginRouter.Group("/")
+		if authMiddleware != nil {
+			legacyGroup.Use(authMiddleware)
+		}
+		{
+			// Model IDs in legacy paths are checked against model_mappings
+			// when legacy_routes.validate_models is set
+			for _, prefix := range []string{"/v1/bedrock", "/bedrock", "/model"} {
+				var routeHandlers []gin.HandlerFunc
+				if instanceConfig != nil {
+					if deprecation, ok := instanceConfig.DeprecatedRoutes[prefix]; ok {
+						log.Printf("Route %s/* is deprecated (sunset: %s)", prefix, deprecation.Sunset)
+						routeHandlers = append(routeHandlers, middleware.Deprecation(prefix, deprecation))
+					}
+				}
+				routeHandlers = append(routeHandlers,
+					handlers.LegacyModelValidation(aiRouter.GetConfig, prefix),
+					createProviderHandler(bedrockProvider, healthChecker, aiRouter))
+				legacyGroup.Any(prefix+"/*path", routeHandlers...)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,413 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+// Package gateway runs the AI gateway as a library. New builds the
+// providers, router, handlers and middleware from a Config; the gateway is
+// then served on its own listeners with Start, or mounted under another
+// service's mux with Handler:
+//
+//	gw, err := gateway.New(gateway.Config{
+//		ModelMappingFile:      "configs/model-mapping.yaml",
+//		ProviderInstancesFile: "configs/provider-instances.yaml",
+//	})
+//	if err != nil {
+//		log.Fatal(err)
+//	}
+//	defer gw.Shutdown(context.Background())
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("/ai/", http.StripPrefix("/ai", requireUser(gw.Handler())))
+//
+// cmd/server is a thin shell that reads the Config from the environment.
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/audit"
+	"github.com/tosharewith/llmproxy_auth/internal/handlers"
+	"github.com/tosharewith/llmproxy_auth/internal/health"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/middleware"
+	"github.com/tosharewith/llmproxy_auth/internal/notify"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// Aliases of the settings of the gateway's internal packages, so services
+// outside this module can fill them in
+type (
+	// Provider is an AI provider the gateway routes requests to
+	Provider = providers.Provider
+
+	// HealthConfig tunes the error-rate tracking of providers
+	HealthConfig = health.Config
+
+	// HealthThresholds are the consecutive check results that eject a
+	// provider from routing and restore it
+	HealthThresholds = health.Thresholds
+
+	// StreamBackpressure bounds the buffer between provider streams and
+	// slow clients
+	StreamBackpressure = handlers.StreamBackpressure
+
+	// RateLimitConfig sets per-key request and token limits
+	RateLimitConfig = ratelimit.Config
+
+	// NotifyConfig sends operational events to webhooks
+	NotifyConfig = notify.Config
+
+	// AuditConfig writes an audit log of who requested what
+	AuditConfig = audit.Config
+
+	// RequestIDConfig lists the networks whose X-Request-ID is reused
+	RequestIDConfig = middleware.RequestIDConfig
+
+	// RequestTagsConfig controls how X-Request-Tags are recorded and
+	// forwarded
+	RequestTagsConfig = middleware.RequestTagsConfig
+
+	// CORSConfig lists the origins allowed to call the gateway from a
+	// browser
+	CORSConfig = middleware.CORSConfig
+
+	// ConfidenceConfig controls the X-Confidence-Score response header
+	ConfidenceConfig = middleware.ConfidenceConfig
+)
+
+// Default durations used when the Config leaves them unset
+const (
+	DefaultHealthCheckTimeout = 10 * time.Second
+	DefaultWarmUpTimeout      = 10 * time.Second
+	DefaultShutdownTimeout    = 30 * time.Second
+)
+
+// Config configures a gateway. Only ModelMappingFile is required; every
+// other zero value selects the default the server uses when its
+// environment variable is unset.
+//
+// Provider credentials are read by the providers themselves, from their
+// instance config or the usual environment (AWS credentials,
+// OPENAI_API_KEY, ...). Providers built by the caller can be added with
+// Providers.
+type Config struct {
+	// ModelMappingFile is the model mapping config
+	ModelMappingFile string
+
+	// ProviderInstancesFile is the provider instances config. Transparent
+	// and protocol modes are disabled when it is empty or fails to load.
+	ProviderInstancesFile string
+
+	// ConfigWatchInterval polls the model mapping file for changes
+	// (0: only reloaded on SIGHUP, with HandleSignals)
+	ConfigWatchInterval time.Duration
+
+	// Providers are added to the providers built for the model mapping and
+	// the instances, replacing any of the same name
+	Providers map[string]Provider
+
+	// AWSRegion is the region of the Bedrock batch inference bucket
+	// (default: us-east-1)
+	AWSRegion string
+
+	// BedrockBatchS3URI enables Bedrock batch inference, run with the
+	// service role BedrockBatchRoleARN
+	BedrockBatchS3URI   string
+	BedrockBatchRoleARN string
+
+	// Auth authenticates the API, admin and provider routes
+	Auth AuthConfig
+
+	// Health tracks provider error rates; HealthThresholds eject providers
+	// that fail consecutive checks
+	Health           HealthConfig
+	HealthThresholds HealthThresholds
+
+	// HealthCheckInterval runs background provider health checks, which
+	// /ready then reports instead of checking on each probe (0: disabled)
+	HealthCheckInterval time.Duration
+	HealthCheckTimeout  time.Duration
+
+	// WarmUp reaches every provider before New returns; with StrictStartup
+	// New fails if a required provider is unreachable
+	WarmUp        bool
+	StrictStartup bool
+	WarmUpTimeout time.Duration
+
+	// StreamBackpressure bounds the buffering of streamed responses
+	StreamBackpressure StreamBackpressure
+
+	// EmbeddingsConcurrency bounds the provider calls of one embeddings
+	// request (default: 4)
+	EmbeddingsConcurrency int
+
+	// LimitMode is enforce (default) or report_only, for the rate limits
+	// and instance concurrency limits
+	LimitMode string
+
+	// RateLimit enables per-key rate limits when a limit is set
+	RateLimit RateLimitConfig
+
+	// Notify sends webhooks when URLs are set
+	Notify NotifyConfig
+
+	// Audit enables the audit log when Path is set
+	Audit AuditConfig
+
+	RequestID   RequestIDConfig
+	RequestTags RequestTagsConfig
+
+	// CORS is applied when origins are allowed
+	CORS       CORSConfig
+	Confidence ConfidenceConfig
+
+	// MaxRequestBytes bounds decompressed request bodies (default: 32 MiB)
+	MaxRequestBytes int64
+
+	// UI serves the status page at /ui/
+	UI bool
+
+	// Chaos enables fault injection through /admin/faults
+	Chaos bool
+
+	// HandleSignals reloads the model mapping and reopens the audit log on
+	// SIGHUP, and dumps the gateway's state on SIGUSR1. Services embedding
+	// the gateway usually leave their signals to themselves.
+	HandleSignals bool
+
+	// Listen are the listeners of Start; they are not used by Handler
+	Listen ListenConfig
+
+	// ShutdownTimeout is how long Start waits for in-flight requests when
+	// it stops (default: 30s)
+	ShutdownTimeout time.Duration
+}
+
+// ListenConfig configures the listeners the gateway serves with Start
+type ListenConfig struct {
+	// Addr is the HTTP address, such as ":8080"; empty serves no plain HTTP
+	Addr string
+
+	// TLSAddr is the HTTPS address, served with TLSCertFile and TLSKeyFile;
+	// empty serves no HTTPS
+	TLSAddr     string
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// SocketPath is a Unix socket to serve on, created with SocketMode
+	// permissions (default: 0660)
+	SocketPath string
+	SocketMode os.FileMode
+}
+
+// Gateway is a configured AI gateway
+type Gateway struct {
+	config           Config
+	engine           *gin.Engine
+	router           *router.Router
+	instances        atomic.Pointer[instance.Config] // replaced by feature flag reloads
+	enabledProviders []string
+	auditLogger      *audit.Logger
+
+	// ctx ends the gateway's background checks, config watcher and signal
+	// handlers when Shutdown cancels it
+	ctx     context.Context
+	cancel  context.CancelFunc
+	serving sync.WaitGroup
+}
+
+// New builds a gateway from config. The gateway's background work starts
+// at once; Shutdown stops it.
+func New(config Config) (*Gateway, error) {
+	if config.ModelMappingFile == "" {
+		return nil, errors.New("gateway: ModelMappingFile is required")
+	}
+	if config.AWSRegion == "" {
+		config.AWSRegion = "us-east-1"
+	}
+	if config.HealthCheckTimeout <= 0 {
+		config.HealthCheckTimeout = DefaultHealthCheckTimeout
+	}
+	if config.WarmUpTimeout <= 0 {
+		config.WarmUpTimeout = DefaultWarmUpTimeout
+	}
+	if config.ShutdownTimeout <= 0 {
+		config.ShutdownTimeout = DefaultShutdownTimeout
+	}
+	if config.MaxRequestBytes <= 0 {
+		config.MaxRequestBytes = middleware.DefaultMaxRequestBytes
+	}
+	if config.Listen.SocketMode == 0 {
+		config.Listen.SocketMode = 0660
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &Gateway{config: config, ctx: ctx, cancel: cancel}
+	if err := g.build(); err != nil {
+		cancel()
+		if g.auditLogger != nil {
+			g.auditLogger.Close()
+		}
+		return nil, fmt.Errorf("gateway: %w", err)
+	}
+	return g, nil
+}
+
+// Handler returns the gateway's routes as an http.Handler, to mount under
+// another service's mux. Requests are authenticated by Config.Auth, if set,
+// after any authentication of the enclosing service.
+func (g *Gateway) Handler() http.Handler {
+	return g.engine
+}
+
+// Start serves the gateway on the configured listeners until ctx is
+// cancelled, Shutdown is called or a listener fails, then gives in-flight
+// requests up to ShutdownTimeout to finish. It returns nil after a graceful
+// stop.
+func (g *Gateway) Start(ctx context.Context) error {
+	servers, err := g.listeners()
+	if err != nil {
+		return err
+	}
+	g.serving.Add(1)
+	defer g.serving.Done()
+
+	ctx, stop := context.WithCancel(ctx)
+	defer stop()
+	defer context.AfterFunc(g.ctx, stop)()
+	return serveUntilDone(ctx, servers, g.config.ShutdownTimeout)
+}
+
+// Shutdown stops the listeners of Start, waiting for it to return until ctx
+// is done, then stops the gateway's background work and closes the audit
+// log. Services mounting Handler call it once their own server has stopped.
+func (g *Gateway) Shutdown(ctx context.Context) error {
+	g.cancel()
+
+	stopped := make(chan struct{})
+	go func() {
+		g.serving.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if g.auditLogger != nil {
+		if err := g.auditLogger.Close(); err != nil {
+			return fmt.Errorf("gateway: closing audit log: %w", err)
+		}
+	}
+	return nil
+}
+
+// listeners creates the servers of the configured listeners
+func (g *Gateway) listeners() ([]*listenerServer, error) {
+	listen := g.config.Listen
+	var servers []*listenerServer
+	if listen.Addr != "" {
+		servers = append(servers, newTCPServer("http "+listen.Addr, listen.Addr, g.engine, "", ""))
+	}
+	if listen.TLSAddr != "" {
+		servers = append(servers, newTCPServer("https "+listen.TLSAddr, listen.TLSAddr, g.engine, listen.TLSCertFile, listen.TLSKeyFile))
+	}
+	if listen.SocketPath != "" {
+		socketServer, err := newUnixSocketServer(listen.SocketPath, listen.SocketMode, g.engine)
+		if err != nil {
+			for _, s := range servers {
+				s.server.Close()
+			}
+			return nil, fmt.Errorf("gateway: %w", err)
+		}
+		servers = append(servers, socketServer)
+	}
+	if len(servers) == 0 {
+		return nil, errors.New("gateway: no listeners configured")
+	}
+	return servers, nil
+}
+
+// WriteBanner writes the gateway's configuration and endpoints to w, as
+// the server does to stdout at startup. The gateway never writes it
+// itself.
+func (g *Gateway) WriteBanner(w io.Writer) {
+	banner := `
+╔══════════════════════════════════════════════════════════════╗
+║                                                              ║
+║              🚀 Multi-Provider AI Gateway                   ║
+║                                                              ║
+╚══════════════════════════════════════════════════════════════╝
+
+Configuration:
+`
+	listen := g.config.Listen
+	fmt.Fprintln(w, banner)
+	if listen.Addr != "" {
+		fmt.Fprintf(w, "  • HTTP Address:      %s\n", listen.Addr)
+	}
+	if listen.TLSAddr != "" {
+		fmt.Fprintf(w, "  • HTTPS Address:     %s (enabled)\n", listen.TLSAddr)
+	}
+	if listen.SocketPath != "" {
+		fmt.Fprintf(w, "  • Unix Socket:       %s\n", listen.SocketPath)
+	}
+	fmt.Fprintf(w, "  • Authentication:    %v\n", g.config.Auth.Enabled())
+	fmt.Fprintf(w, "  • Enabled Providers: %s\n", strings.Join(g.enabledProviders, ", "))
+
+	// Show instance configuration if available
+	instances := g.instances.Load()
+	if instances != nil {
+		transparentInstances := instances.ListInstancesByMode("transparent")
+		protocolInstances := instances.ListInstancesByMode("protocol")
+		fmt.Fprintf(w, "  • Transparent Mode:  %d instances\n", len(transparentInstances))
+		fmt.Fprintf(w, "  • Protocol Mode:     %d instances\n", len(protocolInstances))
+	}
+
+	base := "http://localhost"
+	if strings.HasPrefix(listen.Addr, ":") {
+		base += listen.Addr
+	} else if listen.Addr != "" {
+		base = "http://" + listen.Addr
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "API Endpoints:")
+	fmt.Fprintf(w, "  • OpenAI-compatible: %s/v1/chat/completions\n", base)
+	fmt.Fprintf(w, "  • List models:       %s/v1/models\n", base)
+	fmt.Fprintf(w, "  • Model routing:     %s/v1/route?model={model}\n", base)
+	fmt.Fprintf(w, "  • Rerank:            %s/v1/rerank\n", base)
+	fmt.Fprintf(w, "  • Tokenize:          %s/v1/tokenize\n", base)
+	fmt.Fprintf(w, "  • Embeddings:        %s/v1/embeddings\n", base)
+	fmt.Fprintf(w, "  • Batches:           %s/v1/batches\n", base)
+
+	// Show transparent mode endpoints
+	if instances != nil && instances.HasFeature("transparent_mode") {
+		fmt.Fprintf(w, "  • Transparent mode:  %s/transparent/{provider}/...\n", base)
+	}
+
+	// Show protocol mode endpoints
+	if instances != nil && instances.HasFeature("protocol_mode") {
+		fmt.Fprintf(w, "  • Protocol mode:     %s/{protocol}/{instance}/...\n", base)
+	}
+
+	fmt.Fprintf(w, "  • Native Bedrock:    %s/providers/bedrock/...\n", base)
+	fmt.Fprintf(w, "  • Health check:      %s/health\n", base)
+	fmt.Fprintf(w, "  • Metrics:           %s/metrics\n", base)
+	fmt.Fprintf(w, "  • State dump:        %s/admin/state (or SIGUSR1)\n", base)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "🎯 Ready to accept requests!")
+	fmt.Fprintln(w)
+}
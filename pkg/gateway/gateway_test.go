@@ -0,0 +1,285 @@
+package gateway
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/mock"
+)
+
+// testModelMapping routes mock-chat to the mock provider
+const testModelMapping = `
+model_mappings:
+  mock-chat:
+    default_provider: mock
+    providers:
+      mock:
+        model: mock-chat
+
+providers:
+  mock:
+    enabled: true
+`
+
+// newTestGateway builds a gateway serving the mock provider, supplied
+// through Config.Providers rather than ENABLE_MOCK_PROVIDER
+func newTestGateway(t *testing.T, config Config) *Gateway {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ENABLE_MOCK_PROVIDER", "")
+
+	dir := t.TempDir()
+	config.ModelMappingFile = filepath.Join(dir, "model-mapping.yaml")
+	if err := os.WriteFile(config.ModelMappingFile, []byte(testModelMapping), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := mock.NewMockProvider(mock.MockConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Providers = map[string]Provider{"mock": provider}
+
+	gw, err := New(config)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { gw.Shutdown(context.Background()) })
+	return gw
+}
+
+// TestEmbedHandler tests the gateway mounted under another service's mux,
+// behind that service's own authentication
+func TestEmbedHandler(t *testing.T) {
+	gw := newTestGateway(t, Config{})
+
+	requireUser := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("X-User") == "" {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/ai/", http.StripPrefix("/ai", requireUser(gw.Handler())))
+
+	chat := func(user string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/ai/v1/chat/completions",
+			strings.NewReader(`{"model":"mock-chat","messages":[{"role":"user","content":"Hello"}]}`))
+		req.Header.Set("Content-Type", "application/json")
+		if user != "" {
+			req.Header.Set("X-User", user)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := chat(""); w.Code != http.StatusUnauthorized {
+		t.Errorf("without the service's auth: status = %d, want 401", w.Code)
+	}
+
+	w := chat("alice")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || len(resp.Choices) == 0 {
+		t.Fatalf("response %s: %v", w.Body, err)
+	}
+	if got := resp.Choices[0].Message.Content; got != "Mock response to: Hello" {
+		t.Errorf("reply = %q", got)
+	}
+	if w.Header().Get("X-Request-ID") == "" {
+		t.Error("gateway middleware not applied: no X-Request-ID")
+	}
+}
+
+// TestAuth tests that the gateway's own authentication guards the API but
+// not the health endpoints
+func TestAuth(t *testing.T) {
+	if _, err := New(Config{ModelMappingFile: "unused", Auth: AuthConfig{Mode: "api_key"}}); err == nil {
+		t.Error("New() accepted api_key auth without API keys")
+	}
+
+	gw := newTestGateway(t, Config{Auth: AuthConfig{Mode: "api_key", APIKeys: map[string]string{"secret": "tests"}}})
+	for _, tt := range []struct {
+		path string
+		key  string
+		want int
+	}{
+		{"/v1/models", "", http.StatusUnauthorized},
+		{"/v1/models", "secret", http.StatusOK},
+		{"/health", "", http.StatusOK},
+	} {
+		req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set("X-API-Key", tt.key)
+		}
+		w := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("GET %s with key %q: status = %d, want %d", tt.path, tt.key, w.Code, tt.want)
+		}
+	}
+}
+
+// TestStartShutdown tests that Shutdown stops a gateway serving with Start
+func TestStartShutdown(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "gateway.sock")
+	gw := newTestGateway(t, Config{Listen: ListenConfig{SocketPath: socketPath}})
+
+	done := make(chan error, 1)
+	go func() { done <- gw.Start(context.Background()) }()
+
+	client := newUnixClient(socketPath)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := client.Get("http://gateway/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("health status = %d", resp.StatusCode)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gateway not serving: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := gw.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Start() error = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Start did not return after Shutdown")
+	}
+
+	if _, err := newTestGateway(t, Config{}).listeners(); err == nil {
+		t.Error("listeners() succeeded with none configured")
+	}
+}
+
+// TestDecompressRoutes tests that compressed bodies are decoded for the
+// OpenAI-compatible API but forwarded as sent on transparent routes
+func TestDecompressRoutes(t *testing.T) {
+	var gotBody []byte
+	var gotEncoding string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotEncoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer upstream.Close()
+
+	instancesFile := filepath.Join(t.TempDir(), "provider-instances.yaml")
+	if err := os.WriteFile(instancesFile, []byte(`
+instances:
+  store:
+    type: generic_http
+    mode: transparent
+    base_url: `+upstream.URL+`
+    endpoints:
+      - path: /transparent/store
+features:
+  transparent_mode:
+    enabled: true
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gw := newTestGateway(t, Config{ProviderInstancesFile: instancesFile})
+
+	gzipped := func(s string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		io.WriteString(zw, s)
+		zw.Close()
+		return buf.Bytes()
+	}
+	send := func(path string, body []byte) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Content-Encoding", "gzip")
+		w := httptest.NewRecorder()
+		gw.Handler().ServeHTTP(w, req)
+		return w
+	}
+
+	object := gzipped(`{"stored":"compressed"}`)
+	if w := send("/transparent/store/objects/1", object); w.Code != http.StatusCreated {
+		t.Fatalf("transparent status = %d: %s", w.Code, w.Body)
+	}
+	if !bytes.Equal(gotBody, object) || gotEncoding != "gzip" {
+		t.Errorf("upstream got %q with Content-Encoding %q, want the gzip body as sent", gotBody, gotEncoding)
+	}
+
+	w := send("/v1/chat/completions", gzipped(`{"model":"mock-chat","messages":[{"role":"user","content":"Hello"}]}`))
+	if w.Code != http.StatusOK {
+		t.Errorf("chat completions status = %d: %s", w.Code, w.Body)
+	}
+}
+
+// TestStartupValidation tests that a config routing to a provider that is
+// not registered is rejected at startup, as it would be on reload
+func TestStartupValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	t.Setenv("ENABLE_MOCK_PROVIDER", "")
+
+	config := Config{ModelMappingFile: filepath.Join(t.TempDir(), "model-mapping.yaml")}
+	mapping := testModelMapping + `
+  absent:
+    enabled: true
+`
+	mapping = strings.Replace(mapping, "        model: mock-chat\n", "        model: mock-chat\n      absent:\n        model: absent-chat\n", 1)
+	if err := os.WriteFile(config.ModelMappingFile, []byte(mapping), 0644); err != nil {
+		t.Fatal(err)
+	}
+	provider, err := mock.NewMockProvider(mock.MockConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	config.Providers = map[string]Provider{"mock": provider}
+
+	_, err = New(config)
+	if err == nil || !strings.Contains(err.Error(), `provider "absent", which is not registered`) {
+		t.Errorf("New() error = %v, want the unregistered provider rejected", err)
+	}
+}
+
+// TestWriteBanner tests that the banner goes to the given writer
+func TestWriteBanner(t *testing.T) {
+	gw := newTestGateway(t, Config{Listen: ListenConfig{Addr: "127.0.0.1:9090"}})
+	var buf bytes.Buffer
+	gw.WriteBanner(&buf)
+	for _, want := range []string{"HTTP Address:      127.0.0.1:9090", "http://127.0.0.1:9090/v1/chat/completions", "Enabled Providers: mock"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("banner missing %q:\n%s", want, buf.String())
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// listenerServer is an HTTP server bound to one listener (TCP, TLS or Unix socket)
+type listenerServer struct {
+	name   string
+	server *http.Server
+	serve  func() error
+}
+
+// newTCPServer serves handler on a TCP address, optionally with TLS
+func newTCPServer(name, addr string, handler http.Handler, certFile, keyFile string) *listenerServer {
+	server := &http.Server{Addr: addr, Handler: handler}
+	serve := server.ListenAndServe
+	if certFile != "" {
+		serve = func() error { return server.ListenAndServeTLS(certFile, keyFile) }
+	}
+	return &listenerServer{name: name, server: server, serve: serve}
+}
+
+// newUnixSocketServer serves handler on a Unix domain socket. The socket file
+// is created with the given permissions and removed when the server shuts down.
+func newUnixSocketServer(path string, mode os.FileMode, handler http.Handler) (*listenerServer, error) {
+	listener, err := listenUnixSocket(path, mode)
+	if err != nil {
+		return nil, err
+	}
+
+	server := &http.Server{Handler: handler}
+	return &listenerServer{
+		name:   "unix:" + path,
+		server: server,
+		serve:  func() error { return server.Serve(listener) },
+	}, nil
+}
+
+// listenUnixSocket creates a Unix socket listener at path with the given
+// permissions. A stale socket left by a previous process is replaced; a live
+// socket or a non-socket file at path is an error.
+func listenUnixSocket(path string, mode os.FileMode) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("listen socket %s: file exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("listen socket %s: already in use", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("listen socket %s: failed to remove stale socket: %w", path, err)
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("listen socket %s: %w", path, err)
+	}
+
+	if err := os.Chmod(path, mode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("listen socket %s: failed to set permissions: %w", path, err)
+	}
+
+	return listener, nil
+}
+
+// serveUntilDone runs servers until ctx is cancelled or one of them fails,
+// then shuts all of them down. In-flight requests (including streams) are
+// given until shutdownTimeout to finish.
+func serveUntilDone(ctx context.Context, servers []*listenerServer, shutdownTimeout time.Duration) error {
+	errCh := make(chan error, len(servers))
+	for _, s := range servers {
+		log.Printf("Starting server on %s", s.name)
+		go func(s *listenerServer) {
+			if err := s.serve(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errCh <- fmt.Errorf("%s: %w", s.name, err)
+			}
+		}(s)
+	}
+
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		log.Println("Shutting down...")
+	case serveErr = <-errCh:
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, s := range servers {
+		wg.Add(1)
+		go func(s *listenerServer) {
+			defer wg.Done()
+			if err := s.server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Warning: shutdown of %s: %v", s.name, err)
+			}
+		}(s)
+	}
+	wg.Wait()
+
+	return serveErr
+}
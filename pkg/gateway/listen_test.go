@@ -0,0 +1,176 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/handlers"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/openai"
+)
+
+// newUnixClient returns an HTTP client that dials the given socket for every request
+func newUnixClient(path string) *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		},
+	}
+}
+
+// newSocketTestEngine serves protocol-mode chat completions backed by a fake
+// upstream, plus a slow streaming endpoint
+func newSocketTestEngine(t *testing.T, upstreamURL string, release <-chan struct{}) *gin.Engine {
+	t.Helper()
+
+	provider, err := openai.NewOpenAIProvider(openai.OpenAIConfig{APIKey: "test-key", BaseURL: upstreamURL})
+	if err != nil {
+		t.Fatalf("NewOpenAIProvider: %v", err)
+	}
+	config := &instance.Config{
+		Instances: map[string]instance.InstanceConfig{
+			"openai_test": {
+				Type:      "openai",
+				Mode:      "protocol",
+				Protocol:  "openai",
+				Endpoints: []instance.EndpointConfig{{Path: "/openai/openai_test"}},
+			},
+		},
+	}
+	current := new(atomic.Pointer[instance.Config])
+	current.Store(config)
+	h := handlers.NewProtocolHandler(map[string]providers.Provider{"openai": provider}, current, nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.POST("/openai/*path", h.HandleRequest)
+	engine.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/event-stream")
+		c.Writer.WriteString("data: first\n\n")
+		c.Writer.Flush()
+		<-release
+		c.Writer.WriteString("data: [DONE]\n\n")
+		c.Writer.Flush()
+	})
+	return engine
+}
+
+// TestUnixSocketServer tests chat, streaming and graceful shutdown over a Unix socket
+func TestUnixSocketServer(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"id":"x","object":"chat.completion","model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"over uds"},"finish_reason":"stop"}]}`))
+	}))
+	defer upstream.Close()
+
+	release := make(chan struct{})
+	socketPath := filepath.Join(t.TempDir(), "aigw.sock")
+	server, err := newUnixSocketServer(socketPath, 0600, newSocketTestEngine(t, upstream.URL, release))
+	if err != nil {
+		t.Fatalf("newUnixSocketServer: %v", err)
+	}
+
+	info, err := os.Stat(socketPath)
+	if err != nil {
+		t.Fatalf("socket not created: %v", err)
+	}
+	if info.Mode()&os.ModeSocket == 0 || info.Mode().Perm() != 0600 {
+		t.Errorf("socket mode: got %v, want socket with 0600", info.Mode())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- serveUntilDone(ctx, []*listenerServer{server}, 5*time.Second) }()
+
+	client := newUnixClient(socketPath)
+
+	// Full chat request
+	resp, err := client.Post("http://aigw/openai/openai_test/chat/completions", "application/json",
+		strings.NewReader(`{"model":"gpt-4o","messages":[{"role":"user","content":"hi"}]}`))
+	if err != nil {
+		t.Fatalf("chat request: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), "over uds") {
+		t.Fatalf("chat response: %d %s", resp.StatusCode, body)
+	}
+
+	// Streaming: the first event arrives before the handler finishes
+	streamResp, err := client.Get("http://aigw/stream")
+	if err != nil {
+		t.Fatalf("stream request: %v", err)
+	}
+	reader := bufio.NewReader(streamResp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || line != "data: first\n" {
+		t.Fatalf("first stream event: %q, %v", line, err)
+	}
+
+	// Graceful shutdown waits for the in-flight stream to complete
+	cancel()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+
+	rest, _ := io.ReadAll(reader)
+	streamResp.Body.Close()
+	if !strings.Contains(string(rest), "[DONE]") {
+		t.Errorf("stream cut off by shutdown: %q", rest)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("serveUntilDone: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("shutdown did not complete")
+	}
+
+	if _, err := os.Stat(socketPath); !os.IsNotExist(err) {
+		t.Errorf("socket not removed on shutdown: %v", err)
+	}
+}
+
+// TestListenUnixSocketReplacesStale tests stale socket cleanup and refusal of live sockets
+func TestListenUnixSocketReplacesStale(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "aigw.sock")
+
+	live, err := listenUnixSocket(socketPath, 0660)
+	if err != nil {
+		t.Fatalf("listenUnixSocket: %v", err)
+	}
+	if _, err := listenUnixSocket(socketPath, 0660); err == nil {
+		t.Error("expected error for socket already in use")
+	}
+
+	// Leave the file behind as a crashed process would
+	live.(*net.UnixListener).SetUnlinkOnClose(false)
+	live.Close()
+
+	replaced, err := listenUnixSocket(socketPath, 0660)
+	if err != nil {
+		t.Fatalf("stale socket not replaced: %v", err)
+	}
+	replaced.Close()
+
+	regular := filepath.Join(t.TempDir(), "not-a-socket")
+	os.WriteFile(regular, nil, 0600)
+	if _, err := listenUnixSocket(regular, 0660); err == nil {
+		t.Error("expected error for non-socket file")
+	}
+}
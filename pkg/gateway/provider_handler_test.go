@@ -0,0 +1,112 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/health"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+)
+
+// fixedProvider returns the same response from every Invoke
+type fixedProvider struct {
+	resp *providers.ProviderResponse
+}
+
+func (p *fixedProvider) Name() string                          { return "fixed" }
+func (p *fixedProvider) HealthCheck(ctx context.Context) error { return nil }
+
+func (p *fixedProvider) Invoke(ctx context.Context, req *providers.ProviderRequest) (*providers.ProviderResponse, error) {
+	return p.resp, nil
+}
+
+func (p *fixedProvider) InvokeStreaming(ctx context.Context, req *providers.ProviderRequest) (io.ReadCloser, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *fixedProvider) ListModels(ctx context.Context) ([]providers.Model, error) {
+	return nil, nil
+}
+
+func (p *fixedProvider) GetModelInfo(ctx context.Context, modelID string) (*providers.Model, error) {
+	return nil, errors.New("not found")
+}
+
+func serveProviderRoute(resp *providers.ProviderResponse) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/providers/fixed/*path", createProviderHandler(&fixedProvider{resp: resp}, health.NewChecker(), nil))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/providers/fixed/v1/output", nil))
+	return w
+}
+
+func TestProviderRouteContentType(t *testing.T) {
+	png := []byte("\x89PNG\r\n\x1a\n")
+	sse := []byte("data: {\"delta\":\"hi\"}\n\ndata: [DONE]\n\n")
+
+	tests := []struct {
+		name            string
+		headers         http.Header
+		body            []byte
+		wantType        string
+		wantContentCode string
+	}{
+		{"image", http.Header{"Content-Type": {"image/png"}}, png, "image/png", ""},
+		{"event stream", http.Header{"content-type": {"text/event-stream"}}, sse, "text/event-stream", ""},
+		{"gzip", http.Header{"Content-Type": {"application/json"}, "Content-Encoding": {"gzip"}}, []byte{0x1f, 0x8b}, "application/json", "gzip"},
+		{"no content type", http.Header{}, []byte("raw"), "application/octet-stream", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveProviderRoute(&providers.ProviderResponse{StatusCode: http.StatusOK, Headers: tt.headers, Body: tt.body})
+
+			if got := w.Header().Get("Content-Type"); got != tt.wantType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.wantType)
+			}
+			if got := w.Header().Get("Content-Encoding"); got != tt.wantContentCode {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.wantContentCode)
+			}
+			if w.Body.String() != string(tt.body) {
+				t.Errorf("body = %q, want %q", w.Body, tt.body)
+			}
+		})
+	}
+}
+
+// TestProviderRouteDraining tests that native and legacy routes reject
+// requests to a drained provider without invoking it
+func TestProviderRouteDraining(t *testing.T) {
+	provider := &fixedProvider{resp: &providers.ProviderResponse{StatusCode: http.StatusOK, Body: []byte(`{}`)}}
+	registry := map[string]providers.Provider{"fixed": provider}
+	drains, err := router.NewRouter(&router.Config{Providers: map[string]router.ProviderConfig{"fixed": {Enabled: true}}}, registry)
+	if err != nil {
+		t.Fatalf("NewRouter: %v", err)
+	}
+	drains.Drain("fixed")
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Any("/providers/fixed/*path", createProviderHandler(provider, health.NewChecker(), drains))
+	serve := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/providers/fixed/v1/output", nil))
+		return w
+	}
+
+	if w := serve(); w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "draining") {
+		t.Errorf("drained: status %d body %s, want 503 draining", w.Code, w.Body)
+	}
+	drains.Restore("fixed")
+	if w := serve(); w.Code != http.StatusOK {
+		t.Errorf("restored: status %d body %s, want 200", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright 2025 Bedrock Proxy Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package gateway
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/tosharewith/llmproxy_auth/internal/handlers"
+	"github.com/tosharewith/llmproxy_auth/internal/health"
+	"github.com/tosharewith/llmproxy_auth/internal/instance"
+	"github.com/tosharewith/llmproxy_auth/internal/providers"
+	"github.com/tosharewith/llmproxy_auth/internal/providers/bedrock"
+	"github.com/tosharewith/llmproxy_auth/internal/ratelimit"
+	"github.com/tosharewith/llmproxy_auth/internal/router"
+	"github.com/tosharewith/llmproxy_auth/internal/storage/s3"
+	"github.com/tosharewith/llmproxy_auth/pkg/metrics"
+)
+
+// registerProviderRoutes registers the native API endpoints of each
+// provider. Requests to providers drained in drains are rejected with 503.
+func registerProviderRoutes(group *gin.RouterGroup, providerRegistry map[string]providers.Provider, healthChecker *health.Checker, drains handlers.DrainState) {
+	if bedrockProvider, ok := providerRegistry["bedrock"]; ok {
+		group.Any("/bedrock/*path", createProviderHandler(bedrockProvider, healthChecker, drains))
+	}
+	if azureProvider, ok := providerRegistry["azure"]; ok {
+		group.Any("/azure/*path", createProviderHandler(azureProvider, healthChecker, drains))
+	}
+	if openaiProvider, ok := providerRegistry["openai"]; ok {
+		group.Any("/openai/*path", createProviderHandler(openaiProvider, healthChecker, drains))
+	}
+	if anthropicProvider, ok := providerRegistry["anthropic"]; ok {
+		group.Any("/anthropic/*path", createProviderHandler(anthropicProvider, healthChecker, drains))
+	}
+	if vertexProvider, ok := providerRegistry["vertex"]; ok {
+		group.Any("/vertex/*path", createProviderHandler(vertexProvider, healthChecker, drains))
+	}
+	if ibmProvider, ok := providerRegistry["ibm"]; ok {
+		// Extract and classify share the catch-all, as gin cannot
+		// register fixed routes beside it
+		ibmNative := createProviderHandler(ibmProvider, healthChecker, drains)
+		ibmExtract := handlers.NewIBMExtractHandler(ibmProvider)
+		ibmClassify := handlers.NewIBMClassifyHandler(ibmProvider)
+		group.Any("/ibm/*path", func(c *gin.Context) {
+			if rejectDraining(c, drains, ibmProvider.Name()) {
+				return
+			}
+			switch {
+			case c.Request.Method == "POST" && c.Param("path") == "/extract":
+				ibmExtract.Extract(c)
+			case c.Request.Method == "POST" && c.Param("path") == "/classify":
+				ibmClassify.Classify(c)
+			default:
+				ibmNative(c)
+			}
+		})
+	}
+	if oracleProvider, ok := providerRegistry["oracle"]; ok {
+		group.Any("/oracle/*path", createProviderHandler(oracleProvider, healthChecker, drains))
+	}
+}
+
+// createProviderHandler creates a handler for native provider API. Requests
+// are rejected with 503 while drains has the provider drained; drains may
+// be nil.
+func createProviderHandler(provider providers.Provider, healthChecker *health.Checker, drains handlers.DrainState) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if rejectDraining(c, drains, provider.Name()) {
+			return
+		}
+
+		// Extract path after the prefix
+		path := c.Param("path")
+
+		// Build provider request
+		body, _ := c.GetRawData()
+		providerReq := &providers.ProviderRequest{
+			Method:      c.Request.Method,
+			Path:        path,
+			Headers:     providers.ForwardHeaders(c.Request.Header),
+			Body:        body,
+			QueryParams: make(map[string]string),
+			Context:     c.Request.Context(),
+		}
+
+		// Copy query params
+		for key := range c.Request.URL.Query() {
+			providerReq.QueryParams[key] = c.Request.URL.Query().Get(key)
+		}
+
+		// Invoke provider
+		handlers.ForwardCorrelation(c, providerReq)
+		resp, err := provider.Invoke(c.Request.Context(), providerReq)
+		if err != nil {
+			healthChecker.RecordError(provider.Name())
+			if providerErr, ok := err.(*providers.ProviderError); ok {
+				c.Data(providerErr.StatusCode, "application/json", []byte(fmt.Sprintf(`{"error":"%s"}`, providerErr.Message)))
+			} else {
+				c.JSON(500, gin.H{"error": "Internal server error"})
+			}
+			return
+		}
+
+		healthChecker.RecordSuccess(provider.Name())
+
+		// Return response
+		providers.ApplyHeaders(c.Writer.Header(), providers.ForwardHeaders(resp.Headers))
+		handlers.RecordUpstreamRequestID(c, resp.Headers)
+		c.Data(resp.StatusCode, providers.ContentType(resp.Headers), resp.Body)
+		metrics.RecordPayloadSizes(provider.Name(), "", int64(len(body)), int64(len(resp.Body)))
+	}
+}
+
+// rejectDraining responds 503 if drains has the provider drained, for the
+// native routes, which invoke providers without routing
+func rejectDraining(c *gin.Context, drains handlers.DrainState, providerName string) bool {
+	if drains == nil || !drains.IsDraining(providerName) {
+		return false
+	}
+	c.JSON(503, gin.H{"error": fmt.Sprintf("provider %q: %v", providerName, router.ErrProviderDraining)})
+	return true
+}
+
+func healthHandler(checker *health.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if checker.IsHealthy() {
+			c.JSON(200, gin.H{
+				"status":  "healthy",
+				"service": "ai-gateway",
+			})
+		} else {
+			c.JSON(503, gin.H{
+				"status":  "unhealthy",
+				"service": "ai-gateway",
+			})
+		}
+	}
+}
+
+// providerStatuses health-checks the providers, or with background checks
+// returns the state they last left
+func providerStatuses(c *gin.Context, aiRouter *router.Router, background bool) []router.ProviderHealth {
+	if background {
+		return aiRouter.ProviderStates()
+	}
+	return aiRouter.CheckProviders(c.Request.Context())
+}
+
+func readyHandler(checker *health.Checker, aiRouter *router.Router, background bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Only required providers gate readiness; optional ones are ejected from routing
+		statuses := providerStatuses(c, aiRouter, background)
+
+		if checker.IsHealthy() && router.IsReady(statuses) {
+			c.JSON(200, gin.H{
+				"status": "ready",
+			})
+		} else {
+			c.JSON(503, gin.H{
+				"status": "not ready",
+			})
+		}
+	}
+}
+
+func providersHealthHandler(aiRouter *router.Router, checker *health.Checker, background bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		statuses := providerStatuses(c, aiRouter, background)
+
+		status := "ready"
+		if !router.IsReady(statuses) {
+			status = "not ready"
+		}
+
+		// Always 200: this endpoint reports detail, /ready is the probe
+		c.JSON(200, gin.H{
+			"status":    status,
+			"providers": statuses,
+			"traffic":   checker.ProviderStats(),
+		})
+	}
+}
+
+// enableBedrockBatch configures Bedrock batch inference, logging rather than failing on error
+func enableBedrockBatch(provider *bedrock.BedrockProvider, region, s3URI, roleARN string) {
+	s3Storage, err := s3.NewS3Provider(s3.S3Config{Region: region})
+	if err != nil {
+		log.Printf("Warning: Failed to create S3 storage for Bedrock batch: %v", err)
+		return
+	}
+
+	if err := provider.EnableBatch(bedrock.BatchConfig{
+		Storage: s3Storage,
+		S3URI:   s3URI,
+		RoleARN: roleARN,
+	}); err != nil {
+		log.Printf("Warning: Failed to enable Bedrock batch inference: %v", err)
+		return
+	}
+
+	log.Printf("✓ Bedrock batch inference enabled (%s)", s3URI)
+}
+
+// newInstanceSemaphores creates a concurrency semaphore for every instance
+// with max_concurrency set
+func newInstanceSemaphores(config *instance.Config, mode ratelimit.Mode) *providers.SemaphoreRegistry {
+	semaphores := providers.NewSemaphoreRegistry()
+	for _, name := range config.ListInstances() {
+		inst := config.Instances[name]
+		if inst.MaxConcurrency <= 0 {
+			continue
+		}
+		queueTimeout, _ := inst.QueueTimeoutDuration() // validated at load
+		semaphores.Register(name, providers.NewProviderSemaphore(inst.Type, name, inst.MaxConcurrency, queueTimeout, mode))
+		log.Printf("✓ Concurrency limit for %s: %d in flight, queue timeout %s (%s)", name, inst.MaxConcurrency, queueTimeout, mode)
+	}
+	return semaphores
+}
+
+// registerProtocolRoute registers handler for each method; nil methods means
+// an endpoint accepts all methods
+func registerProtocolRoute(group *gin.RouterGroup, path string, methods []string, handler gin.HandlerFunc) {
+	if methods == nil {
+		group.Any(path, handler)
+		return
+	}
+	for _, method := range methods {
+		group.Handle(method, path, handler)
+	}
+}
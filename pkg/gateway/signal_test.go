@@ -0,0 +1,69 @@
+//go:build unix
+
+package gateway
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestHandleSignals tests that the gateway reloads the model mapping on
+// SIGHUP only with HandleSignals, also when it polls the file
+func TestHandleSignals(t *testing.T) {
+	// Keep SIGHUP from ending the test process when the gateway does not
+	// subscribe to it
+	received := make(chan os.Signal, 1)
+	signal.Notify(received, syscall.SIGHUP)
+	defer signal.Stop(received)
+
+	for _, handleSignals := range []bool{false, true} {
+		gw := newTestGateway(t, Config{HandleSignals: handleSignals, ConfigWatchInterval: time.Hour})
+
+		// Add a model without changing the file's modification time, so
+		// only a SIGHUP reload picks it up
+		path := gw.config.ModelMappingFile
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		mapping := `
+model_mappings:
+  mock-chat:
+    default_provider: mock
+    providers:
+      mock:
+        model: mock-chat
+  mock-extra:
+    default_provider: mock
+    providers:
+      mock:
+        model: mock-extra
+
+providers:
+  mock:
+    enabled: true
+`
+		if err := os.WriteFile(path, []byte(mapping), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, info.ModTime(), info.ModTime()); err != nil {
+			t.Fatal(err)
+		}
+
+		syscall.Kill(os.Getpid(), syscall.SIGHUP)
+		<-received
+		reloaded := false
+		for deadline := time.Now().Add(500 * time.Millisecond); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if _, ok := gw.router.GetConfig().ModelMappings["mock-extra"]; ok {
+				reloaded = true
+				break
+			}
+		}
+		if reloaded != handleSignals {
+			t.Errorf("HandleSignals %v: reloaded on SIGHUP = %v", handleSignals, reloaded)
+		}
+	}
+}
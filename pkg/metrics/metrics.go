@@ -1,5 +1,7 @@
 package metrics
 
+//go:generate go run ../../cmd/alerts -o ../../deployments/prometheus-alerts.yaml
+
 import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
@@ -62,6 +64,16 @@ var (
 		[]string{"method", "status"},
 	)
 
+	// AWSRoleRefreshes tracks assumptions of a role chain, at startup and
+	// on each refresh of the temporary credentials
+	AWSRoleRefreshes = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "aws_role_refreshes_total",
+			Help: "Total number of assumed role credential refreshes",
+		},
+		[]string{"role_arn", "status"},
+	)
+
 	// BedrockModelInvocations tracks Bedrock model invocations
 	BedrockModelInvocations = promauto.NewCounterVec(
 		prometheus.CounterOpts{
@@ -96,6 +108,238 @@ var (
 		},
 		[]string{"check_type"}, // health, readiness
 	)
+
+	// FallbackActivations tracks requests retried against a fallback provider
+	FallbackActivations = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_fallback_activations_total",
+			Help: "Total number of requests retried against a fallback provider after the primary was unavailable",
+		},
+		[]string{"primary", "fallback"},
+	)
+
+	// ProviderErrorRate tracks each provider's error rate over the health window
+	ProviderErrorRate = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_error_rate",
+			Help: "Provider error rate over the health check sliding window",
+		},
+		[]string{"provider"},
+	)
+
+	// ProviderWindowSamples tracks the number of samples in each provider's health window
+	ProviderWindowSamples = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_health_window_samples",
+			Help: "Number of requests in the provider's health check sliding window",
+		},
+		[]string{"provider"},
+	)
+
+	// ProviderHealthy tracks traffic-based provider health
+	ProviderHealthy = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_healthy",
+			Help: "Traffic-based provider health (1 = healthy, 0 = unhealthy)",
+		},
+		[]string{"provider"},
+	)
+
+	// HealthCheckConsecutive tracks each provider's run of identical active
+	// health check results
+	HealthCheckConsecutive = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "provider_health_check_consecutive",
+			Help: "Consecutive active health check failures or successes of a provider",
+		},
+		[]string{"provider", "result"},
+	)
+
+	// HealthCheckTransitions tracks providers changing health state after
+	// the consecutive check thresholds are met
+	HealthCheckTransitions = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "provider_health_transitions_total",
+			Help: "Total number of provider health state transitions from active health checks",
+		},
+		[]string{"provider", "state"},
+	)
+
+	// Notifications tracks webhook notification events by outcome: sent,
+	// suppressed by the per-event cooldown, or dropped
+	Notifications = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_notifications_total",
+			Help: "Total number of webhook notification events by outcome",
+		},
+		[]string{"event", "result"},
+	)
+
+	// NotificationsDropped tracks webhook notifications that were never
+	// delivered, because the queue was full or every retry failed
+	NotificationsDropped = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_notifications_dropped_total",
+			Help: "Total number of webhook notifications dropped without delivery",
+		},
+		[]string{"reason"},
+	)
+
+	// RateLimitRejected tracks requests rejected by the rate limiter
+	RateLimitRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_ratelimit_rejected_total",
+			Help: "Total number of requests rejected by the rate limiter",
+		},
+		[]string{"model", "key_prefix"},
+	)
+
+	// RateLimitRemaining tracks requests remaining in each active rate limit window
+	RateLimitRemaining = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_ratelimit_remaining",
+			Help: "Requests remaining in the current rate limit window",
+		},
+		[]string{"model", "key_prefix"},
+	)
+
+	// LimitWouldBlock tracks requests a limiter in report-only mode would have rejected
+	LimitWouldBlock = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ai_limit_would_block_total",
+			Help: "Total number of requests a report-only limiter would have rejected",
+		},
+		[]string{"limiter", "reason"},
+	)
+
+	// QueueDepth tracks requests waiting for a provider instance
+	// concurrency slot
+	QueueDepth = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_queue_depth",
+			Help: "Requests waiting for a provider instance concurrency slot",
+		},
+		[]string{"provider", "instance"},
+	)
+
+	// RequestsByTag tracks requests by X-Request-Tags tag, for configured
+	// tag keys and a bounded number of values per key
+	RequestsByTag = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_requests_by_tag_total",
+			Help: "Total number of requests carrying each request tag value",
+		},
+		[]string{"tag", "value"},
+	)
+
+	// RequestBytes tracks the size of request bodies sent to providers
+	RequestBytes = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ai_request_bytes",
+			Help:    "Size in bytes of request bodies sent to providers",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 10), // 256B to 64MB
+		},
+		[]string{"provider", "instance"},
+	)
+
+	// ResponseBytes tracks the size of response bodies written to clients,
+	// accumulated over the whole stream for streaming responses
+	ResponseBytes = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "ai_response_bytes",
+			Help:    "Size in bytes of response bodies written to clients",
+			Buckets: prometheus.ExponentialBuckets(256, 4, 10), // 256B to 64MB
+		},
+		[]string{"provider", "instance"},
+	)
+
+	// StreamErrors tracks provider streams that failed after the response
+	// had started
+	StreamErrors = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_stream_errors_total",
+			Help: "Total number of provider streams that failed mid-stream",
+		},
+		[]string{"provider"},
+	)
+
+	// SlowStreamClients tracks streams cancelled because the client read
+	// them more slowly than the provider produced them
+	SlowStreamClients = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_stream_slow_clients_total",
+			Help: "Total number of streams cancelled because the client stopped reading",
+		},
+		[]string{"provider"},
+	)
+
+	// InjectedFaults tracks faults injected by the chaos admin API, so
+	// injected errors can be told apart from real ones
+	InjectedFaults = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_injected_faults_total",
+			Help: "Total number of faults injected into provider instance requests, by instance and fault kind",
+		},
+		[]string{"instance", "fault"},
+	)
+
+	// DeprecatedEndpointRequests tracks requests to deprecated routes, by
+	// route prefix and authenticated identity
+	DeprecatedEndpointRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "deprecated_endpoint_requests_total",
+			Help: "Total number of requests to deprecated endpoints",
+		},
+		[]string{"route", "identity"},
+	)
+
+	// BudgetSpentUSD tracks the spend of keys and tenants with a budget in
+	// their current budget window
+	BudgetSpentUSD = promauto.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "gateway_budget_spent_usd",
+			Help: "Spend in USD of keys and tenants in the current budget window",
+		},
+		[]string{"subject"},
+	)
+
+	// BudgetRejected tracks requests rejected for an exhausted budget
+	BudgetRejected = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_budget_rejected_total",
+			Help: "Total number of requests rejected for an exhausted budget, by key or tenant",
+		},
+		[]string{"subject"},
+	)
+
+	// DeprecatedFieldUsed tracks chat requests using deprecated fields,
+	// rewritten by the legacy migrator, by field
+	DeprecatedFieldUsed = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "deprecated_field_used_total",
+			Help: "Total number of requests migrated from deprecated fields, by field",
+		},
+		[]string{"field"},
+	)
+
+	// CostUSD tracks the priced cost of responses, from the pricing table
+	CostUSD = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_cost_usd_total",
+			Help: "Total cost in USD of responses, by provider and model",
+		},
+		[]string{"provider", "model"},
+	)
+
+	// UnpricedRequests tracks responses whose model has no price, neither
+	// its own nor a provider default, so gaps in the pricing table show
+	UnpricedRequests = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "gateway_unpriced_requests_total",
+			Help: "Total number of responses for models without a configured price",
+		},
+		[]string{"provider", "model"},
+	)
 )
 
 // Init initializes metrics (can be used for custom setup if needed)
@@ -128,6 +372,24 @@ func SetHealthStatus(checkType string, healthy bool) {
 	HealthCheckStatus.WithLabelValues(checkType).Set(value)
 }
 
+// SetProviderHealth records a provider's sliding-window error rate and health
+func SetProviderHealth(provider string, errorRate float64, samples int64, healthy bool) {
+	var value float64
+	if healthy {
+		value = 1
+	}
+	ProviderErrorRate.WithLabelValues(provider).Set(errorRate)
+	ProviderWindowSamples.WithLabelValues(provider).Set(float64(samples))
+	ProviderHealthy.WithLabelValues(provider).Set(value)
+}
+
+// RecordPayloadSizes records the request and response body sizes of a
+// provider request. instance is empty for requests not served by an instance.
+func RecordPayloadSizes(provider, instance string, requestBytes, responseBytes int64) {
+	RequestBytes.WithLabelValues(provider, instance).Observe(float64(requestBytes))
+	ResponseBytes.WithLabelValues(provider, instance).Observe(float64(responseBytes))
+}
+
 // SetConnectedClients sets the number of connected clients
 func SetConnectedClients(count int) {
 	ConnectedClients.Set(float64(count))
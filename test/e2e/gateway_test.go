@@ -0,0 +1,337 @@
+//go:build integration
+
+package e2e
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testAPIKey is the API key the test gateways accept
+const testAPIKey = "e2e-test-key"
+
+// modelMapping routes mock-chat to the mock provider, and gpt-4o to OpenAI
+// with the mock as fallback. OpenAI has a placeholder key and an
+// unreachable base URL in the test gateways, so tests drain it to have
+// gpt-4o served by the fallback.
+const modelMapping = `
+model_mappings:
+  mock-chat:
+    default_provider: mock
+    providers:
+      mock:
+        model: mock-chat
+  gpt-4o:
+    default_provider: openai
+    providers:
+      openai:
+        model: gpt-4o
+      mock:
+        model: mock-chat
+
+routing:
+  fallback:
+    enabled: true
+    providers: [mock]
+    max_attempts: 1
+
+providers:
+  mock:
+    enabled: true
+  openai:
+    enabled: true
+
+features:
+  streaming: true
+  auto_fallback: true
+`
+
+// gatewayBinary is the gateway built by TestMain
+var gatewayBinary string
+
+// TestMain builds the gateway once for every test
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "gateway-e2e")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	gatewayBinary = filepath.Join(dir, "gateway")
+
+	_, file, _, _ := runtime.Caller(0)
+	build := exec.Command("go", "build", "-o", gatewayBinary, "./cmd/server")
+	build.Dir = filepath.Join(filepath.Dir(file), "..", "..")
+	build.Stdout, build.Stderr = os.Stdout, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "building the gateway: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// gateway is a running gateway process serving the mock provider
+type gateway struct {
+	URL string
+
+	mu   sync.Mutex
+	logs bytes.Buffer
+}
+
+func (g *gateway) Write(p []byte) (int, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.logs.Write(p)
+}
+
+// Logs returns the gateway's output so far
+func (g *gateway) Logs() string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.logs.String()
+}
+
+// startGateway runs the gateway with modelMapping, API key authentication
+// and the mock provider, plus env (KEY=value) for the mock's settings. The
+// environment is not inherited, so real provider credentials are never
+// used. The gateway is stopped when the test ends.
+func startGateway(t *testing.T, env ...string) *gateway {
+	t.Helper()
+	dir := t.TempDir()
+	config := filepath.Join(dir, "model-mapping.yaml")
+	if err := os.WriteFile(config, []byte(modelMapping), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	g := &gateway{URL: fmt.Sprintf("http://127.0.0.1:%d", port)}
+	cmd := exec.Command(gatewayBinary)
+	cmd.Dir = dir
+	cmd.Env = append([]string{
+		"PATH=" + os.Getenv("PATH"),
+		"HOME=" + dir,
+		fmt.Sprintf("PORT=%d", port),
+		"GIN_MODE=release",
+		"AUTH_ENABLED=true",
+		"AUTH_MODE=api_key",
+		"BEDROCK_API_KEY_E2E=" + testAPIKey,
+		"ENABLE_MOCK_PROVIDER=true",
+		"OPENAI_API_KEY=e2e-unused",
+		"OPENAI_BASE_URL=http://127.0.0.1:1",
+		"MODEL_MAPPING_CONFIG=" + config,
+		"PROVIDER_INSTANCES_CONFIG=" + filepath.Join(dir, "no-instances.yaml"),
+	}, env...)
+	cmd.Stdout, cmd.Stderr = g, g
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("starting the gateway: %v", err)
+	}
+	exited := make(chan struct{})
+	go func() {
+		cmd.Wait()
+		close(exited)
+	}()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		<-exited
+		if t.Failed() {
+			t.Logf("gateway output:\n%s", g.Logs())
+		}
+	})
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		resp, err := http.Get(g.URL + "/health")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return g
+			}
+		}
+		select {
+		case <-exited:
+			t.Fatalf("gateway exited:\n%s", g.Logs())
+		case <-time.After(50 * time.Millisecond):
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("gateway not healthy after 30s:\n%s", g.Logs())
+		}
+	}
+}
+
+// do sends a request with the test API key, decoding a JSON response into
+// out when it is not nil. It returns the response, whose body is read.
+func (g *gateway) do(t *testing.T, method, path string, body, out interface{}) (*http.Response, []byte) {
+	t.Helper()
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, g.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("X-API-Key", testAPIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(data, out); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, path, err, data)
+		}
+	}
+	return resp, data
+}
+
+// chat sends a chat completion request, failing the test unless it
+// succeeds
+func (g *gateway) chat(t *testing.T, req OpenAIRequest) OpenAIResponse {
+	t.Helper()
+	var resp OpenAIResponse
+	if httpResp, body := g.do(t, http.MethodPost, "/v1/chat/completions", req, &resp); httpResp.StatusCode != http.StatusOK {
+		t.Fatalf("chat completion: status %d: %s", httpResp.StatusCode, body)
+	}
+	if len(resp.Choices) == 0 {
+		t.Fatal("chat completion has no choices")
+	}
+	return resp
+}
+
+// embed sends an embeddings request, failing the test unless it succeeds
+func (g *gateway) embed(t *testing.T, model string, input []string) EmbeddingsResponse {
+	t.Helper()
+	var resp EmbeddingsResponse
+	req := map[string]interface{}{"model": model, "input": input}
+	if httpResp, body := g.do(t, http.MethodPost, "/v1/embeddings", req, &resp); httpResp.StatusCode != http.StatusOK {
+		t.Fatalf("embeddings: status %d: %s", httpResp.StatusCode, body)
+	}
+	if len(resp.Data) != len(input) {
+		t.Fatalf("embeddings: %d embeddings for %d inputs", len(resp.Data), len(input))
+	}
+	return resp
+}
+
+// userMessage returns a user message of text parts
+func userMessage(texts ...string) Message {
+	message := Message{Role: "user"}
+	for _, text := range texts {
+		message.Content = append(message.Content, ContentBlock{Type: "text", Text: text})
+	}
+	return message
+}
+
+// replyText returns the text of the first choice of a response
+func replyText(resp OpenAIResponse) string {
+	var texts []string
+	for _, block := range resp.Choices[0].Message.Content {
+		texts = append(texts, block.Text)
+	}
+	return strings.Join(texts, "")
+}
+
+// Data structures for OpenAI-compatible API
+
+type OpenAIRequest struct {
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature float64   `json:"temperature,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+}
+
+type Message struct {
+	Role    string   `json:"role"`
+	Content Contents `json:"content"`
+}
+
+// Contents are the content blocks of a message, which responses send as a
+// plain string
+type Contents []ContentBlock
+
+func (c *Contents) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*c = Contents{{Type: "text", Text: text}}
+		return nil
+	}
+	var blocks []ContentBlock
+	if err := json.Unmarshal(data, &blocks); err != nil {
+		return err
+	}
+	*c = blocks
+	return nil
+}
+
+type ContentBlock struct {
+	Type string `json:"type"`
+	Text string `json:"text,omitempty"`
+}
+
+type OpenAIResponse struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []Choice `json:"choices"`
+	Usage   Usage    `json:"usage"`
+}
+
+type Choice struct {
+	Index        int     `json:"index"`
+	Message      Message `json:"message"`
+	FinishReason string  `json:"finish_reason"`
+}
+
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type EmbeddingsResponse struct {
+	Data []struct {
+		Index     int       `json:"index"`
+		Embedding []float64 `json:"embedding"`
+	} `json:"data"`
+	Usage Usage `json:"usage"`
+}
+
+type ErrorResponse struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error"`
+}
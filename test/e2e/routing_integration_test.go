@@ -0,0 +1,178 @@
+//go:build integration
+
+package e2e
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RouteResponse is the part of GET /v1/route the tests check
+type RouteResponse struct {
+	Provider        string `json:"provider"`
+	ProviderModel   string `json:"provider_model"`
+	DefaultProvider string `json:"default_provider"`
+	Fallback        bool   `json:"fallback"`
+}
+
+// TestRouting tests that models are routed by mapping and by fallback to
+// the mock provider
+func TestRouting(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E routing test in short mode")
+	}
+	g := startGateway(t)
+	if resp, body := g.do(t, http.MethodPost, "/admin/providers/openai/drain", nil, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("drain openai: status %d: %s", resp.StatusCode, body)
+	}
+
+	tests := []struct {
+		name     string
+		model    string
+		fallback bool
+	}{
+		{"Mapped model", "mock-chat", false},
+		{"Fallback from a drained provider", "gpt-4o", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var route RouteResponse
+			if resp, body := g.do(t, http.MethodGet, "/v1/route?model="+tt.model, nil, &route); resp.StatusCode != http.StatusOK {
+				t.Fatalf("route: status %d: %s", resp.StatusCode, body)
+			}
+			if route.Provider != "mock" || route.Fallback != tt.fallback {
+				t.Errorf("route = %+v, want mock with fallback %v", route, tt.fallback)
+			}
+
+			resp := g.chat(t, OpenAIRequest{Model: tt.model, Messages: []Message{userMessage("Hello")}})
+			if reply := replyText(resp); reply != "Mock response to: Hello" {
+				t.Errorf("reply = %q", reply)
+			}
+		})
+	}
+
+	t.Run("Unknown model", func(t *testing.T) {
+		resp, body := g.do(t, http.MethodPost, "/v1/chat/completions",
+			OpenAIRequest{Model: "no-such-model", Messages: []Message{userMessage("Hello")}}, nil)
+		if resp.StatusCode == http.StatusOK {
+			t.Errorf("status = %d: %s", resp.StatusCode, body)
+		}
+	})
+}
+
+// TestRoutingStreaming tests a streamed chat completion through the gateway
+func TestRoutingStreaming(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+	g := startGateway(t)
+
+	resp, body := g.do(t, http.MethodPost, "/v1/chat/completions", OpenAIRequest{
+		Model:    "mock-chat",
+		Messages: []Message{userMessage("Stream this reply")},
+		Stream:   true,
+	}, nil)
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		t.Fatalf("status %d, Content-Type %q: %s", resp.StatusCode, resp.Header.Get("Content-Type"), body)
+	}
+
+	var text strings.Builder
+	done := false
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			done = true
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", data, err)
+		}
+		for _, choice := range chunk.Choices {
+			text.WriteString(choice.Delta.Content)
+		}
+	}
+	if text.String() != "Mock response to: Stream this reply" || !done {
+		t.Errorf("streamed %q, [DONE] %v", text.String(), done)
+	}
+}
+
+// TestRoutingProviderErrors tests that injected provider errors reach the
+// client as OpenAI errors with the provider's status and code
+func TestRoutingProviderErrors(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+	g := startGateway(t,
+		"MOCK_ERROR_RATE=0.5",
+		"MOCK_ERROR_STATUS=429",
+		"MOCK_ERROR_CODE=rate_limit_exceeded",
+		"MOCK_LATENCY=20ms")
+
+	statuses := make([]int, 4)
+	for i := range statuses {
+		start := time.Now()
+		resp, body := g.do(t, http.MethodPost, "/v1/chat/completions",
+			OpenAIRequest{Model: "mock-chat", Messages: []Message{userMessage("Hello")}}, nil)
+		statuses[i] = resp.StatusCode
+		if elapsed := time.Since(start); elapsed < 20*time.Millisecond {
+			t.Errorf("request %d took %s, want at least the mock's latency", i+1, elapsed)
+		}
+
+		if resp.StatusCode != http.StatusTooManyRequests {
+			continue
+		}
+		var errResp ErrorResponse
+		if err := json.Unmarshal(body, &errResp); err != nil || errResp.Error.Code != "rate_limit_exceeded" || errResp.Error.Type != "rate_limit_error" {
+			t.Errorf("request %d: error body %s", i+1, body)
+		}
+	}
+
+	// Every second request fails
+	want := []int{http.StatusOK, http.StatusTooManyRequests, http.StatusOK, http.StatusTooManyRequests}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Errorf("statuses = %v, want %v", statuses, want)
+			break
+		}
+	}
+}
+
+// TestRoutingMetrics tests that requests routed to the mock are counted in
+// the Prometheus metrics
+func TestRoutingMetrics(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping E2E test in short mode")
+	}
+	g := startGateway(t)
+	g.chat(t, OpenAIRequest{Model: "mock-chat", Messages: []Message{userMessage("Count me")}})
+
+	resp, err := http.Get(g.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var metrics bytes.Buffer
+	metrics.ReadFrom(resp.Body)
+
+	for _, want := range []string{`http_requests_total{`, `provider="mock"`} {
+		if !strings.Contains(metrics.String(), want) {
+			t.Errorf("metrics are missing %s", want)
+		}
+	}
+}